@@ -0,0 +1,213 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// accessStatsFileName is the name of the file in a collection's directory that
+// the access stats of its documents are persisted to, see
+// [Collection.SetAccessStats].
+const accessStatsFileName = "access.stats"
+
+// DocumentAccessStats are the statistics of how often a document was retrieved,
+// see [Collection.SetAccessStats].
+type DocumentAccessStats struct {
+	// The number of query results the document was in.
+	Retrievals int64
+
+	// The time the document was last in a query result. Zero if it was never
+	// retrieved.
+	LastRetrievedAt time.Time
+}
+
+// accessStatsStore holds the access stats of a collection's documents.
+type accessStatsStore struct {
+	lock  sync.Mutex
+	stats map[string]DocumentAccessStats
+	// Whether the stats changed since they were persisted.
+	dirty bool
+	// Serializes flushes, so an older copy of the stats can't overwrite a
+	// newer one. It's held while writing, unlike lock.
+	flushLock sync.Mutex
+}
+
+// SetAccessStats enables tracking how often and when each document is
+// retrieved, so unused documents can be found, for example to delete or archive
+// them (see [Collection.ColdDocuments]). The results of all queries count,
+// including cached ones (see [Collection.SetSessionCache]).
+//
+// The setting is persisted. For performance, the stats aren't written with
+// each query, but with [Collection.FlushAccessStats], with the
+// [MaintenanceOptions.FlushAccessStats] maintenance task and when the DB is
+// closed. Disabling the tracking deletes the stats.
+func (c *Collection) SetAccessStats(enabled bool) error {
+	c.configLock.Lock()
+	c.config.AccessStats = enabled
+	c.configLock.Unlock()
+
+	if !enabled {
+		c.accessStats.reset()
+		if c.persistDirectory != "" {
+			err := removeFile(filepath.Join(c.persistDirectory, accessStatsFileName))
+			if err != nil {
+				return fmt.Errorf("couldn't remove access stats: %w", err)
+			}
+		}
+	}
+	return c.persistMetadata()
+}
+
+// AccessStats returns the access stats of all documents in the collection by
+// their ID, see [Collection.SetAccessStats]. Documents that were never
+// retrieved have zero stats. It returns nil if the tracking is disabled.
+func (c *Collection) AccessStats() map[string]DocumentAccessStats {
+	if !c.getConfig().AccessStats {
+		return nil
+	}
+
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	c.accessStats.lock.Lock()
+	defer c.accessStats.lock.Unlock()
+
+	res := make(map[string]DocumentAccessStats, len(c.documents))
+	for id := range c.documents {
+		res[id] = c.accessStats.stats[id]
+	}
+	return res
+}
+
+// ColdDocuments returns the IDs of the documents that weren't retrieved since
+// the given time, including the ones that were never retrieved, sorted by ID.
+// Note that documents that were added after the time can't have been retrieved
+// before it. It returns an error if the tracking is disabled, see
+// [Collection.SetAccessStats].
+func (c *Collection) ColdDocuments(since time.Time) ([]string, error) {
+	if !c.getConfig().AccessStats {
+		return nil, errors.New("access stats are disabled")
+	}
+
+	var res []string
+	for id, stats := range c.AccessStats() {
+		if stats.LastRetrievedAt.Before(since) {
+			res = append(res, id)
+		}
+	}
+	slices.Sort(res)
+	return res, nil
+}
+
+// FlushAccessStats writes the access stats to disk, if the collection is
+// persistent and they changed since they were last written, see
+// [Collection.SetAccessStats]. Stats of documents that were deleted are
+// dropped.
+func (c *Collection) FlushAccessStats() error {
+	if c.persistDirectory == "" || !c.getConfig().AccessStats {
+		return nil
+	}
+
+	c.accessStats.flushLock.Lock()
+	defer c.accessStats.flushLock.Unlock()
+
+	// Copy the stats, so queries aren't blocked while they're written.
+	c.documentsLock.RLock()
+	c.accessStats.lock.Lock()
+	if !c.accessStats.dirty {
+		c.accessStats.lock.Unlock()
+		c.documentsLock.RUnlock()
+		return nil
+	}
+	for id := range c.accessStats.stats {
+		if _, ok := c.documents[id]; !ok {
+			delete(c.accessStats.stats, id)
+		}
+	}
+	stats := maps.Clone(c.accessStats.stats)
+	// Retrievals that are recorded while writing mark the stats dirty again.
+	c.accessStats.dirty = false
+	c.accessStats.lock.Unlock()
+	c.documentsLock.RUnlock()
+
+	err := c.persistAccessStats(stats)
+	if err != nil {
+		c.accessStats.lock.Lock()
+		c.accessStats.dirty = true
+		c.accessStats.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// persistAccessStats writes the stats to a temporary file first and then
+// renames it, so it's never left half-written.
+func (c *Collection) persistAccessStats(stats map[string]DocumentAccessStats) error {
+	statsPath := filepath.Join(c.persistDirectory, accessStatsFileName)
+	tmpPath := statsPath + ".tmp"
+	err := persistToFileSynced(tmpPath, stats, c.compress, "", c.syncer)
+	if err != nil {
+		return fmt.Errorf("couldn't persist access stats: %w", err)
+	}
+	err = os.Rename(tmpPath, statsPath)
+	if err != nil {
+		return fmt.Errorf("couldn't rename access stats file: %w", err)
+	}
+	return c.syncer.syncDir(statsPath)
+}
+
+// loadAccessStats reads the persisted access stats, if there are any. It's
+// called when the collection is loaded, before the DB is used.
+func (c *Collection) loadAccessStats() error {
+	statsPath := filepath.Join(c.persistDirectory, accessStatsFileName)
+	if _, err := os.Stat(statsPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	stats := make(map[string]DocumentAccessStats)
+	err := readFromFile(statsPath, &stats, "")
+	if err != nil {
+		return fmt.Errorf("couldn't read access stats: %w", err)
+	}
+	c.accessStats.stats = stats
+	return nil
+}
+
+// recordAccess counts the retrieval of the results, if the tracking is enabled.
+func (c *Collection) recordAccess(results []Result) {
+	if len(results) == 0 || !c.getConfig().AccessStats {
+		return
+	}
+	c.accessStats.record(results, time.Now())
+}
+
+// record counts the retrieval of the results at the given time.
+func (s *accessStatsStore) record(results []Result, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stats == nil {
+		s.stats = make(map[string]DocumentAccessStats)
+	}
+	for _, res := range results {
+		stats := s.stats[res.ID]
+		stats.Retrievals++
+		stats.LastRetrievedAt = now
+		s.stats[res.ID] = stats
+	}
+	s.dirty = true
+}
+
+// reset deletes the stats.
+func (s *accessStatsStore) reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stats = nil
+	s.dirty = false
+}
@@ -0,0 +1,167 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCollection_SetAccessStats(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollectionWithOptions("test", CollectionOptions{
+		EmbeddingFunc: embeddingFunc,
+		AccessStats:   true,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "hot", Embedding: []float32{1, 0}, Content: "foo"},
+		{ID: "cold", Embedding: []float32{0, 1}, Content: "bar"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	before := time.Now()
+	for i := 0; i < 2; i++ {
+		_, err = c.Query(ctx, "foo", 1, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	stats := c.AccessStats()
+	if len(stats) != 2 {
+		t.Fatal("expected stats of 2 documents, got", len(stats))
+	}
+	if stats["hot"].Retrievals != 2 || stats["hot"].LastRetrievedAt.Before(before) {
+		t.Fatal("expected 2 retrievals of hot document, got", stats["hot"])
+	}
+	if stats["cold"] != (DocumentAccessStats{}) {
+		t.Fatal("expected no retrievals of cold document, got", stats["cold"])
+	}
+	cold, err := c.ColdDocuments(before)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(cold) != 1 || cold[0] != "cold" {
+		t.Fatal("expected cold document, got", cold)
+	}
+
+	// The stats are persisted when the DB is closed.
+	err = db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	db, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", embeddingFunc)
+	if c == nil {
+		t.Fatal("expected collection, got nil")
+	}
+	if got := c.AccessStats()["hot"]; got.Retrievals != 2 || !got.LastRetrievedAt.Equal(stats["hot"].LastRetrievedAt) {
+		t.Fatal("expected persisted stats of hot document, got", got)
+	}
+
+	// Disabling deletes the stats.
+	err = c.SetAccessStats(false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.AccessStats() != nil {
+		t.Fatal("expected no stats")
+	}
+	if _, err := c.ColdDocuments(before); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.SetAccessStats(true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if got := c.AccessStats()["hot"]; got.Retrievals != 0 {
+		t.Fatal("expected no retrievals after disabling, got", got)
+	}
+}
+
+func TestCollection_FlushAccessStats(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollectionWithOptions("test", CollectionOptions{AccessStats: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	query := func() {
+		_, err := c.QueryEmbedding(ctx, []float32{1, 0}, 1, nil, nil)
+		if err != nil {
+			t.Error("expected no error, got", err)
+		}
+	}
+
+	// A failed flush keeps the stats dirty, so the next one writes them.
+	query()
+	statsPath := filepath.Join(c.persistDirectory, accessStatsFileName)
+	err = os.MkdirAll(filepath.Join(statsPath, "blocked"), 0o700)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.FlushAccessStats()
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = os.RemoveAll(statsPath)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Queries and flushes run concurrently, and the last flush has all
+	// retrievals.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			query()
+		}()
+		go func() {
+			defer wg.Done()
+			if err := c.FlushAccessStats(); err != nil {
+				t.Error("expected no error, got", err)
+			}
+		}()
+	}
+	wg.Wait()
+	err = c.FlushAccessStats()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	stats := make(map[string]DocumentAccessStats)
+	err = readFromFile(statsPath, &stats, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats["1"].Retrievals != 11 {
+		t.Fatal("expected 11 retrievals, got", stats["1"])
+	}
+}
@@ -0,0 +1,446 @@
+package chromem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Default and maximum page sizes for the document listing of the admin API.
+const (
+	adminDefaultLimit = 50
+	adminMaxLimit     = 1000
+)
+
+// AdminHandler returns an [http.Handler] serving a small web UI to browse the
+// collections of the given DB, inspect documents and their metadata, run queries
+// and view basic stats. It's meant for the local development of RAG apps and
+// shouldn't be exposed publicly without additional protection, like
+// [NewAuthMiddleware].
+//
+// The handler serves the UI at "/" and a JSON API under "/api/". To mount it
+// under a different path, use [http.StripPrefix]:
+//
+//	http.Handle("/admin/", http.StripPrefix("/admin", chromem.AdminHandler(db)))
+//
+// The JSON API consists of:
+//
+//   - GET /api/collections: List all collections with their metadata and counts
+//   - GET /api/collections/{name}: Stats of a single collection
+//   - GET /api/collections/{name}/documents?offset=0&limit=50: List documents,
+//     sorted by ID, without embeddings
+//   - GET /api/collections/{name}/documents/{id}: A single document, including
+//     its embedding
+//   - POST /api/collections/{name}/query: Run a query, with a JSON body like
+//     {"query": "...", "nResults": 5, "where": {...}, "whereDocument": {...}},
+//     where the where filter can exclude documents with
+//     {"$not": {"archived": "true"}}, see [CompileFilter]
+//
+// Queries use the collection's embedding function, so for a persistent DB the
+// collection must have been retrieved with [DB.GetCollection] at least once.
+func AdminHandler(db *DB) http.Handler {
+	return &adminHandler{db: db}
+}
+
+type adminHandler struct {
+	db *DB
+}
+
+// adminCollection is the JSON representation of a collection in the admin API.
+type adminCollection struct {
+	Name       string            `json:"name"`
+	Metadata   map[string]string `json:"metadata"`
+	Count      int               `json:"count"`
+	Dimensions int               `json:"dimensions,omitempty"`
+}
+
+// adminDocument is the JSON representation of a document in the admin API.
+type adminDocument struct {
+	ID         string            `json:"id"`
+	Metadata   map[string]string `json:"metadata"`
+	Content    string            `json:"content"`
+	Dimensions int               `json:"dimensions"`
+	Embedding  []float32         `json:"embedding,omitempty"`
+}
+
+// adminResult is the JSON representation of a query result in the admin API.
+type adminResult struct {
+	ID         string            `json:"id"`
+	Metadata   map[string]string `json:"metadata"`
+	Content    string            `json:"content"`
+	Similarity float32           `json:"similarity"`
+}
+
+type adminQueryRequest struct {
+	Query         string            `json:"query"`
+	NResults      int               `json:"nResults"`
+	Where         whereJSON         `json:"where"`
+	WhereDocument map[string]string `json:"whereDocument"`
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" || r.URL.Path == "" {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(adminPage))
+		return
+	}
+
+	// We split the escaped path so that collection names and document IDs can
+	// contain slashes.
+	segments, err := splitEscapedPath(r.URL.EscapedPath())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(segments) < 2 || segments[0] != "api" || segments[1] != "collections" {
+		writeJSONError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	segments = segments[2:]
+
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		h.listCollections(w, r)
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		h.getCollection(w, r, segments[0])
+	case len(segments) == 2 && segments[1] == "documents" && r.Method == http.MethodGet:
+		h.listDocuments(w, r, segments[0])
+	case len(segments) == 3 && segments[1] == "documents" && r.Method == http.MethodGet:
+		h.getDocument(w, r, segments[0], segments[2])
+	case len(segments) == 2 && segments[1] == "query" && r.Method == http.MethodPost:
+		h.query(w, r, segments[0])
+	default:
+		writeJSONError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (h *adminHandler) listCollections(w http.ResponseWriter, r *http.Request) {
+	collections := h.db.ListCollections()
+	res := make([]adminCollection, 0, len(collections))
+	for name, c := range collections {
+		if !authorized(r.Context(), name, PermissionRead) {
+			continue
+		}
+		res = append(res, c.adminInfo())
+	}
+	slices.SortFunc(res, func(a, b adminCollection) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *adminHandler) getCollection(w http.ResponseWriter, r *http.Request, name string) {
+	c, ok := h.collection(w, r, name)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, c.adminInfo())
+}
+
+func (h *adminHandler) listDocuments(w http.ResponseWriter, r *http.Request, name string) {
+	c, ok := h.collection(w, r, name)
+	if !ok {
+		return
+	}
+
+	offset, err := queryParamInt(r.URL.Query(), "offset", 0)
+	if err != nil || offset < 0 {
+		writeJSONError(w, http.StatusBadRequest, errors.New("offset must be a non-negative integer"))
+		return
+	}
+	limit, err := queryParamInt(r.URL.Query(), "limit", adminDefaultLimit)
+	if err != nil || limit < 1 || limit > adminMaxLimit {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("limit must be an integer between 1 and %d", adminMaxLimit))
+		return
+	}
+
+	c.documentsLock.RLock()
+	ids := make([]string, 0, len(c.documents))
+	for id := range c.documents {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	total := len(ids)
+	page := make([]adminDocument, 0, limit)
+	for i := offset; i < total && i < offset+limit; i++ {
+		doc, err := c.withContentLocked(c.documents[ids[i]])
+		if err != nil {
+			c.documentsLock.RUnlock()
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		page = append(page, adminDocument{
+			ID:         doc.ID,
+			Metadata:   doc.Metadata,
+			Content:    doc.Content,
+			Dimensions: len(doc.Embedding),
+		})
+	}
+	c.documentsLock.RUnlock()
+
+	writeJSON(w, http.StatusOK, struct {
+		Total     int             `json:"total"`
+		Offset    int             `json:"offset"`
+		Limit     int             `json:"limit"`
+		Documents []adminDocument `json:"documents"`
+	}{
+		Total:     total,
+		Offset:    offset,
+		Limit:     limit,
+		Documents: page,
+	})
+}
+
+func (h *adminHandler) getDocument(w http.ResponseWriter, r *http.Request, name, id string) {
+	c, ok := h.collection(w, r, name)
+	if !ok {
+		return
+	}
+
+	c.documentsLock.RLock()
+	doc, ok := c.documents[id]
+	c.documentsLock.RUnlock()
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("%w: %q", ErrDocumentNotFound, id))
+		return
+	}
+	doc, err := c.withContent(doc)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adminDocument{
+		ID:         doc.ID,
+		Metadata:   doc.Metadata,
+		Content:    doc.Content,
+		Dimensions: len(doc.Embedding),
+		Embedding:  doc.Embedding,
+	})
+}
+
+func (h *adminHandler) query(w http.ResponseWriter, r *http.Request, name string) {
+	c, ok := h.collection(w, r, name)
+	if !ok {
+		return
+	}
+
+	var req adminQueryRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("couldn't decode request body: %w", err))
+		return
+	}
+	if req.Query == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("query is empty"))
+		return
+	}
+	if c.embed == nil {
+		writeJSONError(w, http.StatusConflict, errors.New("collection has no embedding function yet, get it via DB.GetCollection first"))
+		return
+	}
+
+	// The query fails when requesting more results than there are documents,
+	// which is inconvenient in a UI, so we cap the value.
+	nResults := req.NResults
+	if nResults <= 0 {
+		nResults = 10
+	}
+	nResults = limitResults(r.Context(), nResults)
+	if count := c.Count(); nResults > count {
+		nResults = count
+	}
+	if nResults == 0 {
+		writeJSON(w, http.StatusOK, []adminResult{})
+		return
+	}
+
+	results, err := c.Query(r.Context(), req.Query, nResults, req.Where, req.WhereDocument)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	res := make([]adminResult, 0, len(results))
+	for _, result := range results {
+		res = append(res, adminResult{
+			ID:         result.ID,
+			Metadata:   result.Metadata,
+			Content:    result.Content,
+			Similarity: result.Similarity,
+		})
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// collection returns the collection with the given name, or writes a 404 or
+// 403 error response and returns false.
+// All operations of the admin API only read, so they need read permission.
+func (h *adminHandler) collection(w http.ResponseWriter, r *http.Request, name string) (*Collection, bool) {
+	h.db.collectionsLock.RLock()
+	c, ok := h.db.collections[name]
+	h.db.collectionsLock.RUnlock()
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("%w: %q", ErrCollectionNotFound, name))
+		return nil, false
+	}
+	if !authorized(r.Context(), name, PermissionRead) {
+		writeJSONError(w, http.StatusForbidden, fmt.Errorf("no read permission for collection %q", name))
+		return nil, false
+	}
+	return c, true
+}
+
+// adminInfo returns the collection's name, metadata and stats for the admin API.
+func (c *Collection) adminInfo() adminCollection {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	info := adminCollection{
+		Name:     c.Name,
+		Metadata: c.getMetadata(),
+		Count:    len(c.documents),
+	}
+	// All documents have the same dimensions, so we only need to check one.
+	for _, doc := range c.documents {
+		info.Dimensions = len(doc.Embedding)
+		break
+	}
+	return info
+}
+
+// splitEscapedPath splits an escaped URL path into its unescaped segments.
+// Leading and trailing slashes are ignored.
+func splitEscapedPath(escapedPath string) ([]string, error) {
+	escapedPath = strings.Trim(escapedPath, "/")
+	if escapedPath == "" {
+		return nil, nil
+	}
+	segments := strings.Split(escapedPath, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't unescape path segment %q: %w", segment, err)
+		}
+		segments[i] = unescaped
+	}
+	return segments, nil
+}
+
+// queryParamInt returns the integer value of the given query parameter, or the
+// default value if the parameter isn't set.
+func queryParamInt(values url.Values, key string, defaultValue int) (int, error) {
+	s := values.Get(key)
+	if s == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// writeJSON writes the given value as JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError writes the given error as JSON response with the given status code.
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// adminPage is the single page of the admin UI. It only uses the JSON API, and
+// sets all user-provided values via textContent to avoid HTML injection.
+const adminPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>chromem-go admin</title>
+<style>
+body { font-family: sans-serif; margin: 0; display: flex; height: 100vh; }
+nav { width: 16em; border-right: 1px solid #ccc; padding: 1em; overflow-y: auto; }
+main { flex: 1; padding: 1em; overflow-y: auto; }
+nav a { display: block; cursor: pointer; padding: 0.2em 0; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border: 1px solid #ddd; padding: 0.3em; text-align: left; vertical-align: top; }
+pre { white-space: pre-wrap; margin: 0; }
+.muted { color: #777; }
+</style>
+</head>
+<body>
+<nav><h3>Collections</h3><div id="collections"></div></nav>
+<main>
+<h2 id="title">Select a collection</h2>
+<p id="stats" class="muted"></p>
+<form id="query" hidden>
+<input id="q" placeholder="Query text" size="50">
+<input id="n" type="number" value="5" min="1" style="width: 4em">
+<button>Query</button>
+</form>
+<div id="content"></div>
+<p><button id="prev" hidden>Previous</button> <button id="next" hidden>Next</button></p>
+</main>
+<script>
+const limit = 50;
+let current = null, offset = 0;
+const el = (tag, text) => { const e = document.createElement(tag); if (text !== undefined) e.textContent = text; return e; };
+const api = async (path, opts) => {
+  const res = await fetch("api/collections" + path, opts);
+  const body = await res.json();
+  if (!res.ok) throw new Error(body.error);
+  return body;
+};
+const table = (headers, rows) => {
+  const t = el("table"), h = el("tr");
+  headers.forEach(x => h.appendChild(el("th", x)));
+  t.appendChild(h);
+  rows.forEach(r => { const tr = el("tr"); r.forEach(x => { const td = el("td"); td.appendChild(el("pre", x)); tr.appendChild(td); }); t.appendChild(tr); });
+  return t;
+};
+const show = node => { const c = document.getElementById("content"); c.replaceChildren(node); };
+const showError = err => show(el("p", "Error: " + err.message));
+async function loadCollections() {
+  const list = document.getElementById("collections");
+  list.replaceChildren();
+  (await api("")).forEach(c => {
+    const a = el("a", c.name + " (" + c.count + ")");
+    a.onclick = () => { current = c.name; offset = 0; loadCollection(); };
+    list.appendChild(a);
+  });
+}
+async function loadCollection() {
+  const path = "/" + encodeURIComponent(current);
+  const info = await api(path);
+  document.getElementById("title").textContent = info.name;
+  document.getElementById("stats").textContent = info.count + " documents, " + info.dimensions + " dimensions, metadata: " + JSON.stringify(info.metadata);
+  document.getElementById("query").hidden = false;
+  const page = await api(path + "/documents?offset=" + offset + "&limit=" + limit);
+  show(table(["ID", "Metadata", "Content"], page.documents.map(d => [d.id, JSON.stringify(d.metadata), d.content])));
+  document.getElementById("prev").hidden = offset === 0;
+  document.getElementById("next").hidden = offset + limit >= page.total;
+}
+document.getElementById("prev").onclick = () => { offset = Math.max(0, offset - limit); loadCollection().catch(showError); };
+document.getElementById("next").onclick = () => { offset += limit; loadCollection().catch(showError); };
+document.getElementById("query").onsubmit = async e => {
+  e.preventDefault();
+  try {
+    const body = JSON.stringify({ query: document.getElementById("q").value, nResults: Number(document.getElementById("n").value) });
+    const results = await api("/" + encodeURIComponent(current) + "/query", { method: "POST", headers: { "Content-Type": "application/json" }, body });
+    show(table(["ID", "Similarity", "Metadata", "Content"], results.map(r => [r.id, r.similarity.toFixed(4), JSON.stringify(r.metadata), r.content])));
+    document.getElementById("prev").hidden = true;
+    document.getElementById("next").hidden = true;
+  } catch (err) { showError(err); }
+};
+loadCollections().catch(showError);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,152 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	ctx := context.Background()
+	vectors := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return vectors, nil
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("test/col", map[string]string{"foo": "bar"}, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Add(ctx, []string{"1", "2", "3"}, nil, []map[string]string{{"a": "1"}, {"a": "2"}, {"a": "3"}}, []string{"hello world", "hallo welt", "bonjour"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	ts := httptest.NewServer(AdminHandler(db))
+	defer ts.Close()
+	colPath := "/api/collections/" + url.PathEscape("test/col")
+
+	t.Run("UI", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatal("expected status 200, got", res.StatusCode)
+		}
+		if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+			t.Fatal("expected HTML, got", res.Header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("List collections", func(t *testing.T) {
+		var got []adminCollection
+		getJSON(t, ts.URL+"/api/collections", http.StatusOK, &got)
+		if len(got) != 1 {
+			t.Fatal("expected 1 collection, got", len(got))
+		}
+		if got[0].Name != "test/col" || got[0].Count != 3 || got[0].Dimensions != 3 {
+			t.Fatalf("unexpected collection info: %+v", got[0])
+		}
+	})
+
+	t.Run("List documents", func(t *testing.T) {
+		var got struct {
+			Total     int
+			Documents []adminDocument
+		}
+		getJSON(t, ts.URL+colPath+"/documents?offset=1&limit=1", http.StatusOK, &got)
+		if got.Total != 3 {
+			t.Fatal("expected total 3, got", got.Total)
+		}
+		if len(got.Documents) != 1 || got.Documents[0].ID != "2" {
+			t.Fatalf("expected document 2, got %+v", got.Documents)
+		}
+		if got.Documents[0].Embedding != nil {
+			t.Fatal("expected no embedding in listing, got", got.Documents[0].Embedding)
+		}
+	})
+
+	t.Run("Get document", func(t *testing.T) {
+		var got adminDocument
+		getJSON(t, ts.URL+colPath+"/documents/3", http.StatusOK, &got)
+		if got.Content != "bonjour" || len(got.Embedding) != 3 {
+			t.Fatalf("unexpected document: %+v", got)
+		}
+		getJSON(t, ts.URL+colPath+"/documents/4", http.StatusNotFound, nil)
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		body, _ := json.Marshal(adminQueryRequest{Query: "hello", NResults: 10, Where: map[string]string{"a": "1"}})
+		res, err := http.Post(ts.URL+colPath+"/query", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatal("expected status 200, got", res.StatusCode)
+		}
+		var got []adminResult
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(got) != 1 || got[0].ID != "1" {
+			t.Fatalf("expected result 1, got %+v", got)
+		}
+	})
+
+	t.Run("Query with negation", func(t *testing.T) {
+		body := `{"query": "hello", "nResults": 10, "where": {"$not": {"a": "1"}}}`
+		res, err := http.Post(ts.URL+colPath+"/query", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatal("expected status 200, got", res.StatusCode)
+		}
+		var got []adminResult
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		for _, r := range got {
+			if r.ID == "1" {
+				t.Fatalf("expected document 1 to be excluded, got %+v", got)
+			}
+		}
+		if len(got) == 0 {
+			t.Fatal("expected results, got none")
+		}
+	})
+
+	t.Run("Unknown collection", func(t *testing.T) {
+		getJSON(t, ts.URL+"/api/collections/unknown", http.StatusNotFound, nil)
+	})
+}
+
+// getJSON sends a GET request, checks the status code and decodes the JSON
+// response body into v if it's not nil.
+func getJSON(t *testing.T, url string, wantStatus int, v any) {
+	t.Helper()
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != wantStatus {
+		t.Fatal("expected status", wantStatus, "got", res.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// aliasesFileName is the name of the file in the DB's directory in which the
+// aliases are persisted.
+const aliasesFileName = "aliases"
+
+// SetAlias points the alias to the collection, so that [DB.GetCollection]
+// returns the collection for the alias. If the alias exists already, it's
+// switched to the collection atomically, i.e. every call of
+// [DB.GetCollection] returns either the old or the new collection.
+//
+// This allows blue/green reindexing: applications use the alias, while a new
+// version of the collection is built, for example with a different embedding
+// model. Then the alias is switched to the new version, and the old version
+// can be deleted.
+//
+// The alias can't have the name of a collection, and the collection must exist
+// and can't be an alias itself. Aliases are persisted and part of exports.
+// Deleting a collection deletes its aliases.
+func (db *DB) SetAlias(alias, collection string) error {
+	if alias == "" {
+		return errors.New("alias is empty")
+	}
+	if collection == "" {
+		return errors.New("collection name is empty")
+	}
+
+	db.collectionsLock.Lock()
+	defer db.collectionsLock.Unlock()
+
+	if _, ok := db.collections[alias]; ok {
+		return fmt.Errorf("alias %q is the name of a collection", alias)
+	}
+	if _, ok := db.collections[collection]; !ok {
+		return fmt.Errorf("%w: %q", ErrCollectionNotFound, collection)
+	}
+
+	prev, existed := db.aliases[alias]
+	if db.aliases == nil {
+		db.aliases = make(map[string]string)
+	}
+	db.aliases[alias] = collection
+	err := db.persistAliasesLocked()
+	if err != nil {
+		if existed {
+			db.aliases[alias] = prev
+		} else {
+			delete(db.aliases, alias)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteAlias deletes the alias. The collection isn't affected. If the alias
+// doesn't exist, this is a no-op.
+func (db *DB) DeleteAlias(alias string) error {
+	db.collectionsLock.Lock()
+	defer db.collectionsLock.Unlock()
+
+	collection, ok := db.aliases[alias]
+	if !ok {
+		return nil
+	}
+	delete(db.aliases, alias)
+	err := db.persistAliasesLocked()
+	if err != nil {
+		db.aliases[alias] = collection
+		return err
+	}
+	return nil
+}
+
+// Aliases returns all aliases of the DB, mapping alias->collection name.
+// The returned map is a copy, so it's safe to modify it.
+func (db *DB) Aliases() map[string]string {
+	db.collectionsLock.RLock()
+	defer db.collectionsLock.RUnlock()
+
+	res := make(map[string]string, len(db.aliases))
+	for alias, collection := range db.aliases {
+		res[alias] = collection
+	}
+	return res
+}
+
+// deleteCollectionAliasesLocked deletes the aliases that point to the
+// collection. The caller must hold the collections lock.
+func (db *DB) deleteCollectionAliasesLocked(collection string) error {
+	changed := false
+	for alias, c := range db.aliases {
+		if c == collection {
+			delete(db.aliases, alias)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return db.persistAliasesLocked()
+}
+
+// persistAliasesLocked writes the aliases to the DB's directory, if the DB is
+// persistent. The caller must hold the collections lock.
+func (db *DB) persistAliasesLocked() error {
+	if db.persistDirectory == "" {
+		return nil
+	}
+	filePath := db.aliasesPath()
+	if len(db.aliases) == 0 {
+		err := os.Remove(filePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("couldn't remove aliases file: %w", err)
+		}
+		return nil
+	}
+	err := persistToFileSynced(filePath, db.aliases, db.compress, "", db.syncer)
+	if err != nil {
+		return fmt.Errorf("couldn't persist aliases: %w", err)
+	}
+	return nil
+}
+
+// aliasesPath returns the path of the aliases file of a persistent DB.
+func (db *DB) aliasesPath() string {
+	filePath := filepath.Join(db.persistDirectory, aliasesFileName) + ".gob"
+	if db.compress {
+		filePath += ".gz"
+	}
+	return filePath
+}
@@ -0,0 +1,126 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestDB_SetAlias(t *testing.T) {
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{-0.1, 0.1, 0.2}, nil
+	}
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	v1, err := db.CreateCollection("docs-v1", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	v2, err := db.CreateCollection("docs-v2", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Invalid aliases
+	if err := db.SetAlias("", "docs-v1"); err == nil {
+		t.Fatal("expected error for empty alias, got nil")
+	}
+	if err := db.SetAlias("docs-v2", "docs-v1"); err == nil {
+		t.Fatal("expected error for alias with collection name, got nil")
+	}
+	if err := db.SetAlias("docs", "docs-v3"); err == nil {
+		t.Fatal("expected error for non-existing collection, got nil")
+	}
+
+	// Set and switch
+	err = db.SetAlias("docs", "docs-v1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c := db.GetCollection("docs", nil); c != v1 {
+		t.Fatal("expected alias to resolve to docs-v1")
+	}
+	err = db.SetAlias("docs", "docs-v2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c := db.GetCollection("docs", nil); c != v2 {
+		t.Fatal("expected alias to resolve to docs-v2")
+	}
+	if _, err := db.CreateCollection("docs", nil, embeddingFunc); err == nil {
+		t.Fatal("expected error for collection with alias name, got nil")
+	}
+	if c, err := db.GetOrCreateCollection("docs", nil, embeddingFunc); err != nil || c != v2 {
+		t.Fatal("expected GetOrCreateCollection to resolve alias, got", err)
+	}
+
+	// Persistence
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if aliases := db2.Aliases(); len(aliases) != 1 || aliases["docs"] != "docs-v2" {
+		t.Fatal("expected persisted alias, got", aliases)
+	}
+
+	// Export and import
+	buf := bytes.Buffer{}
+	err = db.ExportToWriter(&buf, false, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	db3 := NewDB()
+	err = db3.ImportFromReader(&buf, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c := db3.GetCollection("docs", nil); c == nil || c.Name != "docs-v2" {
+		t.Fatal("expected imported alias to resolve to docs-v2")
+	}
+
+	// Deleting the collection deletes its aliases
+	err = db.DeleteCollection("docs-v2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c := db.GetCollection("docs", nil); c != nil {
+		t.Fatal("expected alias to be deleted with collection")
+	}
+	db2, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if aliases := db2.Aliases(); len(aliases) != 0 {
+		t.Fatal("expected no persisted aliases, got", aliases)
+	}
+}
+
+func TestDB_DeleteAlias(t *testing.T) {
+	db := NewDB()
+	_, err := db.CreateCollection("docs-v1", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.SetAlias("docs", "docs-v1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.DeleteAlias("docs")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c := db.GetCollection("docs", nil); c != nil {
+		t.Fatal("expected alias to be deleted")
+	}
+	if c := db.GetCollection("docs-v1", nil); c == nil {
+		t.Fatal("expected collection to still exist")
+	}
+	// No-op
+	err = db.DeleteAlias("docs")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
@@ -0,0 +1,531 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+const (
+	// archiveDirName is the name of the directory in a collection's
+	// persistence directory that contains the archive, see [Collection.Archive].
+	archiveDirName = "archive"
+	// archiveCatalogFileName is the name of the file in the archive directory
+	// that maps the IDs of the archived documents to their segments.
+	archiveCatalogFileName = "catalog"
+	archiveSegmentExt      = ".archive"
+)
+
+// archive is the catalog of a collection's archived documents. It's guarded by
+// the collection's documentsLock.
+type archive struct {
+	// The number of the segment that contains each archived document.
+	Segments map[string]int
+	// The number of the next segment.
+	Next int
+}
+
+// Archive moves the documents with the given IDs out of the collection's
+// documents in memory into a compressed segment on disk, for example documents
+// that are rarely retrieved (see [Collection.ColdDocuments]). This reduces the
+// memory usage and speeds up queries. Archived documents are only scanned by
+// queries with [QueryOptions.IncludeArchived], which reads the archive from
+// disk, and they can be moved back with [Collection.Unarchive].
+//
+// Deleting archived documents, by ID or by filter, removes them from the
+// archive, see [Collection.Delete]. They can't be updated, and adding a
+// document with the ID of an archived one shadows it. They're included in
+// exports (see [DB.ExportToWriter]), unless they're shadowed, and [DB.Merge]
+// archives them again in persistent collections. Imports and merges into
+// in-memory collections restore them as regular documents, as imported
+// documents aren't persisted. The collection must be persistent.
+func (c *Collection) Archive(ctx context.Context, ids ...string) error {
+	if err := c.db.checkOpen(); err != nil {
+		return err
+	}
+	if c.persistDirectory == "" {
+		return errors.New("archiving requires a persistent collection")
+	}
+	if len(ids) == 0 {
+		return errors.New("ids are empty")
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	docs := make([]*Document, 0, len(ids))
+	for _, id := range ids {
+		doc, ok := c.documents[id]
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrDocumentNotFound, id)
+		}
+		doc, err := c.withContentLocked(doc)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+
+	err := c.archiveDocumentsLocked(docs)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		err := c.removeArchivedFromMemoryLocked(doc.ID)
+		if err != nil {
+			return err
+		}
+	}
+	c.changed()
+
+	return c.audit(ctx, AuditActionArchive, ids...)
+}
+
+// archiveDocumentsLocked writes the documents into a new segment of the
+// archive, and points the archive's catalog to it. The caller must hold the
+// documents lock for writing.
+func (c *Collection) archiveDocumentsLocked(docs []*Document) error {
+	a := c.archiveLocked()
+	segment := a.Next
+	err := persistToFileSynced(c.archiveSegmentPath(segment), docs, true, "", c.syncer)
+	if err != nil {
+		return fmt.Errorf("couldn't persist archive segment: %w", err)
+	}
+	a.Next++
+	for _, doc := range docs {
+		a.Segments[doc.ID] = segment
+	}
+	return c.persistArchiveLocked()
+}
+
+// removeArchivedFromMemoryLocked removes the document, which was archived,
+// from the documents in memory and on disk. The caller must hold the documents
+// lock for writing.
+func (c *Collection) removeArchivedFromMemoryLocked(id string) error {
+	err := c.removePersistedDocument(id)
+	if err != nil {
+		return err
+	}
+	c.memoryUsage.Add(-documentMemoryUsage(c.documents[id]).Total())
+	delete(c.documents, id)
+	c.unindexDocumentLocked(id)
+	if c.contentCache != nil {
+		c.contentCache.remove(id)
+	}
+	return nil
+}
+
+// mergeArchived archives the archived documents of a merged collection, and
+// handles the ones that exist already, in memory or in the archive, according
+// to the conflict policy, see [DB.Merge]. It returns the IDs of the added and
+// overwritten documents, and the number of skipped ones.
+func (c *Collection) mergeArchived(ctx context.Context, docs map[string]*Document, opts MergeOptions) (added, overwritten []string, skipped int, err error) {
+	ids := make([]string, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	c.documentsLock.Lock()
+	exists := func(id string) bool {
+		if _, ok := c.documents[id]; ok {
+			return true
+		}
+		if c.archive == nil {
+			return false
+		}
+		_, ok := c.archive.Segments[id]
+		return ok
+	}
+	if opts.OnConflict == MergeConflictError {
+		for _, id := range ids {
+			if exists(id) {
+				c.documentsLock.Unlock()
+				return nil, nil, 0, fmt.Errorf("%w: archived document %q exists in collection %q already", ErrMergeConflict, id, c.Name)
+			}
+		}
+	}
+	var archived []*Document
+	for _, id := range ids {
+		doc := docs[id]
+		if exists(id) {
+			if opts.OnConflict != MergeConflictOverwrite {
+				skipped++
+				continue
+			}
+			overwritten = append(overwritten, id)
+		} else {
+			added = append(added, id)
+		}
+		if dim := c.dimensionLocked(""); dim != 0 && dim != len(doc.Embedding) {
+			err = &DimensionMismatchError{DocumentID: id, Expected: dim, Actual: len(doc.Embedding)}
+			break
+		}
+		archived = append(archived, doc)
+	}
+	if err == nil && len(archived) != 0 {
+		err = c.archiveDocumentsLocked(archived)
+	}
+	// Overwritten documents in memory are removed after the merged ones are
+	// archived, so they're never lost.
+	removed := false
+	for _, id := range overwritten {
+		if _, ok := c.documents[id]; !ok || err != nil {
+			continue
+		}
+		err = c.removeArchivedFromMemoryLocked(id)
+		removed = true
+	}
+	c.documentsLock.Unlock()
+	if removed {
+		c.changed()
+	}
+	if err != nil {
+		return nil, nil, skipped, err
+	}
+
+	return added, overwritten, skipped, c.audit(ctx, AuditActionArchive, append(slices.Clip(added), overwritten...)...)
+}
+
+// Unarchive moves the archived documents with the given IDs back into the
+// collection's documents in memory, see [Collection.Archive].
+func (c *Collection) Unarchive(ctx context.Context, ids ...string) error {
+	if err := c.db.checkOpen(); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return errors.New("ids are empty")
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	a := c.archiveLocked()
+	bySegment := make(map[int][]string)
+	for _, id := range ids {
+		segment, ok := a.Segments[id]
+		if !ok {
+			return fmt.Errorf("%w in the archive: %q", ErrDocumentNotFound, id)
+		}
+		if _, ok := c.documents[id]; ok {
+			return fmt.Errorf("document %q exists already", id)
+		}
+		bySegment[segment] = append(bySegment[segment], id)
+	}
+
+	// The documents are added before they're removed from the archive, so
+	// they're never lost. Until they're removed, they're shadowed.
+	restored, remaining, err := c.splitArchiveLocked(bySegment)
+	if err != nil {
+		return err
+	}
+	for _, doc := range restored {
+		if dim := c.dimensionLocked(""); dim != 0 && dim != len(doc.Embedding) {
+			return &DimensionMismatchError{DocumentID: doc.ID, Expected: dim, Actual: len(doc.Embedding)}
+		}
+		err := c.persistDocument(doc)
+		if err != nil {
+			return err
+		}
+		stored := doc
+		if c.contentCache != nil {
+			withoutContent := *doc
+			withoutContent.Content = ""
+			stored = &withoutContent
+		}
+		c.documents[doc.ID] = stored
+		c.indexDocumentLocked(stored)
+		c.memoryUsage.Add(documentMemoryUsage(stored).Total())
+	}
+	c.added(ctx)
+
+	err = c.removeArchivedLocked(ids, remaining)
+	if err != nil {
+		return err
+	}
+
+	return c.audit(ctx, AuditActionUnarchive, ids...)
+}
+
+// deleteArchivedLocked removes the archived documents with the given IDs and
+// the ones that match any of the filters from the archive, and moves them to
+// the trash with soft deletes. It returns the IDs of the removed documents.
+// Archived documents that are shadowed by documents in memory only match by
+// ID. The caller must hold the documents lock for writing.
+func (c *Collection) deleteArchivedLocked(ids []string, filters ...*Filter) ([]string, error) {
+	if c.archive == nil || len(c.archive.Segments) == 0 {
+		return nil, nil
+	}
+
+	var deleted []string
+	bySegment := make(map[int][]string)
+	add := func(id string) {
+		segment, ok := c.archive.Segments[id]
+		if !ok || slices.Contains(bySegment[segment], id) {
+			return
+		}
+		bySegment[segment] = append(bySegment[segment], id)
+		deleted = append(deleted, id)
+	}
+	for _, id := range ids {
+		add(id)
+	}
+	if len(filters) != 0 {
+		docs, err := c.archivedDocumentsLocked()
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			for _, filter := range filters {
+				if filter.matches(doc) {
+					add(doc.ID)
+					break
+				}
+			}
+		}
+	}
+	if len(deleted) == 0 {
+		return nil, nil
+	}
+
+	removed, remaining, err := c.splitArchiveLocked(bySegment)
+	if err != nil {
+		return nil, err
+	}
+	if c.getConfig().SoftDeletePurgeAfter != 0 {
+		for _, doc := range removed {
+			err := c.trashLocked(doc)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't move document %q to trash: %w", doc.ID, err)
+			}
+		}
+	}
+	err = c.removeArchivedLocked(deleted, remaining)
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// appendMissing appends the IDs to ids that it doesn't contain yet.
+func appendMissing(ids, add []string) []string {
+	if len(add) == 0 {
+		return ids
+	}
+	existing := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		existing[id] = struct{}{}
+	}
+	for _, id := range add {
+		if _, ok := existing[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// splitArchiveLocked reads the segments with the given IDs of archived
+// documents, and returns those documents and the other documents by segment.
+// The caller must hold the documents lock.
+func (c *Collection) splitArchiveLocked(bySegment map[int][]string) ([]*Document, map[int][]*Document, error) {
+	var selected []*Document
+	remaining := make(map[int][]*Document, len(bySegment))
+	for segment, segmentIDs := range bySegment {
+		docs, err := c.readArchiveSegment(segment)
+		if err != nil {
+			return nil, nil, err
+		}
+		remaining[segment] = nil
+		for _, doc := range docs {
+			if slices.Contains(segmentIDs, doc.ID) {
+				selected = append(selected, doc)
+			} else if c.archive.Segments[doc.ID] == segment {
+				remaining[segment] = append(remaining[segment], doc)
+			}
+		}
+	}
+	return selected, remaining, nil
+}
+
+// removeArchivedLocked removes the documents with the given IDs from the
+// archive's catalog, and rewrites their segments with the remaining documents,
+// see [Collection.splitArchiveLocked]. Segments without remaining documents
+// are removed. The caller must hold the documents lock for writing.
+func (c *Collection) removeArchivedLocked(ids []string, remaining map[int][]*Document) error {
+	for _, id := range ids {
+		delete(c.archive.Segments, id)
+	}
+	err := c.persistArchiveLocked()
+	if err != nil {
+		return err
+	}
+	for segment, docs := range remaining {
+		path := c.archiveSegmentPath(segment)
+		if len(docs) != 0 {
+			err = persistToFileSynced(path+".tmp", docs, true, "", c.syncer)
+			if err == nil {
+				err = os.Rename(path+".tmp", path)
+			}
+		} else {
+			err = removeFile(path)
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't rewrite archive segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// Archived returns the IDs of the archived documents, sorted, see
+// [Collection.Archive].
+func (c *Collection) Archived() []string {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	if c.archive == nil {
+		return nil
+	}
+	res := make([]string, 0, len(c.archive.Segments))
+	for id := range c.archive.Segments {
+		res = append(res, id)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// archiveLocked returns the collection's archive, which is created if there's
+// none yet. The caller must hold the documents lock for writing.
+func (c *Collection) archiveLocked() *archive {
+	if c.archive == nil {
+		c.archive = &archive{Segments: make(map[string]int), Next: 1}
+	}
+	return c.archive
+}
+
+func (c *Collection) archiveSegmentPath(segment int) string {
+	return filepath.Join(c.persistDirectory, archiveDirName, strconv.Itoa(segment)+archiveSegmentExt)
+}
+
+// persistArchiveLocked writes the archive's catalog. The caller must hold the
+// documents lock for writing.
+func (c *Collection) persistArchiveLocked() error {
+	catalogPath := filepath.Join(c.persistDirectory, archiveDirName, archiveCatalogFileName)
+	tmpPath := catalogPath + ".tmp"
+	err := persistToFileSynced(tmpPath, c.archive, c.compress, "", c.syncer)
+	if err != nil {
+		return fmt.Errorf("couldn't persist archive catalog: %w", err)
+	}
+	err = os.Rename(tmpPath, catalogPath)
+	if err != nil {
+		return fmt.Errorf("couldn't rename archive catalog file: %w", err)
+	}
+	return c.syncer.syncDir(catalogPath)
+}
+
+// loadArchive reads the archive's catalog, if there is one. It's called when
+// the collection is loaded, before the DB is used.
+func (c *Collection) loadArchive() error {
+	catalogPath := filepath.Join(c.persistDirectory, archiveDirName, archiveCatalogFileName)
+	if _, err := os.Stat(catalogPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	a := &archive{}
+	err := readFromFile(catalogPath, a, "")
+	if err != nil {
+		return fmt.Errorf("couldn't read archive catalog: %w", err)
+	}
+	if a.Segments == nil {
+		a.Segments = make(map[string]int)
+	}
+	c.archive = a
+	return nil
+}
+
+func (c *Collection) readArchiveSegment(segment int) ([]*Document, error) {
+	var docs []*Document
+	err := readFromFile(c.archiveSegmentPath(segment), &docs, "")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read archive segment: %w", err)
+	}
+	return docs, nil
+}
+
+// archivedDocumentsLocked reads the archived documents that aren't shadowed by
+// documents in memory. The caller must hold the documents lock.
+func (c *Collection) archivedDocumentsLocked() (map[string]*Document, error) {
+	res := make(map[string]*Document)
+	if c.archive == nil {
+		return res, nil
+	}
+	segments := make(map[int]struct{})
+	for _, segment := range c.archive.Segments {
+		segments[segment] = struct{}{}
+	}
+	for segment := range segments {
+		docs, err := c.readArchiveSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			if _, ok := c.documents[doc.ID]; ok || c.archive.Segments[doc.ID] != segment {
+				continue
+			}
+			res[doc.ID] = doc
+		}
+	}
+	return res, nil
+}
+
+// queryArchived queries the archived documents like the documents in memory
+// with the query function, and merges the results with the results of the
+// documents in memory by similarity.
+func (c *Collection) queryArchived(nResults int, results []Result, stats QueryStats, query func(archived *Collection, nResults int) ([]Result, QueryStats, error)) ([]Result, QueryStats, error) {
+	// The documents are locked while the archive is read, so documents can't
+	// be archived or unarchived concurrently and be missed.
+	c.documentsLock.RLock()
+	docs, err := c.archivedDocumentsLocked()
+	c.documentsLock.RUnlock()
+	if err != nil {
+		return nil, QueryStats{}, err
+	}
+	if len(docs) == 0 {
+		return results, stats, nil
+	}
+
+	// A read-only collection of the archived documents, like a snapshot.
+	c.configLock.RLock()
+	archived := &Collection{
+		Name:         c.Name,
+		metadata:     c.metadata,
+		documents:    docs,
+		config:       c.config,
+		feedback:     c.feedback,
+		variantStats: c.variantStats,
+		accessStats:  c.accessStats,
+	}
+	c.configLock.RUnlock()
+
+	archivedResults, archivedStats, err := query(archived, min(nResults, len(docs)))
+	if err != nil {
+		return nil, QueryStats{}, fmt.Errorf("couldn't query archive: %w", err)
+	}
+	stats.DocumentsScanned += archivedStats.DocumentsScanned
+	stats.Truncated = stats.Truncated || archivedStats.Truncated
+
+	// Like the results of a single query, results with the same similarity
+	// are ordered by ID.
+	res := append(slices.Clone(results), archivedResults...)
+	slices.SortFunc(res, func(a, b Result) int {
+		return compareDocSims(docSim{docID: a.ID, similarity: a.Similarity}, docSim{docID: b.ID, similarity: b.Similarity})
+	})
+	res = res[:min(nResults, len(res))]
+	for i := range res {
+		res[i].Rank = i + 1
+	}
+	return res, stats, nil
+}
@@ -0,0 +1,300 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCollection_Archive(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Content: "foo"},
+		{ID: "2", Embedding: []float32{0.8, 0.6}, Content: "bar", Metadata: map[string]string{"a": "b"}},
+		{ID: "3", Embedding: []float32{0, 1}, Content: "baz"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.Archive(ctx, "2", "3")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != 1 {
+		t.Fatal("expected 1 document in memory, got", c.Count())
+	}
+	if archived := c.Archived(); len(archived) != 2 || archived[0] != "2" || archived[1] != "3" {
+		t.Fatal("expected archived documents 2 and 3, got", archived)
+	}
+	if _, err := os.Stat(filepath.Join(c.persistDirectory, archiveDirName, "1"+archiveSegmentExt)); err != nil {
+		t.Fatal("expected archive segment, got", err)
+	}
+	err = c.Archive(ctx, "2")
+	if !errors.Is(err, ErrDocumentNotFound) {
+		t.Fatal("expected ErrDocumentNotFound, got", err)
+	}
+
+	// Archived documents are only queried on request.
+	res, err := c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{0, 1}, NResults: 1})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" {
+		t.Fatal("expected document 1, got", res)
+	}
+	res, err = c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{0, 1}, NResults: 2, IncludeArchived: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "3" || res[0].Content != "baz" || res[0].Rank != 1 || res[1].ID != "2" {
+		t.Fatal("expected archived documents 3 and 2, got", res)
+	}
+	res, err = c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{0, 1}, NResults: 3, Where: map[string]string{"a": "b"}, IncludeArchived: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "2" {
+		t.Fatal("expected filtered archived document 2, got", res)
+	}
+
+	// The archive is persisted.
+	db, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	if c.Count() != 1 || len(c.Archived()) != 2 {
+		t.Fatal("expected 1 document in memory and 2 archived ones, got", c.Count(), len(c.Archived()))
+	}
+
+	err = c.Unarchive(ctx, "3")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != 2 || c.documents["3"].Content != "baz" {
+		t.Fatal("expected unarchived document in memory")
+	}
+	if archived := c.Archived(); len(archived) != 1 || archived[0] != "2" {
+		t.Fatal("expected archived document 2, got", archived)
+	}
+	err = c.Unarchive(ctx, "3")
+	if !errors.Is(err, ErrDocumentNotFound) {
+		t.Fatal("expected ErrDocumentNotFound, got", err)
+	}
+	err = c.Unarchive(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, err := os.Stat(filepath.Join(c.persistDirectory, archiveDirName, "1"+archiveSegmentExt)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("expected empty archive segment to be removed, got", err)
+	}
+
+	// In-memory collections can't be archived.
+	c, err = NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Archive(ctx, "1")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_Archive_Export(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewPersistentDB(t.TempDir(), false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Content: "foo"},
+		{ID: "2", Embedding: []float32{0.8, 0.6}, Content: "bar"},
+		{ID: "3", Embedding: []float32{0, 1}, Content: "baz"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Archive(ctx, "2", "3")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Results with the same similarity are ordered by ID, also across the
+	// archive.
+	err = c.AddDocument(ctx, Document{ID: "4", Embedding: []float32{0.8, 0.6}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err := c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{0.8, 0.6}, NResults: 2, IncludeArchived: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "2" || res[1].ID != "4" {
+		t.Fatal("expected documents 2 and 4, got", res)
+	}
+	err = c.Delete(ctx, nil, nil, "4")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Archived documents are exported, and imported as documents.
+	buf := bytes.Buffer{}
+	err = db.ExportToWriter(&buf, false, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	imported := NewDB()
+	err = imported.ImportFromReader(bytes.NewReader(buf.Bytes()), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if got := imported.GetCollection("test", nil); got.Count() != 3 || got.documents["3"].Content != "baz" {
+		t.Fatal("expected 3 imported documents, got", got.Count())
+	}
+
+	// Merges archive them again in persistent collections.
+	merged, err := NewPersistentDB(t.TempDir(), false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	stats, err := merged.MergeFromReader(ctx, bytes.NewReader(buf.Bytes()), "", MergeOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if expected := (MergeStats{CollectionsCreated: 1, DocumentsAdded: 3}); stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+	mc := merged.GetCollection("test", nil)
+	if archived := mc.Archived(); mc.Count() != 1 || len(archived) != 2 || archived[0] != "2" || archived[1] != "3" {
+		t.Fatal("expected 1 document and archived documents 2 and 3, got", mc.Count(), archived)
+	}
+	_, err = merged.MergeFromReader(ctx, bytes.NewReader(buf.Bytes()), "", MergeOptions{OnConflict: MergeConflictError})
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatal("expected merge conflict, got", err)
+	}
+	_, _, _, err = mc.mergeArchived(ctx, map[string]*Document{"2": {ID: "2", Embedding: []float32{0.8, 0.6}}}, MergeOptions{OnConflict: MergeConflictError})
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatal("expected merge conflict, got", err)
+	}
+	stats, err = merged.MergeFromReader(ctx, bytes.NewReader(buf.Bytes()), "", MergeOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if expected := (MergeStats{DocumentsSkipped: 3}); stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+
+	// Overwriting a document in memory with an archived one archives it.
+	err = mc.Unarchive(ctx, "3")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	stats, err = merged.MergeFromReader(ctx, bytes.NewReader(buf.Bytes()), "", MergeOptions{OnConflict: MergeConflictOverwrite})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if expected := (MergeStats{DocumentsOverwritten: 3}); stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+	if archived := mc.Archived(); mc.Count() != 1 || len(archived) != 2 {
+		t.Fatal("expected 1 document and 2 archived ones, got", mc.Count(), archived)
+	}
+}
+
+func TestCollection_Archive_Delete(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Content: "foo"},
+		{ID: "2", Embedding: []float32{0.8, 0.6}, Content: "bar", Metadata: map[string]string{"a": "b"}},
+		{ID: "3", Embedding: []float32{0.6, 0.8}, Content: "baz"},
+		{ID: "4", Embedding: []float32{0, 1}, Content: "qux"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Archive(ctx, "2", "3", "4")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// By ID.
+	err = c.Delete(ctx, nil, nil, "3")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if archived := c.Archived(); len(archived) != 2 || archived[0] != "2" || archived[1] != "4" {
+		t.Fatal("expected archived documents 2 and 4, got", archived)
+	}
+	res, err := c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{0.6, 0.8}, NResults: 4, IncludeArchived: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, r := range res {
+		if r.ID == "3" {
+			t.Fatal("expected deleted archived document not to be returned, got", res)
+		}
+	}
+	err = c.Unarchive(ctx, "3")
+	if !errors.Is(err, ErrDocumentNotFound) {
+		t.Fatal("expected ErrDocumentNotFound, got", err)
+	}
+
+	// By filter.
+	err = c.Delete(ctx, map[string]string{"a": "b"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if archived := c.Archived(); len(archived) != 1 || archived[0] != "4" {
+		t.Fatal("expected archived document 4, got", archived)
+	}
+
+	// In a transaction.
+	err = c.Tx(ctx, func(tx *Txn) error {
+		return tx.Delete("4")
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if archived := c.Archived(); len(archived) != 0 {
+		t.Fatal("expected no archived documents, got", archived)
+	}
+	if _, err := os.Stat(filepath.Join(c.persistDirectory, archiveDirName, "1"+archiveSegmentExt)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("expected empty archive segment to be removed, got", err)
+	}
+
+	// The deletes are persisted.
+	db, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	if c.Count() != 1 || len(c.Archived()) != 0 {
+		t.Fatal("expected 1 document and no archived ones, got", c.Count(), len(c.Archived()))
+	}
+}
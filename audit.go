@@ -0,0 +1,320 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of the context that carries the principal,
+// i.e. the user or service on whose behalf the collection is changed. It's
+// recorded in the DB's audit log, see [DB.SetAuditLog].
+func ContextWithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the context, or an empty string
+// if there's none.
+func PrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// AuditAction is the kind of change recorded in an [AuditEntry].
+type AuditAction string
+
+// The audited actions. Updates are adds of documents whose ID existed already.
+const (
+	AuditActionAdd     AuditAction = "add"
+	AuditActionUpdate  AuditAction = "update"
+	AuditActionDelete  AuditAction = "delete"
+	AuditActionRestore AuditAction = "restore"
+	// See [Collection.Archive] and [Collection.Unarchive].
+	AuditActionArchive   AuditAction = "archive"
+	AuditActionUnarchive AuditAction = "unarchive"
+	// Documents that were evicted because of the DB's memory budget, see
+	// [DB.SetMemoryBudget].
+	AuditActionEvict AuditAction = "evict"
+	// Documents that were permanently deleted from the trash, see
+	// [Collection.PurgeTrash].
+	AuditActionPurge AuditAction = "purge"
+	// Documents that were deleted by the maintenance because they expired, see
+	// [MaintenanceOptions.ExpiresAtKey].
+	AuditActionExpire AuditAction = "expire"
+	// Collections that were deleted with all their documents, see
+	// [DB.DeleteCollectionWithContext] and [DB.ResetWithContext].
+	AuditActionDeleteCollection AuditAction = "delete_collection"
+	// Collections that were imported, overwriting existing ones, see
+	// [DB.ImportFromReaderWithContext].
+	AuditActionImport AuditAction = "import"
+)
+
+// AuditPrincipalSystem is the principal of the changes that chromem-go makes on
+// its own instead of on behalf of a user, like evictions, purges and expiries.
+const AuditPrincipalSystem = "system"
+
+// AuditEntry is an entry of the audit log.
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// The principal from the context, see [ContextWithPrincipal]. Empty if the
+	// context didn't carry one.
+	Principal   string      `json:"principal"`
+	Collection  string      `json:"collection"`
+	Action      AuditAction `json:"action"`
+	DocumentIDs []string    `json:"document_ids"`
+}
+
+// AuditFilter filters the entries of an audit log. Empty fields match all
+// entries.
+type AuditFilter struct {
+	Principal  string
+	Collection string
+	Action     AuditAction
+	// Matches entries that contain the document ID.
+	DocumentID string
+	// Matches entries at or after Since and before Until.
+	Since time.Time
+	Until time.Time
+}
+
+func (f AuditFilter) matches(entry AuditEntry) bool {
+	if f.Principal != "" && entry.Principal != f.Principal {
+		return false
+	}
+	if f.Collection != "" && entry.Collection != f.Collection {
+		return false
+	}
+	if f.Action != "" && entry.Action != f.Action {
+		return false
+	}
+	if f.DocumentID != "" && !slices.Contains(entry.DocumentIDs, f.DocumentID) {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// AuditLog is an append-only log of the changes to the documents of a DB.
+// Implementations must be safe for concurrent use.
+// chromem-go comes with [NewMemoryAuditLog] and [NewFileAuditLog], but you can
+// implement your own, for example to write to a database or a SIEM system.
+type AuditLog interface {
+	// Append adds the entry to the log.
+	Append(ctx context.Context, entry AuditEntry) error
+	// Entries returns the entries that match the filter, oldest first.
+	Entries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error)
+}
+
+// SetAuditLog sets the audit log of the DB. Adding, updating, deleting and
+// restoring documents is then recorded in it, including the principal from the
+// context (see [ContextWithPrincipal]). Documents that are evicted because of the
+// memory budget, purged from the trash or expired by the maintenance are
+// recorded with the [AuditPrincipalSystem] principal. Deleting and importing
+// whole collections is recorded with one entry per collection, which contains
+// the IDs of all its documents, including archived ones. The methods without
+// context, like [DB.DeleteCollection], record no principal.
+// If appending to the audit log fails, the change has already been made, and
+// the method that made it returns the error.
+// A nil audit log disables the auditing. The setting isn't persisted.
+func (db *DB) SetAuditLog(log AuditLog) {
+	db.auditLogLock.Lock()
+	defer db.auditLogLock.Unlock()
+
+	db.auditLog = log
+}
+
+// audit appends an entry to the audit log of the collection's DB, if there is
+// one.
+func (c *Collection) audit(ctx context.Context, action AuditAction, docIDs ...string) error {
+	if c.db == nil || len(docIDs) == 0 {
+		return nil
+	}
+	return c.db.audit(ctx, c.Name, action, docIDs)
+}
+
+// audit appends an entry to the audit log of the DB, if there is one. Unlike
+// [Collection.audit], it also appends entries without document IDs, for
+// example of deleted empty collections.
+func (db *DB) audit(ctx context.Context, collection string, action AuditAction, docIDs []string) error {
+	db.auditLogLock.RLock()
+	log := db.auditLog
+	db.auditLogLock.RUnlock()
+	if log == nil {
+		return nil
+	}
+
+	entry := AuditEntry{
+		Time:        time.Now(),
+		Principal:   PrincipalFromContext(ctx),
+		Collection:  collection,
+		Action:      action,
+		DocumentIDs: slices.Clone(docIDs),
+	}
+	err := log.Append(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("couldn't append to audit log: %w", err)
+	}
+	return nil
+}
+
+// auditedIDsLocked returns the sorted IDs of the collection's documents,
+// including archived ones, for the audit entries of the whole collection, or
+// nil if the DB has no audit log. The caller must hold the documents lock.
+func (c *Collection) auditedIDsLocked() []string {
+	if c.db == nil {
+		return nil
+	}
+	c.db.auditLogLock.RLock()
+	log := c.db.auditLog
+	c.db.auditLogLock.RUnlock()
+	if log == nil {
+		return nil
+	}
+
+	ids := make([]string, 0, len(c.documents))
+	for id := range c.documents {
+		ids = append(ids, id)
+	}
+	if c.archive != nil {
+		for id := range c.archive.Segments {
+			if _, ok := c.documents[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// auditSystem appends an entry of a change that chromem-go made on its own to
+// the audit log, with the [AuditPrincipalSystem] principal.
+func (c *Collection) auditSystem(ctx context.Context, action AuditAction, docIDs ...string) error {
+	return c.audit(ContextWithPrincipal(ctx, AuditPrincipalSystem), action, docIDs...)
+}
+
+// MemoryAuditLog is an [AuditLog] that keeps the entries in memory.
+type MemoryAuditLog struct {
+	entries []AuditEntry
+	lock    sync.RWMutex
+}
+
+// NewMemoryAuditLog creates a new in-memory audit log.
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{}
+}
+
+// Append implements [AuditLog].
+func (l *MemoryAuditLog) Append(_ context.Context, entry AuditEntry) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// Entries implements [AuditLog].
+func (l *MemoryAuditLog) Entries(_ context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	var res []AuditEntry
+	for _, entry := range l.entries {
+		if filter.matches(entry) {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+// FileAuditLog is an [AuditLog] that appends the entries to a file as JSON lines.
+type FileAuditLog struct {
+	path string
+	f    *os.File
+	lock sync.Mutex
+}
+
+// NewFileAuditLog opens or creates the audit log file at the given path.
+// Entries are appended to the file as JSON lines, one per entry, and are never
+// modified or removed. Call [FileAuditLog.Close] when done.
+func NewFileAuditLog(path string) (*FileAuditLog, error) {
+	if path == "" {
+		return nil, errors.New("path is empty")
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open audit log file: %w", err)
+	}
+	return &FileAuditLog{
+		path: path,
+		f:    f,
+	}, nil
+}
+
+// Append implements [AuditLog].
+func (l *FileAuditLog) Append(_ context.Context, entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// A single write, so that entries aren't interleaved, even with multiple
+	// processes appending to the file.
+	_, err = l.f.Write(line)
+	if err != nil {
+		return fmt.Errorf("couldn't write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Entries implements [AuditLog]. It reads the whole file.
+func (l *FileAuditLog) Entries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open audit log file: %w", err)
+	}
+	defer f.Close()
+
+	var res []AuditEntry
+	dec := json.NewDecoder(f)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var entry AuditEntry
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("couldn't decode audit entry: %w", err)
+		}
+		if filter.matches(entry) {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+// Close closes the audit log file.
+func (l *FileAuditLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.f.Close()
+}
@@ -0,0 +1,234 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDB_SetAuditLog(t *testing.T) {
+	fileLog, err := NewFileAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer fileLog.Close()
+
+	tt := []struct {
+		name string
+		log  AuditLog
+	}{
+		{"Memory", NewMemoryAuditLog()},
+		{"File", fileLog},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := NewDB()
+			db.SetAuditLog(tc.log)
+			c, err := db.CreateCollection("test", nil, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			start := time.Now()
+
+			aliceCtx := ContextWithPrincipal(ctx, "alice")
+			err = c.AddDocument(aliceCtx, Document{ID: "1", Embedding: []float32{-0.40824828, 0.40824828, 0.81649655}})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.AddDocument(ContextWithPrincipal(ctx, "bob"), Document{ID: "1", Embedding: []float32{0.40824828, -0.40824828, 0.81649655}})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.Delete(aliceCtx, nil, nil, "1", "2")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+
+			entries, err := tc.log.Entries(ctx, AuditFilter{})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			expected := []struct {
+				principal string
+				action    AuditAction
+			}{
+				{"alice", AuditActionAdd},
+				{"bob", AuditActionUpdate},
+				{"alice", AuditActionDelete},
+			}
+			if len(entries) != len(expected) {
+				t.Fatalf("expected %d entries, got %+v", len(expected), entries)
+			}
+			for i, e := range expected {
+				entry := entries[i]
+				if entry.Principal != e.principal || entry.Action != e.action || entry.Collection != "test" || !slices.Equal(entry.DocumentIDs, []string{"1"}) {
+					t.Fatalf("expected entry %d to be %s by %s, got %+v", i, e.action, e.principal, entry)
+				}
+				if entry.Time.Before(start.Add(-time.Second)) {
+					t.Fatal("expected recent time, got", entry.Time)
+				}
+			}
+
+			entries, err = tc.log.Entries(ctx, AuditFilter{Principal: "alice", Action: AuditActionDelete})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected 1 entry, got %+v", entries)
+			}
+		})
+	}
+}
+
+func TestDB_SetAuditLog_System(t *testing.T) {
+	ctx := ContextWithPrincipal(context.Background(), "alice")
+	log := NewMemoryAuditLog()
+	db := NewDB()
+	db.SetAuditLog(log)
+	docSize := documentMemoryUsage(&Document{ID: "1", Embedding: make([]float32, 2)}).Total()
+	err := db.SetMemoryBudget(MemoryBudget{Limit: docSize, Policy: MemoryBudgetEvictRandom})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, id := range []string{"1", "2"} {
+		err = c.AddDocument(ctx, Document{ID: id, Embedding: []float32{1, 0}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	err = db.SetMemoryBudget(MemoryBudget{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.SetSoftDelete(time.Hour)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetSoftDelete(0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.PurgeTrash()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	err = c.AddDocument(ctx, Document{ID: "3", Embedding: []float32{1, 0}, Metadata: map[string]string{"expires_at": past}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.RunMaintenance(ctx, MaintenanceOptions{ExpiresAtKey: "expires_at"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	entries, err := log.Entries(ctx, AuditFilter{Principal: AuditPrincipalSystem})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected := []struct {
+		action AuditAction
+		id     string
+	}{
+		{AuditActionEvict, "1"},
+		{AuditActionPurge, "2"},
+		{AuditActionExpire, "3"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), entries)
+	}
+	for i, e := range expected {
+		entry := entries[i]
+		if entry.Action != e.action || !slices.Equal(entry.DocumentIDs, []string{e.id}) {
+			t.Fatalf("expected entry %d to be %s of %s, got %+v", i, e.action, e.id, entry)
+		}
+	}
+}
+
+func TestDB_SetAuditLog_Collections(t *testing.T) {
+	ctx := ContextWithPrincipal(context.Background(), "alice")
+	log := NewMemoryAuditLog()
+	db, err := NewPersistentDB(t.TempDir(), false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	db.SetAuditLog(log)
+	for _, name := range []string{"a", "b"} {
+		c, err := db.CreateCollection(name, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = c.AddDocuments(ctx, []Document{
+			{ID: "1", Embedding: []float32{1, 0}},
+			{ID: "2", Embedding: []float32{0, 1}},
+		}, 1)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	err = db.GetCollection("a", nil).Archive(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	buf := bytes.Buffer{}
+	err = db.ExportToWriter(&buf, false, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = db.DeleteCollectionWithContext(ctx, "a")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.ImportFromReaderWithContext(ContextWithPrincipal(context.Background(), "bob"), &buf, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.Reset()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The archived document of collection a is imported as a regular one.
+	tt := []struct {
+		action     AuditAction
+		collection string
+		principal  string
+	}{
+		{AuditActionDeleteCollection, "a", "alice"},
+		{AuditActionImport, "a", "bob"},
+		{AuditActionImport, "b", "bob"},
+		{AuditActionDeleteCollection, "a", ""},
+		{AuditActionDeleteCollection, "b", ""},
+	}
+	entries, err := log.Entries(ctx, AuditFilter{Since: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	entries = slices.DeleteFunc(entries, func(entry AuditEntry) bool {
+		return entry.Action != AuditActionDeleteCollection && entry.Action != AuditActionImport
+	})
+	if len(entries) != len(tt) {
+		t.Fatalf("expected %d entries, got %+v", len(tt), entries)
+	}
+	for i, tc := range tt {
+		entry := entries[i]
+		if entry.Action != tc.action || entry.Principal != tc.principal || entry.Collection != tc.collection || !slices.Equal(entry.DocumentIDs, []string{"1", "2"}) {
+			t.Fatalf("expected entry %d to be %s of %s by %q, got %+v", i, tc.action, tc.collection, tc.principal, entry)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package chromem
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Permission is the kind of access to a collection, see [AuthOptions.Authorize].
+type Permission int
+
+const (
+	// PermissionRead allows listing, getting and querying documents.
+	PermissionRead Permission = iota
+	// PermissionWrite allows adding and deleting documents and collections.
+	// It implies PermissionRead.
+	PermissionWrite
+)
+
+// AuthOptions are the options for [NewAuthMiddleware].
+type AuthOptions struct {
+	// Static API keys, mapped to the names of their principals. The key is
+	// expected as bearer token ("Authorization: Bearer <key>") or in the
+	// "X-API-Key" header.
+	APIKeys map[string]string
+
+	// ValidateToken validates bearer tokens that aren't one of the APIKeys, for
+	// example JWTs or OAuth access tokens, and returns the name of their
+	// principal. Optional.
+	ValidateToken func(ctx context.Context, token string) (principal string, err error)
+
+	// Authorize decides whether the principal has the permission for the
+	// collection. The collection is empty when a collection with a generated
+	// name is created, like a vector store of [NewVectorStoresHandler].
+	// Optional, by default all authenticated principals have all permissions.
+	// See [StaticPermissions] for a simple implementation.
+	Authorize func(ctx context.Context, principal, collection string, permission Permission) bool
+}
+
+type authorizeKey struct{}
+
+// NewAuthMiddleware returns a middleware that authenticates requests to the HTTP
+// handlers of chromem-go, like [AdminHandler], [NewVectorStoresHandler] and
+// [MCPServer], so they can be exposed beyond localhost. Requests without valid
+// API key or token are rejected with 401 Unauthorized.
+//
+// The principal is added to the request context (see [ContextWithPrincipal]),
+// so it's recorded in the audit log (see [DB.SetAuditLog]). The handlers check
+// the principal's permissions per collection with [AuthOptions.Authorize], and
+// hide collections the principal can't read from listings.
+//
+//	auth := chromem.NewAuthMiddleware(chromem.AuthOptions{
+//		APIKeys: map[string]string{os.Getenv("API_KEY"): "ingestion"},
+//	})
+//	http.Handle("/admin/", auth(http.StripPrefix("/admin", chromem.AdminHandler(db))))
+func NewAuthMiddleware(opts AuthOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get("X-API-Key")
+			if token == "" {
+				token, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			}
+			if token == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeJSONError(w, http.StatusUnauthorized, errors.New("missing API key or token"))
+				return
+			}
+
+			principal, ok := lookupAPIKey(opts.APIKeys, token)
+			if !ok && opts.ValidateToken != nil {
+				var err error
+				principal, err = opts.ValidateToken(r.Context(), token)
+				ok = err == nil
+			}
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeJSONError(w, http.StatusUnauthorized, errors.New("invalid API key or token"))
+				return
+			}
+
+			ctx := ContextWithPrincipal(r.Context(), principal)
+			if opts.Authorize != nil {
+				ctx = context.WithValue(ctx, authorizeKey{}, opts.Authorize)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// lookupAPIKey returns the principal of the API key. The keys are compared in
+// constant time to prevent timing attacks.
+func lookupAPIKey(apiKeys map[string]string, token string) (string, bool) {
+	for key, principal := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			return principal, true
+		}
+	}
+	return "", false
+}
+
+// StaticPermissions returns an [AuthOptions.Authorize] function for fixed
+// permissions, mapping principals to collections to their permission. The
+// collection "*" applies to all collections that aren't listed explicitly.
+// Principals that aren't listed have no permissions.
+//
+//	chromem.StaticPermissions(map[string]map[string]chromem.Permission{
+//		"ingestion": {"*": chromem.PermissionWrite},
+//		"support-bot": {"faq": chromem.PermissionRead},
+//	})
+func StaticPermissions(permissions map[string]map[string]Permission) func(ctx context.Context, principal, collection string, permission Permission) bool {
+	return func(_ context.Context, principal, collection string, permission Permission) bool {
+		collections, ok := permissions[principal]
+		if !ok {
+			return false
+		}
+		granted, ok := collections[collection]
+		if !ok {
+			granted, ok = collections["*"]
+		}
+		return ok && granted >= permission
+	}
+}
+
+// authorized reports whether the principal of the context has the permission
+// for the collection. Without [NewAuthMiddleware], everything is authorized.
+func authorized(ctx context.Context, collection string, permission Permission) bool {
+	authorize, ok := ctx.Value(authorizeKey{}).(func(ctx context.Context, principal, collection string, permission Permission) bool)
+	if !ok {
+		return true
+	}
+	return authorize(ctx, PrincipalFromContext(ctx), collection, permission)
+}
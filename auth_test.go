@@ -0,0 +1,110 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewAuthMiddleware(t *testing.T) {
+	db := NewDB()
+	for _, name := range []string{"public", "private"} {
+		_, err := db.CreateCollection(name, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	auth := NewAuthMiddleware(AuthOptions{
+		APIKeys: map[string]string{"key-alice": "alice"},
+		ValidateToken: func(_ context.Context, token string) (string, error) {
+			if token == "token-bob" {
+				return "bob", nil
+			}
+			return "", errors.New("invalid token")
+		},
+		Authorize: StaticPermissions(map[string]map[string]Permission{
+			"alice": {"*": PermissionWrite},
+			"bob":   {"public": PermissionRead},
+		}),
+	})
+	ts := httptest.NewServer(auth(AdminHandler(db)))
+	defer ts.Close()
+
+	get := func(t *testing.T, path string, headers map[string]string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	tt := []struct {
+		name     string
+		path     string
+		headers  map[string]string
+		expected int
+	}{
+		{"No key", "/api/collections", nil, http.StatusUnauthorized},
+		{"Invalid key", "/api/collections", map[string]string{"X-API-Key": "foo"}, http.StatusUnauthorized},
+		{"API key header", "/api/collections/private", map[string]string{"X-API-Key": "key-alice"}, http.StatusOK},
+		{"API key as bearer token", "/api/collections/private", map[string]string{"Authorization": "Bearer key-alice"}, http.StatusOK},
+		{"Validated token", "/api/collections/public", map[string]string{"Authorization": "Bearer token-bob"}, http.StatusOK},
+		{"No permission", "/api/collections/private", map[string]string{"Authorization": "Bearer token-bob"}, http.StatusForbidden},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res := get(t, tc.path, tc.headers)
+			if res.StatusCode != tc.expected {
+				t.Fatal("expected status", tc.expected, "got", res.StatusCode)
+			}
+		})
+	}
+
+	t.Run("Listing hides collections", func(t *testing.T) {
+		res := get(t, "/api/collections", map[string]string{"Authorization": "Bearer token-bob"})
+		var collections []adminCollection
+		err := json.NewDecoder(res.Body).Decode(&collections)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(collections) != 1 || collections[0].Name != "public" {
+			t.Fatalf("expected only the public collection, got %+v", collections)
+		}
+	})
+}
+
+func TestStaticPermissions(t *testing.T) {
+	ctx := context.Background()
+	authorize := StaticPermissions(map[string]map[string]Permission{
+		"reader": {"*": PermissionRead, "inbox": PermissionWrite},
+	})
+	tt := []struct {
+		principal  string
+		collection string
+		permission Permission
+		expected   bool
+	}{
+		{"reader", "docs", PermissionRead, true},
+		{"reader", "docs", PermissionWrite, false},
+		{"reader", "inbox", PermissionWrite, true},
+		{"unknown", "docs", PermissionRead, false},
+	}
+	for _, tc := range tt {
+		if got := authorize(ctx, tc.principal, tc.collection, tc.permission); got != tc.expected {
+			t.Errorf("expected %v for %s on %s with permission %d, got %v", tc.expected, tc.principal, tc.collection, tc.permission, got)
+		}
+	}
+}
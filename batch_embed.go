@@ -0,0 +1,359 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// BatchEmbeddingRequest is a text to embed in a batch job, see
+// [BatchEmbeddingProvider].
+type BatchEmbeddingRequest struct {
+	// The ID that the result has, the document ID.
+	CustomID string
+	Text     string
+}
+
+// BatchEmbeddingResult is the result of a [BatchEmbeddingRequest].
+type BatchEmbeddingResult struct {
+	CustomID  string
+	Embedding []float32
+	// The error of the request. Empty if it succeeded.
+	Error string
+}
+
+// BatchJobState is the state of a batch job, see [BatchEmbeddingProvider].
+type BatchJobState string
+
+const (
+	BatchJobPending   BatchJobState = "pending"
+	BatchJobCompleted BatchJobState = "completed"
+	// The job failed, expired or was cancelled.
+	BatchJobFailed BatchJobState = "failed"
+)
+
+// BatchJobStatus is the status of a batch job, see [BatchEmbeddingProvider].
+type BatchJobStatus struct {
+	State BatchJobState
+	// The reason of a failed job. Optional.
+	Error string
+}
+
+// BatchEmbeddingProvider creates embeddings asynchronously in batch jobs, which
+// is usually cheaper than creating them synchronously, for example with the
+// OpenAI Batch API, see [NewBatchEmbeddingProviderOpenAI].
+type BatchEmbeddingProvider interface {
+	// SubmitBatch submits a job to embed the texts and returns its ID.
+	SubmitBatch(ctx context.Context, requests []BatchEmbeddingRequest) (string, error)
+
+	// BatchStatus returns the status of the job.
+	BatchStatus(ctx context.Context, jobID string) (BatchJobStatus, error)
+
+	// BatchResults returns the results of the completed job.
+	BatchResults(ctx context.Context, jobID string) ([]BatchEmbeddingResult, error)
+}
+
+// BatchEmbeddingOptions are the options for
+// [Collection.AddDocumentsWithBatchEmbedding].
+type BatchEmbeddingOptions struct {
+	// The path of the file that the state of the jobs is stored in, so an
+	// interrupted call can be resumed by calling it again with the same path.
+	// Optional, but without it the jobs of an interrupted call are lost. The
+	// file is deleted when all jobs are done.
+	StatePath string
+
+	// The maximum number of documents per job. Optional, defaults to 50,000,
+	// the maximum number of requests of an OpenAI batch.
+	JobSize int
+
+	// The interval in which the status of the jobs is checked. Optional,
+	// defaults to 1 minute.
+	PollInterval time.Duration
+}
+
+// BatchEmbeddingStats are the statistics of
+// [Collection.AddDocumentsWithBatchEmbedding].
+type BatchEmbeddingStats struct {
+	// The number of jobs that were submitted by the call.
+	Submitted int
+
+	// The number of documents that were added by the call.
+	Imported int
+
+	// The errors of the documents that couldn't be embedded, by document ID,
+	// for example to retry them.
+	Failed map[string]string
+}
+
+// batchEmbeddingState is the persisted state of the jobs of
+// [Collection.AddDocumentsWithBatchEmbedding].
+type batchEmbeddingState struct {
+	Jobs []*batchEmbeddingJob `json:"jobs"`
+}
+
+type batchEmbeddingJob struct {
+	ID          string   `json:"id"`
+	DocumentIDs []string `json:"documentIds"`
+	Done        bool     `json:"done"`
+}
+
+// AddDocumentsWithBatchEmbedding adds the documents to the collection, with
+// embeddings that are created in batch jobs of the provider, for example to
+// embed a large number of documents at a lower price. It submits the jobs,
+// waits for them to complete and adds the documents with the results. This
+// can take hours, and the state of the jobs is persisted, so the call can be
+// resumed after interruptions, see [BatchEmbeddingOptions.StatePath]. To
+// resume, pass the same documents again. Documents that are in no job yet are
+// submitted in new jobs.
+//
+// Documents with embedding are added directly. The texts are the contents with
+// the collection's document template (see [Collection.SetEmbeddingTemplate]),
+// after preprocessing, but without summarization and embedding instructions.
+// Documents whose requests fail don't stop the call, and are returned in the
+// stats instead.
+func (c *Collection) AddDocumentsWithBatchEmbedding(ctx context.Context, docs []Document, provider BatchEmbeddingProvider, opts BatchEmbeddingOptions) (BatchEmbeddingStats, error) {
+	if len(docs) == 0 {
+		return BatchEmbeddingStats{}, errors.New("documents slice is nil or empty")
+	}
+	if provider == nil {
+		return BatchEmbeddingStats{}, errors.New("provider is nil")
+	}
+	jobSize := opts.JobSize
+	if jobSize <= 0 {
+		jobSize = 50000
+	}
+	pollInterval := opts.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = time.Minute
+	}
+
+	state, err := readBatchEmbeddingState(opts.StatePath)
+	if err != nil {
+		return BatchEmbeddingStats{}, err
+	}
+	inJob := make(map[string]struct{})
+	for _, job := range state.Jobs {
+		for _, id := range job.DocumentIDs {
+			inJob[id] = struct{}{}
+		}
+	}
+
+	stats := BatchEmbeddingStats{}
+	byID := make(map[string]Document, len(docs))
+	var embedded []Document
+	var requests []BatchEmbeddingRequest
+	for _, doc := range docs {
+		if doc.ID == "" {
+			return stats, errors.New("document ID is empty")
+		}
+		byID[doc.ID] = doc
+		if len(doc.Embedding) != 0 {
+			embedded = append(embedded, doc)
+			continue
+		}
+		if _, ok := inJob[doc.ID]; ok {
+			continue
+		}
+		text, err := c.batchEmbeddingText(doc)
+		if err != nil {
+			return stats, err
+		}
+		requests = append(requests, BatchEmbeddingRequest{CustomID: doc.ID, Text: text})
+	}
+	if len(embedded) != 0 {
+		err := c.AddDocuments(ctx, embedded, runtime.NumCPU())
+		if err != nil {
+			return stats, err
+		}
+		stats.Imported += len(embedded)
+	}
+
+	for start := 0; start < len(requests); start += jobSize {
+		chunk := requests[start:min(start+jobSize, len(requests))]
+		jobID, err := provider.SubmitBatch(ctx, chunk)
+		if err != nil {
+			return stats, fmt.Errorf("couldn't submit batch job: %w", err)
+		}
+		job := &batchEmbeddingJob{ID: jobID}
+		for _, req := range chunk {
+			job.DocumentIDs = append(job.DocumentIDs, req.CustomID)
+		}
+		state.Jobs = append(state.Jobs, job)
+		stats.Submitted++
+		err = writeBatchEmbeddingState(opts.StatePath, state)
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	fail := func(id, err string) {
+		if stats.Failed == nil {
+			stats.Failed = make(map[string]string)
+		}
+		stats.Failed[id] = err
+	}
+	for {
+		pending := 0
+		for _, job := range state.Jobs {
+			if job.Done {
+				continue
+			}
+			status, err := provider.BatchStatus(ctx, job.ID)
+			if err != nil {
+				return stats, fmt.Errorf("couldn't get status of batch job %q: %w", job.ID, err)
+			}
+			switch status.State {
+			case BatchJobPending:
+				pending++
+				continue
+			case BatchJobFailed:
+				reason := status.Error
+				if reason == "" {
+					reason = "batch job failed"
+				}
+				for _, id := range job.DocumentIDs {
+					fail(id, reason)
+				}
+			case BatchJobCompleted:
+				imported, err := c.ingestBatchEmbeddingJob(ctx, provider, job, byID, fail)
+				stats.Imported += imported
+				if err != nil {
+					return stats, err
+				}
+			default:
+				return stats, fmt.Errorf("unknown state of batch job %q: %q", job.ID, status.State)
+			}
+			job.Done = true
+			err = writeBatchEmbeddingState(opts.StatePath, state)
+			if err != nil {
+				return stats, err
+			}
+		}
+		if pending == 0 {
+			break
+		}
+
+		timer := time.NewTimer(pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return stats, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if opts.StatePath != "" {
+		err := removeFile(opts.StatePath)
+		if err != nil {
+			return stats, fmt.Errorf("couldn't remove batch job state: %w", err)
+		}
+	}
+	return stats, nil
+}
+
+// ingestBatchEmbeddingJob adds the documents of the completed job with their
+// embeddings and returns their number.
+func (c *Collection) ingestBatchEmbeddingJob(ctx context.Context, provider BatchEmbeddingProvider, job *batchEmbeddingJob, byID map[string]Document, fail func(id, err string)) (int, error) {
+	results, err := provider.BatchResults(ctx, job.ID)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get results of batch job %q: %w", job.ID, err)
+	}
+	inJob := make(map[string]bool, len(job.DocumentIDs))
+	for _, id := range job.DocumentIDs {
+		inJob[id] = false
+	}
+	var docs []Document
+	for _, res := range results {
+		if done, ok := inJob[res.CustomID]; !ok || done {
+			continue
+		}
+		inJob[res.CustomID] = true
+		doc, ok := byID[res.CustomID]
+		if !ok {
+			// The document wasn't passed when resuming.
+			fail(res.CustomID, "document not found")
+			continue
+		}
+		if res.Error != "" {
+			fail(res.CustomID, res.Error)
+			continue
+		}
+		doc.Embedding = res.Embedding
+		docs = append(docs, doc)
+	}
+	for _, id := range job.DocumentIDs {
+		if !inJob[id] {
+			fail(id, "no result")
+		}
+	}
+	if len(docs) == 0 {
+		return 0, nil
+	}
+	err = c.AddDocuments(ctx, docs, runtime.NumCPU())
+	if err != nil {
+		return 0, fmt.Errorf("couldn't add documents of batch job %q: %w", job.ID, err)
+	}
+	return len(docs), nil
+}
+
+// batchEmbeddingText returns the text of the document to embed in a batch job.
+func (c *Collection) batchEmbeddingText(doc Document) (string, error) {
+	doc, err := c.preprocessDocument(doc)
+	if err != nil {
+		return "", err
+	}
+	if doc.Content == "" {
+		return "", fmt.Errorf("content of document %q is empty", doc.ID)
+	}
+	return formatWithTemplate(c.getConfig().EmbeddingTemplate.Document, doc.Content), nil
+}
+
+func readBatchEmbeddingState(path string) (*batchEmbeddingState, error) {
+	state := &batchEmbeddingState{}
+	if path == "" {
+		return state, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("couldn't read batch job state: %w", err)
+	}
+	err = json.Unmarshal(b, state)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode batch job state: %w", err)
+	}
+	return state, nil
+}
+
+// writeBatchEmbeddingState writes the state to a temporary file first and then
+// renames it, so it's never left half-written.
+func writeBatchEmbeddingState(path string, state *batchEmbeddingState) error {
+	if path == "" {
+		return nil
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("couldn't encode batch job state: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return fmt.Errorf("couldn't create directory of batch job state: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, b, 0o600)
+	if err != nil {
+		return fmt.Errorf("couldn't write batch job state: %w", err)
+	}
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return fmt.Errorf("couldn't rename batch job state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// OpenAIBatchProviderOptions are the options for
+// [NewBatchEmbeddingProviderOpenAI].
+type OpenAIBatchProviderOptions struct {
+	// The embedding model. Optional, defaults to
+	// [EmbeddingModelOpenAI3Small].
+	Model EmbeddingModelOpenAI
+
+	// The base URL of the API. Optional, defaults to [BaseURLOpenAI].
+	BaseURL string
+
+	// The API key. Optional, defaults to the environment variable
+	// "OPENAI_API_KEY".
+	APIKey string
+
+	// The HTTP client to use. Optional, defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+type openAIBatchProvider struct {
+	model   string
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewBatchEmbeddingProviderOpenAI returns a [BatchEmbeddingProvider] that
+// creates embeddings with the OpenAI Batch API
+// (https://platform.openai.com/docs/guides/batch). It uploads the requests as
+// file via the /v1/files endpoint, creates a batch of the /v1/embeddings
+// endpoint with a completion window of 24 hours, and downloads the results
+// when it's completed. Batches that expire are failed, even if some of their
+// requests completed.
+func NewBatchEmbeddingProviderOpenAI(opts OpenAIBatchProviderOptions) BatchEmbeddingProvider {
+	model := opts.Model
+	if model == "" {
+		model = EmbeddingModelOpenAI3Small
+	}
+	baseURL, apiKey, client := openAIDefaults(opts.BaseURL, opts.APIKey, opts.HTTPClient)
+	return &openAIBatchProvider{
+		model:   string(model),
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		client:  client,
+	}
+}
+
+type openAIBatch struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	OutputFileID string `json:"output_file_id"`
+	ErrorFileID  string `json:"error_file_id"`
+	Errors       *struct {
+		Data []openAIBatchError `json:"data"`
+	} `json:"errors"`
+}
+
+func (p *openAIBatchProvider) headers() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + p.apiKey}
+}
+
+func (p *openAIBatchProvider) SubmitBatch(ctx context.Context, requests []BatchEmbeddingRequest) (string, error) {
+	if len(requests) == 0 {
+		return "", errors.New("requests are empty")
+	}
+
+	// Upload the requests as JSONL file.
+	input := &bytes.Buffer{}
+	enc := json.NewEncoder(input)
+	for _, req := range requests {
+		err := enc.Encode(map[string]any{
+			"custom_id": req.CustomID,
+			"method":    http.MethodPost,
+			"url":       "/v1/embeddings",
+			"body":      map[string]string{"model": p.model, "input": req.Text},
+		})
+		if err != nil {
+			return "", fmt.Errorf("couldn't encode request: %w", err)
+		}
+	}
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	err := w.WriteField("purpose", "batch")
+	if err != nil {
+		return "", fmt.Errorf("couldn't write multipart field: %w", err)
+	}
+	part, err := w.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return "", fmt.Errorf("couldn't create multipart file: %w", err)
+	}
+	_, err = io.Copy(part, input)
+	if err != nil {
+		return "", fmt.Errorf("couldn't write multipart file: %w", err)
+	}
+	err = w.Close()
+	if err != nil {
+		return "", fmt.Errorf("couldn't close multipart writer: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/files", body)
+	if err != nil {
+		return "", fmt.Errorf("couldn't create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("couldn't send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLen))
+		return "", fmt.Errorf("couldn't upload batch input: %w", &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(b)})
+	}
+	var file struct {
+		ID string `json:"id"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&file)
+	if err != nil {
+		return "", fmt.Errorf("couldn't decode response body: %w", err)
+	}
+
+	var batch openAIBatch
+	err = doJSONRequest(ctx, p.client, http.MethodPost, p.baseURL+"/batches", p.headers(), map[string]string{
+		"input_file_id":     file.ID,
+		"endpoint":          "/v1/embeddings",
+		"completion_window": "24h",
+	}, &batch)
+	if err != nil {
+		return "", fmt.Errorf("couldn't create batch: %w", err)
+	}
+	return batch.ID, nil
+}
+
+func (p *openAIBatchProvider) batch(ctx context.Context, jobID string) (openAIBatch, error) {
+	var batch openAIBatch
+	err := doJSONRequest(ctx, p.client, http.MethodGet, p.baseURL+"/batches/"+jobID, p.headers(), nil, &batch)
+	if err != nil {
+		return openAIBatch{}, fmt.Errorf("couldn't get batch: %w", err)
+	}
+	return batch, nil
+}
+
+func (p *openAIBatchProvider) BatchStatus(ctx context.Context, jobID string) (BatchJobStatus, error) {
+	batch, err := p.batch(ctx, jobID)
+	if err != nil {
+		return BatchJobStatus{}, err
+	}
+	switch batch.Status {
+	case "validating", "in_progress", "finalizing":
+		return BatchJobStatus{State: BatchJobPending}, nil
+	case "completed":
+		return BatchJobStatus{State: BatchJobCompleted}, nil
+	case "failed", "expired", "cancelling", "cancelled":
+		status := BatchJobStatus{State: BatchJobFailed, Error: "batch " + batch.Status}
+		if batch.Errors != nil {
+			var reasons []string
+			for _, e := range batch.Errors.Data {
+				reasons = append(reasons, e.String())
+			}
+			if len(reasons) != 0 {
+				status.Error += ": " + strings.Join(reasons, "; ")
+			}
+		}
+		return status, nil
+	}
+	return BatchJobStatus{}, fmt.Errorf("unknown batch status %q", batch.Status)
+}
+
+func (p *openAIBatchProvider) BatchResults(ctx context.Context, jobID string) ([]BatchEmbeddingResult, error) {
+	batch, err := p.batch(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	var res []BatchEmbeddingResult
+	// Failed requests are in the error file, but are handled the same.
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		body, err := openAIFileContent(ctx, p.baseURL, p.apiKey, p.client, fileID)
+		if err != nil {
+			return nil, err
+		}
+		err = readOpenAIBatchOutput(body, func(lineNumber int, line openAIBatchOutputLine) error {
+			result := BatchEmbeddingResult{CustomID: line.CustomID, Error: line.err()}
+			if result.Error == "" {
+				if len(line.Response.Body.Data) != 1 {
+					result.Error = fmt.Sprintf("expected 1 embedding, got %d", len(line.Response.Body.Data))
+				} else {
+					embedding, err := decodeOpenAIEmbedding(line.Response.Body.Data[0].Embedding)
+					if err != nil {
+						return fmt.Errorf("couldn't decode embedding of %q on line %d: %w", line.CustomID, lineNumber, err)
+					}
+					result.Embedding = embedding
+				}
+			}
+			res = append(res, result)
+			return nil
+		})
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
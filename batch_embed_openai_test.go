@@ -0,0 +1,106 @@
+package chromem
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewBatchEmbeddingProviderOpenAI(t *testing.T) {
+	ctx := context.Background()
+	var lock sync.Mutex
+	var input []map[string]any
+	status := "in_progress"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/files":
+			if r.FormValue("purpose") != "batch" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				var line map[string]any
+				_ = json.Unmarshal(scanner.Bytes(), &line)
+				input = append(input, line)
+			}
+			_, _ = w.Write([]byte(`{"id":"file-in"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/batches":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["input_file_id"] != "file-in" || body["endpoint"] != "/v1/embeddings" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"batch-1","status":"validating"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/batches/batch-1":
+			fmt.Fprintf(w, `{"id":"batch-1","status":%q,"output_file_id":"file-out","error_file_id":"file-err"}`, status)
+		case r.Method == http.MethodGet && r.URL.Path == "/files/file-out/content":
+			_, _ = w.Write([]byte(`{"custom_id":"1","response":{"status_code":200,"body":{"data":[{"index":0,"embedding":[1,0]}]}}}` + "\n"))
+		case r.Method == http.MethodGet && r.URL.Path == "/files/file-err/content":
+			_, _ = w.Write([]byte(`{"custom_id":"2","response":{"status_code":400,"body":{"error":{"message":"input too long"}}}}` + "\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	p := NewBatchEmbeddingProviderOpenAI(OpenAIBatchProviderOptions{BaseURL: ts.URL, APIKey: "secret"})
+	jobID, err := p.SubmitBatch(ctx, []BatchEmbeddingRequest{{CustomID: "1", Text: "foo"}, {CustomID: "2", Text: "bar"}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if jobID != "batch-1" {
+		t.Fatal("expected batch ID, got", jobID)
+	}
+	if len(input) != 2 || input[0]["custom_id"] != "1" || input[0]["url"] != "/v1/embeddings" {
+		t.Fatal("expected 2 embedding requests in input file, got", input)
+	}
+	if body, _ := input[1]["body"].(map[string]any); body["model"] != string(EmbeddingModelOpenAI3Small) || body["input"] != "bar" {
+		t.Fatal("expected request body with model and input, got", input[1]["body"])
+	}
+
+	s, err := p.BatchStatus(ctx, jobID)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if s.State != BatchJobPending {
+		t.Fatal("expected pending job, got", s)
+	}
+	lock.Lock()
+	status = "expired"
+	lock.Unlock()
+	s, err = p.BatchStatus(ctx, jobID)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if s.State != BatchJobFailed || !strings.Contains(s.Error, "expired") {
+		t.Fatal("expected expired job, got", s)
+	}
+
+	res, err := p.BatchResults(ctx, jobID)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].CustomID != "1" || len(res[0].Embedding) != 2 || res[1].CustomID != "2" || res[1].Error != "input too long" {
+		t.Fatal("expected result and error, got", res)
+	}
+}
@@ -0,0 +1,120 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testBatchProvider struct {
+	lock      sync.Mutex
+	submitted map[string][]BatchEmbeddingRequest
+	completed bool
+	// Called when the status is checked.
+	onStatus func()
+}
+
+func (p *testBatchProvider) SubmitBatch(_ context.Context, requests []BatchEmbeddingRequest) (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.submitted == nil {
+		p.submitted = make(map[string][]BatchEmbeddingRequest)
+	}
+	id := "job-" + string(rune('a'+len(p.submitted)))
+	p.submitted[id] = requests
+	return id, nil
+}
+
+func (p *testBatchProvider) BatchStatus(_ context.Context, jobID string) (BatchJobStatus, error) {
+	if p.onStatus != nil {
+		p.onStatus()
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.completed {
+		return BatchJobStatus{State: BatchJobPending}, nil
+	}
+	return BatchJobStatus{State: BatchJobCompleted}, nil
+}
+
+func (p *testBatchProvider) BatchResults(_ context.Context, jobID string) ([]BatchEmbeddingResult, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var res []BatchEmbeddingResult
+	for _, req := range p.submitted[jobID] {
+		if strings.HasSuffix(req.Text, "fail") {
+			res = append(res, BatchEmbeddingResult{CustomID: req.CustomID, Error: "invalid input"})
+			continue
+		}
+		res = append(res, BatchEmbeddingResult{CustomID: req.CustomID, Embedding: []float32{1, 0}})
+	}
+	return res, nil
+}
+
+func TestCollection_AddDocumentsWithBatchEmbedding(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "jobs.json")
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetEmbeddingTemplate(EmbeddingTemplate{Document: "passage: "})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Content: "foo"},
+		{ID: "2", Content: "bar"},
+		{ID: "3", Content: "fail"},
+		{ID: "4", Content: "embedded", Embedding: []float32{0, 1}},
+	}
+	opts := BatchEmbeddingOptions{StatePath: statePath, JobSize: 2, PollInterval: time.Millisecond}
+
+	// The first call is interrupted while waiting for the jobs.
+	ctx, cancel := context.WithCancel(context.Background())
+	provider := &testBatchProvider{onStatus: cancel}
+	stats, err := c.AddDocumentsWithBatchEmbedding(ctx, docs, provider, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+	if stats.Submitted != 2 || stats.Imported != 1 {
+		t.Fatal("expected 2 submitted jobs and 1 imported document, got", stats)
+	}
+	if len(provider.submitted["job-a"]) != 2 || provider.submitted["job-a"][0].Text != "passage: foo" {
+		t.Fatal("expected first job with template applied, got", provider.submitted["job-a"])
+	}
+	if _, err := os.Stat(statePath); err != nil {
+		t.Fatal("expected state file, got", err)
+	}
+
+	// The second call resumes the jobs instead of submitting them again.
+	provider.onStatus = nil
+	provider.completed = true
+	stats, err = c.AddDocumentsWithBatchEmbedding(context.Background(), docs, provider, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats.Submitted != 0 || len(provider.submitted) != 2 {
+		t.Fatal("expected no new jobs, got", stats.Submitted, len(provider.submitted))
+	}
+	// The embedded document is added again.
+	if stats.Imported != 3 {
+		t.Fatal("expected 3 imported documents, got", stats.Imported)
+	}
+	if len(stats.Failed) != 1 || stats.Failed["3"] != "invalid input" {
+		t.Fatal("expected failed document 3, got", stats.Failed)
+	}
+	if c.Count() != 3 {
+		t.Fatal("expected 3 documents, got", c.Count())
+	}
+	if _, err := os.Stat(statePath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("expected state file to be removed, got", err)
+	}
+}
@@ -0,0 +1,171 @@
+package chromem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// blobDirName is the name of the directory in the DB's persistence directory
+// that contains the blobs.
+const blobDirName = "blobs"
+
+// ErrBlobNotFound is returned by [DB.OpenBlob] and [DB.DeleteBlob] if there is
+// no blob with the given hash.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// PutBlob stores the content of the reader in the DB's blob store and returns
+// its hash, the hex encoded SHA-256 of the content. The blob store keeps the
+// original files of documents, for example the PDF bytes or HTML that the
+// documents' contents were extracted from, so that query results can link back
+// to the full original. Reference a blob from documents with
+// [MetadataKeySourceBlob], for example via [SourceRef.Blob].
+//
+// Blobs are content-addressed, so storing the same content twice only stores
+// it once. For persistent DBs, blobs are stored as files in the "blobs"
+// subdirectory of the persistence directory, without compression. They're not
+// part of exports via [DB.Export].
+func (db *DB) PutBlob(r io.Reader) (string, error) {
+	if db.persistDirectory == "" {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return "", fmt.Errorf("couldn't read blob: %w", err)
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+
+		db.blobsLock.Lock()
+		defer db.blobsLock.Unlock()
+		if db.blobs == nil {
+			db.blobs = make(map[string][]byte)
+		}
+		db.blobs[hash] = data
+		return hash, nil
+	}
+
+	// Write to a temporary file while hashing, and move it to its final path
+	// once the hash is known.
+	dir := filepath.Join(db.persistDirectory, blobDirName)
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return "", fmt.Errorf("couldn't create blob directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, "tmp-")
+	if err != nil {
+		return "", fmt.Errorf("couldn't create blob file: %w", err)
+	}
+	defer os.Remove(f.Name()) // No-op after the rename
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), r)
+	if err == nil {
+		err = db.syncer.syncFile(f)
+	}
+	if err != nil {
+		f.Close()
+		return "", fmt.Errorf("couldn't write blob: %w", err)
+	}
+	err = f.Close()
+	if err != nil {
+		return "", fmt.Errorf("couldn't close blob file: %w", err)
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	db.blobsLock.Lock()
+	defer db.blobsLock.Unlock()
+	blobPath := db.blobPath(hash)
+	err = os.MkdirAll(filepath.Dir(blobPath), 0o700)
+	if err != nil {
+		return "", fmt.Errorf("couldn't create blob directory: %w", err)
+	}
+	err = os.Rename(f.Name(), blobPath)
+	if err != nil {
+		return "", fmt.Errorf("couldn't move blob file: %w", err)
+	}
+	err = db.syncer.syncDir(blobPath)
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// OpenBlob returns a reader for the blob with the given hash, as returned by
+// [DB.PutBlob]. The caller must close it. If there is no such blob, it returns
+// [ErrBlobNotFound].
+func (db *DB) OpenBlob(hash string) (io.ReadCloser, error) {
+	if !isBlobHash(hash) {
+		return nil, fmt.Errorf("invalid blob hash %q", hash)
+	}
+
+	db.blobsLock.RLock()
+	defer db.blobsLock.RUnlock()
+	if db.persistDirectory == "" {
+		data, ok := db.blobs[hash]
+		if !ok {
+			return nil, ErrBlobNotFound
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	f, err := os.Open(db.blobPath(hash))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrBlobNotFound
+		}
+		return nil, fmt.Errorf("couldn't open blob file: %w", err)
+	}
+	return f, nil
+}
+
+// DeleteBlob deletes the blob with the given hash. Documents that reference it
+// aren't changed. If there is no such blob, it returns [ErrBlobNotFound].
+func (db *DB) DeleteBlob(hash string) error {
+	if !isBlobHash(hash) {
+		return fmt.Errorf("invalid blob hash %q", hash)
+	}
+
+	db.blobsLock.Lock()
+	defer db.blobsLock.Unlock()
+	if db.persistDirectory == "" {
+		if _, ok := db.blobs[hash]; !ok {
+			return ErrBlobNotFound
+		}
+		delete(db.blobs, hash)
+		return nil
+	}
+
+	err := os.Remove(db.blobPath(hash))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrBlobNotFound
+		}
+		return fmt.Errorf("couldn't delete blob file: %w", err)
+	}
+	return nil
+}
+
+// blobPath returns the path of the blob's file. The blobs are spread over
+// subdirectories by the first two characters of the hash, to keep the
+// directories small.
+func (db *DB) blobPath(hash string) string {
+	return filepath.Join(db.persistDirectory, blobDirName, hash[:2], hash)
+}
+
+// isBlobHash reports whether the string is a hex encoded SHA-256 hash. This
+// also ensures that it's safe to use in file paths.
+func isBlobHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, r := range hash {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
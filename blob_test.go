@@ -0,0 +1,126 @@
+package chromem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDB_Blob(t *testing.T) {
+	content := "%PDF-1.7 original file"
+	sum := sha256.Sum256([]byte(content))
+	expectedHash := hex.EncodeToString(sum[:])
+
+	tt := []struct {
+		name string
+		db   func(t *testing.T) *DB
+	}{
+		{
+			name: "in-memory",
+			db: func(t *testing.T) *DB {
+				return NewDB()
+			},
+		},
+		{
+			name: "persistent",
+			db: func(t *testing.T) *DB {
+				db, err := NewPersistentDB(t.TempDir(), false)
+				if err != nil {
+					t.Fatal("expected no error, got", err)
+				}
+				return db
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db := tc.db(t)
+
+			hash, err := db.PutBlob(strings.NewReader(content))
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if hash != expectedHash {
+				t.Fatalf("expected hash %s, got %s", expectedHash, hash)
+			}
+			// Storing it again is a no-op
+			hash, err = db.PutBlob(strings.NewReader(content))
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if hash != expectedHash {
+				t.Fatalf("expected hash %s, got %s", expectedHash, hash)
+			}
+
+			r, err := db.OpenBlob(hash)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			data, err := io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if string(data) != content {
+				t.Fatalf("expected %q, got %q", content, data)
+			}
+
+			_, err = db.OpenBlob("../../etc/passwd")
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+
+			err = db.DeleteBlob(hash)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			_, err = db.OpenBlob(hash)
+			if !errors.Is(err, ErrBlobNotFound) {
+				t.Fatal("expected ErrBlobNotFound, got", err)
+			}
+			err = db.DeleteBlob(hash)
+			if !errors.Is(err, ErrBlobNotFound) {
+				t.Fatal("expected ErrBlobNotFound, got", err)
+			}
+		})
+	}
+}
+
+func TestDB_Blob_persistence(t *testing.T) {
+	path := t.TempDir()
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	hash, err := db.PutBlob(strings.NewReader("<html>original</html>"))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, blobDirName, hash[:2], hash)); err != nil {
+		t.Fatal("expected blob file, got", err)
+	}
+
+	// The blob store isn't a collection
+	db, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(db.ListCollections()) != 1 {
+		t.Fatal("expected 1 collection, got", len(db.ListCollections()))
+	}
+	r, err := db.OpenBlob(hash)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	r.Close()
+}
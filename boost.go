@@ -0,0 +1,81 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+)
+
+// BoostRule adjusts the similarity of documents whose metadata matches the rule
+// in all queries of a collection, see [Collection.SetBoostRules].
+type BoostRule struct {
+	// Conditional filtering on metadata, like the where filter of queries.
+	// Must not be empty.
+	Where map[string]string
+
+	// The value that's added to the similarity of matching documents. Negative
+	// values demote documents. It's typically small, like 0.05, as similarities
+	// of relevant documents are often close to each other.
+	Boost float32
+}
+
+// SetBoostRules sets the boost rules of the collection, which are applied to the
+// similarities of documents in all queries. For example documents with
+// type=faq can be ranked higher. This way relevance tuning lives in the
+// collection's configuration instead of every call site.
+// The boosts of all matching rules are added up. Boosted similarities can be
+// outside of the usual range of [-1, 1].
+// The rules are persisted. Nil or empty rules disable boosting.
+func (c *Collection) SetBoostRules(rules []BoostRule) error {
+	rules, err := cloneBoostRules(rules)
+	if err != nil {
+		return err
+	}
+
+	c.configLock.Lock()
+	c.config.BoostRules = rules
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// BoostRules returns a copy of the collection's boost rules.
+func (c *Collection) BoostRules() []BoostRule {
+	// The error can only occur for invalid rules, which can't be set.
+	rules, _ := cloneBoostRules(c.getConfig().BoostRules)
+	return rules
+}
+
+// cloneBoostRules validates the rules and returns a deep copy of them, or nil if
+// there are none.
+func cloneBoostRules(rules []BoostRule) ([]BoostRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	res := make([]BoostRule, 0, len(rules))
+	for i, rule := range rules {
+		if len(rule.Where) == 0 {
+			return nil, fmt.Errorf("where filter of boost rule %d is empty", i)
+		}
+		if math.IsNaN(float64(rule.Boost)) || math.IsInf(float64(rule.Boost), 0) {
+			return nil, errors.New("boost must be a finite number")
+		}
+		res = append(res, BoostRule{
+			Where: maps.Clone(rule.Where),
+			Boost: rule.Boost,
+		})
+	}
+	return res, nil
+}
+
+// boost returns the sum of the boosts of the rules that match the document.
+func boost(doc *Document, rules []BoostRule) float32 {
+	var res float32
+	for _, rule := range rules {
+		if matchesWhere(doc.Metadata, rule.Where) {
+			res += rule.Boost
+		}
+	}
+	return res
+}
@@ -0,0 +1,74 @@
+package chromem
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestCollection_SetBoostRules(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewPersistentDB(t.TempDir(), false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Embedding: []float32{1, 0}},
+		{ID: "2", Embedding: []float32{0.99, 0.14106736}, Metadata: map[string]string{"type": "faq"}},
+		{ID: "3", Embedding: []float32{0.98, 0.19899749}, Metadata: map[string]string{"type": "spam"}},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	queryIDs := func() []string {
+		t.Helper()
+		res, err := c.QueryEmbedding(ctx, []float32{1, 0}, 3, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		var ids []string
+		for _, r := range res {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	if ids := queryIDs(); !slices.Equal([]string{"1", "2", "3"}, ids) {
+		t.Fatal("expected unboosted order, got", ids)
+	}
+
+	err = c.SetBoostRules([]BoostRule{{Where: map[string]string{}, Boost: 1}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	err = c.SetBoostRules([]BoostRule{
+		{Where: map[string]string{"type": "faq"}, Boost: 0.05},
+		{Where: map[string]string{"type": "spam"}, Boost: -0.5},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if ids := queryIDs(); !slices.Equal([]string{"2", "1", "3"}, ids) {
+		t.Fatal("expected boosted order, got", ids)
+	}
+
+	// The rules are persisted
+	db, err = NewPersistentDB(db.persistDirectory, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	if rules := c.BoostRules(); len(rules) != 2 || rules[0].Boost != 0.05 {
+		t.Fatal("expected persisted boost rules, got", rules)
+	}
+	if ids := queryIDs(); !slices.Equal([]string{"2", "1", "3"}, ids) {
+		t.Fatal("expected boosted order, got", ids)
+	}
+}
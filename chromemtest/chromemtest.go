@@ -0,0 +1,113 @@
+// Package chromemtest provides utilities for testing code that uses
+// chromem-go, like RAG pipelines, without network calls or flaky vectors: a
+// deterministic embedding function, a fake server with an OpenAI compatible
+// embeddings API, and collection fixtures.
+package chromemtest
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// DefaultDimensions is the number of dimensions of the embeddings of
+// [NewEmbeddingFunc] if it's called with 0.
+const DefaultDimensions = 64
+
+// NewEmbeddingFunc returns a deterministic embedding function, which creates
+// normalized embeddings with the given number of dimensions, or
+// [DefaultDimensions] if it's 0.
+//
+// The embeddings are based on hashes of the lowercase words of the text, so
+// texts with common words are similar, and texts with the same words are
+// equal. This makes the results of queries predictable, but it's not a
+// semantic similarity: synonyms aren't similar.
+func NewEmbeddingFunc(dimensions int) chromem.EmbeddingFunc {
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+	return func(_ context.Context, text string) ([]float32, error) {
+		if text == "" {
+			return nil, errors.New("text is empty")
+		}
+		return hashEmbedding(text, dimensions), nil
+	}
+}
+
+// hashEmbedding adds a vector per word, which has a 1 or -1 at the dimension
+// that's selected by the word's hash, and normalizes the sum.
+func hashEmbedding(text string, dimensions int) []float32 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		words = []string{text}
+	}
+
+	v := make([]float32, dimensions)
+	for _, word := range words {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(word))
+		sum := h.Sum64()
+		if sum&1 == 0 {
+			v[(sum>>1)%uint64(dimensions)]++
+		} else {
+			v[(sum>>1)%uint64(dimensions)]--
+		}
+	}
+
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		// The words cancel each other out. Any normalized vector is better than
+		// one that can't be normalized.
+		v[0] = 1
+		return v
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i] = float32(float64(v[i]) / norm)
+	}
+	return v
+}
+
+// SampleDocuments returns a small corpus of documents about different topics,
+// with "topic" metadata, for example to test retrieval.
+func SampleDocuments() []chromem.Document {
+	return []chromem.Document{
+		{ID: "cats-1", Metadata: map[string]string{"topic": "cats"}, Content: "Cats are small carnivorous mammals that like to sleep."},
+		{ID: "cats-2", Metadata: map[string]string{"topic": "cats"}, Content: "Cats eat fish and chicken."},
+		{ID: "dogs-1", Metadata: map[string]string{"topic": "dogs"}, Content: "Dogs are loyal animals that like to play fetch."},
+		{ID: "dogs-2", Metadata: map[string]string{"topic": "dogs"}, Content: "Dogs bark at the mailman."},
+		{ID: "go-1", Metadata: map[string]string{"topic": "programming"}, Content: "Go is a statically typed programming language with goroutines."},
+		{ID: "go-2", Metadata: map[string]string{"topic": "programming"}, Content: "Goroutines are lightweight threads managed by the Go runtime."},
+		{ID: "space-1", Metadata: map[string]string{"topic": "space"}, Content: "The moon orbits the earth about once a month."},
+		{ID: "space-2", Metadata: map[string]string{"topic": "space"}, Content: "Mars is the fourth planet from the sun."},
+	}
+}
+
+// NewCollection creates an in-memory DB with a collection named "test", which
+// uses [NewEmbeddingFunc] with [DefaultDimensions], and adds the documents to
+// it. It fails the test on errors.
+func NewCollection(tb testing.TB, docs []chromem.Document) *chromem.Collection {
+	tb.Helper()
+	c, err := chromem.NewDB().CreateCollection("test", nil, NewEmbeddingFunc(0))
+	if err != nil {
+		tb.Fatal("couldn't create collection:", err)
+	}
+	if len(docs) > 0 {
+		err = c.AddDocuments(context.Background(), docs, 1)
+		if err != nil {
+			tb.Fatal("couldn't add documents:", err)
+		}
+	}
+	return c
+}
@@ -0,0 +1,112 @@
+package chromemtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestNewEmbeddingFunc(t *testing.T) {
+	ctx := context.Background()
+	embed := NewEmbeddingFunc(0)
+
+	v1, err := embed(ctx, "Cats eat fish")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(v1) != DefaultDimensions {
+		t.Fatalf("expected %d dimensions, got %d", DefaultDimensions, len(v1))
+	}
+	var norm float32
+	for _, x := range v1 {
+		norm += x * x
+	}
+	if norm < 0.999 || norm > 1.001 {
+		t.Fatal("expected normalized vector, got squared norm", norm)
+	}
+
+	// Deterministic, case and punctuation insensitive
+	v2, err := embed(ctx, "cats eat fish!")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !slices.Equal(v1, v2) {
+		t.Fatal("expected equal embeddings, got", v1, v2)
+	}
+
+	_, err = embed(ctx, "")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestNewCollection(t *testing.T) {
+	c := NewCollection(t, SampleDocuments())
+	if c.Count() != len(SampleDocuments()) {
+		t.Fatalf("expected %d documents, got %d", len(SampleDocuments()), c.Count())
+	}
+
+	// Documents with common words are the most similar ones.
+	res, err := c.Query(context.Background(), "What do cats eat?", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "cats-2" {
+		t.Fatal("expected document cats-2, got", res[0].ID)
+	}
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer(t, 8)
+
+	// Same embeddings as the embedding function
+	v, err := s.EmbeddingFunc()(ctx, "hello world")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected, _ := NewEmbeddingFunc(8)(ctx, "hello world")
+	if !slices.Equal(v, expected) {
+		t.Fatal("expected", expected, "got", v)
+	}
+
+	// Arrays of texts
+	body, err := json.Marshal(map[string]any{"input": []string{"a", "b"}, "model": "test"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	resp, err := http.Post(s.BaseURL()+"/embeddings", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer resp.Body.Close()
+	var res struct {
+		Data []struct {
+			Index int `json:"index"`
+		} `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res.Data) != 2 || res.Data[1].Index != 1 {
+		t.Fatalf("expected 2 embeddings, got %+v", res.Data)
+	}
+
+	// Failures
+	s.SetFailure(http.StatusTooManyRequests)
+	_, err = s.EmbeddingFunc()(ctx, "hello world")
+	var embeddingErr *chromem.EmbeddingError
+	if !errors.As(err, &embeddingErr) || embeddingErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatal("expected embedding error with status 429, got", err)
+	}
+	if s.Requests() != 3 {
+		t.Fatal("expected 3 requests, got", s.Requests())
+	}
+}
@@ -0,0 +1,122 @@
+package chromemtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// Server is a fake server with an OpenAI compatible embeddings API, at
+// "/v1/embeddings". It creates the embeddings like [NewEmbeddingFunc], and
+// supports single texts and arrays of texts as input.
+type Server struct {
+	*httptest.Server
+
+	dimensions int
+	requests   atomic.Int64
+	failStatus atomic.Int64
+}
+
+// NewServer starts a fake server, whose embeddings have the given number of
+// dimensions, or [DefaultDimensions] if it's 0. It's closed when the test
+// finishes.
+func NewServer(tb testing.TB, dimensions int) *Server {
+	tb.Helper()
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+	s := &Server{dimensions: dimensions}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handleEmbeddings))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// BaseURL returns the base URL of the API, for
+// [chromem.NewEmbeddingFuncOpenAICompat].
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1"
+}
+
+// EmbeddingFunc returns an embedding function that uses the server.
+func (s *Server) EmbeddingFunc() chromem.EmbeddingFunc {
+	normalized := true
+	return chromem.NewEmbeddingFuncOpenAICompat(s.BaseURL(), "test", "test", &normalized)
+}
+
+// Requests returns the number of requests the server received, including the
+// failed ones.
+func (s *Server) Requests() int {
+	return int(s.requests.Load())
+}
+
+// SetFailure makes the server respond to all following requests with the HTTP
+// status code, to test error handling. 0 makes it succeed again.
+func (s *Server) SetFailure(status int) {
+	s.failStatus.Store(int64(status))
+}
+
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	if status := int(s.failStatus.Load()); status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if r.URL.Path != "/v1/embeddings" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Input json.RawMessage `json:"input"`
+		Model string          `json:"model"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "couldn't decode request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var inputs []string
+	var input string
+	if err := json.Unmarshal(req.Input, &input); err == nil {
+		inputs = []string{input}
+	} else if err := json.Unmarshal(req.Input, &inputs); err != nil {
+		http.Error(w, "input must be a string or an array of strings", http.StatusBadRequest)
+		return
+	}
+
+	type embedding struct {
+		Object    string    `json:"object"`
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	}
+	res := struct {
+		Object string      `json:"object"`
+		Data   []embedding `json:"data"`
+		Model  string      `json:"model"`
+	}{
+		Object: "list",
+		Data:   make([]embedding, 0, len(inputs)),
+		Model:  req.Model,
+	}
+	for i, text := range inputs {
+		if text == "" {
+			http.Error(w, "input is empty", http.StatusBadRequest)
+			return
+		}
+		res.Data = append(res.Data, embedding{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: hashEmbedding(text, s.dimensions),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
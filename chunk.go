@@ -0,0 +1,60 @@
+package chromem
+
+import (
+	"unicode"
+)
+
+// splitText splits a text into chunks of at most size runes, with overlap runes
+// shared between consecutive chunks. Where possible, chunks end at whitespace,
+// so that words aren't cut in half.
+func splitText(text string, size, overlap int) []string {
+	if size <= 0 {
+		return nil
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	runes := []rune(text)
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			// Look for whitespace in the second half of the chunk to end it there.
+			for i := end; i > start+size/2; i-- {
+				if unicode.IsSpace(runes[i-1]) {
+					end = i
+					break
+				}
+			}
+		}
+
+		chunk := trimSpaceRunes(runes[start:end])
+		if len(chunk) > 0 {
+			chunks = append(chunks, string(chunk))
+		}
+		if end == len(runes) {
+			break
+		}
+
+		next := end - overlap
+		// Always make progress, even with large overlaps and early chunk ends.
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+func trimSpaceRunes(runes []rune) []rune {
+	for len(runes) > 0 && unicode.IsSpace(runes[0]) {
+		runes = runes[1:]
+	}
+	for len(runes) > 0 && unicode.IsSpace(runes[len(runes)-1]) {
+		runes = runes[:len(runes)-1]
+	}
+	return runes
+}
@@ -0,0 +1,65 @@
+package chromem
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitText(t *testing.T) {
+	tt := []struct {
+		name    string
+		text    string
+		size    int
+		overlap int
+		want    []string
+	}{
+		{
+			name: "Empty",
+			text: "",
+			size: 10,
+			want: nil,
+		},
+		{
+			name: "Shorter than size",
+			text: " hello world ",
+			size: 20,
+			want: []string{"hello world"},
+		},
+		{
+			name: "Split at whitespace",
+			text: "aaaa bbbb cccc",
+			size: 7,
+			want: []string{"aaaa", "bbbb", "cccc"},
+		},
+		{
+			name:    "With overlap",
+			text:    "abcdefghij",
+			size:    4,
+			overlap: 2,
+			want:    []string{"abcd", "cdef", "efgh", "ghij"},
+		},
+		{
+			name: "Multi-byte runes",
+			text: "äöüäöü",
+			size: 3,
+			want: []string{"äöü", "äöü"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := splitText(tc.text, tc.size, tc.overlap)
+			if !slices.Equal(tc.want, got) {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	// Chunks must never exceed the size
+	for _, chunk := range splitText(strings.Repeat("word ", 1000), 33, 10) {
+		if len(chunk) > 33 {
+			t.Fatal("expected chunk of at most 33 runes, got", len(chunk))
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by embedding functions wrapped with a
+// [CircuitBreaker] while the circuit is open, without calling the embedding API.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a [CircuitBreaker].
+type CircuitState int
+
+const (
+	// CircuitClosed is the normal state, in which calls are passed on.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen is the state after repeated failures, in which calls fail fast.
+	CircuitOpen
+	// CircuitHalfOpen is the state after the cooldown, in which a single trial
+	// call is passed on to check whether the embedding API is available again.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(s))
+	}
+}
+
+// CircuitBreaker protects against spending a long time on calls to an embedding
+// API that's down, for example during the bulk ingestion of documents. After a
+// number of consecutive failures it "opens" and fails all calls immediately with
+// [ErrCircuitOpen]. After a cooldown it lets a single trial call through, and
+// "closes" again if it succeeds.
+//
+// Use [CircuitBreaker.Middleware] to wrap an embedding function. A circuit
+// breaker can be shared by multiple embedding functions that use the same API.
+type CircuitBreaker struct {
+	failureThreshold int
+	cooldown         time.Duration
+
+	lock     sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trialing bool
+
+	// For testing
+	now func() time.Time
+}
+
+// NewCircuitBreaker creates a new circuit breaker that opens after
+// failureThreshold consecutive failures, and lets a trial call through after
+// the cooldown.
+func NewCircuitBreaker(failureThreshold int, cooldown time.Duration) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		now:              time.Now,
+	}
+}
+
+// State returns the current state of the circuit breaker.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	if cb.state == CircuitOpen && cb.now().Sub(cb.openedAt) >= cb.cooldown {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// Middleware returns an [EmbeddingMiddleware] that passes calls through the
+// circuit breaker. Failures due to a canceled context or a text that's too long
+// ([ErrInputTooLong]) don't count, as they don't indicate a problem with the
+// embedding API.
+func (cb *CircuitBreaker) Middleware() EmbeddingMiddleware {
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			if err := cb.allow(); err != nil {
+				return nil, err
+			}
+			v, err := next(ctx, text)
+			cb.record(err)
+			return v, err
+		}
+	}
+}
+
+func (cb *CircuitBreaker) allow() error {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		retryIn := cb.cooldown - cb.now().Sub(cb.openedAt)
+		if retryIn > 0 {
+			return fmt.Errorf("%w, next trial in %s", ErrCircuitOpen, retryIn.Round(time.Millisecond))
+		}
+		cb.state = CircuitHalfOpen
+		cb.trialing = true
+		return nil
+	case CircuitHalfOpen:
+		// Only one trial call at a time
+		if cb.trialing {
+			return fmt.Errorf("%w, trial call in progress", ErrCircuitOpen)
+		}
+		cb.trialing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (cb *CircuitBreaker) record(err error) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	wasTrial := cb.state == CircuitHalfOpen
+	if wasTrial {
+		cb.trialing = false
+	}
+
+	if err == nil {
+		cb.state = CircuitClosed
+		cb.failures = 0
+		return
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrInputTooLong) {
+		// Let the next call do the trial instead.
+		return
+	}
+
+	cb.failures++
+	if wasTrial || cb.failures >= cb.failureThreshold {
+		cb.state = CircuitOpen
+		cb.openedAt = cb.now()
+	}
+}
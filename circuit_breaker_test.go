@@ -0,0 +1,89 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	cb := NewCircuitBreaker(2, time.Minute)
+	cb.now = func() time.Time { return now }
+
+	calls := 0
+	var apiErr error
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		calls++
+		return []float32{1}, apiErr
+	}
+	f := cb.Middleware()(embeddingFunc)
+	ctx := context.Background()
+
+	// Failures below the threshold keep the circuit closed
+	apiErr = errors.New("oops")
+	_, _ = f(ctx, "hello")
+	if cb.State() != CircuitClosed {
+		t.Fatal("expected closed, got", cb.State())
+	}
+	// Too long inputs don't count
+	apiErr = ErrInputTooLong
+	_, _ = f(ctx, "hello")
+	if cb.State() != CircuitClosed {
+		t.Fatal("expected closed, got", cb.State())
+	}
+
+	// Open after threshold
+	apiErr = errors.New("oops")
+	_, _ = f(ctx, "hello")
+	if cb.State() != CircuitOpen {
+		t.Fatal("expected open, got", cb.State())
+	}
+	_, err := f(ctx, "hello")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected ErrCircuitOpen, got", err)
+	}
+	if calls != 3 {
+		t.Fatal("expected 3 calls, got", calls)
+	}
+
+	// Failed trial after cooldown opens again
+	now = now.Add(time.Minute)
+	if cb.State() != CircuitHalfOpen {
+		t.Fatal("expected half-open, got", cb.State())
+	}
+	_, _ = f(ctx, "hello")
+	if cb.State() != CircuitOpen || calls != 4 {
+		t.Fatal("expected open after 4 calls, got", cb.State(), calls)
+	}
+
+	// Successful trial closes
+	now = now.Add(time.Minute)
+	apiErr = nil
+	_, err = f(ctx, "hello")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if cb.State() != CircuitClosed {
+		t.Fatal("expected closed, got", cb.State())
+	}
+}
+
+func TestCircuitBreaker_noRetry(t *testing.T) {
+	cb := NewCircuitBreaker(1, time.Minute)
+	calls := 0
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		calls++
+		return nil, errors.New("oops")
+	}
+
+	f := WrapEmbeddingFunc(embeddingFunc, NewEmbeddingMiddlewareRetry(5, time.Millisecond), cb.Middleware())
+	_, err := f(context.Background(), "hello")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected ErrCircuitOpen, got", err)
+	}
+	if calls != 1 {
+		t.Fatal("expected 1 call, got", calls)
+	}
+}
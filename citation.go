@@ -0,0 +1,107 @@
+package chromem
+
+import (
+	"strconv"
+)
+
+// The metadata keys of the convention for source references. Set them when
+// adding documents, for example with [SourceRef.AddToMetadata], so that query
+// results contain a [Citation].
+const (
+	// The URI of the source, for example a URL or file path.
+	MetadataKeySourceURI = "source_uri"
+	// The page of the source, starting at 1.
+	MetadataKeySourcePage = "source_page"
+	// The byte offsets of the document's content in the source, for example
+	// when the source was split into chunks.
+	MetadataKeySourceStart = "source_start"
+	MetadataKeySourceEnd   = "source_end"
+	// The hash of the original file of the source in the DB's blob store, see
+	// [DB.PutBlob].
+	MetadataKeySourceBlob = "source_blob"
+)
+
+// citationURIFallbackKeys are the metadata keys that are used for the URI of
+// citations if the document doesn't have [MetadataKeySourceURI]. They're set by
+// the importers of this package, like [Collection.AddFromURLs] and
+// [SyncDirectory].
+var citationURIFallbackKeys = []string{"url", "source_path", "link", "path"}
+
+// SourceRef is a reference to the source of a document.
+type SourceRef struct {
+	// The URI of the source, for example a URL or file path. Required
+	// unless Blob is set.
+	URI string
+	// The page of the source, starting at 1. Optional.
+	Page int
+	// The byte offsets of the document's content in the source. Optional, only
+	// used if End > Start.
+	Start int
+	End   int
+	// The hash of the original file in the DB's blob store, as returned by
+	// [DB.PutBlob], to link back to the full original. Optional.
+	Blob string
+}
+
+// AddToMetadata adds the source reference to the metadata with the keys of the
+// convention (see [MetadataKeySourceURI]) and returns it. If the metadata is
+// nil, a new map is created.
+func (s SourceRef) AddToMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		metadata = make(map[string]string, 5)
+	}
+	if s.URI != "" {
+		metadata[MetadataKeySourceURI] = s.URI
+	}
+	if s.Page > 0 {
+		metadata[MetadataKeySourcePage] = strconv.Itoa(s.Page)
+	}
+	if s.End > s.Start {
+		metadata[MetadataKeySourceStart] = strconv.Itoa(s.Start)
+		metadata[MetadataKeySourceEnd] = strconv.Itoa(s.End)
+	}
+	if s.Blob != "" {
+		metadata[MetadataKeySourceBlob] = s.Blob
+	}
+	return metadata
+}
+
+// Citation is a structured reference to the source of a query result, so that
+// LLM answers can cite documents reliably.
+type Citation struct {
+	// The ID of the document.
+	DocumentID string
+	SourceRef
+}
+
+// citationFromMetadata returns the citation of a document, or nil if its
+// metadata contains neither a source URI nor a blob. Invalid page numbers and offsets are
+// ignored.
+func citationFromMetadata(id string, metadata map[string]string) *Citation {
+	uri := metadata[MetadataKeySourceURI]
+	for _, k := range citationURIFallbackKeys {
+		if uri != "" {
+			break
+		}
+		uri = metadata[k]
+	}
+	blob := metadata[MetadataKeySourceBlob]
+	if uri == "" && blob == "" {
+		return nil
+	}
+
+	citation := &Citation{
+		DocumentID: id,
+		SourceRef:  SourceRef{URI: uri, Blob: blob},
+	}
+	if page, err := strconv.Atoi(metadata[MetadataKeySourcePage]); err == nil && page > 0 {
+		citation.Page = page
+	}
+	start, err1 := strconv.Atoi(metadata[MetadataKeySourceStart])
+	end, err2 := strconv.Atoi(metadata[MetadataKeySourceEnd])
+	if err1 == nil && err2 == nil && start >= 0 && end > start {
+		citation.Start = start
+		citation.End = end
+	}
+	return citation
+}
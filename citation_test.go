@@ -0,0 +1,63 @@
+package chromem
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSourceRef_AddToMetadata(t *testing.T) {
+	metadata := SourceRef{URI: "file.pdf", Page: 3, Start: 10, End: 20}.AddToMetadata(map[string]string{"foo": "bar"})
+	expected := map[string]string{
+		"foo":                  "bar",
+		MetadataKeySourceURI:   "file.pdf",
+		MetadataKeySourcePage:  "3",
+		MetadataKeySourceStart: "10",
+		MetadataKeySourceEnd:   "20",
+	}
+	if !reflect.DeepEqual(expected, metadata) {
+		t.Fatalf("expected %v, got %v", expected, metadata)
+	}
+
+	metadata = SourceRef{Blob: "abc"}.AddToMetadata(nil)
+	expected = map[string]string{MetadataKeySourceBlob: "abc"}
+	if !reflect.DeepEqual(expected, metadata) {
+		t.Fatalf("expected %v, got %v", expected, metadata)
+	}
+}
+
+func TestCollection_Query_Citation(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "ref", Content: "a", Metadata: SourceRef{URI: "file.pdf", Page: 2, Start: 5, End: 6}.AddToMetadata(nil)},
+		{ID: "url", Content: "b", Metadata: map[string]string{"url": "https://example.com", MetadataKeySourcePage: "x"}},
+		{ID: "none", Content: "c", Metadata: map[string]string{"foo": "bar"}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	res, err := c.Query(ctx, "q", 3, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	citations := make(map[string]*Citation)
+	for _, r := range res {
+		citations[r.ID] = r.Citation
+	}
+	expected := map[string]*Citation{
+		"ref":  {DocumentID: "ref", SourceRef: SourceRef{URI: "file.pdf", Page: 2, Start: 5, End: 6}},
+		"url":  {DocumentID: "url", SourceRef: SourceRef{URI: "https://example.com"}},
+		"none": nil,
+	}
+	if !reflect.DeepEqual(expected, citations) {
+		t.Fatalf("expected %+v, got %+v", expected, citations)
+	}
+}
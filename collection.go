@@ -4,24 +4,86 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ErrDocumentNotFound is returned when a document doesn't exist.
+var ErrDocumentNotFound = errors.New("document not found")
+
 // Collection represents a collection of documents.
 // It also has a configured embedding function, which is used when adding documents
 // that don't have embeddings yet.
 type Collection struct {
 	Name string
 
+	// Guarded by configLock. It's replaced instead of modified when it's changed,
+	// so it can be read without copying.
 	metadata      map[string]string
 	documents     map[string]*Document
-	documentsLock sync.RWMutex
+	documentsLock documentsMutex
 	embed         EmbeddingFunc
 
+	// Set via setter, so it's guarded by configLock.
+	embedMultimodal EmbeddingFuncMultimodal
+	// See [Collection.SetPreprocessing]. Guarded by configLock.
+	preprocessing PreprocessingOptions
+	// See [Collection.SetSummaryIndexing]. Guarded by configLock.
+	summaryIndexing SummaryIndexing
+	// See [Collection.SetSessionCache]. Nil if it's disabled. Guarded by
+	// configLock.
+	sessionCache *sessionCache
+
+	// The DB the collection belongs to, for its memory budget. Can be nil.
+	db *DB
+	// Estimated memory usage of the documents in bytes, see [MemoryUsage].
+	memoryUsage atomic.Int64
+	// Cache of document contents when they're spilled to disk, nil otherwise.
+	// Guarded by documentsLock.
+	contentCache *lruCache[string, string]
+	// Soft deleted documents, see [Collection.SetSoftDelete]. Guarded by
+	// documentsLock.
+	trash map[string]*trashedDocument
+	// Recent queries and feedback, see [Collection.SetFeedbackBoost]. Can be
+	// nil.
+	feedback *feedbackStore
+	// Metrics of the retrieval variants, see [Collection.SetRetrievalVariants].
+	// Can be nil.
+	variantStats *variantStatsStore
+	// See [Collection.SetAccessStats]. Shared with snapshots.
+	accessStats *accessStatsStore
+	// The vector index, see [Collection.SetIndex]. Nil without index. Guarded
+	// by documentsLock.
+	index vectorIndex
+	// Serializes builds of the index, so they're swapped in in order.
+	indexBuildLock sync.Mutex
+	// See [Collection.PartitionBy]. Nil if the collection isn't partitioned.
+	// Guarded by documentsLock.
+	partitions *partitions
+	// See [Collection.Archive]. Nil if no documents were archived yet. Guarded
+	// by documentsLock.
+	archive *archive
+
+	// Runtime configuration, which is persisted with the metadata.
+	config     collectionConfig
+	configLock sync.RWMutex
+	// Serializes writes of the metadata file.
+	persistMetadataLock sync.Mutex
+
 	persistDirectory string
 	compress         bool
+	// Syncs the written files according to the DB's durability level.
+	syncer *fileSyncer
+	// The segment files the documents are persisted in. Nil if they're
+	// persisted in one file per document.
+	segments *segmentStore
 
 	// ⚠️ When adding fields here, consider adding them to the persistence struct
 	// versions in [DB.Export] and [DB.Import] as well!
@@ -57,6 +119,13 @@ type QueryOptions struct {
 	// If both QueryText and QueryEmbedding are set, QueryEmbedding will be used.
 	QueryEmbedding []float32
 
+	// The image (or other media) to search for. Its embedding is created with the
+	// collection's multimodal embedding function, see
+	// [Collection.SetEmbeddingFuncMultimodal].
+	// If QueryEmbedding is set as well, QueryEmbedding will be used. If QueryText
+	// is set as well, QueryMedia will be used.
+	QueryMedia *Media
+
 	// The number of results to return.
 	NResults int
 
@@ -66,9 +135,89 @@ type QueryOptions struct {
 	// Conditional filtering on documents.
 	WhereDocument map[string]string
 
+	// A compiled filter, as alternative to Where and WhereDocument, which can't
+	// be set then. See [CompileFilter].
+	Filter *Filter
+
 	// Negative is the negative query options.
 	// They can be used to exclude certain results from the query.
 	Negative NegativeQueryOptions
+
+	// Concepts are weighted texts or embeddings that are combined with the query
+	// into the final query vector: q = v0 + w1·v1 + w2·v2 + ..., normalized,
+	// where v0 is the query's embedding (if any) and all vectors are normalized.
+	// Concepts with a negative weight are subtracted, so this allows queries
+	// like "like this, but not about X" with more control than the Negative
+	// options. If Concepts are set, QueryText, QueryEmbedding and QueryMedia
+	// are optional.
+	Concepts []QueryConcept
+
+	// SnippetSize enables snippets: if it's > 0, the Content of the results is
+	// replaced by windows of about SnippetSize bytes around each match of the
+	// WhereDocument "$contains" operators, separated by "…". This reduces the
+	// payload size and is suitable for previews in UIs. The Highlights of the
+	// results then refer to the snippet. Without matches, the snippet is the
+	// start of the content.
+	SnippetSize int
+
+	// DedupeBy is a metadata key by which the results are deduplicated. If it's
+	// set, only the most similar document per value of the key is returned, for
+	// example only the best chunk per "source_url", instead of multiple chunks of
+	// the same page. Documents without the key aren't deduplicated.
+	DedupeBy string
+
+	// Limits of the resources the query can use. Optional. When a limit is
+	// reached, the query returns partial results instead of failing, see
+	// [Collection.QueryWithStats].
+	Limits QueryLimits
+
+	// The algorithm that selects the most similar documents. Optional, defaults
+	// to [TopKAuto], which selects it based on NResults relative to the number
+	// of documents.
+	TopKAlgorithm TopKAlgorithm
+
+	// The name of the retrieval variant whose settings are used instead of the
+	// collection's, for A/B experiments. Optional. See
+	// [Collection.SetRetrievalVariants].
+	Variant string
+
+	// Exhaustive makes the query scan all documents, even if the collection has
+	// a vector index (see [Collection.SetIndex]), for exact results.
+	Exhaustive bool
+
+	// SameLanguage restricts the query to documents in the language of the
+	// query text, if it can be detected. Requires the collection's language
+	// detection, see [Collection.SetLanguageDetection].
+	SameLanguage bool
+
+	// HyDE additionally ranks the documents by their similarity to a
+	// hypothetical answer to the query text, and fuses both rankings with
+	// reciprocal rank fusion. This helps when queries are short or phrased
+	// differently than the documents, for example questions. Requires
+	// QueryText. Not supported by [Collection.QueryStream].
+	HyDE HyDEOptions
+
+	// IncludeArchived makes the query scan the archived documents as well, see
+	// [Collection.Archive]. The archive is read from disk, so this is slower,
+	// and writes are blocked while it's read. NResults can then exceed the
+	// number of documents in memory. Not supported by [Collection.QueryStream].
+	IncludeArchived bool
+}
+
+// QueryConcept is a weighted text or embedding for [QueryOptions.Concepts].
+type QueryConcept struct {
+	// The text whose embedding is used. It's created like the embedding of a
+	// query text.
+	Text string
+
+	// The embedding to use. It must be created with the same embedding model
+	// as the document embeddings in the collection.
+	// If both Text and Embedding are set, Embedding will be used.
+	Embedding []float32
+
+	// The weight of the concept. Negative weights subtract the concept from the
+	// query. Must not be 0.
+	Weight float32
 }
 
 type NegativeQueryOptions struct {
@@ -90,7 +239,7 @@ type NegativeQueryOptions struct {
 
 // We don't export this yet to keep the API surface to the bare minimum.
 // Users create collections via [Client.CreateCollection].
-func newCollection(name string, metadata map[string]string, embed EmbeddingFunc, dbDir string, compress bool) (*Collection, error) {
+func newCollection(name string, metadata map[string]string, embed EmbeddingFunc, config collectionConfig, dbDir string, compress bool, syncer *fileSyncer) (*Collection, error) {
 	// We copy the metadata to avoid data races in case the caller modifies the
 	// map after creating the collection while we range over it.
 	m := make(map[string]string, len(metadata))
@@ -101,32 +250,25 @@ func newCollection(name string, metadata map[string]string, embed EmbeddingFunc,
 	c := &Collection{
 		Name: name,
 
-		metadata:  m,
-		documents: make(map[string]*Document),
-		embed:     embed,
+		metadata:     m,
+		documents:    make(map[string]*Document),
+		embed:        embed,
+		config:       config,
+		feedback:     newFeedbackStore(),
+		accessStats:  &accessStatsStore{},
+		variantStats: newVariantStatsStore(),
 	}
 
 	// Persistence
 	if dbDir != "" {
-		safeName := hash2hex(name)
+		safeName := EncodePathName(name)
 		c.persistDirectory = filepath.Join(dbDir, safeName)
 		c.compress = compress
-		// Persist name and metadata
-		metadataPath := filepath.Join(c.persistDirectory, metadataFileName)
-		metadataPath += ".gob"
-		if c.compress {
-			metadataPath += ".gz"
-		}
-		pc := struct {
-			Name     string
-			Metadata map[string]string
-		}{
-			Name:     name,
-			Metadata: m,
-		}
-		err := persistToFile(metadataPath, pc, compress, "")
+		c.syncer = syncer
+		// Persist name, metadata and config
+		err := c.persistMetadata()
 		if err != nil {
-			return nil, fmt.Errorf("couldn't persist collection metadata: %w", err)
+			return nil, err
 		}
 	}
 
@@ -215,6 +357,8 @@ func (c *Collection) AddDocuments(ctx context.Context, documents []Document, con
 	}
 	// For other validations we rely on AddDocument.
 
+	ctx, progress := startProgress(ctx, "add", len(documents))
+
 	var sharedErr error
 	sharedErrLock := sync.Mutex{}
 	ctx, cancel := context.WithCancelCause(ctx)
@@ -230,12 +374,17 @@ func (c *Collection) AddDocuments(ctx context.Context, documents []Document, con
 		}
 	}
 
+	executor := c.executor()
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, concurrency)
 	for _, doc := range documents {
+		doc := doc
 		wg.Add(1)
-		go func(doc Document) {
+		executor.Submit(func() {
 			defer wg.Done()
+			defer recoverPanic(func(err error) {
+				setSharedErr(fmt.Errorf("couldn't add document '%s': %w", doc.ID, err))
+			})
 
 			// Don't even start if another goroutine already failed.
 			if ctx.Err() != nil {
@@ -251,7 +400,8 @@ func (c *Collection) AddDocuments(ctx context.Context, documents []Document, con
 				setSharedErr(fmt.Errorf("couldn't add document '%s': %w", doc.ID, err))
 				return
 			}
-		}(doc)
+			progress.add(1)
+		})
 	}
 
 	wg.Wait()
@@ -263,13 +413,118 @@ func (c *Collection) AddDocuments(ctx context.Context, documents []Document, con
 // If the document doesn't have an embedding, it will be created using the collection's
 // embedding function.
 func (c *Collection) AddDocument(ctx context.Context, doc Document) error {
-	if doc.ID == "" {
-		return errors.New("document ID is empty")
+	if err := c.db.checkOpen(); err != nil {
+		return err
+	}
+	config := c.getConfig()
+	doc, err := c.preprocessDocument(doc)
+	if err != nil {
+		return err
+	}
+	if err := c.checkDocument(doc, config); err != nil {
+		return err
+	}
+	// Fail early instead of after creating the embedding. It's checked again
+	// when the document is stored.
+	c.documentsLock.RLock()
+	err = c.checkDocumentsLocked(config.Limits, doc.ID)
+	c.documentsLock.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	doc, err = c.prepareDocument(ctx, doc, config)
+	if err != nil {
+		return err
+	}
+
+	// Check the memory budget. This is done before locking the documents, as it
+	// needs to lock the DB's collections.
+	usage := documentMemoryUsage(&doc).Total()
+	evictBytes, err := c.checkMemoryBudget(usage)
+	if err != nil {
+		return fmt.Errorf("couldn't add document %q: %w", doc.ID, err)
+	}
+
+	c.documentsLock.Lock()
+	// We don't defer the unlock because we want to do it earlier.
+	// The dimension must be checked while holding the lock, so that concurrently
+	// added documents can't establish different dimensions.
+	if dim := c.dimensionLocked(doc.ID); dim != 0 && dim != len(doc.Embedding) {
+		c.documentsLock.Unlock()
+		return &DimensionMismatchError{DocumentID: doc.ID, Expected: dim, Actual: len(doc.Embedding)}
+	}
+	if err := c.checkDocumentsLocked(config.Limits, doc.ID); err != nil {
+		c.documentsLock.Unlock()
+		return err
+	}
+	var evicted []string
+	if evictBytes > 0 {
+		evicted, err = c.evictLocked(evictBytes, doc.ID, config.AccessStats)
+		if err != nil {
+			c.documentsLock.Unlock()
+			return fmt.Errorf("couldn't add document %q: %w", doc.ID, err)
+		}
+	}
+	stored := &doc
+	if c.contentCache != nil {
+		withoutContent := doc
+		withoutContent.Content = ""
+		stored = &withoutContent
+		c.contentCache.add(doc.ID, doc.Content)
+		usage = documentMemoryUsage(stored).Total()
+	}
+	action := AuditActionAdd
+	if old, ok := c.documents[doc.ID]; ok {
+		usage -= documentMemoryUsage(old).Total()
+		action = AuditActionUpdate
+	}
+	c.documents[doc.ID] = stored
+	c.indexDocumentLocked(stored)
+	c.memoryUsage.Add(usage)
+	c.documentsLock.Unlock()
+	if action == AuditActionAdd && len(evicted) == 0 {
+		c.added(ctx)
+	} else {
+		// Updates and evictions change the results of all sessions.
+		c.changed()
+	}
+
+	// Remove evicted documents from disk
+	for _, id := range evicted {
+		err := c.removePersistedDocument(id)
+		if err != nil {
+			return fmt.Errorf("couldn't remove evicted document: %w", err)
+		}
 	}
-	if len(doc.Embedding) == 0 && doc.Content == "" {
-		return errors.New("either document embedding or content must be filled")
+
+	// Persist the document
+	err = c.persistDocument(&doc)
+	if err != nil {
+		return err
 	}
 
+	if err := c.auditSystem(ctx, AuditActionEvict, evicted...); err != nil {
+		return err
+	}
+	return c.audit(ctx, action, doc.ID)
+}
+
+// checkDocument returns an error if the document is invalid or doesn't match
+// the collection's metadata schema or limits.
+func (c *Collection) checkDocument(doc Document, config collectionConfig) error {
+	if err := ValidateDocument(doc); err != nil {
+		return err
+	}
+	if err := config.MetadataSchema.validate(doc.ID, doc.Metadata); err != nil {
+		return err
+	}
+	return config.Limits.checkDocument(&doc)
+}
+
+// prepareDocument creates the embedding of the checked document if it doesn't
+// have one, or validates and normalizes it if necessary.
+func (c *Collection) prepareDocument(ctx context.Context, doc Document, config collectionConfig) (Document, error) {
 	// We copy the metadata to avoid data races in case the caller modifies the
 	// map after creating the document while we range over it.
 	m := make(map[string]string, len(doc.Metadata))
@@ -277,55 +532,77 @@ func (c *Collection) AddDocument(ctx context.Context, doc Document) error {
 		m[k] = v
 	}
 
+	// Store the detected language, unless it's known already
+	if key := config.LanguageMetadataKey; key != "" {
+		if _, ok := m[key]; !ok {
+			if language := DetectLanguage(doc.Content); language != "" {
+				m[key] = language
+				doc.Metadata = m
+			}
+		}
+	}
+
 	// Create embedding if they don't exist, otherwise normalize if necessary
-	if len(doc.Embedding) == 0 {
-		embedding, err := c.embed(ctx, doc.Content)
+	if len(doc.Embedding) == 0 && doc.Media != nil {
+		embedding, err := c.embedMedia(ctx, doc.Media)
 		if err != nil {
-			return fmt.Errorf("couldn't create embedding of document: %w", err)
+			return Document{}, fmt.Errorf("couldn't create embedding of document media: %w", err)
 		}
 		doc.Embedding = embedding
-	} else {
-		if !isNormalized(doc.Embedding) {
-			doc.Embedding = normalizeVector(doc.Embedding)
+	} else if len(doc.Embedding) == 0 {
+		text := doc.Content
+		summary, key, err := c.summarize(ctx, doc.Content)
+		if err != nil {
+			return Document{}, fmt.Errorf("couldn't summarize document: %w", err)
 		}
-	}
-
-	c.documentsLock.Lock()
-	// We don't defer the unlock because we want to do it earlier.
-	c.documents[doc.ID] = &doc
-	c.documentsLock.Unlock()
-
-	// Persist the document
-	if c.persistDirectory != "" {
-		docPath := c.getDocPath(doc.ID)
-		err := persistToFile(docPath, doc, c.compress, "")
+		if summary != "" {
+			text = summary
+			if key != "" {
+				m[key] = summary
+				doc.Metadata = m
+			}
+		}
+		embedding, err := c.embedDocument(ctx, text)
 		if err != nil {
-			return fmt.Errorf("couldn't persist document to %q: %w", docPath, err)
+			return Document{}, fmt.Errorf("couldn't create embedding of document: %w", err)
 		}
+		doc.Embedding = embedding
+	} else {
+		embedding, err := validateEmbedding(doc.Embedding, config.NormalizationPolicy)
+		if err != nil {
+			return Document{}, fmt.Errorf("invalid embedding of document %q: %w", doc.ID, err)
+		}
+		doc.Embedding = embedding
 	}
-
-	return nil
+	return doc, nil
 }
 
-// Delete removes document(s) from the collection.
+// Delete removes document(s) from the collection, including archived ones,
+// see [Collection.Archive].
+// With soft deletes, the documents are moved to the trash instead, see
+// [Collection.SetSoftDelete].
 //
 //   - where: Conditional filtering on metadata. Optional.
 //   - whereDocument: Conditional filtering on documents. Optional.
 //   - ids: The ids of the documents to delete. If empty, all documents are deleted.
-func (c *Collection) Delete(_ context.Context, where, whereDocument map[string]string, ids ...string) error {
+func (c *Collection) Delete(ctx context.Context, where, whereDocument map[string]string, ids ...string) error {
+	return c.deleteDocuments(ctx, AuditActionDelete, where, whereDocument, ids...)
+}
+
+// deleteDocuments is like [Collection.Delete], but records the deletion with
+// the given action in the audit log.
+func (c *Collection) deleteDocuments(ctx context.Context, action AuditAction, where, whereDocument map[string]string, ids ...string) error {
+	if err := c.db.checkOpen(); err != nil {
+		return err
+	}
 	// must have at least one of where, whereDocument or ids
 	if len(where) == 0 && len(whereDocument) == 0 && len(ids) == 0 {
 		return fmt.Errorf("must have at least one of where, whereDocument or ids")
 	}
 
-	if len(c.documents) == 0 {
-		return nil
-	}
-
-	for k := range whereDocument {
-		if !slices.Contains(supportedFilters, k) {
-			return errors.New("unsupported whereDocument operator")
-		}
+	filter, err := CompileFilter(where, whereDocument)
+	if err != nil {
+		return err
 	}
 
 	var docIDs []string
@@ -333,9 +610,19 @@ func (c *Collection) Delete(_ context.Context, where, whereDocument map[string]s
 	c.documentsLock.Lock()
 	defer c.documentsLock.Unlock()
 
+	hasArchived := c.archive != nil && len(c.archive.Segments) != 0
+	if len(c.documents) == 0 && !hasArchived {
+		return nil
+	}
+
+	var archiveFilters []*Filter
 	if where != nil || whereDocument != nil {
+		archiveFilters = append(archiveFilters, filter)
 		// metadata + content filters
-		filteredDocs := filterDocs(c.documents, where, whereDocument)
+		filteredDocs, err := c.filterDocsLocked(filter)
+		if err != nil {
+			return err
+		}
 		for _, doc := range filteredDocs {
 			docIDs = append(docIDs, doc.ID)
 		}
@@ -344,24 +631,54 @@ func (c *Collection) Delete(_ context.Context, where, whereDocument map[string]s
 	}
 
 	// No-op if no docs are left
-	if len(docIDs) == 0 {
+	if len(docIDs) == 0 && !hasArchived {
 		return nil
 	}
+	// Also when deleting some of the documents fails.
+	defer c.changed()
 
+	softDelete := c.getConfig().SoftDeletePurgeAfter != 0
+	var deleted []string
 	for _, docID := range docIDs {
+		if doc, ok := c.documents[docID]; ok {
+			deleted = append(deleted, docID)
+			if softDelete {
+				err := c.trashLocked(doc)
+				if err != nil {
+					return fmt.Errorf("couldn't move document %q to trash: %w", docID, err)
+				}
+			}
+			c.memoryUsage.Add(-documentMemoryUsage(doc).Total())
+		}
 		delete(c.documents, docID)
+		c.unindexDocumentLocked(docID)
+		if c.contentCache != nil {
+			c.contentCache.remove(docID)
+		}
 
 		// Remove the document from disk
-		if c.persistDirectory != "" {
-			docPath := c.getDocPath(docID)
-			err := removeFile(docPath)
-			if err != nil {
-				return fmt.Errorf("couldn't remove document at %q: %w", docPath, err)
-			}
+		err := c.removePersistedDocument(docID)
+		if err != nil {
+			return err
 		}
 	}
 
-	return nil
+	// Archived documents with the same IDs as deleted ones are removed as
+	// well, so they don't reappear.
+	archived, err := c.deleteArchivedLocked(docIDs, archiveFilters...)
+	if err != nil {
+		return fmt.Errorf("couldn't delete archived documents: %w", err)
+	}
+	deleted = appendMissing(deleted, archived)
+
+	if c.trash != nil {
+		_, err := c.purgeTrashLocked(ctx)
+		if err != nil {
+			return fmt.Errorf("couldn't purge trash: %w", err)
+		}
+	}
+
+	return c.audit(ctx, action, deleted...)
 }
 
 // Count returns the number of documents in the collection.
@@ -377,11 +694,60 @@ type Result struct {
 	Metadata  map[string]string
 	Embedding []float32
 	Content   string
+	Media     *Media
 
 	// The cosine similarity between the query and the document.
 	// The higher the value, the more similar the document is to the query.
-	// The value is in the range [-1, 1].
+	// The value is in the range [-1, 1], unless the collection has boost rules
+	// (see [Collection.SetBoostRules]), in which case it includes the boost.
 	Similarity float32
+
+	// The position of the result, starting at 1 for the most similar document.
+	Rank int
+	// The name of the collection the document is from, which is useful when
+	// results of multiple collections are combined.
+	Collection string
+	// The positions of the substrings of Content that matched the "$contains"
+	// operators of the whereDocument filter, sorted by position. Empty if there
+	// were no such operators.
+	Highlights []Highlight
+	// The source of the document, if its metadata contains a source reference
+	// (see [MetadataKeySourceURI]). Nil otherwise.
+	Citation *Citation
+}
+
+// Highlight is the position of a matched substring in a document's content, as
+// byte offsets, so the substring is Content[Start:End].
+type Highlight struct {
+	Start int
+	End   int
+}
+
+// findHighlights returns the positions of all non-overlapping occurrences of the
+// "$contains" values of the filter in the content, sorted by position.
+func findHighlights(content string, filter *Filter) []Highlight {
+	if filter == nil {
+		return nil
+	}
+	var highlights []Highlight
+	for _, v := range filter.contains {
+		if v == "" {
+			continue
+		}
+		for offset := 0; ; {
+			i := strings.Index(content[offset:], v)
+			if i == -1 {
+				break
+			}
+			start := offset + i
+			highlights = append(highlights, Highlight{Start: start, End: start + len(v)})
+			offset = start + len(v)
+		}
+	}
+	slices.SortFunc(highlights, func(a, b Highlight) int {
+		return a.Start - b.Start
+	})
+	return highlights
 }
 
 // Query performs an exhaustive nearest neighbor search on the collection.
@@ -397,37 +763,115 @@ func (c *Collection) Query(ctx context.Context, queryText string, nResults int,
 		return nil, errors.New("queryText is empty")
 	}
 
-	queryVector, err := c.embed(ctx, queryText)
+	// Cached like the equivalent QueryWithOptions call.
+	res, _, ok, cached := c.getSessionCache().lookup(SessionFromContext(ctx), QueryOptions{
+		QueryText:     queryText,
+		NResults:      nResults,
+		Where:         where,
+		WhereDocument: whereDocument,
+	})
+	if ok {
+		c.recordAccess(res)
+		return res, nil
+	}
+
+	queryVector, err := c.embedQuery(ctx, queryText)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create embedding of query: %w", err)
 	}
 
-	return c.QueryEmbedding(ctx, queryVector, nResults, where, whereDocument)
+	filter, err := CompileFilter(where, whereDocument)
+	if err != nil {
+		return nil, err
+	}
+	res, stats, err := c.queryEmbedding(ctx, queryVector, nil, 0, nResults, filter, "", TopKAuto, nil, false, queryLimits{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	stats.QueryID = c.recordQuery(ctx, nil, queryText, queryVector, where, whereDocument, nResults, res)
+	c.recordAccess(res)
+	cached.add(res, stats)
+	return res, nil
 }
 
 // QueryWithOptions performs an exhaustive nearest neighbor search on the collection.
 //
 //   - options: The options for the query. See QueryOptions for more information.
+//
+// If the query reaches one of the [QueryOptions.Limits], the results are
+// partial. Use [Collection.QueryWithStats] to find out whether that's the case.
 func (c *Collection) QueryWithOptions(ctx context.Context, options QueryOptions) ([]Result, error) {
-	if options.QueryText == "" && len(options.QueryEmbedding) == 0 {
-		return nil, errors.New("QueryText and QueryEmbedding options are empty")
-	}
+	res, _, err := c.QueryWithStats(ctx, options)
+	return res, err
+}
 
-	var err error
-	queryVector := options.QueryEmbedding
-	if len(queryVector) == 0 {
-		queryVector, err = c.embed(ctx, options.QueryText)
+// QueryWithStats is like [Collection.QueryWithOptions], but it also returns
+// statistics of the query, including whether the results were truncated due to
+// the [QueryOptions.Limits].
+func (c *Collection) QueryWithStats(ctx context.Context, options QueryOptions) ([]Result, QueryStats, error) {
+	return c.queryWithStats(ctx, options, nil)
+}
+
+// queryWithStats implements [Collection.QueryWithStats]. If the stream isn't
+// nil, candidates are passed to it during the scan.
+func (c *Collection) queryWithStats(ctx context.Context, options QueryOptions, stream *candidateStream) ([]Result, QueryStats, error) {
+	if err := validateQueryOptions(options); err != nil {
+		return nil, QueryStats{}, err
+	}
+	if stream != nil && options.HyDE.Generate != nil {
+		return nil, QueryStats{}, errors.New("HyDE isn't supported for streamed queries")
+	}
+	if stream != nil && options.IncludeArchived {
+		return nil, QueryStats{}, errors.New("archived documents aren't supported for streamed queries")
+	}
+	var cached *sessionCacheLookup
+	if stream == nil {
+		res, stats, ok, lookup := c.getSessionCache().lookup(SessionFromContext(ctx), options)
+		if ok {
+			c.recordAccess(res)
+			return res, stats, nil
+		}
+		cached = lookup
+	}
+	start := time.Now()
+	limits, err := options.Limits.start(start)
+	if err != nil {
+		return nil, QueryStats{}, err
+	}
+	variant, err := c.retrievalVariant(options.Variant)
+	if err != nil {
+		return nil, QueryStats{}, err
+	}
+	topKAlgorithm := options.TopKAlgorithm
+	if variant != nil && topKAlgorithm == TopKAuto {
+		topKAlgorithm = variant.TopKAlgorithm
+	}
+	filter := options.Filter
+	if filter == nil {
+		filter, err = CompileFilter(options.Where, options.WhereDocument)
 		if err != nil {
-			return nil, fmt.Errorf("couldn't create embedding of query: %w", err)
+			return nil, QueryStats{}, err
+		}
+	}
+	if options.SameLanguage {
+		filter, err = c.sameLanguageFilter(filter, options.QueryText)
+		if err != nil {
+			return nil, QueryStats{}, err
 		}
 	}
 
+	queryVector, err := c.queryVector(ctx, options)
+	if err != nil {
+		return nil, QueryStats{}, err
+	}
+	loggedVector := queryVector
+
 	negativeFilterThreshold := options.Negative.FilterThreshold
 	negativeVector := options.Negative.Embedding
 	if len(negativeVector) == 0 && options.Negative.Text != "" {
-		negativeVector, err = c.embed(ctx, options.Negative.Text)
+		negativeVector, err = c.embedQuery(ctx, options.Negative.Text)
 		if err != nil {
-			return nil, fmt.Errorf("couldn't create embedding of negative: %w", err)
+			return nil, QueryStats{}, fmt.Errorf("couldn't create embedding of negative: %w", err)
 		}
 	}
 
@@ -444,16 +888,144 @@ func (c *Collection) QueryWithOptions(ctx context.Context, options QueryOptions)
 				negativeFilterThreshold = DEFAULT_NEGATIVE_FILTER_THRESHOLD
 			}
 		} else {
-			return nil, fmt.Errorf("unsupported negative mode: %q", options.Negative.Mode)
+			return nil, QueryStats{}, fmt.Errorf("unsupported negative mode: %q", options.Negative.Mode)
 		}
 	}
 
-	result, err := c.queryEmbedding(ctx, queryVector, negativeVector, negativeFilterThreshold, options.NResults, options.Where, options.WhereDocument)
+	query := func(vector []float32) ([]Result, QueryStats, error) {
+		if !options.IncludeArchived {
+			return c.queryEmbedding(ctx, vector, negativeVector, negativeFilterThreshold, options.NResults, filter, options.DedupeBy, topKAlgorithm, variant, options.Exhaustive, limits, stream)
+		}
+		queryEmbedding := func(c *Collection, nResults int) ([]Result, QueryStats, error) {
+			return c.queryEmbedding(ctx, vector, negativeVector, negativeFilterThreshold, nResults, filter, options.DedupeBy, topKAlgorithm, variant, options.Exhaustive, limits, nil)
+		}
+		var results []Result
+		var stats QueryStats
+		if nResults := min(options.NResults, c.Count()); nResults > 0 {
+			var err error
+			results, stats, err = queryEmbedding(c, nResults)
+			if err != nil {
+				return nil, QueryStats{}, err
+			}
+		}
+		return c.queryArchived(options.NResults, results, stats, queryEmbedding)
+	}
+	result, stats, err := query(queryVector)
 	if err != nil {
-		return nil, err
+		return nil, QueryStats{}, err
+	}
+	if options.HyDE.Generate != nil {
+		var hydeStats QueryStats
+		result, hydeStats, err = c.hyde(ctx, options, result, func(vector []float32) ([]Result, QueryStats, error) {
+			if options.Negative.Mode == NEGATIVE_MODE_SUBTRACT && len(negativeVector) != 0 {
+				vector = normalizeVector(subtractVector(vector, negativeVector))
+			}
+			return query(vector)
+		})
+		if err != nil {
+			return nil, QueryStats{}, err
+		}
+		stats.DocumentsScanned += hydeStats.DocumentsScanned
+		stats.Truncated = stats.Truncated || hydeStats.Truncated
 	}
 
-	return result, nil
+	if options.SnippetSize > 0 {
+		for i := range result {
+			result[i].Content, result[i].Highlights = snippet(result[i].Content, result[i].Highlights, options.SnippetSize)
+		}
+	}
+
+	stats.QueryID = c.recordQuery(ctx, variant, options.QueryText, loggedVector, options.Where, options.WhereDocument, options.NResults, result)
+	if variant != nil {
+		c.variantStats.queryServed(variant.Name, stats.QueryID, stats.DocumentsScanned, time.Since(start))
+	}
+	c.recordAccess(result)
+	cached.add(result, stats)
+
+	return result, stats, nil
+}
+
+// queryVector returns the query vector of the options, from the query embedding,
+// media or text, combined with the concepts. Negatives aren't applied yet.
+func (c *Collection) queryVector(ctx context.Context, options QueryOptions) ([]float32, error) {
+	var err error
+	queryVector := options.QueryEmbedding
+	if len(queryVector) == 0 && options.QueryMedia != nil {
+		queryVector, err = c.embedMedia(ctx, options.QueryMedia)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create embedding of query media: %w", err)
+		}
+	} else if len(queryVector) == 0 && options.QueryText != "" {
+		queryVector, err = c.embedQuery(ctx, options.QueryText)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create embedding of query: %w", err)
+		}
+	}
+
+	if len(options.Concepts) != 0 {
+		queryVector, err = c.composeQueryVector(ctx, queryVector, options.Concepts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return queryVector, nil
+}
+
+// composeQueryVector combines the query vector (which can be empty) and the
+// weighted concepts into one normalized vector.
+func (c *Collection) composeQueryVector(ctx context.Context, queryVector []float32, concepts []QueryConcept) ([]float32, error) {
+	var res []float32
+	addVector := func(v []float32, weight float32) error {
+		if !isNormalized(v) {
+			v = normalizeVector(v)
+		}
+		if res == nil {
+			res = make([]float32, len(v))
+		} else if len(v) != len(res) {
+			return &DimensionMismatchError{Expected: len(res), Actual: len(v)}
+		}
+		for i, val := range v {
+			res[i] += weight * val
+		}
+		return nil
+	}
+
+	if len(queryVector) != 0 {
+		err := addVector(queryVector, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, concept := range concepts {
+		if concept.Weight == 0 {
+			return nil, fmt.Errorf("weight of concept %d is 0", i)
+		}
+		v := concept.Embedding
+		if len(v) == 0 {
+			if concept.Text == "" {
+				return nil, fmt.Errorf("text and embedding of concept %d are empty", i)
+			}
+			var err error
+			v, err = c.embedQuery(ctx, concept.Text)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't create embedding of concept %d: %w", i, err)
+			}
+		}
+		err := addVector(v, concept.Weight)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't add concept %d: %w", i, err)
+		}
+	}
+
+	var sqSum float64
+	for _, val := range res {
+		sqSum += float64(val) * float64(val)
+	}
+	if sqSum == 0 {
+		return nil, errors.New("query and concepts cancel each other out")
+	}
+	return normalizeVector(res), nil
 }
 
 // QueryEmbedding performs an exhaustive nearest neighbor search on the collection.
@@ -466,40 +1038,156 @@ func (c *Collection) QueryWithOptions(ctx context.Context, options QueryOptions)
 //   - where: Conditional filtering on metadata. Optional.
 //   - whereDocument: Conditional filtering on documents. Optional.
 func (c *Collection) QueryEmbedding(ctx context.Context, queryEmbedding []float32, nResults int, where, whereDocument map[string]string) ([]Result, error) {
-	return c.queryEmbedding(ctx, queryEmbedding, nil, 0, nResults, where, whereDocument)
+	filter, err := CompileFilter(where, whereDocument)
+	if err != nil {
+		return nil, err
+	}
+	res, _, err := c.queryEmbedding(ctx, queryEmbedding, nil, 0, nResults, filter, "", TopKAuto, nil, false, queryLimits{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.recordQuery(ctx, nil, "", queryEmbedding, where, whereDocument, nResults, res)
+	c.recordAccess(res)
+	return res, nil
 }
 
-// queryEmbedding performs an exhaustive nearest neighbor search on the collection.
-func (c *Collection) queryEmbedding(ctx context.Context, queryEmbedding, negativeEmbeddings []float32, negativeFilterThreshold float32, nResults int, where, whereDocument map[string]string) ([]Result, error) {
-	if len(queryEmbedding) == 0 {
-		return nil, errors.New("queryEmbedding is empty")
+// SimilarToDocument performs an exhaustive nearest neighbor search on the
+// collection, using the embedding of a stored document as query. The document
+// itself is excluded from the results. This is useful for "more like this"
+// recommendations, without creating an embedding again.
+//
+//   - docID: The ID of the document to find similar documents for.
+//   - nResults: The maximum number of results to return. Must be > 0 and less
+//     than the number of documents in the collection.
+//     There can be fewer results if a filter is applied.
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+func (c *Collection) SimilarToDocument(ctx context.Context, docID string, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	if docID == "" {
+		return nil, errors.New("docID is empty")
 	}
 	if nResults <= 0 {
 		return nil, errors.New("nResults must be > 0")
 	}
+
 	c.documentsLock.RLock()
-	defer c.documentsLock.RUnlock()
-	if nResults > len(c.documents) {
-		return nil, errors.New("nResults must be <= the number of documents in the collection")
+	doc, ok := c.documents[docID]
+	nDocs := len(c.documents)
+	c.documentsLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrDocumentNotFound, docID)
+	}
+	if nResults >= nDocs {
+		return nil, errors.New("nResults must be < the number of documents in the collection")
 	}
 
-	if len(c.documents) == 0 {
-		return nil, nil
+	filter, err := CompileFilter(where, whereDocument)
+	if err != nil {
+		return nil, err
+	}
+	// Query one more, as the document itself is usually among the results.
+	res, _, err := c.queryEmbedding(ctx, doc.Embedding, nil, 0, nResults+1, filter, "", TopKAuto, nil, false, queryLimits{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	res = slices.DeleteFunc(res, func(r Result) bool {
+		return r.ID == docID
+	})
+	if len(res) > nResults {
+		res = res[:nResults]
+	}
+	for i := range res {
+		res[i].Rank = i + 1
+	}
+	c.recordAccess(res)
+	return res, nil
+}
+
+// Centroid returns the mean embedding of the documents that match the filters,
+// normalized, so it can be used as query embedding with [Collection.QueryEmbedding]
+// or as negative embedding in [QueryOptions]. It's useful for example to build
+// prototypes of classes or topics.
+// The where and whereDocument filters work like in [Collection.Query]. If both
+// are nil, the centroid of all documents is returned.
+func (c *Collection) Centroid(ctx context.Context, where, whereDocument map[string]string) ([]float32, error) {
+	filter, err := CompileFilter(where, whereDocument)
+	if err != nil {
+		return nil, err
+	}
+	c.documentsLock.RLock()
+	docs, err := c.filterDocsLocked(filter)
+	c.documentsLock.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't filter documents: %w", err)
+	}
+	if len(docs) == 0 {
+		return nil, errors.New("no documents match the filters")
 	}
 
-	// Validate whereDocument operators
-	for k := range whereDocument {
-		if !slices.Contains(supportedFilters, k) {
-			return nil, errors.New("unsupported operator")
+	// Sum up in float64 to limit rounding errors with many documents.
+	sum := make([]float64, len(docs[0].Embedding))
+	for i, doc := range docs {
+		if i%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if len(doc.Embedding) != len(sum) {
+			return nil, &DimensionMismatchError{DocumentID: doc.ID, Expected: len(sum), Actual: len(doc.Embedding)}
+		}
+		for j, val := range doc.Embedding {
+			sum[j] += float64(val)
 		}
 	}
 
-	// Filter docs by metadata and content
-	filteredDocs := filterDocs(c.documents, where, whereDocument)
+	var sqSum float64
+	for _, val := range sum {
+		sqSum += val * val
+	}
+	if sqSum == 0 {
+		return nil, errors.New("embeddings of the documents cancel each other out")
+	}
+	magnitude := math.Sqrt(sqSum)
+	centroid := make([]float32, len(sum))
+	for j, val := range sum {
+		centroid[j] = float32(val / magnitude)
+	}
+	return centroid, nil
+}
 
-	// No need to continue if the filters got rid of all documents
-	if len(filteredDocs) == 0 {
-		return nil, nil
+// queryEmbedding performs a nearest neighbor search on the collection, which is
+// exhaustive unless the collection has a vector index and exhaustive is false.
+// The results are truncated when a limit is reached. If the stream isn't nil,
+// candidates are passed to it during the scan.
+func (c *Collection) queryEmbedding(ctx context.Context, queryEmbedding, negativeEmbeddings []float32, negativeFilterThreshold float32, nResults int, filter *Filter, dedupeBy string, topKAlgorithm TopKAlgorithm, variant *RetrievalVariant, exhaustive bool, limits queryLimits, stream *candidateStream) ([]Result, QueryStats, error) {
+	if len(queryEmbedding) == 0 {
+		return nil, QueryStats{}, errors.New("queryEmbedding is empty")
+	}
+	if nResults <= 0 {
+		return nil, QueryStats{}, errors.New("nResults must be > 0")
+	}
+	// The documents are only locked to get them, and to get the contents of
+	// the results, but not while scanning them, see documentsMutex.
+	c.documentsLock.RLock()
+	docs := c.documentsSliceLocked()
+	dim := c.dimensionLocked("")
+	filterContents := c.contentCache != nil && filter.hasContentConditions()
+	c.documentsLock.RUnlock()
+	if nResults > len(docs) {
+		return nil, QueryStats{}, errors.New("nResults must be <= the number of documents in the collection")
+	}
+
+	if len(docs) == 0 {
+		return nil, QueryStats{}, nil
+	}
+
+	// Check the dimensions, so that a query embedding created with a different
+	// model leads to a helpful error.
+	if len(queryEmbedding) != dim {
+		return nil, QueryStats{}, &DimensionMismatchError{Expected: dim, Actual: len(queryEmbedding)}
+	}
+	if len(negativeEmbeddings) != 0 && len(negativeEmbeddings) != dim {
+		return nil, QueryStats{}, &DimensionMismatchError{Expected: dim, Actual: len(negativeEmbeddings)}
 	}
 
 	// Normalize embedding if not the case yet. We only support cosine similarity
@@ -508,38 +1196,326 @@ func (c *Collection) queryEmbedding(ctx context.Context, queryEmbedding, negativ
 		queryEmbedding = normalizeVector(queryEmbedding)
 	}
 
-	// If the filtering already reduced the number of documents to fewer than nResults,
-	// we only need to find the most similar docs among the filtered ones.
-	resLen := nResults
-	if len(filteredDocs) < nResults {
-		resLen = len(filteredDocs)
+	// With partitions, only the documents of the partition the filter
+	// restricts the query to are scanned, and with a vector index, only the
+	// index's candidates for the query. The content filters of spilled over
+	// contents are applied to all documents anyway.
+	if !filterContents {
+		c.documentsLock.RLock()
+		if candidates, ok := c.partitions.candidates(filter, c.documents); ok {
+			docs = candidates
+		} else if !exhaustive && c.index != nil {
+			if candidates, ok := c.index.candidates(queryEmbedding, c.documents); ok && len(candidates) != 0 {
+				docs = candidates
+			}
+		}
+		c.documentsLock.RUnlock()
 	}
 
-	// For the remaining documents, get the most similar docs.
-	nMaxDocs, err := getMostSimilarDocs(ctx, queryEmbedding, negativeEmbeddings, negativeFilterThreshold, filteredDocs, resLen)
+	topK, err := newTopKCollector(topKAlgorithm, nResults, len(docs))
 	if err != nil {
-		return nil, fmt.Errorf("couldn't get most similar docs: %w", err)
+		return nil, QueryStats{}, err
+	}
+	config := c.getConfig()
+	boostRules, feedbackBoost := config.BoostRules, config.FeedbackBoost
+	if variant != nil {
+		boostRules, feedbackBoost = variant.BoostRules, variant.FeedbackBoost
+	}
+	scorer := newDocScorer(queryEmbedding, negativeEmbeddings, negativeFilterThreshold, topK, dedupeBy, boostRules)
+	scorer.feedbackBoosts = c.feedback.boosts(queryEmbedding, feedbackBoost)
+	if stream != nil {
+		scorer.onCandidate = func(doc *Document, similarity float32) {
+			if similarity < stream.threshold {
+				return
+			}
+			if withContent, err := c.withContent(doc); err == nil {
+				doc = withContent
+			}
+			stream.send(c.newResult(doc, similarity, 0, filter))
+		}
+	}
+	stats := QueryStats{}
+	var memory int64
+	if !filterContents {
+		// Filter the docs by metadata and content and get the most similar ones
+		// in a single pass.
+		var err error
+		stats.DocumentsScanned, stats.Truncated, err = filterAndScoreDocs(ctx, c.executor(), scorer, docs, filter, limits)
+		if err != nil {
+			return nil, QueryStats{}, fmt.Errorf("couldn't get most similar docs: %w", err)
+		}
+	} else {
+		// The contents have to be loaded from disk for the content filters, which
+		// is done before scoring.
+		c.documentsLock.RLock()
+		filteredDocs, visitedTruncated, err := c.filterDocsLimitedLocked(filter, limits.maxVisited)
+		c.documentsLock.RUnlock()
+		if err != nil {
+			return nil, QueryStats{}, fmt.Errorf("couldn't filter documents: %w", err)
+		}
+		filteredDocs, stats.Truncated = limits.limitCandidates(filteredDocs)
+		stats.Truncated = stats.Truncated || visitedTruncated
+		if len(filteredDocs) == 0 {
+			c.counters().queryServed(1, 0)
+			return nil, stats, nil
+		}
+		memory = int64(len(filteredDocs)) * candidateSize
+
+		stats.DocumentsScanned, err = getMostSimilarDocs(ctx, c.executor(), scorer, filteredDocs, limits.deadline)
+		if err != nil {
+			return nil, QueryStats{}, fmt.Errorf("couldn't get most similar docs: %w", err)
+		}
+		if stats.DocumentsScanned < len(filteredDocs) {
+			stats.Truncated = true
+		}
+	}
+	c.counters().queryServed(1, stats.DocumentsScanned)
+	nMaxDocs := scorer.values()
+	// No need to continue if the filters got rid of all documents
+	if len(nMaxDocs) == 0 {
+		return nil, stats, nil
 	}
 
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
 	res := make([]Result, 0, len(nMaxDocs))
 	for i := 0; i < len(nMaxDocs); i++ {
-		res = append(res, Result{
-			ID:         nMaxDocs[i].docID,
-			Metadata:   c.documents[nMaxDocs[i].docID].Metadata,
-			Embedding:  c.documents[nMaxDocs[i].docID].Embedding,
-			Content:    c.documents[nMaxDocs[i].docID].Content,
-			Similarity: nMaxDocs[i].similarity,
-		})
+		doc, err := c.withContentLocked(nMaxDocs[i].doc)
+		if err != nil {
+			return nil, QueryStats{}, err
+		}
+		if limits.maxMemory > 0 {
+			memory += documentMemoryUsage(doc).Total()
+			if memory > limits.maxMemory {
+				stats.Truncated = true
+				break
+			}
+		}
+		res = append(res, c.newResult(doc, nMaxDocs[i].similarity, i+1, filter))
 	}
 
-	return res, nil
+	return res, stats, nil
+}
+
+// newResult returns the query result for the document, which must have its
+// content.
+func (c *Collection) newResult(doc *Document, similarity float32, rank int, filter *Filter) Result {
+	return Result{
+		ID:         doc.ID,
+		Metadata:   doc.Metadata,
+		Embedding:  doc.Embedding,
+		Content:    doc.Content,
+		Media:      doc.Media,
+		Similarity: similarity,
+		Rank:       rank,
+		Collection: c.Name,
+		Highlights: findHighlights(doc.Content, filter),
+		Citation:   citationFromMetadata(doc.ID, doc.Metadata),
+	}
+}
+
+// collectionConfig is the runtime configuration of a collection. Its fields
+// are exported so that it can be persisted as gob.
+type collectionConfig struct {
+	EmbeddingTemplate     EmbeddingTemplate
+	EmbeddingInstructions EmbeddingInstructions
+	NormalizationPolicy   NormalizationPolicy
+	ContentSpillover      bool
+	ContentCacheSize      int
+	SoftDeletePurgeAfter  time.Duration
+	BoostRules            []BoostRule
+	MetadataSchema        *MetadataSchema
+	Limits                CollectionLimits
+	FeedbackBoost         FeedbackBoost
+	RetrievalVariants     []RetrievalVariant
+	Index                 IndexOptions
+	IndexCentroids        [][]float32
+	IndexFile             string
+	PartitionKey          string
+	LanguageMetadataKey   string
+	AccessStats           bool
+}
+
+// getConfig returns a copy of the collection's configuration.
+func (c *Collection) getConfig() collectionConfig {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+
+	return c.config
+}
+
+// Metadata returns a copy of the collection's metadata.
+func (c *Collection) Metadata() map[string]string {
+	return maps.Clone(c.getMetadata())
+}
+
+// SetMetadata sets the given keys in the collection's metadata, overwriting
+// existing values, for example to record the owner, embedding model or schema
+// version of a live collection.
+// The metadata of a persistent collection is persisted atomically, so either
+// all or none of the keys are set, also in case of a crash.
+func (c *Collection) SetMetadata(metadata map[string]string) error {
+	return c.updateMetadata(func(m map[string]string) {
+		for k, v := range metadata {
+			m[k] = v
+		}
+	})
+}
+
+// DeleteMetadataKey deletes the given key from the collection's metadata.
+// Deleting a key that doesn't exist is not an error.
+func (c *Collection) DeleteMetadataKey(key string) error {
+	return c.updateMetadata(func(m map[string]string) {
+		delete(m, key)
+	})
+}
+
+// updateMetadata applies the update to a copy of the metadata, replaces the
+// metadata with it and persists it. If persisting fails, the change is rolled
+// back.
+func (c *Collection) updateMetadata(update func(m map[string]string)) error {
+	c.persistMetadataLock.Lock()
+	defer c.persistMetadataLock.Unlock()
+
+	c.configLock.Lock()
+	old := c.metadata
+	m := make(map[string]string, len(old))
+	for k, v := range old {
+		m[k] = v
+	}
+	update(m)
+	c.metadata = m
+	c.configLock.Unlock()
+
+	err := c.persistMetadataLocked()
+	if err != nil {
+		c.configLock.Lock()
+		c.metadata = old
+		c.configLock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// getMetadata returns the collection's metadata. It must not be modified.
+func (c *Collection) getMetadata() map[string]string {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+
+	return c.metadata
+}
+
+// persistMetadata persists the collection's name, metadata and configuration
+// to its metadata file, if the collection is persistent.
+func (c *Collection) persistMetadata() error {
+	// It's called after the configuration changed, which can change the
+	// results of queries.
+	c.changed()
+
+	c.persistMetadataLock.Lock()
+	defer c.persistMetadataLock.Unlock()
+
+	return c.persistMetadataLocked()
+}
+
+// persistMetadataLocked is like persistMetadata, but the caller must hold
+// persistMetadataLock. The file is written to a temporary file first and then
+// renamed, so it's never left half-written.
+func (c *Collection) persistMetadataLocked() error {
+	if c.persistDirectory == "" {
+		return nil
+	}
+
+	metadataPath := filepath.Join(c.persistDirectory, metadataFileName)
+	metadataPath += ".gob"
+	if c.compress {
+		metadataPath += ".gz"
+	}
+	pc := persistenceCollectionMetadata{
+		Name:     c.Name,
+		Metadata: c.getMetadata(),
+		Config:   c.getConfig(),
+	}
+	tmpPath := metadataPath + ".tmp"
+	err := persistToFileSynced(tmpPath, pc, c.compress, "", c.syncer)
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	err = os.Rename(tmpPath, metadataPath)
+	if err != nil {
+		return fmt.Errorf("couldn't rename collection metadata file: %w", err)
+	}
+	return c.syncer.syncDir(metadataPath)
+}
+
+// persistDocument writes the document to disk, if the collection is
+// persistent.
+func (c *Collection) persistDocument(doc *Document) error {
+	if c.persistDirectory == "" {
+		return nil
+	}
+	if c.segments != nil {
+		err := c.segments.put(doc)
+		if err != nil {
+			return fmt.Errorf("couldn't persist document %q to segment: %w", doc.ID, err)
+		}
+		return nil
+	}
+	docPath := c.getDocPath(doc.ID)
+	err := persistToFileSynced(docPath, doc, c.compress, "", c.syncer)
+	if err != nil {
+		return fmt.Errorf("couldn't persist document to %q: %w", docPath, err)
+	}
+	return nil
+}
+
+// removePersistedDocument removes the document from disk, if the collection is
+// persistent. With segments, the document's file is removed as well, in case
+// it was persisted before the collection switched to segments.
+func (c *Collection) removePersistedDocument(docID string) error {
+	if c.persistDirectory == "" {
+		return nil
+	}
+	if c.segments != nil {
+		err := c.segments.remove(docID)
+		if err != nil {
+			return fmt.Errorf("couldn't remove document %q from segment: %w", docID, err)
+		}
+	}
+	docPath := c.getDocPath(docID)
+	err := removeFile(docPath)
+	if err != nil {
+		return fmt.Errorf("couldn't remove document at %q: %w", docPath, err)
+	}
+	return c.syncer.syncDir(docPath)
+}
+
+// readPersistedDocument reads the document from disk.
+func (c *Collection) readPersistedDocument(docID string) (*Document, error) {
+	if c.segments != nil {
+		doc, err := c.segments.read(docID)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read document %q from segment: %w", docID, err)
+		} else if doc != nil {
+			return doc, nil
+		}
+		// The document might have been persisted before the collection
+		// switched to segments.
+	}
+	docPath := c.getDocPath(docID)
+	doc := &Document{}
+	err := readFromFile(docPath, doc, "")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read document %q from %q: %w", docID, docPath, err)
+	}
+	return doc, nil
 }
 
 // getDocPath generates the path to the document file.
 func (c *Collection) getDocPath(docID string) string {
-	safeID := hash2hex(docID)
+	safeID := EncodePathName(docID)
 	docPath := filepath.Join(c.persistDirectory, safeID)
-	docPath += ".gob"
+	docPath += documentExt
 	if c.compress {
 		docPath += ".gz"
 	}
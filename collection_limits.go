@@ -0,0 +1,105 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLimitExceeded is returned when adding a document would exceed one of the
+// collection's limits, see [Collection.SetLimits]. The errors are of type
+// [*LimitError], which wraps it, so it can be checked with [errors.Is].
+var ErrLimitExceeded = errors.New("collection limit exceeded")
+
+// CollectionLimits are guardrails for the documents of a collection, see
+// [Collection.SetLimits]. Zero values disable the respective limit.
+type CollectionLimits struct {
+	// The maximum size of a document's content in bytes.
+	MaxContentBytes int
+	// The maximum size of a document's metadata in bytes, which is the sum of
+	// the lengths of its keys and values.
+	MaxMetadataBytes int
+	// The maximum number of documents in the collection. Replacing an existing
+	// document is always possible.
+	MaxDocuments int
+}
+
+// LimitError is returned when adding a document would exceed one of the
+// collection's [CollectionLimits].
+type LimitError struct {
+	DocumentID string
+	// The exceeded limit, like "MaxContentBytes".
+	Limit string
+	// The value of the limit.
+	Max int
+	// The value the document would have led to.
+	Actual int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: document %q exceeds %s of %d with %d", ErrLimitExceeded, e.DocumentID, e.Limit, e.Max, e.Actual)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// SetLimits sets limits for the collection's documents. Documents that are
+// added afterwards must be within them, otherwise adding them fails with a
+// [*LimitError]. This prevents a runaway ingestion job from consuming all
+// memory, for example in an embedded deployment. The content and metadata are
+// checked before embedding the document. Existing documents aren't checked.
+// The limits are persisted. The zero value disables them.
+func (c *Collection) SetLimits(limits CollectionLimits) error {
+	if err := limits.validate(); err != nil {
+		return err
+	}
+
+	c.configLock.Lock()
+	c.config.Limits = limits
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// Limits returns the collection's limits.
+func (c *Collection) Limits() CollectionLimits {
+	return c.getConfig().Limits
+}
+
+func (l CollectionLimits) validate() error {
+	if l.MaxContentBytes < 0 || l.MaxMetadataBytes < 0 || l.MaxDocuments < 0 {
+		return errors.New("collection limits must be >= 0")
+	}
+	return nil
+}
+
+// checkDocument returns a [*LimitError] if the document's content or metadata
+// exceed the limits.
+func (l CollectionLimits) checkDocument(doc *Document) error {
+	if l.MaxContentBytes > 0 && len(doc.Content) > l.MaxContentBytes {
+		return &LimitError{DocumentID: doc.ID, Limit: "MaxContentBytes", Max: l.MaxContentBytes, Actual: len(doc.Content)}
+	}
+	if l.MaxMetadataBytes > 0 {
+		size := 0
+		for k, v := range doc.Metadata {
+			size += len(k) + len(v)
+		}
+		if size > l.MaxMetadataBytes {
+			return &LimitError{DocumentID: doc.ID, Limit: "MaxMetadataBytes", Max: l.MaxMetadataBytes, Actual: size}
+		}
+	}
+	return nil
+}
+
+// checkDocumentsLocked returns a [*LimitError] if adding the document with the
+// given ID would exceed the maximum number of documents. The caller must hold
+// the documents lock.
+func (c *Collection) checkDocumentsLocked(limits CollectionLimits, id string) error {
+	if limits.MaxDocuments == 0 || len(c.documents) < limits.MaxDocuments {
+		return nil
+	}
+	if _, ok := c.documents[id]; ok {
+		return nil
+	}
+	return &LimitError{DocumentID: id, Limit: "MaxDocuments", Max: limits.MaxDocuments, Actual: len(c.documents) + 1}
+}
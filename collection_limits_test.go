@@ -0,0 +1,120 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCollection_SetLimits(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	embedded := 0
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		embedded++
+		return []float32{1, 0}, nil
+	}
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Invalid limits
+	if err := c.SetLimits(CollectionLimits{MaxDocuments: -1}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	limits := CollectionLimits{MaxContentBytes: 10, MaxMetadataBytes: 8, MaxDocuments: 2}
+	err = c.SetLimits(limits)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	tt := []struct {
+		name          string
+		doc           Document
+		expectedLimit string
+		expectedValue int
+	}{
+		{
+			name: "within limits",
+			doc:  Document{ID: "1", Content: "hello", Metadata: map[string]string{"lang": "en"}},
+		},
+		{
+			name:          "content too large",
+			doc:           Document{ID: "2", Content: "hello world"},
+			expectedLimit: "MaxContentBytes",
+			expectedValue: 11,
+		},
+		{
+			name:          "metadata too large",
+			doc:           Document{ID: "2", Content: "hello", Metadata: map[string]string{"lang": "en", "ab": "c"}},
+			expectedLimit: "MaxMetadataBytes",
+			expectedValue: 9,
+		},
+		{
+			name: "second document",
+			doc:  Document{ID: "2", Content: "hello"},
+		},
+		{
+			name:          "too many documents",
+			doc:           Document{ID: "3", Content: "hello"},
+			expectedLimit: "MaxDocuments",
+			expectedValue: 3,
+		},
+		{
+			name: "replacing a document",
+			doc:  Document{ID: "2", Content: "hi"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			before := embedded
+			err := c.AddDocument(ctx, tc.doc)
+			if tc.expectedLimit == "" {
+				if err != nil {
+					t.Fatal("expected no error, got", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Fatal("expected ErrLimitExceeded, got", err)
+			}
+			limitErr := &LimitError{}
+			if !errors.As(err, &limitErr) {
+				t.Fatal("expected limit error, got", err)
+			}
+			if limitErr.Limit != tc.expectedLimit || limitErr.Actual != tc.expectedValue {
+				t.Fatalf("expected %s exceeded with %d, got %v", tc.expectedLimit, tc.expectedValue, err)
+			}
+			// The limits are checked before embedding.
+			if embedded != before {
+				t.Fatal("expected no embedding to be created")
+			}
+		})
+	}
+
+	// The limits are persisted.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if got := db2.GetCollection("test", nil).Limits(); got != limits {
+		t.Fatalf("expected %+v, got %+v", limits, got)
+	}
+
+	// The zero value disables the limits.
+	err = c.SetLimits(CollectionLimits{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "3", Content: strings.Repeat("a", 100)})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
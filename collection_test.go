@@ -3,6 +3,7 @@ package chromem
 import (
 	"context"
 	"errors"
+	"maps"
 	"math/rand"
 	"os"
 	"slices"
@@ -423,6 +424,329 @@ func TestCollection_Count(t *testing.T) {
 	}
 }
 
+func TestCollection_SimilarToDocument(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0, 0}, Metadata: map[string]string{"lang": "en"}},
+		{ID: "2", Embedding: []float32{0.8, 0.6, 0}, Metadata: map[string]string{"lang": "de"}},
+		{ID: "3", Embedding: []float32{0.6, 0.8, 0}, Metadata: map[string]string{"lang": "en"}},
+		{ID: "4", Embedding: []float32{0, 0, 1}, Metadata: map[string]string{"lang": "en"}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	tt := []struct {
+		name    string
+		docID   string
+		n       int
+		where   map[string]string
+		wantIDs []string
+		wantErr bool
+	}{
+		{name: "without filter", docID: "1", n: 2, wantIDs: []string{"2", "3"}},
+		{name: "with filter", docID: "1", n: 2, where: map[string]string{"lang": "en"}, wantIDs: []string{"3", "4"}},
+		{name: "document doesn't match filter", docID: "2", n: 1, where: map[string]string{"lang": "en"}, wantIDs: []string{"3"}},
+		{name: "unknown document", docID: "5", n: 1, wantErr: true},
+		{name: "too many results", docID: "1", n: 4, wantErr: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := c.SimilarToDocument(ctx, tc.docID, tc.n, tc.where, nil)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			var gotIDs []string
+			for _, r := range res {
+				gotIDs = append(gotIDs, r.ID)
+			}
+			if !slices.Equal(tc.wantIDs, gotIDs) {
+				t.Fatal("expected", tc.wantIDs, "got", gotIDs)
+			}
+		})
+	}
+}
+
+func TestCollection_QueryWithOptions_Result(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Content: "the cat sat on the cat mat"},
+		{ID: "2", Embedding: []float32{0.70710677, 0.70710677}, Content: "a cat"},
+		{ID: "3", Embedding: []float32{0, 1}, Content: "a dog"},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	res, err := c.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: []float32{1, 0},
+		NResults:       2,
+		WhereDocument:  map[string]string{"$contains": "cat"},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 {
+		t.Fatal("expected 2 results, got", len(res))
+	}
+	for i, r := range res {
+		if r.Rank != i+1 {
+			t.Fatalf("expected rank %d, got %d", i+1, r.Rank)
+		}
+		if r.Collection != "test" {
+			t.Fatal("expected collection test, got", r.Collection)
+		}
+	}
+	expected := []Highlight{{Start: 4, End: 7}, {Start: 19, End: 22}}
+	if !slices.Equal(expected, res[0].Highlights) {
+		t.Fatalf("expected highlights %v, got %v", expected, res[0].Highlights)
+	}
+	for _, h := range res[0].Highlights {
+		if res[0].Content[h.Start:h.End] != "cat" {
+			t.Fatal("expected highlighted cat, got", res[0].Content[h.Start:h.End])
+		}
+	}
+
+	// Without $contains there are no highlights
+	res, err = c.QueryEmbedding(ctx, []float32{1, 0}, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].Highlights != nil {
+		t.Fatal("expected no highlights, got", res[0].Highlights)
+	}
+}
+
+func TestCollection_QueryWithOptions_DedupeBy(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "a1", Embedding: []float32{1, 0}, Metadata: map[string]string{"source_url": "a"}},
+		{ID: "a2", Embedding: []float32{0.9, 0.43588989}, Metadata: map[string]string{"source_url": "a"}},
+		{ID: "b1", Embedding: []float32{0.8, 0.6}, Metadata: map[string]string{"source_url": "b"}},
+		{ID: "b2", Embedding: []float32{0.6, 0.8}, Metadata: map[string]string{"source_url": "b"}},
+		{ID: "c1", Embedding: []float32{0.7, 0.71414284}},
+		{ID: "c2", Embedding: []float32{0, 1}},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	res, err := c.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: []float32{1, 0},
+		NResults:       4,
+		DedupeBy:       "source_url",
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	var ids []string
+	for _, r := range res {
+		ids = append(ids, r.ID)
+	}
+	// Documents without the key aren't deduplicated
+	expected := []string{"a1", "b1", "c1", "c2"}
+	if !slices.Equal(expected, ids) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+}
+
+func TestCollection_Centroid(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Embedding: []float32{1, 0, 0}, Metadata: map[string]string{"topic": "a"}, Content: "foo"},
+		{ID: "2", Embedding: []float32{0, 1, 0}, Metadata: map[string]string{"topic": "a"}, Content: "bar"},
+		{ID: "3", Embedding: []float32{0, 0, 1}, Metadata: map[string]string{"topic": "b"}, Content: "foo"},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	tt := []struct {
+		name          string
+		where         map[string]string
+		whereDocument map[string]string
+		expected      []float32
+	}{
+		{"All", nil, nil, []float32{0.57735026, 0.57735026, 0.57735026}},
+		{"Where", map[string]string{"topic": "a"}, nil, []float32{0.70710677, 0.70710677, 0}},
+		{"WhereDocument", nil, map[string]string{"$contains": "foo"}, []float32{0.70710677, 0, 0.70710677}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			centroid, err := c.Centroid(ctx, tc.where, tc.whereDocument)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if !slices.Equal(centroid, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, centroid)
+			}
+		})
+	}
+
+	// No match
+	_, err = c.Centroid(ctx, map[string]string{"topic": "c"}, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_QueryWithOptions_Concepts(t *testing.T) {
+	ctx := context.Background()
+	vectors := map[string][]float32{
+		"cats":    {1, 0, 0},
+		"dogs":    {0, 1, 0},
+		"outdoor": {0, 0, 1},
+	}
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		v, ok := vectors[text]
+		if !ok {
+			return nil, errors.New("unknown text")
+		}
+		return v, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "cats indoor", Embedding: []float32{0.9, 0, -0.43588989}},
+		{ID: "cats outdoor", Embedding: []float32{0.70710677, 0, 0.70710677}},
+		{ID: "dogs outdoor", Embedding: []float32{0, 0.70710677, 0.70710677}},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	tt := []struct {
+		name     string
+		options  QueryOptions
+		expected string
+	}{
+		{
+			name:     "Query only",
+			options:  QueryOptions{QueryText: "cats", NResults: 1},
+			expected: "cats indoor",
+		},
+		{
+			name: "Positive concept",
+			options: QueryOptions{QueryText: "cats", NResults: 1, Concepts: []QueryConcept{
+				{Text: "outdoor", Weight: 1},
+			}},
+			expected: "cats outdoor",
+		},
+		{
+			name: "Negative concept",
+			options: QueryOptions{QueryText: "outdoor", NResults: 1, Concepts: []QueryConcept{
+				{Text: "dogs", Weight: -0.5},
+			}},
+			expected: "cats outdoor",
+		},
+		{
+			name: "Concepts only",
+			options: QueryOptions{NResults: 1, Concepts: []QueryConcept{
+				{Embedding: []float32{0, 1, 0}, Weight: 2},
+				{Text: "outdoor", Weight: 1},
+			}},
+			expected: "dogs outdoor",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := c.QueryWithOptions(ctx, tc.options)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if len(res) != 1 || res[0].ID != tc.expected {
+				t.Fatalf("expected %q, got %+v", tc.expected, res)
+			}
+		})
+	}
+
+	// Concepts that cancel out the query
+	_, err = c.QueryWithOptions(ctx, QueryOptions{QueryText: "cats", NResults: 1, Concepts: []QueryConcept{
+		{Text: "cats", Weight: -1},
+	}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	// Zero weight
+	_, err = c.QueryWithOptions(ctx, QueryOptions{QueryText: "cats", NResults: 1, Concepts: []QueryConcept{
+		{Text: "dogs"},
+	}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_SetMetadata(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", map[string]string{"owner": "alice", "model": "a"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Modifying the returned metadata doesn't affect the collection
+	c.Metadata()["owner"] = "mallory"
+
+	err = c.SetMetadata(map[string]string{"model": "b", "schema": "2"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.DeleteMetadataKey("owner")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected := map[string]string{"model": "b", "schema": "2"}
+	if !maps.Equal(c.Metadata(), expected) {
+		t.Fatalf("expected %v, got %v", expected, c.Metadata())
+	}
+
+	// The metadata is persisted
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	if !maps.Equal(c.Metadata(), expected) {
+		t.Fatalf("expected %v, got %v", expected, c.Metadata())
+	}
+}
+
 func TestCollection_Delete(t *testing.T) {
 	// Create persistent collection
 	tmpdir, err := os.MkdirTemp(os.TempDir(), "chromem-test-*")
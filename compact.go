@@ -0,0 +1,179 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CompactionStats are the statistics of a [Collection.Compact] run.
+type CompactionStats struct {
+	// The number of document files that were rewritten.
+	DocumentsRewritten int
+	// The number of stale files that were removed, for example of documents
+	// whose deletion failed, or that were written with a different compression
+	// setting.
+	FilesRemoved int
+	// The size of the collection's directory before and after the compaction,
+	// in bytes.
+	BytesBefore int64
+	BytesAfter  int64
+	// The number of bytes that were reclaimed, which is BytesBefore - BytesAfter.
+	// It can be negative if document files were missing and had to be rewritten.
+	BytesReclaimed int64
+}
+
+// Compact rewrites the collection's persistent storage compactly and reclaims
+// space:
+//
+//   - All documents are rewritten with the current compression setting. If the
+//     collection is persisted in segments, they're rewritten into new segments
+//     without the records of overwritten and deleted documents.
+//   - Document files that don't belong to any document anymore, or that were
+//     written with a different compression setting, are removed.
+//   - The in-memory document map is rebuilt, as Go maps don't shrink when
+//     documents are deleted.
+//
+// For in-memory collections only the latter applies, and the byte counts in the
+// returned stats are 0.
+// Adding, deleting and querying documents is blocked while the collection is
+// compacted. If the context is canceled, the compaction is aborted, leaving the
+// collection consistent, but only partly compacted.
+func (c *Collection) Compact(ctx context.Context) (CompactionStats, error) {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	stats := CompactionStats{}
+	_, progress := startProgress(ctx, "compact", len(c.documents))
+
+	// Rebuild the map to release the memory of deleted entries.
+	documents := make(map[string]*Document, len(c.documents))
+	for id, doc := range c.documents {
+		documents[id] = doc
+	}
+	c.documents = documents
+
+	if c.persistDirectory == "" {
+		return stats, nil
+	}
+
+	var err error
+	stats.BytesBefore, err = c.persistedSize()
+	if err != nil {
+		return stats, err
+	}
+
+	// Rewrite the metadata and all documents, and remember the files to keep.
+	err = c.persistMetadata()
+	if err != nil {
+		return stats, err
+	}
+	metadataPath := filepath.Join(c.persistDirectory, metadataFileName) + ".gob"
+	if c.compress {
+		metadataPath += ".gz"
+	}
+	keep := map[string]struct{}{metadataPath: {}}
+	var segmentDocs []*Document
+	for _, doc := range c.documents {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		// With content spillover, the content is only on disk.
+		doc, err := c.withContentLocked(doc)
+		if err != nil {
+			return stats, err
+		}
+		if c.segments != nil {
+			segmentDocs = append(segmentDocs, doc)
+		} else {
+			docPath := c.getDocPath(doc.ID)
+			err = persistToFileSynced(docPath, doc, c.compress, "", c.syncer)
+			if err != nil {
+				return stats, fmt.Errorf("couldn't persist document to %q: %w", docPath, err)
+			}
+			keep[docPath] = struct{}{}
+		}
+		stats.DocumentsRewritten++
+		progress.add(1)
+	}
+	// With segments, the documents are rewritten into new segments, which
+	// drops all overwritten and deleted records. Document files are stale then.
+	if c.segments != nil {
+		err = c.segments.rewrite(segmentDocs)
+		if err != nil {
+			return stats, fmt.Errorf("couldn't rewrite segments: %w", err)
+		}
+	}
+
+	// Remove stale files.
+	entries, err := os.ReadDir(c.persistDirectory)
+	if err != nil {
+		return stats, fmt.Errorf("couldn't read collection directory: %w", err)
+	}
+	for _, entry := range entries {
+		// Only document and metadata files can be stale. Other files, like the
+		// vector index or the access stats, are kept.
+		if entry.IsDir() || !isDocumentFile(strings.TrimSuffix(entry.Name(), ".gz"), documentExt, ".gob") {
+			continue
+		}
+		path := filepath.Join(c.persistDirectory, entry.Name())
+		if _, ok := keep[path]; ok {
+			continue
+		}
+		err := removeFile(path)
+		if err != nil {
+			return stats, err
+		}
+		stats.FilesRemoved++
+	}
+
+	stats.BytesAfter, err = c.persistedSize()
+	if err != nil {
+		return stats, err
+	}
+	stats.BytesReclaimed = stats.BytesBefore - stats.BytesAfter
+
+	return stats, nil
+}
+
+// persistedSize returns the size of the collection's files, including the
+// segments, but not the trash.
+func (c *Collection) persistedSize() (int64, error) {
+	size, err := dirSize(c.persistDirectory)
+	if err != nil {
+		return 0, err
+	}
+	if c.segments != nil {
+		segmentsSize, err := dirSize(c.segments.dir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return 0, err
+		}
+		size += segmentsSize
+	}
+	return size, nil
+}
+
+// dirSize returns the total size of the regular files in the directory, not
+// including subdirectories.
+func dirSize(dir string) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't read directory: %w", err)
+	}
+	var size int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return 0, fmt.Errorf("couldn't get file info: %w", err)
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
@@ -0,0 +1,85 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCollection_Compact(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{-0.40824828, 0.40824828, 0.81649655}, Content: "hello world"},
+		{ID: "2", Embedding: []float32{0.40824828, -0.40824828, 0.81649655}, Content: "hallo welt"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Simulate a stale file, e.g. of a document whose deletion failed
+	stalePath := filepath.Join(c.persistDirectory, EncodePathName("3")+".gob")
+	err = os.WriteFile(stalePath, []byte("stale document"), 0o600)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	stats, err := c.Compact(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats.DocumentsRewritten != 2 {
+		t.Fatal("expected 2 rewritten documents, got", stats.DocumentsRewritten)
+	}
+	if stats.FilesRemoved != 1 {
+		t.Fatal("expected 1 removed file, got", stats.FilesRemoved)
+	}
+	if stats.BytesReclaimed != int64(len("stale document")) {
+		t.Fatal("expected", len("stale document"), "reclaimed bytes, got", stats.BytesReclaimed)
+	}
+	if _, err := os.Stat(stalePath); !os.IsNotExist(err) {
+		t.Fatal("expected stale file to be removed, got", err)
+	}
+
+	// The documents must still be loadable
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	if c.Count() != 2 {
+		t.Fatal("expected 2 documents, got", c.Count())
+	}
+}
+
+func TestCollection_Compact_InMemory(t *testing.T) {
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(context.Background(), Document{ID: "1", Embedding: []float32{-0.40824828, 0.40824828, 0.81649655}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	stats, err := c.Compact(context.Background())
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats != (CompactionStats{}) {
+		t.Fatalf("expected empty stats, got %+v", stats)
+	}
+	if c.Count() != 1 {
+		t.Fatal("expected 1 document, got", c.Count())
+	}
+}
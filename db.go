@@ -6,10 +6,21 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrCollectionNotFound is returned when a collection doesn't exist.
+	ErrCollectionNotFound = errors.New("collection not found")
+	// ErrClosed is returned when the DB is modified after [DB.Close].
+	ErrClosed = errors.New("DB is closed")
 )
 
 // EmbeddingFunc is a function that creates embeddings for a given text.
@@ -20,6 +31,11 @@ import (
 // others like Nomic's "nomic-embed-text-v1.5" don't.
 type EmbeddingFunc func(ctx context.Context, text string) ([]float32, error)
 
+// BatchEmbeddingFunc is a function that creates embeddings for multiple texts
+// in one go, which is more efficient for embedding providers that support it.
+// The returned embeddings must be *normalized* and in the same order as the texts.
+type BatchEmbeddingFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
 // DB is the chromem-go database. It holds collections, which hold documents.
 //
 //	+----+    1-n    +------------+    n-n    +----------+
@@ -31,6 +47,34 @@ type DB struct {
 
 	persistDirectory string
 	compress         bool
+	segmentSize      int
+	syncer           *fileSyncer
+
+	// Guarded by collectionsLock.
+	memoryBudget MemoryBudget
+	// Alias -> collection name. Guarded by collectionsLock.
+	aliases map[string]string
+
+	auditLog     AuditLog
+	auditLogLock sync.RWMutex
+
+	queryLog        QueryLog
+	queryLogOptions QueryLogOptions
+	queryLogLock    sync.RWMutex
+
+	executor     Executor
+	executorLock sync.RWMutex
+
+	counters dbCounters
+	// Set by Close
+	closed atomic.Bool
+
+	maintenance     *maintenance
+	maintenanceLock sync.Mutex
+
+	// The blobs of in-memory DBs. Persistent DBs store them as files.
+	blobs     map[string][]byte
+	blobsLock sync.RWMutex
 
 	// ⚠️ When adding fields here, consider adding them to the persistence struct
 	// versions in [DB.Export] and [DB.Import] as well!
@@ -57,13 +101,49 @@ func NewDB() *DB {
 // existing collection and adding more documents to it.
 //
 // Currently, the persistence is done synchronously on each write operation, and
-// each document addition leads to a new file, encoded as gob. In the future we
-// will make this configurable (encoding, async writes, WAL-based writes, etc.).
+// each document addition leads to a new file, unless segment files are enabled
+// via [NewPersistentDBWithOptions]. In the future we will make this
+// configurable (async writes, WAL-based writes, etc.).
 //
 // In addition to persistence for each added collection and document you can use
 // [DB.Export] and [DB.Import] to export and import the entire DB to/from a file,
 // which also works for the pure in-memory DB.
+//
+// For more options, use [NewPersistentDBWithOptions].
 func NewPersistentDB(path string, compress bool) (*DB, error) {
+	return NewPersistentDBWithOptions(path, PersistentDBOptions{Compress: compress})
+}
+
+// PersistentDBOptions are the options for [NewPersistentDBWithOptions].
+type PersistentDBOptions struct {
+	// If true, the files are compressed with gzip.
+	Compress bool
+
+	// The number of records per segment file. If > 0, documents are persisted
+	// in append-only segment files instead of one file per document, which
+	// needs far fewer files and loads faster for large collections. Deleting or
+	// overwriting documents appends records, so use [Collection.Compact] to
+	// reclaim the space.
+	//
+	// Collections that were persisted with one file per document keep their
+	// files, and new writes go to segments. Compacting removes the files.
+	// Collections that were persisted with segments always use segments, with
+	// [DefaultSegmentSize] if SegmentSize is 0.
+	SegmentSize int
+
+	// The durability level of writes. Optional, defaults to [DurabilityNone].
+	Durability Durability
+	// The interval of [DurabilityInterval]. Optional, defaults to
+	// [DefaultSyncInterval]. Call [DB.Close] to stop the interval syncs.
+	SyncInterval time.Duration
+}
+
+// NewPersistentDBWithOptions is like [NewPersistentDB], but with options.
+func NewPersistentDBWithOptions(path string, opts PersistentDBOptions) (*DB, error) {
+	if opts.SegmentSize < 0 {
+		return nil, errors.New("segment size must be >= 0")
+	}
+	compress := opts.Compress
 	if path == "" {
 		path = "./chromem-go"
 	} else {
@@ -71,16 +151,25 @@ func NewPersistentDB(path string, compress bool) (*DB, error) {
 		path = filepath.Clean(path)
 	}
 
-	// We check for this file extension and skip others
+	// We check for these file extensions and skip others. Documents of earlier
+	// versions have the gob extension.
 	ext := ".gob"
+	docExt := documentExt
 	if compress {
 		ext += ".gz"
+		docExt += ".gz"
 	}
 
 	db := &DB{
 		collections:      make(map[string]*Collection),
 		persistDirectory: path,
 		compress:         compress,
+		segmentSize:      opts.SegmentSize,
+	}
+	var err error
+	db.syncer, err = newFileSyncer(opts.Durability, opts.SyncInterval)
+	if err != nil {
+		return nil, err
 	}
 
 	// If the directory doesn't exist, create it and return an empty DB.
@@ -92,6 +181,7 @@ func NewPersistentDB(path string, compress bool) (*DB, error) {
 				return nil, fmt.Errorf("couldn't create persistence directory: %w", err)
 			}
 
+			db.syncer.start()
 			return db, nil
 		}
 		return nil, fmt.Errorf("couldn't get info about persistence directory: %w", err)
@@ -99,15 +189,20 @@ func NewPersistentDB(path string, compress bool) (*DB, error) {
 		return nil, fmt.Errorf("path is not a directory: %s", path)
 	}
 
-	// Otherwise, read all collections and their documents from the directory.
+	// Otherwise, read the aliases and all collections and their documents from
+	// the directory.
+	err = readFromFile(db.aliasesPath(), &db.aliases, "")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("couldn't read aliases: %w", err)
+	}
 	dirEntries, err := os.ReadDir(path)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't read persistence directory: %w", err)
 	}
 	for _, dirEntry := range dirEntries {
 		// Collections are subdirectories, so skip any files (which the user might
-		// have placed).
-		if !dirEntry.IsDir() {
+		// have placed), as well as the blob store.
+		if !dirEntry.IsDir() || dirEntry.Name() == blobDirName {
 			continue
 		}
 		// For each subdirectory, create a collection and read its name, metadata
@@ -121,17 +216,29 @@ func NewPersistentDB(path string, compress bool) (*DB, error) {
 		}
 		c := &Collection{
 			documents:        make(map[string]*Document),
+			feedback:         newFeedbackStore(),
+			variantStats:     newVariantStatsStore(),
+			accessStats:      &accessStatsStore{},
 			persistDirectory: collectionPath,
 			compress:         compress,
+			syncer:           db.syncer,
 			// We can fill Name and metadata only after reading
 			// the metadata.
 			// We can fill embed only when the user calls DB.GetCollection() or
 			// DB.GetOrCreateCollection().
 		}
+		renames := make(map[string]string)
 		for _, collectionDirEntry := range collectionDirEntries {
 			// Files should be metadata and documents; skip subdirectories which
-			// the user might have placed.
+			// the user might have placed, except for the trash. Segments are
+			// read after the document files, as they're newer.
 			if collectionDirEntry.IsDir() {
+				if collectionDirEntry.Name() == trashDirName {
+					err := c.loadTrash(ext)
+					if err != nil {
+						return nil, err
+					}
+				}
 				continue
 			}
 
@@ -139,17 +246,15 @@ func NewPersistentDB(path string, compress bool) (*DB, error) {
 			// Differentiate between collection metadata, documents and other files.
 			if collectionDirEntry.Name() == metadataFileName+ext {
 				// Read name and metadata
-				pc := struct {
-					Name     string
-					Metadata map[string]string
-				}{}
+				pc := persistenceCollectionMetadata{}
 				err := readFromFile(fPath, &pc, "")
 				if err != nil {
 					return nil, fmt.Errorf("couldn't read collection metadata: %w", err)
 				}
 				c.Name = pc.Name
 				c.metadata = pc.Metadata
-			} else if strings.HasSuffix(collectionDirEntry.Name(), ext) {
+				c.config = pc.Config
+			} else if isDocumentFile(collectionDirEntry.Name(), docExt, ext) {
 				// Read document
 				d := &Document{}
 				err := readFromFile(fPath, d, "")
@@ -157,11 +262,55 @@ func NewPersistentDB(path string, compress bool) (*DB, error) {
 					return nil, fmt.Errorf("couldn't read document: %w", err)
 				}
 				c.documents[d.ID] = d
+				// Files of earlier versions are named by a hash of the ID, or
+				// have the gob extension.
+				if docPath := c.getDocPath(d.ID); fPath != docPath {
+					renames[fPath] = docPath
+				}
 			} else {
 				// Might be a file that the user has placed
 				continue
 			}
 		}
+		// Migrate the names of files and directories of earlier versions, so
+		// paths can be derived from names and IDs.
+		err = renameFiles(renames)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't migrate document file names: %w", err)
+		}
+		if c.Name != "" {
+			newPath := filepath.Join(path, EncodePathName(c.Name))
+			if _, err := os.Stat(newPath); newPath != collectionPath && errors.Is(err, fs.ErrNotExist) {
+				err := os.Rename(collectionPath, newPath)
+				if err != nil {
+					return nil, fmt.Errorf("couldn't migrate collection directory name: %w", err)
+				}
+				c.persistDirectory = newPath
+			}
+		}
+		c.initSegments(opts.SegmentSize)
+		if c.segments != nil {
+			err := c.segments.load(c.documents)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't read collection segments: %w", err)
+			}
+		}
+		err = c.replayTxJournal()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't replay transaction: %w", err)
+		}
+		err = c.loadAccessStats()
+		if err != nil {
+			return nil, err
+		}
+		err = c.loadArchive()
+		if err != nil {
+			return nil, err
+		}
+		err = c.loadIndexesLocked()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load indexes: %w", err)
+		}
 		// If we have neither name nor documents, it was likely a user-added
 		// directory, so skip it.
 		if c.Name == "" && len(c.documents) == 0 {
@@ -172,12 +321,26 @@ func NewPersistentDB(path string, compress bool) (*DB, error) {
 			return nil, fmt.Errorf("collection metadata file not found: %s", collectionPath)
 		}
 
+		c.db = db
+		if c.config.ContentSpillover {
+			c.enableContentSpilloverLocked(c.config.ContentCacheSize)
+		}
+		c.recalculateMemoryUsage()
 		db.collections[c.Name] = c
 	}
 
+	db.syncer.start()
 	return db, nil
 }
 
+// isDocumentFile returns whether the file in a collection's directory is a
+// document, with the document extension or the gob one of earlier versions, or
+// a document that's being renamed.
+func isDocumentFile(name, docExt, gobExt string) bool {
+	name = strings.TrimSuffix(name, migratingSuffix)
+	return strings.HasSuffix(name, docExt) || strings.HasSuffix(name, gobExt)
+}
+
 // Import imports the DB from a file at the given path. The file must be encoded
 // as gob and can optionally be compressed with flate (as gzip) and encrypted
 // with AES-GCM.
@@ -222,16 +385,7 @@ func (db *DB) ImportFromFile(filePath string, encryptionKey string) error {
 		return fmt.Errorf("path is a directory: %s", filePath)
 	}
 
-	// Create persistence structs with exported fields so that they can be decoded
-	// from gob.
-	type persistenceCollection struct {
-		Name      string
-		Metadata  map[string]string
-		Documents map[string]*Document
-	}
-	persistenceDB := struct {
-		Collections map[string]*persistenceCollection
-	}{
+	persistenceDB := persistenceDB{
 		Collections: make(map[string]*persistenceCollection, len(db.collections)),
 	}
 
@@ -243,21 +397,15 @@ func (db *DB) ImportFromFile(filePath string, encryptionKey string) error {
 		return fmt.Errorf("couldn't read file: %w", err)
 	}
 
-	for _, pc := range persistenceDB.Collections {
-		c := &Collection{
-			Name: pc.Name,
-
-			metadata:  pc.Metadata,
-			documents: pc.Documents,
-		}
-		if db.persistDirectory != "" {
-			c.persistDirectory = filepath.Join(db.persistDirectory, hash2hex(pc.Name))
-			c.compress = db.compress
-		}
-		db.collections[c.Name] = c
+	err = db.importCollectionsLocked(persistenceDB.Collections)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	err = db.importAliasesLocked(persistenceDB.Aliases)
+	if err != nil {
+		return err
+	}
+	return db.auditImportLocked(context.Background(), persistenceDB.Collections)
 }
 
 // ImportFromReader imports the DB from a reader. The stream must be encoded as
@@ -265,11 +413,22 @@ func (db *DB) ImportFromFile(filePath string, encryptionKey string) error {
 // AES-GCM.
 // This works for both the in-memory and persistent DBs.
 // Existing collections are overwritten.
-// If the writer has to be closed, it's the caller's responsibility.
+// If the reader has to be closed, it's the caller's responsibility.
+//
+// The reader doesn't have to support seeking, so the DB can be imported
+// directly from a network stream like an HTTP response body or an S3 download,
+// without a temporary file. The DB isn't locked while the stream is read.
+// Encrypted streams are read into memory entirely before they're decrypted.
 //
-// - reader: An implementation of [io.ReadSeeker]
+// - reader: An implementation of [io.Reader]
 // - encryptionKey: Optional, must be 32 bytes long if provided
-func (db *DB) ImportFromReader(reader io.ReadSeeker, encryptionKey string) error {
+func (db *DB) ImportFromReader(reader io.Reader, encryptionKey string) error {
+	return db.ImportFromReaderWithContext(context.Background(), reader, encryptionKey)
+}
+
+// ImportFromReaderWithContext is like [DB.ImportFromReader], but records the
+// principal of the context in the audit log, see [DB.SetAuditLog].
+func (db *DB) ImportFromReaderWithContext(ctx context.Context, reader io.Reader, encryptionKey string) error {
 	if encryptionKey != "" {
 		// AES 256 requires a 32 byte key
 		if len(encryptionKey) != 32 {
@@ -277,46 +436,140 @@ func (db *DB) ImportFromReader(reader io.ReadSeeker, encryptionKey string) error
 		}
 	}
 
-	// Create persistence structs with exported fields so that they can be decoded
-	// from gob.
-	type persistenceCollection struct {
-		Name      string
-		Metadata  map[string]string
-		Documents map[string]*Document
+	persistenceDB := persistenceDB{
+		Collections: make(map[string]*persistenceCollection),
 	}
-	persistenceDB := struct {
-		Collections map[string]*persistenceCollection
-	}{
-		Collections: make(map[string]*persistenceCollection, len(db.collections)),
+
+	err := readFromReader(reader, &persistenceDB, encryptionKey)
+	if err != nil {
+		return fmt.Errorf("couldn't read stream: %w", err)
 	}
 
 	db.collectionsLock.Lock()
 	defer db.collectionsLock.Unlock()
 
-	err := readFromReader(reader, &persistenceDB, encryptionKey)
+	err = db.importCollectionsLocked(persistenceDB.Collections)
 	if err != nil {
-		return fmt.Errorf("couldn't read stream: %w", err)
+		return err
+	}
+	err = db.importAliasesLocked(persistenceDB.Aliases)
+	if err != nil {
+		return err
+	}
+	return db.auditImportLocked(ctx, persistenceDB.Collections)
+}
+
+// auditImportLocked appends an entry for each imported collection to the audit
+// log. The caller must hold the collections lock.
+func (db *DB) auditImportLocked(ctx context.Context, pcs map[string]*persistenceCollection) error {
+	names := make([]string, 0, len(pcs))
+	for _, pc := range pcs {
+		names = append(names, pc.Name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		c, ok := db.collections[name]
+		if !ok {
+			continue
+		}
+		c.documentsLock.RLock()
+		ids := c.auditedIDsLocked()
+		c.documentsLock.RUnlock()
+		if err := db.audit(ctx, name, AuditActionImport, ids); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	for _, pc := range persistenceDB.Collections {
+// importCollectionsLocked adds the imported collections to the DB, overwriting
+// existing ones. With [MemoryBudgetReject], the import is refused if it would
+// exceed the memory budget. The caller must hold the collections lock.
+func (db *DB) importCollectionsLocked(pcs map[string]*persistenceCollection) error {
+	collections := make([]*Collection, 0, len(pcs))
+	total := db.memoryUsageTotalLocked()
+	for _, pc := range pcs {
+		// Imported documents aren't persisted, so archived documents can't be
+		// archived again, and are restored as documents.
+		documents := pc.Documents
+		if len(pc.Archived) != 0 {
+			documents = maps.Clone(documents)
+			if documents == nil {
+				documents = make(map[string]*Document, len(pc.Archived))
+			}
+			maps.Copy(documents, pc.Archived)
+		}
 		c := &Collection{
 			Name: pc.Name,
 
-			metadata:  pc.Metadata,
-			documents: pc.Documents,
+			metadata:     pc.Metadata,
+			documents:    documents,
+			config:       pc.Config,
+			db:           db,
+			feedback:     newFeedbackStore(),
+			variantStats: newVariantStatsStore(),
+			accessStats:  &accessStatsStore{},
 		}
 		if db.persistDirectory != "" {
-			c.persistDirectory = filepath.Join(db.persistDirectory, hash2hex(pc.Name))
+			c.persistDirectory = filepath.Join(db.persistDirectory, EncodePathName(pc.Name))
+			if existing, ok := db.collections[pc.Name]; ok && existing.persistDirectory != "" {
+				c.persistDirectory = existing.persistDirectory
+			}
 			c.compress = db.compress
+			c.syncer = db.syncer
+			c.initSegments(db.segmentSize)
 		}
-		db.collections[c.Name] = c
+		// Imported documents aren't written to disk, so their contents can't be
+		// spilled over.
+		c.config.ContentSpillover = false
+		if err := c.loadIndexesLocked(); err != nil {
+			return fmt.Errorf("couldn't load indexes of collection %q: %w", c.Name, err)
+		}
+		c.recalculateMemoryUsage()
+		total += c.memoryUsage.Load()
+		if existing, ok := db.collections[c.Name]; ok {
+			total -= existing.memoryUsage.Load()
+		}
+		collections = append(collections, c)
+	}
+
+	if db.memoryBudget.Limit != 0 && db.memoryBudget.Policy == MemoryBudgetReject && total > db.memoryBudget.Limit {
+		return fmt.Errorf("couldn't import collections: %w: %d bytes needed, budget is %d bytes", ErrMemoryBudgetExceeded, total, db.memoryBudget.Limit)
 	}
 
+	for _, c := range collections {
+		db.collections[c.Name] = c
+	}
 	return nil
 }
 
+// importAliasesLocked adds the imported aliases to the DB, overwriting existing
+// ones. Aliases whose collection doesn't exist or that have the name of a
+// collection are skipped. The caller must hold the collections lock.
+func (db *DB) importAliasesLocked(aliases map[string]string) error {
+	changed := false
+	for alias, collection := range aliases {
+		if _, ok := db.collections[alias]; ok {
+			continue
+		}
+		if _, ok := db.collections[collection]; !ok {
+			continue
+		}
+		if db.aliases == nil {
+			db.aliases = make(map[string]string)
+		}
+		db.aliases[alias] = collection
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return db.persistAliasesLocked()
+}
+
 // Export exports the DB to a file at the given path. The file is encoded as gob,
-// optionally compressed with flate (as gzip) and optionally encrypted with AES-GCM.
+// with the documents in a compact binary format, optionally compressed with
+// flate (as gzip) and optionally encrypted with AES-GCM.
 // This works for both the in-memory and persistent DBs.
 // If the file exists, it's overwritten, otherwise created.
 //
@@ -331,7 +584,8 @@ func (db *DB) Export(filePath string, compress bool, encryptionKey string) error
 }
 
 // ExportToFile exports the DB to a file at the given path. The file is encoded as gob,
-// optionally compressed with flate (as gzip) and optionally encrypted with AES-GCM.
+// with the documents in a compact binary format, optionally compressed with
+// flate (as gzip) and optionally encrypted with AES-GCM.
 // This works for both the in-memory and persistent DBs.
 // If the file exists, it's overwritten, otherwise created.
 //
@@ -356,31 +610,12 @@ func (db *DB) ExportToFile(filePath string, compress bool, encryptionKey string)
 		}
 	}
 
-	// Create persistence structs with exported fields so that they can be encoded
-	// as gob.
-	type persistenceCollection struct {
-		Name      string
-		Metadata  map[string]string
-		Documents map[string]*Document
-	}
-	persistenceDB := struct {
-		Collections map[string]*persistenceCollection
-	}{
-		Collections: make(map[string]*persistenceCollection, len(db.collections)),
-	}
-
-	db.collectionsLock.RLock()
-	defer db.collectionsLock.RUnlock()
-
-	for k, v := range db.collections {
-		persistenceDB.Collections[k] = &persistenceCollection{
-			Name:      v.Name,
-			Metadata:  v.metadata,
-			Documents: v.documents,
-		}
+	persistenceDB, err := db.exportSnapshot()
+	if err != nil {
+		return err
 	}
 
-	err := persistToFile(filePath, persistenceDB, compress, encryptionKey)
+	err = persistToFile(filePath, persistenceDB, compress, encryptionKey)
 	if err != nil {
 		return fmt.Errorf("couldn't export DB: %w", err)
 	}
@@ -389,10 +624,17 @@ func (db *DB) ExportToFile(filePath string, compress bool, encryptionKey string)
 }
 
 // ExportToWriter exports the DB to a writer. The stream is encoded as gob,
-// optionally compressed with flate (as gzip) and optionally encrypted with AES-GCM.
+// with the documents in a compact binary format, optionally compressed with
+// flate (as gzip) and optionally encrypted with AES-GCM.
 // This works for both the in-memory and persistent DBs.
 // If the writer has to be closed, it's the caller's responsibility.
 //
+// The DB is streamed to the writer in one pass, so it can be a network stream
+// like an HTTP response or an S3 upload, without a temporary file. The DB is
+// only locked while a snapshot of it is taken, not while the stream is
+// written. Encrypted streams are built in memory entirely before they're
+// written.
+//
 //   - writer: An implementation of [io.Writer]
 //   - compress: Optional. Compresses as gzip if true.
 //   - encryptionKey: Optional. Encrypts with AES-GCM if provided. Must be 32 bytes
@@ -405,36 +647,53 @@ func (db *DB) ExportToWriter(writer io.Writer, compress bool, encryptionKey stri
 		}
 	}
 
-	// Create persistence structs with exported fields so that they can be encoded
-	// as gob.
-	type persistenceCollection struct {
-		Name      string
-		Metadata  map[string]string
-		Documents map[string]*Document
+	persistenceDB, err := db.exportSnapshot()
+	if err != nil {
+		return err
 	}
-	persistenceDB := struct {
-		Collections map[string]*persistenceCollection
-	}{
-		Collections: make(map[string]*persistenceCollection, len(db.collections)),
+
+	err = persistToWriter(writer, persistenceDB, compress, encryptionKey)
+	if err != nil {
+		return fmt.Errorf("couldn't export DB: %w", err)
 	}
 
+	return nil
+}
+
+// exportSnapshot returns a snapshot of the DB for exports. The document maps are
+// copied, but not the documents, as they're replaced instead of modified when
+// they're updated.
+func (db *DB) exportSnapshot() (persistenceDB, error) {
 	db.collectionsLock.RLock()
 	defer db.collectionsLock.RUnlock()
 
+	snapshot := persistenceDB{
+		Collections: make(map[string]*persistenceCollection, len(db.collections)),
+		Aliases:     maps.Clone(db.aliases),
+	}
 	for k, v := range db.collections {
-		persistenceDB.Collections[k] = &persistenceCollection{
+		v.documentsLock.RLock()
+		documents, err := v.documentsWithContentLocked()
+		if err == nil {
+			documents = maps.Clone(documents)
+		}
+		var archived map[string]*Document
+		if err == nil && v.archive != nil && len(v.archive.Segments) != 0 {
+			archived, err = v.archivedDocumentsLocked()
+		}
+		v.documentsLock.RUnlock()
+		if err != nil {
+			return persistenceDB{}, fmt.Errorf("couldn't export collection %q: %w", k, err)
+		}
+		snapshot.Collections[k] = &persistenceCollection{
 			Name:      v.Name,
-			Metadata:  v.metadata,
-			Documents: v.documents,
+			Metadata:  v.getMetadata(),
+			Documents: documents,
+			Archived:  archived,
+			Config:    v.getConfig(),
 		}
 	}
-
-	err := persistToWriter(writer, persistenceDB, compress, encryptionKey)
-	if err != nil {
-		return fmt.Errorf("couldn't export DB: %w", err)
-	}
-
-	return nil
+	return snapshot, nil
 }
 
 // CreateCollection creates a new collection with the given name and metadata.
@@ -443,20 +702,192 @@ func (db *DB) ExportToWriter(writer io.Writer, compress bool, encryptionKey stri
 //   - metadata: Optional metadata to associate with the collection.
 //   - embeddingFunc: Optional function to use to embed documents.
 //     Uses the default embedding function if not provided.
+//
+// For more options, see [DB.CreateCollectionWithOptions].
 func (db *DB) CreateCollection(name string, metadata map[string]string, embeddingFunc EmbeddingFunc) (*Collection, error) {
+	return db.CreateCollectionWithOptions(name, CollectionOptions{
+		Metadata:      metadata,
+		EmbeddingFunc: embeddingFunc,
+	})
+}
+
+// CollectionOptions are the options for [DB.CreateCollectionWithOptions].
+// All fields are optional. They correspond to the collection's setters, so the
+// collection is created with its final configuration, instead of being changed
+// (and persisted) multiple times after being created.
+type CollectionOptions struct {
+	// Metadata to associate with the collection.
+	Metadata map[string]string
+
+	// The function to use to embed documents and queries. Uses the default
+	// embedding function if not provided.
+	EmbeddingFunc EmbeddingFunc
+
+	// See [Collection.SetEmbeddingFuncMultimodal].
+	EmbeddingFuncMultimodal EmbeddingFuncMultimodal
+
+	// See [Collection.SetEmbeddingTemplate].
+	EmbeddingTemplate EmbeddingTemplate
+
+	// See [Collection.SetPreprocessing].
+	Preprocessing PreprocessingOptions
+
+	// See [Collection.SetSummaryIndexing].
+	SummaryIndexing SummaryIndexing
+
+	// See [Collection.SetEmbeddingInstructions].
+	EmbeddingInstructions EmbeddingInstructions
+
+	// See [Collection.SetNormalizationPolicy].
+	NormalizationPolicy NormalizationPolicy
+
+	// See [Collection.SetContentSpillover]. Requires a persistent DB.
+	ContentSpillover bool
+	ContentCacheSize int
+
+	// See [Collection.SetSoftDelete].
+	SoftDeletePurgeAfter time.Duration
+
+	// See [Collection.SetBoostRules].
+	BoostRules []BoostRule
+
+	// See [Collection.SetMetadataSchema].
+	MetadataSchema *MetadataSchema
+
+	// See [Collection.SetLimits].
+	Limits CollectionLimits
+
+	// See [Collection.SetFeedbackBoost].
+	FeedbackBoost FeedbackBoost
+
+	// See [Collection.SetRetrievalVariants].
+	RetrievalVariants []RetrievalVariant
+
+	// See [Collection.SetLanguageDetection].
+	LanguageMetadataKey string
+
+	// See [Collection.SetAccessStats].
+	AccessStats bool
+
+	// If GetOrCreate is true and a collection with the name exists already, it's
+	// returned instead of being replaced, like with [DB.GetOrCreateCollection].
+	// The other options are then only used to set the embedding functions if
+	// none are set yet (see [DB.GetCollection]), and the existing collection's
+	// configuration isn't changed.
+	GetOrCreate bool
+}
+
+// CreateCollectionWithOptions creates a new collection with the given name and
+// options. Like with [DB.CreateCollection], an existing collection with the
+// same name is replaced, unless [CollectionOptions.GetOrCreate] is true.
+func (db *DB) CreateCollectionWithOptions(name string, opts CollectionOptions) (*Collection, error) {
+	if err := db.checkOpen(); err != nil {
+		return nil, err
+	}
 	if name == "" {
 		return nil, errors.New("collection name is empty")
 	}
-	if embeddingFunc == nil {
-		embeddingFunc = NewEmbeddingFuncDefault()
+	if opts.ContentSpillover && db.persistDirectory == "" {
+		return nil, errors.New("content spillover requires a persistent DB")
+	}
+	if opts.EmbeddingFunc == nil {
+		opts.EmbeddingFunc = NewEmbeddingFuncDefault()
+	}
+
+	if err := opts.Limits.validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.FeedbackBoost.validate(); err != nil {
+		return nil, err
+	}
+	if opts.SummaryIndexing.MinLength < 0 {
+		return nil, errors.New("minLength of summary indexing must be >= 0")
 	}
-	collection, err := newCollection(name, metadata, embeddingFunc, db.persistDirectory, db.compress)
+
+	config := collectionConfig{
+		EmbeddingTemplate:     opts.EmbeddingTemplate,
+		EmbeddingInstructions: opts.EmbeddingInstructions,
+		NormalizationPolicy:   opts.NormalizationPolicy,
+		SoftDeletePurgeAfter:  opts.SoftDeletePurgeAfter,
+		Limits:                opts.Limits,
+		FeedbackBoost:         opts.FeedbackBoost,
+		LanguageMetadataKey:   opts.LanguageMetadataKey,
+		AccessStats:           opts.AccessStats,
+	}
+	boostRules, err := cloneBoostRules(opts.BoostRules)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't create collection: %w", err)
+		return nil, err
+	}
+	config.BoostRules = boostRules
+	metadataSchema, err := cloneMetadataSchema(opts.MetadataSchema)
+	if err != nil {
+		return nil, err
+	}
+	config.MetadataSchema = metadataSchema
+	retrievalVariants, err := cloneRetrievalVariants(opts.RetrievalVariants)
+	if err != nil {
+		return nil, err
+	}
+	config.RetrievalVariants = retrievalVariants
+	if opts.ContentSpillover {
+		config.ContentSpillover = true
+		config.ContentCacheSize = opts.ContentCacheSize
+		if config.ContentCacheSize <= 0 {
+			config.ContentCacheSize = defaultContentCacheSize
+		}
 	}
 
 	db.collectionsLock.Lock()
 	defer db.collectionsLock.Unlock()
+
+	if target, ok := db.aliases[name]; ok {
+		if !opts.GetOrCreate {
+			return nil, fmt.Errorf("collection name %q is used by an alias", name)
+		}
+		name = target
+	}
+	if existing, ok := db.collections[name]; ok && opts.GetOrCreate {
+		// Functions aren't persisted, so a loaded collection doesn't have them.
+		if existing.embed == nil {
+			existing.embed = opts.EmbeddingFunc
+		}
+		if opts.EmbeddingFuncMultimodal != nil {
+			existing.configLock.Lock()
+			if existing.embedMultimodal == nil {
+				existing.embedMultimodal = opts.EmbeddingFuncMultimodal
+			}
+			existing.configLock.Unlock()
+		}
+		if len(opts.Preprocessing.Preprocessors) != 0 {
+			existing.configLock.Lock()
+			if len(existing.preprocessing.Preprocessors) == 0 {
+				existing.preprocessing = opts.Preprocessing
+			}
+			existing.configLock.Unlock()
+		}
+		if opts.SummaryIndexing.Summarize != nil {
+			existing.configLock.Lock()
+			if existing.summaryIndexing.Summarize == nil {
+				existing.summaryIndexing = opts.SummaryIndexing
+			}
+			existing.configLock.Unlock()
+		}
+		return existing, nil
+	}
+
+	collection, err := newCollection(name, opts.Metadata, opts.EmbeddingFunc, config, db.persistDirectory, db.compress, db.syncer)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create collection: %w", err)
+	}
+	collection.embedMultimodal = opts.EmbeddingFuncMultimodal
+	collection.SetPreprocessing(opts.Preprocessing)
+	collection.summaryIndexing = opts.SummaryIndexing
+	collection.initSegments(db.segmentSize)
+	if config.ContentSpillover {
+		collection.enableContentSpilloverLocked(config.ContentCacheSize)
+	}
+	collection.db = db
+
 	db.collections[name] = collection
 	return collection, nil
 }
@@ -480,6 +911,50 @@ func (db *DB) ListCollections() map[string]*Collection {
 	return res
 }
 
+// ListCollectionsWhere returns the collections whose metadata contains all
+// key-value pairs of the where filter, sorted by name. If where is nil, all
+// collections match.
+// For DBs with many collections, for example one per user, the result can be
+// paginated with offset and limit, where a limit of 0 means no limit.
+// Like with [DB.ListCollections], the returned collections are the original ones.
+func (db *DB) ListCollectionsWhere(where map[string]string, offset, limit int) ([]*Collection, error) {
+	if offset < 0 {
+		return nil, errors.New("offset must be >= 0")
+	}
+	if limit < 0 {
+		return nil, errors.New("limit must be >= 0")
+	}
+
+	db.collectionsLock.RLock()
+	var res []*Collection
+	for _, c := range db.collections {
+		metadata := c.getMetadata()
+		matches := true
+		for k, v := range where {
+			if metadata[k] != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			res = append(res, c)
+		}
+	}
+	db.collectionsLock.RUnlock()
+
+	slices.SortFunc(res, func(a, b *Collection) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	if offset >= len(res) {
+		return nil, nil
+	}
+	res = res[offset:]
+	if limit > 0 && limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
 // GetCollection returns the collection with the given name.
 // The embeddingFunc param is only used if the DB is persistent and was just loaded
 // from storage, in which case no embedding func is set yet (funcs are not (de-)serializable).
@@ -487,6 +962,8 @@ func (db *DB) ListCollections() map[string]*Collection {
 // The returned collection is a reference to the original collection, so any methods
 // on the collection like Add() will be reflected on the DB's collection. Those
 // operations are concurrency-safe.
+// The name can also be an alias (see [DB.SetAlias]), in which case the
+// collection it points to is returned.
 // If the collection doesn't exist, this returns nil.
 func (db *DB) GetCollection(name string, embeddingFunc EmbeddingFunc) *Collection {
 	db.collectionsLock.RLock()
@@ -494,7 +971,10 @@ func (db *DB) GetCollection(name string, embeddingFunc EmbeddingFunc) *Collectio
 
 	c, ok := db.collections[name]
 	if !ok {
-		return nil
+		c, ok = db.collections[db.aliases[name]]
+		if !ok {
+			return nil
+		}
 	}
 
 	if c.embed == nil {
@@ -527,11 +1007,22 @@ func (db *DB) GetOrCreateCollection(name string, metadata map[string]string, emb
 	return collection, nil
 }
 
-// DeleteCollection deletes the collection with the given name.
+// DeleteCollection deletes the collection with the given name, and the aliases
+// that point to it. Aliases themselves can't be used as name here, to prevent
+// deleting the wrong collection after the alias has been switched.
 // If the collection doesn't exist, this is a no-op.
 // If the DB is persistent, it also removes the collection's directory.
 // You shouldn't hold any references to the collection after calling this method.
 func (db *DB) DeleteCollection(name string) error {
+	return db.DeleteCollectionWithContext(context.Background(), name)
+}
+
+// DeleteCollectionWithContext is like [DB.DeleteCollection], but records the
+// principal of the context in the audit log, see [DB.SetAuditLog].
+func (db *DB) DeleteCollectionWithContext(ctx context.Context, name string) error {
+	if err := db.checkOpen(); err != nil {
+		return err
+	}
 	db.collectionsLock.Lock()
 	defer db.collectionsLock.Unlock()
 
@@ -540,6 +1031,16 @@ func (db *DB) DeleteCollection(name string) error {
 		return nil
 	}
 
+	// The index files must be closed before they're removed, at least on
+	// Windows.
+	col.documentsLock.Lock()
+	ids := col.auditedIDsLocked()
+	err := col.dropIndexLocked()
+	col.documentsLock.Unlock()
+	if err != nil {
+		return fmt.Errorf("couldn't drop vector index: %w", err)
+	}
+
 	if db.persistDirectory != "" {
 		collectionPath := col.persistDirectory
 		err := os.RemoveAll(collectionPath)
@@ -549,16 +1050,40 @@ func (db *DB) DeleteCollection(name string) error {
 	}
 
 	delete(db.collections, name)
-	return nil
+	err = db.deleteCollectionAliasesLocked(name)
+	if err != nil {
+		return err
+	}
+	return db.audit(ctx, name, AuditActionDeleteCollection, ids)
 }
 
-// Reset removes all collections from the DB.
+// Reset removes all collections, aliases and blobs from the DB.
 // If the DB is persistent, it also removes all contents of the DB directory.
 // You shouldn't hold any references to old collections after calling this method.
 func (db *DB) Reset() error {
+	return db.ResetWithContext(context.Background())
+}
+
+// ResetWithContext is like [DB.Reset], but records the principal of the
+// context in the audit log, see [DB.SetAuditLog].
+func (db *DB) ResetWithContext(ctx context.Context) error {
+	if err := db.checkOpen(); err != nil {
+		return err
+	}
 	db.collectionsLock.Lock()
 	defer db.collectionsLock.Unlock()
 
+	ids := make(map[string][]string, len(db.collections))
+	for name, c := range db.collections {
+		c.documentsLock.Lock()
+		ids[name] = c.auditedIDsLocked()
+		err := c.dropIndexLocked()
+		c.documentsLock.Unlock()
+		if err != nil {
+			return fmt.Errorf("couldn't drop vector index: %w", err)
+		}
+	}
+
 	if db.persistDirectory != "" {
 		err := os.RemoveAll(db.persistDirectory)
 		if err != nil {
@@ -573,5 +1098,20 @@ func (db *DB) Reset() error {
 
 	// Just assign a new map, the GC will take care of the rest.
 	db.collections = make(map[string]*Collection)
+	db.aliases = nil
+	db.blobsLock.Lock()
+	db.blobs = nil
+	db.blobsLock.Unlock()
+
+	names := make([]string, 0, len(ids))
+	for name := range ids {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if err := db.audit(ctx, name, AuditActionDeleteCollection, ids[name]); err != nil {
+			return err
+		}
+	}
 	return nil
 }
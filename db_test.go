@@ -2,6 +2,8 @@ package chromem
 
 import (
 	"context"
+	"errors"
+	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -163,6 +165,37 @@ func TestDB_ImportExport(t *testing.T) {
 	}
 }
 
+func TestDB_ImportExport_Stream(t *testing.T) {
+	ctx := context.Background()
+	orig := NewDB()
+	c, err := orig.CreateCollection("test", map[string]string{"foo": "bar"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{-0.40824828, 0.40824828, 0.81649655}, Content: "test"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	for _, compress := range []bool{false, true} {
+		// A pipe doesn't support seeking, like network streams
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(orig.ExportToWriter(pw, compress, ""))
+		}()
+
+		new := NewDB()
+		err = new.ImportFromReader(pr, "")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		newC := new.GetCollection("test", nil)
+		if newC == nil || newC.Count() != 1 || newC.documents["1"].Content != "test" {
+			t.Fatal("expected imported collection with 1 document")
+		}
+	}
+}
+
 func TestDB_CreateCollection(t *testing.T) {
 	// Values in the collection
 	name := "test"
@@ -216,6 +249,72 @@ func TestDB_CreateCollection(t *testing.T) {
 	})
 }
 
+func TestDB_CreateCollectionWithOptions(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	opts := CollectionOptions{
+		Metadata:            map[string]string{"foo": "bar"},
+		EmbeddingTemplate:   EmbeddingTemplateE5,
+		NormalizationPolicy: NormalizationPolicyReject,
+		ContentSpillover:    true,
+	}
+	c, err := db.CreateCollectionWithOptions("test", opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.contentCache == nil {
+		t.Fatal("expected content spillover to be enabled")
+	}
+	expectedConfig := collectionConfig{
+		EmbeddingTemplate:   EmbeddingTemplateE5,
+		NormalizationPolicy: NormalizationPolicyReject,
+		ContentSpillover:    true,
+		ContentCacheSize:    defaultContentCacheSize,
+	}
+
+	// The configuration is persisted
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// GetOrCreate returns the existing collection without changing it
+	opts.GetOrCreate = true
+	opts.NormalizationPolicy = NormalizationPolicyNormalize
+	c, err = db.CreateCollectionWithOptions("test", opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !reflect.DeepEqual(c.getConfig(), expectedConfig) {
+		t.Fatalf("expected config %+v, got %+v", expectedConfig, c.getConfig())
+	}
+	if c.getMetadata()["foo"] != "bar" {
+		t.Fatal("expected metadata foo=bar, got", c.getMetadata())
+	}
+	if c.embed == nil {
+		t.Fatal("expected embedding func to be set")
+	}
+
+	// Without GetOrCreate the collection is replaced
+	opts.GetOrCreate = false
+	c2, err := db.CreateCollectionWithOptions("test", opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c2 == c || c2.getConfig().NormalizationPolicy != NormalizationPolicyNormalize {
+		t.Fatal("expected new collection")
+	}
+
+	// Content spillover requires a persistent DB
+	_, err = NewDB().CreateCollectionWithOptions("test", CollectionOptions{ContentSpillover: true})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
 func TestDB_ListCollections(t *testing.T) {
 	// Values in the collection
 	name := "test"
@@ -267,6 +366,58 @@ func TestDB_ListCollections(t *testing.T) {
 	}
 }
 
+func TestDB_ListCollectionsWhere(t *testing.T) {
+	db := NewDB()
+	for _, c := range []struct {
+		name, owner string
+	}{
+		{"c", "alice"},
+		{"a", "alice"},
+		{"b", "bob"},
+		{"d", "alice"},
+	} {
+		_, err := db.CreateCollection(c.name, map[string]string{"owner": c.owner}, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	tt := []struct {
+		name     string
+		where    map[string]string
+		offset   int
+		limit    int
+		expected []string
+	}{
+		{"All", nil, 0, 0, []string{"a", "b", "c", "d"}},
+		{"Where", map[string]string{"owner": "alice"}, 0, 0, []string{"a", "c", "d"}},
+		{"Where no match", map[string]string{"owner": "carol"}, 0, 0, nil},
+		{"Limit", map[string]string{"owner": "alice"}, 0, 2, []string{"a", "c"}},
+		{"Offset", map[string]string{"owner": "alice"}, 2, 2, []string{"d"}},
+		{"Offset too large", nil, 4, 0, nil},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := db.ListCollectionsWhere(tc.where, tc.offset, tc.limit)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			var names []string
+			for _, c := range res {
+				names = append(names, c.Name)
+			}
+			if !slices.Equal(names, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, names)
+			}
+		})
+	}
+
+	_, err := db.ListCollectionsWhere(nil, -1, 0)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
 func TestDB_GetCollection(t *testing.T) {
 	// Values in the collection
 	name := "test"
@@ -443,3 +594,50 @@ func TestDB_Reset(t *testing.T) {
 		t.Fatal("expected 0 collections, got", len(db.collections))
 	}
 }
+
+func TestDB_Errors(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{0.6, 0.8}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = db.SetAlias("alias", "missing")
+	if !errors.Is(err, ErrCollectionNotFound) {
+		t.Fatal("expected ErrCollectionNotFound, got", err)
+	}
+	_, err = c.SimilarToDocument(ctx, "missing", 1, nil, nil)
+	if !errors.Is(err, ErrDocumentNotFound) {
+		t.Fatal("expected ErrDocumentNotFound, got", err)
+	}
+	_, err = c.QueryEmbedding(ctx, []float32{1, 0, 0}, 1, nil, nil)
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatal("expected ErrDimensionMismatch, got", err)
+	}
+
+	// Modifications after closing
+	if err := db.Close(); err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{0.8, 0.6}})
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+	_, err = db.CreateCollection("test2", nil, nil)
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+	err = db.DeleteCollection("test")
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+	// Reading is still possible
+	if c.Count() != 1 {
+		t.Fatal("expected 1 document, got", c.Count())
+	}
+}
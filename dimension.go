@@ -0,0 +1,56 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDimensionMismatch is returned when embeddings don't have the same number
+// of dimensions. Errors with more details, like [DimensionMismatchError], wrap
+// it, so it can be checked with [errors.Is].
+var ErrDimensionMismatch = errors.New("dimension mismatch")
+
+// DimensionMismatchError is returned when an embedding doesn't have the same
+// number of dimensions as the embeddings in the collection, which is usually
+// the case when they were created with different embedding models.
+type DimensionMismatchError struct {
+	// The ID of the document with the mismatching embedding. Empty for queries.
+	DocumentID string
+	// The number of dimensions of the embeddings in the collection.
+	Expected int
+	// The number of dimensions of the mismatching embedding.
+	Actual int
+}
+
+func (e *DimensionMismatchError) Error() string {
+	if e.DocumentID == "" {
+		return fmt.Sprintf("query embedding has %d dimensions, but the collection's embeddings have %d", e.Actual, e.Expected)
+	}
+	return fmt.Sprintf("embedding of document %q has %d dimensions, but the collection's embeddings have %d", e.DocumentID, e.Actual, e.Expected)
+}
+
+func (e *DimensionMismatchError) Unwrap() error {
+	return ErrDimensionMismatch
+}
+
+// Dimension returns the number of dimensions of the collection's embeddings, or
+// 0 if the collection is empty.
+func (c *Collection) Dimension() int {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	return c.dimensionLocked("")
+}
+
+// dimensionLocked returns the number of dimensions of the embeddings of the
+// collection's documents other than the one with the given ID, or 0 if there
+// are none. As all documents have the same dimension, it only looks at one.
+// The caller must hold the documents lock.
+func (c *Collection) dimensionLocked(excludeID string) int {
+	for id, doc := range c.documents {
+		if id != excludeID {
+			return len(doc.Embedding)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCollection_DimensionMismatch(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Dimension() != 0 {
+		t.Fatal("expected dimension 0, got", c.Dimension())
+	}
+
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{-0.40824828, 0.40824828, 0.81649655}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Dimension() != 3 {
+		t.Fatal("expected dimension 3, got", c.Dimension())
+	}
+
+	// Adding a document with a different dimension
+	err = c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{0.6, 0.8}})
+	var dimErr *DimensionMismatchError
+	if !errors.As(err, &dimErr) {
+		t.Fatal("expected DimensionMismatchError, got", err)
+	}
+	want := DimensionMismatchError{DocumentID: "2", Expected: 3, Actual: 2}
+	if *dimErr != want {
+		t.Fatalf("expected %+v, got %+v", want, *dimErr)
+	}
+
+	// Querying with a different dimension
+	_, err = c.QueryEmbedding(ctx, []float32{0.6, 0.8}, 1, nil, nil)
+	if !errors.As(err, &dimErr) {
+		t.Fatal("expected DimensionMismatchError, got", err)
+	}
+	want = DimensionMismatchError{Expected: 3, Actual: 2}
+	if *dimErr != want {
+		t.Fatalf("expected %+v, got %+v", want, *dimErr)
+	}
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatal("expected ErrDimensionMismatch, got", err)
+	}
+
+	// Replacing the only document with one of a different dimension is allowed
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{0.6, 0.8}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Dimension() != 2 {
+		t.Fatal("expected dimension 2, got", c.Dimension())
+	}
+}
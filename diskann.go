@@ -0,0 +1,671 @@
+package chromem
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// IndexTypeDiskANN is a graph index in the style of DiskANN (Vamana), which is
+// stored on disk instead of in memory: Each document is a node of the graph,
+// with edges to similar documents, and queries search the graph greedily from
+// a central node, reading the embeddings and edges of the visited nodes from
+// the file. The file is memory-mapped where supported, and read with regular
+// file reads otherwise, for example in WebAssembly. Only the document IDs of
+// the nodes and the nodes near the central one are kept in memory.
+//
+// The index requires a persistent collection, whose directory it's stored in.
+// Note that the collection still keeps its documents, including the
+// embeddings, in memory (see [DB.SetMemoryBudget]), so the index reduces the
+// memory of the graph, not of the documents. Building the index keeps the
+// graph in memory.
+//
+// The graph isn't changed after it's built: Documents that are added or
+// updated later are scanned by all queries in addition to the nodes that the
+// search found, and deleted ones are skipped, until [Collection.RebuildIndex].
+const IndexTypeDiskANN IndexType = "diskann"
+
+const (
+	// diskANNDefaultDegree is the default maximum number of edges per node.
+	diskANNDefaultDegree = 32
+	// diskANNDefaultSearchListSize is the default number of candidates that
+	// queries keep while searching.
+	diskANNDefaultSearchListSize = 64
+	// diskANNAlpha is the factor by which the pruning of the edges prefers
+	// distant nodes in the second pass of the build, which makes searches take
+	// fewer steps.
+	diskANNAlpha = 1.2
+	// diskANNCacheNodes is the number of nodes near the central one that are
+	// kept in memory, as all searches visit them.
+	diskANNCacheNodes = 1024
+
+	diskANNMagic      = "CHRMDANN"
+	diskANNVersion    = 1
+	diskANNHeaderSize = 32
+)
+
+// diskANNIndexType is the implementation of [IndexTypeDiskANN].
+var diskANNIndexType = indexType{
+	validate: func(options IndexOptions) error {
+		if options.Degree < 0 || options.SearchListSize < 0 {
+			return errors.New("degree and searchListSize must be >= 0")
+		}
+		return nil
+	},
+	build: buildDiskANN,
+	load:  loadDiskANN,
+	buildOptions: func(options IndexOptions) IndexOptions {
+		options.SearchListSize = 0
+		return options
+	},
+	tuningOptions: func(int) []IndexOptions {
+		var res []IndexOptions
+		for _, degree := range []int{16, 32, 64} {
+			for _, listSize := range []int{16, 32, 64, 128, 256} {
+				res = append(res, IndexOptions{Type: IndexTypeDiskANN, Degree: degree, SearchListSize: listSize})
+			}
+		}
+		return res
+	},
+}
+
+// diskANNData is the data of a DiskANN index file. It's memory-mapped where
+// supported, see diskann_unix.go.
+type diskANNData interface {
+	// read returns n bytes at the offset. It can use the buffer, which must be
+	// at least n bytes long.
+	read(off, n int64, buf []byte) ([]byte, error)
+	close() error
+}
+
+// diskANNNode is a node of the graph that's kept in memory.
+type diskANNNode struct {
+	embedding []float32
+	neighbors []int32
+}
+
+// diskANNIndex is a DiskANN index. The file has a header, then a record for
+// each node with its embedding and edges, and then the document IDs of the
+// nodes.
+type diskANNIndex struct {
+	path   string
+	file   *diskANNFile
+	dim    int
+	degree int
+	medoid int32
+	// The document IDs of the nodes, and the nodes of the document IDs. They
+	// and the cache aren't changed after the index is opened, so they're
+	// shared with clones.
+	ids   []string
+	nodes map[string]int32
+	cache map[int32]diskANNNode
+
+	searchListSize int
+
+	// The IDs of the documents that were added or updated after the build,
+	// which all queries scan.
+	added map[string]struct{}
+	// The nodes of the documents that were deleted or updated after the build,
+	// which queries skip.
+	deleted map[int32]struct{}
+}
+
+func (x *diskANNIndex) recordSize() int64 {
+	return int64(4*x.dim + 4 + 4*x.degree)
+}
+
+// node reads the node's embedding and edges from the cache or the file.
+func (x *diskANNIndex) node(i int32, buf []byte) (diskANNNode, error) {
+	if node, ok := x.cache[i]; ok {
+		return node, nil
+	}
+	node, err := x.file.readNode(diskANNHeaderSize+int64(i)*x.recordSize(), x.recordSize(), buf, x.dim)
+	if err != nil {
+		return diskANNNode{}, fmt.Errorf("couldn't read node: %w", err)
+	}
+	return node, nil
+}
+
+// diskANNFile is the data of the index file, which the index shares with its
+// clones for snapshots. Once the index is dropped, reads fail instead of
+// accessing unmapped memory, so that queries of the snapshots scan all
+// documents.
+type diskANNFile struct {
+	lock sync.RWMutex
+	// Nil when closed.
+	data diskANNData
+}
+
+// readNode reads and decodes the node at the offset.
+func (f *diskANNFile) readNode(off, n int64, buf []byte, dim int) (diskANNNode, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if f.data == nil {
+		return diskANNNode{}, errors.New("index file is closed")
+	}
+	b, err := f.data.read(off, n, buf)
+	if err != nil {
+		return diskANNNode{}, err
+	}
+	return decodeDiskANNNode(b, dim), nil
+}
+
+func (f *diskANNFile) close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.data == nil {
+		return nil
+	}
+	err := f.data.close()
+	f.data = nil
+	return err
+}
+
+func decodeDiskANNNode(b []byte, dim int) diskANNNode {
+	node := diskANNNode{embedding: make([]float32, dim)}
+	for j := range node.embedding {
+		node.embedding[j] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*j:]))
+	}
+	b = b[4*dim:]
+	node.neighbors = make([]int32, binary.LittleEndian.Uint32(b))
+	for j := range node.neighbors {
+		node.neighbors[j] = int32(binary.LittleEndian.Uint32(b[4+4*j:]))
+	}
+	return node
+}
+
+func (x *diskANNIndex) set(doc *Document) {
+	if i, ok := x.nodes[doc.ID]; ok {
+		x.deleted[i] = struct{}{}
+	}
+	x.added[doc.ID] = struct{}{}
+}
+
+func (x *diskANNIndex) remove(id string) {
+	if i, ok := x.nodes[id]; ok {
+		x.deleted[i] = struct{}{}
+	}
+	delete(x.added, id)
+}
+
+func (x *diskANNIndex) contains(id string) bool {
+	if _, ok := x.added[id]; ok {
+		return true
+	}
+	i, ok := x.nodes[id]
+	if !ok {
+		return false
+	}
+	_, deleted := x.deleted[i]
+	return !deleted
+}
+
+// candidates returns the documents of the nodes that the search of the graph
+// found for the normalized query, and the documents that were added after the
+// build. It returns false if the query has a different dimension, or if the
+// file can't be read.
+func (x *diskANNIndex) candidates(query []float32, documents map[string]*Document) ([]*Document, bool) {
+	if len(query) != x.dim {
+		return nil, false
+	}
+	buf := make([]byte, x.recordSize())
+	var readErr error
+	nearest, _ := greedySearch(x.medoid, x.searchListSize, func(i int32) (float32, []int32) {
+		node, err := x.node(i, buf)
+		if err != nil {
+			readErr = err
+			return float32(math.Inf(1)), nil
+		}
+		sim, _ := dotProduct(query, node.embedding)
+		return 1 - sim, node.neighbors
+	})
+	if readErr != nil {
+		return nil, false
+	}
+
+	docs := make([]*Document, 0, len(nearest)+len(x.added))
+	for _, candidate := range nearest {
+		if _, ok := x.deleted[candidate.node]; ok {
+			continue
+		}
+		if doc, ok := documents[x.ids[candidate.node]]; ok {
+			docs = append(docs, doc)
+		}
+	}
+	for id := range x.added {
+		if doc, ok := documents[id]; ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, true
+}
+
+// setSearchOptions sets the number of candidates that queries keep while
+// searching.
+func (x *diskANNIndex) setSearchOptions(options IndexOptions) {
+	x.searchListSize = options.SearchListSize
+	if x.searchListSize <= 0 {
+		x.searchListSize = diskANNDefaultSearchListSize
+	}
+}
+
+// clone copies the changes since the build, and shares the file and the nodes.
+func (x *diskANNIndex) clone() vectorIndex {
+	res := *x
+	res.added = maps.Clone(x.added)
+	res.deleted = maps.Clone(x.deleted)
+	return &res
+}
+
+func (x *diskANNIndex) persist(config *collectionConfig) {
+	config.IndexFile = filepath.Base(x.path)
+}
+
+// drop unmaps and removes the file.
+func (x *diskANNIndex) drop() error {
+	err := x.file.close()
+	if err != nil {
+		return fmt.Errorf("couldn't close index file: %w", err)
+	}
+	err = os.Remove(x.path)
+	if err != nil {
+		return fmt.Errorf("couldn't remove index file: %w", err)
+	}
+	return nil
+}
+
+// buildDiskANN builds the graph of the documents and writes it to a new file
+// in the collection's directory.
+func buildDiskANN(ctx context.Context, c *Collection, docs []*Document, dim int, options IndexOptions) (vectorIndex, error) {
+	if c.persistDirectory == "" {
+		return nil, errors.New("the DiskANN index requires a persistent collection")
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	embeddings := make([][]float32, len(docs))
+	for i, doc := range docs {
+		if len(doc.Embedding) != dim {
+			return nil, &DimensionMismatchError{Expected: dim, Actual: len(doc.Embedding)}
+		}
+		embeddings[i] = doc.Embedding
+	}
+	degree := options.Degree
+	if degree == 0 {
+		degree = diskANNDefaultDegree
+	}
+
+	graph, medoid, err := buildVamanaGraph(ctx, embeddings, degree)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp(c.persistDirectory, "diskann-*.idx")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create index file: %w", err)
+	}
+	path := f.Name()
+	err = writeDiskANN(f, docs, graph, medoid, dim, degree)
+	if err == nil {
+		err = c.syncer.syncFile(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = c.syncer.syncDir(path)
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("couldn't write index file: %w", err)
+	}
+
+	x, err := openDiskANN(path)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	x.setSearchOptions(options)
+	return x, nil
+}
+
+// loadDiskANN opens the persisted file, and applies the changes of the
+// documents since the build. It returns nil if there's no file, for example
+// after an import, so that queries scan all documents until the index is
+// rebuilt.
+func loadDiskANN(c *Collection, config collectionConfig, docs []*Document) (vectorIndex, error) {
+	if c.persistDirectory == "" || config.IndexFile == "" {
+		return nil, nil
+	}
+	path := filepath.Join(c.persistDirectory, config.IndexFile)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	x, err := openDiskANN(path)
+	if err != nil {
+		return nil, err
+	}
+	x.setSearchOptions(config.Index)
+
+	// Documents that were updated since the build have a different embedding
+	// than their node.
+	buf := make([]byte, x.recordSize())
+	present := make(map[int32]struct{}, len(docs))
+	for _, doc := range docs {
+		i, ok := x.nodes[doc.ID]
+		if !ok {
+			x.set(doc)
+			continue
+		}
+		present[i] = struct{}{}
+		node, err := x.node(i, buf)
+		if err != nil {
+			_ = x.file.close()
+			return nil, err
+		}
+		if !slices.Equal(node.embedding, doc.Embedding) {
+			x.set(doc)
+		}
+	}
+	for i := range x.ids {
+		if _, ok := present[int32(i)]; !ok {
+			x.deleted[int32(i)] = struct{}{}
+		}
+	}
+	return x, nil
+}
+
+// writeDiskANN writes the header, the nodes and the document IDs to the file.
+func writeDiskANN(w io.Writer, docs []*Document, graph [][]int32, medoid int32, dim, degree int) error {
+	bw := bufio.NewWriter(w)
+	header := make([]byte, 0, diskANNHeaderSize)
+	header = append(header, diskANNMagic...)
+	for _, v := range []int{diskANNVersion, dim, degree, len(docs), int(medoid)} {
+		header = binary.LittleEndian.AppendUint32(header, uint32(v))
+	}
+	header = header[:diskANNHeaderSize]
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]byte, 0, 4*dim+4+4*degree)
+	for i, doc := range docs {
+		record = record[:0]
+		for _, v := range doc.Embedding {
+			record = binary.LittleEndian.AppendUint32(record, math.Float32bits(v))
+		}
+		record = binary.LittleEndian.AppendUint32(record, uint32(len(graph[i])))
+		for _, neighbor := range graph[i] {
+			record = binary.LittleEndian.AppendUint32(record, uint32(neighbor))
+		}
+		// Unused edges are zero.
+		record = record[:cap(record)]
+		clear(record[4*dim+4+4*len(graph[i]):])
+		if _, err := bw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	for _, doc := range docs {
+		b := binary.LittleEndian.AppendUint32(nil, uint32(len(doc.ID)))
+		if _, err := bw.Write(append(b, doc.ID...)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// openDiskANN opens the file, reads the header and the document IDs, and
+// caches the nodes near the central one.
+func openDiskANN(path string) (*diskANNIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open index file: %w", err)
+	}
+	x, err := readDiskANN(f, path)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("couldn't read index file: %w", err)
+	}
+	return x, nil
+}
+
+// readDiskANN reads the index from the file, which it takes ownership of if
+// there's no error.
+func readDiskANN(f *os.File, path string) (*diskANNIndex, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, diskANNHeaderSize)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	if string(header[:len(diskANNMagic)]) != diskANNMagic {
+		return nil, errors.New("invalid file")
+	}
+	fields := make([]int, 5)
+	for i := range fields {
+		fields[i] = int(binary.LittleEndian.Uint32(header[len(diskANNMagic)+4*i:]))
+	}
+	if fields[0] != diskANNVersion {
+		return nil, fmt.Errorf("unsupported version %d", fields[0])
+	}
+	x := &diskANNIndex{
+		path:    path,
+		dim:     fields[1],
+		degree:  fields[2],
+		medoid:  int32(fields[4]),
+		ids:     make([]string, fields[3]),
+		nodes:   make(map[string]int32, fields[3]),
+		added:   make(map[string]struct{}),
+		deleted: make(map[int32]struct{}),
+	}
+	x.setSearchOptions(IndexOptions{})
+
+	idsOffset := diskANNHeaderSize + int64(len(x.ids))*x.recordSize()
+	if idsOffset > stat.Size() {
+		return nil, errors.New("truncated file")
+	}
+	r := bufio.NewReader(io.NewSectionReader(f, idsOffset, stat.Size()-idsOffset))
+	length := make([]byte, 4)
+	for i := range x.ids {
+		if _, err := io.ReadFull(r, length); err != nil {
+			return nil, err
+		}
+		id := make([]byte, binary.LittleEndian.Uint32(length))
+		if _, err := io.ReadFull(r, id); err != nil {
+			return nil, err
+		}
+		x.ids[i] = string(id)
+		x.nodes[x.ids[i]] = int32(i)
+	}
+
+	data, err := openDiskANNData(f, idsOffset)
+	if err != nil {
+		return nil, err
+	}
+	x.file = &diskANNFile{data: data}
+
+	// The nodes near the central one, breadth-first.
+	x.cache = make(map[int32]diskANNNode, min(diskANNCacheNodes, len(x.ids)))
+	buf := make([]byte, x.recordSize())
+	queue := []int32{x.medoid}
+	for len(queue) > 0 && len(x.cache) < diskANNCacheNodes {
+		i := queue[0]
+		queue = queue[1:]
+		if _, ok := x.cache[i]; ok {
+			continue
+		}
+		node, err := x.node(i, buf)
+		if err != nil {
+			_ = x.file.close()
+			return nil, err
+		}
+		x.cache[i] = node
+		queue = append(queue, node.neighbors...)
+	}
+	return x, nil
+}
+
+// diskANNCandidate is a node in the list of a graph search.
+type diskANNCandidate struct {
+	node      int32
+	distance  float32
+	neighbors []int32
+	expanded  bool
+}
+
+// greedySearch searches the graph from the start node for the listSize nodes
+// nearest to a query, whose distance and edges are returned by the node
+// function. It returns the nearest nodes, sorted by distance, and the nodes
+// whose edges were followed.
+func greedySearch(start int32, listSize int, node func(i int32) (float32, []int32)) ([]diskANNCandidate, []int32) {
+	distance, neighbors := node(start)
+	list := []diskANNCandidate{{node: start, distance: distance, neighbors: neighbors}}
+	seen := map[int32]struct{}{start: {}}
+	var visited []int32
+	for {
+		next := slices.IndexFunc(list, func(c diskANNCandidate) bool { return !c.expanded })
+		if next < 0 {
+			return list, visited
+		}
+		list[next].expanded = true
+		visited = append(visited, list[next].node)
+		for _, neighbor := range list[next].neighbors {
+			if _, ok := seen[neighbor]; ok {
+				continue
+			}
+			seen[neighbor] = struct{}{}
+			distance, neighbors := node(neighbor)
+			if len(list) == listSize && distance >= list[len(list)-1].distance {
+				continue
+			}
+			i, _ := slices.BinarySearchFunc(list, distance, func(c diskANNCandidate, d float32) int {
+				if c.distance < d {
+					return -1
+				} else if c.distance > d {
+					return 1
+				}
+				return 0
+			})
+			list = slices.Insert(list, i, diskANNCandidate{node: neighbor, distance: distance, neighbors: neighbors})
+			if len(list) > listSize {
+				list = list[:listSize]
+			}
+		}
+	}
+}
+
+// buildVamanaGraph builds the graph of the normalized embeddings with the
+// Vamana algorithm: Starting with random edges, each node is searched in the
+// graph, and gets edges to the visited nodes, pruned to the nearest ones in
+// different directions, and edges back from them. It returns the edges of the
+// nodes, and the central node, which searches start from.
+func buildVamanaGraph(ctx context.Context, embeddings [][]float32, degree int) ([][]int32, int32, error) {
+	n := len(embeddings)
+	degree = min(degree, n-1)
+	distance := func(a, b int32) float32 {
+		sim, _ := dotProduct(embeddings[a], embeddings[b])
+		return 1 - sim
+	}
+
+	// The central node is the one nearest to the mean.
+	mean := make([]float32, len(embeddings[0]))
+	for _, embedding := range embeddings {
+		for j, v := range embedding {
+			mean[j] += v
+		}
+	}
+	medoid, best := int32(0), float32(math.Inf(-1))
+	for i, embedding := range embeddings {
+		sim, _ := dotProduct(mean, embedding)
+		if sim > best {
+			medoid, best = int32(i), sim
+		}
+	}
+
+	// A fixed seed, so that the graph is the same for the same documents.
+	r := rand.New(rand.NewSource(1))
+	graph := make([][]int32, n)
+	for i := range graph {
+		graph[i] = make([]int32, 0, degree)
+		for len(graph[i]) < degree {
+			j := int32(r.Intn(n))
+			if j != int32(i) && !slices.Contains(graph[i], j) {
+				graph[i] = append(graph[i], j)
+			}
+		}
+	}
+
+	listSize := max(2*degree, diskANNDefaultSearchListSize)
+	for _, alpha := range []float32{1, diskANNAlpha} {
+		for k, i := range r.Perm(n) {
+			if k%256 == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, 0, err
+				}
+			}
+			p := int32(i)
+			_, visited := greedySearch(medoid, listSize, func(j int32) (float32, []int32) {
+				return distance(p, j), graph[j]
+			})
+			graph[p] = robustPrune(p, append(visited, graph[p]...), alpha, degree, distance)
+			for _, j := range graph[p] {
+				if slices.Contains(graph[j], p) {
+					continue
+				}
+				if len(graph[j]) < degree {
+					graph[j] = append(graph[j], p)
+				} else {
+					graph[j] = robustPrune(j, append(slices.Clone(graph[j]), p), alpha, degree, distance)
+				}
+			}
+		}
+	}
+	return graph, medoid, nil
+}
+
+// robustPrune returns up to degree of the candidates as edges of the node p:
+// The nearest candidate, and then the nearest ones that are nearer to p than
+// alpha times to the already selected ones, so that the edges go in different
+// directions.
+func robustPrune(p int32, candidates []int32, alpha float32, degree int, distance func(a, b int32) float32) []int32 {
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+	candidates = slices.DeleteFunc(candidates, func(c int32) bool { return c == p })
+	distances := make(map[int32]float32, len(candidates))
+	for _, c := range candidates {
+		distances[c] = distance(p, c)
+	}
+	slices.SortFunc(candidates, func(a, b int32) int {
+		if distances[a] < distances[b] {
+			return -1
+		} else if distances[a] > distances[b] {
+			return 1
+		}
+		return int(a - b)
+	})
+
+	res := make([]int32, 0, degree)
+	for len(candidates) > 0 && len(res) < degree {
+		nearest := candidates[0]
+		res = append(res, nearest)
+		candidates = slices.DeleteFunc(candidates[1:], func(c int32) bool {
+			return alpha*distance(nearest, c) <= distances[c]
+		})
+	}
+	return res
+}
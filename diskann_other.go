@@ -0,0 +1,30 @@
+//go:build !unix
+
+package chromem
+
+import (
+	"os"
+)
+
+// fileData is a file that's read with regular file reads, where memory mapping
+// isn't supported, for example in WebAssembly.
+type fileData struct {
+	f *os.File
+}
+
+// openDiskANNData reads the file, which it closes when the data is closed.
+func openDiskANNData(f *os.File, _ int64) (diskANNData, error) {
+	return fileData{f: f}, nil
+}
+
+func (d fileData) read(off, n int64, buf []byte) ([]byte, error) {
+	_, err := d.f.ReadAt(buf[:n], off)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func (d fileData) close() error {
+	return d.f.Close()
+}
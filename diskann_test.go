@@ -0,0 +1,301 @@
+package chromem
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_SetIndex_DiskANN(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	n, d, clusters := 2000, 16, 20
+	centers := make([][]float32, clusters)
+	for i := range centers {
+		centers[i] = make([]float32, d)
+		for j := range centers[i] {
+			centers[i][j] = r.Float32()*2 - 1
+		}
+	}
+	randomVector := func() []float32 {
+		center := centers[r.Intn(clusters)]
+		v := make([]float32, d)
+		for j := range v {
+			v[j] = center[j] + (r.Float32()-0.5)*0.2
+		}
+		return normalizeVector(v)
+	}
+	docs := make([]Document, n)
+	for i := range docs {
+		docs[i] = Document{ID: strconv.Itoa(i), Embedding: randomVector()}
+	}
+
+	// In-memory collections aren't supported.
+	memC, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = memC.AddDocuments(ctx, docs[:10], 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if err := memC.SetIndex(ctx, IndexOptions{Type: IndexTypeDiskANN}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, docs, 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	if err := c.SetIndex(ctx, IndexOptions{Type: IndexTypeDiskANN, Degree: -1}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.SetIndex(ctx, IndexOptions{Type: IndexTypeDiskANN, Degree: 16, SearchListSize: 32})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	indexFiles := func() []string {
+		t.Helper()
+		files, err := filepath.Glob(filepath.Join(c.persistDirectory, "diskann-*.idx"))
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		return files
+	}
+	if files := indexFiles(); len(files) != 1 || filepath.Base(files[0]) != c.getConfig().IndexFile {
+		t.Fatal("expected one index file, got", files)
+	}
+
+	recall := func(c *Collection) float64 {
+		t.Helper()
+		hits, total := 0, 0
+		for q := 0; q < 20; q++ {
+			qv := randomVector()
+			exact, err := c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: qv, NResults: 10, Exhaustive: true})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			approx, stats, err := c.QueryWithStats(ctx, QueryOptions{QueryEmbedding: qv, NResults: 10})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if stats.DocumentsScanned >= n/10 {
+				t.Fatal("expected less than a tenth of the documents to be scanned, got", stats.DocumentsScanned)
+			}
+			ids := make(map[string]struct{})
+			for _, res := range approx {
+				ids[res.ID] = struct{}{}
+			}
+			for _, res := range exact {
+				if _, ok := ids[res.ID]; ok {
+					hits++
+				}
+				total++
+			}
+		}
+		return float64(hits) / float64(total)
+	}
+	if rc := recall(c); rc < 0.9 {
+		t.Fatal("expected recall >= 0.9, got", rc)
+	}
+
+	// Added, updated and deleted documents are applied, also after loading.
+	added, updated := randomVector(), randomVector()
+	err = c.AddDocument(ctx, Document{ID: "added", Embedding: added})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: updated})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "0")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if options := c2.Index(); options.Type != IndexTypeDiskANN || options.Degree != 16 {
+		t.Fatal("expected persisted DiskANN index options, got", options)
+	}
+	for _, c := range []*Collection{c, c2} {
+		for _, tc := range []struct {
+			id        string
+			embedding []float32
+		}{{"added", added}, {"1", updated}} {
+			res, err := c.QueryEmbedding(ctx, tc.embedding, 1, nil, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if res[0].ID != tc.id {
+				t.Fatalf("expected document %q, got %q", tc.id, res[0].ID)
+			}
+		}
+		res, err := c.QueryEmbedding(ctx, docs[0].Embedding, 1, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if res[0].ID == "0" {
+			t.Fatal("expected deleted document not to be found")
+		}
+		if rc := recall(c); rc < 0.9 {
+			t.Fatal("expected recall >= 0.9, got", rc)
+		}
+	}
+
+	// Rebuilding replaces the file, and compactions keep it. Without index
+	// there's none.
+	err = c.RebuildIndex(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if files := indexFiles(); len(files) != 1 || filepath.Base(files[0]) != c.getConfig().IndexFile {
+		t.Fatal("expected one index file, got", files)
+	}
+	_, err = c.Compact(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if files := indexFiles(); len(files) != 1 || filepath.Base(files[0]) != c.getConfig().IndexFile {
+		t.Fatal("expected index file to be kept, got", files)
+	}
+	err = c.SetIndex(ctx, IndexOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if files := indexFiles(); len(files) != 0 {
+		t.Fatal("expected no index file, got", files)
+	}
+}
+
+func TestCollection_TuneIndex_DiskANN(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	db, err := NewPersistentDB(t.TempDir(), false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := make([]Document, 500)
+	for i := range docs {
+		v := make([]float32, 8)
+		for j := range v {
+			v[j] = r.Float32()*2 - 1
+		}
+		docs[i] = Document{ID: strconv.Itoa(i), Embedding: v}
+	}
+	err = c.AddDocuments(ctx, docs, 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	options := []IndexOptions{
+		{Type: IndexTypeDiskANN, Degree: 8, SearchListSize: 10},
+		{Type: IndexTypeDiskANN, Degree: 8, SearchListSize: 100},
+		{Type: IndexTypeDiskANN, Degree: 16, SearchListSize: 10},
+		{Type: IndexTypeDiskANN, Degree: 16, SearchListSize: 100},
+	}
+	report, err := c.TuneIndex(ctx, IndexTuningOptions{Options: options, SampleQueries: 20, TargetRecall: 0.9, Apply: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(report.Results) != 4 || !report.TargetReached {
+		t.Fatal("expected 4 results that reach the target, got", report)
+	}
+	if c.Index() != report.Recommended.Options {
+		t.Fatal("expected recommended options to be applied, got", c.Index(), report.Recommended.Options)
+	}
+
+	// Only the file of the applied index is kept.
+	files, err := filepath.Glob(filepath.Join(c.persistDirectory, "diskann-*.idx"))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != c.getConfig().IndexFile {
+		t.Fatal("expected one index file, got", files)
+	}
+	if x := c.index.(*diskANNIndex); x.degree != c.Index().Degree || x.searchListSize != c.Index().SearchListSize {
+		t.Fatal("expected the index of the recommended options, got", x.degree, x.searchListSize)
+	}
+
+	// Deleting the collection drops the index.
+	err = db.DeleteCollection("test")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, err := os.Stat(c.persistDirectory); !os.IsNotExist(err) {
+		t.Fatal("expected collection directory to be removed, got", err)
+	}
+	if c.index != nil {
+		t.Fatal("expected index to be dropped")
+	}
+}
+
+func TestOpenDiskANN(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diskann.idx")
+	err := os.WriteFile(path, []byte("not an index file, but long enough"), 0o600)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, err := openDiskANN(path); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []*Document{
+		{ID: "a", Embedding: []float32{1, 0}},
+		{ID: "b", Embedding: []float32{0, 1}},
+		{ID: "c", Embedding: []float32{0.6, 0.8}},
+	}
+	err = writeDiskANN(f, docs, [][]int32{{2}, {2}, {0, 1}}, 2, 2, 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	x, err := openDiskANN(path)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer x.drop()
+	if x.dim != 2 || x.degree != 4 || x.medoid != 2 || len(x.ids) != 3 || x.nodes["b"] != 1 {
+		t.Fatal("expected the written header and IDs, got", x.dim, x.degree, x.medoid, x.ids)
+	}
+	// The cache is empty, so the node is read from the file.
+	x.cache = nil
+	node, err := x.node(2, make([]byte, x.recordSize()))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if node.embedding[1] != 0.8 || len(node.neighbors) != 2 || node.neighbors[1] != 1 {
+		t.Fatal("expected the written node, got", node)
+	}
+	byID := map[string]*Document{"a": docs[0], "b": docs[1], "c": docs[2]}
+	candidates, ok := x.candidates([]float32{0, 1}, byID)
+	if !ok || len(candidates) != 3 {
+		t.Fatal("expected all documents to be candidates, got", candidates)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package chromem
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mmapData is a memory-mapped file.
+type mmapData []byte
+
+// openDiskANNData maps the first size bytes of the file into memory. The file
+// is closed, as the mapping doesn't need it.
+func openDiskANNData(f *os.File, size int64) (diskANNData, error) {
+	if size <= 0 || int64(int(size)) != size {
+		return nil, errors.New("invalid size for memory mapping")
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		_ = syscall.Munmap(data)
+		return nil, err
+	}
+	return mmapData(data), nil
+}
+
+// read returns the mapped bytes, without copying them to the buffer.
+func (d mmapData) read(off, n int64, _ []byte) ([]byte, error) {
+	if off < 0 || off+n > int64(len(d)) {
+		return nil, errors.New("read out of range")
+	}
+	return d[off : off+n], nil
+}
+
+func (d mmapData) close() error {
+	return syscall.Munmap(d)
+}
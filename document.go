@@ -12,8 +12,15 @@ type Document struct {
 	Embedding []float32
 	Content   string
 
+	// Media is an optional reference to an image (or other media) that the
+	// document represents. If the document has no embedding, it's created from
+	// the media with the collection's multimodal embedding function.
+	Media *Media
+
 	// ⚠️ When adding unexported fields here, consider adding a persistence struct
 	// version of this in [DB.Export] and [DB.Import].
+	// ⚠️ When adding fields here, add them to the binary format in
+	// [encodeDocument] and [decodeDocument] as well, with a new format version!
 }
 
 // NewDocument creates a new document, including its embeddings.
@@ -0,0 +1,42 @@
+package chromem
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// documentsMutex is the lock of a collection's documents. In addition to
+// locking, it caches a slice of the documents for queries, which is dropped
+// when a write lock is released. This allows queries to hold the read lock only
+// while getting the slice instead of while scanning the documents, so writes
+// don't have to wait for long scans, and reads don't have to wait behind a
+// waiting write. Scanning the slice without the lock is safe, as documents are
+// replaced instead of modified.
+type documentsMutex struct {
+	sync.RWMutex
+	// The documents of the collection, in random order. Nil if they changed
+	// since the last call of documentsSliceLocked.
+	slice atomic.Pointer[[]*Document]
+}
+
+func (m *documentsMutex) Unlock() {
+	m.slice.Store(nil)
+	m.RWMutex.Unlock()
+}
+
+// documentsSliceLocked returns the collection's documents as slice, which
+// must not be modified. The caller must hold the documents lock, but can use
+// the slice after releasing it.
+func (c *Collection) documentsSliceLocked() []*Document {
+	if docs := c.documentsLock.slice.Load(); docs != nil {
+		return *docs
+	}
+	docs := make([]*Document, 0, len(c.documents))
+	for _, doc := range c.documents {
+		docs = append(docs, doc)
+	}
+	// No writer can hold the lock while the caller holds it, so concurrent
+	// readers create the same slice.
+	c.documentsLock.slice.Store(&docs)
+	return docs
+}
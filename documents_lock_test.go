@@ -0,0 +1,124 @@
+package chromem
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCollection_documentsSliceLocked(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	c.documentsLock.RLock()
+	docs := c.documentsSliceLocked()
+	cached := c.documentsSliceLocked()
+	c.documentsLock.RUnlock()
+	if len(docs) != 1 || &docs[0] != &cached[0] {
+		t.Fatal("expected the same slice with 1 document, got", docs, cached)
+	}
+
+	// Writes drop the slice.
+	err = c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{0, 1}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c.documentsLock.RLock()
+	docs = c.documentsSliceLocked()
+	c.documentsLock.RUnlock()
+	if len(docs) != 2 {
+		t.Fatal("expected 2 documents, got", len(docs))
+	}
+	err = c.Delete(ctx, nil, nil, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c.documentsLock.RLock()
+	docs = c.documentsSliceLocked()
+	c.documentsLock.RUnlock()
+	if len(docs) != 1 || docs[0].ID != "2" {
+		t.Fatal("expected document 2, got", docs)
+	}
+}
+
+// BenchmarkCollection_MixedLoad measures the throughput of queries while
+// documents are replaced concurrently, and reports the throughput of the
+// writes.
+func BenchmarkCollection_MixedLoad(b *testing.B) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	n, d := 25_000, 384
+	randomVector := func(r *rand.Rand) []float32 {
+		v := make([]float32, d)
+		for j := range v {
+			v[j] = r.Float32()
+		}
+		return normalizeVector(v)
+	}
+
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		b.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < n; i++ {
+		err := c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: randomVector(r)})
+		if err != nil {
+			b.Fatal("expected no error, got", err)
+		}
+	}
+	qv := randomVector(r)
+
+	// Writers replace random documents until the queries are done, each with
+	// up to 1000 writes per second.
+	done := make(chan struct{})
+	var writes atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		r := rand.New(rand.NewSource(int64(w)))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+				}
+				err := c.AddDocument(ctx, Document{ID: strconv.Itoa(r.Intn(n)), Embedding: randomVector(r)})
+				if err != nil {
+					b.Error("expected no error, got", err)
+					return
+				}
+				writes.Add(1)
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, err := c.QueryEmbedding(ctx, qv, 10, nil, nil)
+			if err != nil {
+				b.Error("expected no error, got", err)
+				return
+			}
+		}
+	})
+	b.StopTimer()
+	close(done)
+	wg.Wait()
+	b.ReportMetric(float64(writes.Load())/b.Elapsed().Seconds(), "writes/s")
+}
@@ -0,0 +1,145 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// DuplicateCluster is a group of documents that are duplicates or near
+// duplicates of each other, as found by [Collection.FindDuplicates].
+type DuplicateCluster struct {
+	// The IDs of the documents in the cluster, sorted.
+	IDs []string
+	// The lowest similarity of the document pairs that link the cluster. As
+	// clusters are built transitively, two documents in a cluster can be less
+	// similar than this, if they're linked via other documents.
+	MinSimilarity float32
+}
+
+type duplicatePair struct {
+	a, b       int
+	similarity float32
+}
+
+// FindDuplicates finds clusters of documents whose embeddings have a cosine
+// similarity of at least the given threshold, for example 0.98 for near
+// duplicates, like pages that were scraped repeatedly with minor changes.
+// Clusters are built transitively: if A is similar to B and B to C, all three
+// are in one cluster. Documents without duplicates aren't returned.
+// The clusters are sorted by their first ID.
+//
+// All pairs of documents are compared, which is done concurrently, but takes
+// quadratic time, so it can take a while for large collections. It can be
+// canceled via the context.
+func (c *Collection) FindDuplicates(ctx context.Context, threshold float32) ([]DuplicateCluster, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, errors.New("threshold must be in (0, 1]")
+	}
+
+	// The documents aren't locked during the comparison, see sortedDocuments.
+	docs := c.sortedDocuments()
+
+	// Compare all pairs concurrently, with each worker taking the next row.
+	var pairs []duplicatePair
+	pairsLock := sync.Mutex{}
+	rows := make(chan int)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	executor := c.executor()
+	wg := sync.WaitGroup{}
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		executor.Submit(func() {
+			defer wg.Done()
+			var found []duplicatePair
+			for i := range rows {
+				for j := i + 1; j < len(docs); j++ {
+					sim, err := dotProduct(docs[i].Embedding, docs[j].Embedding)
+					if err != nil {
+						// Can't happen with the dimension check when adding documents,
+						// but documents might have been imported.
+						continue
+					}
+					if sim >= threshold {
+						found = append(found, duplicatePair{a: i, b: j, similarity: sim})
+					}
+				}
+			}
+			pairsLock.Lock()
+			pairs = append(pairs, found...)
+			pairsLock.Unlock()
+		})
+	}
+rowLoop:
+	for i := range docs {
+		select {
+		case <-ctx.Done():
+			break rowLoop
+		case rows <- i:
+		}
+	}
+	close(rows)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return clusterDuplicates(docs, pairs), nil
+}
+
+// clusterDuplicates groups the documents of the pairs into clusters, with a
+// union-find on the document indexes.
+func clusterDuplicates(docs []*Document, pairs []duplicatePair) []DuplicateCluster {
+	parent := make(map[int]int)
+	var find func(i int) int
+	find = func(i int) int {
+		p, ok := parent[i]
+		if !ok || p == i {
+			return i
+		}
+		root := find(p)
+		parent[i] = root
+		return root
+	}
+	member := make(map[int]bool)
+	for _, p := range pairs {
+		member[p.a], member[p.b] = true, true
+		rootA, rootB := find(p.a), find(p.b)
+		if rootA != rootB {
+			// Use the lower index as root, for deterministic results.
+			parent[max(rootA, rootB)] = min(rootA, rootB)
+		}
+	}
+
+	byRoot := make(map[int]*DuplicateCluster)
+	var roots []int
+	for _, p := range pairs {
+		root := find(p.a)
+		cluster, ok := byRoot[root]
+		if !ok {
+			cluster = &DuplicateCluster{MinSimilarity: p.similarity}
+			byRoot[root] = cluster
+			roots = append(roots, root)
+		}
+		cluster.MinSimilarity = min(cluster.MinSimilarity, p.similarity)
+	}
+	for i := range docs {
+		if !member[i] {
+			continue
+		}
+		cluster := byRoot[find(i)]
+		cluster.IDs = append(cluster.IDs, docs[i].ID)
+	}
+
+	// The roots are the lowest indexes of their clusters, so sorting them sorts
+	// the clusters by their first ID.
+	slices.Sort(roots)
+	clusters := make([]DuplicateCluster, 0, len(roots))
+	for _, root := range roots {
+		clusters = append(clusters, *byRoot[root])
+	}
+	return clusters
+}
@@ -0,0 +1,71 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCollection_FindDuplicates(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "b", Embedding: []float32{0.99, 0.14106736, 0}},
+		{ID: "c", Embedding: []float32{0, 1, 0}},
+		{ID: "d", Embedding: []float32{0, 0, 1}},
+		{ID: "e", Embedding: []float32{0, 0.14106736, 0.99}},
+		{ID: "f", Embedding: []float32{0, 0, 1}},
+	}
+	for _, doc := range docs {
+		err = c.AddDocument(ctx, doc)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	clusters, err := c.FindDuplicates(ctx, 0.98)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %+v", clusters)
+	}
+	if !slices.Equal(clusters[0].IDs, []string{"a", "b"}) {
+		t.Fatalf("expected cluster [a b], got %v", clusters[0].IDs)
+	}
+	if !slices.Equal(clusters[1].IDs, []string{"d", "e", "f"}) {
+		t.Fatalf("expected cluster [d e f], got %v", clusters[1].IDs)
+	}
+	if clusters[0].MinSimilarity < 0.98 || clusters[0].MinSimilarity > 1 {
+		t.Fatal("expected min similarity between 0.98 and 1, got", clusters[0].MinSimilarity)
+	}
+
+	// Exact duplicates only
+	clusters, err = c.FindDuplicates(ctx, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(clusters) != 1 || !slices.Equal(clusters[0].IDs, []string{"d", "f"}) {
+		t.Fatalf("expected cluster [d f], got %+v", clusters)
+	}
+
+	// Invalid threshold
+	_, err = c.FindDuplicates(ctx, 0)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Canceled context
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.FindDuplicates(canceledCtx, 0.98)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+}
@@ -0,0 +1,210 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Durability is the durability level of the writes of a persistent DB, see
+// [PersistentDBOptions.Durability]. Higher levels protect against data loss on
+// operating system crashes and power loss, at the cost of write throughput.
+// Process crashes don't lose data at any level, as each write operation
+// writes to the files before it returns.
+type Durability int
+
+const (
+	// DurabilityNone leaves syncing written files to disk to the operating
+	// system, which typically happens within seconds. This is the fastest level.
+	DurabilityNone Durability = iota
+	// DurabilityInterval syncs the written files to disk periodically, see
+	// [PersistentDBOptions.SyncInterval], so at most the writes of the last
+	// interval are lost.
+	DurabilityInterval
+	// DurabilityAlways syncs each written file, and the directories of created,
+	// renamed and removed files, to disk before the write operation returns, so
+	// no completed write is lost. This is the slowest level.
+	DurabilityAlways
+)
+
+// DefaultSyncInterval is the interval of [DurabilityInterval] if
+// [PersistentDBOptions.SyncInterval] isn't set.
+const DefaultSyncInterval = time.Second
+
+// fileSyncer syncs written files to disk according to the durability level.
+// A nil fileSyncer doesn't sync, which is [DurabilityNone].
+type fileSyncer struct {
+	durability Durability
+
+	// The paths of the files and directories to sync with the next interval,
+	// mapped to whether they're directories, and the first error of the
+	// interval syncs.
+	pending     map[string]bool
+	intervalErr error
+	lock        sync.Mutex
+
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// newFileSyncer returns a syncer for the durability level, or nil for
+// [DurabilityNone]. For [DurabilityInterval], the syncs have to be started with
+// start.
+func newFileSyncer(durability Durability, interval time.Duration) (*fileSyncer, error) {
+	switch durability {
+	case DurabilityNone:
+		return nil, nil
+	case DurabilityAlways:
+		return &fileSyncer{durability: durability}, nil
+	case DurabilityInterval:
+	default:
+		return nil, fmt.Errorf("unknown durability level %d", durability)
+	}
+
+	if interval < 0 {
+		return nil, errors.New("sync interval must be >= 0")
+	} else if interval == 0 {
+		interval = DefaultSyncInterval
+	}
+	s := &fileSyncer{
+		durability: durability,
+		pending:    make(map[string]bool),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	s.interval = interval
+	return s, nil
+}
+
+// start starts a goroutine that syncs in the interval until the syncer is
+// closed.
+func (s *fileSyncer) start() {
+	if s == nil || s.durability != DurabilityInterval {
+		return
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				err := s.flush()
+				if err != nil {
+					s.lock.Lock()
+					if s.intervalErr == nil {
+						s.intervalErr = err
+					}
+					s.lock.Unlock()
+				}
+			}
+		}
+	}()
+}
+
+// syncFile syncs the written file, or marks it for the next interval. It must
+// be called before the file is closed.
+func (s *fileSyncer) syncFile(f *os.File) error {
+	if s == nil {
+		return nil
+	}
+	if s.durability == DurabilityInterval {
+		s.lock.Lock()
+		s.pending[f.Name()] = false
+		s.lock.Unlock()
+		return nil
+	}
+	err := f.Sync()
+	if err != nil {
+		return fmt.Errorf("couldn't sync file: %w", err)
+	}
+	return nil
+}
+
+// syncDir syncs the parent directory of the path, after a file was created,
+// renamed or removed there, or marks it for the next interval.
+func (s *fileSyncer) syncDir(path string) error {
+	// Directories can't be synced on Windows, where their entries are durable
+	// with the files.
+	if s == nil || runtime.GOOS == "windows" {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	if s.durability == DurabilityInterval {
+		s.lock.Lock()
+		s.pending[dir] = true
+		s.lock.Unlock()
+		return nil
+	}
+	return syncPath(dir, true)
+}
+
+// flush syncs the files and directories that were marked for the interval.
+func (s *fileSyncer) flush() error {
+	if s == nil || s.durability != DurabilityInterval {
+		return nil
+	}
+	s.lock.Lock()
+	pending := s.pending
+	s.pending = make(map[string]bool)
+	s.lock.Unlock()
+
+	var errs []error
+	for path, isDir := range pending {
+		err := syncPath(path, isDir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// close stops the interval syncs after a final one, and returns the first
+// error of the interval syncs, if any.
+func (s *fileSyncer) close() error {
+	if s == nil || s.durability != DurabilityInterval {
+		return nil
+	}
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+	err := s.flush()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return errors.Join(s.intervalErr, err)
+}
+
+// syncPath opens the file or directory at the path and syncs it to disk. Files
+// are opened for writing, as Windows can't sync read-only handles.
+func syncPath(path string, isDir bool) error {
+	flag := os.O_WRONLY
+	if isDir {
+		flag = os.O_RDONLY
+	}
+	f, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return fmt.Errorf("couldn't open %q for syncing: %w", path, err)
+	}
+	defer f.Close()
+	err = f.Sync()
+	if err != nil {
+		return fmt.Errorf("couldn't sync %q: %w", path, err)
+	}
+	return nil
+}
+
+// Sync syncs all files that were written since the last sync to disk. It's
+// only needed with [DurabilityInterval], to make sure that the latest writes
+// are durable without waiting for the next interval. Otherwise it's a no-op.
+func (db *DB) Sync() error {
+	return db.syncer.flush()
+}
@@ -0,0 +1,84 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewPersistentDBWithOptions_Durability(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewPersistentDBWithOptions(t.TempDir(), PersistentDBOptions{Durability: Durability(42)})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	_, err = NewPersistentDBWithOptions(t.TempDir(), PersistentDBOptions{Durability: DurabilityInterval, SyncInterval: -time.Second})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	tt := []struct {
+		name string
+		opts PersistentDBOptions
+	}{
+		{name: "none", opts: PersistentDBOptions{}},
+		{name: "always", opts: PersistentDBOptions{Durability: DurabilityAlways}},
+		{name: "always with segments", opts: PersistentDBOptions{Durability: DurabilityAlways, SegmentSize: 1}},
+		{name: "interval", opts: PersistentDBOptions{Durability: DurabilityInterval, SyncInterval: time.Millisecond}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			db, err := NewPersistentDBWithOptions(dir, tc.opts)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			c, err := db.CreateCollection("test", nil, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.AddDocuments(ctx, []Document{
+				{ID: "1", Embedding: []float32{1, 0}, Content: "one"},
+				{ID: "2", Embedding: []float32{0, 1}, Content: "two"},
+			}, 2)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.Delete(ctx, nil, nil, "2")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = db.Sync()
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if db.syncer != nil {
+				db.syncer.lock.Lock()
+				pending := len(db.syncer.pending)
+				db.syncer.lock.Unlock()
+				if pending != 0 {
+					t.Fatal("expected no pending syncs, got", pending)
+				}
+			}
+			err = db.Close()
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			// Closing twice is fine
+			err = db.Close()
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+
+			db, err = NewPersistentDB(dir, false)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if db.GetCollection("test", nil).Count() != 1 {
+				t.Fatal("expected 1 document, got", db.GetCollection("test", nil).Count())
+			}
+		})
+	}
+}
@@ -80,11 +80,8 @@ type cohereResponse struct {
 // You can also keep the prefix in the document, and only remove it after querying.
 //
 // We plan to improve this in the future.
-func NewEmbeddingFuncCohere(apiKey string, model EmbeddingModelCohere) EmbeddingFunc {
-	// We don't set a default timeout here, although it's usually a good idea.
-	// In our case though, the library user can set the timeout on the context,
-	// and it might have to be a long timeout, depending on the text length.
-	client := &http.Client{}
+func NewEmbeddingFuncCohere(apiKey string, model EmbeddingModelCohere, opts ...EmbeddingOption) EmbeddingFunc {
+	client := newEmbeddingHTTPClient(opts)
 
 	var checkedNormalized bool
 	checkNormalized := sync.Once{}
@@ -113,7 +110,7 @@ func NewEmbeddingFuncCohere(apiKey string, model EmbeddingModelCohere) Embedding
 		}
 
 		// Create the request. Creating it with context is important for a timeout
-		// to be possible, because the client is configured without a timeout.
+		// to be possible, because the client is configured without a timeout by default.
 		req, err := http.NewRequestWithContext(ctx, "POST", baseURLCohere+"/embed", bytes.NewBuffer(reqBody))
 		if err != nil {
 			return nil, fmt.Errorf("couldn't create request: %w", err)
@@ -131,7 +128,7 @@ func NewEmbeddingFuncCohere(apiKey string, model EmbeddingModelCohere) Embedding
 
 		// Check the response status.
 		if resp.StatusCode != http.StatusOK {
-			return nil, errors.New("error response from the embedding API: " + resp.Status)
+			return nil, newEmbeddingError(resp)
 		}
 
 		// Read and decode the response body.
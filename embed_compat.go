@@ -1,5 +1,9 @@
 package chromem
 
+import (
+	"strings"
+)
+
 const (
 	baseURLMistral = "https://api.mistral.ai/v1"
 	// Currently there's only one. Let's turn this into a pseudo-enum as soon as there are more.
@@ -8,14 +12,14 @@ const (
 
 // NewEmbeddingFuncMistral returns a function that creates embeddings for a text
 // using the Mistral API.
-func NewEmbeddingFuncMistral(apiKey string) EmbeddingFunc {
+func NewEmbeddingFuncMistral(apiKey string, opts ...EmbeddingOption) EmbeddingFunc {
 	// Mistral embeddings are normalized, see section "Distance Measures" on
 	// https://docs.mistral.ai/guides/embeddings/.
 	normalized := true
 
 	// The Mistral API docs don't mention the `encoding_format` as optional,
 	// but it seems to be, just like OpenAI. So we reuse the OpenAI function.
-	return NewEmbeddingFuncOpenAICompat(baseURLMistral, apiKey, embeddingModelMistral, &normalized)
+	return NewEmbeddingFuncOpenAICompat(baseURLMistral, apiKey, embeddingModelMistral, &normalized, opts...)
 }
 
 const baseURLJina = "https://api.jina.ai/v1"
@@ -31,8 +35,8 @@ const (
 
 // NewEmbeddingFuncJina returns a function that creates embeddings for a text
 // using the Jina API.
-func NewEmbeddingFuncJina(apiKey string, model EmbeddingModelJina) EmbeddingFunc {
-	return NewEmbeddingFuncOpenAICompat(baseURLJina, apiKey, string(model), nil)
+func NewEmbeddingFuncJina(apiKey string, model EmbeddingModelJina, opts ...EmbeddingOption) EmbeddingFunc {
+	return NewEmbeddingFuncOpenAICompat(baseURLJina, apiKey, string(model), nil, opts...)
 }
 
 const baseURLMixedbread = "https://api.mixedbread.ai"
@@ -52,8 +56,8 @@ const (
 
 // NewEmbeddingFuncMixedbread returns a function that creates embeddings for a text
 // using the mixedbread.ai API.
-func NewEmbeddingFuncMixedbread(apiKey string, model EmbeddingModelMixedbread) EmbeddingFunc {
-	return NewEmbeddingFuncOpenAICompat(baseURLMixedbread, apiKey, string(model), nil)
+func NewEmbeddingFuncMixedbread(apiKey string, model EmbeddingModelMixedbread, opts ...EmbeddingOption) EmbeddingFunc {
+	return NewEmbeddingFuncOpenAICompat(baseURLMixedbread, apiKey, string(model), nil, opts...)
 }
 
 const baseURLLocalAI = "http://localhost:8080/v1"
@@ -67,8 +71,50 @@ const baseURLLocalAI = "http://localhost:8080/v1"
 // And then call this constructor with model "bert-cpp-minilm-v6".
 // But other embedding models are supported as well. See the LocalAI documentation
 // for details.
-func NewEmbeddingFuncLocalAI(model string) EmbeddingFunc {
-	return NewEmbeddingFuncOpenAICompat(baseURLLocalAI, "", model, nil)
+func NewEmbeddingFuncLocalAI(model string, opts ...EmbeddingOption) EmbeddingFunc {
+	return NewEmbeddingFuncLocalAIWithAuth("", "", model, opts...)
+}
+
+// NewEmbeddingFuncLocalAIWithAuth is like [NewEmbeddingFuncLocalAI], but for
+// LocalAI instances that don't run on "http://localhost:8080" or that were started
+// with API keys (via the "API_KEY" environment variable or "--api-keys" flag).
+//
+//   - baseURL: The base URL of the LocalAI API, including the "/v1" path. If it's
+//     empty, "http://localhost:8080/v1" is used.
+//   - apiKey: One of the API keys LocalAI was started with. Optional.
+//   - model: The name of the embedding model, as configured in LocalAI
+func NewEmbeddingFuncLocalAIWithAuth(baseURL, apiKey, model string, opts ...EmbeddingOption) EmbeddingFunc {
+	if baseURL == "" {
+		baseURL = baseURLLocalAI
+	}
+	// Depending on the backend (bert.cpp, llama.cpp, sentence-transformers) and
+	// model, LocalAI returns normalized or non-normalized embeddings. Some
+	// backends don't normalize *most* vectors, so autodetecting on the first
+	// request isn't reliable, and we always normalize.
+	normalized := false
+	return NewEmbeddingFuncOpenAICompat(strings.TrimSuffix(baseURL, "/"), apiKey, model, &normalized, opts...)
+}
+
+const baseURLLMStudio = "http://localhost:1234/v1"
+
+// NewEmbeddingFuncLMStudio returns a function that creates embeddings for a text
+// using the OpenAI compatible API of LM Studio's local server.
+// You have to load an embedding model, like "text-embedding-nomic-embed-text-v1.5",
+// and start the server in LM Studio or via `lms server start` first.
+// See https://lmstudio.ai/docs/app/api/endpoints/openai
+//
+//   - model: The identifier of the loaded embedding model
+//   - baseURL: The base URL of the LM Studio API, including the "/v1" path. If
+//     it's empty, "http://localhost:1234/v1" is used.
+func NewEmbeddingFuncLMStudio(model, baseURL string, opts ...EmbeddingOption) EmbeddingFunc {
+	if baseURL == "" {
+		baseURL = baseURLLMStudio
+	}
+	// The embedding models commonly used with LM Studio, like nomic-embed-text,
+	// don't return normalized embeddings.
+	normalized := false
+	// LM Studio doesn't support API keys.
+	return NewEmbeddingFuncOpenAICompat(strings.TrimSuffix(baseURL, "/"), "", model, &normalized, opts...)
 }
 
 const (
@@ -79,9 +125,9 @@ const (
 // using the Azure OpenAI API.
 // The `deploymentURL` is the URL of the deployed model, e.g. "https://YOUR_RESOURCE_NAME.openai.azure.com/openai/deployments/YOUR_DEPLOYMENT_NAME"
 // See https://learn.microsoft.com/en-us/azure/ai-services/openai/how-to/embeddings?tabs=console#how-to-get-embeddings
-func NewEmbeddingFuncAzureOpenAI(apiKey string, deploymentURL string, apiVersion string, model string) EmbeddingFunc {
+func NewEmbeddingFuncAzureOpenAI(apiKey string, deploymentURL string, apiVersion string, model string, opts ...EmbeddingOption) EmbeddingFunc {
 	if apiVersion == "" {
 		apiVersion = azureDefaultAPIVersion
 	}
-	return newEmbeddingFuncOpenAICompat(deploymentURL, apiKey, model, nil, map[string]string{"api-key": apiKey}, map[string]string{"api-version": apiVersion})
+	return newEmbeddingFuncOpenAICompat(deploymentURL, apiKey, model, nil, map[string]string{"api-key": apiKey}, map[string]string{"api-version": apiVersion}, opts...)
 }
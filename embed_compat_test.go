@@ -0,0 +1,90 @@
+package chromem_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestNewEmbeddingFuncLocalPresets(t *testing.T) {
+	model := "model-small"
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+
+	tt := []struct {
+		name     string
+		newFunc  func(baseURL string) chromem.EmbeddingFunc
+		wantAuth string
+	}{
+		{
+			name: "LocalAI",
+			newFunc: func(baseURL string) chromem.EmbeddingFunc {
+				return chromem.NewEmbeddingFuncLocalAIWithAuth(baseURL, "secret", model)
+			},
+			wantAuth: "Bearer secret",
+		},
+		{
+			name: "LM Studio",
+			newFunc: func(baseURL string) chromem.EmbeddingFunc {
+				return chromem.NewEmbeddingFuncLMStudio(model, baseURL)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Mock server returning a non-normalized embedding only on the *second*
+			// request, which must be normalized as well, as autodetecting it on
+			// the first request isn't reliable with local models.
+			requests := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/embeddings" {
+					t.Fatal("expected URL", "/v1/embeddings", "got", r.URL.Path)
+				}
+				if tc.wantAuth != "" && r.Header.Get("Authorization") != tc.wantAuth {
+					t.Fatal("expected Authorization header", tc.wantAuth, "got", r.Header.Get("Authorization"))
+				}
+				var req map[string]string
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+				if req["model"] != model {
+					t.Fatal("expected model", model, "got", req["model"])
+				}
+
+				requests++
+				embedding := wantRes
+				if requests > 1 {
+					embedding = []float32{-0.1, 0.1, 0.2}
+				}
+				resp := openAIResponse{
+					Data: []struct {
+						Embedding []float32 `json:"embedding"`
+					}{
+						{Embedding: embedding},
+					},
+				}
+				_ = json.NewEncoder(w).Encode(resp)
+			}))
+			defer ts.Close()
+
+			f := tc.newFunc(ts.URL + "/v1/")
+			for i := 0; i < 2; i++ {
+				res, err := f(context.Background(), "hello world")
+				if err != nil {
+					t.Fatal("expected nil, got", err)
+				}
+				// Normalizing an already normalized vector can change the last digits.
+				for j := range wantRes {
+					if math.Abs(float64(wantRes[j]-res[j])) > 1e-6 {
+						t.Fatal("expected res", wantRes, "got", res)
+					}
+				}
+			}
+		})
+	}
+}
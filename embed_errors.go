@@ -0,0 +1,170 @@
+package chromem
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrRateLimited is returned when the embedding API rejects a request due to
+	// rate limiting or an exhausted quota. The [EmbeddingError] wrapping it might
+	// contain the time to wait before retrying.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnauthorized is returned when the embedding API rejects the API key.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrInputTooLong is returned when a text exceeds the maximum input length of
+	// the embedding model.
+	ErrInputTooLong = errors.New("input too long")
+)
+
+// EmbeddingError is the error returned by the built-in embedding functions when
+// the embedding API responds with an error status. Depending on the status and
+// error message, it wraps [ErrRateLimited], [ErrUnauthorized] or [ErrInputTooLong],
+// so you can check for these with [errors.Is]:
+//
+//	_, err := embeddingFunc(ctx, text)
+//	if errors.Is(err, chromem.ErrRateLimited) {
+//		var embErr *chromem.EmbeddingError
+//		if errors.As(err, &embErr) {
+//			time.Sleep(embErr.RetryAfter)
+//		}
+//	}
+type EmbeddingError struct {
+	// The HTTP status code and status, e.g. 429 and "429 Too Many Requests".
+	StatusCode int
+	Status     string
+	// The error message from the response body, if it could be parsed.
+	Message string
+	// The time to wait before retrying, from the "Retry-After" response header.
+	// Zero if the API didn't send it.
+	RetryAfter time.Duration
+
+	kind error
+}
+
+func (e *EmbeddingError) Error() string {
+	msg := "error response from the embedding API: " + e.Status
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func (e *EmbeddingError) Unwrap() error {
+	return e.kind
+}
+
+// inputTooLongHints are parts of error messages with which embedding APIs
+// reject texts that are longer than the model's maximum input length.
+var inputTooLongHints = []string{
+	"maximum context length",
+	"context_length_exceeded",
+	"context length",
+	"too long",
+	"too large",
+	"too many tokens",
+	"exceeds the maximum",
+	"must have less than",
+	"input length",
+}
+
+// newEmbeddingError creates an [EmbeddingError] from a non-OK response of an
+// embedding API. It reads (part of) the response body.
+func newEmbeddingError(resp *http.Response) *EmbeddingError {
+	e := &EmbeddingError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: parseRetryAfter(resp.Header, time.Now()),
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLen))
+	e.Message = parseErrorMessage(body)
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.kind = ErrRateLimited
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		e.kind = ErrUnauthorized
+	case resp.StatusCode == http.StatusRequestEntityTooLarge:
+		e.kind = ErrInputTooLong
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity:
+		msg := strings.ToLower(e.Message)
+		for _, hint := range inputTooLongHints {
+			if strings.Contains(msg, hint) {
+				e.kind = ErrInputTooLong
+				break
+			}
+		}
+	}
+	return e
+}
+
+// parseErrorMessage extracts the error message from the common error response
+// shapes of embedding APIs:
+//
+//   - OpenAI, Azure, Jina, llama.cpp: {"error": {"message": "..."}}
+//   - Ollama, HuggingFace, TEI: {"error": "..."}
+//   - Cohere, Mistral: {"message": "..."}
+//   - Mixedbread and other FastAPI based APIs: {"detail": "..."}
+//
+// For other bodies the (truncated) body itself is returned.
+func parseErrorMessage(body []byte) string {
+	var res struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+		Detail  json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+	if len(res.Error) > 0 {
+		var s string
+		if err := json.Unmarshal(res.Error, &s); err == nil {
+			return s
+		}
+		var obj struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(res.Error, &obj); err == nil && obj.Message != "" {
+			return obj.Message
+		}
+	}
+	if res.Message != "" {
+		return res.Message
+	}
+	if len(res.Detail) > 0 {
+		var s string
+		if err := json.Unmarshal(res.Detail, &s); err == nil {
+			return s
+		}
+		// FastAPI validation errors are lists of objects
+		return string(res.Detail)
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// parseRetryAfter parses the "Retry-After" header, which is either a number of
+// seconds or an HTTP date, and OpenAI's "Retry-After-Ms" header.
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.ParseFloat(v, 64); err == nil {
+		if s < 0 {
+			return 0
+		}
+		return time.Duration(s * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewEmbeddingError(t *testing.T) {
+	tt := []struct {
+		name           string
+		status         int
+		header         map[string]string
+		body           string
+		wantKind       error
+		wantMessage    string
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:           "OpenAI rate limit",
+			status:         http.StatusTooManyRequests,
+			header:         map[string]string{"Retry-After": "2"},
+			body:           `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+			wantKind:       ErrRateLimited,
+			wantMessage:    "Rate limit reached",
+			wantRetryAfter: 2 * time.Second,
+		},
+		{
+			name:           "OpenAI retry after ms",
+			status:         http.StatusTooManyRequests,
+			header:         map[string]string{"Retry-After-Ms": "1500", "Retry-After": "2"},
+			body:           `{"error":{"message":"Rate limit reached"}}`,
+			wantKind:       ErrRateLimited,
+			wantMessage:    "Rate limit reached",
+			wantRetryAfter: 1500 * time.Millisecond,
+		},
+		{
+			name:        "OpenAI context length",
+			status:      http.StatusBadRequest,
+			body:        `{"error":{"message":"This model's maximum context length is 8192 tokens, however you requested 9000 tokens","code":"context_length_exceeded"}}`,
+			wantKind:    ErrInputTooLong,
+			wantMessage: "This model's maximum context length is 8192 tokens, however you requested 9000 tokens",
+		},
+		{
+			name:        "Ollama",
+			status:      http.StatusUnauthorized,
+			body:        `{"error":"unauthorized"}`,
+			wantKind:    ErrUnauthorized,
+			wantMessage: "unauthorized",
+		},
+		{
+			name:        "TEI input too long",
+			status:      http.StatusRequestEntityTooLarge,
+			body:        `{"error":"Input validation error: inputs must have less than 512 tokens","error_type":"Validation"}`,
+			wantKind:    ErrInputTooLong,
+			wantMessage: "Input validation error: inputs must have less than 512 tokens",
+		},
+		{
+			name:        "Cohere",
+			status:      http.StatusBadRequest,
+			body:        `{"message":"invalid request: texts is empty"}`,
+			wantMessage: "invalid request: texts is empty",
+		},
+		{
+			name:        "FastAPI",
+			status:      http.StatusForbidden,
+			body:        `{"detail":"Not authenticated"}`,
+			wantKind:    ErrUnauthorized,
+			wantMessage: "Not authenticated",
+		},
+		{
+			name:        "plain text",
+			status:      http.StatusInternalServerError,
+			body:        "internal error\n",
+			wantMessage: "internal error",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			for k, v := range tc.header {
+				rec.Header().Set(k, v)
+			}
+			rec.WriteHeader(tc.status)
+			_, _ = rec.WriteString(tc.body)
+
+			err := newEmbeddingError(rec.Result())
+			if err.StatusCode != tc.status {
+				t.Fatal("expected status", tc.status, "got", err.StatusCode)
+			}
+			if err.Message != tc.wantMessage {
+				t.Fatalf("expected message %q, got %q", tc.wantMessage, err.Message)
+			}
+			if err.RetryAfter != tc.wantRetryAfter {
+				t.Fatal("expected retry after", tc.wantRetryAfter, "got", err.RetryAfter)
+			}
+			if tc.wantKind != nil && !errors.Is(err, tc.wantKind) {
+				t.Fatal("expected", tc.wantKind, "got", err)
+			}
+			if tc.wantKind == nil && err.Unwrap() != nil {
+				t.Fatal("expected no kind, got", err.Unwrap())
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	header := http.Header{}
+	header.Set("Retry-After", now.Add(30*time.Second).Format(http.TimeFormat))
+	if got := parseRetryAfter(header, now); got != 30*time.Second {
+		t.Fatal("expected 30s, got", got)
+	}
+	header.Set("Retry-After", "invalid")
+	if got := parseRetryAfter(header, now); got != 0 {
+		t.Fatal("expected 0, got", got)
+	}
+}
+
+func TestEmbeddingError_retry(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"message":"Incorrect API key provided"}}`))
+	}))
+	defer ts.Close()
+
+	f := WrapEmbeddingFunc(NewEmbeddingFuncOpenAICompat(ts.URL, "wrong", "model", nil), NewEmbeddingMiddlewareRetry(3, time.Millisecond))
+	_, err := f(context.Background(), "hello")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatal("expected ErrUnauthorized, got", err)
+	}
+	if err.Error() != "error response from the embedding API: 401 Unauthorized: Incorrect API key provided" {
+		t.Fatal("unexpected error message:", err)
+	}
+	// Unauthorized requests aren't retried
+	if requests != 1 {
+		t.Fatal("expected 1 request, got", requests)
+	}
+}
@@ -0,0 +1,216 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const baseURLHuggingFace = "https://router.huggingface.co/hf-inference/models"
+
+// HuggingFacePooling is the way token embeddings are pooled into a single
+// embedding for the text.
+type HuggingFacePooling string
+
+const (
+	// HuggingFacePoolingServer uses the embedding as pooled by the server, which
+	// is the case for all sentence-transformers models. This is the default.
+	HuggingFacePoolingServer HuggingFacePooling = ""
+	// HuggingFacePoolingMean averages all token embeddings.
+	HuggingFacePoolingMean HuggingFacePooling = "mean"
+	// HuggingFacePoolingCLS uses the embedding of the first ("[CLS]") token.
+	HuggingFacePoolingCLS HuggingFacePooling = "cls"
+	// HuggingFacePoolingLast uses the embedding of the last token, which is
+	// common for decoder-based embedding models.
+	HuggingFacePoolingLast HuggingFacePooling = "last"
+)
+
+// HuggingFaceOptions are the options for [NewEmbeddingFuncHuggingFace] and
+// [NewEmbeddingFuncTEI].
+type HuggingFaceOptions struct {
+	// Whether the server should truncate texts that are longer than the model's
+	// maximum input length. Without truncation, such texts lead to an error.
+	Truncate bool
+
+	// The side from which texts are truncated, "Right" or "Left". Optional,
+	// the server's default is "Right".
+	TruncationDirection string
+
+	// The pooling of token embeddings. Optional, defaults to the server's pooling.
+	// When set, the token embeddings are requested (via TEI's "/embed_all"
+	// endpoint) and pooled on the client side. This is useful for models that
+	// aren't sentence-transformers models, or when the TEI server was started
+	// with a pooling method that doesn't match the model.
+	Pooling HuggingFacePooling
+}
+
+type huggingFaceRequest struct {
+	Inputs              string `json:"inputs"`
+	Truncate            bool   `json:"truncate,omitempty"`
+	TruncationDirection string `json:"truncation_direction,omitempty"`
+}
+
+// NewEmbeddingFuncHuggingFace returns a function that creates embeddings for a
+// text using HuggingFace's Inference API ("feature-extraction" task).
+// You can pass any model that's deployed for it, for example
+// "sentence-transformers/all-MiniLM-L6-v2" or "BAAI/bge-small-en-v1.5".
+// See https://huggingface.co/docs/inference-providers/tasks/feature-extraction
+func NewEmbeddingFuncHuggingFace(apiKey, model string, hfOpts HuggingFaceOptions, opts ...EmbeddingOption) EmbeddingFunc {
+	url := baseURLHuggingFace + "/" + model + "/pipeline/feature-extraction"
+	return newEmbeddingFuncHuggingFace(url, apiKey, hfOpts, opts)
+}
+
+// NewEmbeddingFuncTEI returns a function that creates embeddings for a text
+// using a Text Embeddings Inference (TEI) server, which is also what HuggingFace
+// Inference Endpoints use for embedding models.
+// See https://huggingface.co/docs/text-embeddings-inference
+//
+//   - baseURL: The URL of the TEI server or Inference Endpoint, e.g.
+//     "http://localhost:8080"
+//   - apiKey: The API key, sent as bearer token. Optional, only required for
+//     Inference Endpoints or TEI servers started with "--api-key".
+//   - hfOpts: Options for truncation and pooling, see [HuggingFaceOptions]
+//   - opts: Options for the HTTP client, see [EmbeddingOption]
+func NewEmbeddingFuncTEI(baseURL, apiKey string, hfOpts HuggingFaceOptions, opts ...EmbeddingOption) EmbeddingFunc {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	url := baseURL + "/embed"
+	if hfOpts.Pooling != HuggingFacePoolingServer {
+		url = baseURL + "/embed_all"
+	}
+	return newEmbeddingFuncHuggingFace(url, apiKey, hfOpts, opts)
+}
+
+func newEmbeddingFuncHuggingFace(url, apiKey string, hfOpts HuggingFaceOptions, opts []EmbeddingOption) EmbeddingFunc {
+	client := newEmbeddingHTTPClient(opts)
+
+	var checkedNormalized bool
+	checkNormalized := sync.Once{}
+
+	return func(ctx context.Context, text string) ([]float32, error) {
+		// Prepare the request body.
+		reqBody, err := json.Marshal(huggingFaceRequest{
+			Inputs:              text,
+			Truncate:            hfOpts.Truncate,
+			TruncationDirection: hfOpts.TruncationDirection,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal request body: %w", err)
+		}
+
+		// Create the request. Creating it with context is important for a timeout
+		// to be possible, because the client is configured without a timeout by default.
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+
+		// Send the request.
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		// Check the response status.
+		if resp.StatusCode != http.StatusOK {
+			return nil, newEmbeddingError(resp)
+		}
+
+		// Read and decode the response body.
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read response body: %w", err)
+		}
+		v, err := decodeHuggingFaceEmbedding(body, hfOpts.Pooling)
+		if err != nil {
+			return nil, err
+		}
+
+		checkNormalized.Do(func() {
+			if isNormalized(v) {
+				checkedNormalized = true
+			} else {
+				checkedNormalized = false
+			}
+		})
+		if !checkedNormalized {
+			v = normalizeVector(v)
+		}
+
+		return v, nil
+	}
+}
+
+// decodeHuggingFaceEmbedding decodes the response body of the Inference API or
+// TEI, which depending on the endpoint and model is a single embedding, a list
+// of embeddings (one per input), or a list of token embeddings per input.
+// Token embeddings are pooled with the given pooling.
+func decodeHuggingFaceEmbedding(body []byte, pooling HuggingFacePooling) ([]float32, error) {
+	var tokens [][]float32
+
+	var v []float32
+	var vs [][]float32
+	var vss [][][]float32
+	if err := json.Unmarshal(body, &v); err == nil {
+		if len(v) == 0 {
+			return nil, errors.New("no embeddings found in the response")
+		}
+		// Already pooled by the server
+		return v, nil
+	} else if err := json.Unmarshal(body, &vs); err == nil {
+		if pooling == HuggingFacePoolingServer {
+			// One embedding per input
+			if len(vs) != 1 || len(vs[0]) == 0 {
+				return nil, fmt.Errorf("expected 1 embedding in the response, got %d; for token embeddings set the pooling", len(vs))
+			}
+			return vs[0], nil
+		}
+		tokens = vs
+	} else if err := json.Unmarshal(body, &vss); err == nil {
+		if len(vss) != 1 {
+			return nil, fmt.Errorf("expected token embeddings for 1 input in the response, got %d", len(vss))
+		}
+		if pooling == HuggingFacePoolingServer && len(vss[0]) != 1 {
+			return nil, errors.New("got token embeddings in the response, set the pooling")
+		}
+		tokens = vss[0]
+	} else {
+		return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
+	}
+
+	if len(tokens) == 0 || len(tokens[0]) == 0 {
+		return nil, errors.New("no embeddings found in the response")
+	}
+	switch pooling {
+	case HuggingFacePoolingServer, HuggingFacePoolingCLS:
+		return tokens[0], nil
+	case HuggingFacePoolingLast:
+		return tokens[len(tokens)-1], nil
+	case HuggingFacePoolingMean:
+		res := make([]float32, len(tokens[0]))
+		for _, t := range tokens {
+			if len(t) != len(res) {
+				return nil, errors.New("token embeddings have different dimensions")
+			}
+			for i, f := range t {
+				res[i] += f
+			}
+		}
+		for i := range res {
+			res[i] /= float32(len(tokens))
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("unsupported pooling %q", pooling)
+	}
+}
@@ -0,0 +1,113 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNewEmbeddingFuncTEI(t *testing.T) {
+	apiKey := "secret"
+	text := "hello world"
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+
+	tt := []struct {
+		name     string
+		opts     HuggingFaceOptions
+		wantPath string
+		wantReq  huggingFaceRequest
+		response any
+	}{
+		{
+			name:     "server pooling",
+			opts:     HuggingFaceOptions{Truncate: true, TruncationDirection: "Left"},
+			wantPath: "/embed",
+			wantReq:  huggingFaceRequest{Inputs: text, Truncate: true, TruncationDirection: "Left"},
+			response: [][]float32{{-0.1, 0.1, 0.2}},
+		},
+		{
+			name:     "mean pooling",
+			opts:     HuggingFaceOptions{Pooling: HuggingFacePoolingMean},
+			wantPath: "/embed_all",
+			wantReq:  huggingFaceRequest{Inputs: text},
+			response: [][][]float32{{{-0.2, 0, 0.1}, {0, 0.2, 0.3}}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Mock server
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Check URL and method
+				if r.Method != "POST" || r.URL.Path != tc.wantPath {
+					t.Fatal("expected POST", tc.wantPath, "got", r.Method, r.URL.Path)
+				}
+				// Check headers
+				if r.Header.Get("Authorization") != "Bearer "+apiKey {
+					t.Fatal("expected Authorization header", "Bearer "+apiKey, "got", r.Header.Get("Authorization"))
+				}
+				// Check body
+				var req huggingFaceRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+				if req != tc.wantReq {
+					t.Fatalf("expected request %+v, got %+v", tc.wantReq, req)
+				}
+
+				// Write response
+				_ = json.NewEncoder(w).Encode(tc.response)
+			}))
+			defer ts.Close()
+
+			f := NewEmbeddingFuncTEI(ts.URL+"/", apiKey, tc.opts)
+			res, err := f(context.Background(), text)
+			if err != nil {
+				t.Fatal("expected nil, got", err)
+			}
+			if !reflect.DeepEqual(wantRes, res) {
+				t.Fatal("expected res", wantRes, "got", res)
+			}
+		})
+	}
+}
+
+func TestDecodeHuggingFaceEmbedding(t *testing.T) {
+	tt := []struct {
+		name    string
+		body    string
+		pooling HuggingFacePooling
+		want    []float32
+		wantErr bool
+	}{
+		{name: "single embedding", body: `[1,2]`, want: []float32{1, 2}},
+		{name: "embedding per input", body: `[[1,2]]`, want: []float32{1, 2}},
+		{name: "token embeddings without pooling", body: `[[[1,2],[3,4]]]`, wantErr: true},
+		{name: "token embeddings mean", body: `[[[1,2],[3,4]]]`, pooling: HuggingFacePoolingMean, want: []float32{2, 3}},
+		{name: "token embeddings cls", body: `[[[1,2],[3,4]]]`, pooling: HuggingFacePoolingCLS, want: []float32{1, 2}},
+		{name: "token embeddings last", body: `[[1,2],[3,4]]`, pooling: HuggingFacePoolingLast, want: []float32{3, 4}},
+		{name: "empty", body: `[]`, wantErr: true},
+		{name: "error object", body: `{"error":"model is loading"}`, wantErr: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decodeHuggingFaceEmbedding([]byte(tc.body), tc.pooling)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatal("expected", tc.want, "got", got)
+			}
+		})
+	}
+}
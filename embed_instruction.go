@@ -0,0 +1,97 @@
+package chromem
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// InstructionFormatInstructor is the format for Instructor models, like
+	// "hkunlp/instructor-large", with instructions like "Represent the
+	// Wikipedia document for retrieval:".
+	InstructionFormatInstructor = "{instruction} {text}"
+	// InstructionFormatNVEmbed is the format for NV-Embed and E5-Mistral models,
+	// like "nvidia/NV-Embed-v2" or "intfloat/e5-mistral-7b-instruct", with
+	// instructions like "Given a question, retrieve passages that answer the
+	// question". These models only use instructions for queries.
+	InstructionFormatNVEmbed = "Instruct: {instruction}\nQuery: {text}"
+)
+
+type embeddingInstructionKey struct{}
+
+// ContextWithEmbeddingInstruction returns a copy of the context that carries the
+// task instruction for instruction-tuned embedding models. Embedding functions
+// wrapped with [NewEmbeddingMiddlewareInstruction] pass it on to the model.
+// Collections set the instruction automatically, see
+// [Collection.SetEmbeddingInstructions], but an instruction in the context
+// passed to a method like [Collection.Query] takes precedence.
+func ContextWithEmbeddingInstruction(ctx context.Context, instruction string) context.Context {
+	return context.WithValue(ctx, embeddingInstructionKey{}, instruction)
+}
+
+// EmbeddingInstructionFromContext returns the task instruction of the context,
+// or an empty string if there's none.
+func EmbeddingInstructionFromContext(ctx context.Context) string {
+	instruction, _ := ctx.Value(embeddingInstructionKey{}).(string)
+	return instruction
+}
+
+// EmbeddingInstructions are the task instructions for instruction-tuned
+// embedding models, separately for ingestion and querying. An empty instruction
+// means no instruction is used.
+type EmbeddingInstructions struct {
+	// Instruction for the content of documents when adding them to the collection.
+	Document string
+	// Instruction for the query text and negative text when querying the collection.
+	Query string
+}
+
+// NewEmbeddingMiddlewareInstruction returns a middleware that passes the task
+// instruction from the context (see [ContextWithEmbeddingInstruction]) to an
+// instruction-tuned embedding model, by formatting the text with the given
+// format. The format must contain the placeholders "{instruction}" and "{text}",
+// like [InstructionFormatInstructor] or [InstructionFormatNVEmbed].
+// Texts without instruction in the context are passed on unchanged.
+func NewEmbeddingMiddlewareInstruction(format string) EmbeddingMiddleware {
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			if instruction := EmbeddingInstructionFromContext(ctx); instruction != "" {
+				text = strings.NewReplacer("{instruction}", instruction, "{text}", text).Replace(format)
+			}
+			return next(ctx, text)
+		}
+	}
+}
+
+// SetEmbeddingInstructions sets the task instructions that the collection
+// passes to the embedding function via the context when creating embeddings of
+// documents and queries. The embedding function must be wrapped with
+// [NewEmbeddingMiddlewareInstruction] (or read the instruction with
+// [EmbeddingInstructionFromContext] itself) for them to have an effect.
+// For persistent collections they're persisted, so they're also applied after
+// restarts.
+//
+// Like with [Collection.SetEmbeddingTemplate], changing the instructions of a
+// collection with existing documents leads to embeddings that aren't comparable
+// anymore.
+func (c *Collection) SetEmbeddingInstructions(instructions EmbeddingInstructions) error {
+	c.configLock.Lock()
+	c.config.EmbeddingInstructions = instructions
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// EmbeddingInstructions returns the collection's task instructions.
+func (c *Collection) EmbeddingInstructions() EmbeddingInstructions {
+	return c.getConfig().EmbeddingInstructions
+}
+
+// withEmbeddingInstruction returns the context with the given instruction,
+// unless it's empty or the context already has an instruction.
+func withEmbeddingInstruction(ctx context.Context, instruction string) context.Context {
+	if instruction == "" || EmbeddingInstructionFromContext(ctx) != "" {
+		return ctx
+	}
+	return ContextWithEmbeddingInstruction(ctx, instruction)
+}
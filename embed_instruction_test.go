@@ -0,0 +1,80 @@
+package chromem
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNewEmbeddingMiddlewareInstruction(t *testing.T) {
+	var got string
+	f := WrapEmbeddingFunc(func(_ context.Context, text string) ([]float32, error) {
+		got = text
+		return []float32{-0.40824828, 0.40824828, 0.81649655}, nil
+	}, NewEmbeddingMiddlewareInstruction(InstructionFormatNVEmbed))
+
+	// Without instruction
+	_, err := f(context.Background(), "hello")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if got != "hello" {
+		t.Fatalf("expected %q, got %q", "hello", got)
+	}
+
+	// With instruction
+	ctx := ContextWithEmbeddingInstruction(context.Background(), "Retrieve passages")
+	_, err = f(ctx, "hello")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	want := "Instruct: Retrieve passages\nQuery: hello"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCollection_EmbeddingInstructions(t *testing.T) {
+	var instructions []string
+	embeddingFunc := func(ctx context.Context, _ string) ([]float32, error) {
+		instructions = append(instructions, EmbeddingInstructionFromContext(ctx))
+		return []float32{-0.40824828, 0.40824828, 0.81649655}, nil
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetEmbeddingInstructions(EmbeddingInstructions{
+		Document: "Represent the document for retrieval:",
+		Query:    "Represent the question for retrieving documents:",
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	ctx := context.Background()
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "hello world"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// An instruction in the context takes precedence
+	_, err = c.Query(ContextWithEmbeddingInstruction(ctx, "custom"), "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	want := []string{
+		"Represent the document for retrieval:",
+		"Represent the question for retrieving documents:",
+		"custom",
+	}
+	if !reflect.DeepEqual(want, instructions) {
+		t.Fatalf("expected instructions %v, got %v", want, instructions)
+	}
+}
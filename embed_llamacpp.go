@@ -0,0 +1,191 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const defaultBaseURLLlamaCPP = "http://localhost:8080"
+
+// llamaCPPEmbedding is an element of the response of newer llama.cpp server
+// versions, which return a list with one element per input. The embedding is
+// a list of token embeddings, or a list with a single pooled embedding.
+type llamaCPPEmbedding struct {
+	Index     int             `json:"index"`
+	Embedding json.RawMessage `json:"embedding"`
+}
+
+// llamaCPPResponse is the response of older llama.cpp server versions, with
+// either a single embedding or, for multiple inputs, a list of results.
+type llamaCPPResponse struct {
+	Embedding json.RawMessage `json:"embedding"`
+	Results   []struct {
+		Embedding json.RawMessage `json:"embedding"`
+	} `json:"results"`
+}
+
+// NewEmbeddingFuncLlamaCPP returns a function that creates embeddings for a text
+// using the "/embedding" endpoint of llama.cpp's server ("llama-server").
+// The server must be started with the "--embedding" flag and a pooling type
+// other than "none", e.g. `llama-server -m nomic-embed-text-v1.5.Q8_0.gguf --embedding --pooling mean`.
+// See https://github.com/ggml-org/llama.cpp/tree/master/tools/server
+// baseURL is the base URL of the server. If it's empty, "http://localhost:8080"
+// is used.
+func NewEmbeddingFuncLlamaCPP(baseURL string, opts ...EmbeddingOption) EmbeddingFunc {
+	f := NewBatchEmbeddingFuncLlamaCPP(baseURL, opts...)
+	return func(ctx context.Context, text string) ([]float32, error) {
+		vs, err := f(ctx, []string{text})
+		if err != nil {
+			return nil, err
+		}
+		return vs[0], nil
+	}
+}
+
+// NewBatchEmbeddingFuncLlamaCPP returns a function that creates embeddings for
+// multiple texts with a single request to the "/embedding" endpoint of llama.cpp's
+// server. See [NewEmbeddingFuncLlamaCPP] for the server requirements.
+// The server processes the texts in parallel, up to its configured number of
+// slots ("--parallel") and batch size.
+func NewBatchEmbeddingFuncLlamaCPP(baseURL string, opts ...EmbeddingOption) BatchEmbeddingFunc {
+	if baseURL == "" {
+		baseURL = defaultBaseURLLlamaCPP
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	client := newEmbeddingHTTPClient(opts)
+
+	return func(ctx context.Context, texts []string) ([][]float32, error) {
+		if len(texts) == 0 {
+			return nil, errors.New("texts are empty")
+		}
+
+		// Prepare the request body. A single text is sent as string, which all
+		// server versions support.
+		var content any = texts
+		if len(texts) == 1 {
+			content = texts[0]
+		}
+		reqBody, err := json.Marshal(map[string]any{
+			"content": content,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal request body: %w", err)
+		}
+
+		// Create the request. Creating it with context is important for a timeout
+		// to be possible, because the client is configured without a timeout by default.
+		req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/embedding", bytes.NewBuffer(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		// Send the request.
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		// Check the response status.
+		if resp.StatusCode != http.StatusOK {
+			return nil, newEmbeddingError(resp)
+		}
+
+		// Read and decode the response body.
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read response body: %w", err)
+		}
+		raws, err := decodeLlamaCPPResponse(body)
+		if err != nil {
+			return nil, err
+		}
+		if len(raws) != len(texts) {
+			return nil, fmt.Errorf("expected %d embeddings in the response, got %d", len(texts), len(raws))
+		}
+
+		vs := make([][]float32, len(raws))
+		for i, raw := range raws {
+			v, err := decodeLlamaCPPEmbedding(raw)
+			if err != nil {
+				return nil, err
+			}
+			// Depending on the server version and "--embd-normalize" flag, the
+			// embeddings are normalized or not.
+			if !isNormalized(v) {
+				v = normalizeVector(v)
+			}
+			vs[i] = v
+		}
+
+		return vs, nil
+	}
+}
+
+// decodeLlamaCPPResponse decodes the response of any llama.cpp server version
+// into the raw embeddings, in the order of the inputs.
+func decodeLlamaCPPResponse(body []byte) ([]json.RawMessage, error) {
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var res []llamaCPPEmbedding
+		err := json.Unmarshal(body, &res)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
+		}
+		slices.SortFunc(res, func(a, b llamaCPPEmbedding) int {
+			return a.Index - b.Index
+		})
+		raws := make([]json.RawMessage, len(res))
+		for i, e := range res {
+			raws[i] = e.Embedding
+		}
+		return raws, nil
+	}
+
+	var res llamaCPPResponse
+	err := json.Unmarshal(body, &res)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
+	}
+	if len(res.Results) > 0 {
+		raws := make([]json.RawMessage, len(res.Results))
+		for i, r := range res.Results {
+			raws[i] = r.Embedding
+		}
+		return raws, nil
+	}
+	if len(res.Embedding) == 0 {
+		return nil, errors.New("no embeddings found in the response")
+	}
+	return []json.RawMessage{res.Embedding}, nil
+}
+
+// decodeLlamaCPPEmbedding decodes a single embedding, which is either a plain
+// vector or a list containing one pooled vector.
+func decodeLlamaCPPEmbedding(raw json.RawMessage) ([]float32, error) {
+	var v []float32
+	if err := json.Unmarshal(raw, &v); err != nil {
+		var vs [][]float32
+		if err := json.Unmarshal(raw, &vs); err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal embedding: %w", err)
+		}
+		if len(vs) > 1 {
+			return nil, errors.New("got token embeddings instead of a pooled embedding, start the server with a pooling type other than \"none\"")
+		}
+		if len(vs) == 1 {
+			v = vs[0]
+		}
+	}
+	if len(v) == 0 {
+		return nil, errors.New("no embeddings found in the response")
+	}
+	return v, nil
+}
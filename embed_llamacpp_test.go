@@ -0,0 +1,118 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNewBatchEmbeddingFuncLlamaCPP(t *testing.T) {
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+
+	tt := []struct {
+		name     string
+		texts    []string
+		response string
+		want     [][]float32
+		wantErr  bool
+	}{
+		{
+			name:     "old single",
+			texts:    []string{"hello"},
+			response: `{"embedding":[-0.1,0.1,0.2]}`,
+			want:     [][]float32{wantRes},
+		},
+		{
+			name:     "old batch",
+			texts:    []string{"hello", "world"},
+			response: `{"results":[{"embedding":[-0.1,0.1,0.2]},{"embedding":[0,0,2]}]}`,
+			want:     [][]float32{wantRes, {0, 0, 1}},
+		},
+		{
+			name:     "new batch out of order",
+			texts:    []string{"hello", "world"},
+			response: `[{"index":1,"embedding":[[0,0,2]]},{"index":0,"embedding":[[-0.1,0.1,0.2]]}]`,
+			want:     [][]float32{wantRes, {0, 0, 1}},
+		},
+		{
+			name:     "token embeddings",
+			texts:    []string{"hello"},
+			response: `[{"index":0,"embedding":[[-0.1,0.1,0.2],[0,0,1]]}]`,
+			wantErr:  true,
+		},
+		{
+			name:     "missing embeddings",
+			texts:    []string{"hello", "world"},
+			response: `[{"index":0,"embedding":[[-0.1,0.1,0.2]]}]`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Mock server
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Check URL and method
+				if r.Method != "POST" || r.URL.Path != "/embedding" {
+					t.Fatal("expected POST /embedding, got", r.Method, r.URL.Path)
+				}
+				// Check body
+				var req struct {
+					Content any `json:"content"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+				var wantContent any = tc.texts[0]
+				if len(tc.texts) > 1 {
+					texts := make([]any, len(tc.texts))
+					for i, text := range tc.texts {
+						texts[i] = text
+					}
+					wantContent = texts
+				}
+				if !reflect.DeepEqual(req.Content, wantContent) {
+					t.Fatal("expected content", wantContent, "got", req.Content)
+				}
+
+				// Write response
+				_, _ = w.Write([]byte(tc.response))
+			}))
+			defer ts.Close()
+
+			f := NewBatchEmbeddingFuncLlamaCPP(ts.URL)
+			res, err := f(context.Background(), tc.texts)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("expected nil, got", err)
+			}
+			if !reflect.DeepEqual(tc.want, res) {
+				t.Fatal("expected res", tc.want, "got", res)
+			}
+		})
+	}
+}
+
+func TestNewEmbeddingFuncLlamaCPP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"index":0,"embedding":[[0,3,4]]}]`))
+	}))
+	defer ts.Close()
+
+	f := NewEmbeddingFuncLlamaCPP(ts.URL + "/")
+	res, err := f(context.Background(), "hello")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if want := []float32{0, 0.6, 0.8}; !reflect.DeepEqual(want, res) {
+		t.Fatal("expected res", want, "got", res)
+	}
+}
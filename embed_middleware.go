@@ -0,0 +1,200 @@
+package chromem
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// EmbeddingMiddleware wraps an [EmbeddingFunc] to add behavior like logging,
+// caching or retries, without changing the embedding function itself.
+// Use [WrapEmbeddingFunc] to apply multiple middlewares.
+type EmbeddingMiddleware func(next EmbeddingFunc) EmbeddingFunc
+
+// WrapEmbeddingFunc wraps the embedding function with the given middlewares.
+// The first middleware is the outermost one, so it's called first and sees the
+// result of all others. For example:
+//
+//	embeddingFunc := chromem.WrapEmbeddingFunc(
+//		chromem.NewEmbeddingFuncOpenAI(apiKey, chromem.EmbeddingModelOpenAI3Small),
+//		chromem.NewEmbeddingMiddlewareLogging(nil),
+//		chromem.NewEmbeddingMiddlewareCache(10_000),
+//		chromem.NewEmbeddingMiddlewareRetry(3, time.Second),
+//		chromem.NewEmbeddingMiddlewareTruncate(8191),
+//	)
+//
+// Here cache hits are logged, but not retried, and only the truncated texts are
+// sent to the embedding API.
+func WrapEmbeddingFunc(f EmbeddingFunc, middlewares ...EmbeddingMiddleware) EmbeddingFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		f = middlewares[i](f)
+	}
+	return f
+}
+
+// NewEmbeddingMiddlewareLogging returns a middleware that logs each call of the
+// embedding function with its duration, the text length and the number of
+// dimensions, at debug level, and failed calls at error level. The text itself
+// isn't logged, as it might contain sensitive data.
+// If logger is nil, [slog.Default] is used.
+func NewEmbeddingMiddlewareLogging(logger *slog.Logger) EmbeddingMiddleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			start := time.Now()
+			v, err := next(ctx, text)
+			duration := time.Since(start)
+			if err != nil {
+				logger.ErrorContext(ctx, "Couldn't create embedding", "textLength", len(text), "duration", duration, "error", err)
+				return nil, err
+			}
+			logger.DebugContext(ctx, "Created embedding", "textLength", len(text), "duration", duration, "dimensions", len(v))
+			return v, nil
+		}
+	}
+}
+
+// NewEmbeddingMiddlewareRetry returns a middleware that retries failed calls of
+// the embedding function up to maxAttempts times in total, with exponential
+// backoff starting at the given duration. If the embedding API sent a
+// "Retry-After" header (see [EmbeddingError]) that's longer, it's used instead.
+// Errors that a retry can't fix aren't retried: from a canceled context or an
+// exceeded deadline, [ErrUnauthorized], [ErrInputTooLong], and [ErrCircuitOpen],
+// as the circuit breaker is meant to make calls fail fast.
+// The error of the last attempt is returned.
+func NewEmbeddingMiddlewareRetry(maxAttempts int, backoff time.Duration) EmbeddingMiddleware {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			wait := backoff
+			for attempt := 1; ; attempt++ {
+				v, err := next(ctx, text)
+				if err == nil {
+					return v, nil
+				}
+				if attempt >= maxAttempts || ctx.Err() != nil || !isRetryable(err) {
+					return nil, err
+				}
+
+				delay := wait
+				var embErr *EmbeddingError
+				if errors.As(err, &embErr) && embErr.RetryAfter > delay {
+					delay = embErr.RetryAfter
+				}
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, err
+				case <-timer.C:
+				}
+				wait *= 2
+			}
+		}
+	}
+}
+
+func isRetryable(err error) bool {
+	for _, target := range []error{context.Canceled, context.DeadlineExceeded, ErrUnauthorized, ErrInputTooLong, ErrCircuitOpen} {
+		if errors.Is(err, target) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewEmbeddingMiddlewareCache returns a middleware that caches the embeddings
+// of up to size texts in memory, evicting the least recently used ones. This
+// avoids repeated API calls, for example for the same queries, or when adding
+// the same documents again.
+// Each middleware has its own cache, so you must not share one between
+// embedding functions with different models.
+func NewEmbeddingMiddlewareCache(size int) EmbeddingMiddleware {
+	cache := newLRUCache[[sha256.Size]byte, []float32](size)
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			// We use the hash as key, so that the cache doesn't keep the texts.
+			key := sha256.Sum256([]byte(text))
+			if v, ok := cache.get(key); ok {
+				// Return a copy so that callers can't modify the cached embedding.
+				return append([]float32(nil), v...), nil
+			}
+
+			v, err := next(ctx, text)
+			if err != nil {
+				return nil, err
+			}
+			cache.add(key, append([]float32(nil), v...))
+			return v, nil
+		}
+	}
+}
+
+// NewEmbeddingMiddlewareTruncate returns a middleware that truncates texts that
+// are longer than roughly maxTokens tokens before passing them on, so that the
+// embedding API doesn't reject them. The number of tokens is estimated with
+// 4 characters per token, which is a common rule of thumb for English texts.
+// The text is cut at whitespace where possible.
+// For exact token counts use [NewEmbeddingMiddlewareTokenLimit] with a
+// [BPETokenizer].
+func NewEmbeddingMiddlewareTruncate(maxTokens int) EmbeddingMiddleware {
+	return NewEmbeddingMiddlewareTokenLimit(maxTokens, nil, TokenLimitTruncate)
+}
+
+// TokenLimitAction is what [NewEmbeddingMiddlewareTokenLimit] does with texts
+// that exceed the maximum number of tokens.
+type TokenLimitAction int
+
+const (
+	// TokenLimitTruncate truncates the text to the maximum number of tokens.
+	TokenLimitTruncate TokenLimitAction = iota
+	// TokenLimitWarn logs a warning via [slog.Default] and passes the text on
+	// unchanged, leaving it to the embedding API to handle it.
+	TokenLimitWarn
+	// TokenLimitReject returns an error wrapping [ErrInputTooLong], without
+	// calling the embedding API.
+	TokenLimitReject
+)
+
+// NewEmbeddingMiddlewareTokenLimit returns a middleware that counts the tokens
+// of texts and truncates, warns about or rejects the ones that exceed maxTokens,
+// instead of getting opaque errors from the embedding API.
+// Use [MaxTokensForModel] to get the limit of known models.
+//
+//   - maxTokens: The maximum number of tokens
+//   - tokenizer: The tokenizer to count tokens with. Optional, defaults to
+//     [NewTokenizerHeuristic]. For OpenAI models you can use [LoadTokenizerTiktoken]
+//     for exact counts.
+//   - action: What to do with texts that are too long
+func NewEmbeddingMiddlewareTokenLimit(maxTokens int, tokenizer Tokenizer, action TokenLimitAction) EmbeddingMiddleware {
+	if tokenizer == nil {
+		tokenizer = NewTokenizerHeuristic()
+	}
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			switch action {
+			case TokenLimitTruncate:
+				text = tokenizer.Truncate(text, maxTokens)
+			case TokenLimitWarn, TokenLimitReject:
+				n := tokenizer.CountTokens(text)
+				if n <= maxTokens {
+					break
+				}
+				if action == TokenLimitReject {
+					return nil, fmt.Errorf("%w: %d tokens, maximum is %d", ErrInputTooLong, n, maxTokens)
+				}
+				slog.WarnContext(ctx, "Text exceeds maximum number of tokens", "tokens", n, "maxTokens", maxTokens)
+			default:
+				return nil, fmt.Errorf("unknown token limit action %d", action)
+			}
+			return next(ctx, text)
+		}
+	}
+}
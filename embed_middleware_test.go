@@ -0,0 +1,161 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrapEmbeddingFunc(t *testing.T) {
+	var calls []string
+	middleware := func(name string) EmbeddingMiddleware {
+		return func(next EmbeddingFunc) EmbeddingFunc {
+			return func(ctx context.Context, text string) ([]float32, error) {
+				calls = append(calls, name)
+				return next(ctx, text)
+			}
+		}
+	}
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		calls = append(calls, "func")
+		return []float32{1}, nil
+	}
+
+	f := WrapEmbeddingFunc(embeddingFunc, middleware("first"), middleware("second"))
+	_, err := f(context.Background(), "hello")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if want := []string{"first", "second", "func"}; !reflect.DeepEqual(calls, want) {
+		t.Fatal("expected calls", want, "got", calls)
+	}
+}
+
+func TestNewEmbeddingMiddlewareLogging(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	fail := false
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		if fail {
+			return nil, errors.New("oops")
+		}
+		return []float32{1, 0}, nil
+	}
+
+	f := NewEmbeddingMiddlewareLogging(logger)(embeddingFunc)
+	_, _ = f(context.Background(), "secret text")
+	fail = true
+	_, _ = f(context.Background(), "secret text")
+
+	logs := buf.String()
+	if !strings.Contains(logs, "level=DEBUG msg=\"Created embedding\" textLength=11") || !strings.Contains(logs, "dimensions=2") {
+		t.Fatal("expected debug log, got", logs)
+	}
+	if !strings.Contains(logs, "level=ERROR msg=\"Couldn't create embedding\"") || !strings.Contains(logs, "error=oops") {
+		t.Fatal("expected error log, got", logs)
+	}
+	if strings.Contains(logs, "secret") {
+		t.Fatal("expected text not to be logged, got", logs)
+	}
+}
+
+func TestNewEmbeddingMiddlewareRetry(t *testing.T) {
+	tt := []struct {
+		name         string
+		failures     int
+		canceled     bool
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "success after retries", failures: 2, wantAttempts: 3},
+		{name: "give up after max attempts", failures: 5, wantAttempts: 3, wantErr: true},
+		{name: "no retry when canceled", failures: 5, canceled: true, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+				attempts++
+				if attempts <= tc.failures {
+					return nil, errors.New("oops")
+				}
+				return []float32{1}, nil
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.canceled {
+				cancel()
+			}
+
+			f := NewEmbeddingMiddlewareRetry(3, time.Millisecond)(embeddingFunc)
+			_, err := f(ctx, "hello")
+			if tc.wantErr && err == nil {
+				t.Fatal("expected error, got nil")
+			} else if !tc.wantErr && err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if attempts != tc.wantAttempts {
+				t.Fatal("expected", tc.wantAttempts, "attempts, got", attempts)
+			}
+		})
+	}
+}
+
+func TestNewEmbeddingMiddlewareCache(t *testing.T) {
+	calls := 0
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		calls++
+		return []float32{float32(len(text))}, nil
+	}
+
+	f := NewEmbeddingMiddlewareCache(10)(embeddingFunc)
+	for i := 0; i < 3; i++ {
+		v, err := f(context.Background(), "hello")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if v[0] != 5 {
+			t.Fatal("expected 5, got", v[0])
+		}
+		// Modifying the result must not modify the cache
+		v[0] = 0
+	}
+	if calls != 1 {
+		t.Fatal("expected 1 call, got", calls)
+	}
+	_, _ = f(context.Background(), "world!")
+	if calls != 2 {
+		t.Fatal("expected 2 calls, got", calls)
+	}
+}
+
+func TestNewEmbeddingMiddlewareTruncate(t *testing.T) {
+	var got string
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		got = text
+		return []float32{1}, nil
+	}
+
+	f := NewEmbeddingMiddlewareTruncate(2)(embeddingFunc)
+	tt := []struct {
+		text string
+		want string
+	}{
+		{text: "short", want: "short"},
+		{text: "hello world foo", want: "hello"},
+		{text: "helloworldfoo", want: "hellowor"},
+		{text: "äöüäöüäöüä", want: "äöüäöüäö"},
+	}
+	for _, tc := range tt {
+		_, _ = f(context.Background(), tc.text)
+		if got != tc.want {
+			t.Fatalf("expected %q, got %q", tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Media is a reference to an image (or other media) that's embedded by an
+// [EmbeddingFuncMultimodal]. Either URL or Data must be set.
+type Media struct {
+	// The URL of the media, for example "https://example.com/cat.jpg", a data URI
+	// like "data:image/png;base64,...", or a Google Cloud Storage URI like
+	// "gs://bucket/cat.jpg" for Vertex AI. Which schemes are supported depends
+	// on the embedding provider.
+	URL string
+
+	// The raw bytes of the media. Used if URL is empty. As documents are kept in
+	// memory (and persisted if the DB is persistent), it's better to use URLs for
+	// documents, and bytes only for queries.
+	Data []byte
+
+	// The MIME type of Data, like "image/png". Optional, it's detected from the
+	// bytes if empty.
+	MIMEType string
+}
+
+// dataURI returns the media as data URI, or its URL if it's set.
+func (m Media) dataURI() string {
+	if m.URL != "" {
+		return m.URL
+	}
+	mimeType := m.MIMEType
+	if mimeType == "" {
+		mimeType = http.DetectContentType(m.Data)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(m.Data)
+}
+
+// bytes returns the bytes of the media, decoding them from a data URI if
+// necessary. It returns false if the media has neither bytes nor a base64 data
+// URI.
+func (m Media) bytes() ([]byte, bool, error) {
+	if m.URL == "" {
+		return m.Data, true, nil
+	}
+	after, ok := strings.CutPrefix(m.URL, "data:")
+	if !ok {
+		return nil, false, nil
+	}
+	_, data, ok := strings.Cut(after, ";base64,")
+	if !ok {
+		return nil, false, errors.New("data URI isn't base64 encoded")
+	}
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("couldn't decode data URI: %w", err)
+	}
+	return b, true, nil
+}
+
+// EmbeddingInput is the input for an [EmbeddingFuncMultimodal]. Exactly one of
+// Text and Media must be set.
+type EmbeddingInput struct {
+	Text  string
+	Media *Media
+}
+
+func (in EmbeddingInput) validate() error {
+	if (in.Text == "") == (in.Media == nil) {
+		return errors.New("exactly one of text and media must be set")
+	}
+	if in.Media != nil && in.Media.URL == "" && len(in.Media.Data) == 0 {
+		return errors.New("either media URL or data must be set")
+	}
+	return nil
+}
+
+// EmbeddingFuncMultimodal is a function that creates embeddings for texts and
+// images (or other media) in the same vector space, as CLIP-style models do.
+// This enables searching images by text and vice versa. Set it for a collection
+// with [Collection.SetEmbeddingFuncMultimodal].
+// The embeddings must be normalized, like for [EmbeddingFunc].
+type EmbeddingFuncMultimodal func(ctx context.Context, input EmbeddingInput) ([]float32, error)
+
+// NewEmbeddingFuncFromMultimodal returns an [EmbeddingFunc] that creates
+// embeddings of texts with the multimodal embedding function. Use it as the
+// collection's embedding function for searching images by text.
+func NewEmbeddingFuncFromMultimodal(f EmbeddingFuncMultimodal) EmbeddingFunc {
+	return func(ctx context.Context, text string) ([]float32, error) {
+		return f(ctx, EmbeddingInput{Text: text})
+	}
+}
+
+// EmbeddingModelJinaCLIP are Jina's multimodal models.
+const (
+	EmbeddingModelJinaCLIPV1 EmbeddingModelJina = "jina-clip-v1"
+	EmbeddingModelJinaCLIPV2 EmbeddingModelJina = "jina-clip-v2"
+)
+
+// NewEmbeddingFuncMultimodalJina returns a function that creates embeddings for
+// texts and images using a Jina CLIP model via the Jina API. Images can be
+// passed as URL or bytes.
+func NewEmbeddingFuncMultimodalJina(apiKey string, model EmbeddingModelJina, opts ...EmbeddingOption) EmbeddingFuncMultimodal {
+	return NewEmbeddingFuncMultimodalOpenAICompat(baseURLJina, apiKey, string(model), opts...)
+}
+
+// NewEmbeddingFuncMultimodalOpenAICompat returns a function that creates
+// embeddings for texts and images using an OpenAI compatible API that accepts
+// objects like {"text": "..."} and {"image": "..."} as input, like Jina's API
+// and servers that are compatible with it. Images are sent as URL, or as base64
+// encoded bytes.
+// The embeddings are normalized if they aren't already.
+func NewEmbeddingFuncMultimodalOpenAICompat(baseURL, apiKey, model string, opts ...EmbeddingOption) EmbeddingFuncMultimodal {
+	client := newEmbeddingHTTPClient(opts)
+
+	return func(ctx context.Context, input EmbeddingInput) ([]float32, error) {
+		if err := input.validate(); err != nil {
+			return nil, err
+		}
+
+		// Prepare the request body.
+		reqInput := map[string]string{"text": input.Text}
+		if input.Media != nil {
+			image := input.Media.URL
+			if image == "" {
+				image = base64.StdEncoding.EncodeToString(input.Media.Data)
+			}
+			reqInput = map[string]string{"image": image}
+		}
+		reqBody, err := json.Marshal(map[string]any{
+			"input": []map[string]string{reqInput},
+			"model": model,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal request body: %w", err)
+		}
+
+		// Create the request. Creating it with context is important for a timeout
+		// to be possible, because the client is configured without a timeout by default.
+		req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/embeddings", bytes.NewBuffer(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+
+		// Send the request.
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		// Check the response status.
+		if resp.StatusCode != http.StatusOK {
+			return nil, newEmbeddingError(resp)
+		}
+
+		// Read and decode the response body.
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read response body: %w", err)
+		}
+		var embeddingResponse openAIResponse
+		err = json.Unmarshal(body, &embeddingResponse)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
+		}
+
+		// Check if the response contains embeddings.
+		if len(embeddingResponse.Data) == 0 || len(embeddingResponse.Data[0].Embedding) == 0 {
+			return nil, errors.New("no embeddings found in the response")
+		}
+
+		v := embeddingResponse.Data[0].Embedding
+		if !isNormalized(v) {
+			v = normalizeVector(v)
+		}
+		return v, nil
+	}
+}
+
+const baseURLVertexTemplate = "https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/publishers/google/models/multimodalembedding@001:predict"
+
+type vertexMultimodalResponse struct {
+	Predictions []struct {
+		TextEmbedding  []float32 `json:"textEmbedding"`
+		ImageEmbedding []float32 `json:"imageEmbedding"`
+	} `json:"predictions"`
+}
+
+// NewEmbeddingFuncMultimodalVertex returns a function that creates embeddings
+// for texts and images using Google's Vertex AI "multimodalembedding@001" model.
+// Images can be passed as bytes, data URI or Google Cloud Storage URI
+// ("gs://..."), but not as HTTP URL.
+// See https://cloud.google.com/vertex-ai/generative-ai/docs/embeddings/get-multimodal-embeddings
+//
+//   - accessToken: An OAuth 2 access token, for example from
+//     "gcloud auth print-access-token"
+//   - project: The Google Cloud project ID
+//   - location: The region, like "us-central1"
+//   - opts: Options for the HTTP client, see [EmbeddingOption]
+func NewEmbeddingFuncMultimodalVertex(accessToken, project, location string, opts ...EmbeddingOption) EmbeddingFuncMultimodal {
+	url := fmt.Sprintf(baseURLVertexTemplate, location, project, location)
+	return newEmbeddingFuncMultimodalVertex(url, accessToken, opts)
+}
+
+func newEmbeddingFuncMultimodalVertex(url, accessToken string, opts []EmbeddingOption) EmbeddingFuncMultimodal {
+	client := newEmbeddingHTTPClient(opts)
+
+	var checkedNormalized bool
+	checkNormalized := sync.Once{}
+
+	return func(ctx context.Context, input EmbeddingInput) ([]float32, error) {
+		if err := input.validate(); err != nil {
+			return nil, err
+		}
+
+		// Prepare the request body.
+		instance := map[string]any{}
+		if input.Media == nil {
+			instance["text"] = input.Text
+		} else if strings.HasPrefix(input.Media.URL, "gs://") {
+			instance["image"] = map[string]string{"gcsUri": input.Media.URL}
+		} else {
+			b, ok, err := input.Media.bytes()
+			if err != nil {
+				return nil, err
+			} else if !ok {
+				return nil, fmt.Errorf("unsupported media URL %q, only data and gs:// URIs are supported", input.Media.URL)
+			}
+			instance["image"] = map[string]string{"bytesBase64Encoded": base64.StdEncoding.EncodeToString(b)}
+		}
+		reqBody, err := json.Marshal(map[string]any{
+			"instances": []map[string]any{instance},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal request body: %w", err)
+		}
+
+		// Create the request. Creating it with context is important for a timeout
+		// to be possible, because the client is configured without a timeout by default.
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+
+		// Send the request.
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		// Check the response status.
+		if resp.StatusCode != http.StatusOK {
+			return nil, newEmbeddingError(resp)
+		}
+
+		// Read and decode the response body.
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read response body: %w", err)
+		}
+		var embeddingResponse vertexMultimodalResponse
+		err = json.Unmarshal(body, &embeddingResponse)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
+		}
+
+		// Check if the response contains embeddings.
+		if len(embeddingResponse.Predictions) == 0 {
+			return nil, errors.New("no embeddings found in the response")
+		}
+		v := embeddingResponse.Predictions[0].TextEmbedding
+		if input.Media != nil {
+			v = embeddingResponse.Predictions[0].ImageEmbedding
+		}
+		if len(v) == 0 {
+			return nil, errors.New("no embeddings found in the response")
+		}
+
+		checkNormalized.Do(func() {
+			if isNormalized(v) {
+				checkedNormalized = true
+			} else {
+				checkedNormalized = false
+			}
+		})
+		if !checkedNormalized {
+			v = normalizeVector(v)
+		}
+
+		return v, nil
+	}
+}
+
+// SetEmbeddingFuncMultimodal sets the multimodal embedding function of the
+// collection, which is used for documents with media (see [Document.Media]) and
+// queries with media (see [QueryOptions.QueryMedia]). Texts are still embedded
+// with the collection's regular embedding function, so for searching images by
+// text you should create the collection with [NewEmbeddingFuncFromMultimodal].
+//
+// Like the regular embedding function, it's not persisted, so you have to set
+// it again after loading a persistent DB.
+func (c *Collection) SetEmbeddingFuncMultimodal(f EmbeddingFuncMultimodal) {
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+
+	c.embedMultimodal = f
+}
+
+// embedMedia creates the embedding of the media with the collection's
+// multimodal embedding function.
+func (c *Collection) embedMedia(ctx context.Context, media *Media) ([]float32, error) {
+	c.configLock.RLock()
+	embed := c.embedMultimodal
+	c.configLock.RUnlock()
+
+	if embed == nil {
+		return nil, errors.New("collection has no multimodal embedding function")
+	}
+	embedding, err := embed(ctx, EmbeddingInput{Media: media})
+	c.counters().embedded(err)
+	return embedding, err
+}
@@ -0,0 +1,213 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNewEmbeddingFuncMultimodalOpenAICompat(t *testing.T) {
+	apiKey := "secret"
+	model := "jina-clip-v2"
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+
+	tt := []struct {
+		name      string
+		input     EmbeddingInput
+		wantInput map[string]string
+	}{
+		{
+			name:      "text",
+			input:     EmbeddingInput{Text: "a cat"},
+			wantInput: map[string]string{"text": "a cat"},
+		},
+		{
+			name:      "image URL",
+			input:     EmbeddingInput{Media: &Media{URL: "https://example.com/cat.jpg"}},
+			wantInput: map[string]string{"image": "https://example.com/cat.jpg"},
+		},
+		{
+			name:      "image bytes",
+			input:     EmbeddingInput{Media: &Media{Data: []byte("cat")}},
+			wantInput: map[string]string{"image": "Y2F0"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Mock server
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Check URL and method
+				if r.Method != "POST" || r.URL.Path != "/embeddings" {
+					t.Fatal("expected POST /embeddings, got", r.Method, r.URL.Path)
+				}
+				// Check headers
+				if r.Header.Get("Authorization") != "Bearer "+apiKey {
+					t.Fatal("expected Authorization header", "Bearer "+apiKey, "got", r.Header.Get("Authorization"))
+				}
+				// Check body
+				var req struct {
+					Input []map[string]string `json:"input"`
+					Model string              `json:"model"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+				if req.Model != model || len(req.Input) != 1 || !reflect.DeepEqual(tc.wantInput, req.Input[0]) {
+					t.Fatalf("expected model %q and input %v, got %+v", model, tc.wantInput, req)
+				}
+
+				// Write response
+				_, _ = w.Write([]byte(`{"data":[{"embedding":[-0.1,0.1,0.2]}]}`))
+			}))
+			defer ts.Close()
+
+			f := NewEmbeddingFuncMultimodalOpenAICompat(ts.URL, apiKey, model)
+			res, err := f(context.Background(), tc.input)
+			if err != nil {
+				t.Fatal("expected nil, got", err)
+			}
+			if !reflect.DeepEqual(wantRes, res) {
+				t.Fatal("expected res", wantRes, "got", res)
+			}
+		})
+	}
+
+	t.Run("invalid input", func(t *testing.T) {
+		f := NewEmbeddingFuncMultimodalOpenAICompat("http://localhost:1", apiKey, model)
+		_, err := f(context.Background(), EmbeddingInput{Text: "a cat", Media: &Media{URL: "https://example.com/cat.jpg"}})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestNewEmbeddingFuncMultimodalVertex(t *testing.T) {
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655}
+
+	tt := []struct {
+		name         string
+		input        EmbeddingInput
+		wantInstance string
+		wantErr      bool
+	}{
+		{
+			name:         "text",
+			input:        EmbeddingInput{Text: "a cat"},
+			wantInstance: `{"text":"a cat"}`,
+		},
+		{
+			name:         "image bytes",
+			input:        EmbeddingInput{Media: &Media{Data: []byte("cat")}},
+			wantInstance: `{"image":{"bytesBase64Encoded":"Y2F0"}}`,
+		},
+		{
+			name:         "data URI",
+			input:        EmbeddingInput{Media: &Media{URL: "data:image/png;base64,Y2F0"}},
+			wantInstance: `{"image":{"bytesBase64Encoded":"Y2F0"}}`,
+		},
+		{
+			name:         "GCS URI",
+			input:        EmbeddingInput{Media: &Media{URL: "gs://bucket/cat.png"}},
+			wantInstance: `{"image":{"gcsUri":"gs://bucket/cat.png"}}`,
+		},
+		{
+			name:    "HTTP URL",
+			input:   EmbeddingInput{Media: &Media{URL: "https://example.com/cat.png"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Mock server
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer token" {
+					t.Fatal("expected Authorization header", "Bearer token", "got", r.Header.Get("Authorization"))
+				}
+				var req struct {
+					Instances []json.RawMessage `json:"instances"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+				if len(req.Instances) != 1 || string(req.Instances[0]) != tc.wantInstance {
+					t.Fatalf("expected instance %s, got %s", tc.wantInstance, req.Instances)
+				}
+				_, _ = w.Write([]byte(`{"predictions":[{"textEmbedding":[-0.1,0.1,0.2],"imageEmbedding":[-0.1,0.1,0.2]}]}`))
+			}))
+			defer ts.Close()
+
+			f := newEmbeddingFuncMultimodalVertex(ts.URL, "token", nil)
+			res, err := f(context.Background(), tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("expected nil, got", err)
+			}
+			if !reflect.DeepEqual(wantRes, res) {
+				t.Fatal("expected res", wantRes, "got", res)
+			}
+		})
+	}
+}
+
+func TestCollection_Multimodal(t *testing.T) {
+	ctx := context.Background()
+	vectors := map[string][]float32{
+		"cat": {-0.40824828, 0.40824828, 0.81649655},
+		"dog": {0.40824828, -0.40824828, 0.81649655},
+	}
+	embeddingFunc := func(_ context.Context, input EmbeddingInput) ([]float32, error) {
+		if input.Media != nil {
+			return vectors[string(input.Media.Data)+input.Media.URL], nil
+		}
+		return vectors[input.Text], nil
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("images", nil, NewEmbeddingFuncFromMultimodal(embeddingFunc))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Without multimodal embedding function
+	err = c.AddDocument(ctx, Document{ID: "1", Media: &Media{URL: "cat"}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	c.SetEmbeddingFuncMultimodal(embeddingFunc)
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Media: &Media{URL: "cat"}},
+		{ID: "2", Media: &Media{URL: "dog"}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Query by text
+	res, err := c.Query(ctx, "dog", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "2" {
+		t.Fatal("expected document 2, got", res[0].ID)
+	}
+
+	// Query by image
+	res, err = c.QueryWithOptions(ctx, QueryOptions{QueryMedia: &Media{Data: []byte("cat")}, NResults: 1})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "1" || res[0].Media.URL != "cat" {
+		t.Fatal("expected document 1 with media, got", res[0].ID, res[0].Media)
+	}
+}
@@ -23,15 +23,12 @@ type ollamaResponse struct {
 // See https://ollama.com/library/nomic-embed-text
 // baseURLOllama is the base URL of the Ollama API. If it's empty,
 // "http://localhost:11434/api" is used.
-func NewEmbeddingFuncOllama(model string, baseURLOllama string) EmbeddingFunc {
+func NewEmbeddingFuncOllama(model string, baseURLOllama string, opts ...EmbeddingOption) EmbeddingFunc {
 	if baseURLOllama == "" {
 		baseURLOllama = defaultBaseURLOllama
 	}
 
-	// We don't set a default timeout here, although it's usually a good idea.
-	// In our case though, the library user can set the timeout on the context,
-	// and it might have to be a long timeout, depending on the text length.
-	client := &http.Client{}
+	client := newEmbeddingHTTPClient(opts)
 
 	var checkedNormalized bool
 	checkNormalized := sync.Once{}
@@ -47,7 +44,7 @@ func NewEmbeddingFuncOllama(model string, baseURLOllama string) EmbeddingFunc {
 		}
 
 		// Create the request. Creating it with context is important for a timeout
-		// to be possible, because the client is configured without a timeout.
+		// to be possible, because the client is configured without a timeout by default.
 		req, err := http.NewRequestWithContext(ctx, "POST", baseURLOllama+"/embeddings", bytes.NewBuffer(reqBody))
 		if err != nil {
 			return nil, fmt.Errorf("couldn't create request: %w", err)
@@ -63,7 +60,7 @@ func NewEmbeddingFuncOllama(model string, baseURLOllama string) EmbeddingFunc {
 
 		// Check the response status.
 		if resp.StatusCode != http.StatusOK {
-			return nil, errors.New("error response from the embedding API: " + resp.Status)
+			return nil, newEmbeddingError(resp)
 		}
 
 		// Read and decode the response body.
@@ -32,17 +32,17 @@ type openAIResponse struct {
 // using OpenAI`s "text-embedding-3-small" model via their API.
 // The model supports a maximum text length of 8191 tokens.
 // The API key is read from the environment variable "OPENAI_API_KEY".
-func NewEmbeddingFuncDefault() EmbeddingFunc {
+func NewEmbeddingFuncDefault(opts ...EmbeddingOption) EmbeddingFunc {
 	apiKey := os.Getenv("OPENAI_API_KEY")
-	return NewEmbeddingFuncOpenAI(apiKey, EmbeddingModelOpenAI3Small)
+	return NewEmbeddingFuncOpenAI(apiKey, EmbeddingModelOpenAI3Small, opts...)
 }
 
 // NewEmbeddingFuncOpenAI returns a function that creates embeddings for a text
 // using the OpenAI API.
-func NewEmbeddingFuncOpenAI(apiKey string, model EmbeddingModelOpenAI) EmbeddingFunc {
+func NewEmbeddingFuncOpenAI(apiKey string, model EmbeddingModelOpenAI, opts ...EmbeddingOption) EmbeddingFunc {
 	// OpenAI embeddings are normalized
 	normalized := true
-	return NewEmbeddingFuncOpenAICompat(BaseURLOpenAI, apiKey, string(model), &normalized)
+	return NewEmbeddingFuncOpenAICompat(BaseURLOpenAI, apiKey, string(model), &normalized, opts...)
 }
 
 // NewEmbeddingFuncOpenAICompat returns a function that creates embeddings for a text
@@ -56,8 +56,8 @@ func NewEmbeddingFuncOpenAI(apiKey string, model EmbeddingModelOpenAI) Embedding
 // model are already normalized, as is the case for OpenAI's and Mistral's models.
 // The flag is optional. If it's nil, it will be autodetected on the first request
 // (which bears a small risk that the vector just happens to have a length of 1).
-func NewEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *bool) EmbeddingFunc {
-	return newEmbeddingFuncOpenAICompat(baseURL, apiKey, model, normalized, nil, nil)
+func NewEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *bool, opts ...EmbeddingOption) EmbeddingFunc {
+	return newEmbeddingFuncOpenAICompat(baseURL, apiKey, model, normalized, nil, nil, opts...)
 }
 
 // newEmbeddingFuncOpenAICompat returns a function that creates embeddings for a text
@@ -69,11 +69,8 @@ func NewEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *boo
 // model are already normalized, as is the case for OpenAI's and Mistral's models.
 // The flag is optional. If it's nil, it will be autodetected on the first request
 // (which bears a small risk that the vector just happens to have a length of 1).
-func newEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *bool, headers map[string]string, queryParams map[string]string) EmbeddingFunc {
-	// We don't set a default timeout here, although it's usually a good idea.
-	// In our case though, the library user can set the timeout on the context,
-	// and it might have to be a long timeout, depending on the text length.
-	client := &http.Client{}
+func newEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *bool, headers map[string]string, queryParams map[string]string, opts ...EmbeddingOption) EmbeddingFunc {
+	client := newEmbeddingHTTPClient(opts)
 
 	var checkedNormalized bool
 	checkNormalized := sync.Once{}
@@ -89,7 +86,7 @@ func newEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *boo
 		}
 
 		// Create the request. Creating it with context is important for a timeout
-		// to be possible, because the client is configured without a timeout.
+		// to be possible, because the client is configured without a timeout by default.
 		req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/embeddings", bytes.NewBuffer(reqBody))
 		if err != nil {
 			return nil, fmt.Errorf("couldn't create request: %w", err)
@@ -118,7 +115,7 @@ func newEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *boo
 
 		// Check the response status.
 		if resp.StatusCode != http.StatusOK {
-			return nil, errors.New("error response from the embedding API: " + resp.Status)
+			return nil, newEmbeddingError(resp)
 		}
 
 		// Read and decode the response body.
@@ -0,0 +1,92 @@
+package chromem
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// EmbeddingOption configures the HTTP client of the built-in embedding functions,
+// like [NewEmbeddingFuncOpenAI] or [NewEmbeddingFuncOllama].
+type EmbeddingOption func(*embeddingConfig)
+
+type embeddingConfig struct {
+	client    *http.Client
+	proxyURL  *url.URL
+	tlsConfig *tls.Config
+	timeout   time.Duration
+}
+
+// WithHTTPClient sets the HTTP client that's used for the requests to the
+// embedding API, for example with a custom transport for tracing or mTLS.
+// When it's set, [WithProxy] and [WithTLSConfig] are ignored, as they're meant
+// for configuring the default client.
+func WithHTTPClient(client *http.Client) EmbeddingOption {
+	return func(c *embeddingConfig) {
+		c.client = client
+	}
+}
+
+// WithProxy sets the URL of the proxy for the requests to the embedding API,
+// e.g. "http://proxy.example.com:3128". By default, the proxy is read from the
+// environment variables HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
+func WithProxy(proxyURL *url.URL) EmbeddingOption {
+	return func(c *embeddingConfig) {
+		c.proxyURL = proxyURL
+	}
+}
+
+// WithTLSConfig sets the TLS configuration for the requests to the embedding
+// API, for example with a custom root CA for a corporate proxy, or with client
+// certificates for mTLS.
+func WithTLSConfig(tlsConfig *tls.Config) EmbeddingOption {
+	return func(c *embeddingConfig) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// WithTimeout sets the timeout for each request to the embedding API. By default
+// there's no timeout, so that you can control it via the context, as the time
+// for creating an embedding depends on the text length. This also applies to
+// the client set via [WithHTTPClient], without modifying it.
+func WithTimeout(timeout time.Duration) EmbeddingOption {
+	return func(c *embeddingConfig) {
+		c.timeout = timeout
+	}
+}
+
+// newEmbeddingHTTPClient creates the HTTP client for an embedding function,
+// according to the options.
+func newEmbeddingHTTPClient(opts []EmbeddingOption) *http.Client {
+	cfg := embeddingConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.client != nil {
+		if cfg.timeout == 0 {
+			return cfg.client
+		}
+		// Copy, so that we don't modify the user's client.
+		client := *cfg.client
+		client.Timeout = cfg.timeout
+		return &client
+	}
+
+	// We don't set a default timeout here, although it's usually a good idea.
+	// In our case though, the library user can set the timeout on the context,
+	// and it might have to be a long timeout, depending on the text length.
+	client := &http.Client{Timeout: cfg.timeout}
+	if cfg.proxyURL != nil || cfg.tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if cfg.proxyURL != nil {
+			transport.Proxy = http.ProxyURL(cfg.proxyURL)
+		}
+		if cfg.tlsConfig != nil {
+			transport.TLSClientConfig = cfg.tlsConfig
+		}
+		client.Transport = transport
+	}
+	return client
+}
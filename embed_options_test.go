@@ -0,0 +1,99 @@
+package chromem
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"embedding":[0,0,1]}`))
+	}))
+	defer ts.Close()
+
+	transport := &countingTransport{}
+	client := &http.Client{Transport: transport}
+	f := NewEmbeddingFuncOllama("model", ts.URL, WithHTTPClient(client), WithTimeout(time.Minute))
+	_, err := f(context.Background(), "hello")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if transport.requests != 1 {
+		t.Fatal("expected 1 request via the custom client, got", transport.requests)
+	}
+	// The user's client must not be modified
+	if client.Timeout != 0 {
+		t.Fatal("expected client timeout to be unchanged, got", client.Timeout)
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests to a proxy contain the absolute URL
+		proxied = r.URL.String()
+		_, _ = w.Write([]byte(`{"embedding":[0,0,1]}`))
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	f := NewEmbeddingFuncOllama("model", "http://ollama.invalid/api", WithProxy(proxyURL))
+	_, err = f(context.Background(), "hello")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if proxied != "http://ollama.invalid/api/embeddings" {
+		t.Fatal("expected request via proxy, got", proxied)
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"embedding":[0,0,1]}`))
+	}))
+	defer ts.Close()
+
+	// Without the server's certificate the request fails
+	f := NewEmbeddingFuncOllama("model", ts.URL)
+	_, err := f(context.Background(), "hello")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ts.Certificate())
+	f = NewEmbeddingFuncOllama("model", ts.URL, WithTLSConfig(&tls.Config{RootCAs: rootCAs}))
+	_, err = f(context.Background(), "hello")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	client := newEmbeddingHTTPClient([]EmbeddingOption{WithTimeout(time.Second)})
+	if client.Timeout != time.Second {
+		t.Fatal("expected timeout of 1s, got", client.Timeout)
+	}
+	client = newEmbeddingHTTPClient(nil)
+	if client.Timeout != 0 {
+		t.Fatal("expected no timeout, got", client.Timeout)
+	}
+}
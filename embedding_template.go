@@ -0,0 +1,102 @@
+package chromem
+
+import (
+	"context"
+	"strings"
+)
+
+// templatePlaceholder is the placeholder for the text in an embedding template.
+const templatePlaceholder = "{text}"
+
+// EmbeddingTemplate defines how texts are formatted before their embeddings are
+// created. Many embedding models were trained with different prefixes for the
+// texts that are searched for and the texts that are searched in, and the
+// retrieval quality degrades when they're missing. For example E5 models require
+// "query: " and "passage: " prefixes.
+//
+// Each template can contain the placeholder "{text}", which is replaced by the
+// text. A template without the placeholder is used as prefix. An empty template
+// leaves the text unchanged.
+//
+// Set it with [Collection.SetEmbeddingTemplate]. It's only applied when the
+// collection creates embeddings, not for documents or queries that come with
+// their embedding. The document content itself is stored without the template.
+type EmbeddingTemplate struct {
+	// Template for the content of documents when adding them to the collection.
+	Document string
+	// Template for the query text and negative text when querying the collection.
+	Query string
+}
+
+var (
+	// EmbeddingTemplateE5 is the template for the E5 family of models, like
+	// "intfloat/e5-large-v2" or "intfloat/multilingual-e5-large".
+	EmbeddingTemplateE5 = EmbeddingTemplate{
+		Document: "passage: ",
+		Query:    "query: ",
+	}
+	// EmbeddingTemplateBGE is the template for the English BGE v1.5 models, like
+	// "BAAI/bge-large-en-v1.5", which only use an instruction for queries.
+	EmbeddingTemplateBGE = EmbeddingTemplate{
+		Query: "Represent this sentence for searching relevant passages: ",
+	}
+	// EmbeddingTemplateNomic is the template for Nomic's models, like
+	// "nomic-embed-text". The prefixes are the same as the ones for
+	// [NewEmbeddingFuncCohere], which detects them and sets the input type
+	// accordingly, so the template can be used for Cohere as well.
+	EmbeddingTemplateNomic = EmbeddingTemplate{
+		Document: InputTypeCohereSearchDocumentPrefix,
+		Query:    InputTypeCohereSearchQueryPrefix,
+	}
+)
+
+// formatWithTemplate returns the text formatted with the template.
+func formatWithTemplate(template, text string) string {
+	if template == "" {
+		return text
+	}
+	if !strings.Contains(template, templatePlaceholder) {
+		return template + text
+	}
+	return strings.ReplaceAll(template, templatePlaceholder, text)
+}
+
+// SetEmbeddingTemplate sets the template that's applied to texts before the
+// collection creates their embeddings. For persistent collections it's
+// persisted, so it's also applied after restarts.
+//
+// Changing the template of a collection with existing documents leads to
+// embeddings that aren't comparable anymore, so it should be set right after
+// creating the collection.
+func (c *Collection) SetEmbeddingTemplate(template EmbeddingTemplate) error {
+	c.configLock.Lock()
+	c.config.EmbeddingTemplate = template
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// EmbeddingTemplate returns the collection's embedding template.
+func (c *Collection) EmbeddingTemplate() EmbeddingTemplate {
+	return c.getConfig().EmbeddingTemplate
+}
+
+// embedDocument creates the embedding of a document's content, applying the
+// collection's document template and instruction.
+func (c *Collection) embedDocument(ctx context.Context, content string) ([]float32, error) {
+	config := c.getConfig()
+	ctx = withEmbeddingInstruction(ctx, config.EmbeddingInstructions.Document)
+	embedding, err := c.embed(ctx, formatWithTemplate(config.EmbeddingTemplate.Document, content))
+	c.counters().embedded(err)
+	return embedding, err
+}
+
+// embedQuery creates the embedding of a query text, applying the collection's
+// query template and instruction.
+func (c *Collection) embedQuery(ctx context.Context, text string) ([]float32, error) {
+	config := c.getConfig()
+	ctx = withEmbeddingInstruction(ctx, config.EmbeddingInstructions.Query)
+	embedding, err := c.embed(ctx, formatWithTemplate(config.EmbeddingTemplate.Query, text))
+	c.counters().embedded(err)
+	return embedding, err
+}
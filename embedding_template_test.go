@@ -0,0 +1,85 @@
+package chromem
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestFormatWithTemplate(t *testing.T) {
+	tt := []struct {
+		name     string
+		template string
+		text     string
+		want     string
+	}{
+		{name: "empty", template: "", text: "foo", want: "foo"},
+		{name: "prefix", template: "query: ", text: "foo", want: "query: foo"},
+		{name: "placeholder", template: "<q>{text}</q>", text: "foo", want: "<q>foo</q>"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := formatWithTemplate(tc.template, tc.text)
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCollection_EmbeddingTemplate(t *testing.T) {
+	ctx := context.Background()
+	vectors := map[string][]float32{
+		"passage: hello world": {-0.40824828, 0.40824828, 0.81649655},
+		"query: hello":         {0.40824828, -0.40824828, 0.81649655},
+	}
+	var texts []string
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		texts = append(texts, text)
+		return vectors[text], nil
+	}
+
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetEmbeddingTemplate(EmbeddingTemplateE5)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "hello world"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err := c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	wantTexts := []string{"passage: hello world", "query: hello"}
+	if !reflect.DeepEqual(wantTexts, texts) {
+		t.Fatalf("expected texts %v, got %v", wantTexts, texts)
+	}
+	// The content is stored without the template
+	if res[0].Content != "hello world" {
+		t.Fatal("expected content \"hello world\", got", res[0].Content)
+	}
+
+	// The template must be persisted
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", embeddingFunc)
+	if c == nil {
+		t.Fatal("expected collection, got nil")
+	}
+	if c.EmbeddingTemplate() != EmbeddingTemplateE5 {
+		t.Fatalf("expected template %v, got %v", EmbeddingTemplateE5, c.EmbeddingTemplate())
+	}
+}
@@ -0,0 +1,80 @@
+package chromem
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Executor runs the concurrent tasks of queries and of adding documents, see
+// [DB.SetExecutor].
+type Executor interface {
+	// Submit runs the task asynchronously. It can queue the task, for example
+	// until a worker of a pool is available, but it must not wait for other
+	// submitted tasks to finish, as the tasks of an operation can depend on
+	// the caller continuing after submitting them.
+	Submit(task func())
+}
+
+// goroutineExecutor is the default executor, which starts a goroutine for each
+// task.
+type goroutineExecutor struct{}
+
+func (goroutineExecutor) Submit(task func()) {
+	go task()
+}
+
+// SetExecutor sets the executor that runs the concurrent tasks of the DB's
+// collections, like scanning documents in queries and creating embeddings when
+// adding documents. This allows applications with managed worker pools, or with
+// constraints on the number of goroutines, to control their creation.
+// A nil executor restores the default, which starts a goroutine for each task.
+// The setting isn't persisted.
+func (db *DB) SetExecutor(executor Executor) {
+	db.executorLock.Lock()
+	defer db.executorLock.Unlock()
+
+	db.executor = executor
+}
+
+// executor returns the executor of the collection's DB, or the default one.
+func (c *Collection) executor() Executor {
+	if c.db == nil {
+		return goroutineExecutor{}
+	}
+	c.db.executorLock.RLock()
+	executor := c.db.executor
+	c.db.executorLock.RUnlock()
+	if executor == nil {
+		return goroutineExecutor{}
+	}
+	return executor
+}
+
+// PanicError is returned when a task of a concurrent operation panics, for
+// example in an embedding function or a custom tokenizer. Instead of crashing
+// the process, the panic is recovered and returned by the operation, like
+// [Collection.AddDocuments] or [Collection.Query].
+type PanicError struct {
+	// The value passed to panic.
+	Value any
+	// The stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in concurrent task: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic if it's an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic recovers a panic of the task and passes it as [*PanicError] to
+// setErr. It must be deferred by the task itself.
+func recoverPanic(setErr func(error)) {
+	if r := recover(); r != nil {
+		setErr(&PanicError{Value: r, Stack: debug.Stack()})
+	}
+}
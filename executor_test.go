@@ -0,0 +1,144 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// singleWorkerExecutor runs the tasks one after another in a single goroutine,
+// like a pool with one worker.
+type singleWorkerExecutor struct {
+	tasks     chan func()
+	submitted atomic.Int64
+}
+
+func newSingleWorkerExecutor() *singleWorkerExecutor {
+	e := &singleWorkerExecutor{tasks: make(chan func(), 1000)}
+	go func() {
+		for task := range e.tasks {
+			task()
+		}
+	}()
+	return e
+}
+
+func (e *singleWorkerExecutor) Submit(task func()) {
+	e.submitted.Add(1)
+	e.tasks <- task
+}
+
+func TestDB_SetExecutor(t *testing.T) {
+	ctx := context.Background()
+	executor := newSingleWorkerExecutor()
+	defer close(executor.tasks)
+
+	db := NewDB()
+	db.SetExecutor(executor)
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	var docs []Document
+	for i := 0; i < 1000; i++ {
+		docs = append(docs, Document{
+			ID:        strconv.Itoa(i),
+			Embedding: []float32{float32(i), 100},
+			Metadata:  map[string]string{"even": strconv.FormatBool(i%2 == 0)},
+			Content:   "hello world",
+		})
+	}
+	err = c.AddDocuments(ctx, docs, 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	added := executor.submitted.Load()
+	if added != int64(len(docs)) {
+		t.Fatalf("expected %d tasks for adding documents, got %d", len(docs), added)
+	}
+
+	// Unfiltered and filtered queries
+	for where, expectedID := range map[string]string{"": "999", "true": "998"} {
+		opts := QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 3}
+		if where != "" {
+			opts.Where = map[string]string{"even": where}
+		}
+		res, err := c.QueryWithOptions(ctx, opts)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 3 || res[0].ID != expectedID {
+			t.Fatalf("expected 3 results starting with document %s, got %v", expectedID, res)
+		}
+	}
+	if executor.submitted.Load() == added {
+		t.Fatal("expected tasks for queries")
+	}
+
+	// A nil executor restores the default
+	db.SetExecutor(nil)
+	if _, ok := c.executor().(goroutineExecutor); !ok {
+		t.Fatalf("expected the default executor, got %T", c.executor())
+	}
+}
+
+// panicTokenizer panics when counting tokens.
+type panicTokenizer struct {
+	runeTokenizer
+}
+
+func (panicTokenizer) CountTokens(string) int {
+	panic("tokenizer failed")
+}
+
+func TestPanicError(t *testing.T) {
+	ctx := context.Background()
+	errPanic := errors.New("embedding failed")
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		if text == "panic" {
+			panic(errPanic)
+		}
+		return []float32{0.6, 0.8}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Adding documents
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Content: "hello world"},
+		{ID: "2", Content: "panic"},
+	}, 2)
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatal("expected PanicError, got", err)
+	}
+	if !errors.Is(err, errPanic) {
+		t.Fatal("expected error to wrap the panic value, got", err)
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Fatal("expected stack trace")
+	}
+
+	// Querying
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "hello world"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	filter, err := CompileFilter(nil, map[string]string{"$tokens_gt": "1"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: []float32{0.6, 0.8},
+		NResults:       1,
+		Filter:         filter.WithTokenizer(panicTokenizer{}),
+	})
+	if !errors.As(err, &panicErr) || panicErr.Value != "tokenizer failed" {
+		t.Fatal("expected PanicError, got", err)
+	}
+}
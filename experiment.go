@@ -0,0 +1,289 @@
+package chromem
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// variantQueryCacheSize is the number of recent queries whose variant is
+// remembered, for counting the feedback per variant.
+const variantQueryCacheSize = 1000
+
+// RetrievalVariant is a named retrieval configuration of a collection, for A/B
+// experiments, see [Collection.SetRetrievalVariants].
+type RetrievalVariant struct {
+	// The name of the variant, which queries are tagged with, see
+	// [QueryOptions.Variant]. Must not be empty.
+	Name string
+
+	// The relative share of traffic of the variant for [Collection.AssignVariant].
+	// For example with weights 9 and 1, 90% of the keys are assigned to the
+	// first variant. 0 means the variant isn't assigned, but it can still be
+	// used explicitly.
+	Weight float64
+
+	// The retrieval settings of the variant. They're used instead of the
+	// collection's, so unset ones are disabled. See [Collection.SetBoostRules],
+	// [Collection.SetFeedbackBoost] and [QueryOptions.TopKAlgorithm].
+	BoostRules    []BoostRule
+	FeedbackBoost FeedbackBoost
+	// Used unless the query sets one.
+	TopKAlgorithm TopKAlgorithm
+}
+
+// VariantStats are the metrics of a retrieval variant, see
+// [Collection.VariantStats].
+type VariantStats struct {
+	// The number of successful queries with the variant.
+	Queries int64 `json:"queries"`
+	// The number of documents the queries scanned.
+	DocumentsScanned int64 `json:"documents_scanned"`
+	// The total duration of the queries, including the embedding of the query.
+	TotalLatency time.Duration `json:"total_latency"`
+
+	// The feedback on the results of the queries, see
+	// [Collection.RecordFeedback].
+	Clicks  int64 `json:"clicks"`
+	Accepts int64 `json:"accepts"`
+	Rejects int64 `json:"rejects"`
+}
+
+// AverageLatency returns the average duration of the queries, or 0 without
+// queries.
+func (s VariantStats) AverageLatency() time.Duration {
+	if s.Queries == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Queries)
+}
+
+// AcceptRate returns the ratio of accepts to queries, or 0 without queries.
+func (s VariantStats) AcceptRate() float64 {
+	if s.Queries == 0 {
+		return 0
+	}
+	return float64(s.Accepts) / float64(s.Queries)
+}
+
+// SetRetrievalVariants sets the named retrieval configurations of the
+// collection, for evaluating changes of the retrieval safely in production:
+// Queries are tagged with a variant via [QueryOptions.Variant], for example one
+// assigned by [Collection.AssignVariant], and use the variant's settings instead
+// of the collection's. Queries without a variant use the collection's settings.
+// The metrics per variant, including the feedback on the results (see
+// [Collection.RecordFeedback]), are returned by [Collection.VariantStats].
+//
+// The variants are persisted, but the metrics are only kept in memory. Nil or
+// empty variants remove all variants.
+func (c *Collection) SetRetrievalVariants(variants []RetrievalVariant) error {
+	variants, err := cloneRetrievalVariants(variants)
+	if err != nil {
+		return err
+	}
+
+	c.configLock.Lock()
+	c.config.RetrievalVariants = variants
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// RetrievalVariants returns a copy of the collection's retrieval variants.
+func (c *Collection) RetrievalVariants() []RetrievalVariant {
+	// The error can only occur for invalid variants, which can't be set.
+	variants, _ := cloneRetrievalVariants(c.getConfig().RetrievalVariants)
+	return variants
+}
+
+// AssignVariant returns the name of the retrieval variant for the key, for
+// example a user or session ID, according to the weights of the variants. The
+// same key is always assigned to the same variant, as long as the variants
+// don't change. It returns an empty string, for the collection's settings, if
+// there are no variants with a weight.
+func (c *Collection) AssignVariant(key string) string {
+	variants := c.getConfig().RetrievalVariants
+	var total float64
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+	if total == 0 {
+		return ""
+	}
+
+	// A uniformly distributed point in [0, total), from the hash of the key
+	sum := sha256.Sum256([]byte(key))
+	point := float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53) * total
+	for _, variant := range variants {
+		if point < variant.Weight {
+			return variant.Name
+		}
+		point -= variant.Weight
+	}
+	// Only reached because of rounding errors
+	for i := len(variants) - 1; i >= 0; i-- {
+		if variants[i].Weight > 0 {
+			return variants[i].Name
+		}
+	}
+	return ""
+}
+
+// VariantStats returns the metrics of the retrieval variants since the
+// collection was loaded or the metrics were reset, by variant name.
+func (c *Collection) VariantStats() map[string]VariantStats {
+	return c.variantStats.get()
+}
+
+// ResetVariantStats resets the metrics of the retrieval variants, for example
+// when a new experiment starts.
+func (c *Collection) ResetVariantStats() {
+	c.variantStats.reset()
+}
+
+// retrievalVariant returns the variant with the name, or nil if the name is
+// empty.
+func (c *Collection) retrievalVariant(name string) (*RetrievalVariant, error) {
+	if name == "" {
+		return nil, nil
+	}
+	for _, variant := range c.getConfig().RetrievalVariants {
+		if variant.Name == name {
+			return &variant, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown retrieval variant %q", name)
+}
+
+// cloneRetrievalVariants validates the variants and returns a deep copy of them,
+// or nil if there are none.
+func cloneRetrievalVariants(variants []RetrievalVariant) ([]RetrievalVariant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	res := make([]RetrievalVariant, 0, len(variants))
+	names := make(map[string]struct{}, len(variants))
+	for i, variant := range variants {
+		if variant.Name == "" {
+			return nil, fmt.Errorf("name of retrieval variant %d is empty", i)
+		}
+		if _, ok := names[variant.Name]; ok {
+			return nil, fmt.Errorf("duplicate retrieval variant %q", variant.Name)
+		}
+		names[variant.Name] = struct{}{}
+		if math.IsNaN(variant.Weight) || math.IsInf(variant.Weight, 0) || variant.Weight < 0 {
+			return nil, errors.New("weight of retrieval variant must be a finite number >= 0")
+		}
+		boostRules, err := cloneBoostRules(variant.BoostRules)
+		if err != nil {
+			return nil, fmt.Errorf("retrieval variant %q: %w", variant.Name, err)
+		}
+		if err := variant.FeedbackBoost.validate(); err != nil {
+			return nil, fmt.Errorf("retrieval variant %q: %w", variant.Name, err)
+		}
+		if _, err := newTopKCollector(variant.TopKAlgorithm, 1, 1); err != nil {
+			return nil, fmt.Errorf("retrieval variant %q: %w", variant.Name, err)
+		}
+		variant.BoostRules = boostRules
+		res = append(res, variant)
+	}
+	return res, nil
+}
+
+// variantStatsStore keeps the metrics of the retrieval variants. It's safe for
+// concurrent use, and the methods of a nil store do nothing.
+type variantStatsStore struct {
+	// Query ID -> variant name, for the feedback
+	queries *lruCache[string, string]
+
+	lock  sync.Mutex
+	stats map[string]*VariantStats
+}
+
+func newVariantStatsStore() *variantStatsStore {
+	return &variantStatsStore{
+		queries: newLRUCache[string, string](variantQueryCacheSize),
+		stats:   make(map[string]*VariantStats),
+	}
+}
+
+// queryServed counts the query of the variant.
+func (s *variantStatsStore) queryServed(variant, queryID string, documentsScanned int, latency time.Duration) {
+	if s == nil {
+		return
+	}
+	s.queries.add(queryID, variant)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stats := s.statsLocked(variant)
+	stats.Queries++
+	stats.DocumentsScanned += int64(documentsScanned)
+	stats.TotalLatency += latency
+}
+
+// feedback counts the feedback on a result of the query. It returns false if
+// the query isn't known.
+func (s *variantStatsStore) feedback(queryID string, signal FeedbackSignal) bool {
+	if s == nil {
+		return false
+	}
+	variant, ok := s.queries.get(queryID)
+	if !ok {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stats := s.statsLocked(variant)
+	switch signal {
+	case FeedbackSignalClick:
+		stats.Clicks++
+	case FeedbackSignalAccept:
+		stats.Accepts++
+	case FeedbackSignalReject:
+		stats.Rejects++
+	}
+	return true
+}
+
+func (s *variantStatsStore) statsLocked(variant string) *VariantStats {
+	stats, ok := s.stats[variant]
+	if !ok {
+		stats = &VariantStats{}
+		s.stats[variant] = stats
+	}
+	return stats
+}
+
+func (s *variantStatsStore) get() map[string]VariantStats {
+	res := make(map[string]VariantStats)
+	if s == nil {
+		return res
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for variant, stats := range s.stats {
+		res[variant] = *stats
+	}
+	return res
+}
+
+func (s *variantStatsStore) reset() {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stats = make(map[string]*VariantStats)
+}
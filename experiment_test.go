@@ -0,0 +1,133 @@
+package chromem
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_SetRetrievalVariants(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollectionWithOptions("test", CollectionOptions{
+		BoostRules: []BoostRule{{Where: map[string]string{"type": "faq"}, Boost: 0.5}},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0}},
+		{ID: "2", Embedding: []float32{0.6, 0.8}, Metadata: map[string]string{"type": "faq"}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Invalid variants
+	invalid := [][]RetrievalVariant{
+		{{Name: ""}},
+		{{Name: "a"}, {Name: "a"}},
+		{{Name: "a", Weight: -1}},
+		{{Name: "a", TopKAlgorithm: "foo"}},
+	}
+	for _, variants := range invalid {
+		if err := c.SetRetrievalVariants(variants); err == nil {
+			t.Fatal("expected error for", variants)
+		}
+	}
+
+	err = c.SetRetrievalVariants([]RetrievalVariant{
+		{Name: "control", Weight: 1, BoostRules: []BoostRule{{Where: map[string]string{"type": "faq"}, Boost: 0.5}}},
+		{Name: "no-boost", Weight: 1},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	query := func(variant string) ([]Result, string) {
+		t.Helper()
+		res, stats, err := c.QueryWithStats(ctx, QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 1, Variant: variant})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		return res, stats.QueryID
+	}
+
+	// The variant's settings are used instead of the collection's.
+	res, _ := query("")
+	if res[0].ID != "2" {
+		t.Fatal("expected boosted document 2, got", res)
+	}
+	res, controlID := query("control")
+	if res[0].ID != "2" {
+		t.Fatal("expected boosted document 2, got", res)
+	}
+	res, noBoostID := query("no-boost")
+	if res[0].ID != "1" {
+		t.Fatal("expected document 1, got", res)
+	}
+	query("no-boost")
+	if controlID == "" || noBoostID == "" {
+		t.Fatal("expected query IDs, got", controlID, noBoostID)
+	}
+	_, err = c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 1, Variant: "unknown"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Metrics
+	err = c.RecordFeedback(ctx, noBoostID, "1", FeedbackSignalAccept)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	stats := c.VariantStats()
+	if len(stats) != 2 {
+		t.Fatal("expected stats of 2 variants, got", stats)
+	}
+	if s := stats["control"]; s.Queries != 1 || s.DocumentsScanned != 2 || s.Accepts != 0 || s.TotalLatency <= 0 {
+		t.Fatalf("unexpected stats of control: %+v", s)
+	}
+	if s := stats["no-boost"]; s.Queries != 2 || s.Accepts != 1 || s.AcceptRate() != 0.5 || s.AverageLatency() != s.TotalLatency/2 {
+		t.Fatalf("unexpected stats of no-boost: %+v", s)
+	}
+	c.ResetVariantStats()
+	if stats := c.VariantStats(); len(stats) != 0 {
+		t.Fatal("expected no stats, got", stats)
+	}
+
+	// Assignment is deterministic and follows the weights.
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		key := "user" + strconv.Itoa(i)
+		variant := c.AssignVariant(key)
+		if variant != c.AssignVariant(key) {
+			t.Fatal("expected the same variant for key", key)
+		}
+		counts[variant]++
+	}
+	if counts["control"] < 400 || counts["no-boost"] < 400 {
+		t.Fatal("expected about half of the keys per variant, got", counts)
+	}
+
+	// Persisted
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	variants := db2.GetCollection("test", nil).RetrievalVariants()
+	if len(variants) != 2 || variants[0].Name != "control" || len(variants[0].BoostRules) != 1 {
+		t.Fatal("expected persisted variants, got", variants)
+	}
+
+	err = c.SetRetrievalVariants(nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if variant := c.AssignVariant("user1"); variant != "" {
+		t.Fatal("expected no variant, got", variant)
+	}
+}
@@ -0,0 +1,237 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	// defaultFeedbackQuerySimilarity is the default minimum similarity of a
+	// query to a query with feedback, for the feedback to be applied.
+	defaultFeedbackQuerySimilarity = 0.9
+	// feedbackQueryCacheSize is the number of recent queries whose vectors are
+	// remembered for feedback.
+	feedbackQueryCacheSize = 1000
+	// maxFeedbackEvents is the number of feedback events that are kept. Older
+	// ones are dropped.
+	maxFeedbackEvents = 10_000
+)
+
+// FeedbackBoost configures the adjustment of similarities based on the
+// feedback on the results of earlier queries, see [Collection.SetFeedbackBoost].
+type FeedbackBoost struct {
+	// The maximum value that's added to (or, for rejected documents, subtracted
+	// from) the similarity of a document. It's typically small, like 0.05. 0
+	// disables the feedback boost.
+	Weight float32
+
+	// The minimum similarity of a query to an earlier query, for the feedback on
+	// the earlier query's results to be applied. Must be between 0 and 1. 0 means
+	// the default of 0.9.
+	QuerySimilarity float32
+}
+
+func (b FeedbackBoost) validate() error {
+	if math.IsNaN(float64(b.Weight)) || math.IsInf(float64(b.Weight), 0) || b.Weight < 0 {
+		return errors.New("feedback boost weight must be a finite number >= 0")
+	}
+	if b.QuerySimilarity < 0 || b.QuerySimilarity > 1 {
+		return errors.New("feedback query similarity must be between 0 and 1")
+	}
+	return nil
+}
+
+// SetFeedbackBoost enables lightweight learning to rank: Documents that were
+// accepted (or clicked) for queries that are similar to the current one are
+// ranked higher, and rejected ones lower. The feedback is recorded with
+// [Collection.RecordFeedback].
+//
+// For each document, the signals of the feedback on similar queries are added
+// up (1 per accept, 0.5 per click, -1 per reject), and the sum s is turned into
+// the boost Weight·s/(1+|s|), so it approaches Weight with more feedback. The
+// boost is added to the similarity of the document in all queries of the
+// collection, like the boosts of [Collection.SetBoostRules].
+//
+// The configuration is persisted, but the feedback is only kept in memory, for
+// the latest 10,000 feedback events.
+func (c *Collection) SetFeedbackBoost(boost FeedbackBoost) error {
+	if err := boost.validate(); err != nil {
+		return err
+	}
+
+	c.configLock.Lock()
+	c.config.FeedbackBoost = boost
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// FeedbackBoost returns the collection's feedback boost configuration.
+func (c *Collection) FeedbackBoost() FeedbackBoost {
+	return c.getConfig().FeedbackBoost
+}
+
+// RecordFeedback records feedback on a result of a query. The query ID is the
+// one returned in [QueryStats.QueryID], or set via [ContextWithQueryID].
+// The feedback adjusts the similarities of later, similar queries if the
+// collection has a feedback boost (see [Collection.SetFeedbackBoost]), it's
+// counted in the metrics of the query's retrieval variant (see
+// [Collection.VariantStats]), and it's appended to the DB's query log if there
+// is one (see [DB.SetQueryLog]).
+//
+// For the feedback boost and variant metrics, only the latest 1,000 queries can
+// get feedback, as the query vectors and variants have to be remembered.
+func (c *Collection) RecordFeedback(ctx context.Context, queryID, docID string, signal FeedbackSignal) error {
+	if queryID == "" {
+		return errors.New("queryID is empty")
+	}
+	if docID == "" {
+		return errors.New("docID is empty")
+	}
+	value, err := signal.value()
+	if err != nil {
+		return err
+	}
+
+	// Queries are only remembered for a feedback boost or with a variant.
+	recorded := c.feedback.add(queryID, docID, value)
+	if recorded {
+		// The feedback boosts change the results of all sessions.
+		c.changed()
+	}
+	if c.variantStats.feedback(queryID, signal) {
+		recorded = true
+	}
+
+	if c.db != nil {
+		c.db.queryLogLock.RLock()
+		hasLog := c.db.queryLog != nil
+		c.db.queryLogLock.RUnlock()
+		if hasLog {
+			return c.db.LogQueryFeedback(ctx, queryID, docID, signal)
+		}
+	}
+	if !recorded {
+		return fmt.Errorf("unknown query ID %q", queryID)
+	}
+	return nil
+}
+
+// value returns the value of the signal for the feedback boost.
+func (s FeedbackSignal) value() (float32, error) {
+	switch s {
+	case FeedbackSignalAccept:
+		return 1, nil
+	case FeedbackSignalClick:
+		return 0.5, nil
+	case FeedbackSignalReject:
+		return -1, nil
+	default:
+		return 0, fmt.Errorf("unsupported feedback signal: %q", s)
+	}
+}
+
+// feedbackStore keeps the vectors of recent queries and the feedback on their
+// results, for the feedback boost. It's safe for concurrent use, and the
+// methods of a nil store do nothing.
+type feedbackStore struct {
+	queries *lruCache[string, []float32]
+
+	lock   sync.RWMutex
+	events []feedbackEvent
+}
+
+type feedbackEvent struct {
+	queryVector []float32
+	docID       string
+	value       float32
+}
+
+func newFeedbackStore() *feedbackStore {
+	return &feedbackStore{
+		queries: newLRUCache[string, []float32](feedbackQueryCacheSize),
+	}
+}
+
+// rememberQuery remembers the query's vector, so that feedback can be recorded
+// for the query.
+func (s *feedbackStore) rememberQuery(queryID string, queryVector []float32) {
+	if s == nil {
+		return
+	}
+	if !isNormalized(queryVector) {
+		queryVector = normalizeVector(queryVector)
+	}
+	s.queries.add(queryID, queryVector)
+}
+
+// add records feedback on a result of the query. It returns false if the query
+// isn't known.
+func (s *feedbackStore) add(queryID, docID string, value float32) bool {
+	if s == nil {
+		return false
+	}
+	queryVector, ok := s.queries.get(queryID)
+	if !ok {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.events) == maxFeedbackEvents {
+		copy(s.events, s.events[1:])
+		s.events = s.events[:len(s.events)-1]
+	}
+	s.events = append(s.events, feedbackEvent{
+		queryVector: queryVector,
+		docID:       docID,
+		value:       value,
+	})
+	return true
+}
+
+// boosts returns the feedback boosts of the documents for the query vector, or
+// nil if there are none.
+func (s *feedbackStore) boosts(queryVector []float32, boost FeedbackBoost) map[string]float32 {
+	if s == nil || boost.Weight == 0 {
+		return nil
+	}
+	minSimilarity := boost.QuerySimilarity
+	if minSimilarity == 0 {
+		minSimilarity = defaultFeedbackQuerySimilarity
+	}
+	if !isNormalized(queryVector) {
+		queryVector = normalizeVector(queryVector)
+	}
+
+	s.lock.RLock()
+	var sums map[string]float32
+	for _, event := range s.events {
+		// Feedback on queries of another embedding model doesn't apply.
+		if len(event.queryVector) != len(queryVector) {
+			continue
+		}
+		sim, err := dotProduct(queryVector, event.queryVector)
+		if err != nil || sim < minSimilarity {
+			continue
+		}
+		if sums == nil {
+			sums = make(map[string]float32)
+		}
+		sums[event.docID] += event.value
+	}
+	s.lock.RUnlock()
+
+	for docID, sum := range sums {
+		abs := sum
+		if abs < 0 {
+			abs = -abs
+		}
+		sums[docID] = boost.Weight * sum / (1 + abs)
+	}
+	return sums
+}
@@ -0,0 +1,101 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCollection_RecordFeedback(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = db.CreateCollectionWithOptions("invalid", CollectionOptions{FeedbackBoost: FeedbackBoost{Weight: -1}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	c, err := db.CreateCollectionWithOptions("test", CollectionOptions{FeedbackBoost: FeedbackBoost{Weight: 0.1}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0.1}},
+		{ID: "2", Embedding: []float32{1, 0.12}},
+		{ID: "3", Embedding: []float32{0, 1}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	query := func(queryVector []float32) ([]Result, string) {
+		t.Helper()
+		res, stats, err := c.QueryWithStats(ctx, QueryOptions{QueryEmbedding: queryVector, NResults: 2})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		return res, stats.QueryID
+	}
+
+	res, queryID := query([]float32{1, 0.1})
+	if res[0].ID != "1" || queryID == "" {
+		t.Fatal("expected document 1 and a query ID, got", res, queryID)
+	}
+
+	// Invalid feedback
+	if err := c.RecordFeedback(ctx, queryID, "2", "like"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if err := c.RecordFeedback(ctx, "unknown", "2", FeedbackSignalAccept); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Document 2 is accepted, so it's ranked first for similar queries, but not
+	// for other queries.
+	err = c.RecordFeedback(ctx, queryID, "2", FeedbackSignalAccept)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, _ = query([]float32{1, 0.09})
+	if res[0].ID != "2" {
+		t.Fatal("expected document 2 first, got", res)
+	}
+	if res[0].Similarity < 1.04 {
+		t.Fatal("expected boosted similarity, got", res[0].Similarity)
+	}
+	res, _ = query([]float32{0.5, 1})
+	if res[0].ID != "3" || res[0].Similarity > 1 {
+		t.Fatal("expected unboosted document 3 first, got", res)
+	}
+
+	// The rejects outweigh the accept.
+	_, queryID = query([]float32{1, 0.1})
+	for i := 0; i < 2; i++ {
+		err = c.RecordFeedback(ctx, queryID, "2", FeedbackSignalReject)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	res, _ = query([]float32{1, 0.1})
+	if res[0].ID != "1" {
+		t.Fatal("expected document 1 first, got", res)
+	}
+
+	// The configuration is persisted, and disabling it disables the boost.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if boost := db2.GetCollection("test", nil).FeedbackBoost(); boost.Weight != 0.1 {
+		t.Fatal("expected persisted weight 0.1, got", boost.Weight)
+	}
+	err = c.SetFeedbackBoost(FeedbackBoost{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, queryID = query([]float32{1, 0.1})
+	if res[0].ID != "1" || res[0].Similarity > 1 || queryID != "" {
+		t.Fatal("expected unboosted document 1 first and no query ID, got", res, queryID)
+	}
+}
@@ -0,0 +1,413 @@
+package chromem
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Filter is a validated and compiled filter on the metadata and content of
+// documents, see [CompileFilter]. It's immutable, so it can be reused for many
+// queries, also concurrently.
+type Filter struct {
+	// Nil if unset
+	ids      map[string]struct{}
+	idPrefix string
+	// Sorted by key
+	where       []metadataCondition
+	contains    []string
+	notContains []string
+	regex       []*regexp.Regexp
+	notRegex    []*regexp.Regexp
+	// The content length in characters and tokens
+	length    lengthRange
+	tokens    lengthRange
+	tokenizer Tokenizer
+	// Documents that match any of these filters don't match
+	not []*Filter
+}
+
+// lengthRange is an exclusive range of lengths, where each bound is optional.
+type lengthRange struct {
+	gt, lt       int
+	hasGT, hasLT bool
+}
+
+func (r lengthRange) isSet() bool {
+	return r.hasGT || r.hasLT
+}
+
+func (r lengthRange) contains(n int) bool {
+	return (!r.hasGT || n > r.gt) && (!r.hasLT || n < r.lt)
+}
+
+type metadataCondition struct {
+	key   string
+	value string
+}
+
+// CompileFilter validates the metadata and content filters and compiles them
+// into a [Filter], which can be passed to queries via [QueryOptions.Filter].
+// The result is the same as with the where and whereDocument arguments of
+// queries, but the filters aren't validated and compiled again for each query,
+// which avoids work and allocations for frequent queries with the same filters.
+//
+//   - where: Conditional filtering on metadata. A document's metadata must have
+//     all key-value pairs. Additionally, documents can be filtered by their ID
+//     with the "$id_in" operator, whose value is a comma-separated list of IDs,
+//     and with the "$id_prefix" operator, for example to restrict a query to
+//     all chunks of a file whose IDs share a prefix. Documents that match the
+//     where filter of the "$not" operator are excluded. Its value is a JSON
+//     object with conditions like where's, for example {"archived": "true"}
+//     to exclude all archived documents, or a JSON array of such objects to
+//     exclude the documents that match any of them. See also [Filter.Not].
+//     Optional.
+//   - whereDocument: Conditional filtering on documents. The supported operators
+//     are "$contains" and "$not_contains" with a substring, "$regex" and
+//     "$not_regex" with a regular expression (see [regexp/syntax]),
+//     "$length_gt" and "$length_lt" with a number of characters, and
+//     "$tokens_gt" and "$tokens_lt" with a number of tokens, which are counted
+//     with [NewTokenizerHeuristic] (see [Filter.WithTokenizer]). The length
+//     operators allow excluding tiny boilerplate chunks or overly long
+//     documents. A document must satisfy all operators. Optional.
+func CompileFilter(where, whereDocument map[string]string) (*Filter, error) {
+	f := &Filter{}
+	for k, v := range where {
+		switch k {
+		case "$id_in":
+			ids := strings.Split(v, ",")
+			f.ids = make(map[string]struct{}, len(ids))
+			for _, id := range ids {
+				f.ids[id] = struct{}{}
+			}
+		case "$id_prefix":
+			f.idPrefix = v
+		case "$not":
+			nots, err := decodeNotFilter(v)
+			if err != nil {
+				return nil, &ValidationError{Field: "where", Key: k, Err: fmt.Errorf("invalid value for %q: %w", k, err)}
+			}
+			for _, not := range nots {
+				notFilter, err := CompileFilter(not, nil)
+				if err != nil {
+					return nil, err
+				}
+				f.not = append(f.not, notFilter)
+			}
+		default:
+			f.where = append(f.where, metadataCondition{key: k, value: v})
+		}
+	}
+	slices.SortFunc(f.where, func(a, b metadataCondition) int {
+		return cmp.Compare(a.key, b.key)
+	})
+
+	for k, v := range whereDocument {
+		switch k {
+		case "$contains":
+			f.contains = append(f.contains, v)
+		case "$not_contains":
+			f.notContains = append(f.notContains, v)
+		case "$regex", "$not_regex":
+			re, err := regexp.Compile(v)
+			if err != nil {
+				return nil, &ValidationError{Field: "whereDocument", Key: k, Err: fmt.Errorf("invalid regular expression for %q: %w", k, err)}
+			}
+			if k == "$regex" {
+				f.regex = append(f.regex, re)
+			} else {
+				f.notRegex = append(f.notRegex, re)
+			}
+		case "$length_gt", "$length_lt", "$tokens_gt", "$tokens_lt":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, &ValidationError{Field: "whereDocument", Key: k, Err: fmt.Errorf("invalid value for %q: must be an integer >= 0", k)}
+			}
+			r := &f.length
+			if strings.HasPrefix(k, "$tokens") {
+				r = &f.tokens
+			}
+			if strings.HasSuffix(k, "_gt") {
+				r.gt, r.hasGT = n, true
+			} else {
+				r.lt, r.hasLT = n, true
+			}
+		default:
+			return nil, &ValidationError{Field: "whereDocument", Key: k, Err: errors.New("unsupported operator")}
+		}
+	}
+
+	return f, nil
+}
+
+// decodeNotFilter decodes the value of the "$not" operator, a JSON object or an
+// array of JSON objects, into where filters.
+func decodeNotFilter(v string) ([]map[string]string, error) {
+	var raws []json.RawMessage
+	if strings.HasPrefix(strings.TrimSpace(v), "[") {
+		if err := json.Unmarshal([]byte(v), &raws); err != nil {
+			return nil, err
+		}
+	} else {
+		raws = []json.RawMessage{json.RawMessage(v)}
+	}
+	if len(raws) == 0 {
+		return nil, errors.New("array is empty")
+	}
+	res := make([]map[string]string, 0, len(raws))
+	for _, raw := range raws {
+		where, err := decodeWhere(raw)
+		if err != nil {
+			return nil, err
+		}
+		if len(where) == 0 {
+			return nil, errors.New("object is empty")
+		}
+		res = append(res, where)
+	}
+	return res, nil
+}
+
+// decodeWhere decodes a where filter from a JSON object. The values must be
+// strings, except for the "$not" operator, whose object or array value is kept
+// as JSON string for [CompileFilter].
+func decodeWhere(data []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	where := make(map[string]string, len(raw))
+	for k, v := range raw {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			where[k] = s
+			continue
+		}
+		if k != "$not" {
+			return nil, fmt.Errorf("value of %q must be a string", k)
+		}
+		where[k] = string(v)
+	}
+	return where, nil
+}
+
+// whereJSON is a where filter that's decoded from JSON, where the value of the
+// "$not" operator can be a JSON object or array instead of a string, see
+// [CompileFilter].
+type whereJSON map[string]string
+
+func (w *whereJSON) UnmarshalJSON(data []byte) error {
+	where, err := decodeWhere(data)
+	if err != nil {
+		return err
+	}
+	*w = where
+	return nil
+}
+
+// metadataValue returns the value the filter requires for the metadata key, or
+// false if it doesn't restrict the key.
+func (f *Filter) metadataValue(key string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	for _, cond := range f.where {
+		if cond.key == key {
+			return cond.value, true
+		}
+	}
+	return "", false
+}
+
+// withMetadataCondition returns a copy of the filter that additionally requires
+// the value for the metadata key.
+func (f *Filter) withMetadataCondition(key, value string) *Filter {
+	res := &Filter{}
+	if f != nil {
+		*res = *f
+	}
+	cond := metadataCondition{key: key, value: value}
+	i, _ := slices.BinarySearchFunc(res.where, cond, func(a, b metadataCondition) int {
+		return cmp.Compare(a.key, b.key)
+	})
+	res.where = slices.Insert(slices.Clip(res.where), i, cond)
+	return res
+}
+
+// WithTokenizer returns a copy of the filter that counts tokens for the
+// "$tokens_gt" and "$tokens_lt" operators with the tokenizer, for example a
+// [BPETokenizer] of the embedding model, instead of the heuristic one. Negated
+// filters (see [Filter.Not]) without their own tokenizer use it as well. A nil
+// filter is treated like one without conditions.
+func (f *Filter) WithTokenizer(tokenizer Tokenizer) *Filter {
+	res := &Filter{}
+	if f != nil {
+		*res = *f
+	}
+	res.tokenizer = tokenizer
+	return res
+}
+
+// Not returns a copy of the filter that additionally excludes the documents
+// that match the other filter. For example, to query everything except the
+// archived documents:
+//
+//	archived, _ := chromem.CompileFilter(map[string]string{"archived": "true"}, nil)
+//	filter, _ := chromem.CompileFilter(nil, nil)
+//	filter = filter.Not(archived)
+//
+// A document is only excluded if it matches all conditions of the other
+// filter, which can itself contain negations. In where filters, for example in
+// JSON APIs, use the "$not" operator instead, see [CompileFilter]. A nil
+// filter is treated like one without conditions.
+func (f *Filter) Not(other *Filter) *Filter {
+	res := &Filter{}
+	if f != nil {
+		*res = *f
+	}
+	res.not = append(slices.Clip(res.not), other)
+	return res
+}
+
+// hasContentConditions returns true if the filter has conditions on the
+// content, for which spilled contents must be loaded.
+func (f *Filter) hasContentConditions() bool {
+	if f == nil {
+		return false
+	}
+	if len(f.contains)+len(f.notContains)+len(f.regex)+len(f.notRegex) != 0 || f.length.isSet() || f.tokens.isSet() {
+		return true
+	}
+	for _, not := range f.not {
+		if not.hasContentConditions() {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutContentConditions returns a copy of the filter without the conditions
+// on the content, including the negations with conditions on the content.
+func (f *Filter) withoutContentConditions() *Filter {
+	res := &Filter{ids: f.ids, idPrefix: f.idPrefix, where: f.where}
+	for _, not := range f.not {
+		if !not.hasContentConditions() {
+			res.not = append(res.not, not)
+		}
+	}
+	return res
+}
+
+// matches returns true if the document matches the filter. A nil filter
+// matches all documents.
+func (f *Filter) matches(doc *Document) bool {
+	return f.matchesWithTokenizer(doc, nil)
+}
+
+// matchesWithTokenizer is like matches, but counts tokens with the tokenizer
+// of the enclosing filter, if the filter doesn't have its own.
+func (f *Filter) matchesWithTokenizer(doc *Document, tokenizer Tokenizer) bool {
+	if f == nil {
+		return true
+	}
+	if f.tokenizer != nil {
+		tokenizer = f.tokenizer
+	}
+	if !f.matchesID(doc.ID) || !f.matchesMetadata(doc.Metadata) || !f.matchesContent(doc.Content, tokenizer) {
+		return false
+	}
+	for _, not := range f.not {
+		if not.matchesWithTokenizer(doc, tokenizer) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesID returns true if the document ID matches the filter's ID
+// conditions.
+func (f *Filter) matchesID(id string) bool {
+	if f == nil {
+		return true
+	}
+	if f.ids != nil {
+		if _, ok := f.ids[id]; !ok {
+			return false
+		}
+	}
+	return strings.HasPrefix(id, f.idPrefix)
+}
+
+// matchesMetadata returns true if the metadata matches the filter's metadata
+// conditions.
+func (f *Filter) matchesMetadata(metadata map[string]string) bool {
+	if f == nil {
+		return true
+	}
+	for _, cond := range f.where {
+		// TODO: Do we want to check for existence of the key? I.e. should
+		// a where clause with empty string as value match a document's
+		// metadata that doesn't have the key at all?
+		if metadata[cond.key] != cond.value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesContent returns true if the content matches the filter's content
+// conditions, counting tokens with the tokenizer, or the heuristic one if it's
+// nil. The cheaper conditions are checked first.
+func (f *Filter) matchesContent(content string, tokenizer Tokenizer) bool {
+	if f == nil {
+		return true
+	}
+	if f.length.isSet() && !f.length.contains(utf8.RuneCountInString(content)) {
+		return false
+	}
+	for _, s := range f.contains {
+		if !strings.Contains(content, s) {
+			return false
+		}
+	}
+	for _, s := range f.notContains {
+		if strings.Contains(content, s) {
+			return false
+		}
+	}
+	for _, re := range f.regex {
+		if !re.MatchString(content) {
+			return false
+		}
+	}
+	for _, re := range f.notRegex {
+		if re.MatchString(content) {
+			return false
+		}
+	}
+	if f.tokens.isSet() {
+		if tokenizer == nil {
+			tokenizer = heuristicTokenizer{}
+		}
+		if !f.tokens.contains(tokenizer.CountTokens(content)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesWhere returns true if the metadata has all key-value pairs of where.
+func matchesWhere(metadata, where map[string]string) bool {
+	for k, v := range where {
+		if metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
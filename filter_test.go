@@ -0,0 +1,334 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCompileFilter(t *testing.T) {
+	doc := &Document{
+		ID:       "1",
+		Metadata: map[string]string{"language": "en", "category": "greeting"},
+		Content:  "hello world",
+	}
+
+	tt := []struct {
+		name          string
+		where         map[string]string
+		whereDocument map[string]string
+		expectErr     bool
+		expectMatch   bool
+	}{
+		{
+			name:        "empty",
+			expectMatch: true,
+		},
+		{
+			name:        "metadata",
+			where:       map[string]string{"language": "en", "category": "greeting"},
+			expectMatch: true,
+		},
+		{
+			name:  "metadata no match",
+			where: map[string]string{"language": "en", "category": "farewell"},
+		},
+		{
+			name:        "id in",
+			where:       map[string]string{"$id_in": "0,1,2"},
+			expectMatch: true,
+		},
+		{
+			name:  "id in no match",
+			where: map[string]string{"$id_in": "0,2"},
+		},
+		{
+			name:        "id prefix",
+			where:       map[string]string{"$id_prefix": "1", "language": "en"},
+			expectMatch: true,
+		},
+		{
+			name:  "id prefix no match",
+			where: map[string]string{"$id_prefix": "10"},
+		},
+		{
+			name:          "contains and not contains",
+			whereDocument: map[string]string{"$contains": "hello", "$not_contains": "bye"},
+			expectMatch:   true,
+		},
+		{
+			name:          "regex",
+			whereDocument: map[string]string{"$regex": "^hel+o w"},
+			expectMatch:   true,
+		},
+		{
+			name:          "regex no match",
+			whereDocument: map[string]string{"$regex": "^world"},
+		},
+		{
+			name:          "not regex",
+			whereDocument: map[string]string{"$not_regex": "[0-9]"},
+			expectMatch:   true,
+		},
+		{
+			name:          "not regex no match",
+			whereDocument: map[string]string{"$not_regex": "w.rld$"},
+		},
+		{
+			name:          "invalid regex",
+			whereDocument: map[string]string{"$regex": "("},
+			expectErr:     true,
+		},
+		{
+			name:          "length",
+			whereDocument: map[string]string{"$length_gt": "10", "$length_lt": "12"},
+			expectMatch:   true,
+		},
+		{
+			name:          "length no match",
+			whereDocument: map[string]string{"$length_gt": "11"},
+		},
+		{
+			name:          "tokens",
+			whereDocument: map[string]string{"$tokens_gt": "2", "$tokens_lt": "4"},
+			expectMatch:   true,
+		},
+		{
+			name:          "tokens no match",
+			whereDocument: map[string]string{"$tokens_lt": "3"},
+		},
+		{
+			name:          "invalid length",
+			whereDocument: map[string]string{"$length_gt": "-1"},
+			expectErr:     true,
+		},
+		{
+			name:          "unsupported operator",
+			whereDocument: map[string]string{"$foo": "bar"},
+			expectErr:     true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := CompileFilter(tc.where, tc.whereDocument)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if f.matches(doc) != tc.expectMatch {
+				t.Fatalf("expected match to be %v", tc.expectMatch)
+			}
+		})
+	}
+}
+
+func TestCollection_QueryWithOptions_Filter(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Content: "hello world", Metadata: map[string]string{"language": "en"}},
+		{ID: "2", Embedding: []float32{0, 1}, Content: "hello there", Metadata: map[string]string{"language": "en"}},
+		{ID: "3", Embedding: []float32{1, 1}, Content: "hallo welt", Metadata: map[string]string{"language": "de"}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	filter, err := CompileFilter(map[string]string{"language": "en"}, map[string]string{"$contains": "hello", "$regex": "wor?ld"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// The filter can be reused.
+	for i := 0; i < 2; i++ {
+		res, err := c.QueryWithOptions(ctx, QueryOptions{
+			QueryEmbedding: []float32{0, 1},
+			NResults:       3,
+			Filter:         filter,
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 1 || res[0].ID != "1" {
+			t.Fatal("expected document 1, got", res)
+		}
+		if len(res[0].Highlights) != 1 || res[0].Highlights[0] != (Highlight{Start: 0, End: 5}) {
+			t.Fatal("expected highlight of the $contains value, got", res[0].Highlights)
+		}
+	}
+
+	// Filter can't be combined with Where and WhereDocument
+	_, err = c.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: []float32{0, 1},
+		NResults:       3,
+		Where:          map[string]string{"language": "en"},
+		Filter:         filter,
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestFilter_WithTokenizer(t *testing.T) {
+	f, err := CompileFilter(nil, map[string]string{"$tokens_gt": "5"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc := &Document{ID: "1", Content: "hello world"}
+	if f.matches(doc) {
+		t.Fatal("expected no match with the heuristic tokenizer")
+	}
+
+	f2 := f.WithTokenizer(runeTokenizer{})
+	if !f2.matches(doc) {
+		t.Fatal("expected match with the custom tokenizer")
+	}
+	// The original filter must be unchanged
+	if f.matches(doc) {
+		t.Fatal("expected no match with the original filter")
+	}
+
+	// Negated filters use the tokenizer of the enclosing filter, unless they
+	// have their own.
+	all, err := CompileFilter(nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !all.Not(f).matches(doc) {
+		t.Fatal("expected match with the heuristic tokenizer in the negation")
+	}
+	if all.Not(f).WithTokenizer(runeTokenizer{}).matches(doc) {
+		t.Fatal("expected no match with the custom tokenizer in the negation")
+	}
+	if !all.Not(f.WithTokenizer(heuristicTokenizer{})).WithTokenizer(runeTokenizer{}).matches(doc) {
+		t.Fatal("expected match with the negation's own tokenizer")
+	}
+
+	// A nil filter is treated like one without conditions.
+	var nilFilter *Filter
+	if !nilFilter.WithTokenizer(runeTokenizer{}).matches(doc) {
+		t.Fatal("expected match with a nil filter")
+	}
+}
+
+// runeTokenizer counts every character as token.
+type runeTokenizer struct{}
+
+func (runeTokenizer) CountTokens(text string) int {
+	return len([]rune(text))
+}
+
+func (runeTokenizer) Truncate(text string, maxTokens int) string {
+	return string([]rune(text)[:min(maxTokens, len([]rune(text)))])
+}
+
+func TestFilter_Not(t *testing.T) {
+	archived := &Document{ID: "1", Metadata: map[string]string{"archived": "true"}, Content: "hello world"}
+	active := &Document{ID: "2", Metadata: map[string]string{"archived": "false"}, Content: "hello world"}
+
+	isArchived, err := CompileFilter(map[string]string{"archived": "true"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	all, err := CompileFilter(nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	f := all.Not(isArchived)
+	if f.matches(archived) || !f.matches(active) {
+		t.Fatal("expected only the active document to match")
+	}
+	// The original filter must be unchanged
+	if !all.matches(archived) {
+		t.Fatal("expected the original filter to match all documents")
+	}
+
+	// Nested negations and content conditions
+	hello, err := CompileFilter(nil, map[string]string{"$contains": "hello"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	f = all.Not(hello.Not(isArchived))
+	if !f.hasContentConditions() {
+		t.Fatal("expected content conditions")
+	}
+	if !f.matches(archived) || f.matches(active) {
+		t.Fatal("expected only the archived document to match")
+	}
+	if len(f.withoutContentConditions().not) != 0 {
+		t.Fatal("expected no negations without content conditions")
+	}
+
+	// A nil filter is treated like one without conditions.
+	var nilFilter *Filter
+	f = nilFilter.Not(isArchived)
+	if f.matches(archived) || !f.matches(active) {
+		t.Fatal("expected only the active document to match")
+	}
+}
+
+func TestCompileFilter_Not(t *testing.T) {
+	archived := &Document{ID: "1", Metadata: map[string]string{"archived": "true", "lang": "en"}}
+	active := &Document{ID: "2", Metadata: map[string]string{"archived": "false", "lang": "en"}}
+	german := &Document{ID: "3", Metadata: map[string]string{"archived": "false", "lang": "de"}}
+
+	tt := []struct {
+		name    string
+		where   map[string]string
+		matches []*Document
+	}{
+		{"object", map[string]string{"$not": `{"archived": "true"}`}, []*Document{active, german}},
+		{"with other conditions", map[string]string{"lang": "en", "$not": `{"archived": "true"}`}, []*Document{active}},
+		{"array", map[string]string{"$not": `[{"archived": "true"}, {"lang": "de"}]`}, []*Document{active}},
+		{"all conditions", map[string]string{"$not": `{"archived": "false", "lang": "de"}`}, []*Document{archived, active}},
+		{"nested", map[string]string{"$not": `{"lang": "en", "$not": {"archived": "true"}}`}, []*Document{archived, german}},
+		{"id operators", map[string]string{"$not": `{"$id_in": "1,3"}`}, []*Document{active}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := CompileFilter(tc.where, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			for _, doc := range []*Document{archived, active, german} {
+				if want := slices.Contains(tc.matches, doc); f.matches(doc) != want {
+					t.Fatalf("expected document %s to match: %v", doc.ID, want)
+				}
+			}
+		})
+	}
+
+	for _, v := range []string{`archived`, `{"archived": true}`, `{}`, `[]`, `[{"archived": "true"}, "x"]`, `{"$not": {"a": 1}}`} {
+		_, err := CompileFilter(map[string]string{"$not": v}, nil)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Key != "$not" {
+			t.Fatalf("expected validation error for %q, got %v", v, err)
+		}
+	}
+}
+
+func TestWhereJSON(t *testing.T) {
+	var where whereJSON
+	err := json.Unmarshal([]byte(`{"lang": "en", "$not": {"archived": "true"}}`), &where)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if where["lang"] != "en" || where["$not"] != `{"archived": "true"}` {
+		t.Fatal("unexpected where filter:", where)
+	}
+	err = json.Unmarshal([]byte(`{"lang": {"a": "b"}}`), &where)
+	if err == nil {
+		t.Fatal("expected error for non-string value, got nil")
+	}
+}
@@ -0,0 +1,93 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+)
+
+// rrfConstant is the constant k of reciprocal rank fusion, which dampens the
+// influence of the top ranks. 60 is the value of the original paper.
+const rrfConstant = 60
+
+// HyDEOptions configure Hypothetical Document Embeddings (HyDE) for a query,
+// see [QueryOptions.HyDE].
+type HyDEOptions struct {
+	// Generate returns a hypothetical answer to the query text, for example
+	// generated by an LLM. The answer doesn't need to be correct, as it's only
+	// used to find documents that look like an answer. Nil disables HyDE.
+	Generate func(ctx context.Context, query string) (string, error)
+
+	// The weight of the hypothetical answer's ranking in the fusion, relative to
+	// the query's ranking with weight 1. 0 means 1.
+	Weight float32
+}
+
+// hyde runs the query with the embedding of the hypothetical answer to the
+// query text, and fuses its ranking with the query's results.
+func (c *Collection) hyde(ctx context.Context, options QueryOptions, results []Result, query func(vector []float32) ([]Result, QueryStats, error)) ([]Result, QueryStats, error) {
+	answer, err := options.HyDE.Generate(ctx, options.QueryText)
+	if err != nil {
+		return nil, QueryStats{}, fmt.Errorf("couldn't generate hypothetical answer: %w", err)
+	}
+	// The answer is embedded like a document, as it's compared to documents.
+	vector, err := c.embedDocument(ctx, answer)
+	if err != nil {
+		return nil, QueryStats{}, fmt.Errorf("couldn't create embedding of hypothetical answer: %w", err)
+	}
+	if !isNormalized(vector) {
+		vector = normalizeVector(vector)
+	}
+	hypothetical, stats, err := query(vector)
+	if err != nil {
+		return nil, QueryStats{}, err
+	}
+	weight := options.HyDE.Weight
+	if weight == 0 {
+		weight = 1
+	}
+	return fuseRankings(options.NResults, []float32{1, weight}, results, hypothetical), stats, nil
+}
+
+// fuseRankings combines the rankings with weighted reciprocal rank fusion and
+// returns the top nResults. Documents that are in multiple rankings keep the
+// result with the highest similarity.
+func fuseRankings(nResults int, weights []float32, rankings ...[]Result) []Result {
+	type fused struct {
+		res   Result
+		score float32
+	}
+	byID := make(map[string]*fused)
+	for i, ranking := range rankings {
+		for rank, res := range ranking {
+			score := weights[i] / float32(rrfConstant+rank+1)
+			if f, ok := byID[res.ID]; ok {
+				f.score += score
+				if res.Similarity > f.res.Similarity {
+					f.res = res
+				}
+				continue
+			}
+			byID[res.ID] = &fused{res: res, score: score}
+		}
+	}
+
+	all := make([]*fused, 0, len(byID))
+	for _, f := range byID {
+		all = append(all, f)
+	}
+	slices.SortFunc(all, func(a, b *fused) int {
+		if a.score != b.score {
+			return cmp.Compare(b.score, a.score)
+		}
+		return cmp.Compare(a.res.ID, b.res.ID)
+	})
+
+	res := make([]Result, 0, min(nResults, len(all)))
+	for i, f := range all[:min(nResults, len(all))] {
+		f.res.Rank = i + 1
+		res = append(res, f.res)
+	}
+	return res
+}
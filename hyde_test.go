@@ -0,0 +1,88 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestQueryOptions_HyDE(t *testing.T) {
+	ctx := context.Background()
+	vectors := map[string][]float32{
+		"question":       {1, 0, 0},
+		"answer":         {0, 1, 0},
+		"about question": {0.9, 0.1, 0.1},
+		"about answer":   {0.1, 0.9, 0.1},
+		"both":           {0.6, 0.6, 0},
+		"other":          {0, 0, 1},
+	}
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		return vectors[text], nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, id := range []string{"about question", "about answer", "both", "other"} {
+		err = c.AddDocument(ctx, Document{ID: id, Content: id})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	var generated string
+	generate := func(_ context.Context, query string) (string, error) {
+		generated = query
+		return "answer", nil
+	}
+	res, stats, err := c.QueryWithStats(ctx, QueryOptions{QueryText: "question", NResults: 3, HyDE: HyDEOptions{Generate: generate}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if generated != "question" {
+		t.Fatal("expected the answer to be generated for the query text, got", generated)
+	}
+	if stats.DocumentsScanned != 8 {
+		t.Fatal("expected 8 documents to be scanned by both queries, got", stats.DocumentsScanned)
+	}
+	// The documents that are first in one of the rankings win the fusion, with
+	// equal scores sorted by ID.
+	expected := []string{"about answer", "about question", "both"}
+	if len(res) != len(expected) {
+		t.Fatal("expected", len(expected), "results, got", len(res))
+	}
+	for i, id := range expected {
+		if res[i].ID != id || res[i].Rank != i+1 {
+			t.Fatalf("expected %q at rank %d, got %q at rank %d", id, i+1, res[i].ID, res[i].Rank)
+		}
+	}
+	if res[1].Similarity < 0.9 {
+		t.Fatal("expected the similarity to the query, got", res[2].Similarity)
+	}
+
+	// With a low weight, the query's ranking dominates.
+	res, err = c.QueryWithOptions(ctx, QueryOptions{QueryText: "question", NResults: 1, HyDE: HyDEOptions{Generate: generate, Weight: 0.1}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "about question" {
+		t.Fatal("expected the most similar document to the query, got", res[0].ID)
+	}
+
+	failing := func(context.Context, string) (string, error) {
+		return "", errors.New("LLM unavailable")
+	}
+	_, err = c.QueryWithOptions(ctx, QueryOptions{QueryText: "question", NResults: 1, HyDE: HyDEOptions{Generate: failing}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	_, err = c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{1, 0, 0}, NResults: 1, HyDE: HyDEOptions{Generate: generate}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	_, err = c.QueryStream(ctx, QueryOptions{QueryText: "question", NResults: 1, HyDE: HyDEOptions{Generate: generate}}, 0, func(Result) {})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
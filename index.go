@@ -0,0 +1,300 @@
+package chromem
+
+import (
+	"context"
+	"fmt"
+)
+
+// IndexType is the type of a collection's vector index, see
+// [Collection.SetIndex].
+type IndexType string
+
+const (
+	// IndexTypeNone means queries scan all documents. This is the default.
+	IndexTypeNone IndexType = ""
+)
+
+// IndexOptions are the options of a collection's vector index.
+type IndexOptions struct {
+	// The type of the index.
+	Type IndexType
+
+	// The number of lists (clusters) of the IVF index. 0 means the square root
+	// of the number of documents when the index is built.
+	Lists int
+
+	// The number of lists that queries scan. More lists mean a higher recall,
+	// but slower queries. 0 means 10% of the lists, but at least 1.
+	NProbe int
+
+	// The maximum number of edges per document in the graph of the DiskANN
+	// index. More edges mean a higher recall, but a larger index and slower
+	// queries. 0 means 32.
+	Degree int
+
+	// The number of candidates that queries of the DiskANN index keep while
+	// searching the graph, and scan. More candidates mean a higher recall, but
+	// slower queries. It should be at least the number of results. 0 means 64.
+	SearchListSize int
+}
+
+func (o IndexOptions) validate() error {
+	if o.Type == IndexTypeNone {
+		return nil
+	}
+	t, ok := indexTypes[o.Type]
+	if !ok {
+		return fmt.Errorf("unsupported index type: %q", o.Type)
+	}
+	if t.validate != nil {
+		return t.validate(o)
+	}
+	return nil
+}
+
+// vectorIndex is a collection's vector index. It's guarded by the collection's
+// documents lock.
+type vectorIndex interface {
+	// set adds the added or replaced document to the index.
+	set(doc *Document)
+	// remove removes the deleted document from the index.
+	remove(id string)
+	// contains returns whether the document is in the index.
+	contains(id string) bool
+	// candidates returns the documents to scan for the normalized query, or
+	// false if the index can't be used, for example because the query has a
+	// different dimension than the indexed documents.
+	candidates(query []float32, documents map[string]*Document) ([]*Document, bool)
+	// setSearchOptions applies the options that only affect queries, see
+	// [indexType.buildOptions].
+	setSearchOptions(options IndexOptions)
+	// persist stores the state that's needed to load the index in the
+	// collection's configuration.
+	persist(config *collectionConfig)
+	// drop releases the resources of the index and removes its files, when
+	// it's not used anymore.
+	drop() error
+	// clone returns a copy of the index for a snapshot (see
+	// [Collection.Snapshot]), which later changes of the index don't affect.
+	clone() vectorIndex
+}
+
+// indexType is the implementation of an [IndexType].
+type indexType struct {
+	// validate validates the type's options. Optional.
+	validate func(options IndexOptions) error
+	// build builds an index of the documents, which have the dimension. It
+	// returns nil if the index can't be built for the documents, for example
+	// without documents, so that queries scan all documents.
+	build func(ctx context.Context, c *Collection, docs []*Document, dim int, options IndexOptions) (vectorIndex, error)
+	// load creates the index from the state that was persisted in the
+	// configuration, and the documents.
+	load func(c *Collection, config collectionConfig, docs []*Document) (vectorIndex, error)
+	// buildOptions returns the options without the ones that only affect
+	// queries. Indexes with the same build options only differ in their search
+	// options, so [Collection.TuneIndex] builds them once.
+	buildOptions func(options IndexOptions) IndexOptions
+	// tuningOptions returns the options that [Collection.TuneIndex] tries by
+	// default for the number of documents.
+	tuningOptions func(n int) []IndexOptions
+}
+
+// indexTypes are the implementations of the supported index types.
+var indexTypes = map[IndexType]indexType{
+	IndexTypeIVF:     ivfIndexType,
+	IndexTypeDiskANN: diskANNIndexType,
+}
+
+// SetIndex sets the vector index of the collection and builds it from the
+// current documents. With an index, queries scan only part of the documents,
+// which makes them faster for large collections, but they're approximate: Some
+// of the most similar documents can be missing from the results, and with
+// selective filters there can be fewer results than requested. Use
+// [QueryOptions.Exhaustive] for exact results, and [Collection.TuneIndex] to
+// find options with a good tradeoff between recall and speed.
+//
+// Documents that are added, updated or deleted later are applied to the index.
+// Until the index is built with documents, and when the embeddings of the
+// documents have a different dimension than the index's (see
+// [Collection.Dimension]), queries scan all documents.
+// [Collection.QueryBatch] always scans all documents.
+//
+// The index is built without blocking writes or queries: Until it's swapped
+// in, queries use the previous index, or scan all documents without one, and
+// documents that are added, updated or deleted during the build are applied to
+// the new index when it's swapped in. To not wait for the build, use
+// [Collection.SetIndexInBackground]. Concurrent builds are serialized.
+//
+// The options are persisted, and the index is loaded with the collection.
+func (c *Collection) SetIndex(ctx context.Context, options IndexOptions) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+	return c.buildIndex(ctx, options)
+}
+
+// SetIndexInBackground is like [Collection.SetIndex], but builds the index in
+// the background, for example when an index is enabled for a large existing
+// collection. It returns an error if the options are invalid. Otherwise it
+// returns a channel that receives the error of the build, or nil, when the
+// index is swapped in, and is closed then. Cancel the context to cancel the
+// build.
+func (c *Collection) SetIndexInBackground(ctx context.Context, options IndexOptions) (<-chan error, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+		done <- c.buildIndex(ctx, options)
+	}()
+	return done, nil
+}
+
+// Index returns the options of the collection's vector index.
+func (c *Collection) Index() IndexOptions {
+	return c.getConfig().Index
+}
+
+// RebuildIndex rebuilds the collection's vector index from the current
+// documents, see [Collection.SetIndex].
+func (c *Collection) RebuildIndex(ctx context.Context) error {
+	return c.buildIndex(ctx, c.getConfig().Index)
+}
+
+// buildIndex builds the index with the current documents, swaps it in and
+// persists it. The documents are only locked for reading while they're
+// collected, and for writing while the changes since then are applied to the
+// index, so writes aren't blocked while the index is built.
+func (c *Collection) buildIndex(ctx context.Context, options IndexOptions) error {
+	c.indexBuildLock.Lock()
+	defer c.indexBuildLock.Unlock()
+
+	c.documentsLock.RLock()
+	docs := c.documentsSliceLocked()
+	dim := c.dimensionLocked("")
+	c.documentsLock.RUnlock()
+
+	var index vectorIndex
+	if options.Type != IndexTypeNone {
+		var err error
+		index, err = indexTypes[options.Type].build(ctx, c, docs, dim, options)
+		if err != nil {
+			return fmt.Errorf("couldn't build %s index: %w", options.Type, err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		_ = dropIndex(index)
+		return err
+	}
+
+	// The options and the index are swapped together, so queries never use
+	// an index that doesn't match the options.
+	c.documentsLock.Lock()
+	if index != nil {
+		updateIndex(index, docs, c.documents)
+	}
+	previous := c.swapIndexLocked(index, options)
+	c.documentsLock.Unlock()
+
+	// The previous index is only dropped when the new one is persisted.
+	if err := c.persistMetadata(); err != nil {
+		return err
+	}
+	return dropIndex(previous)
+}
+
+// swapIndexLocked sets the index and its options, and returns the previous
+// index. The caller must hold the documents lock for writing.
+func (c *Collection) swapIndexLocked(index vectorIndex, options IndexOptions) vectorIndex {
+	previous := c.index
+	c.index = index
+	c.configLock.Lock()
+	c.config.Index = options
+	// The state of the previous index
+	c.config.IndexCentroids = nil
+	c.config.IndexFile = ""
+	if index != nil {
+		index.persist(&c.config)
+	}
+	c.configLock.Unlock()
+	return previous
+}
+
+// dropIndexLocked removes the collection's index, for example when the
+// collection is deleted, and drops it. The caller must hold the documents lock
+// for writing.
+func (c *Collection) dropIndexLocked() error {
+	index := c.index
+	c.index = nil
+	return dropIndex(index)
+}
+
+// dropIndex drops the index, if it's not nil.
+func dropIndex(index vectorIndex) error {
+	if index == nil {
+		return nil
+	}
+	return index.drop()
+}
+
+// updateIndex applies the changes of the documents since the indexed ones were
+// collected to the index.
+func updateIndex(index vectorIndex, indexed []*Document, documents map[string]*Document) {
+	// Deleted and replaced documents
+	for _, doc := range indexed {
+		if documents[doc.ID] != doc {
+			index.remove(doc.ID)
+		}
+	}
+	// Added and replaced documents
+	for id, doc := range documents {
+		if !index.contains(id) {
+			index.set(doc)
+		}
+	}
+}
+
+// loadIndexesLocked loads the index with the persisted options, and the
+// partitions. The caller must hold the documents lock for writing, or have
+// exclusive access to the collection.
+func (c *Collection) loadIndexesLocked() error {
+	config := c.getConfig()
+	c.partitions = newPartitions(config.PartitionKey, c.documents)
+	c.index = nil
+	if config.Index.Type == IndexTypeNone {
+		return nil
+	}
+	t, ok := indexTypes[config.Index.Type]
+	if !ok {
+		return fmt.Errorf("unsupported index type: %q", config.Index.Type)
+	}
+	docs := make([]*Document, 0, len(c.documents))
+	for _, doc := range c.documents {
+		docs = append(docs, doc)
+	}
+	index, err := t.load(c, config, docs)
+	if err != nil {
+		return err
+	}
+	c.index = index
+	return nil
+}
+
+// indexDocumentLocked adds the added or replaced document to the collection's
+// index and partitions. The caller must hold the documents lock for writing.
+func (c *Collection) indexDocumentLocked(doc *Document) {
+	if c.index != nil {
+		c.index.set(doc)
+	}
+	c.partitions.set(doc)
+}
+
+// unindexDocumentLocked removes the deleted document from the collection's
+// index and partitions. The caller must hold the documents lock for writing.
+func (c *Collection) unindexDocumentLocked(id string) {
+	if c.index != nil {
+		c.index.remove(id)
+	}
+	c.partitions.remove(id)
+}
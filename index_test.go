@@ -0,0 +1,315 @@
+package chromem
+
+import (
+	"context"
+	"maps"
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	// indexTypeTestAll is an index for tests whose candidates are all documents.
+	indexTypeTestAll IndexType = "test-all"
+	// indexTypeTestHalf is an index for tests whose candidates are the first
+	// half of the documents, by ID.
+	indexTypeTestHalf IndexType = "test-half"
+)
+
+// testIndexBuilds is the number of builds of the test indexes.
+var testIndexBuilds atomic.Int32
+
+func init() {
+	for t, fraction := range map[IndexType]float64{indexTypeTestAll: 1, indexTypeTestHalf: 0.5} {
+		t, fraction := t, fraction
+		newIndex := func(docs []*Document) vectorIndex {
+			if len(docs) == 0 {
+				return nil
+			}
+			x := &testIndex{fraction: fraction, ids: make(map[string]struct{}, len(docs))}
+			for _, doc := range docs {
+				x.set(doc)
+			}
+			return x
+		}
+		indexTypes[t] = indexType{
+			build: func(ctx context.Context, _ *Collection, docs []*Document, _ int, _ IndexOptions) (vectorIndex, error) {
+				testIndexBuilds.Add(1)
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				return newIndex(docs), nil
+			},
+			load: func(_ *Collection, _ collectionConfig, docs []*Document) (vectorIndex, error) {
+				return newIndex(docs), nil
+			},
+			buildOptions: func(options IndexOptions) IndexOptions {
+				return options
+			},
+			tuningOptions: func(int) []IndexOptions {
+				return []IndexOptions{{Type: t}}
+			},
+		}
+	}
+}
+
+// testIndex is a vector index for tests, whose candidates are a fraction of the
+// documents, by ID.
+type testIndex struct {
+	fraction float64
+	ids      map[string]struct{}
+}
+
+func (x *testIndex) set(doc *Document) {
+	x.ids[doc.ID] = struct{}{}
+}
+
+func (x *testIndex) remove(id string) {
+	delete(x.ids, id)
+}
+
+func (x *testIndex) contains(id string) bool {
+	_, ok := x.ids[id]
+	return ok
+}
+
+func (x *testIndex) candidates(_ []float32, documents map[string]*Document) ([]*Document, bool) {
+	ids := make([]string, 0, len(x.ids))
+	for id := range x.ids {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	ids = ids[:int(float64(len(ids))*x.fraction)]
+	docs := make([]*Document, 0, len(ids))
+	for _, id := range ids {
+		docs = append(docs, documents[id])
+	}
+	return docs, true
+}
+
+func (x *testIndex) setSearchOptions(IndexOptions) {}
+
+func (x *testIndex) persist(*collectionConfig) {}
+
+func (x *testIndex) drop() error {
+	return nil
+}
+
+func (x *testIndex) clone() vectorIndex {
+	return &testIndex{fraction: x.fraction, ids: maps.Clone(x.ids)}
+}
+
+func TestCollection_SetIndex(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	randomVector := func() []float32 {
+		v := make([]float32, 8)
+		for j := range v {
+			v[j] = r.Float32()*2 - 1
+		}
+		return normalizeVector(v)
+	}
+
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	n := 100
+	docs := make([]Document, n)
+	for i := range docs {
+		docs[i] = Document{ID: strconv.Itoa(i), Embedding: randomVector()}
+	}
+	err = c.AddDocuments(ctx, docs, 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	if err := c.SetIndex(ctx, IndexOptions{Type: "hnsw"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.SetIndex(ctx, IndexOptions{Type: indexTypeTestHalf})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Index().Type != indexTypeTestHalf {
+		t.Fatal("expected test index, got", c.Index())
+	}
+
+	scanned := func(c *Collection, exhaustive bool) int {
+		t.Helper()
+		_, stats, err := c.QueryWithStats(ctx, QueryOptions{QueryEmbedding: randomVector(), NResults: 1, Exhaustive: exhaustive})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		return stats.DocumentsScanned
+	}
+	if s := scanned(c, false); s != n/2 {
+		t.Fatalf("expected %d documents to be scanned, got %d", n/2, s)
+	}
+	if s := scanned(c, true); s != n {
+		t.Fatalf("expected %d documents to be scanned by exhaustive queries, got %d", n, s)
+	}
+
+	// Added and deleted documents are indexed.
+	err = c.AddDocument(ctx, Document{ID: "added", Embedding: randomVector()})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "0")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !c.index.contains("added") || c.index.contains("0") {
+		t.Fatal("expected added document to be indexed and deleted one not")
+	}
+
+	// The index is loaded with the collection.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if c2.Index().Type != indexTypeTestHalf {
+		t.Fatal("expected persisted index options, got", c2.Index())
+	}
+	if s := scanned(c2, false); s != n/2 {
+		t.Fatalf("expected %d documents to be scanned after loading, got %d", n/2, s)
+	}
+
+	// Without index, all documents are scanned.
+	err = c.SetIndex(ctx, IndexOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.index != nil {
+		t.Fatal("expected no index, got", c.index)
+	}
+	if s := scanned(c, false); s != n {
+		t.Fatalf("expected %d documents to be scanned, got %d", n, s)
+	}
+}
+
+// onErrContext calls fn on the first call of Err, which the index build does
+// after it collected the documents, when it starts building.
+type onErrContext struct {
+	context.Context
+	once sync.Once
+	fn   func()
+}
+
+func (c *onErrContext) Err() error {
+	c.once.Do(c.fn)
+	return c.Context.Err()
+}
+
+func TestCollection_SetIndexInBackground(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	randomVector := func() []float32 {
+		v := make([]float32, 8)
+		for j := range v {
+			v[j] = r.Float32()*2 - 1
+		}
+		return normalizeVector(v)
+	}
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	n := 100
+	docs := make([]Document, n)
+	for i := range docs {
+		docs[i] = Document{ID: strconv.Itoa(i), Embedding: randomVector()}
+	}
+	err = c.AddDocuments(context.Background(), docs, 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = c.SetIndexInBackground(context.Background(), IndexOptions{Type: "hnsw"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Queries scan all documents during the build, and writes aren't blocked.
+	updated := &Document{}
+	ctx := &onErrContext{Context: context.Background(), fn: func() {
+		_, stats, err := c.QueryWithStats(context.Background(), QueryOptions{QueryEmbedding: randomVector(), NResults: 1})
+		if err != nil {
+			t.Error("expected no error, got", err)
+		}
+		if stats.DocumentsScanned != n {
+			t.Errorf("expected %d documents to be scanned during the build, got %d", n, stats.DocumentsScanned)
+		}
+		for _, err := range []error{
+			c.AddDocument(context.Background(), Document{ID: "added", Embedding: randomVector()}),
+			c.AddDocument(context.Background(), Document{ID: "1", Embedding: randomVector()}),
+			c.Delete(context.Background(), nil, nil, "2"),
+		} {
+			if err != nil {
+				t.Error("expected no error, got", err)
+			}
+		}
+		c.documentsLock.RLock()
+		updated = c.documents["1"]
+		c.documentsLock.RUnlock()
+	}}
+	done, err := c.SetIndexInBackground(ctx, IndexOptions{Type: indexTypeTestAll})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, ok := <-done; ok {
+		t.Fatal("expected closed channel")
+	}
+	if c.Index().Type != indexTypeTestAll || c.index == nil {
+		t.Fatal("expected test index, got", c.Index())
+	}
+
+	// The writes during the build are in the index.
+	if !c.index.contains("added") || c.index.contains("2") {
+		t.Fatal("expected added document to be indexed and deleted one not")
+	}
+	candidates, _ := c.index.candidates(nil, c.documents)
+	if len(candidates) != n || !slices.Contains(candidates, updated) {
+		t.Fatal("expected all documents with the updated one to be candidates, got", len(candidates))
+	}
+
+	// Cancelled builds aren't swapped in.
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = c.SetIndex(cancelled, IndexOptions{Type: indexTypeTestHalf})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if c.Index().Type != indexTypeTestAll {
+		t.Fatal("expected previous index, got", c.Index())
+	}
+
+	// Concurrent builds are serialized, so the index always matches the
+	// options.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(typ IndexType) {
+			defer wg.Done()
+			err := c.SetIndex(context.Background(), IndexOptions{Type: typ})
+			if err != nil {
+				t.Error("expected no error, got", err)
+			}
+		}([]IndexType{indexTypeTestAll, indexTypeTestHalf}[i%2])
+	}
+	wg.Wait()
+	if x := c.index.(*testIndex); (x.fraction == 1) != (c.Index().Type == indexTypeTestAll) {
+		t.Fatal("expected index to match the options, got", x.fraction, c.Index())
+	}
+}
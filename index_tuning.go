@@ -0,0 +1,269 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// IndexTuningOptions are the options for [Collection.TuneIndex].
+type IndexTuningOptions struct {
+	// The index type whose default options are tried if Options is empty.
+	Type IndexType
+
+	// The index options to try, which can be of different types. Optional,
+	// defaults to options of the Type for the number of documents.
+	Options []IndexOptions
+
+	// The number of documents whose embeddings are used as queries. Optional,
+	// defaults to 100.
+	SampleQueries int
+
+	// The number of results per query that the recall is measured for.
+	// Optional, defaults to 10.
+	NResults int
+
+	// The recall that the recommended options must reach. Optional, defaults
+	// to 0.95.
+	TargetRecall float64
+
+	// Whether to set the recommended options as the collection's index, see
+	// [Collection.SetIndex]. The index that was built for the recommended
+	// options is used, so it's not built again.
+	Apply bool
+}
+
+// IndexTuningResult is the measured recall and latency of index options, see
+// [Collection.TuneIndex].
+type IndexTuningResult struct {
+	Options IndexOptions
+
+	// The average fraction of the exact nearest neighbors that the queries
+	// with the index returned, from 0 to 1.
+	Recall float64
+
+	// The average duration of a query with the index.
+	Latency time.Duration
+
+	// The average number of documents that a query scanned.
+	DocumentsScanned float64
+}
+
+// IndexTuningReport is the result of [Collection.TuneIndex].
+type IndexTuningReport struct {
+	// The results of all tried options, in the order they were tried.
+	Results []IndexTuningResult
+
+	// The recommended options: The ones that scan the fewest documents while
+	// reaching the target recall, or the ones with the highest recall if none
+	// reaches it.
+	Recommended IndexTuningResult
+
+	// Whether the recommended options reach the target recall.
+	TargetReached bool
+
+	// The average duration of an exhaustive query, which scans all documents,
+	// for comparison.
+	ExhaustiveLatency time.Duration
+}
+
+// TuneIndex measures the recall and latency of vector index options (see
+// [Collection.SetIndex]) for the collection's documents, and recommends the
+// options to use. It samples documents as queries, calculates their exact
+// nearest neighbors by scanning all documents as ground truth, and then builds
+// an index for the options and queries it.
+//
+// Options that only differ in the ones that only affect queries share one
+// index, but building an index takes a while for large collections, so the
+// tuning takes about as long as building an index for each of the other
+// options. It doesn't change the collection, unless [IndexTuningOptions.Apply]
+// is set, and doesn't block writes or queries. The latency is measured without
+// filters and without the overhead of results, so it's only comparable between
+// the tried options.
+func (c *Collection) TuneIndex(ctx context.Context, opts IndexTuningOptions) (IndexTuningReport, error) {
+	if opts.SampleQueries < 0 || opts.NResults < 0 || opts.TargetRecall < 0 || opts.TargetRecall > 1 {
+		return IndexTuningReport{}, errors.New("sampleQueries and nResults must be >= 0, and targetRecall must be between 0 and 1")
+	}
+	sampleQueries := opts.SampleQueries
+	if sampleQueries == 0 {
+		sampleQueries = 100
+	}
+	nResults := opts.NResults
+	if nResults == 0 {
+		nResults = 10
+	}
+	targetRecall := opts.TargetRecall
+	if targetRecall == 0 {
+		targetRecall = 0.95
+	}
+
+	c.documentsLock.RLock()
+	docs := c.documentsSliceLocked()
+	dim := c.dimensionLocked("")
+	c.documentsLock.RUnlock()
+	if len(docs) == 0 {
+		return IndexTuningReport{}, errors.New("collection is empty")
+	}
+
+	options := opts.Options
+	if len(options) == 0 {
+		t, ok := indexTypes[opts.Type]
+		if !ok {
+			return IndexTuningReport{}, fmt.Errorf("unsupported index type: %q", opts.Type)
+		}
+		options = t.tuningOptions(len(docs))
+	}
+	for _, o := range options {
+		if o.Type == IndexTypeNone {
+			return IndexTuningReport{}, errors.New("options without index type can't be tuned")
+		}
+		if err := o.validate(); err != nil {
+			return IndexTuningReport{}, err
+		}
+	}
+
+	nResults = min(nResults, len(docs))
+	byID := make(map[string]*Document, len(docs))
+	for _, doc := range docs {
+		byID[doc.ID] = doc
+	}
+
+	// A fixed seed, so that the tuning is the same for the same documents.
+	r := rand.New(rand.NewSource(1))
+	queries := make([][]float32, 0, min(sampleQueries, len(docs)))
+	for _, i := range r.Perm(len(docs))[:cap(queries)] {
+		queries = append(queries, docs[i].Embedding)
+	}
+
+	report := IndexTuningReport{}
+	exact := make([][]string, len(queries))
+	start := time.Now()
+	for i, query := range queries {
+		exact[i] = nearestDocumentIDs(query, docs, nResults)
+	}
+	report.ExhaustiveLatency = time.Since(start) / time.Duration(len(queries))
+
+	// The options are grouped by their build options, in the order of their
+	// first occurrence, so that each index is built once.
+	var groups [][]IndexOptions
+	groupOf := make(map[IndexOptions]int)
+	for _, o := range options {
+		key := indexTypes[o.Type].buildOptions(o)
+		i, ok := groupOf[key]
+		if !ok {
+			i = len(groups)
+			groupOf[key] = i
+			groups = append(groups, nil)
+		}
+		if !slices.Contains(groups[i], o) {
+			groups[i] = append(groups[i], o)
+		}
+	}
+
+	// The index of the recommended options. The other indexes are dropped when
+	// they're not needed anymore, and the recommended one at the end, unless
+	// it's applied.
+	var recommended vectorIndex
+	defer func() {
+		_ = dropIndex(recommended)
+	}()
+	for _, group := range groups {
+		index, err := indexTypes[group[0].Type].build(ctx, c, docs, dim, group[0])
+		if err != nil {
+			return IndexTuningReport{}, fmt.Errorf("couldn't build %s index: %w", group[0].Type, err)
+		}
+		for _, o := range group {
+			if err := ctx.Err(); err != nil {
+				if index != recommended {
+					_ = dropIndex(index)
+				}
+				return IndexTuningReport{}, err
+			}
+			if index != nil {
+				index.setSearchOptions(o)
+			}
+			result := IndexTuningResult{Options: o}
+			hits, scanned := 0, 0
+			start := time.Now()
+			for i, query := range queries {
+				candidates := docs
+				if index != nil {
+					if indexed, ok := index.candidates(query, byID); ok && len(indexed) != 0 {
+						candidates = indexed
+					}
+				}
+				scanned += len(candidates)
+				for _, id := range nearestDocumentIDs(query, candidates, nResults) {
+					if slices.Contains(exact[i], id) {
+						hits++
+					}
+				}
+			}
+			result.Latency = time.Since(start) / time.Duration(len(queries))
+			result.Recall = float64(hits) / float64(len(queries)*nResults)
+			result.DocumentsScanned = float64(scanned) / float64(len(queries))
+			report.Results = append(report.Results, result)
+
+			reached := result.Recall >= targetRecall
+			best := report.Recommended
+			switch {
+			case len(report.Results) == 1,
+				reached && !report.TargetReached,
+				reached && result.DocumentsScanned < best.DocumentsScanned,
+				!reached && !report.TargetReached && result.Recall > best.Recall:
+				report.Recommended = result
+				report.TargetReached = reached
+				if recommended != index {
+					_ = dropIndex(recommended)
+				}
+				recommended = index
+			}
+		}
+		if index != recommended {
+			_ = dropIndex(index)
+		}
+	}
+
+	if opts.Apply {
+		// The recommended index has the search options of its group's last
+		// options, and misses the changes since the documents were collected.
+		c.indexBuildLock.Lock()
+		defer c.indexBuildLock.Unlock()
+		c.documentsLock.Lock()
+		if recommended != nil {
+			recommended.setSearchOptions(report.Recommended.Options)
+			updateIndex(recommended, docs, c.documents)
+		}
+		previous := c.swapIndexLocked(recommended, report.Recommended.Options)
+		c.documentsLock.Unlock()
+		recommended = nil
+		if err := c.persistMetadata(); err != nil {
+			return report, err
+		}
+		return report, dropIndex(previous)
+	}
+	return report, nil
+}
+
+// nearestDocumentIDs returns the IDs of the n documents that are the most
+// similar to the normalized query. Documents with a different dimension are
+// skipped.
+func nearestDocumentIDs(query []float32, docs []*Document, n int) []string {
+	topK := newMaxDocSims(n)
+	for _, doc := range docs {
+		sim, err := dotProduct(query, doc.Embedding)
+		if err != nil {
+			continue
+		}
+		topK.add(docSim{docID: doc.ID, similarity: sim})
+	}
+	values := topK.values()
+	ids := make([]string, len(values))
+	for i, v := range values {
+		ids[i] = v.docID
+	}
+	return ids
+}
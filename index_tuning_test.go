@@ -0,0 +1,94 @@
+package chromem
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_TuneIndex(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = c.TuneIndex(ctx, IndexTuningOptions{Type: indexTypeTestAll})
+	if err == nil {
+		t.Fatal("expected error for empty collection, got nil")
+	}
+
+	n := 200
+	docs := make([]Document, n)
+	for i := range docs {
+		v := make([]float32, 8)
+		for j := range v {
+			v[j] = r.Float32()*2 - 1
+		}
+		docs[i] = Document{ID: strconv.Itoa(i), Embedding: v}
+	}
+	err = c.AddDocuments(ctx, docs, 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = c.TuneIndex(ctx, IndexTuningOptions{Type: indexTypeTestAll, TargetRecall: 2})
+	if err == nil {
+		t.Fatal("expected error for invalid target recall, got nil")
+	}
+	_, err = c.TuneIndex(ctx, IndexTuningOptions{})
+	if err == nil {
+		t.Fatal("expected error without index type, got nil")
+	}
+
+	// The default options of the type
+	report, err := c.TuneIndex(ctx, IndexTuningOptions{Type: indexTypeTestAll})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(report.Results) != 1 || report.Results[0].Recall != 1 || report.Results[0].DocumentsScanned != float64(n) {
+		t.Fatal("expected exact results, got", report.Results)
+	}
+	if report.ExhaustiveLatency <= 0 {
+		t.Fatal("expected exhaustive latency, got", report.ExhaustiveLatency)
+	}
+
+	options := []IndexOptions{{Type: indexTypeTestAll}, {Type: indexTypeTestHalf}, {Type: indexTypeTestHalf}}
+	report, err = c.TuneIndex(ctx, IndexTuningOptions{Options: options, SampleQueries: 50})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(report.Results) != 2 {
+		t.Fatal("expected 2 results, got", len(report.Results))
+	}
+	if half := report.Results[1]; half.Recall >= 1 || half.DocumentsScanned != float64(n/2) {
+		t.Fatal("expected half of the documents to be scanned with lower recall, got", half)
+	}
+	if !report.TargetReached || report.Recommended.Options.Type != indexTypeTestAll {
+		t.Fatal("expected the exact index to be recommended, got", report.Recommended)
+	}
+	if c.Index().Type != IndexTypeNone {
+		t.Fatal("expected index not to be set, got", c.Index())
+	}
+
+	// Applying the recommended options uses the tuned index.
+	builds := testIndexBuilds.Load()
+	report, err = c.TuneIndex(ctx, IndexTuningOptions{Options: options, SampleQueries: 50, TargetRecall: 0.1, Apply: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !report.TargetReached || report.Recommended.Options.Type != indexTypeTestHalf {
+		t.Fatal("expected the half index to be recommended, got", report.Recommended)
+	}
+	if c.Index() != report.Recommended.Options {
+		t.Fatal("expected recommended options to be applied, got", c.Index(), report.Recommended.Options)
+	}
+	if b := testIndexBuilds.Load() - builds; b != 2 {
+		t.Fatal("expected 2 builds, got", b)
+	}
+	if x, ok := c.index.(*testIndex); !ok || x.fraction != 0.5 || len(x.ids) != n {
+		t.Fatal("expected the tuned index, got", c.index)
+	}
+}
@@ -0,0 +1,223 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxErrorBodyLen is the maximum number of bytes of an error response body that
+// we include in error messages.
+const maxErrorBodyLen = 512
+
+// doJSONRequest sends an HTTP request with an optional JSON body to the given
+// URL and decodes the JSON response body into respBody, unless it's nil.
+// It's used for the import from and export to other vector databases.
+func doJSONRequest(ctx context.Context, client *http.Client, method, url string, headers map[string]string, reqBody, respBody any) error {
+	var body io.Reader
+	if reqBody != nil {
+		b, err := json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("couldn't marshal request body: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return fmt.Errorf("couldn't create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLen))
+		return &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(b)}
+	}
+
+	if respBody == nil {
+		return nil
+	}
+	dec := json.NewDecoder(resp.Body)
+	// Keep numbers in metadata as they are, instead of converting them to float64.
+	dec.UseNumber()
+	err = dec.Decode(respBody)
+	if err != nil {
+		return fmt.Errorf("couldn't decode response body: %w", err)
+	}
+	return nil
+}
+
+// httpStatusError is returned by doJSONRequest for non-2xx responses.
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	if e.Body == "" {
+		return "unexpected response status: " + e.Status
+	}
+	return "unexpected response status: " + e.Status + ": " + e.Body
+}
+
+// metadataToStrings converts arbitrary JSON metadata values to strings, as
+// chromem-go only supports string metadata. Nested values are encoded as JSON,
+// null values are skipped.
+func metadataToStrings(metadata map[string]any) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	res := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		switch v := v.(type) {
+		case nil:
+			continue
+		case string:
+			res[k] = v
+		case json.Number:
+			res[k] = v.String()
+		case bool:
+			res[k] = strconv.FormatBool(v)
+		case float64:
+			res[k] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				res[k] = fmt.Sprint(v)
+			} else {
+				res[k] = string(b)
+			}
+		}
+	}
+	return res
+}
+
+// interopDefaultBatchSize is the default number of documents per request for
+// the imports from and exports to other vector databases.
+const interopDefaultBatchSize = 100
+
+// interopIDKey is the metadata/payload key under which we store the original
+// document ID when exporting to vector databases that only support UUIDs or
+// integers as IDs. When importing, the value is used as document ID if present.
+const interopIDKey = "chromem_id"
+
+// sortedDocuments returns the collection's documents sorted by ID. The documents
+// themselves aren't copied, but they're never modified after being added, as
+// updates replace them.
+func (c *Collection) sortedDocuments() []*Document {
+	c.documentsLock.RLock()
+	docs := make([]*Document, 0, len(c.documents))
+	for _, doc := range c.documents {
+		docs = append(docs, doc)
+	}
+	c.documentsLock.RUnlock()
+
+	slices.SortFunc(docs, func(a, b *Document) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return docs
+}
+
+// uuidFromString returns a deterministic, name-based UUID (version 5) for the
+// given string, or the string itself if it's a UUID already.
+func uuidFromString(s string) string {
+	if isUUID(s) {
+		return strings.ToLower(s)
+	}
+	// The "OID" namespace from RFC 4122
+	namespace := []byte{0x6b, 0xa7, 0xb8, 0x12, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	h := sha1.New()
+	h.Write(namespace)
+	h.Write([]byte(s))
+	u := h.Sum(nil)[:16]
+	u[6] = (u[6] & 0x0f) | 0x50 // Version 5
+	u[8] = (u[8] & 0x3f) | 0x80 // Variant RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// isUUID checks whether the string is a UUID in its canonical textual form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// splitInteropPayload splits a payload/properties object of another vector DB
+// into the document ID (if stored), the content and the remaining metadata.
+func splitInteropPayload(payload map[string]any, contentKey string) (id, content string, metadata map[string]string) {
+	metadata = metadataToStrings(payload)
+	id = metadata[interopIDKey]
+	content = metadata[contentKey]
+	delete(metadata, interopIDKey)
+	delete(metadata, contentKey)
+	return id, content, metadata
+}
+
+// interopPayload creates a payload/properties object for another vector DB from
+// a document, storing the content under contentKey.
+func interopPayload(doc *Document, contentKey string, withID bool) map[string]any {
+	payload := make(map[string]any, len(doc.Metadata)+2)
+	for k, v := range doc.Metadata {
+		payload[k] = v
+	}
+	if doc.Content != "" {
+		payload[contentKey] = doc.Content
+	}
+	if withID {
+		payload[interopIDKey] = doc.ID
+	}
+	return payload
+}
+
+// interopDefaults validates and defaults the common options of the imports from
+// and exports to other vector databases.
+func interopDefaults(baseURL string, client *http.Client, batchSize int, contentKey string) (string, *http.Client, int, string, error) {
+	if baseURL == "" {
+		return "", nil, 0, "", errors.New("base URL is empty")
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if batchSize <= 0 {
+		batchSize = interopDefaultBatchSize
+	}
+	if contentKey == "" {
+		contentKey = "content"
+	}
+	return baseURL, client, batchSize, contentKey, nil
+}
@@ -0,0 +1,177 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+const (
+	chromaDefaultTenant    = "default_tenant"
+	chromaDefaultDatabase  = "default_database"
+	chromaDefaultBatchSize = 1000
+)
+
+// ChromaImportOptions are the options for [DB.ImportFromChroma].
+type ChromaImportOptions struct {
+	// Names of the collections to import. Optional. If empty, all collections
+	// are imported.
+	Collections []string
+
+	// Tenant and database, only used by Chroma's v2 API. Optional, the defaults
+	// are "default_tenant" and "default_database".
+	Tenant   string
+	Database string
+
+	// Headers to send with each request, for example "Authorization" or
+	// "X-Chroma-Token" when authentication is enabled on the Chroma server. Optional.
+	Headers map[string]string
+
+	// Number of documents to fetch per request. Optional, defaults to 1000.
+	BatchSize int
+
+	// The embedding function for the imported collections. Optional, the default
+	// one is used if nil. Chroma collections are usually created with Chroma's
+	// default "all-MiniLM-L6-v2" model, in which case you must pass an embedding
+	// function using the same model, to be able to query with text.
+	EmbeddingFunc EmbeddingFunc
+
+	// The HTTP client to use. Optional, defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+type chromaCollection struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+type chromaGetResponse struct {
+	IDs        []string         `json:"ids"`
+	Embeddings [][]float32      `json:"embeddings"`
+	Metadatas  []map[string]any `json:"metadatas"`
+	Documents  []*string        `json:"documents"`
+}
+
+// ImportFromChroma imports collections, including their documents, embeddings
+// and metadata, from a running Chroma (https://www.trychroma.com/) server to
+// ease the migration to chromem-go. Both Chroma's v1 and v2 HTTP API are
+// supported, which is detected automatically.
+// Existing collections with the same name are overwritten. For a persistent DB
+// the imported collections and documents are persisted.
+//
+// Chroma stores embeddings in HNSW index files next to its SQLite database, so
+// there's no way to read a persistent directory without Chroma itself. To migrate
+// from a directory, start a Chroma server on it (`chroma run --path /path/to/dir`)
+// and import from that.
+//
+// As chromem-go only supports string metadata values, numbers and booleans are
+// converted to strings.
+//
+//   - baseURL: The URL of the Chroma server, e.g. "http://localhost:8000"
+//   - opts: Options for the import, see [ChromaImportOptions]
+func (db *DB) ImportFromChroma(ctx context.Context, baseURL string, opts ChromaImportOptions) error {
+	if baseURL == "" {
+		return errors.New("baseURL is empty")
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = chromaDefaultBatchSize
+	}
+	tenant := opts.Tenant
+	if tenant == "" {
+		tenant = chromaDefaultTenant
+	}
+	database := opts.Database
+	if database == "" {
+		database = chromaDefaultDatabase
+	}
+	ctx, _ = startProgress(ctx, "import", 0)
+
+	// Chroma >= 0.6 only offers the v2 API, older versions only the v1 API.
+	apiURL := baseURL + "/api/v2/tenants/" + url.PathEscape(tenant) + "/databases/" + url.PathEscape(database)
+	err := doJSONRequest(ctx, client, http.MethodGet, baseURL+"/api/v2/heartbeat", opts.Headers, nil, nil)
+	if err != nil {
+		var statusErr *httpStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("couldn't reach Chroma server: %w", err)
+		}
+		apiURL = baseURL + "/api/v1"
+	}
+
+	var collections []chromaCollection
+	err = doJSONRequest(ctx, client, http.MethodGet, apiURL+"/collections", opts.Headers, nil, &collections)
+	if err != nil {
+		return fmt.Errorf("couldn't list Chroma collections: %w", err)
+	}
+
+	for _, cc := range collections {
+		if len(opts.Collections) > 0 && !slices.Contains(opts.Collections, cc.Name) {
+			continue
+		}
+
+		// Overwrite existing collections, like the other imports do.
+		err := db.DeleteCollectionWithContext(ctx, cc.Name)
+		if err != nil {
+			return fmt.Errorf("couldn't delete existing collection %q: %w", cc.Name, err)
+		}
+		c, err := db.CreateCollection(cc.Name, metadataToStrings(cc.Metadata), opts.EmbeddingFunc)
+		if err != nil {
+			return fmt.Errorf("couldn't create collection %q: %w", cc.Name, err)
+		}
+
+		for offset := 0; ; offset += batchSize {
+			reqBody := map[string]any{
+				"limit":   batchSize,
+				"offset":  offset,
+				"include": []string{"embeddings", "metadatas", "documents"},
+			}
+			var res chromaGetResponse
+			err := doJSONRequest(ctx, client, http.MethodPost, apiURL+"/collections/"+url.PathEscape(cc.ID)+"/get", opts.Headers, reqBody, &res)
+			if err != nil {
+				return fmt.Errorf("couldn't get documents of Chroma collection %q: %w", cc.Name, err)
+			}
+			if len(res.IDs) == 0 {
+				break
+			}
+			if len(res.Embeddings) != len(res.IDs) {
+				return fmt.Errorf("chroma collection %q returned %d embeddings for %d IDs", cc.Name, len(res.Embeddings), len(res.IDs))
+			}
+
+			docs := make([]Document, 0, len(res.IDs))
+			for i, id := range res.IDs {
+				doc := Document{
+					ID:        id,
+					Embedding: res.Embeddings[i],
+				}
+				if i < len(res.Metadatas) {
+					doc.Metadata = metadataToStrings(res.Metadatas[i])
+				}
+				if i < len(res.Documents) && res.Documents[i] != nil {
+					doc.Content = *res.Documents[i]
+				}
+				docs = append(docs, doc)
+			}
+			err = c.AddDocuments(ctx, docs, runtime.NumCPU())
+			if err != nil {
+				return fmt.Errorf("couldn't add documents to collection %q: %w", cc.Name, err)
+			}
+
+			if len(res.IDs) < batchSize {
+				break
+			}
+		}
+	}
+
+	return nil
+}
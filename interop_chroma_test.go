@@ -0,0 +1,89 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDB_ImportFromChroma(t *testing.T) {
+	for _, apiVersion := range []string{"v1", "v2"} {
+		t.Run(apiVersion, func(t *testing.T) {
+			prefix := "/api/v1"
+			if apiVersion == "v2" {
+				prefix = "/api/v2/tenants/default_tenant/databases/default_database"
+			}
+
+			// Mock server with two collections, one of which has three documents,
+			// served in batches of two.
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-Chroma-Token") != "secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				switch r.URL.Path {
+				case "/api/v2/heartbeat":
+					if apiVersion == "v1" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					_, _ = w.Write([]byte(`{"nanosecond heartbeat": 1}`))
+				case prefix + "/collections":
+					_, _ = w.Write([]byte(`[{"id":"c1","name":"docs","metadata":{"hnsw:space":"cosine","version":2}},{"id":"c2","name":"other","metadata":null}]`))
+				case prefix + "/collections/c1/get":
+					var req struct {
+						Limit  int
+						Offset int
+					}
+					if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+						t.Fatal("unexpected error:", err)
+					}
+					if req.Offset == 0 {
+						_, _ = w.Write([]byte(`{"ids":["1","2"],"embeddings":[[1,0,0],[0,2,0]],"metadatas":[{"page":1,"draft":true},null],"documents":["one",null]}`))
+					} else {
+						_, _ = w.Write([]byte(`{"ids":["3"],"embeddings":[[0,0,1]],"metadatas":[{"page":3.5}],"documents":["three"]}`))
+					}
+				default:
+					t.Fatal("unexpected request", r.Method, r.URL.Path)
+				}
+			}))
+			defer ts.Close()
+
+			db := NewDB()
+			err := db.ImportFromChroma(context.Background(), ts.URL, ChromaImportOptions{
+				Collections: []string{"docs"},
+				Headers:     map[string]string{"X-Chroma-Token": "secret"},
+				BatchSize:   2,
+				EmbeddingFunc: func(_ context.Context, _ string) ([]float32, error) {
+					return []float32{1, 0, 0}, nil
+				},
+			})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+
+			if len(db.collections) != 1 {
+				t.Fatal("expected 1 collection, got", len(db.collections))
+			}
+			c := db.collections["docs"]
+			if c.metadata["version"] != "2" || c.metadata["hnsw:space"] != "cosine" {
+				t.Fatal("unexpected collection metadata", c.metadata)
+			}
+			if len(c.documents) != 3 {
+				t.Fatal("expected 3 documents, got", len(c.documents))
+			}
+			if d := c.documents["1"]; d.Content != "one" || d.Metadata["page"] != "1" || d.Metadata["draft"] != "true" {
+				t.Fatalf("unexpected document: %+v", d)
+			}
+			// Embeddings are normalized
+			if d := c.documents["2"]; d.Content != "" || d.Embedding[1] != 1 {
+				t.Fatalf("unexpected document: %+v", d)
+			}
+			if d := c.documents["3"]; d.Metadata["page"] != "3.5" {
+				t.Fatalf("unexpected document: %+v", d)
+			}
+		})
+	}
+}
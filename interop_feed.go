@@ -0,0 +1,260 @@
+package chromem
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// FeedOptions are the options for [Collection.SyncFeed].
+type FeedOptions struct {
+	// Entries that were published longer ago than MaxAge aren't added, and
+	// are deleted if they were added before. Optional, defaults to keeping all
+	// entries.
+	MaxAge time.Duration
+
+	// The HTTP client to use. Optional, defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+// FeedSyncStats are the statistics of a [Collection.SyncFeed] run.
+type FeedSyncStats struct {
+	EntriesAdded   int
+	EntriesExpired int
+}
+
+// feedEntry is an entry of an RSS or Atom feed.
+type feedEntry struct {
+	id        string
+	title     string
+	link      string
+	content   string
+	published time.Time
+}
+
+// SyncFeed fetches an RSS or Atom feed and adds its new entries as documents to
+// this collection, for example to monitor news. Entries are deduplicated by
+// their GUID (RSS) or ID (Atom), which becomes the document ID, so existing
+// entries aren't embedded again. Call it periodically to stay up to date, for
+// example as task of [DB.StartMaintenance].
+//
+// The content of a document is the entry's title and its content or summary as
+// text. The metadata contains "feed_url", "title", "link" and "published" (as
+// RFC 3339 timestamp, if the entry has one).
+func (c *Collection) SyncFeed(ctx context.Context, feedURL string, opts FeedOptions) (FeedSyncStats, error) {
+	stats := FeedSyncStats{}
+	if feedURL == "" {
+		return stats, errors.New("feed URL is empty")
+	}
+	if opts.MaxAge < 0 {
+		return stats, errors.New("maxAge must be >= 0")
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ctx, _ = startProgress(ctx, "import", 0)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return stats, fmt.Errorf("couldn't create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml, text/xml")
+	resp, err := client.Do(req)
+	if err != nil {
+		return stats, fmt.Errorf("couldn't send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return stats, &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	entries, err := parseFeed(io.LimitReader(resp.Body, webMaxBodySize))
+	if err != nil {
+		return stats, err
+	}
+
+	now := time.Now()
+	c.documentsLock.RLock()
+	var docs []Document
+	for _, e := range entries {
+		if _, ok := c.documents[e.id]; ok {
+			continue
+		}
+		if opts.MaxAge > 0 && !e.published.IsZero() && now.Sub(e.published) > opts.MaxAge {
+			continue
+		}
+		content := strings.TrimSpace(e.title + "\n\n" + e.content)
+		if content == "" {
+			continue
+		}
+		metadata := map[string]string{
+			"feed_url": feedURL,
+			"title":    e.title,
+			"link":     e.link,
+		}
+		if !e.published.IsZero() {
+			metadata["published"] = e.published.UTC().Format(time.RFC3339)
+		}
+		docs = append(docs, Document{
+			ID:       e.id,
+			Metadata: metadata,
+			Content:  content,
+		})
+	}
+	var expired []string
+	if opts.MaxAge > 0 {
+		for id, doc := range c.documents {
+			if doc.Metadata["feed_url"] != feedURL {
+				continue
+			}
+			published, err := time.Parse(time.RFC3339, doc.Metadata["published"])
+			if err == nil && now.Sub(published) > opts.MaxAge {
+				expired = append(expired, id)
+			}
+		}
+	}
+	c.documentsLock.RUnlock()
+
+	if len(docs) > 0 {
+		err = c.AddDocuments(ctx, docs, runtime.NumCPU())
+		if err != nil {
+			return stats, fmt.Errorf("couldn't add entries: %w", err)
+		}
+		stats.EntriesAdded = len(docs)
+	}
+	if len(expired) > 0 {
+		err = c.Delete(ctx, nil, nil, expired...)
+		if err != nil {
+			return stats, fmt.Errorf("couldn't delete expired entries: %w", err)
+		}
+		stats.EntriesExpired = len(expired)
+	}
+
+	return stats, nil
+}
+
+// parseFeed parses an RSS 2.0 or Atom feed. Entries without ID use their link
+// as ID, and entries without both are skipped.
+func parseFeed(r io.Reader) ([]feedEntry, error) {
+	var feed struct {
+		XMLName xml.Name
+		// RSS
+		Items []struct {
+			Title          string `xml:"title"`
+			Link           string `xml:"link"`
+			GUID           string `xml:"guid"`
+			PubDate        string `xml:"pubDate"`
+			Description    string `xml:"description"`
+			ContentEncoded string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+		} `xml:"channel>item"`
+		// Atom
+		Entries []struct {
+			Title string `xml:"title"`
+			Links []struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+			} `xml:"link"`
+			ID        string   `xml:"id"`
+			Published string   `xml:"published"`
+			Updated   string   `xml:"updated"`
+			Summary   atomText `xml:"summary"`
+			Content   atomText `xml:"content"`
+		} `xml:"entry"`
+	}
+	err := xml.NewDecoder(r).Decode(&feed)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode feed: %w", err)
+	}
+
+	var entries []feedEntry
+	switch feed.XMLName.Local {
+	case "rss":
+		for _, item := range feed.Items {
+			content := item.ContentEncoded
+			if content == "" {
+				content = item.Description
+			}
+			entries = append(entries, feedEntry{
+				id:        strings.TrimSpace(item.GUID),
+				title:     strings.TrimSpace(item.Title),
+				link:      strings.TrimSpace(item.Link),
+				content:   content,
+				published: parseFeedTime(item.PubDate),
+			})
+		}
+	case "feed":
+		for _, entry := range feed.Entries {
+			link := ""
+			for _, l := range entry.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			content := entry.Content.String()
+			if content == "" {
+				content = entry.Summary.String()
+			}
+			published := entry.Published
+			if published == "" {
+				published = entry.Updated
+			}
+			entries = append(entries, feedEntry{
+				id:        strings.TrimSpace(entry.ID),
+				title:     strings.TrimSpace(entry.Title),
+				link:      strings.TrimSpace(link),
+				content:   content,
+				published: parseFeedTime(published),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported feed format %q", feed.XMLName.Local)
+	}
+
+	res := entries[:0]
+	for _, e := range entries {
+		if e.id == "" {
+			e.id = e.link
+		}
+		if e.id == "" {
+			continue
+		}
+		// Feed content is often HTML.
+		_, e.content = extractHTMLText(e.content)
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// atomText is an Atom text construct. The content of the "xhtml" type consists
+// of XML elements, while the "text" and "html" types have escaped text.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return t.Inner
+	}
+	return t.Text
+}
+
+// parseFeedTime parses the date formats of RSS and Atom feeds. It returns the
+// zero time if the value can't be parsed.
+func parseFeedTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339, time.RFC1123Z, time.RFC1123, time.RFC822Z, time.RFC822, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,113 @@
+package chromem
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollection_SyncFeed(t *testing.T) {
+	ctx := context.Background()
+	recent := time.Now().Add(-time.Hour).UTC()
+	old := time.Now().Add(-30 * 24 * time.Hour).UTC()
+	feed := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+	<title>News</title>
+	<item>
+		<title>First</title>
+		<link>https://example.com/1</link>
+		<guid>1</guid>
+		<pubDate>` + recent.Format(time.RFC1123Z) + `</pubDate>
+		<description>Short</description>
+		<content:encoded><![CDATA[<p>Full <b>text</b></p>]]></content:encoded>
+	</item>
+	<item>
+		<title>Old</title>
+		<link>https://example.com/2</link>
+		<guid>2</guid>
+		<pubDate>` + old.Format(time.RFC1123Z) + `</pubDate>
+		<description>Old news</description>
+	</item>
+</channel>
+</rss>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(feed))
+	}))
+	defer srv.Close()
+
+	embedded := 0
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		embedded++
+		return []float32{float32(len(text)), 1}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	stats, err := c.SyncFeed(ctx, srv.URL, FeedOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats != (FeedSyncStats{EntriesAdded: 2}) {
+		t.Fatalf("expected 2 added entries, got %+v", stats)
+	}
+	doc := c.documents["1"]
+	if doc == nil || doc.Content != "First\n\nFull text" {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	if doc.Metadata["link"] != "https://example.com/1" || doc.Metadata["published"] != recent.Format(time.RFC3339) || doc.Metadata["feed_url"] != srv.URL {
+		t.Fatalf("unexpected metadata %v", doc.Metadata)
+	}
+
+	// Existing entries aren't added again, and old ones expire
+	embedded = 0
+	stats, err = c.SyncFeed(ctx, srv.URL, FeedOptions{MaxAge: 7 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats != (FeedSyncStats{EntriesExpired: 1}) || embedded != 0 {
+		t.Fatalf("expected 1 expired entry, got %+v and %d embeddings", stats, embedded)
+	}
+	if c.Count() != 1 {
+		t.Fatal("expected 1 document, got", c.Count())
+	}
+}
+
+func TestParseFeed_Atom(t *testing.T) {
+	entries, err := parseFeed(strings.NewReader(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Blog</title>
+	<entry>
+		<title>Post</title>
+		<link rel="self" href="https://example.com/self"/>
+		<link href="https://example.com/post"/>
+		<id>urn:uuid:1</id>
+		<updated>2024-01-02T03:04:05Z</updated>
+		<summary>Summary</summary>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Full <b>text</b></p></div></content>
+	</entry>
+	<entry>
+		<title>Without ID</title>
+		<link href="https://example.com/other"/>
+	</entry>
+</feed>`))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(entries) != 2 {
+		t.Fatal("expected 2 entries, got", len(entries))
+	}
+	e := entries[0]
+	if e.id != "urn:uuid:1" || e.title != "Post" || e.link != "https://example.com/post" || e.content != "Full text" || !e.published.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if entries[1].id != "https://example.com/other" {
+		t.Fatal("expected link as ID, got", entries[1].id)
+	}
+}
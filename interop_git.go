@@ -0,0 +1,247 @@
+package chromem
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// gitLanguages maps file extensions to the language names in the metadata of
+// documents imported with [Collection.ImportFromGit].
+var gitLanguages = map[string]string{
+	".c":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".cs":    "C#",
+	".css":   "CSS",
+	".go":    "Go",
+	".h":     "C",
+	".hpp":   "C++",
+	".html":  "HTML",
+	".java":  "Java",
+	".js":    "JavaScript",
+	".json":  "JSON",
+	".jsx":   "JavaScript",
+	".kt":    "Kotlin",
+	".md":    "Markdown",
+	".php":   "PHP",
+	".py":    "Python",
+	".rb":    "Ruby",
+	".rs":    "Rust",
+	".scala": "Scala",
+	".sh":    "Shell",
+	".sql":   "SQL",
+	".swift": "Swift",
+	".toml":  "TOML",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".yaml":  "YAML",
+	".yml":   "YAML",
+}
+
+// GitImportOptions are the options for [Collection.ImportFromGit].
+type GitImportOptions struct {
+	// Patterns of the paths to import, like "*.go" or "docs". A pattern matches
+	// the slash-separated path relative to the repository root as in
+	// [path.Match], the file name if the pattern doesn't contain a slash, or a
+	// directory and all files in it. Optional, defaults to all files.
+	Include []string
+
+	// Patterns of the paths to skip, like "vendor" or "*_test.go". Optional.
+	Exclude []string
+
+	// Files larger than this number of bytes are skipped. Optional, defaults
+	// to 1 MiB.
+	MaxFileSize int64
+
+	// The maximum size of a chunk in characters. Optional, defaults to 1000.
+	ChunkSize int
+
+	// The number of characters that consecutive chunks share. Optional,
+	// defaults to 100. Negative values disable the overlap.
+	ChunkOverlap int
+
+	// The concurrency of adding documents, i.e. of creating embeddings.
+	// Optional, defaults to [runtime.NumCPU].
+	Concurrency int
+}
+
+// ImportFromGit imports the files that are tracked in a local git repository
+// into this collection, for RAG over code. It requires the git command.
+// Binary files are skipped. The files are split into chunks, which become
+// documents with the IDs "<path>#<index>" and the metadata "path", "language"
+// (if it's known from the file extension) and "commit", which is the hash of
+// the last commit that changed the file. Existing documents with the same IDs
+// are overwritten.
+func (c *Collection) ImportFromGit(ctx context.Context, repoPath string, opts GitImportOptions) error {
+	if repoPath == "" {
+		return errors.New("repository path is empty")
+	}
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = 1 << 20
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 1000
+	}
+	if opts.ChunkOverlap == 0 {
+		opts.ChunkOverlap = 100
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = runtime.NumCPU()
+	}
+	ctx, _ = startProgress(ctx, "import", 0)
+
+	out, err := runGit(ctx, repoPath, "ls-files", "-z")
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" && matchesGitPatterns(p, opts.Include, true) && !matchesGitPatterns(p, opts.Exclude, false) {
+			files = append(files, p)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	commits, err := lastCommits(ctx, repoPath, files)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		filePath := filepath.Join(repoPath, filepath.FromSlash(p))
+		info, err := os.Lstat(filePath)
+		if err != nil {
+			// Tracked files can be deleted in the working tree.
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return fmt.Errorf("couldn't get file info of %q: %w", p, err)
+		}
+		if !info.Mode().IsRegular() || info.Size() > opts.MaxFileSize {
+			continue
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("couldn't read file %q: %w", p, err)
+		}
+		// Like git, we consider files with NUL bytes in the beginning binary.
+		if bytes.IndexByte(content[:min(len(content), 8000)], 0) != -1 {
+			continue
+		}
+
+		var docs []Document
+		for i, chunk := range splitText(string(content), opts.ChunkSize, opts.ChunkOverlap) {
+			metadata := map[string]string{
+				"path":   p,
+				"commit": commits[p],
+			}
+			if language, ok := gitLanguages[strings.ToLower(path.Ext(p))]; ok {
+				metadata["language"] = language
+			}
+			docs = append(docs, Document{
+				ID:       p + "#" + strconv.Itoa(i),
+				Metadata: metadata,
+				Content:  chunk,
+			})
+		}
+		if len(docs) == 0 {
+			continue
+		}
+		err = c.AddDocuments(ctx, docs, opts.Concurrency)
+		if err != nil {
+			return fmt.Errorf("couldn't add documents of %q: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+// matchesGitPatterns reports whether the path matches one of the patterns, or
+// returns the default if there are none.
+func matchesGitPatterns(p string, patterns []string, def bool) bool {
+	if len(patterns) == 0 {
+		return def
+	}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(pattern, "/")
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(p)); ok && !strings.Contains(pattern, "/") {
+			return true
+		}
+		if strings.HasPrefix(p, pattern+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// lastCommits returns the hash of the last commit that changed each file. It
+// walks the history from the newest commit until all files are found.
+func lastCommits(ctx context.Context, repoPath string, files []string) (map[string]string, error) {
+	missing := make(map[string]struct{}, len(files))
+	for _, p := range files {
+		missing[p] = struct{}{}
+	}
+	res := make(map[string]string, len(files))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "-c", "core.quotePath=false", "log", "--format=commit %H", "--name-only", "--no-renames")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run git log: %w", err)
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run git log: %w", err)
+	}
+
+	commit := ""
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() && len(missing) > 0 {
+		line := scanner.Text()
+		if hash, ok := strings.CutPrefix(line, "commit "); ok {
+			commit = hash
+			continue
+		}
+		if _, ok := missing[line]; ok {
+			res[line] = commit
+			delete(missing, line)
+		}
+	}
+	// Stop git if we're done early.
+	cancel()
+	_ = cmd.Wait()
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't read git log: %w", err)
+	}
+	return res, nil
+}
+
+// runGit runs a git command in the repository and returns its output.
+func runGit(ctx context.Context, repoPath string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repoPath}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,104 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCollection_ImportFromGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("expected no error, got %v: %s", err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0o700)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	git("init", "-q")
+	writeFile("main.go", "package main")
+	writeFile("docs/README.md", "# Docs")
+	writeFile("vendor/lib.go", "package lib")
+	writeFile("logo.bin", "\x00\x01\x02")
+	git("add", ".")
+	git("commit", "-q", "-m", "first")
+	first := git("rev-parse", "HEAD")
+	writeFile("main.go", "package main\n\nfunc main() {}")
+	git("commit", "-q", "-am", "second")
+	second := git("rev-parse", "HEAD")
+	writeFile("untracked.go", "package untracked")
+
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		return []float32{float32(len(text)), 1}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.ImportFromGit(ctx, dir, GitImportOptions{Exclude: []string{"vendor"}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	if c.Count() != 2 {
+		t.Fatal("expected 2 documents, got", c.Count())
+	}
+	doc := c.documents["main.go#0"]
+	if doc == nil {
+		t.Fatal("expected document main.go#0")
+	}
+	if doc.Metadata["path"] != "main.go" || doc.Metadata["language"] != "Go" || doc.Metadata["commit"] != second {
+		t.Fatalf("unexpected metadata %v", doc.Metadata)
+	}
+	doc = c.documents["docs/README.md#0"]
+	if doc == nil {
+		t.Fatal("expected document docs/README.md#0")
+	}
+	if doc.Metadata["language"] != "Markdown" || doc.Metadata["commit"] != first {
+		t.Fatalf("unexpected metadata %v", doc.Metadata)
+	}
+}
+
+func TestMatchesGitPatterns(t *testing.T) {
+	tt := []struct {
+		path     string
+		patterns []string
+		expected bool
+	}{
+		{"main.go", nil, true},
+		{"main.go", []string{"*.go"}, true},
+		{"cmd/main.go", []string{"*.go"}, true},
+		{"cmd/main.go", []string{"cmd"}, true},
+		{"cmd/main.go", []string{"cmd/"}, true},
+		{"cmd/main.go", []string{"cmd/*.go"}, true},
+		{"cmd/main.go", []string{"pkg"}, false},
+		{"cmdline/main.go", []string{"cmd"}, false},
+	}
+	for _, tc := range tt {
+		if got := matchesGitPatterns(tc.path, tc.patterns, true); got != tc.expected {
+			t.Errorf("expected %v for %q with %v, got %v", tc.expected, tc.path, tc.patterns, got)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package chromem
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// OpenAIBatchImportOptions are the options for
+// [Collection.ImportOpenAIBatchOutput] and [Collection.ImportFromOpenAIBatch].
+type OpenAIBatchImportOptions struct {
+	// CustomID returns the custom_id of the batch request that the document's
+	// embedding was requested with. Optional, defaults to the document's ID.
+	// If multiple documents were embedded with one request, with a list as
+	// input, they must have the same custom ID and be in the order of the
+	// input.
+	CustomID func(doc Document) string
+
+	// Number of documents that are added at once. Optional, defaults to 100.
+	BatchSize int
+
+	// The base URL of the API, for [Collection.ImportFromOpenAIBatch].
+	// Optional, defaults to [BaseURLOpenAI].
+	BaseURL string
+
+	// The API key, for [Collection.ImportFromOpenAIBatch]. Optional, defaults to
+	// the environment variable "OPENAI_API_KEY".
+	APIKey string
+
+	// The HTTP client to use. Optional, defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+// OpenAIBatchImportStats are the statistics of an import of OpenAI Batch API
+// output.
+type OpenAIBatchImportStats struct {
+	// The number of documents that were added.
+	Imported int
+
+	// The errors of the failed requests by custom ID, for example to retry
+	// them.
+	Failed map[string]string
+
+	// The IDs of the documents without result in the output, in the order of
+	// the documents.
+	Missing []string
+
+	// The custom IDs in the output without documents.
+	Unmatched []string
+}
+
+// openAIBatchOutputLine is a line of an OpenAI Batch API output file.
+type openAIBatchOutputLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int `json:"status_code"`
+		Body       struct {
+			Data []struct {
+				Index     int             `json:"index"`
+				Embedding json.RawMessage `json:"embedding"`
+			} `json:"data"`
+			Error *openAIBatchError `json:"error"`
+		} `json:"body"`
+	} `json:"response"`
+	Error *openAIBatchError `json:"error"`
+}
+
+type openAIBatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// readOpenAIBatchOutput decodes the lines of an OpenAI Batch API output or error
+// file and calls fn for each of them, until it returns an error.
+func readOpenAIBatchOutput(r io.Reader, fn func(lineNumber int, line openAIBatchOutputLine) error) error {
+	scanner := bufio.NewScanner(r)
+	// Lines with embeddings of many inputs can be long.
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var line openAIBatchOutputLine
+		err := json.Unmarshal(scanner.Bytes(), &line)
+		if err != nil {
+			return fmt.Errorf("couldn't decode line %d: %w", lineNumber, err)
+		}
+		if err := fn(lineNumber, line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("couldn't read batch output: %w", err)
+	}
+	return nil
+}
+
+// err returns the error of the request of the line, or an empty string if it
+// succeeded.
+func (l *openAIBatchOutputLine) err() string {
+	switch {
+	case l.Error != nil:
+		return l.Error.String()
+	case l.Response == nil:
+		return "no response"
+	case l.Response.Body.Error != nil:
+		return l.Response.Body.Error.String()
+	case l.Response.StatusCode != http.StatusOK:
+		return fmt.Sprintf("unexpected response status: %d", l.Response.StatusCode)
+	}
+	return ""
+}
+
+func (e *openAIBatchError) String() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return e.Code + ": " + e.Message
+}
+
+// ImportOpenAIBatchOutput adds the documents with the embeddings from the
+// output file of an OpenAI Batch API (https://platform.openai.com/docs/guides/batch)
+// job of the /v1/embeddings endpoint, which is cheaper than embedding them
+// synchronously. The output is JSONL, and the results are matched to the
+// documents by their custom ID, see [OpenAIBatchImportOptions.CustomID].
+// Embeddings encoded as float lists and as base64 are supported.
+//
+// Failed requests, documents without result and results without document
+// don't stop the import, and are returned in the stats instead. Documents are
+// added in batches, like with [Collection.AddDocuments].
+func (c *Collection) ImportOpenAIBatchOutput(ctx context.Context, r io.Reader, docs []Document, opts OpenAIBatchImportOptions) (OpenAIBatchImportStats, error) {
+	customID := opts.CustomID
+	if customID == nil {
+		customID = func(doc Document) string { return doc.ID }
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = interopDefaultBatchSize
+	}
+
+	byCustomID := make(map[string][]int)
+	for i, doc := range docs {
+		id := customID(doc)
+		byCustomID[id] = append(byCustomID[id], i)
+	}
+	matched := make([]bool, len(docs))
+
+	stats := OpenAIBatchImportStats{}
+	fail := func(customID, err string) {
+		if stats.Failed == nil {
+			stats.Failed = make(map[string]string)
+		}
+		stats.Failed[customID] = err
+	}
+	var batch []Document
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := c.AddDocuments(ctx, batch, runtime.NumCPU())
+		if err != nil {
+			return fmt.Errorf("couldn't add documents: %w", err)
+		}
+		stats.Imported += len(batch)
+		batch = nil
+		return nil
+	}
+
+	err := readOpenAIBatchOutput(r, func(lineNumber int, line openAIBatchOutputLine) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		indexes, ok := byCustomID[line.CustomID]
+		if !ok {
+			stats.Unmatched = append(stats.Unmatched, line.CustomID)
+			return nil
+		}
+		if err := line.err(); err != "" {
+			fail(line.CustomID, err)
+			return nil
+		}
+
+		for _, data := range line.Response.Body.Data {
+			if data.Index < 0 || data.Index >= len(indexes) {
+				fail(line.CustomID, fmt.Sprintf("no document for embedding %d", data.Index))
+				continue
+			}
+			embedding, err := decodeOpenAIEmbedding(data.Embedding)
+			if err != nil {
+				return fmt.Errorf("couldn't decode embedding of %q on line %d: %w", line.CustomID, lineNumber, err)
+			}
+			i := indexes[data.Index]
+			doc := docs[i]
+			doc.Embedding = embedding
+			batch = append(batch, doc)
+			matched[i] = true
+		}
+		if len(batch) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+
+	for i, doc := range docs {
+		if !matched[i] {
+			stats.Missing = append(stats.Missing, doc.ID)
+		}
+	}
+	return stats, nil
+}
+
+// ImportFromOpenAIBatch downloads the output file with the given ID of an
+// OpenAI Batch API job via the /v1/files endpoint, and imports it like
+// [Collection.ImportOpenAIBatchOutput]. The file ID is the output_file_id of
+// the completed batch.
+func (c *Collection) ImportFromOpenAIBatch(ctx context.Context, outputFileID string, docs []Document, opts OpenAIBatchImportOptions) (OpenAIBatchImportStats, error) {
+	if outputFileID == "" {
+		return OpenAIBatchImportStats{}, errors.New("outputFileID is empty")
+	}
+	body, err := openAIFileContent(ctx, opts.BaseURL, opts.APIKey, opts.HTTPClient, outputFileID)
+	if err != nil {
+		return OpenAIBatchImportStats{}, err
+	}
+	defer body.Close()
+
+	return c.ImportOpenAIBatchOutput(ctx, body, docs, opts)
+}
+
+// openAIFileContent returns the content of the file with the given ID of the
+// OpenAI Files API. The caller must close it.
+func openAIFileContent(ctx context.Context, baseURL, apiKey string, client *http.Client, fileID string) (io.ReadCloser, error) {
+	baseURL, apiKey, client = openAIDefaults(baseURL, apiKey, client)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/files/"+fileID+"/content", nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLen))
+		return nil, fmt.Errorf("couldn't download file %q: %w", fileID, &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(b)})
+	}
+	return resp.Body, nil
+}
+
+// openAIDefaults returns the base URL, API key and HTTP client, with the
+// defaults for the empty ones.
+func openAIDefaults(baseURL, apiKey string, client *http.Client) (string, string, *http.Client) {
+	if baseURL == "" {
+		baseURL = BaseURLOpenAI
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return baseURL, apiKey, client
+}
+
+// decodeOpenAIEmbedding decodes an embedding of the OpenAI API, which is either
+// a list of floats or a base64 encoded string of little-endian float32 values.
+func decodeOpenAIEmbedding(raw json.RawMessage) ([]float32, error) {
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		var embedding []float32
+		if err := json.Unmarshal(raw, &embedding); err != nil {
+			return nil, err
+		}
+		if len(embedding) == 0 {
+			return nil, errors.New("embedding is empty")
+		}
+		return embedding, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode base64: %w", err)
+	}
+	if len(b) == 0 || len(b)%4 != 0 {
+		return nil, fmt.Errorf("invalid length of base64 embedding: %d bytes", len(b))
+	}
+	embedding := make([]float32, len(b)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return embedding, nil
+}
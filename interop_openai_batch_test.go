@@ -0,0 +1,101 @@
+package chromem
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testOpenAIBatchOutput = `{"id":"batch_req_1","custom_id":"1","response":{"status_code":200,"request_id":"r1","body":{"object":"list","data":[{"object":"embedding","index":0,"embedding":[1,0]}],"model":"text-embedding-3-small"}},"error":null}
+{"id":"batch_req_2","custom_id":"group","response":{"status_code":200,"request_id":"r2","body":{"object":"list","data":[{"object":"embedding","index":1,"embedding":"%s"},{"object":"embedding","index":0,"embedding":[0,1]}]}},"error":null}
+{"id":"batch_req_3","custom_id":"4","response":{"status_code":400,"request_id":"r3","body":{"error":{"code":"invalid_request","message":"input too long"}}},"error":null}
+
+{"id":"batch_req_4","custom_id":"unknown","response":{"status_code":200,"body":{"data":[{"index":0,"embedding":[1,0]}]}},"error":null}
+`
+
+func TestCollection_ImportOpenAIBatchOutput(t *testing.T) {
+	ctx := context.Background()
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b, math.Float32bits(0.6))
+	binary.LittleEndian.PutUint32(b[4:], math.Float32bits(0.8))
+	output := strings.Replace(testOpenAIBatchOutput, "%s", base64.StdEncoding.EncodeToString(b), 1)
+
+	docs := []Document{
+		{ID: "1", Content: "foo"},
+		{ID: "2", Content: "bar", Metadata: map[string]string{"request": "group"}},
+		{ID: "3", Content: "baz", Metadata: map[string]string{"request": "group"}},
+		{ID: "4", Content: "qux"},
+		{ID: "5", Content: "quux"},
+	}
+	opts := OpenAIBatchImportOptions{
+		CustomID: func(doc Document) string {
+			if request, ok := doc.Metadata["request"]; ok {
+				return request
+			}
+			return doc.ID
+		},
+	}
+
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	stats, err := c.ImportOpenAIBatchOutput(ctx, strings.NewReader(output), docs, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats.Imported != 3 {
+		t.Fatal("expected 3 imported documents, got", stats.Imported)
+	}
+	if stats.Failed["4"] != "invalid_request: input too long" || len(stats.Failed) != 1 {
+		t.Fatal("expected failed request 4, got", stats.Failed)
+	}
+	if len(stats.Missing) != 2 || stats.Missing[0] != "4" || stats.Missing[1] != "5" {
+		t.Fatal("expected missing documents 4 and 5, got", stats.Missing)
+	}
+	if len(stats.Unmatched) != 1 || stats.Unmatched[0] != "unknown" {
+		t.Fatal("expected unmatched custom ID, got", stats.Unmatched)
+	}
+	if c.Count() != 3 {
+		t.Fatal("expected 3 documents, got", c.Count())
+	}
+	if e := c.documents["2"].Embedding; e[0] != 0 || e[1] != 1 {
+		t.Fatal("expected embedding with index 0 for document 2, got", e)
+	}
+	if e := c.documents["3"].Embedding; e[0] != 0.6 || e[1] != 0.8 {
+		t.Fatal("expected base64 embedding with index 1 for document 3, got", e)
+	}
+
+	// From the Files API
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/file-abc/content" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(output))
+	}))
+	defer ts.Close()
+
+	c, err = NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	opts.BaseURL = ts.URL
+	opts.APIKey = "secret"
+	stats, err = c.ImportFromOpenAIBatch(ctx, "file-abc", docs, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats.Imported != 3 || c.Count() != 3 {
+		t.Fatal("expected 3 imported documents, got", stats.Imported, c.Count())
+	}
+	_, err = c.ImportFromOpenAIBatch(ctx, "file-unknown", docs, opts)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,167 @@
+package chromem
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+)
+
+// PineconeOptions are the options for [Collection.ImportFromPinecone] and
+// [Collection.ExportToPinecone].
+type PineconeOptions struct {
+	// The host of the Pinecone index, e.g. "https://my-index-abc123.svc.us-east-1-aws.pinecone.io".
+	// Mandatory.
+	IndexHost string
+
+	// The API key, sent as "Api-Key" header. Mandatory for the Pinecone service.
+	APIKey string
+
+	// The namespace within the index. Optional, defaults to the default namespace.
+	Namespace string
+
+	// The metadata key holding the document content. Optional, defaults to "content".
+	ContentKey string
+
+	// Number of vectors per request. Optional, defaults to 100, which is the
+	// maximum for listing and fetching.
+	BatchSize int
+
+	// The HTTP client to use. Optional, defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+type pineconeVector struct {
+	ID       string         `json:"id"`
+	Values   []float32      `json:"values"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// ImportFromPinecone imports all vectors of a Pinecone (https://www.pinecone.io/)
+// serverless index namespace as documents into this collection. The metadata is
+// kept, except for the content, which is read from opts.ContentKey.
+// Lists in the metadata are encoded as JSON strings.
+func (c *Collection) ImportFromPinecone(ctx context.Context, opts PineconeOptions) error {
+	baseURL, client, batchSize, contentKey, err := interopDefaults(opts.IndexHost, opts.HTTPClient, opts.BatchSize, opts.ContentKey)
+	if err != nil {
+		return err
+	}
+	headers := pineconeHeaders(opts.APIKey)
+	ctx, _ = startProgress(ctx, "import", 0)
+
+	// Pinecone doesn't have an endpoint to get all vectors at once, so we list
+	// the IDs page by page and fetch the vectors for each page.
+	paginationToken := ""
+	for {
+		q := url.Values{}
+		q.Set("namespace", opts.Namespace)
+		q.Set("limit", strconv.Itoa(batchSize))
+		if paginationToken != "" {
+			q.Set("paginationToken", paginationToken)
+		}
+		var listRes struct {
+			Vectors []struct {
+				ID string `json:"id"`
+			} `json:"vectors"`
+			Pagination *struct {
+				Next string `json:"next"`
+			} `json:"pagination"`
+		}
+		err := doJSONRequest(ctx, client, http.MethodGet, baseURL+"/vectors/list?"+q.Encode(), headers, nil, &listRes)
+		if err != nil {
+			return fmt.Errorf("couldn't list Pinecone vectors: %w", err)
+		}
+
+		if len(listRes.Vectors) > 0 {
+			q := url.Values{}
+			q.Set("namespace", opts.Namespace)
+			for _, v := range listRes.Vectors {
+				q.Add("ids", v.ID)
+			}
+			var fetchRes struct {
+				Vectors map[string]pineconeVector `json:"vectors"`
+			}
+			err = doJSONRequest(ctx, client, http.MethodGet, baseURL+"/vectors/fetch?"+q.Encode(), headers, nil, &fetchRes)
+			if err != nil {
+				return fmt.Errorf("couldn't fetch Pinecone vectors: %w", err)
+			}
+
+			docs := make([]Document, 0, len(fetchRes.Vectors))
+			for _, v := range listRes.Vectors {
+				pv, ok := fetchRes.Vectors[v.ID]
+				if !ok {
+					// Deleted between listing and fetching
+					continue
+				}
+				_, content, metadata := splitInteropPayload(pv.Metadata, contentKey)
+				docs = append(docs, Document{
+					ID:        pv.ID,
+					Metadata:  metadata,
+					Embedding: pv.Values,
+					Content:   content,
+				})
+			}
+			if len(docs) > 0 {
+				err = c.AddDocuments(ctx, docs, runtime.NumCPU())
+				if err != nil {
+					return fmt.Errorf("couldn't add documents: %w", err)
+				}
+			}
+		}
+
+		if listRes.Pagination == nil || listRes.Pagination.Next == "" {
+			return nil
+		}
+		paginationToken = listRes.Pagination.Next
+	}
+}
+
+// ExportToPinecone upserts all documents of this collection as vectors into a
+// Pinecone (https://www.pinecone.io/) index namespace. The index must already
+// exist, with the dimension of the collection's embeddings and cosine or dot
+// product as metric. The content is stored in the metadata key opts.ContentKey.
+// Note that Pinecone limits the metadata size to 40 KB per vector.
+func (c *Collection) ExportToPinecone(ctx context.Context, opts PineconeOptions) error {
+	baseURL, client, batchSize, contentKey, err := interopDefaults(opts.IndexHost, opts.HTTPClient, opts.BatchSize, opts.ContentKey)
+	if err != nil {
+		return err
+	}
+	headers := pineconeHeaders(opts.APIKey)
+
+	docs := c.sortedDocuments()
+	for start := 0; start < len(docs); start += batchSize {
+		end := min(start+batchSize, len(docs))
+		vectors := make([]pineconeVector, 0, end-start)
+		for _, doc := range docs[start:end] {
+			// The content might be spilled to disk
+			doc, err := c.withContent(doc)
+			if err != nil {
+				return err
+			}
+			vectors = append(vectors, pineconeVector{
+				ID:       doc.ID,
+				Values:   doc.Embedding,
+				Metadata: interopPayload(doc, contentKey, false),
+			})
+		}
+		reqBody := map[string]any{
+			"vectors":   vectors,
+			"namespace": opts.Namespace,
+		}
+		err := doJSONRequest(ctx, client, http.MethodPost, baseURL+"/vectors/upsert", headers, reqBody, nil)
+		if err != nil {
+			return fmt.Errorf("couldn't upsert Pinecone vectors: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func pineconeHeaders(apiKey string) map[string]string {
+	if apiKey == "" {
+		return nil
+	}
+	return map[string]string{"Api-Key": apiKey}
+}
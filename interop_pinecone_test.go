@@ -0,0 +1,107 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCollection_ExportImportPinecone(t *testing.T) {
+	ctx := context.Background()
+
+	// Fake Pinecone index that keeps the vectors of one namespace in memory.
+	vectors := map[string]pineconeVector{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		q := r.URL.Query()
+		switch r.Method + " " + r.URL.Path {
+		case "POST /vectors/upsert":
+			var req struct {
+				Vectors   []pineconeVector `json:"vectors"`
+				Namespace string           `json:"namespace"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if req.Namespace != "ns" {
+				t.Fatal("unexpected namespace", req.Namespace)
+			}
+			for _, v := range req.Vectors {
+				vectors[v.ID] = v
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"upsertedCount": len(req.Vectors)})
+		case "GET /vectors/list":
+			ids := make([]string, 0, len(vectors))
+			for id := range vectors {
+				ids = append(ids, id)
+			}
+			slices.Sort(ids)
+			limit, _ := strconv.Atoi(q.Get("limit"))
+			start, _ := strconv.Atoi(q.Get("paginationToken"))
+			end := min(start+limit, len(ids))
+			res := map[string]any{}
+			list := []map[string]string{}
+			for _, id := range ids[start:end] {
+				list = append(list, map[string]string{"id": id})
+			}
+			res["vectors"] = list
+			if end < len(ids) {
+				res["pagination"] = map[string]string{"next": strconv.Itoa(end)}
+			}
+			writeJSON(w, http.StatusOK, res)
+		case "GET /vectors/fetch":
+			res := map[string]pineconeVector{}
+			for _, id := range q["ids"] {
+				res[id] = vectors[id]
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"vectors": res, "namespace": q.Get("namespace")})
+		default:
+			t.Fatal("unexpected request", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	src := newInteropTestCollection(t)
+	opts := PineconeOptions{IndexHost: ts.URL, APIKey: "secret", Namespace: "ns", BatchSize: 2}
+	err := src.ExportToPinecone(ctx, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(vectors) != 3 {
+		t.Fatal("expected 3 vectors, got", len(vectors))
+	}
+	// Pinecone supports arbitrary string IDs, so they're kept as they are
+	if v, ok := vectors["doc-a"]; !ok || v.Metadata["content"] != "Hello" {
+		t.Fatalf("unexpected vector: %+v", v)
+	}
+
+	dst := newInteropTestCollection(t)
+	dst.documents = map[string]*Document{}
+	err = dst.ImportFromPinecone(ctx, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	assertInteropDocs(t, src, dst)
+}
+
+func TestCollection_ExportToPinecone_error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid API key"}`))
+	}))
+	defer ts.Close()
+
+	c := newInteropTestCollection(t)
+	err := c.ExportToPinecone(context.Background(), PineconeOptions{IndexHost: ts.URL})
+	if err == nil || !strings.Contains(err.Error(), "invalid API key") {
+		t.Fatal("expected error with response body, got", err)
+	}
+}
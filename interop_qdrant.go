@@ -0,0 +1,219 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// QdrantOptions are the options for [Collection.ImportFromQdrant] and
+// [Collection.ExportToQdrant].
+type QdrantOptions struct {
+	// The URL of the Qdrant server, e.g. "http://localhost:6333". Mandatory.
+	BaseURL string
+
+	// The API key, sent as "api-key" header. Optional.
+	APIKey string
+
+	// The name of the Qdrant collection. Mandatory.
+	Collection string
+
+	// The name of the vector, for Qdrant collections with named vectors. Optional.
+	VectorName string
+
+	// The payload key holding the document content. Optional, defaults to "content".
+	ContentKey string
+
+	// Number of points per request. Optional, defaults to 100.
+	BatchSize int
+
+	// The HTTP client to use. Optional, defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+type qdrantPoint struct {
+	ID      any             `json:"id"`
+	Vector  json.RawMessage `json:"vector"`
+	Payload map[string]any  `json:"payload"`
+}
+
+// ImportFromQdrant imports all points of a Qdrant (https://qdrant.tech/)
+// collection as documents into this collection. The payload becomes the
+// metadata, except for the content, which is read from opts.ContentKey.
+// Points that were exported with [Collection.ExportToQdrant] get their original
+// document IDs back, others use the Qdrant point ID.
+func (c *Collection) ImportFromQdrant(ctx context.Context, opts QdrantOptions) error {
+	baseURL, client, batchSize, contentKey, err := interopDefaults(opts.BaseURL, opts.HTTPClient, opts.BatchSize, opts.ContentKey)
+	if err != nil {
+		return err
+	}
+	if opts.Collection == "" {
+		return errors.New("Qdrant collection name is empty")
+	}
+	headers := qdrantHeaders(opts.APIKey)
+	scrollURL := baseURL + "/collections/" + url.PathEscape(opts.Collection) + "/points/scroll"
+	ctx, _ = startProgress(ctx, "import", 0)
+
+	var offset any
+	for {
+		reqBody := map[string]any{
+			"limit":        batchSize,
+			"with_payload": true,
+			"with_vector":  true,
+		}
+		if offset != nil {
+			reqBody["offset"] = offset
+		}
+		var res struct {
+			Result struct {
+				Points         []qdrantPoint `json:"points"`
+				NextPageOffset any           `json:"next_page_offset"`
+			} `json:"result"`
+		}
+		err := doJSONRequest(ctx, client, http.MethodPost, scrollURL, headers, reqBody, &res)
+		if err != nil {
+			return fmt.Errorf("couldn't scroll Qdrant points: %w", err)
+		}
+
+		docs := make([]Document, 0, len(res.Result.Points))
+		for _, p := range res.Result.Points {
+			vector, err := qdrantVector(p.Vector, opts.VectorName)
+			if err != nil {
+				return fmt.Errorf("couldn't read vector of point %v: %w", p.ID, err)
+			}
+			id, content, metadata := splitInteropPayload(p.Payload, contentKey)
+			if id == "" {
+				id = fmt.Sprint(p.ID)
+			}
+			docs = append(docs, Document{
+				ID:        id,
+				Metadata:  metadata,
+				Embedding: vector,
+				Content:   content,
+			})
+		}
+		if len(docs) > 0 {
+			err = c.AddDocuments(ctx, docs, runtime.NumCPU())
+			if err != nil {
+				return fmt.Errorf("couldn't add documents: %w", err)
+			}
+		}
+
+		if res.Result.NextPageOffset == nil {
+			return nil
+		}
+		offset = res.Result.NextPageOffset
+	}
+}
+
+// ExportToQdrant exports all documents of this collection as points to a Qdrant
+// (https://qdrant.tech/) collection, which is created with cosine distance if
+// it doesn't exist yet. Qdrant only supports unsigned integers and UUIDs as point
+// IDs, so other document IDs are converted to deterministic UUIDs, with the
+// original ID stored in the "chromem_id" payload field. The content is stored
+// in the payload field opts.ContentKey.
+func (c *Collection) ExportToQdrant(ctx context.Context, opts QdrantOptions) error {
+	baseURL, client, batchSize, contentKey, err := interopDefaults(opts.BaseURL, opts.HTTPClient, opts.BatchSize, opts.ContentKey)
+	if err != nil {
+		return err
+	}
+	if opts.Collection == "" {
+		return errors.New("Qdrant collection name is empty")
+	}
+	headers := qdrantHeaders(opts.APIKey)
+	collectionURL := baseURL + "/collections/" + url.PathEscape(opts.Collection)
+
+	docs := c.sortedDocuments()
+	if len(docs) == 0 {
+		return nil
+	}
+
+	// Create the collection if it doesn't exist yet
+	err = doJSONRequest(ctx, client, http.MethodGet, collectionURL, headers, nil, nil)
+	if err != nil {
+		var statusErr *httpStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("couldn't get Qdrant collection: %w", err)
+		}
+		vectorParams := map[string]any{"size": len(docs[0].Embedding), "distance": "Cosine"}
+		var vectors any = vectorParams
+		if opts.VectorName != "" {
+			vectors = map[string]any{opts.VectorName: vectorParams}
+		}
+		err = doJSONRequest(ctx, client, http.MethodPut, collectionURL, headers, map[string]any{"vectors": vectors}, nil)
+		if err != nil {
+			return fmt.Errorf("couldn't create Qdrant collection: %w", err)
+		}
+	}
+
+	for start := 0; start < len(docs); start += batchSize {
+		end := min(start+batchSize, len(docs))
+		points := make([]map[string]any, 0, end-start)
+		for _, doc := range docs[start:end] {
+			// The content might be spilled to disk
+			doc, err := c.withContent(doc)
+			if err != nil {
+				return err
+			}
+			var id any
+			withID := false
+			if n, err := strconv.ParseUint(doc.ID, 10, 64); err == nil && strconv.FormatUint(n, 10) == doc.ID {
+				id = n
+			} else if isUUID(doc.ID) && doc.ID == strings.ToLower(doc.ID) {
+				id = doc.ID
+			} else {
+				id = uuidFromString(doc.ID)
+				withID = true
+			}
+			var vector any = doc.Embedding
+			if opts.VectorName != "" {
+				vector = map[string]any{opts.VectorName: doc.Embedding}
+			}
+			points = append(points, map[string]any{
+				"id":      id,
+				"vector":  vector,
+				"payload": interopPayload(doc, contentKey, withID),
+			})
+		}
+		err := doJSONRequest(ctx, client, http.MethodPut, collectionURL+"/points?wait=true", headers, map[string]any{"points": points}, nil)
+		if err != nil {
+			return fmt.Errorf("couldn't upsert Qdrant points: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func qdrantHeaders(apiKey string) map[string]string {
+	if apiKey == "" {
+		return nil
+	}
+	return map[string]string{"api-key": apiKey}
+}
+
+// qdrantVector decodes a point's vector, which is either a plain vector or a
+// map of named vectors.
+func qdrantVector(raw json.RawMessage, name string) ([]float32, error) {
+	if name == "" {
+		var v []float32
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, errors.New("vector isn't a plain vector, set the vector name")
+		}
+		return v, nil
+	}
+	var named map[string][]float32
+	if err := json.Unmarshal(raw, &named); err != nil {
+		return nil, errors.New("vector isn't a map of named vectors")
+	}
+	v, ok := named[name]
+	if !ok {
+		return nil, fmt.Errorf("no vector with name %q", name)
+	}
+	return v, nil
+}
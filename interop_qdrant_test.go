@@ -0,0 +1,108 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestCollection_ExportImportQdrant(t *testing.T) {
+	ctx := context.Background()
+
+	// Fake Qdrant server that keeps the points in memory and serves them one
+	// page of 2 at a time.
+	created := false
+	var points []qdrantPoint
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /collections/test":
+			if !created {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"result":{}}`))
+		case "PUT /collections/test":
+			created = true
+			_, _ = w.Write([]byte(`{"result":true}`))
+		case "PUT /collections/test/points":
+			var req struct {
+				Points []qdrantPoint `json:"points"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			points = append(points, req.Points...)
+			_, _ = w.Write([]byte(`{"result":{"status":"completed"}}`))
+		case "POST /collections/test/points/scroll":
+			var req struct {
+				Offset int `json:"offset"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			end := min(req.Offset+2, len(points))
+			var next any
+			if end < len(points) {
+				next = end
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"result": map[string]any{"points": points[req.Offset:end], "next_page_offset": next}})
+		default:
+			t.Fatal("unexpected request", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	src := newInteropTestCollection(t)
+	opts := QdrantOptions{BaseURL: ts.URL, APIKey: "secret", Collection: "test", BatchSize: 2}
+	err := src.ExportToQdrant(ctx, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !created {
+		t.Fatal("expected collection to be created")
+	}
+	if len(points) != 3 {
+		t.Fatal("expected 3 points, got", len(points))
+	}
+	// Integer IDs are kept, others are converted to UUIDs
+	ids := []string{}
+	for _, p := range points {
+		ids = append(ids, string(mustMarshal(p.ID)))
+	}
+	if !slices.Contains(ids, "42") || !slices.Contains(ids, `"`+uuidFromString("doc-a")+`"`) {
+		t.Fatal("unexpected point IDs", ids)
+	}
+
+	dst := newInteropTestCollection(t)
+	dst.documents = map[string]*Document{}
+	err = dst.ImportFromQdrant(ctx, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	assertInteropDocs(t, src, dst)
+}
+
+func TestQdrantVector(t *testing.T) {
+	v, err := qdrantVector(json.RawMessage(`{"text":[1,2],"image":[3]}`), "text")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !slices.Equal(v, []float32{1, 2}) {
+		t.Fatal("unexpected vector", v)
+	}
+	_, err = qdrantVector(json.RawMessage(`{"text":[1,2]}`), "")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	_, err = qdrantVector(json.RawMessage(`[1,2]`), "text")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
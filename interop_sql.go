@@ -0,0 +1,193 @@
+package chromem
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+)
+
+// SQLImportOptions are the options for [Collection.ImportFromSQL].
+type SQLImportOptions struct {
+	// The query that selects the documents, e.g.
+	// "SELECT id, body, author, updated_at FROM articles WHERE updated_at > $1 ORDER BY updated_at".
+	// Mandatory.
+	Query string
+
+	// The arguments of the query, for example the checkpoint of the previous
+	// import. Optional.
+	Args []any
+
+	// The column holding the document ID. Optional, defaults to "id".
+	IDColumn string
+
+	// The column holding the document content. Optional, defaults to "content".
+	ContentColumn string
+
+	// The columns that become the document metadata. Optional, defaults to all
+	// other columns. NULL values are omitted.
+	MetadataColumns []string
+
+	// The column whose value of the last imported row is the checkpoint, for
+	// example a monotonically increasing "updated_at" column that the query
+	// orders by. Optional.
+	CheckpointColumn string
+
+	// OnCheckpoint is called with the checkpoint after each batch of documents
+	// has been added, for example to persist it so that the next import can
+	// continue from there. If it returns an error, the import is aborted.
+	// Optional.
+	OnCheckpoint func(ctx context.Context, checkpoint string) error
+
+	// Number of documents per AddDocuments batch. Optional, defaults to 100.
+	BatchSize int
+
+	// The concurrency of adding documents, i.e. of creating embeddings.
+	// Optional, defaults to [runtime.NumCPU].
+	Concurrency int
+}
+
+// ImportFromSQL runs a query on a SQL database, for example Postgres or MySQL,
+// and adds the rows as documents to this collection, in batches. Documents
+// with existing IDs are overwritten, so the import can be used to sync a
+// knowledge base. The embeddings are created with the collection's embedding
+// function.
+//
+// Values are converted to strings like with [sql.NullString]. Rows with NULL ID
+// are an error, rows with NULL content get empty content.
+//
+// With opts.CheckpointColumn, the checkpoint of the last imported row is
+// returned, and passed to opts.OnCheckpoint after each batch. To sync
+// incrementally, pass it as argument to a query that only selects newer rows.
+func (c *Collection) ImportFromSQL(ctx context.Context, db *sql.DB, opts SQLImportOptions) (string, error) {
+	if db == nil {
+		return "", errors.New("db is nil")
+	}
+	if opts.Query == "" {
+		return "", errors.New("query is empty")
+	}
+	if opts.IDColumn == "" {
+		opts.IDColumn = "id"
+	}
+	if opts.ContentColumn == "" {
+		opts.ContentColumn = "content"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = interopDefaultBatchSize
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = runtime.NumCPU()
+	}
+	ctx, _ = startProgress(ctx, "import", 0)
+
+	rows, err := db.QueryContext(ctx, opts.Query, opts.Args...)
+	if err != nil {
+		return "", fmt.Errorf("couldn't run query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("couldn't get columns: %w", err)
+	}
+	idIdx := slices.Index(columns, opts.IDColumn)
+	if idIdx == -1 {
+		return "", fmt.Errorf("ID column %q is missing in the result", opts.IDColumn)
+	}
+	contentIdx := slices.Index(columns, opts.ContentColumn)
+	if contentIdx == -1 {
+		return "", fmt.Errorf("content column %q is missing in the result", opts.ContentColumn)
+	}
+	checkpointIdx := -1
+	if opts.CheckpointColumn != "" {
+		checkpointIdx = slices.Index(columns, opts.CheckpointColumn)
+		if checkpointIdx == -1 {
+			return "", fmt.Errorf("checkpoint column %q is missing in the result", opts.CheckpointColumn)
+		}
+	}
+	var metadataIdxs []int
+	if len(opts.MetadataColumns) == 0 {
+		for i := range columns {
+			if i != idIdx && i != contentIdx {
+				metadataIdxs = append(metadataIdxs, i)
+			}
+		}
+	} else {
+		for _, col := range opts.MetadataColumns {
+			i := slices.Index(columns, col)
+			if i == -1 {
+				return "", fmt.Errorf("metadata column %q is missing in the result", col)
+			}
+			metadataIdxs = append(metadataIdxs, i)
+		}
+	}
+
+	checkpoint := ""
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	docs := make([]Document, 0, opts.BatchSize)
+	flush := func(rowCheckpoint string) error {
+		if len(docs) == 0 {
+			return nil
+		}
+		err := c.AddDocuments(ctx, docs, opts.Concurrency)
+		if err != nil {
+			return fmt.Errorf("couldn't add documents: %w", err)
+		}
+		docs = docs[:0]
+		checkpoint = rowCheckpoint
+		if opts.OnCheckpoint != nil && checkpointIdx != -1 {
+			err = opts.OnCheckpoint(ctx, checkpoint)
+			if err != nil {
+				return fmt.Errorf("couldn't save checkpoint: %w", err)
+			}
+		}
+		return nil
+	}
+
+	rowCheckpoint := ""
+	for rows.Next() {
+		err := rows.Scan(dest...)
+		if err != nil {
+			return checkpoint, fmt.Errorf("couldn't scan row: %w", err)
+		}
+		if !values[idIdx].Valid {
+			return checkpoint, errors.New("row has NULL ID")
+		}
+		doc := Document{
+			ID:       values[idIdx].String,
+			Metadata: make(map[string]string, len(metadataIdxs)),
+			Content:  values[contentIdx].String,
+		}
+		for _, i := range metadataIdxs {
+			if values[i].Valid {
+				doc.Metadata[columns[i]] = values[i].String
+			}
+		}
+		docs = append(docs, doc)
+		if checkpointIdx != -1 {
+			rowCheckpoint = values[checkpointIdx].String
+		}
+
+		if len(docs) == opts.BatchSize {
+			err := flush(rowCheckpoint)
+			if err != nil {
+				return checkpoint, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return checkpoint, fmt.Errorf("couldn't read rows: %w", err)
+	}
+
+	err = flush(rowCheckpoint)
+	if err != nil {
+		return checkpoint, err
+	}
+	return checkpoint, nil
+}
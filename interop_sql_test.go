@@ -0,0 +1,131 @@
+package chromem
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeSQLDriver is a database/sql driver that returns fixed rows for all
+// queries, as the standard library doesn't come with a driver.
+type fakeSQLDriver struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (d *fakeSQLDriver) Open(string) (driver.Conn, error) { return &fakeSQLConn{d}, nil }
+
+type fakeSQLConn struct{ d *fakeSQLDriver }
+
+func (c *fakeSQLConn) Prepare(string) (driver.Stmt, error) { return &fakeSQLStmt{c.d}, nil }
+func (c *fakeSQLConn) Close() error                        { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type fakeSQLStmt struct{ d *fakeSQLDriver }
+
+func (s *fakeSQLStmt) Close() error  { return nil }
+func (s *fakeSQLStmt) NumInput() int { return -1 }
+func (s *fakeSQLStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s *fakeSQLStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeSQLRows{d: s.d}, nil
+}
+
+type fakeSQLRows struct {
+	d *fakeSQLDriver
+	i int
+}
+
+func (r *fakeSQLRows) Columns() []string { return r.d.columns }
+func (r *fakeSQLRows) Close() error      { return nil }
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if r.i == len(r.d.rows) {
+		return io.EOF
+	}
+	copy(dest, r.d.rows[r.i])
+	r.i++
+	return nil
+}
+
+func TestCollection_ImportFromSQL(t *testing.T) {
+	ctx := context.Background()
+	d := &fakeSQLDriver{
+		columns: []string{"id", "body", "author", "updated_at"},
+		rows: [][]driver.Value{
+			{int64(1), "hello", "alice", "2024-01-01"},
+			{int64(2), "world", nil, "2024-01-02"},
+			{int64(3), "foo", "bob", "2024-01-03"},
+		},
+	}
+	sql.Register("chromem-fake-"+t.Name(), d)
+	sqlDB, err := sql.Open("chromem-fake-"+t.Name(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer sqlDB.Close()
+
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		return []float32{float32(len(text)), 1}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	var checkpoints []string
+	checkpoint, err := c.ImportFromSQL(ctx, sqlDB, SQLImportOptions{
+		Query:            "SELECT id, body, author, updated_at FROM articles ORDER BY updated_at",
+		ContentColumn:    "body",
+		MetadataColumns:  []string{"author"},
+		CheckpointColumn: "updated_at",
+		OnCheckpoint: func(_ context.Context, checkpoint string) error {
+			checkpoints = append(checkpoints, checkpoint)
+			return nil
+		},
+		BatchSize: 2,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if checkpoint != "2024-01-03" {
+		t.Fatal("expected checkpoint 2024-01-03, got", checkpoint)
+	}
+	if !slices.Equal([]string{"2024-01-02", "2024-01-03"}, checkpoints) {
+		t.Fatal("expected a checkpoint per batch, got", checkpoints)
+	}
+	if c.Count() != 3 {
+		t.Fatal("expected 3 documents, got", c.Count())
+	}
+	doc := c.documents["1"]
+	if doc.Content != "hello" || doc.Metadata["author"] != "alice" || len(doc.Metadata) != 1 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	if _, ok := c.documents["2"].Metadata["author"]; ok {
+		t.Fatal("expected NULL metadata to be omitted")
+	}
+
+	// Missing columns
+	_, err = c.ImportFromSQL(ctx, sqlDB, SQLImportOptions{Query: "SELECT 1"})
+	if err == nil || !strings.Contains(err.Error(), `content column "content"`) {
+		t.Fatal("expected missing content column error, got", err)
+	}
+
+	// Failing checkpoints abort the import
+	_, err = c.ImportFromSQL(ctx, sqlDB, SQLImportOptions{
+		Query:            "SELECT 1",
+		ContentColumn:    "body",
+		CheckpointColumn: "updated_at",
+		OnCheckpoint: func(context.Context, string) error {
+			return errors.New("disk full")
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,72 @@
+package chromem
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// newInteropTestCollection returns a collection with three documents that
+// cover the different ID formats the other vector DBs treat differently.
+func newInteropTestCollection(t *testing.T) *Collection {
+	t.Helper()
+
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{-0.40824828, 0.40824828, 0.81649655}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "doc-a", Metadata: map[string]string{"lang": "en"}, Content: "Hello"},
+		{ID: "42", Embedding: []float32{0, 1, 0}, Content: "Answer"},
+		{ID: "0b6c6fe2-8a8c-4d36-a4b4-8b6f7a0a6c3e", Metadata: map[string]string{"page": "1"}, Embedding: []float32{1, 0, 0}},
+	}
+	err = c.AddDocuments(context.Background(), docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	return c
+}
+
+// assertInteropDocs checks that the documents survived an export and import
+// round trip.
+func assertInteropDocs(t *testing.T, want, got *Collection) {
+	t.Helper()
+
+	if len(got.documents) != len(want.documents) {
+		t.Fatal("expected", len(want.documents), "documents, got", len(got.documents))
+	}
+	for id, w := range want.documents {
+		g, ok := got.documents[id]
+		if !ok {
+			t.Fatal("expected document", id, "to exist")
+		}
+		if g.Content != w.Content || !reflect.DeepEqual(g.Embedding, w.Embedding) || len(g.Metadata) != len(w.Metadata) {
+			t.Fatalf("expected %+v, got %+v", w, g)
+		}
+		for k, v := range w.Metadata {
+			if g.Metadata[k] != v {
+				t.Fatalf("expected %+v, got %+v", w, g)
+			}
+		}
+	}
+}
+
+func TestUUIDFromString(t *testing.T) {
+	// Name-based UUIDs must be valid, deterministic and unique
+	u := uuidFromString("chromem-go")
+	if !isUUID(u) || u[14] != '5' {
+		t.Fatal("expected UUID v5, got", u)
+	}
+	if uuidFromString("chromem-go") != u {
+		t.Fatal("expected deterministic UUID")
+	}
+	if uuidFromString("chromem") == u {
+		t.Fatal("expected different UUIDs for different strings")
+	}
+	if got := uuidFromString("0B6C6FE2-8A8C-4D36-A4B4-8B6F7A0A6C3E"); got != "0b6c6fe2-8a8c-4d36-a4b4-8b6f7a0a6c3e" {
+		t.Fatal("expected UUID to be kept in lower case, got", got)
+	}
+}
@@ -0,0 +1,171 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+)
+
+// WeaviateOptions are the options for [Collection.ImportFromWeaviate] and
+// [Collection.ExportToWeaviate].
+type WeaviateOptions struct {
+	// The URL of the Weaviate server, e.g. "http://localhost:8080". Mandatory.
+	BaseURL string
+
+	// The API key, sent as bearer token. Optional.
+	APIKey string
+
+	// The name of the Weaviate class (collection). Mandatory.
+	Class string
+
+	// The property holding the document content. Optional, defaults to "content".
+	ContentKey string
+
+	// Number of objects per request. Optional, defaults to 100.
+	BatchSize int
+
+	// The HTTP client to use. Optional, defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+type weaviateObject struct {
+	ID         string         `json:"id"`
+	Class      string         `json:"class"`
+	Properties map[string]any `json:"properties"`
+	Vector     []float32      `json:"vector"`
+}
+
+// ImportFromWeaviate imports all objects of a Weaviate (https://weaviate.io/)
+// class, including their vectors, as documents into this collection. The
+// properties become the metadata, except for the content, which is read from
+// opts.ContentKey. Objects that were exported with [Collection.ExportToWeaviate]
+// get their original document IDs back, others use the Weaviate object ID.
+func (c *Collection) ImportFromWeaviate(ctx context.Context, opts WeaviateOptions) error {
+	baseURL, client, batchSize, contentKey, err := interopDefaults(opts.BaseURL, opts.HTTPClient, opts.BatchSize, opts.ContentKey)
+	if err != nil {
+		return err
+	}
+	if opts.Class == "" {
+		return errors.New("Weaviate class is empty")
+	}
+	headers := weaviateHeaders(opts.APIKey)
+	ctx, _ = startProgress(ctx, "import", 0)
+
+	// We use cursor-based pagination via "after", which is the recommended way
+	// to list all objects of a class.
+	after := ""
+	for {
+		q := url.Values{}
+		q.Set("class", opts.Class)
+		q.Set("include", "vector")
+		q.Set("limit", strconv.Itoa(batchSize))
+		if after != "" {
+			q.Set("after", after)
+		}
+		var res struct {
+			Objects []weaviateObject `json:"objects"`
+		}
+		err := doJSONRequest(ctx, client, http.MethodGet, baseURL+"/v1/objects?"+q.Encode(), headers, nil, &res)
+		if err != nil {
+			return fmt.Errorf("couldn't list Weaviate objects: %w", err)
+		}
+		if len(res.Objects) == 0 {
+			return nil
+		}
+
+		docs := make([]Document, 0, len(res.Objects))
+		for _, o := range res.Objects {
+			id, content, metadata := splitInteropPayload(o.Properties, contentKey)
+			if id == "" {
+				id = o.ID
+			}
+			docs = append(docs, Document{
+				ID:        id,
+				Metadata:  metadata,
+				Embedding: o.Vector,
+				Content:   content,
+			})
+		}
+		err = c.AddDocuments(ctx, docs, runtime.NumCPU())
+		if err != nil {
+			return fmt.Errorf("couldn't add documents: %w", err)
+		}
+
+		if len(res.Objects) < batchSize {
+			return nil
+		}
+		after = res.Objects[len(res.Objects)-1].ID
+	}
+}
+
+// ExportToWeaviate exports all documents of this collection as objects to a
+// Weaviate (https://weaviate.io/) class, using the batch API. If the class
+// doesn't exist yet, Weaviate creates it with auto-schema, as long as that isn't
+// disabled. Weaviate only supports UUIDs as object IDs, so other document IDs are
+// converted to deterministic UUIDs, with the original ID stored in the "chromem_id"
+// property. The content is stored in the property opts.ContentKey.
+func (c *Collection) ExportToWeaviate(ctx context.Context, opts WeaviateOptions) error {
+	baseURL, client, batchSize, contentKey, err := interopDefaults(opts.BaseURL, opts.HTTPClient, opts.BatchSize, opts.ContentKey)
+	if err != nil {
+		return err
+	}
+	if opts.Class == "" {
+		return errors.New("Weaviate class is empty")
+	}
+	headers := weaviateHeaders(opts.APIKey)
+
+	docs := c.sortedDocuments()
+	for start := 0; start < len(docs); start += batchSize {
+		end := min(start+batchSize, len(docs))
+		objects := make([]weaviateObject, 0, end-start)
+		for _, doc := range docs[start:end] {
+			// The content might be spilled to disk
+			doc, err := c.withContent(doc)
+			if err != nil {
+				return err
+			}
+			id := uuidFromString(doc.ID)
+			objects = append(objects, weaviateObject{
+				ID:         id,
+				Class:      opts.Class,
+				Properties: interopPayload(doc, contentKey, id != doc.ID),
+				Vector:     doc.Embedding,
+			})
+		}
+
+		// The batch endpoint returns 200 even if single objects fail, with the
+		// errors in the respective objects.
+		var res []struct {
+			ID     string `json:"id"`
+			Result struct {
+				Errors *struct {
+					Error []struct {
+						Message string `json:"message"`
+					} `json:"error"`
+				} `json:"errors"`
+			} `json:"result"`
+		}
+		err := doJSONRequest(ctx, client, http.MethodPost, baseURL+"/v1/batch/objects", headers, map[string]any{"objects": objects}, &res)
+		if err != nil {
+			return fmt.Errorf("couldn't create Weaviate objects: %w", err)
+		}
+		for _, o := range res {
+			if o.Result.Errors != nil && len(o.Result.Errors.Error) > 0 {
+				return fmt.Errorf("couldn't create Weaviate object %q: %s", o.ID, o.Result.Errors.Error[0].Message)
+			}
+		}
+	}
+
+	return nil
+}
+
+func weaviateHeaders(apiKey string) map[string]string {
+	if apiKey == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + apiKey}
+}
@@ -0,0 +1,92 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCollection_ExportImportWeaviate(t *testing.T) {
+	ctx := context.Background()
+
+	// Fake Weaviate server that keeps the objects in memory, sorted by ID like
+	// Weaviate does for cursor-based listing.
+	var objects []weaviateObject
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/batch/objects":
+			var req struct {
+				Objects []weaviateObject `json:"objects"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			objects = append(objects, req.Objects...)
+			slices.SortFunc(objects, func(a, b weaviateObject) int {
+				return strings.Compare(a.ID, b.ID)
+			})
+			writeJSON(w, http.StatusOK, req.Objects)
+		case "GET /v1/objects":
+			q := r.URL.Query()
+			if q.Get("class") != "Test" || q.Get("include") != "vector" {
+				t.Fatal("unexpected query", q)
+			}
+			limit, _ := strconv.Atoi(q.Get("limit"))
+			start := 0
+			if after := q.Get("after"); after != "" {
+				start = slices.IndexFunc(objects, func(o weaviateObject) bool { return o.ID == after }) + 1
+			}
+			end := min(start+limit, len(objects))
+			writeJSON(w, http.StatusOK, map[string]any{"objects": objects[start:end]})
+		default:
+			t.Fatal("unexpected request", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	src := newInteropTestCollection(t)
+	opts := WeaviateOptions{BaseURL: ts.URL, APIKey: "secret", Class: "Test", BatchSize: 2}
+	err := src.ExportToWeaviate(ctx, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(objects) != 3 {
+		t.Fatal("expected 3 objects, got", len(objects))
+	}
+	// Non-UUID IDs are converted, with the original ID in the properties
+	for _, o := range objects {
+		if !isUUID(o.ID) || (o.ID != "0b6c6fe2-8a8c-4d36-a4b4-8b6f7a0a6c3e") != (o.Properties[interopIDKey] != nil) {
+			t.Fatalf("unexpected object: %+v", o)
+		}
+	}
+
+	dst := newInteropTestCollection(t)
+	dst.documents = map[string]*Document{}
+	err = dst.ImportFromWeaviate(ctx, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	assertInteropDocs(t, src, dst)
+}
+
+func TestCollection_ExportToWeaviate_objectError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"id":"x","result":{"errors":{"error":[{"message":"no such class"}]}}}]`))
+	}))
+	defer ts.Close()
+
+	c := newInteropTestCollection(t)
+	err := c.ExportToWeaviate(context.Background(), WeaviateOptions{BaseURL: ts.URL, Class: "Test"})
+	if err == nil || !strings.Contains(err.Error(), "no such class") {
+		t.Fatal("expected object error, got", err)
+	}
+}
@@ -0,0 +1,509 @@
+package chromem
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	webDefaultUserAgent   = "chromem-go"
+	webDefaultConcurrency = 4
+	webMaxBodySize        = 10 << 20
+)
+
+// URLOptions are the options for [Collection.AddFromURLs].
+type URLOptions struct {
+	// The user agent that's sent with requests and that's used to find the
+	// rules in robots.txt files. Optional, defaults to "chromem-go".
+	UserAgent string
+
+	// IgnoreRobotsTxt disables the check of robots.txt files. Only use it for
+	// sites you own.
+	IgnoreRobotsTxt bool
+
+	// The number of pages that are fetched concurrently. Optional, defaults
+	// to 4.
+	Concurrency int
+
+	// The minimum delay between requests to the same host. If a robots.txt
+	// file specifies a longer crawl delay, that's used instead. Optional,
+	// defaults to no delay.
+	Delay time.Duration
+
+	// The maximum size of a chunk in characters. Optional, defaults to 1000.
+	ChunkSize int
+
+	// The number of characters that consecutive chunks share. Optional,
+	// defaults to 100. Negative values disable the overlap.
+	ChunkOverlap int
+
+	// The HTTP client to use. Optional, defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+// AddFromURLs fetches the web pages at the given URLs, extracts their readable
+// text, splits it into chunks and adds them as documents to this collection.
+// The documents have the IDs "<url>#<index>" and the metadata "url", "title"
+// and "fetched_at".
+//
+// For HTML pages, the text of the <main> or <article> element is used if
+// there is one, and scripts, styles, navigation, headers and footers are
+// skipped. Plain text pages are used as they are, other content types are an
+// error. Links aren't followed.
+//
+// Unless opts.IgnoreRobotsTxt is set, pages that are disallowed by the
+// robots.txt of their host are skipped with an error. Failing URLs don't stop
+// the others, and all errors are returned together.
+func (c *Collection) AddFromURLs(ctx context.Context, urls []string, opts URLOptions) error {
+	if len(urls) == 0 {
+		return errors.New("urls are empty")
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = webDefaultUserAgent
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = webDefaultConcurrency
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 1000
+	}
+	if opts.ChunkOverlap == 0 {
+		opts.ChunkOverlap = 100
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	ctx, _ = startProgress(ctx, "import", 0)
+
+	f := &webFetcher{
+		opts:   opts,
+		robots: make(map[string]*robotsRules),
+		next:   make(map[string]time.Time),
+	}
+
+	errs := make([]error, len(urls))
+	semaphore := make(chan struct{}, opts.Concurrency)
+	executor := c.executor()
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		i, u := i, u
+		wg.Add(1)
+		executor.Submit(func() {
+			defer wg.Done()
+			defer recoverPanic(func(err error) {
+				errs[i] = fmt.Errorf("couldn't add %q: %w", u, err)
+			})
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			err := c.addFromURL(ctx, f, u)
+			if err != nil {
+				errs[i] = fmt.Errorf("couldn't add %q: %w", u, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// addFromURL fetches the page at the URL and adds its chunks as documents.
+func (c *Collection) addFromURL(ctx context.Context, f *webFetcher, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("couldn't parse URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+
+	delay := f.opts.Delay
+	if !f.opts.IgnoreRobotsTxt {
+		rules, err := f.robotsRules(ctx, u)
+		if err != nil {
+			return err
+		}
+		if !rules.allowed(u.EscapedPath()) {
+			return errors.New("disallowed by robots.txt")
+		}
+		delay = max(delay, rules.crawlDelay)
+	}
+
+	body, contentType, err := f.get(ctx, u.Host, u.String(), delay)
+	if err != nil {
+		return err
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var title, text string
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		title, text = extractHTMLText(body)
+	case "text/plain", "text/markdown":
+		text = body
+	default:
+		return fmt.Errorf("unsupported content type %q", contentType)
+	}
+
+	fetchedAt := time.Now().UTC().Format(time.RFC3339)
+	var docs []Document
+	for i, chunk := range splitText(text, f.opts.ChunkSize, f.opts.ChunkOverlap) {
+		metadata := map[string]string{
+			"url":        rawURL,
+			"fetched_at": fetchedAt,
+		}
+		if title != "" {
+			metadata["title"] = title
+		}
+		docs = append(docs, Document{
+			ID:       rawURL + "#" + strconv.Itoa(i),
+			Metadata: metadata,
+			Content:  chunk,
+		})
+	}
+	if len(docs) == 0 {
+		return errors.New("page has no text")
+	}
+	return c.AddDocuments(ctx, docs, runtime.NumCPU())
+}
+
+// webFetcher fetches pages politely, i.e. with the robots.txt rules and a delay
+// between requests per host.
+type webFetcher struct {
+	opts URLOptions
+
+	// By host
+	robots     map[string]*robotsRules
+	robotsLock sync.Mutex
+
+	// The time of the next allowed request, by host
+	next     map[string]time.Time
+	nextLock sync.Mutex
+}
+
+// robotsRules returns the robots.txt rules for the URL's host. They're fetched
+// once per host.
+func (f *webFetcher) robotsRules(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	// We hold the lock while fetching, so that the file is only fetched once.
+	f.robotsLock.Lock()
+	defer f.robotsLock.Unlock()
+
+	if rules, ok := f.robots[u.Host]; ok {
+		return rules, nil
+	}
+	robotsURL := u.Scheme + "://" + u.Host + "/robots.txt"
+	body, _, err := f.get(ctx, u.Host, robotsURL, f.opts.Delay)
+	rules := &robotsRules{}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 {
+		// No robots.txt, so everything is allowed.
+	} else if err != nil {
+		return nil, fmt.Errorf("couldn't get robots.txt: %w", err)
+	} else {
+		rules = parseRobotsTxt(body, f.opts.UserAgent)
+	}
+	f.robots[u.Host] = rules
+	return rules, nil
+}
+
+// get waits until the next request to the host is allowed and fetches the URL.
+// The delay is reserved before the following request to the host. It returns
+// the body and its content type.
+func (f *webFetcher) get(ctx context.Context, host, rawURL string, delay time.Duration) (string, string, error) {
+	f.nextLock.Lock()
+	now := time.Now()
+	at := f.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	f.next[host] = at.Add(delay)
+	f.nextLock.Unlock()
+	if wait := time.Until(at); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+	resp, err := f.opts.HTTPClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, webMaxBodySize))
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return string(body), resp.Header.Get("Content-Type"), nil
+}
+
+// robotsRules are the rules of a robots.txt file for a user agent.
+type robotsRules struct {
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+}
+
+// parseRobotsTxt parses the rules of the group for the user agent, or of the
+// "*" group if there's none for it.
+func parseRobotsTxt(content, userAgent string) *robotsRules {
+	userAgent = strings.ToLower(userAgent)
+	var specific, wildcard *robotsRules
+	var current []*robotsRules
+	inAgents := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			// Consecutive user agent lines share a group.
+			if !inAgents {
+				current = nil
+				inAgents = true
+			}
+			agent := strings.ToLower(value)
+			if agent == "*" {
+				if wildcard == nil {
+					wildcard = &robotsRules{}
+				}
+				current = append(current, wildcard)
+			} else if agent != "" && strings.Contains(userAgent, agent) {
+				if specific == nil {
+					specific = &robotsRules{}
+				}
+				current = append(current, specific)
+			}
+			continue
+		}
+		inAgents = false
+		for _, rules := range current {
+			switch key {
+			case "allow":
+				if value != "" {
+					rules.allow = append(rules.allow, value)
+				}
+			case "disallow":
+				// An empty value allows everything.
+				if value != "" {
+					rules.disallow = append(rules.disallow, value)
+				}
+			case "crawl-delay":
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					rules.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	if specific != nil {
+		return specific
+	}
+	if wildcard != nil {
+		return wildcard
+	}
+	return &robotsRules{}
+}
+
+// allowed reports whether the path is allowed. The longest matching rule wins,
+// and allow rules win ties.
+func (r *robotsRules) allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	longestAllow, longestDisallow := -1, -1
+	for _, pattern := range r.allow {
+		if len(pattern) > longestAllow && robotsMatch(pattern, path) {
+			longestAllow = len(pattern)
+		}
+	}
+	for _, pattern := range r.disallow {
+		if len(pattern) > longestDisallow && robotsMatch(pattern, path) {
+			longestDisallow = len(pattern)
+		}
+	}
+	return longestAllow >= longestDisallow
+}
+
+// robotsMatch reports whether the robots.txt path pattern matches the path.
+// Patterns are prefixes that can contain "*" wildcards and end with "$" to
+// match the end of the path.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	path = path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		// The last part must be at the end for anchored patterns.
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(path, part)
+		}
+		idx := strings.Index(path, part)
+		if idx == -1 {
+			return false
+		}
+		path = path[idx+len(part):]
+	}
+	return !anchored || path == ""
+}
+
+// htmlSkipTags are the elements whose content isn't readable text.
+var htmlSkipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "svg": true, "template": true,
+	"iframe": true, "nav": true, "header": true, "footer": true, "aside": true,
+	"form": true, "head": true,
+}
+
+// htmlBlockTags are the elements that start a new line.
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "pre": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "main": true, "blockquote": true,
+	"table": true, "ul": true, "ol": true, "dt": true, "dd": true, "hr": true,
+}
+
+// extractHTMLText returns the title and the readable text of the HTML page. If
+// the page has a <main> or <article> element, only its text is extracted.
+func extractHTMLText(page string) (string, string) {
+	title := ""
+	if content, ok := htmlElementContent(page, "title"); ok {
+		title = strings.Join(strings.Fields(html.UnescapeString(content)), " ")
+	}
+	for _, tag := range []string{"main", "article"} {
+		if content, ok := htmlElementContent(page, tag); ok {
+			page = content
+			break
+		}
+	}
+
+	sb := strings.Builder{}
+	for len(page) > 0 {
+		i := strings.IndexByte(page, '<')
+		if i == -1 {
+			sb.WriteString(html.UnescapeString(page))
+			break
+		}
+		sb.WriteString(html.UnescapeString(page[:i]))
+		page = page[i:]
+
+		if strings.HasPrefix(page, "<!--") {
+			end := strings.Index(page, "-->")
+			if end == -1 {
+				break
+			}
+			page = page[end+len("-->"):]
+			continue
+		}
+
+		end := htmlTagEnd(page)
+		tag := page[1:end]
+		page = page[min(end+1, len(page)):]
+		closing := strings.HasPrefix(tag, "/")
+		name := strings.ToLower(strings.TrimLeft(tag, "/"))
+		if j := strings.IndexAny(name, " \t\r\n/"); j != -1 {
+			name = name[:j]
+		}
+
+		if !closing && htmlSkipTags[name] && !strings.HasSuffix(tag, "/") {
+			// Skip to the closing tag.
+			j := strings.Index(strings.ToLower(page), "</"+name)
+			if j == -1 {
+				break
+			}
+			page = page[j:]
+			page = page[min(htmlTagEnd(page)+1, len(page)):]
+			continue
+		}
+		if htmlBlockTags[name] {
+			sb.WriteByte('\n')
+		} else {
+			// Inline elements like <span> don't separate words, but elements
+			// like <td> do.
+			sb.WriteByte(' ')
+		}
+	}
+
+	// Collapse whitespace and remove empty lines.
+	var lines []string
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return title, strings.Join(lines, "\n")
+}
+
+// htmlElementContent returns the raw content of the first element with the
+// tag name, up to its last closing tag.
+func htmlElementContent(page, name string) (string, bool) {
+	lower := strings.ToLower(page)
+	start := -1
+	for offset := 0; ; {
+		i := strings.Index(lower[offset:], "<"+name)
+		if i == -1 {
+			return "", false
+		}
+		i += offset
+		// Make sure it's not a longer tag name, like <mainframe>.
+		if next := i + 1 + len(name); next < len(lower) && strings.IndexByte(" \t\r\n>/", lower[next]) != -1 {
+			start = i
+			break
+		}
+		offset = i + 1
+	}
+	contentStart := start + htmlTagEnd(page[start:]) + 1
+	end := strings.LastIndex(lower, "</"+name)
+	if contentStart > len(page) || end < contentStart {
+		return "", false
+	}
+	return page[contentStart:end], true
+}
+
+// htmlTagEnd returns the index of the ">" that ends the tag at the start of s,
+// taking quoted attribute values into account, or len(s) if there's none.
+func htmlTagEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '>':
+			return i
+		}
+	}
+	return len(s)
+}
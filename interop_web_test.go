@@ -0,0 +1,109 @@
+package chromem
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCollection_AddFromURLs(t *testing.T) {
+	ctx := context.Background()
+	var userAgents []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.UserAgent())
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head><title>The &amp; Title</title><script>var x = "<p>";</script></head>
+<body><nav>Home | About</nav><main><h1>Hello</h1><p>Some <b>bold</b> text.</p></main><footer>Imprint</footer></body></html>`))
+	})
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("plain text"))
+	})
+	mux.HandleFunc("/private/page", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected disallowed page not to be fetched")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		return []float32{float32(len(text)), 1}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.AddFromURLs(ctx, []string{srv.URL + "/page", srv.URL + "/text", srv.URL + "/private/page"}, URLOptions{Concurrency: 1})
+	if err == nil || !strings.Contains(err.Error(), "disallowed by robots.txt") {
+		t.Fatal("expected robots.txt error, got", err)
+	}
+	if c.Count() != 2 {
+		t.Fatal("expected 2 documents, got", c.Count())
+	}
+	doc := c.documents[srv.URL+"/page#0"]
+	if doc == nil {
+		t.Fatal("expected document of page")
+	}
+	if doc.Content != "Hello\nSome bold text." {
+		t.Fatalf("unexpected content %q", doc.Content)
+	}
+	if doc.Metadata["title"] != "The & Title" || doc.Metadata["url"] != srv.URL+"/page" {
+		t.Fatalf("unexpected metadata %v", doc.Metadata)
+	}
+	if len(userAgents) != 1 || userAgents[0] != "chromem-go" {
+		t.Fatal("expected chromem-go user agent, got", userAgents)
+	}
+	if doc := c.documents[srv.URL+"/text#0"]; doc == nil || doc.Content != "plain text" {
+		t.Fatalf("unexpected document of text %+v", doc)
+	}
+}
+
+func TestParseRobotsTxt(t *testing.T) {
+	content := `# Comment
+User-agent: Googlebot
+Disallow: /
+
+User-agent: chromem-go
+User-agent: other
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: *
+Disallow: /tmp
+`
+	rules := parseRobotsTxt(content, "chromem-go")
+	if rules.crawlDelay.Seconds() != 2 {
+		t.Fatal("expected crawl delay of 2s, got", rules.crawlDelay)
+	}
+	tt := []struct {
+		path     string
+		expected bool
+	}{
+		{"/", true},
+		{"/tmp/x", true},
+		{"/private", false},
+		{"/private/x", false},
+		{"/private/public/x", true},
+		{"/docs/file.pdf", false},
+		{"/docs/file.pdf.html", true},
+	}
+	for _, tc := range tt {
+		if got := rules.allowed(tc.path); got != tc.expected {
+			t.Errorf("expected %v for %q, got %v", tc.expected, tc.path, got)
+		}
+	}
+
+	// Other user agents use the wildcard group
+	rules = parseRobotsTxt(content, "someone")
+	if rules.allowed("/tmp/x") || !rules.allowed("/private") {
+		t.Fatal("expected wildcard rules")
+	}
+}
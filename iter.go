@@ -0,0 +1,130 @@
+//go:build go1.23
+
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// Documents returns an iterator over the documents of the collection, sorted by
+// ID, for use with range-over-func:
+//
+//	for id, doc := range c.Documents() {
+//		...
+//	}
+//
+// It iterates over a snapshot of the documents at the time of the call, so it's
+// safe to add or delete documents while iterating. With content spillover (see
+// [Collection.SetContentSpillover]), the contents are loaded lazily. If a
+// content can't be loaded, the document is yielded without it.
+func (c *Collection) Documents() iter.Seq2[string, Document] {
+	docs := c.sortedDocuments()
+	return func(yield func(string, Document) bool) {
+		for _, doc := range docs {
+			if withContent, err := c.withContent(doc); err == nil {
+				doc = withContent
+			}
+			if !yield(doc.ID, *doc) {
+				return
+			}
+		}
+	}
+}
+
+// QueryIter returns an iterator over the query results, from the most to the
+// least similar document, for use with range-over-func:
+//
+//	for res, err := range c.QueryIter(ctx, chromem.QueryOptions{QueryText: "..."}) {
+//		if err != nil {
+//			...
+//		}
+//		if res.Similarity < 0.5 {
+//			break
+//		}
+//		...
+//	}
+//
+// Unlike [Collection.QueryWithOptions], the number of results doesn't have to
+// be known beforehand. The results are queried lazily in pages, starting with
+// options.NResults (or 10 if it's 0) and doubling the page size for each
+// further page, until the consumer stops or all matching documents were yielded.
+// The embeddings of the query are only created once, and the filters are only
+// compiled once. An error ends the iteration.
+func (c *Collection) QueryIter(ctx context.Context, options QueryOptions) iter.Seq2[Result, error] {
+	return func(yield func(Result, error) bool) {
+		if options.NResults < 0 {
+			yield(Result{}, errors.New("nResults must be >= 0"))
+			return
+		}
+		if options.QueryText == "" && len(options.QueryEmbedding) == 0 && options.QueryMedia == nil && len(options.Concepts) == 0 {
+			yield(Result{}, errors.New("QueryText, QueryEmbedding, QueryMedia and Concepts options are empty"))
+			return
+		}
+		pageSize := options.NResults
+		if pageSize == 0 {
+			pageSize = 10
+		}
+
+		// Create the embeddings only once, not for each page.
+		var err error
+		options.QueryEmbedding, err = c.queryVector(ctx, options)
+		if err != nil {
+			yield(Result{}, err)
+			return
+		}
+		options.Concepts = nil
+		if options.Filter == nil {
+			options.Filter, err = CompileFilter(options.Where, options.WhereDocument)
+			if err != nil {
+				yield(Result{}, err)
+				return
+			}
+			options.Where, options.WhereDocument = nil, nil
+		}
+		if len(options.Negative.Embedding) == 0 && options.Negative.Text != "" {
+			options.Negative.Embedding, err = c.embedQuery(ctx, options.Negative.Text)
+			if err != nil {
+				yield(Result{}, fmt.Errorf("couldn't create embedding of negative: %w", err))
+				return
+			}
+		}
+
+		yielded := 0
+		for {
+			options.NResults = min(yielded+pageSize, c.Count())
+			if options.NResults <= yielded {
+				return
+			}
+			results, err := c.QueryWithOptions(ctx, options)
+			if err != nil {
+				yield(Result{}, err)
+				return
+			}
+			for _, res := range results[min(yielded, len(results)):] {
+				if !yield(res, nil) {
+					return
+				}
+				yielded++
+			}
+			// Fewer results than requested means there are no more matches.
+			if len(results) < options.NResults {
+				return
+			}
+			pageSize *= 2
+		}
+	}
+}
+
+// Documents is like [Collection.Documents], for the documents of the snapshot.
+func (s *Snapshot) Documents() iter.Seq2[string, Document] {
+	return s.c.Documents()
+}
+
+// QueryIter is like [Collection.QueryIter], for the documents of the snapshot.
+// Unlike with the collection, the pages are consistent with each other.
+func (s *Snapshot) QueryIter(ctx context.Context, options QueryOptions) iter.Seq2[Result, error] {
+	return s.c.QueryIter(ctx, options)
+}
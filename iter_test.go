@@ -0,0 +1,90 @@
+//go:build go1.23
+
+package chromem
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_Documents(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, id := range []string{"b", "c", "a"} {
+		err := c.AddDocument(ctx, Document{ID: id, Embedding: []float32{-0.40824828, 0.40824828, 0.81649655}, Content: "content " + id})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	var ids []string
+	for id, doc := range c.Documents() {
+		if doc.Content != "content "+id {
+			t.Fatalf("expected content %q, got %q", "content "+id, doc.Content)
+		}
+		ids = append(ids, id)
+		if id == "b" {
+			break
+		}
+	}
+	if !slices.Equal(ids, []string{"a", "b"}) {
+		t.Fatal("expected [a b], got", ids)
+	}
+}
+
+func TestCollection_QueryIter(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Documents with decreasing similarity to the query
+	for i := 0; i < 25; i++ {
+		err := c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: []float32{float32(25 - i), float32(i)}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	var ids []string
+	for res, err := range c.QueryIter(ctx, QueryOptions{QueryText: "foo", NResults: 2}) {
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		ids = append(ids, res.ID)
+	}
+	if len(ids) != 25 {
+		t.Fatal("expected 25 results, got", len(ids))
+	}
+	for i, id := range ids {
+		if id != strconv.Itoa(i) {
+			t.Fatalf("expected results in order of similarity, got %v", ids)
+		}
+	}
+
+	// Stopping early
+	n := 0
+	for range c.QueryIter(ctx, QueryOptions{QueryText: "foo"}) {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+
+	// Errors
+	for _, err := range c.QueryIter(ctx, QueryOptions{}) {
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	}
+}
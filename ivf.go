@@ -0,0 +1,292 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+const (
+	// ivfTrainingIterations is the number of k-means iterations when training
+	// the IVF index.
+	ivfTrainingIterations = 10
+	// ivfSamplesPerList is the maximum number of documents per list that are
+	// sampled for training the IVF index.
+	ivfSamplesPerList = 256
+)
+
+// IndexTypeIVF is an inverted file index: The documents are clustered around
+// centroids with k-means, and queries only scan the documents of the lists
+// whose centroids are the most similar to the query. It's trained with k-means
+// on a sample of the documents, which takes a while for large collections.
+// Documents that are added later are assigned to the existing lists, so when
+// the documents change a lot, for example when most of them are added after the
+// index was built, call [Collection.RebuildIndex]. The trained centroids are
+// persisted, and the lists are recalculated when the collection is loaded.
+const IndexTypeIVF IndexType = "ivf"
+
+// ivfIndexType is the implementation of [IndexTypeIVF].
+var ivfIndexType = indexType{
+	validate: func(options IndexOptions) error {
+		if options.Lists < 0 || options.NProbe < 0 {
+			return errors.New("lists and nProbe must be >= 0")
+		}
+		return nil
+	},
+	build: func(ctx context.Context, _ *Collection, docs []*Document, dim int, options IndexOptions) (vectorIndex, error) {
+		centroids, err := trainIVF(ctx, docs, dim, options.Lists)
+		if err != nil {
+			return nil, err
+		}
+		return newIVFIndex(centroids, options.NProbe, docs), nil
+	},
+	load: func(_ *Collection, config collectionConfig, docs []*Document) (vectorIndex, error) {
+		return newIVFIndex(config.IndexCentroids, config.Index.NProbe, docs), nil
+	},
+	buildOptions: func(options IndexOptions) IndexOptions {
+		options.NProbe = 0
+		return options
+	},
+	tuningOptions: func(n int) []IndexOptions {
+		// Half, once and twice the square root of the number of documents as
+		// lists, and the powers of two up to the number of lists as nprobe.
+		sqrt := int(math.Sqrt(float64(n)))
+		var res []IndexOptions
+		for _, lists := range []int{sqrt / 2, sqrt, sqrt * 2} {
+			lists = max(1, min(lists, n))
+			for nProbe := 1; nProbe < lists; nProbe *= 2 {
+				res = append(res, IndexOptions{Type: IndexTypeIVF, Lists: lists, NProbe: nProbe})
+			}
+			res = append(res, IndexOptions{Type: IndexTypeIVF, Lists: lists, NProbe: lists})
+		}
+		return res
+	},
+}
+
+// ivfIndex is an inverted file index.
+type ivfIndex struct {
+	centroids [][]float32
+	nProbe    int
+	// The IDs of the documents per centroid
+	lists *postingLists[int]
+	// Set when a document with a different dimension than the centroids' is
+	// added, so that queries scan all documents.
+	stale bool
+}
+
+// newIVFIndex creates an index with the centroids, and assigns the documents to
+// the lists. It returns nil without centroids.
+func newIVFIndex(centroids [][]float32, nProbe int, docs []*Document) vectorIndex {
+	if len(centroids) == 0 {
+		return nil
+	}
+	x := &ivfIndex{
+		centroids: centroids,
+		lists:     newPostingLists[int](len(docs)),
+	}
+	x.setSearchOptions(IndexOptions{NProbe: nProbe})
+
+	assignments := assignToCentroids(docs, centroids)
+	for i, doc := range docs {
+		if assignments[i] < 0 {
+			x.stale = true
+			continue
+		}
+		x.lists.add(doc.ID, assignments[i])
+	}
+	return x
+}
+
+// set assigns the added or replaced document to its list.
+func (x *ivfIndex) set(doc *Document) {
+	list := nearestCentroid(doc.Embedding, x.centroids)
+	if current, ok := x.lists.listOf(doc.ID); ok {
+		if current == list {
+			return
+		}
+		x.lists.remove(doc.ID)
+	}
+	if list < 0 {
+		x.stale = true
+		return
+	}
+	x.lists.add(doc.ID, list)
+}
+
+// remove removes the document from its list.
+func (x *ivfIndex) remove(id string) {
+	x.lists.remove(id)
+}
+
+func (x *ivfIndex) contains(id string) bool {
+	_, ok := x.lists.listOf(id)
+	return ok
+}
+
+// candidates returns the documents of the lists whose centroids are the most
+// similar to the normalized query, or false if the index can't be used.
+func (x *ivfIndex) candidates(query []float32, documents map[string]*Document) ([]*Document, bool) {
+	if x.stale || len(query) != len(x.centroids[0]) {
+		return nil, false
+	}
+
+	type centroidSim struct {
+		list int
+		sim  float32
+	}
+	sims := make([]centroidSim, len(x.centroids))
+	for i, centroid := range x.centroids {
+		sim, _ := dotProduct(query, centroid)
+		sims[i] = centroidSim{list: i, sim: sim}
+	}
+	slices.SortFunc(sims, func(a, b centroidSim) int {
+		if a.sim > b.sim {
+			return -1
+		} else if a.sim < b.sim {
+			return 1
+		}
+		return 0
+	})
+
+	n := 0
+	for _, s := range sims[:x.nProbe] {
+		n += len(x.lists.lists[s.list])
+	}
+	docs := make([]*Document, 0, n)
+	for _, s := range sims[:x.nProbe] {
+		for _, id := range x.lists.lists[s.list] {
+			if doc, ok := documents[id]; ok {
+				docs = append(docs, doc)
+			}
+		}
+	}
+	return docs, true
+}
+
+// setSearchOptions sets the number of lists that queries scan.
+func (x *ivfIndex) setSearchOptions(options IndexOptions) {
+	nProbe := options.NProbe
+	if nProbe <= 0 {
+		nProbe = max(1, len(x.centroids)/10)
+	}
+	x.nProbe = min(nProbe, len(x.centroids))
+}
+
+func (x *ivfIndex) persist(config *collectionConfig) {
+	config.IndexCentroids = x.centroids
+}
+
+func (x *ivfIndex) drop() error {
+	return nil
+}
+
+// clone copies the lists. The centroids aren't changed after the training, so
+// they're shared.
+func (x *ivfIndex) clone() vectorIndex {
+	res := *x
+	res.lists = x.lists.clone()
+	return &res
+}
+
+// nearestCentroid returns the index of the centroid that's the most similar to
+// the normalized vector, or -1 if the dimensions don't match.
+func nearestCentroid(v []float32, centroids [][]float32) int {
+	best, bestSim := -1, float32(math.Inf(-1))
+	for i, centroid := range centroids {
+		sim, err := dotProduct(v, centroid)
+		if err != nil {
+			return -1
+		}
+		if sim > bestSim {
+			best, bestSim = i, sim
+		}
+	}
+	return best
+}
+
+// assignToCentroids returns the index of the nearest centroid of each document,
+// calculated concurrently.
+func assignToCentroids(docs []*Document, centroids [][]float32) []int {
+	res := make([]int, len(docs))
+	concurrency := min(runtime.NumCPU(), len(docs))
+	if concurrency == 0 {
+		return res
+	}
+	batchSize := (len(docs) + concurrency - 1) / concurrency
+	var wg sync.WaitGroup
+	for start := 0; start < len(docs); start += batchSize {
+		end := min(start+batchSize, len(docs))
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				res[i] = nearestCentroid(docs[i].Embedding, centroids)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	return res
+}
+
+// trainIVF calculates the centroids of the documents with spherical k-means on
+// a sample of them. It returns nil without documents.
+func trainIVF(ctx context.Context, docs []*Document, dim, lists int) ([][]float32, error) {
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	if lists == 0 {
+		lists = int(math.Sqrt(float64(len(docs))))
+	}
+	lists = max(1, min(lists, len(docs)))
+
+	// A fixed seed, so that the index is the same for the same documents.
+	r := rand.New(rand.NewSource(1))
+	sample := slices.Clone(docs)
+	r.Shuffle(len(sample), func(i, j int) { sample[i], sample[j] = sample[j], sample[i] })
+	sample = sample[:min(len(sample), lists*ivfSamplesPerList)]
+
+	centroids := make([][]float32, lists)
+	for i := range centroids {
+		centroids[i] = slices.Clone(sample[i].Embedding)
+	}
+	for iteration := 0; iteration < ivfTrainingIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		assignments := assignToCentroids(sample, centroids)
+		sums := make([][]float32, lists)
+		for i, list := range assignments {
+			if list < 0 {
+				return nil, &DimensionMismatchError{Expected: dim, Actual: len(sample[i].Embedding)}
+			}
+			if sums[list] == nil {
+				sums[list] = make([]float32, dim)
+			}
+			for j, v := range sample[i].Embedding {
+				sums[list][j] += v
+			}
+		}
+		for i, sum := range sums {
+			if sum == nil || isZeroVector(sum) {
+				// An empty list gets a random document as new centroid.
+				centroids[i] = slices.Clone(sample[r.Intn(len(sample))].Embedding)
+				continue
+			}
+			centroids[i] = normalizeVector(sum)
+		}
+	}
+	return centroids, nil
+}
+
+func isZeroVector(v []float32) bool {
+	for _, val := range v {
+		if val != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,241 @@
+package chromem
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_SetIndex_IVF(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	n, d, clusters := 2000, 16, 20
+	centers := make([][]float32, clusters)
+	for i := range centers {
+		centers[i] = make([]float32, d)
+		for j := range centers[i] {
+			centers[i][j] = r.Float32()*2 - 1
+		}
+	}
+	randomVector := func() []float32 {
+		center := centers[r.Intn(clusters)]
+		v := make([]float32, d)
+		for j := range v {
+			v[j] = center[j] + (r.Float32()-0.5)*0.2
+		}
+		return normalizeVector(v)
+	}
+
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := make([]Document, n)
+	for i := range docs {
+		docs[i] = Document{ID: strconv.Itoa(i), Embedding: randomVector()}
+	}
+	err = c.AddDocuments(ctx, docs, 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	if err := c.SetIndex(ctx, IndexOptions{Type: IndexTypeIVF, Lists: -1}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.SetIndex(ctx, IndexOptions{Type: IndexTypeIVF, Lists: clusters, NProbe: 3})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	recall := func(c *Collection) float64 {
+		t.Helper()
+		hits, total := 0, 0
+		for q := 0; q < 20; q++ {
+			qv := randomVector()
+			exact, err := c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: qv, NResults: 10, Exhaustive: true})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			approx, stats, err := c.QueryWithStats(ctx, QueryOptions{QueryEmbedding: qv, NResults: 10})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if stats.DocumentsScanned >= n/2 {
+				t.Fatal("expected less than half of the documents to be scanned, got", stats.DocumentsScanned)
+			}
+			ids := make(map[string]struct{})
+			for _, res := range approx {
+				ids[res.ID] = struct{}{}
+			}
+			for _, res := range exact {
+				if _, ok := ids[res.ID]; ok {
+					hits++
+				}
+				total++
+			}
+		}
+		return float64(hits) / float64(total)
+	}
+	if rc := recall(c); rc < 0.9 {
+		t.Fatal("expected recall >= 0.9, got", rc)
+	}
+
+	// Added and deleted documents are indexed.
+	added := randomVector()
+	err = c.AddDocument(ctx, Document{ID: "added", Embedding: added})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "0")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err := c.QueryEmbedding(ctx, added, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "added" {
+		t.Fatal("expected added document, got", res[0].ID)
+	}
+	res, err = c.QueryEmbedding(ctx, docs[0].Embedding, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID == "0" {
+		t.Fatal("expected deleted document not to be found")
+	}
+
+	// The index is persisted.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if options := c2.Index(); options.Type != IndexTypeIVF || options.Lists != clusters {
+		t.Fatal("expected persisted IVF index options, got", options)
+	}
+	if rc := recall(c2); rc < 0.9 {
+		t.Fatal("expected recall >= 0.9 after loading, got", rc)
+	}
+
+	// Without index, all documents are scanned.
+	err = c.SetIndex(ctx, IndexOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, stats, err := c.QueryWithStats(ctx, QueryOptions{QueryEmbedding: added, NResults: 1})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats.DocumentsScanned != n {
+		t.Fatalf("expected %d documents to be scanned, got %d", n, stats.DocumentsScanned)
+	}
+}
+
+func TestCollection_TuneIndex_IVF(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	n, d, clusters := 1000, 16, 20
+	centers := make([][]float32, clusters)
+	for i := range centers {
+		centers[i] = make([]float32, d)
+		for j := range centers[i] {
+			centers[i][j] = r.Float32()*2 - 1
+		}
+	}
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	docs := make([]Document, n)
+	for i := range docs {
+		center := centers[r.Intn(clusters)]
+		v := make([]float32, d)
+		for j := range v {
+			v[j] = center[j] + (r.Float32()-0.5)*0.2
+		}
+		docs[i] = Document{ID: strconv.Itoa(i), Embedding: v}
+	}
+	err = c.AddDocuments(ctx, docs, 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The default options: 15, 31 and 62 lists, with nprobe 1, 2, 4, 8 and 15,
+	// 1, 2, 4, 8, 16 and 31, and 1, 2, 4, 8, 16, 32 and 62.
+	report, err := c.TuneIndex(ctx, IndexTuningOptions{Type: IndexTypeIVF, SampleQueries: 50, TargetRecall: 0.9})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(report.Results) != 18 {
+		t.Fatal("expected 18 results, got", len(report.Results))
+	}
+	for _, res := range report.Results {
+		if res.Options.NProbe == res.Options.Lists && (res.Recall != 1 || res.DocumentsScanned != float64(n)) {
+			t.Fatal("expected exact results when all lists are scanned, got", res)
+		}
+	}
+	rec := report.Recommended
+	if !report.TargetReached || rec.Recall < 0.9 || rec.DocumentsScanned >= float64(n)/2 {
+		t.Fatal("expected recommended options with recall >= 0.9 that scan less than half of the documents, got", rec)
+	}
+	for _, res := range report.Results {
+		if res.Recall >= 0.9 && res.DocumentsScanned < rec.DocumentsScanned {
+			t.Fatal("expected recommended options to scan the fewest documents, got", rec, res)
+		}
+	}
+	if c.Index().Type != IndexTypeNone {
+		t.Fatal("expected index not to be set, got", c.Index())
+	}
+
+	// Applying the recommended options uses the index that was trained for
+	// them.
+	options := []IndexOptions{
+		{Type: IndexTypeIVF, Lists: 10, NProbe: 1},
+		{Type: IndexTypeIVF, Lists: 10, NProbe: 2},
+		{Type: IndexTypeIVF, Lists: 20, NProbe: 1},
+		{Type: IndexTypeIVF, Lists: 20, NProbe: 2},
+	}
+	report, err = c.TuneIndex(ctx, IndexTuningOptions{Options: options, SampleQueries: 20, TargetRecall: 0.99, Apply: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(report.Results) != 4 {
+		t.Fatal("expected 4 results, got", len(report.Results))
+	}
+	if c.Index() != report.Recommended.Options {
+		t.Fatal("expected recommended options to be applied, got", c.Index(), report.Recommended.Options)
+	}
+	x := c.index.(*ivfIndex)
+	if len(x.centroids) != c.Index().Lists || x.nProbe != c.Index().NProbe || len(x.lists.postings) != n {
+		t.Fatal("expected the index of the recommended options, got", len(x.centroids), x.nProbe, len(x.lists.postings))
+	}
+}
+
+func TestIVFIndex_set(t *testing.T) {
+	x := newIVFIndex([][]float32{{1, 0}, {0, 1}}, 1, []*Document{
+		{ID: "1", Embedding: []float32{1, 0}},
+		{ID: "2", Embedding: []float32{0.8, 0.6}},
+		{ID: "3", Embedding: []float32{0.6, 0.8}},
+	}).(*ivfIndex)
+	if len(x.lists.lists[0]) != 2 || len(x.lists.lists[1]) != 1 {
+		t.Fatal("expected 2 and 1 documents in the lists, got", x.lists.lists)
+	}
+	// Moved to the other list
+	x.set(&Document{ID: "2", Embedding: []float32{0, 1}})
+	if len(x.lists.lists[0]) != 1 || len(x.lists.lists[1]) != 2 {
+		t.Fatal("expected 1 and 2 documents in the lists, got", x.lists.lists)
+	}
+	// Another dimension
+	x.set(&Document{ID: "4", Embedding: []float32{0, 0, 1}})
+	if _, ok := x.candidates([]float32{1, 0}, nil); ok {
+		t.Fatal("expected stale index not to be used")
+	}
+}
@@ -0,0 +1,229 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// languageStopwords are frequent function words of languages with Latin script,
+// by ISO 639-1 code. The order breaks ties in [DetectLanguage].
+var languageStopwords = []struct {
+	language string
+	words    map[string]struct{}
+}{
+	{"en", stopwordSet("the and of to is in that it for with as was on are this be by not you have from or which what how")},
+	{"de", stopwordSet("der die das und ist nicht ein eine zu den mit sich des auf für im dem auch es wie wird sind von ich was")},
+	{"fr", stopwordSet("le la les et est des une un du que pour dans pas qui sur au avec ce il sont par plus je ne comment")},
+	{"es", stopwordSet("el la los las y es de que en un una por con para del se no al lo como más pero sus está qué cómo")},
+	{"it", stopwordSet("il lo la gli le e è di che un una per non con del della sono si nel anche come più ma questo")},
+	{"nl", stopwordSet("de het een en is van niet dat die in op te zijn met voor er maar ook als wat hoe wordt")},
+	{"pt", stopwordSet("o a os as e é de que um uma não para com do da em no na por mais se como dos são")},
+}
+
+func stopwordSet(words string) map[string]struct{} {
+	res := make(map[string]struct{})
+	for _, word := range strings.Fields(words) {
+		res[word] = struct{}{}
+	}
+	return res
+}
+
+// languageScripts are the Unicode scripts that identify a language on their
+// own, by ISO 639-1 code. Han and Kana are handled separately for Chinese and
+// Japanese.
+var languageScripts = []struct {
+	language string
+	script   *unicode.RangeTable
+}{
+	{"ru", unicode.Cyrillic},
+	{"el", unicode.Greek},
+	{"ar", unicode.Arabic},
+	{"he", unicode.Hebrew},
+	{"hi", unicode.Devanagari},
+	{"th", unicode.Thai},
+	{"ko", unicode.Hangul},
+}
+
+// DetectLanguage returns the ISO 639-1 code of the text's language, for example
+// "en" or "de", or an empty string if it can't be detected.
+//
+// It's a fast heuristic without a model: Languages with their own script are
+// detected by the script, with Japanese detected by Kana, Chinese by Han
+// characters without Kana, and Cyrillic detected as Russian. English, German,
+// French, Spanish, Italian, Dutch and Portuguese are detected by the frequency
+// of common words, which works for sentences, but not for single words or
+// keywords. For other languages and better accuracy, use a dedicated library and
+// set the language in the metadata of the documents yourself.
+func DetectLanguage(text string) string {
+	scripts := make([]int, len(languageScripts))
+	latin, han, kana := 0, 0, 0
+	for _, r := range text {
+		switch {
+		case !unicode.IsLetter(r):
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		default:
+			for i, s := range languageScripts {
+				if unicode.Is(s.script, r) {
+					scripts[i]++
+					break
+				}
+			}
+		}
+	}
+
+	// The most frequent script. Japanese mixes Kana with Han characters.
+	best, bestCount := "", latin
+	if kana > 0 && kana+han > bestCount {
+		best, bestCount = "ja", kana+han
+	} else if han > bestCount {
+		best, bestCount = "zh", han
+	}
+	for i, count := range scripts {
+		if count > bestCount {
+			best, bestCount = languageScripts[i].language, count
+		}
+	}
+	if bestCount == 0 || best != "" {
+		return best
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	bestScore := 0
+	for _, l := range languageStopwords {
+		score := 0
+		for _, word := range words {
+			if _, ok := l.words[word]; ok {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = l.language, score
+		}
+	}
+	return best
+}
+
+// SetLanguageDetection enables the detection of the language of documents when
+// they're added, with [DetectLanguage]. The language is stored in the
+// document's metadata with the key, for example "language", unless the document
+// has the key already, so languages that are known or detected otherwise take
+// precedence. Documents whose language can't be detected don't get the key.
+// Queries can then be restricted to documents in the language of the query with
+// [QueryOptions.SameLanguage]. To make those queries faster, partition the
+// collection by the key with [Collection.PartitionBy].
+//
+// An empty key disables the detection. Existing documents aren't changed. The
+// key is persisted.
+func (c *Collection) SetLanguageDetection(metadataKey string) error {
+	c.configLock.Lock()
+	c.config.LanguageMetadataKey = metadataKey
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// LanguageDetection returns the metadata key that the detected language of
+// documents is stored with, or an empty string if the detection is disabled.
+func (c *Collection) LanguageDetection() string {
+	return c.getConfig().LanguageMetadataKey
+}
+
+// sameLanguageFilter returns a copy of the filter that additionally restricts
+// the query to documents in the language of the query text. The filter is
+// returned unchanged if the query's language can't be detected.
+func (c *Collection) sameLanguageFilter(filter *Filter, queryText string) (*Filter, error) {
+	key := c.getConfig().LanguageMetadataKey
+	if key == "" {
+		return nil, errors.New("same language queries require language detection, see Collection.SetLanguageDetection")
+	}
+	language := DetectLanguage(queryText)
+	if language == "" {
+		return filter, nil
+	}
+	return filter.withMetadataCondition(key, language), nil
+}
+
+// LanguageRouter routes documents and queries to collections by their language,
+// for example to use a different embedding model per language, which are set in
+// the collections. Documents are routed by the language of their content, and
+// queries by the language of their text.
+type LanguageRouter struct {
+	// The collections by ISO 639-1 language code, see [DetectLanguage].
+	Collections map[string]*Collection
+
+	// The collection for languages that can't be detected or don't have a
+	// collection, for example one with a multilingual embedding model.
+	// Optional. Without it, routing such documents and queries fails.
+	Default *Collection
+
+	// Detects the language of texts. Optional. [DetectLanguage] is used if nil.
+	Detector func(text string) string
+}
+
+// Route returns the collection for the text and its detected language, which is
+// empty if it can't be detected.
+func (r *LanguageRouter) Route(text string) (*Collection, string, error) {
+	detect := r.Detector
+	if detect == nil {
+		detect = DetectLanguage
+	}
+	language := detect(text)
+	if c, ok := r.Collections[language]; ok && language != "" {
+		return c, language, nil
+	}
+	if r.Default != nil {
+		return r.Default, language, nil
+	}
+	if language == "" {
+		return nil, "", errors.New("couldn't detect language and there's no default collection")
+	}
+	return nil, language, fmt.Errorf("no collection for language %q and no default collection", language)
+}
+
+// AddDocuments adds the documents to the collections of their languages, see
+// [Collection.AddDocuments]. Documents without content go to the default
+// collection. The documents are routed before any of them are added, so if one
+// of them can't be routed, none are added.
+func (r *LanguageRouter) AddDocuments(ctx context.Context, documents []Document, concurrency int) error {
+	var order []*Collection
+	batches := make(map[*Collection][]Document)
+	for _, doc := range documents {
+		c, _, err := r.Route(doc.Content)
+		if err != nil {
+			return fmt.Errorf("couldn't route document %q: %w", doc.ID, err)
+		}
+		if _, ok := batches[c]; !ok {
+			order = append(order, c)
+		}
+		batches[c] = append(batches[c], doc)
+	}
+	for _, c := range order {
+		if err := c.AddDocuments(ctx, batches[c], concurrency); err != nil {
+			return fmt.Errorf("couldn't add documents to collection %q: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// Query queries the collection of the language of the query text, see
+// [Collection.QueryWithOptions]. The query must have a text.
+func (r *LanguageRouter) Query(ctx context.Context, options QueryOptions) ([]Result, error) {
+	if options.QueryText == "" {
+		return nil, &ValidationError{Field: "QueryText", Err: errors.New("QueryText is empty, but needed for routing by language")}
+	}
+	c, _, err := r.Route(options.QueryText)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't route query: %w", err)
+	}
+	return c.QueryWithOptions(ctx, options)
+}
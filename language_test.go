@@ -0,0 +1,152 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tt := []struct {
+		text     string
+		expected string
+	}{
+		{"The quick brown fox jumps over the lazy dog and runs away.", "en"},
+		{"Der schnelle braune Fuchs springt über den faulen Hund und ist weg.", "de"},
+		{"Le renard brun saute par-dessus le chien paresseux et il est parti.", "fr"},
+		{"El zorro marrón salta sobre el perro perezoso y se va.", "es"},
+		{"Il cane è molto pigro e la volpe non lo è.", "it"},
+		{"De snelle bruine vos springt over de luie hond en het is weg.", "nl"},
+		{"A raposa marrom pula sobre o cão preguiçoso e não volta mais.", "pt"},
+		{"Быстрая коричневая лиса прыгает через ленивую собаку.", "ru"},
+		{"素早い茶色の狐が怠惰な犬を飛び越える。", "ja"},
+		{"敏捷的棕色狐狸跳过了懒狗。", "zh"},
+		{"빠른 갈색 여우가 게으른 개를 뛰어넘는다.", "ko"},
+		{"Chromem", ""},
+		{"123 !?", ""},
+		{"", ""},
+	}
+	for _, tc := range tt {
+		if language := DetectLanguage(tc.text); language != tc.expected {
+			t.Errorf("expected %q for %q, got %q", tc.expected, tc.text, language)
+		}
+	}
+}
+
+func TestCollection_SetLanguageDetection(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{0, 1}, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollectionWithOptions("test", CollectionOptions{EmbeddingFunc: embeddingFunc, LanguageMetadataKey: "lang"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.LanguageDetection() != "lang" {
+		t.Fatal("expected language detection with key lang, got", c.LanguageDetection())
+	}
+
+	metadata := map[string]string{"source": "web"}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "en", Content: "This is a document about the history of the city.", Metadata: metadata},
+		{ID: "de", Content: "Das ist ein Dokument über die Geschichte der Stadt."},
+		{ID: "known", Content: "This is a document in English.", Metadata: map[string]string{"lang": "fr"}},
+		{ID: "unknown", Content: "Chromem"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected := map[string]string{"en": "en", "de": "de", "known": "fr", "unknown": ""}
+	for id, language := range expected {
+		doc := c.documents[id]
+		if doc.Metadata["lang"] != language {
+			t.Fatalf("expected language %q for document %q, got %q", language, id, doc.Metadata["lang"])
+		}
+	}
+	if _, ok := metadata["lang"]; ok {
+		t.Fatal("expected the caller's metadata not to be modified")
+	}
+
+	res, err := c.QueryWithOptions(ctx, QueryOptions{QueryText: "What is the history of the city?", NResults: 4, SameLanguage: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "en" {
+		t.Fatal("expected only the English document, got", res)
+	}
+	// Without a detected language, the query isn't restricted.
+	res, err = c.QueryWithOptions(ctx, QueryOptions{QueryText: "Chromem", NResults: 4, SameLanguage: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 4 {
+		t.Fatal("expected 4 results, got", len(res))
+	}
+
+	_, err = c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{0, 1}, NResults: 1, SameLanguage: true})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.SetLanguageDetection("")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.QueryWithOptions(ctx, QueryOptions{QueryText: "What is the history of the city?", NResults: 1, SameLanguage: true})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestLanguageRouter(t *testing.T) {
+	ctx := context.Background()
+	newEmbeddingFunc := func(embedding []float32) EmbeddingFunc {
+		return func(_ context.Context, _ string) ([]float32, error) {
+			return embedding, nil
+		}
+	}
+	db := NewDB()
+	en, err := db.CreateCollection("en", nil, newEmbeddingFunc([]float32{0, 1}))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Another embedding model with another dimension
+	de, err := db.CreateCollection("de", nil, newEmbeddingFunc([]float32{0, 0, 1}))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	r := &LanguageRouter{Collections: map[string]*Collection{"en": en, "de": de}}
+
+	docs := []Document{
+		{ID: "1", Content: "This is a document about the history of the city."},
+		{ID: "2", Content: "Das ist ein Dokument über die Geschichte der Stadt."},
+		{ID: "3", Content: "Le document est sur l'histoire de la ville."},
+	}
+	err = r.AddDocuments(ctx, docs, 1)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if en.Count() != 0 || de.Count() != 0 {
+		t.Fatal("expected no documents to be added")
+	}
+
+	r.Default = en
+	err = r.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if en.Count() != 2 || de.Count() != 1 {
+		t.Fatal("expected 2 and 1 documents, got", en.Count(), de.Count())
+	}
+
+	res, err := r.Query(ctx, QueryOptions{QueryText: "Was ist die Geschichte der Stadt?", NResults: 1})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "2" {
+		t.Fatal("expected document 2, got", res)
+	}
+	_, err = r.Query(ctx, QueryOptions{QueryEmbedding: []float32{0, 1}, NResults: 1})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,157 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LimitOptions are the options for [NewLimitMiddleware]. Zero values disable
+// the respective limit.
+type LimitOptions struct {
+	// The number of requests per second that each client can make on average.
+	RequestsPerSecond float64
+	// The number of requests that each client can make at once, in addition to
+	// the average rate. Defaults to 1 if RequestsPerSecond is set.
+	Burst int
+	// ClientKey identifies the client of a request for the rate limit. By
+	// default, it's the principal (see [NewAuthMiddleware]) if there is one, or
+	// the IP address of the remote end otherwise. When the server runs behind a
+	// reverse proxy, you can use a header like "X-Forwarded-For" instead.
+	ClientKey func(r *http.Request) string
+
+	// The maximum size of request bodies in bytes. Larger requests fail when
+	// the handler reads the body.
+	MaxBodyBytes int64
+	// The maximum number of results per query. Larger numbers in queries are
+	// reduced to it.
+	MaxResults int
+}
+
+type maxResultsKey struct{}
+
+// NewLimitMiddleware returns a middleware that limits the requests to the HTTP
+// handlers of chromem-go, like [AdminHandler], [NewVectorStoresHandler] and
+// [MCPServer], so that a single misbehaving client can't tip over a shared
+// instance. Clients that exceed the rate limit get a 429 Too Many Requests
+// response with a Retry-After header.
+//
+// To rate limit per principal, wrap it with [NewAuthMiddleware]:
+//
+//	handler = auth(limit(handler))
+func NewLimitMiddleware(opts LimitOptions) (func(http.Handler) http.Handler, error) {
+	if opts.RequestsPerSecond < 0 || opts.Burst < 0 || opts.MaxBodyBytes < 0 || opts.MaxResults < 0 {
+		return nil, errors.New("limits must be >= 0")
+	}
+	if opts.Burst == 0 {
+		opts.Burst = 1
+	}
+	if opts.ClientKey == nil {
+		opts.ClientKey = defaultClientKey
+	}
+	limiter := &rateLimiter{
+		rate:    opts.RequestsPerSecond,
+		burst:   float64(opts.Burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.RequestsPerSecond > 0 {
+				if wait := limiter.take(opts.ClientKey(r), time.Now()); wait > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+					writeJSONError(w, http.StatusTooManyRequests, fmt.Errorf("rate limit of %g requests per second exceeded", opts.RequestsPerSecond))
+					return
+				}
+			}
+			if opts.MaxBodyBytes > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, opts.MaxBodyBytes)
+			}
+			if opts.MaxResults > 0 {
+				r = r.WithContext(context.WithValue(r.Context(), maxResultsKey{}, opts.MaxResults))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// defaultClientKey returns the principal of the request, or its remote IP
+// address.
+func defaultClientKey(r *http.Request) string {
+	if principal := PrincipalFromContext(r.Context()); principal != "" {
+		return "principal:" + principal
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// limitResults reduces the number of results to the maximum of the context, if
+// there is one.
+func limitResults(ctx context.Context, nResults int) int {
+	if maxResults, ok := ctx.Value(maxResultsKey{}).(int); ok && nResults > maxResults {
+		return maxResults
+	}
+	return nResults
+}
+
+// rateLimiter is a token bucket rate limiter per client.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+	lock        sync.Mutex
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take takes a token from the client's bucket. If there's none, it returns how
+// long to wait for the next one.
+func (l *rateLimiter) take(client string, now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.cleanupLocked(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+// cleanupLocked removes the buckets that are full again, as they're the same
+// as new ones, so the memory usage doesn't grow with the number of clients.
+// It runs at most once per minute.
+func (l *rateLimiter) cleanupLocked(now time.Time) {
+	if now.Sub(l.lastCleanup) < time.Minute {
+		return
+	}
+	l.lastCleanup = now
+	fullAfter := time.Duration(l.burst / l.rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= fullAfter {
+			delete(l.buckets, client)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLimitMiddleware(t *testing.T) {
+	ctx := context.Background()
+	vectors := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return vectors, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Add(ctx, []string{"1", "2", "3"}, nil, nil, []string{"hello world", "hallo welt", "bonjour"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	t.Run("Rate limit", func(t *testing.T) {
+		limit, err := NewLimitMiddleware(LimitOptions{RequestsPerSecond: 0.1, Burst: 2})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		ts := httptest.NewServer(limit(AdminHandler(db)))
+		defer ts.Close()
+
+		for i, expected := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+			res, err := http.Get(ts.URL + "/api/collections")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			res.Body.Close()
+			if res.StatusCode != expected {
+				t.Fatalf("expected status %d for request %d, got %d", expected, i, res.StatusCode)
+			}
+			if expected == http.StatusTooManyRequests && res.Header.Get("Retry-After") == "" {
+				t.Fatal("expected Retry-After header")
+			}
+		}
+	})
+
+	t.Run("Body and results limits", func(t *testing.T) {
+		limit, err := NewLimitMiddleware(LimitOptions{MaxBodyBytes: 100, MaxResults: 2})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		ts := httptest.NewServer(limit(AdminHandler(db)))
+		defer ts.Close()
+
+		body := `{"query": "hello", "nResults": 3}`
+		res, err := http.Post(ts.URL+"/api/collections/test/query", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		defer res.Body.Close()
+		var results []adminResult
+		err = json.NewDecoder(res.Body).Decode(&results)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(results) != 2 {
+			t.Fatal("expected 2 results, got", len(results))
+		}
+
+		body = `{"query": "` + strings.Repeat("a", 100) + `"}`
+		res2, err := http.Post(ts.URL+"/api/collections/test/query", "application/json", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		res2.Body.Close()
+		if res2.StatusCode != http.StatusBadRequest {
+			t.Fatal("expected status 400, got", res2.StatusCode)
+		}
+	})
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{rate: 1, burst: 1, buckets: make(map[string]*tokenBucket)}
+	now := time.Now()
+	if wait := l.take("a", now); wait != 0 {
+		t.Fatal("expected no wait, got", wait)
+	}
+	if wait := l.take("a", now.Add(500*time.Millisecond)); wait != 500*time.Millisecond {
+		t.Fatal("expected wait of 500ms, got", wait)
+	}
+	// Other clients have their own bucket
+	if wait := l.take("b", now); wait != 0 {
+		t.Fatal("expected no wait, got", wait)
+	}
+	if wait := l.take("a", now.Add(time.Second)); wait != 0 {
+		t.Fatal("expected no wait, got", wait)
+	}
+	// Full buckets are cleaned up
+	l.take("c", now.Add(2*time.Minute))
+	if len(l.buckets) != 1 {
+		t.Fatal("expected 1 bucket after cleanup, got", len(l.buckets))
+	}
+}
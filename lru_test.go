@@ -0,0 +1,34 @@
+package chromem
+
+import "testing"
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache[string, int](2)
+	c.add("a", 1)
+	c.add("b", 2)
+	// Use "a", so that "b" is the least recently used one
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Fatal("expected 1, got", v, ok)
+	}
+	c.add("c", 3)
+	if _, ok := c.get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if c.len() != 2 {
+		t.Fatal("expected 2 entries, got", c.len())
+	}
+
+	// Update
+	c.add("a", 4)
+	if v, _ := c.get("a"); v != 4 {
+		t.Fatal("expected 4, got", v)
+	}
+
+	c.remove("a")
+	if _, ok := c.get("a"); ok {
+		t.Fatal("expected a to be removed")
+	}
+	if c.len() != 1 {
+		t.Fatal("expected 1 entry, got", c.len())
+	}
+}
@@ -0,0 +1,217 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// MaintenanceTask is a custom maintenance task that's run for each collection,
+// see [MaintenanceOptions.Tasks].
+type MaintenanceTask func(ctx context.Context, c *Collection) error
+
+// MaintenanceOptions are the options for [DB.StartMaintenance] and
+// [DB.RunMaintenance]. The tasks are run in the order of the fields.
+type MaintenanceOptions struct {
+	// The interval in which the maintenance runs in the background. Required for
+	// [DB.StartMaintenance].
+	Interval time.Duration
+
+	// ExpiresAtKey is the metadata key that holds the expiry time of documents,
+	// as RFC 3339 timestamp. Expired documents are deleted like with
+	// [Collection.Delete], so with soft deletes they're moved to the trash.
+	// Values that aren't valid timestamps are ignored. Empty disables the expiry.
+	ExpiresAtKey string
+
+	// PurgeTrash purges the trash of collections with soft deletes, see
+	// [Collection.PurgeTrash].
+	PurgeTrash bool
+
+	// Compact compacts the collections, see [Collection.Compact]. This blocks
+	// the collection while it runs, so it should be used with a long interval.
+	Compact bool
+
+	// FlushAccessStats writes the access stats of the collections to disk, see
+	// [Collection.FlushAccessStats].
+	FlushAccessStats bool
+
+	// Custom tasks, for example to refresh statistics or rebuild indexes.
+	Tasks []MaintenanceTask
+
+	// OnError is called for errors of the background maintenance. By default
+	// they're logged via [slog.Default].
+	OnError func(err error)
+}
+
+// maintenance is a running background maintenance.
+type maintenance struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartMaintenance starts the maintenance of all collections in the background,
+// which is run every opts.Interval until [DB.StopMaintenance] or [DB.Close] is
+// called. It's opt-in, and an error is returned if it's running already.
+// Errors don't stop the maintenance, and are passed to opts.OnError instead.
+func (db *DB) StartMaintenance(opts MaintenanceOptions) error {
+	if opts.Interval <= 0 {
+		return errors.New("interval must be > 0")
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(err error) {
+			slog.Error("Maintenance failed", "error", err)
+		}
+	}
+
+	db.maintenanceLock.Lock()
+	defer db.maintenanceLock.Unlock()
+
+	if db.maintenance != nil {
+		return errors.New("maintenance is running already")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &maintenance{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := db.RunMaintenance(ctx, opts)
+				if err != nil && ctx.Err() == nil {
+					opts.OnError(err)
+				}
+			}
+		}
+	}()
+	db.maintenance = m
+
+	return nil
+}
+
+// StopMaintenance stops the background maintenance and waits for a running
+// maintenance to be aborted. It's a no-op if the maintenance isn't running.
+func (db *DB) StopMaintenance() {
+	db.maintenanceLock.Lock()
+	defer db.maintenanceLock.Unlock()
+
+	if db.maintenance == nil {
+		return
+	}
+	db.maintenance.cancel()
+	<-db.maintenance.done
+	db.maintenance = nil
+}
+
+// Close stops the background maintenance of the DB, see [DB.StartMaintenance].
+// All data of persistent DBs is written synchronously, except for the access
+// stats of documents, which are written now (see [Collection.SetAccessStats]).
+// With [DurabilityInterval] it then stops the interval syncs after syncing the
+// latest writes, and returns the errors of the interval syncs, if any.
+// Afterwards, creating and deleting collections and adding and deleting
+// documents fails with [ErrClosed]. Closing a closed DB is a no-op.
+func (db *DB) Close() error {
+	db.closed.Store(true)
+	db.StopMaintenance()
+	var errs []error
+	for _, c := range db.ListCollections() {
+		err := c.FlushAccessStats()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't flush access stats of collection %q: %w", c.Name, err))
+		}
+	}
+	return errors.Join(append(errs, db.syncer.close())...)
+}
+
+// checkOpen returns [ErrClosed] if the DB is closed. A nil DB, as of
+// collections without DB, is open.
+func (db *DB) checkOpen() error {
+	if db != nil && db.closed.Load() {
+		return ErrClosed
+	}
+	return nil
+}
+
+// RunMaintenance runs the maintenance of all collections once, in the
+// foreground. It continues with the next task and collection when a task
+// fails, and returns all errors.
+func (db *DB) RunMaintenance(ctx context.Context, opts MaintenanceOptions) error {
+	var errs []error
+	for _, c := range db.ListCollections() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := c.runMaintenance(ctx, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't maintain collection %q: %w", c.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runMaintenance runs the maintenance tasks for the collection.
+func (c *Collection) runMaintenance(ctx context.Context, opts MaintenanceOptions) error {
+	var errs []error
+	if opts.ExpiresAtKey != "" {
+		_, err := c.expireDocuments(ctx, opts.ExpiresAtKey, time.Now())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't expire documents: %w", err))
+		}
+	}
+	if opts.PurgeTrash {
+		_, err := c.PurgeTrash()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't purge trash: %w", err))
+		}
+	}
+	if opts.Compact {
+		_, err := c.Compact(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't compact: %w", err))
+		}
+	}
+	if opts.FlushAccessStats {
+		err := c.FlushAccessStats()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, task := range opts.Tasks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := task(ctx, c)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// expireDocuments deletes the documents whose expiry time in the metadata key
+// is at or before now. It returns the number of expired documents.
+func (c *Collection) expireDocuments(ctx context.Context, key string, now time.Time) (int, error) {
+	var expired []string
+	c.documentsLock.RLock()
+	for id, doc := range c.documents {
+		expiresAt, err := time.Parse(time.RFC3339, doc.Metadata[key])
+		if err == nil && !expiresAt.After(now) {
+			expired = append(expired, id)
+		}
+	}
+	c.documentsLock.RUnlock()
+
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	return len(expired), c.deleteDocuments(ContextWithPrincipal(ctx, AuditPrincipalSystem), AuditActionExpire, nil, nil, expired...)
+}
@@ -0,0 +1,130 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDB_RunMaintenance(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewPersistentDB(t.TempDir(), false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	docs := []Document{
+		{ID: "expired", Embedding: []float32{1, 0}, Metadata: map[string]string{"expires_at": past}},
+		{ID: "valid", Embedding: []float32{1, 0}, Metadata: map[string]string{"expires_at": future}},
+		{ID: "invalid", Embedding: []float32{1, 0}, Metadata: map[string]string{"expires_at": "tomorrow"}},
+		{ID: "none", Embedding: []float32{1, 0}},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	var tasks []string
+	opts := MaintenanceOptions{
+		ExpiresAtKey: "expires_at",
+		PurgeTrash:   true,
+		Compact:      true,
+		Tasks: []MaintenanceTask{
+			func(_ context.Context, c *Collection) error {
+				tasks = append(tasks, c.Name)
+				return nil
+			},
+		},
+	}
+	err = db.RunMaintenance(ctx, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != 3 {
+		t.Fatal("expected 3 documents, got", c.Count())
+	}
+	if _, ok := c.documents["expired"]; ok {
+		t.Fatal("expected expired document to be deleted")
+	}
+	if len(tasks) != 1 || tasks[0] != "test" {
+		t.Fatal("expected custom task to run for test, got", tasks)
+	}
+
+	// Errors of tasks are returned, and don't stop the other collections
+	_, err = db.CreateCollection("other", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	opts.Tasks = []MaintenanceTask{
+		func(_ context.Context, c *Collection) error {
+			return errors.New("failed " + c.Name)
+		},
+	}
+	err = db.RunMaintenance(ctx, opts)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "failed test") || !strings.Contains(err.Error(), "failed other") {
+		t.Fatal("expected errors of both collections, got", err)
+	}
+}
+
+func TestDB_StartMaintenance(t *testing.T) {
+	db := NewDB()
+	_, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = db.StartMaintenance(MaintenanceOptions{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	runs := atomic.Int64{}
+	ran := make(chan struct{}, 1)
+	err = db.StartMaintenance(MaintenanceOptions{
+		Interval: time.Millisecond,
+		Tasks: []MaintenanceTask{
+			func(_ context.Context, _ *Collection) error {
+				runs.Add(1)
+				select {
+				case ran <- struct{}{}:
+				default:
+				}
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.StartMaintenance(MaintenanceOptions{Interval: time.Millisecond})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected maintenance to run")
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	stopped := runs.Load()
+	time.Sleep(10 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Fatal("expected maintenance to be stopped")
+	}
+}
@@ -0,0 +1,430 @@
+package chromem
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// mcpProtocolVersion is the latest Model Context Protocol version we support.
+// When a client requests another version, we still answer with this one and let
+// the client decide whether it can work with it, as the spec suggests.
+const mcpProtocolVersion = "2025-03-26"
+
+// JSON-RPC 2.0 error codes, see https://www.jsonrpc.org/specification#error_object
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+)
+
+// MCPServer exposes the collections of a DB as retrieval tools via the Model
+// Context Protocol (MCP), so that agents like Claude Desktop or IDE assistants
+// can use chromem-go as knowledge base without custom glue code.
+// See https://modelcontextprotocol.io for details about the protocol.
+//
+// The server offers the following tools:
+//
+//   - list_collections: Lists all collections with their metadata and counts
+//   - query: Queries a collection for the documents most similar to a text
+//   - add_documents: Adds documents to a collection, creating it if necessary
+//
+// It can be served via stdio (see [MCPServer.ServeStdio]), which is how most
+// desktop clients start local MCP servers, or via HTTP, as MCPServer implements
+// [http.Handler].
+//
+// The tools use the embedding functions of the collections. Collections that
+// don't have one yet, like the ones of a persistent DB that was just loaded, get
+// the embedding function that's passed to [NewMCPServer].
+type MCPServer struct {
+	db *DB
+
+	// Used for new collections created via the add_documents tool. Can be nil
+	// to use the default embedding function.
+	embeddingFunc EmbeddingFunc
+}
+
+// NewMCPServer creates a new MCP server for the given DB.
+// The embeddingFunc is used for collections that are created via the add_documents
+// tool and for persisted collections that don't have an embedding function yet.
+// It can be nil, in which case the default one is used.
+func NewMCPServer(db *DB, embeddingFunc EmbeddingFunc) *MCPServer {
+	return &MCPServer{
+		db:            db,
+		embeddingFunc: embeddingFunc,
+	}
+}
+
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+var mcpTools = []mcpTool{
+	{
+		Name:        "list_collections",
+		Description: "Lists all collections of the vector database with their metadata and number of documents.",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{},
+		},
+	},
+	{
+		Name:        "query",
+		Description: "Searches a collection for the documents that are semantically most similar to the query text.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"collection": map[string]any{"type": "string", "description": "Name of the collection to search"},
+				"query":      map[string]any{"type": "string", "description": "Text to search for"},
+				"n_results":  map[string]any{"type": "integer", "description": "Maximum number of results, defaults to 5"},
+				"where": map[string]any{
+					"type":                 "object",
+					"description":          `Optional exact matches on document metadata. Documents that match the object of the "$not" key, like {"$not": {"archived": "true"}}, are excluded.`,
+					"additionalProperties": map[string]any{"type": []string{"string", "object"}},
+				},
+			},
+			"required": []string{"collection", "query"},
+		},
+	},
+	{
+		Name:        "add_documents",
+		Description: "Adds documents to a collection. The collection is created if it doesn't exist yet.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"collection": map[string]any{"type": "string", "description": "Name of the collection"},
+				"documents": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"id":      map[string]any{"type": "string"},
+							"content": map[string]any{"type": "string"},
+							"metadata": map[string]any{
+								"type":                 "object",
+								"additionalProperties": map[string]any{"type": "string"},
+							},
+						},
+						"required": []string{"id", "content"},
+					},
+				},
+			},
+			"required": []string{"collection", "documents"},
+		},
+	},
+}
+
+// ServeStdio serves the MCP server on the given reader and writer, typically
+// [os.Stdin] and [os.Stdout]. Messages are newline-delimited JSON-RPC messages.
+// It returns when the reader is exhausted, in which case the error is nil, or
+// when reading or writing fails. The context is used for the tool calls; as
+// reading from the reader blocks, a canceled context only takes effect after
+// the next message.
+func (s *MCPServer) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	for {
+		line, readErr := br.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			res := s.handleMessage(ctx, line)
+			if res != nil {
+				res = append(res, '\n')
+				if _, err := w.Write(res); err != nil {
+					return fmt.Errorf("couldn't write response: %w", err)
+				}
+			}
+		}
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("couldn't read message: %w", readErr)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// ServeHTTP implements [http.Handler] for the MCP "Streamable HTTP" transport,
+// without server-initiated streams: Each POST request contains one JSON-RPC
+// message or batch and is answered with a single JSON response.
+func (s *MCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "couldn't read request body", http.StatusBadRequest)
+		return
+	}
+	res := s.handleMessage(r.Context(), body)
+	if res == nil {
+		// Only notifications or responses, which don't get a response
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(res)
+}
+
+// handleMessage handles a single JSON-RPC message or batch and returns the
+// encoded response, or nil if there's nothing to respond.
+func (s *MCPServer) handleMessage(ctx context.Context, msg []byte) []byte {
+	if len(msg) > 0 && msg[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(msg, &batch); err != nil {
+			return mustMarshal(newJSONRPCError(nil, jsonRPCParseError, "parse error"))
+		}
+		var responses []jsonRPCResponse
+		for _, m := range batch {
+			if res := s.handleRequest(ctx, m); res != nil {
+				responses = append(responses, *res)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		return mustMarshal(responses)
+	}
+
+	res := s.handleRequest(ctx, msg)
+	if res == nil {
+		return nil
+	}
+	return mustMarshal(res)
+}
+
+// handleRequest handles a single JSON-RPC request. It returns nil for notifications.
+func (s *MCPServer) handleRequest(ctx context.Context, msg []byte) *jsonRPCResponse {
+	var req jsonRPCRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return newJSONRPCError(nil, jsonRPCParseError, "parse error")
+	}
+	// Notifications (like "notifications/initialized") and responses to our
+	// (nonexistent) requests don't have a method with ID, so there's nothing to
+	// respond.
+	if len(req.ID) == 0 {
+		return nil
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return newJSONRPCError(req.ID, jsonRPCInvalidRequest, "invalid request")
+	}
+
+	switch req.Method {
+	case "initialize":
+		return newJSONRPCResult(req.ID, map[string]any{
+			"protocolVersion": mcpProtocolVersion,
+			"capabilities": map[string]any{
+				"tools": map[string]any{},
+			},
+			"serverInfo": map[string]any{
+				"name":    "chromem-go",
+				"version": "vNext",
+			},
+		})
+	case "ping":
+		return newJSONRPCResult(req.ID, map[string]any{})
+	case "tools/list":
+		return newJSONRPCResult(req.ID, map[string]any{"tools": mcpTools})
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return newJSONRPCError(req.ID, jsonRPCInvalidParams, "invalid params")
+		}
+		var res any
+		var err error
+		switch params.Name {
+		case "list_collections":
+			res = s.listCollections(ctx)
+		case "query":
+			res, err = s.query(ctx, params.Arguments)
+		case "add_documents":
+			res, err = s.addDocuments(ctx, params.Arguments)
+		default:
+			return newJSONRPCError(req.ID, jsonRPCInvalidParams, fmt.Sprintf("unknown tool: %q", params.Name))
+		}
+		// Tool errors are reported as results, so that the model can see them.
+		if err != nil {
+			return newJSONRPCResult(req.ID, mcpToolResult{
+				Content: []mcpContent{{Type: "text", Text: err.Error()}},
+				IsError: true,
+			})
+		}
+		return newJSONRPCResult(req.ID, mcpToolResult{
+			Content: []mcpContent{{Type: "text", Text: string(mustMarshal(res))}},
+		})
+	default:
+		return newJSONRPCError(req.ID, jsonRPCMethodNotFound, fmt.Sprintf("method not found: %q", req.Method))
+	}
+}
+
+func (s *MCPServer) listCollections(ctx context.Context) []adminCollection {
+	collections := s.db.ListCollections()
+	res := make([]adminCollection, 0, len(collections))
+	for name, c := range collections {
+		if !authorized(ctx, name, PermissionRead) {
+			continue
+		}
+		res = append(res, c.adminInfo())
+	}
+	slices.SortFunc(res, func(a, b adminCollection) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return res
+}
+
+func (s *MCPServer) query(ctx context.Context, arguments json.RawMessage) ([]adminResult, error) {
+	var args struct {
+		Collection string    `json:"collection"`
+		Query      string    `json:"query"`
+		NResults   int       `json:"n_results"`
+		Where      whereJSON `json:"where"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if !authorized(ctx, args.Collection, PermissionRead) {
+		return nil, fmt.Errorf("no read permission for collection %q", args.Collection)
+	}
+	c := s.db.GetCollection(args.Collection, s.embeddingFunc)
+	if c == nil {
+		return nil, fmt.Errorf("%w: %q", ErrCollectionNotFound, args.Collection)
+	}
+
+	// The query fails when requesting more results than there are documents,
+	// but the model can't know how many there are, so we cap the value.
+	nResults := args.NResults
+	if nResults <= 0 {
+		nResults = 5
+	}
+	nResults = limitResults(ctx, nResults)
+	if count := c.Count(); nResults > count {
+		nResults = count
+	}
+	if nResults == 0 {
+		return []adminResult{}, nil
+	}
+
+	results, err := c.Query(ctx, args.Query, nResults, args.Where, nil)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]adminResult, 0, len(results))
+	for _, result := range results {
+		res = append(res, adminResult{
+			ID:         result.ID,
+			Metadata:   result.Metadata,
+			Content:    result.Content,
+			Similarity: result.Similarity,
+		})
+	}
+	return res, nil
+}
+
+func (s *MCPServer) addDocuments(ctx context.Context, arguments json.RawMessage) (map[string]int, error) {
+	var args struct {
+		Collection string `json:"collection"`
+		Documents  []struct {
+			ID       string            `json:"id"`
+			Content  string            `json:"content"`
+			Metadata map[string]string `json:"metadata"`
+		} `json:"documents"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if !authorized(ctx, args.Collection, PermissionWrite) {
+		return nil, fmt.Errorf("no write permission for collection %q", args.Collection)
+	}
+	c, err := s.db.GetOrCreateCollection(args.Collection, nil, s.embeddingFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]Document, 0, len(args.Documents))
+	for _, d := range args.Documents {
+		docs = append(docs, Document{
+			ID:       d.ID,
+			Content:  d.Content,
+			Metadata: d.Metadata,
+		})
+	}
+	err = c.AddDocuments(ctx, docs, mcpAddConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int{"added": len(docs)}, nil
+}
+
+// mcpAddConcurrency is the concurrency used for adding documents via MCP. Most
+// embedding APIs have rate limits, so we keep it low.
+const mcpAddConcurrency = 4
+
+func newJSONRPCResult(id json.RawMessage, result any) *jsonRPCResponse {
+	return &jsonRPCResponse{JSONRPC: "2.0", ID: id, Result: result}
+}
+
+func newJSONRPCError(id json.RawMessage, code int, message string) *jsonRPCResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &jsonRPCResponse{JSONRPC: "2.0", ID: id, Error: &jsonRPCError{Code: code, Message: message}}
+}
+
+// mustMarshal marshals the value to JSON. It's only used for values that are
+// known to be marshalable, so it panics otherwise.
+func mustMarshal(v any) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("couldn't marshal value: %v", err))
+	}
+	return b
+}
+
+// Ensure at compile time that the MCP server implements http.Handler.
+var _ http.Handler = (*MCPServer)(nil)
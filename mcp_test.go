@@ -0,0 +1,124 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMCPServer_ServeStdio(t *testing.T) {
+	ctx := context.Background()
+	vectors := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return vectors, nil
+	}
+
+	db := NewDB()
+	s := NewMCPServer(db, embeddingFunc)
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"add_documents","arguments":{"collection":"knowledge","documents":[{"id":"1","content":"The sky is blue.","metadata":{"lang":"en"}},{"id":"2","content":"Der Himmel ist blau.","metadata":{"lang":"de"}}]}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"query","arguments":{"collection":"knowledge","query":"sky","n_results":10,"where":{"lang":"de","$not":{"lang":"en"}}}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"query","arguments":{"collection":"unknown","query":"sky"}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"unknown"}`,
+	}, "\n")
+	out := &bytes.Buffer{}
+	err := s.ServeStdio(ctx, strings.NewReader(in), out)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The notification doesn't get a response
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatal("expected 6 responses, got", len(lines))
+	}
+	responses := make([]struct {
+		ID     int
+		Result json.RawMessage
+		Error  *jsonRPCError
+	}, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &responses[i]); err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if responses[i].ID != i+1 {
+			t.Fatal("unexpected response ID", responses[i].ID)
+		}
+	}
+
+	if !strings.Contains(string(responses[0].Result), mcpProtocolVersion) {
+		t.Fatal("expected protocol version in initialize result, got", string(responses[0].Result))
+	}
+	if !strings.Contains(string(responses[1].Result), `"name":"query"`) {
+		t.Fatal("expected query tool in tools list, got", string(responses[1].Result))
+	}
+	if c := db.GetCollection("knowledge", nil); c == nil || c.Count() != 2 {
+		t.Fatal("expected collection with 2 documents")
+	}
+
+	var queryRes mcpToolResult
+	if err := json.Unmarshal(responses[3].Result, &queryRes); err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if queryRes.IsError || len(queryRes.Content) != 1 {
+		t.Fatalf("unexpected query result: %+v", queryRes)
+	}
+	var results []adminResult
+	if err := json.Unmarshal([]byte(queryRes.Content[0].Text), &results); err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(results) != 1 || results[0].ID != "2" {
+		t.Fatalf("expected document 2, got %+v", results)
+	}
+
+	// Tool errors are results, unknown methods are JSON-RPC errors
+	var errRes mcpToolResult
+	if err := json.Unmarshal(responses[4].Result, &errRes); err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !errRes.IsError {
+		t.Fatal("expected tool error for unknown collection")
+	}
+	if responses[5].Error == nil || responses[5].Error.Code != jsonRPCMethodNotFound {
+		t.Fatalf("expected method not found error, got %+v", responses[5].Error)
+	}
+}
+
+func TestMCPServer_ServeHTTP(t *testing.T) {
+	s := NewMCPServer(NewDB(), nil)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":"a","method":"ping"}`))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatal("expected status 200, got", res.StatusCode)
+	}
+	var got jsonRPCResponse
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if string(got.ID) != `"a"` || got.Error != nil {
+		t.Fatalf("unexpected response: %+v", got)
+	}
+
+	res2, err := http.Post(ts.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer res2.Body.Close()
+	if res2.StatusCode != http.StatusAccepted {
+		t.Fatal("expected status 202, got", res2.StatusCode)
+	}
+}
@@ -0,0 +1,273 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// ErrMemoryBudgetExceeded is returned when adding documents or importing
+// collections would exceed the DB's memory budget, see [DB.SetMemoryBudget].
+var ErrMemoryBudgetExceeded = errors.New("memory budget exceeded")
+
+// Sizes for estimating the memory usage of documents, in bytes. The overhead
+// of a map entry (hash bits, slots) is a rough estimate.
+const (
+	mapEntryOverhead      = 16
+	stringHeaderSize      = int64(unsafe.Sizeof(""))
+	documentStructSize    = int64(unsafe.Sizeof(Document{}))
+	float32Size           = 4
+	metadataEntryOverhead = 2*stringHeaderSize + mapEntryOverhead
+)
+
+// MemoryUsage is the estimated memory usage of documents, in bytes. It covers
+// the data that grows with the number of documents, but not the fixed overhead
+// of the DB and collections.
+type MemoryUsage struct {
+	// The number of documents.
+	Documents int
+	// The memory used by the embeddings.
+	Embeddings int64
+	// The memory used by the contents, including media data.
+	Contents int64
+	// The memory used by the metadata keys and values.
+	Metadata int64
+	// The memory used by the document structs, IDs, and the collection's map of
+	// documents.
+	Overhead int64
+}
+
+// Total returns the total memory usage in bytes.
+func (m MemoryUsage) Total() int64 {
+	return m.Embeddings + m.Contents + m.Metadata + m.Overhead
+}
+
+func (m *MemoryUsage) add(other MemoryUsage) {
+	m.Documents += other.Documents
+	m.Embeddings += other.Embeddings
+	m.Contents += other.Contents
+	m.Metadata += other.Metadata
+	m.Overhead += other.Overhead
+}
+
+// documentMemoryUsage returns the estimated memory usage of the document, when
+// it's stored in a collection.
+func documentMemoryUsage(doc *Document) MemoryUsage {
+	m := MemoryUsage{
+		Documents:  1,
+		Embeddings: int64(len(doc.Embedding)) * float32Size,
+		Contents:   int64(len(doc.Content)),
+		// The ID is stored in the document and as key of the collection's map.
+		Overhead: documentStructSize + 2*int64(len(doc.ID)) + stringHeaderSize + mapEntryOverhead,
+	}
+	if doc.Media != nil {
+		m.Contents += int64(len(doc.Media.URL) + len(doc.Media.Data) + len(doc.Media.MIMEType))
+	}
+	for k, v := range doc.Metadata {
+		m.Metadata += int64(len(k)+len(v)) + metadataEntryOverhead
+	}
+	return m
+}
+
+// MemoryUsage returns the estimated memory usage of the collection's documents.
+// It iterates over all documents, so for frequent checks of large collections
+// use [DB.MemoryUsageTotal] instead.
+func (c *Collection) MemoryUsage() MemoryUsage {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	m := MemoryUsage{}
+	for _, doc := range c.documents {
+		m.add(documentMemoryUsage(doc))
+	}
+	return m
+}
+
+// recalculateMemoryUsage sets the tracked total memory usage of the collection
+// from its documents, for example after loading them from storage.
+func (c *Collection) recalculateMemoryUsage() {
+	c.memoryUsage.Store(c.MemoryUsage().Total())
+}
+
+// MemoryBudgetPolicy defines what happens when adding documents would exceed the
+// DB's memory budget.
+type MemoryBudgetPolicy int
+
+const (
+	// MemoryBudgetReject rejects adding documents and importing collections that
+	// would exceed the budget, with an error wrapping [ErrMemoryBudgetExceeded].
+	MemoryBudgetReject MemoryBudgetPolicy = iota
+	// MemoryBudgetEvictRandom evicts random documents of the collection that a
+	// document is added to, until the budget is met again. If the collection
+	// tracks access stats (see [Collection.SetAccessStats]), the least recently
+	// retrieved of a random sample of documents are evicted first instead.
+	// Documents of other collections aren't evicted, so if the collection's
+	// other documents don't use enough memory, adding fails with an error
+	// wrapping [ErrMemoryBudgetExceeded] like with [MemoryBudgetReject].
+	// For persistent DBs evicted documents are deleted from storage as well.
+	// This is useful when the DB is used as a cache, for example of LLM
+	// responses. Imports aren't affected.
+	MemoryBudgetEvictRandom
+)
+
+// MemoryBudget limits the estimated memory usage of all documents in a DB.
+type MemoryBudget struct {
+	// The maximum total memory usage in bytes. 0 means no limit.
+	Limit int64
+	// What happens when adding documents would exceed the limit.
+	Policy MemoryBudgetPolicy
+}
+
+// SetMemoryBudget sets the DB's memory budget, which is enforced when adding
+// documents and importing collections. It's based on the estimated memory usage
+// of the documents (see [MemoryUsage]), so the actual memory usage of the
+// process is higher. Setting a budget that's already exceeded doesn't evict
+// documents, but adding documents is then handled according to the policy.
+// Concurrently added documents can exceed the budget slightly.
+// The budget isn't persisted.
+func (db *DB) SetMemoryBudget(budget MemoryBudget) error {
+	if budget.Limit < 0 {
+		return errors.New("memory budget limit must be >= 0")
+	}
+	switch budget.Policy {
+	case MemoryBudgetReject, MemoryBudgetEvictRandom:
+	default:
+		return fmt.Errorf("unknown memory budget policy %d", budget.Policy)
+	}
+
+	db.collectionsLock.Lock()
+	defer db.collectionsLock.Unlock()
+
+	db.memoryBudget = budget
+	return nil
+}
+
+// MemoryUsage returns the estimated memory usage of all documents in the DB.
+// Like [Collection.MemoryUsage], it iterates over all documents.
+func (db *DB) MemoryUsage() MemoryUsage {
+	m := MemoryUsage{}
+	for _, c := range db.ListCollections() {
+		m.add(c.MemoryUsage())
+	}
+	return m
+}
+
+// MemoryUsageTotal returns the estimated total memory usage of all documents in
+// the DB in bytes. It's tracked when adding and deleting documents, so unlike
+// [DB.MemoryUsage] it doesn't iterate over the documents.
+func (db *DB) MemoryUsageTotal() int64 {
+	db.collectionsLock.RLock()
+	defer db.collectionsLock.RUnlock()
+
+	return db.memoryUsageTotalLocked()
+}
+
+// memoryUsageTotalLocked returns the tracked total memory usage. The caller
+// must hold the collections lock.
+func (db *DB) memoryUsageTotalLocked() int64 {
+	var total int64
+	for _, c := range db.collections {
+		total += c.memoryUsage.Load()
+	}
+	return total
+}
+
+// checkMemoryBudget checks whether adding the given number of bytes to the
+// collection fits into the DB's memory budget. It returns an error if it
+// doesn't and the policy is to reject, or the number of bytes to evict if the
+// policy is to evict.
+func (c *Collection) checkMemoryBudget(bytes int64) (int64, error) {
+	if c.db == nil {
+		return 0, nil
+	}
+
+	c.db.collectionsLock.RLock()
+	budget := c.db.memoryBudget
+	total := c.db.memoryUsageTotalLocked()
+	c.db.collectionsLock.RUnlock()
+
+	if budget.Limit == 0 || total+bytes <= budget.Limit {
+		return 0, nil
+	}
+	if budget.Policy == MemoryBudgetReject {
+		return 0, fmt.Errorf("%w: %d of %d bytes used, adding %d bytes", ErrMemoryBudgetExceeded, total, budget.Limit, bytes)
+	}
+	return total + bytes - budget.Limit, nil
+}
+
+// evictionSampleSize is the number of documents that are sampled to find the
+// least recently retrieved one to evict, like the approximated LRU of Redis.
+// This avoids sorting all documents of the collection for each eviction.
+const evictionSampleSize = 16
+
+// evictLocked removes documents other than the one with the given ID from
+// memory, until at least the given number of bytes are freed. If access stats
+// are enabled, the least recently retrieved of a random sample of documents is
+// evicted each time, otherwise a random one. Documents of other collections
+// aren't evicted, so if the collection's other documents don't use enough
+// memory, nothing is evicted and an error wrapping [ErrMemoryBudgetExceeded] is
+// returned. Otherwise it returns the IDs of the evicted documents.
+// The access stats setting must be read from the config before, as the caller
+// must hold the documents lock for writing.
+func (c *Collection) evictLocked(bytes int64, keepID string, accessStats bool) ([]string, error) {
+	evictable := c.memoryUsage.Load()
+	if keep, ok := c.documents[keepID]; ok {
+		evictable -= documentMemoryUsage(keep).Total()
+	}
+	if evictable < bytes {
+		return nil, fmt.Errorf("%w: only %d bytes of collection %q can be evicted, %d bytes needed", ErrMemoryBudgetExceeded, evictable, c.Name, bytes)
+	}
+
+	c.accessStats.lock.Lock()
+	defer c.accessStats.lock.Unlock()
+
+	var evicted []string
+	for bytes > 0 {
+		id, ok := c.evictionCandidateLocked(keepID, accessStats)
+		if !ok {
+			break
+		}
+		freed := documentMemoryUsage(c.documents[id]).Total()
+		delete(c.documents, id)
+		c.unindexDocumentLocked(id)
+		if c.contentCache != nil {
+			c.contentCache.remove(id)
+		}
+		if _, ok := c.accessStats.stats[id]; ok {
+			delete(c.accessStats.stats, id)
+			c.accessStats.dirty = true
+		}
+		c.memoryUsage.Add(-freed)
+		bytes -= freed
+		evicted = append(evicted, id)
+	}
+	return evicted, nil
+}
+
+// evictionCandidateLocked returns the ID of the next document to evict, see
+// [Collection.evictLocked]. Documents that were never retrieved come first.
+// The map iteration order is random, so the first documents are a random
+// sample. The caller must hold the documents lock and the access stats lock.
+func (c *Collection) evictionCandidateLocked(keepID string, accessStats bool) (string, bool) {
+	var candidate string
+	var candidateRetrievedAt time.Time
+	sampled := 0
+	for id := range c.documents {
+		if id == keepID {
+			continue
+		}
+		if !accessStats {
+			return id, true
+		}
+		retrievedAt := c.accessStats.stats[id].LastRetrievedAt
+		if sampled == 0 || retrievedAt.Before(candidateRetrievedAt) {
+			candidate, candidateRetrievedAt = id, retrievedAt
+		}
+		sampled++
+		if sampled == evictionSampleSize {
+			break
+		}
+	}
+	return candidate, sampled > 0
+}
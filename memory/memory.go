@@ -0,0 +1,309 @@
+// Package memory provides a conversation memory for chatbots, built on a
+// chromem-go collection. It stores the turns of chat sessions and retrieves
+// the recent ones, or the ones that are most similar to a query within a time
+// window, to add them to the prompt of an LLM.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// The roles of turns.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleSystem    = "system"
+	// RoleSummary is the role of the summaries created by [Options.Summarize].
+	RoleSummary = "summary"
+)
+
+// The metadata keys of the documents that hold the turns.
+const (
+	sessionIDKey = "session_id"
+	roleKey      = "role"
+	timeKey      = "time"
+)
+
+// Turn is a turn of a chat session.
+type Turn struct {
+	// The ID of the turn. Optional when adding a turn, a unique ID is generated
+	// if it's empty.
+	ID        string
+	SessionID string
+	Role      string
+	Content   string
+	// The time of the turn. Optional when adding a turn, defaults to now.
+	Time time.Time
+	// Additional metadata, for example the user ID. The keys "session_id",
+	// "role" and "time" are reserved.
+	Metadata map[string]string
+
+	// The similarity to the query, only set by [Memory.Search].
+	Similarity float32
+}
+
+// SummarizeFunc summarizes the turns of a session, for example with an LLM.
+type SummarizeFunc func(ctx context.Context, sessionID string, turns []Turn) (string, error)
+
+// Options are the options for [New].
+type Options struct {
+	// Summarize is called with the latest turns of a session every
+	// SummarizeEvery turns, and the summary is added to the session as a turn
+	// with [RoleSummary]. This keeps long sessions retrievable with a few turns.
+	// Optional.
+	Summarize SummarizeFunc
+
+	// The number of turns after which a session is summarized. Optional,
+	// defaults to 20.
+	SummarizeEvery int
+}
+
+// Memory is a conversation memory. It's safe for concurrent use.
+type Memory struct {
+	c    *chromem.Collection
+	opts Options
+
+	// The number of turns since the last summary, by session
+	pending     map[string]int
+	pendingLock sync.Mutex
+
+	idCounter atomic.Uint64
+}
+
+// New creates a conversation memory that stores the turns in the collection.
+// The collection's embedding function is used for the turns and queries, and
+// the collection can be persistent to keep the memory across restarts. It
+// shouldn't contain other documents.
+func New(c *chromem.Collection, opts Options) (*Memory, error) {
+	if c == nil {
+		return nil, errors.New("collection is nil")
+	}
+	if opts.SummarizeEvery <= 0 {
+		opts.SummarizeEvery = 20
+	}
+	return &Memory{
+		c:       c,
+		opts:    opts,
+		pending: make(map[string]int),
+	}, nil
+}
+
+// Add adds a turn to its session. If summarization is enabled and the session
+// has enough turns since the last summary, they're summarized.
+func (m *Memory) Add(ctx context.Context, turn Turn) error {
+	if turn.SessionID == "" {
+		return errors.New("session ID is empty")
+	}
+	if turn.Role == "" {
+		return errors.New("role is empty")
+	}
+	err := m.add(ctx, turn)
+	if err != nil {
+		return err
+	}
+
+	if m.opts.Summarize == nil || turn.Role == RoleSummary {
+		return nil
+	}
+	m.pendingLock.Lock()
+	m.pending[turn.SessionID]++
+	summarize := m.pending[turn.SessionID] >= m.opts.SummarizeEvery
+	if summarize {
+		m.pending[turn.SessionID] = 0
+	}
+	m.pendingLock.Unlock()
+	if !summarize {
+		return nil
+	}
+
+	turns, err := m.Recent(ctx, turn.SessionID, m.opts.SummarizeEvery)
+	if err != nil {
+		return fmt.Errorf("couldn't get turns to summarize: %w", err)
+	}
+	summary, err := m.opts.Summarize(ctx, turn.SessionID, turns)
+	if err != nil {
+		return fmt.Errorf("couldn't summarize session: %w", err)
+	}
+	return m.add(ctx, Turn{
+		SessionID: turn.SessionID,
+		Role:      RoleSummary,
+		Content:   summary,
+	})
+}
+
+// add stores the turn as document.
+func (m *Memory) add(ctx context.Context, turn Turn) error {
+	if turn.Time.IsZero() {
+		turn.Time = time.Now()
+	}
+	if turn.ID == "" {
+		turn.ID = turn.SessionID + "#" + strconv.FormatInt(turn.Time.UnixNano(), 10) + "-" + strconv.FormatUint(m.idCounter.Add(1), 10)
+	}
+	metadata := make(map[string]string, len(turn.Metadata)+3)
+	for k, v := range turn.Metadata {
+		metadata[k] = v
+	}
+	metadata[sessionIDKey] = turn.SessionID
+	metadata[roleKey] = turn.Role
+	metadata[timeKey] = turn.Time.UTC().Format(time.RFC3339Nano)
+
+	err := m.c.AddDocument(ctx, chromem.Document{
+		ID:       turn.ID,
+		Metadata: metadata,
+		Content:  turn.Content,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't add turn: %w", err)
+	}
+	return nil
+}
+
+// Recent returns the latest n turns of the session, oldest first.
+func (m *Memory) Recent(ctx context.Context, sessionID string, n int) ([]Turn, error) {
+	if n <= 0 {
+		return nil, errors.New("n must be > 0")
+	}
+	turns, err := m.session(ctx, sessionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	sortByTime(turns)
+	if len(turns) > n {
+		turns = turns[len(turns)-n:]
+	}
+	return turns, nil
+}
+
+// SearchOptions are the options for [Memory.Search].
+type SearchOptions struct {
+	// The number of turns to return. Optional, defaults to 5.
+	NResults int
+
+	// Only turns at or after Since and before Until are returned. Optional.
+	Since time.Time
+	Until time.Time
+}
+
+// Search returns the turns of the session that are most similar to the query,
+// within the time window of the options, most similar first.
+func (m *Memory) Search(ctx context.Context, sessionID, query string, opts SearchOptions) ([]Turn, error) {
+	if query == "" {
+		return nil, errors.New("query is empty")
+	}
+	if opts.NResults <= 0 {
+		opts.NResults = 5
+	}
+	turns, err := m.session(ctx, sessionID, &chromem.QueryOptions{QueryText: query})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Turn, 0, opts.NResults)
+	for _, turn := range turns {
+		if !opts.Since.IsZero() && turn.Time.Before(opts.Since) {
+			continue
+		}
+		if !opts.Until.IsZero() && !turn.Time.Before(opts.Until) {
+			continue
+		}
+		res = append(res, turn)
+		if len(res) == opts.NResults {
+			break
+		}
+	}
+	return res, nil
+}
+
+// Clear deletes all turns of the session.
+func (m *Memory) Clear(ctx context.Context, sessionID string) error {
+	m.pendingLock.Lock()
+	delete(m.pending, sessionID)
+	m.pendingLock.Unlock()
+
+	if m.c.Count() == 0 {
+		return nil
+	}
+	err := m.c.Delete(ctx, map[string]string{sessionIDKey: sessionID}, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't delete turns: %w", err)
+	}
+	return nil
+}
+
+// session returns all turns of the session, sorted by their similarity to the
+// query of the options. Without options, the order is arbitrary.
+func (m *Memory) session(ctx context.Context, sessionID string, options *chromem.QueryOptions) ([]Turn, error) {
+	if sessionID == "" {
+		return nil, errors.New("session ID is empty")
+	}
+	count := m.c.Count()
+	if count == 0 {
+		return nil, nil
+	}
+	if options == nil {
+		// Collections can only be listed via queries. As all turns of the
+		// session are returned, the query embedding doesn't matter.
+		dim := m.c.Dimension()
+		if dim == 0 {
+			return nil, nil
+		}
+		embedding := make([]float32, dim)
+		embedding[0] = 1
+		options = &chromem.QueryOptions{QueryEmbedding: embedding}
+	}
+	options.NResults = count
+	options.Where = map[string]string{sessionIDKey: sessionID}
+
+	results, err := m.c.QueryWithOptions(ctx, *options)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't query turns: %w", err)
+	}
+	turns := make([]Turn, 0, len(results))
+	for _, r := range results {
+		turns = append(turns, turnFromResult(r))
+	}
+	return turns, nil
+}
+
+// turnFromResult converts a query result to a turn.
+func turnFromResult(r chromem.Result) Turn {
+	turn := Turn{
+		ID:         r.ID,
+		SessionID:  r.Metadata[sessionIDKey],
+		Role:       r.Metadata[roleKey],
+		Content:    r.Content,
+		Similarity: r.Similarity,
+	}
+	turn.Time, _ = time.Parse(time.RFC3339Nano, r.Metadata[timeKey])
+	for k, v := range r.Metadata {
+		if k == sessionIDKey || k == roleKey || k == timeKey {
+			continue
+		}
+		if turn.Metadata == nil {
+			turn.Metadata = make(map[string]string)
+		}
+		turn.Metadata[k] = v
+	}
+	return turn
+}
+
+// sortByTime sorts the turns by time, oldest first. Turns with the same time
+// are sorted by ID, so the order is deterministic.
+func sortByTime(turns []Turn) {
+	slices.SortFunc(turns, func(a, b Turn) int {
+		if c := a.Time.Compare(b.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// embeddingFunc returns embeddings that are similar for texts with the same
+// first letter.
+func embeddingFunc(_ context.Context, text string) ([]float32, error) {
+	if strings.HasPrefix(text, "c") {
+		return []float32{1, 0.1}, nil
+	}
+	return []float32{0.1, 1}, nil
+}
+
+func newMemory(t *testing.T, opts Options) *Memory {
+	t.Helper()
+	c, err := chromem.NewDB().CreateCollection("memory", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	m, err := New(c, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	return m
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := newMemory(t, Options{})
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	turns := []Turn{
+		{SessionID: "a", Role: RoleUser, Content: "cats are great", Time: start},
+		{SessionID: "a", Role: RoleAssistant, Content: "dogs too", Time: start.Add(time.Minute)},
+		{SessionID: "a", Role: RoleUser, Content: "cats again", Time: start.Add(2 * time.Minute), Metadata: map[string]string{"user": "u1"}},
+		{SessionID: "b", Role: RoleUser, Content: "cats in other session", Time: start},
+	}
+	for _, turn := range turns {
+		err := m.Add(ctx, turn)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	recent, err := m.Recent(ctx, "a", 2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(recent) != 2 || recent[0].Content != "dogs too" || recent[1].Content != "cats again" {
+		t.Fatalf("unexpected recent turns %+v", recent)
+	}
+	if recent[1].Role != RoleUser || !recent[1].Time.Equal(turns[2].Time) || recent[1].Metadata["user"] != "u1" {
+		t.Fatalf("unexpected turn %+v", recent[1])
+	}
+
+	found, err := m.Search(ctx, "a", "cats", SearchOptions{NResults: 2})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(found) != 2 || !strings.HasPrefix(found[0].Content, "cats") || !strings.HasPrefix(found[1].Content, "cats") {
+		t.Fatalf("unexpected search results %+v", found)
+	}
+	if found[0].Similarity == 0 {
+		t.Fatal("expected similarity, got 0")
+	}
+
+	// With time window
+	found, err = m.Search(ctx, "a", "cats", SearchOptions{NResults: 2, Since: start.Add(30 * time.Second)})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(found) != 2 || found[0].Content != "cats again" || found[1].Content != "dogs too" {
+		t.Fatalf("unexpected search results %+v", found)
+	}
+
+	err = m.Clear(ctx, "a")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	recent, err = m.Recent(ctx, "a", 10)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(recent) != 0 {
+		t.Fatal("expected no turns, got", recent)
+	}
+	recent, err = m.Recent(ctx, "b", 10)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(recent) != 1 {
+		t.Fatal("expected other session to be kept, got", recent)
+	}
+}
+
+func TestMemory_Summarize(t *testing.T) {
+	ctx := context.Background()
+	var summarized []Turn
+	m := newMemory(t, Options{
+		Summarize: func(_ context.Context, sessionID string, turns []Turn) (string, error) {
+			summarized = turns
+			return "cats and dogs were discussed", nil
+		},
+		SummarizeEvery: 2,
+	})
+	for i, content := range []string{"cats", "dogs", "cows"} {
+		err := m.Add(ctx, Turn{SessionID: "a", Role: RoleUser, Content: content, Time: time.Unix(int64(i), 0)})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	if len(summarized) != 2 || summarized[0].Content != "cats" || summarized[1].Content != "dogs" {
+		t.Fatalf("expected first 2 turns to be summarized, got %+v", summarized)
+	}
+	recent, err := m.Recent(ctx, "a", 10)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(recent) != 4 {
+		t.Fatal("expected 4 turns, got", len(recent))
+	}
+	// The summary was added after the second turn, with the current time
+	if recent[3].Role != RoleSummary || recent[3].Content != "cats and dogs were discussed" {
+		t.Fatalf("expected summary, got %+v", recent[3])
+	}
+}
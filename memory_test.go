@@ -0,0 +1,226 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCollection_MemoryUsage(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{
+		ID:        "1",
+		Metadata:  map[string]string{"foo": "bar"},
+		Embedding: []float32{-0.40824828, 0.40824828, 0.81649655},
+		Content:   "hello world",
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	m := c.MemoryUsage()
+	if m.Documents != 1 {
+		t.Fatal("expected 1 document, got", m.Documents)
+	}
+	if m.Embeddings != 3*4 {
+		t.Fatal("expected 12 bytes of embeddings, got", m.Embeddings)
+	}
+	if m.Contents != int64(len("hello world")) {
+		t.Fatal("expected 11 bytes of contents, got", m.Contents)
+	}
+	if m.Metadata != int64(len("foobar"))+metadataEntryOverhead {
+		t.Fatal("expected", int64(len("foobar"))+metadataEntryOverhead, "bytes of metadata, got", m.Metadata)
+	}
+	if db.MemoryUsageTotal() != m.Total() {
+		t.Fatal("expected tracked total", m.Total(), "got", db.MemoryUsageTotal())
+	}
+
+	err = c.Delete(ctx, nil, nil, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if db.MemoryUsageTotal() != 0 {
+		t.Fatal("expected tracked total 0, got", db.MemoryUsageTotal())
+	}
+}
+
+func TestDB_SetMemoryBudget(t *testing.T) {
+	ctx := context.Background()
+	doc := func(id string) Document {
+		return Document{ID: id, Embedding: []float32{-0.40824828, 0.40824828, 0.81649655}, Content: "hello world"}
+	}
+	docSize := documentMemoryUsage(&Document{ID: "1", Embedding: make([]float32, 3), Content: "hello world"}).Total()
+
+	t.Run("reject", func(t *testing.T) {
+		db := NewDB()
+		err := db.SetMemoryBudget(MemoryBudget{Limit: 2 * docSize, Policy: MemoryBudgetReject})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		c, err := db.CreateCollection("test", nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		for _, id := range []string{"1", "2"} {
+			err = c.AddDocument(ctx, doc(id))
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+		}
+		err = c.AddDocument(ctx, doc("3"))
+		if !errors.Is(err, ErrMemoryBudgetExceeded) {
+			t.Fatal("expected ErrMemoryBudgetExceeded, got", err)
+		}
+		if c.Count() != 2 {
+			t.Fatal("expected 2 documents, got", c.Count())
+		}
+	})
+
+	t.Run("evict", func(t *testing.T) {
+		db := NewDB()
+		err := db.SetMemoryBudget(MemoryBudget{Limit: 2 * docSize, Policy: MemoryBudgetEvictRandom})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		c, err := db.CreateCollection("test", nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		for _, id := range []string{"1", "2", "3"} {
+			err = c.AddDocument(ctx, doc(id))
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+		}
+		if c.Count() != 2 {
+			t.Fatal("expected 2 documents, got", c.Count())
+		}
+		// The added document must never be evicted
+		if _, ok := c.documents["3"]; !ok {
+			t.Fatal("expected document 3 to be kept")
+		}
+		if db.MemoryUsageTotal() != 2*docSize {
+			t.Fatal("expected tracked total", 2*docSize, "got", db.MemoryUsageTotal())
+		}
+	})
+
+	t.Run("evict other collection", func(t *testing.T) {
+		db := NewDB()
+		err := db.SetMemoryBudget(MemoryBudget{Limit: 2 * docSize, Policy: MemoryBudgetEvictRandom})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		a, err := db.CreateCollection("a", nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		b, err := db.CreateCollection("b", nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		for _, id := range []string{"1", "2"} {
+			err = a.AddDocument(ctx, doc(id))
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+		}
+		// Documents of other collections aren't evicted.
+		err = b.AddDocument(ctx, doc("3"))
+		if !errors.Is(err, ErrMemoryBudgetExceeded) {
+			t.Fatal("expected ErrMemoryBudgetExceeded, got", err)
+		}
+		if a.Count() != 2 || b.Count() != 0 {
+			t.Fatal("expected 2 and 0 documents, got", a.Count(), b.Count())
+		}
+		err = b.Tx(ctx, func(tx *Txn) error {
+			return tx.AddDocument(doc("3"))
+		})
+		if !errors.Is(err, ErrMemoryBudgetExceeded) {
+			t.Fatal("expected ErrMemoryBudgetExceeded, got", err)
+		}
+	})
+
+	t.Run("evict least recently retrieved", func(t *testing.T) {
+		path := t.TempDir()
+		db, err := NewPersistentDB(path, false)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		// The contents are spilled to disk, so only the one of the added
+		// document counts.
+		size := documentMemoryUsage(&Document{ID: "1", Embedding: make([]float32, 3)}).Total()
+		err = db.SetMemoryBudget(MemoryBudget{Limit: 2*size + docSize - size, Policy: MemoryBudgetEvictRandom})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		c, err := db.CreateCollection("test", nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = c.SetAccessStats(true)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = c.SetContentSpillover(true, 0)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		for _, id := range []string{"1", "2"} {
+			err = c.AddDocument(ctx, doc(id))
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+		}
+		now := time.Now()
+		c.accessStats.record([]Result{{ID: "2"}}, now.Add(-time.Hour))
+		c.accessStats.record([]Result{{ID: "1"}}, now)
+
+		err = c.AddDocument(ctx, doc("3"))
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if _, ok := c.documents["2"]; ok || c.Count() != 2 {
+			t.Fatal("expected document 2 to be evicted, got", c.Count(), "documents")
+		}
+		if _, ok := c.contentCache.get("2"); ok {
+			t.Fatal("expected content of document 2 to be removed from the cache")
+		}
+		if _, ok := c.AccessStats()["2"]; ok {
+			t.Fatal("expected access stats of document 2 to be removed")
+		}
+		err = c.FlushAccessStats()
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+
+		// Evicted documents don't come back after a reload
+		db, err = NewPersistentDB(path, false)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		c = db.GetCollection("test", nil)
+		if c == nil {
+			t.Fatal("expected collection, got nil")
+		}
+		if _, ok := c.documents["2"]; ok || c.Count() != 2 {
+			t.Fatal("expected document 2 to stay evicted, got", c.Count(), "documents")
+		}
+		if stats := c.AccessStats(); len(stats) != 2 || stats["1"].Retrievals != 1 {
+			t.Fatal("expected access stats of documents 1 and 3, got", stats)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		db := NewDB()
+		err := db.SetMemoryBudget(MemoryBudget{Limit: -1})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,340 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// MergeConflictPolicy decides what happens when a merged document has the same
+// ID as a document in the same collection of the DB, see [DB.Merge].
+type MergeConflictPolicy int
+
+const (
+	// MergeConflictSkip keeps the existing document.
+	MergeConflictSkip MergeConflictPolicy = iota
+	// MergeConflictOverwrite replaces the existing document with the merged one.
+	MergeConflictOverwrite
+	// MergeConflictError aborts the merge with an error wrapping
+	// [ErrMergeConflict], before anything is merged unless the conflicting
+	// documents are added concurrently.
+	MergeConflictError
+)
+
+// ErrMergeConflict is returned by [DB.Merge] with [MergeConflictError] if there
+// are conflicting documents.
+var ErrMergeConflict = errors.New("merge conflict")
+
+// MergeOptions are the options for [DB.Merge] and [DB.MergeFromReader].
+type MergeOptions struct {
+	// What happens with documents that exist in the DB already. Optional,
+	// defaults to [MergeConflictSkip].
+	OnConflict MergeConflictPolicy
+
+	// The names of the collections to merge. Optional, defaults to all.
+	Collections []string
+
+	// The concurrency for persisting the documents. Optional, defaults to the
+	// number of CPUs.
+	Concurrency int
+}
+
+// MergeStats are the statistics of a merge.
+type MergeStats struct {
+	CollectionsCreated   int
+	DocumentsAdded       int
+	DocumentsOverwritten int
+	DocumentsSkipped     int
+}
+
+// Merge merges the collections of the other DB into this DB, for example to
+// combine shards. Collections that don't exist in this DB are created with the
+// other collection's metadata and configuration, but without embedding function,
+// like collections that are loaded from disk (see [DB.GetCollection]). The
+// documents are inserted as they are, like imported ones: they aren't embedded
+// or preprocessed again, the schema isn't validated and the memory budget
+// isn't enforced. Only the dimension of their embeddings is checked.
+// Existing collections keep their metadata and configuration, and documents
+// that exist in them already are handled according to the conflict policy.
+// Archived documents (see [Collection.Archive]) are archived again in
+// persistent collections and added as regular documents to in-memory ones.
+// They conflict with both the documents and the archived documents of this DB.
+//
+// The other DB isn't changed, and it's only locked while a snapshot of it is
+// taken. Merges aren't atomic: with [MergeConflictError], conflicts are checked
+// before anything is merged, and again for each collection while it's locked
+// for the merge, so that documents that are added concurrently aren't
+// overwritten or skipped. If a conflict is only found then, or if adding a
+// document fails, for example because of a dimension mismatch, the documents
+// that were merged before remain.
+func (db *DB) Merge(ctx context.Context, other *DB, opts MergeOptions) (MergeStats, error) {
+	if other == nil {
+		return MergeStats{}, errors.New("other DB is nil")
+	}
+	snapshot, err := other.exportSnapshot()
+	if err != nil {
+		return MergeStats{}, fmt.Errorf("couldn't take snapshot of other DB: %w", err)
+	}
+	return db.merge(ctx, snapshot, opts)
+}
+
+// MergeFromReader is like [DB.Merge], but merges a DB that was exported with
+// [DB.ExportToWriter] or [DB.ExportToFile], for example to restore a partial
+// backup into a live DB. The encryption key must be the one of the export.
+func (db *DB) MergeFromReader(ctx context.Context, reader io.Reader, encryptionKey string, opts MergeOptions) (MergeStats, error) {
+	if encryptionKey != "" {
+		// AES 256 requires a 32 byte key
+		if len(encryptionKey) != 32 {
+			return MergeStats{}, errors.New("encryption key must be 32 bytes long")
+		}
+	}
+	snapshot := persistenceDB{}
+	err := readFromReader(reader, &snapshot, encryptionKey)
+	if err != nil {
+		return MergeStats{}, fmt.Errorf("couldn't read stream: %w", err)
+	}
+	return db.merge(ctx, snapshot, opts)
+}
+
+func (db *DB) merge(ctx context.Context, snapshot persistenceDB, opts MergeOptions) (MergeStats, error) {
+	stats := MergeStats{}
+	switch opts.OnConflict {
+	case MergeConflictSkip, MergeConflictOverwrite, MergeConflictError:
+	default:
+		return stats, fmt.Errorf("unknown merge conflict policy %d", opts.OnConflict)
+	}
+	if opts.Concurrency < 0 {
+		return stats, errors.New("concurrency must be >= 0")
+	} else if opts.Concurrency == 0 {
+		opts.Concurrency = runtime.NumCPU()
+	}
+
+	var names []string
+	for name := range snapshot.Collections {
+		if len(opts.Collections) == 0 || slices.Contains(opts.Collections, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	if opts.OnConflict == MergeConflictError {
+		for _, name := range names {
+			c := db.getCollection(name)
+			if c == nil {
+				continue
+			}
+			if err := c.checkMergeConflicts(snapshot.Collections[name]); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		pc := snapshot.Collections[name]
+		c, created, err := db.getOrCreateMergedCollection(pc)
+		if err != nil {
+			return stats, fmt.Errorf("couldn't create collection %q: %w", name, err)
+		}
+		if created {
+			stats.CollectionsCreated++
+		}
+
+		// Archived documents are archived again in persistent collections,
+		// and merged as documents in in-memory ones.
+		docs := pc.Documents
+		if len(pc.Archived) != 0 && c.persistDirectory == "" {
+			docs = maps.Clone(docs)
+			if docs == nil {
+				docs = make(map[string]*Document, len(pc.Archived))
+			}
+			maps.Copy(docs, pc.Archived)
+		}
+		added, overwritten, skipped, err := c.mergeDocuments(ctx, docs, opts)
+		stats.DocumentsAdded += len(added)
+		stats.DocumentsOverwritten += len(overwritten)
+		stats.DocumentsSkipped += skipped
+		if err != nil {
+			return stats, fmt.Errorf("couldn't merge documents into collection %q: %w", name, err)
+		}
+		if len(pc.Archived) != 0 && c.persistDirectory != "" {
+			added, overwritten, skipped, err := c.mergeArchived(ctx, pc.Archived, opts)
+			stats.DocumentsAdded += len(added)
+			stats.DocumentsOverwritten += len(overwritten)
+			stats.DocumentsSkipped += skipped
+			if err != nil {
+				return stats, fmt.Errorf("couldn't merge archived documents into collection %q: %w", name, err)
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// checkMergeConflicts returns an error wrapping [ErrMergeConflict] if a
+// document of the merged collection exists in the collection already. Archived
+// documents also conflict with the collection's archived documents.
+func (c *Collection) checkMergeConflicts(pc *persistenceCollection) error {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	for id := range pc.Documents {
+		if _, ok := c.documents[id]; ok {
+			return fmt.Errorf("%w: document %q exists in collection %q already", ErrMergeConflict, id, c.Name)
+		}
+	}
+	for id := range pc.Archived {
+		_, ok := c.documents[id]
+		if c.archive != nil && !ok {
+			_, ok = c.archive.Segments[id]
+		}
+		if ok {
+			return fmt.Errorf("%w: archived document %q exists in collection %q already", ErrMergeConflict, id, c.Name)
+		}
+	}
+	return nil
+}
+
+// mergeDocuments inserts the merged documents into the collection, and handles
+// the ones that exist already according to the conflict policy. It returns the
+// IDs of the added and overwritten documents, and the number of skipped ones.
+func (c *Collection) mergeDocuments(ctx context.Context, docs map[string]*Document, opts MergeOptions) (added, overwritten []string, skipped int, err error) {
+	ids := make([]string, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	c.documentsLock.Lock()
+	// Documents might have been added since the check of all collections in
+	// DB.merge, so the conflicts are checked again under the write lock.
+	if opts.OnConflict == MergeConflictError {
+		for _, id := range ids {
+			if _, ok := c.documents[id]; ok {
+				c.documentsLock.Unlock()
+				return nil, nil, 0, fmt.Errorf("%w: document %q exists in collection %q already", ErrMergeConflict, id, c.Name)
+			}
+		}
+	}
+	var merged []*Document
+	for _, id := range ids {
+		// A copy, as the documents of a snapshot are shared with the other DB.
+		doc := *docs[id]
+		old, exists := c.documents[id]
+		if exists && opts.OnConflict != MergeConflictOverwrite {
+			skipped++
+			continue
+		}
+		if dim := c.dimensionLocked(id); dim != 0 && dim != len(doc.Embedding) {
+			err = &DimensionMismatchError{DocumentID: id, Expected: dim, Actual: len(doc.Embedding)}
+			break
+		}
+
+		stored := &doc
+		if c.contentCache != nil {
+			withoutContent := doc
+			withoutContent.Content = ""
+			stored = &withoutContent
+			c.contentCache.add(id, doc.Content)
+		}
+		usage := documentMemoryUsage(stored).Total()
+		if exists {
+			usage -= documentMemoryUsage(old).Total()
+			overwritten = append(overwritten, id)
+		} else {
+			added = append(added, id)
+		}
+		c.documents[id] = stored
+		c.indexDocumentLocked(stored)
+		c.memoryUsage.Add(usage)
+		merged = append(merged, &doc)
+	}
+	// The documents are persisted while holding the lock, so concurrent
+	// deletes can't be overwritten on disk.
+	persistErr := c.persistMergedDocumentsLocked(merged, opts.Concurrency)
+	c.documentsLock.Unlock()
+	if len(overwritten) != 0 {
+		c.changed()
+	} else if len(added) != 0 {
+		c.added(ctx)
+	}
+	if err == nil {
+		err = persistErr
+	}
+	if err != nil {
+		return added, overwritten, skipped, err
+	}
+
+	if err := c.audit(ctx, AuditActionAdd, added...); err != nil {
+		return added, overwritten, skipped, err
+	}
+	return added, overwritten, skipped, c.audit(ctx, AuditActionUpdate, overwritten...)
+}
+
+// persistMergedDocumentsLocked persists the documents with the given
+// concurrency, if the collection is persistent. The caller must hold the
+// documents lock for writing.
+func (c *Collection) persistMergedDocumentsLocked(docs []*Document, concurrency int) error {
+	if c.persistDirectory == "" {
+		return nil
+	}
+	errs := make([]error, len(docs))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, doc := range docs {
+		i, doc := i, doc
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			errs[i] = c.persistDocument(doc)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// getCollection returns the collection with the name, or nil. Unlike
+// [DB.GetCollection], it doesn't set an embedding function.
+func (db *DB) getCollection(name string) *Collection {
+	db.collectionsLock.RLock()
+	defer db.collectionsLock.RUnlock()
+
+	return db.collections[name]
+}
+
+// getOrCreateMergedCollection returns the collection with the name of the
+// merged collection, or creates it with its metadata and configuration.
+func (db *DB) getOrCreateMergedCollection(pc *persistenceCollection) (*Collection, bool, error) {
+	db.collectionsLock.Lock()
+	defer db.collectionsLock.Unlock()
+
+	if c, ok := db.collections[pc.Name]; ok {
+		return c, false, nil
+	}
+
+	config := pc.Config
+	if db.persistDirectory == "" {
+		config.ContentSpillover = false
+	}
+	c, err := newCollection(pc.Name, pc.Metadata, nil, config, db.persistDirectory, db.compress, db.syncer)
+	if err != nil {
+		return nil, false, err
+	}
+	c.initSegments(db.segmentSize)
+	if config.ContentSpillover {
+		c.enableContentSpilloverLocked(config.ContentCacheSize)
+	}
+	c.db = db
+	db.collections[pc.Name] = c
+	return c, true, nil
+}
@@ -0,0 +1,165 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDB_Merge(t *testing.T) {
+	ctx := context.Background()
+	newDB := func(t *testing.T, docs map[string][]Document) *DB {
+		t.Helper()
+		db := NewDB()
+		for name, docs := range docs {
+			c, err := db.CreateCollection(name, map[string]string{"origin": name}, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.AddDocuments(ctx, docs, 1)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+		}
+		return db
+	}
+	newTarget := func(t *testing.T) *DB {
+		return newDB(t, map[string][]Document{
+			"a": {{ID: "1", Embedding: []float32{1, 0}, Content: "target 1"}},
+		})
+	}
+	source := newDB(t, map[string][]Document{
+		"a": {
+			{ID: "1", Embedding: []float32{1, 0}, Content: "source 1"},
+			{ID: "2", Embedding: []float32{0, 1}, Content: "source 2"},
+		},
+		"b": {{ID: "3", Embedding: []float32{1, 0}, Content: "source 3"}},
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		db := newTarget(t)
+		stats, err := db.Merge(ctx, source, MergeOptions{})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		expected := MergeStats{CollectionsCreated: 1, DocumentsAdded: 2, DocumentsSkipped: 1}
+		if stats != expected {
+			t.Fatalf("expected %+v, got %+v", expected, stats)
+		}
+		if db.collections["a"].documents["1"].Content != "target 1" {
+			t.Fatal("expected existing document to be kept")
+		}
+		b := db.collections["b"]
+		if b.Count() != 1 || b.getMetadata()["origin"] != "b" {
+			t.Fatal("expected created collection with document and metadata")
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		db := newTarget(t)
+		stats, err := db.Merge(ctx, source, MergeOptions{OnConflict: MergeConflictOverwrite, Collections: []string{"a"}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		expected := MergeStats{DocumentsAdded: 1, DocumentsOverwritten: 1}
+		if stats != expected {
+			t.Fatalf("expected %+v, got %+v", expected, stats)
+		}
+		if db.collections["a"].documents["1"].Content != "source 1" {
+			t.Fatal("expected existing document to be overwritten")
+		}
+		if _, ok := db.collections["b"]; ok {
+			t.Fatal("expected collection b not to be merged")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		db := newTarget(t)
+		_, err := db.Merge(ctx, source, MergeOptions{OnConflict: MergeConflictError})
+		if !errors.Is(err, ErrMergeConflict) {
+			t.Fatal("expected merge conflict, got", err)
+		}
+		// Nothing is merged
+		if len(db.collections) != 1 || db.collections["a"].Count() != 1 {
+			t.Fatal("expected DB to be unchanged")
+		}
+
+		// Documents that are added after the check of all collections are
+		// conflicts as well.
+		a := db.collections["a"]
+		_, _, _, err = a.mergeDocuments(ctx, source.collections["a"].documents, MergeOptions{OnConflict: MergeConflictError})
+		if !errors.Is(err, ErrMergeConflict) {
+			t.Fatal("expected merge conflict, got", err)
+		}
+		if a.Count() != 1 {
+			t.Fatal("expected collection to be unchanged")
+		}
+	})
+
+	t.Run("as is", func(t *testing.T) {
+		db := newTarget(t)
+		a := db.collections["a"]
+		upper := func(content string) (string, error) {
+			return strings.ToUpper(content), nil
+		}
+		a.SetPreprocessing(PreprocessingOptions{Preprocessors: []Preprocessor{upper}})
+		err := a.SetMetadataSchema(&MetadataSchema{Fields: map[string]MetadataField{"required": {Required: true}}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = a.SetLanguageDetection("language")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = db.SetMemoryBudget(MemoryBudget{Limit: 1, Policy: MemoryBudgetEvictRandom})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+
+		stats, err := db.Merge(ctx, source, MergeOptions{OnConflict: MergeConflictOverwrite, Collections: []string{"a"}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		expected := MergeStats{DocumentsAdded: 1, DocumentsOverwritten: 1}
+		if stats != expected {
+			t.Fatalf("expected %+v, got %+v", expected, stats)
+		}
+		// Nothing is preprocessed, validated, detected or evicted.
+		for id, content := range map[string]string{"1": "source 1", "2": "source 2"} {
+			doc, ok := a.documents[id]
+			if !ok || doc.Content != content || len(doc.Metadata) != 0 {
+				t.Fatalf("expected document %s as is, got %+v", id, doc)
+			}
+		}
+
+		// The dimension is checked.
+		other := newDB(t, map[string][]Document{"a": {{ID: "4", Embedding: []float32{1, 0, 0}}}})
+		_, err = db.Merge(ctx, other, MergeOptions{})
+		var mismatch *DimensionMismatchError
+		if !errors.As(err, &mismatch) || mismatch.DocumentID != "4" {
+			t.Fatal("expected dimension mismatch of document 4, got", err)
+		}
+		if a.Count() != 2 {
+			t.Fatal("expected 2 documents, got", a.Count())
+		}
+	})
+
+	t.Run("reader", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		err := source.ExportToWriter(&buf, true, "")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		db := newTarget(t)
+		stats, err := db.MergeFromReader(ctx, &buf, "", MergeOptions{})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		expected := MergeStats{CollectionsCreated: 1, DocumentsAdded: 2, DocumentsSkipped: 1}
+		if stats != expected {
+			t.Fatalf("expected %+v, got %+v", expected, stats)
+		}
+	})
+}
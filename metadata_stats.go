@@ -0,0 +1,158 @@
+package chromem
+
+import (
+	"cmp"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// MaxExactDistinctValues is the number of distinct values up to which
+// [Collection.MetadataValueStats] counts exactly. Above it, the number of
+// distinct values is estimated.
+const MaxExactDistinctValues = 10_000
+
+// MetadataValueStats are the statistics of the values of a metadata key, see
+// [Collection.MetadataValueStats].
+type MetadataValueStats struct {
+	Key string
+
+	// The number of documents that have the key.
+	Documents int
+
+	// The number of distinct values. It's exact, unless Approximate is true.
+	DistinctValues int
+
+	// Approximate is true if there are more than [MaxExactDistinctValues]
+	// distinct values. DistinctValues is then estimated with HyperLogLog, with
+	// a typical error of about 1%, and Values is nil.
+	Approximate bool
+
+	// The values with their number of documents, sorted by count (descending)
+	// and value.
+	Values []MetadataValueCount
+}
+
+// MetadataValueCount is the number of documents with a metadata value.
+type MetadataValueCount struct {
+	Value string
+	Count int
+}
+
+// MetadataValueStats returns the distinct values of the metadata key and the
+// number of documents with each value. This can be used to populate filter UIs,
+// or to detect keys with an unexpectedly high cardinality, like IDs that were
+// put into metadata that's meant for categories.
+// For keys with more than [MaxExactDistinctValues] distinct values, the number
+// of distinct values is estimated, so that the memory usage stays bounded.
+func (c *Collection) MetadataValueStats(key string) (MetadataValueStats, error) {
+	if key == "" {
+		return MetadataValueStats{}, errors.New("key is empty")
+	}
+
+	stats := MetadataValueStats{Key: key}
+	counts := make(map[string]int)
+	var hll *hyperLogLog
+
+	c.documentsLock.RLock()
+	for _, doc := range c.documents {
+		value, ok := doc.Metadata[key]
+		if !ok {
+			continue
+		}
+		stats.Documents++
+		if hll != nil {
+			hll.add(value)
+			continue
+		}
+		counts[value]++
+		if len(counts) > MaxExactDistinctValues {
+			hll = newHyperLogLog()
+			for v := range counts {
+				hll.add(v)
+			}
+			counts = nil
+		}
+	}
+	c.documentsLock.RUnlock()
+
+	if hll != nil {
+		stats.Approximate = true
+		stats.DistinctValues = int(hll.estimate())
+		return stats, nil
+	}
+
+	stats.DistinctValues = len(counts)
+	stats.Values = make([]MetadataValueCount, 0, len(counts))
+	for value, count := range counts {
+		stats.Values = append(stats.Values, MetadataValueCount{Value: value, Count: count})
+	}
+	slices.SortFunc(stats.Values, func(a, b MetadataValueCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+	return stats, nil
+}
+
+// hllPrecision is the number of bits of the hash that select the register of a
+// HyperLogLog. 2^14 registers lead to a standard error of 1.04/sqrt(2^14),
+// which is about 0.8%.
+const hllPrecision = 14
+
+// hyperLogLog estimates the number of distinct values with a fixed amount of
+// memory. See https://en.wikipedia.org/wiki/HyperLogLog.
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+func (h *hyperLogLog) add(value string) {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(value))
+	x := mix64(hash.Sum64())
+
+	i := x >> (64 - hllPrecision)
+	// The position of the first 1 bit in the remaining bits, starting at 1. The
+	// sentinel bit limits it for hashes whose remaining bits are all 0.
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[i] {
+		h.registers[i] = rank
+	}
+}
+
+func (h *hyperLogLog) estimate() float64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	// Linear counting is more accurate for small cardinalities.
+	if estimate <= 2.5*m && zeros != 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return math.Round(estimate)
+}
+
+// mix64 is the finalizer of SplitMix64, which distributes the bits of FNV
+// hashes better, as HyperLogLog requires.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,91 @@
+package chromem
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_MetadataValueStats(t *testing.T) {
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i, language := range []string{"en", "de", "en", "fr", "de", "en", ""} {
+		doc := &Document{ID: strconv.Itoa(i), Metadata: map[string]string{"language": language}}
+		if language == "" {
+			doc.Metadata = nil
+		}
+		c.documents[doc.ID] = doc
+	}
+
+	stats, err := c.MetadataValueStats("language")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected := MetadataValueStats{
+		Key:            "language",
+		Documents:      6,
+		DistinctValues: 3,
+		Values: []MetadataValueCount{
+			{Value: "en", Count: 3},
+			{Value: "de", Count: 2},
+			{Value: "fr", Count: 1},
+		},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+
+	_, err = c.MetadataValueStats("")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_MetadataValueStats_Approximate(t *testing.T) {
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	n := 5 * MaxExactDistinctValues
+	for i := 0; i < n; i++ {
+		id := strconv.Itoa(i)
+		c.documents[id] = &Document{ID: id, Metadata: map[string]string{"user": "user-" + id}}
+	}
+
+	stats, err := c.MetadataValueStats("user")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !stats.Approximate || stats.Values != nil || stats.Documents != n {
+		t.Fatalf("expected approximate stats of %d documents, got %+v", n, stats)
+	}
+	if relErr := math.Abs(float64(stats.DistinctValues-n)) / float64(n); relErr > 0.03 {
+		t.Fatalf("expected about %d distinct values, got %d", n, stats.DistinctValues)
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 10_000, 1_000_000} {
+		h := newHyperLogLog()
+		for i := 0; i < n; i++ {
+			h.add(strconv.Itoa(i))
+			// Duplicates don't count
+			h.add(strconv.Itoa(i))
+		}
+		estimate := h.estimate()
+		if n == 0 {
+			if estimate != 0 {
+				t.Fatalf("expected 0, got %v", estimate)
+			}
+			continue
+		}
+		if relErr := math.Abs(estimate-float64(n)) / float64(n); relErr > 0.03 {
+			t.Fatalf("expected about %d, got %v", n, estimate)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrNotNormalized is returned when adding a document with an embedding that
+// isn't normalized to a collection with [NormalizationPolicyReject].
+var ErrNotNormalized = errors.New("embedding isn't normalized")
+
+// NormalizationPolicy defines how a collection handles precomputed embeddings
+// that aren't normalized when adding documents. chromem-go calculates the
+// cosine similarity as dot product, which requires normalized embeddings, so
+// such embeddings would otherwise lead to wrong similarities.
+type NormalizationPolicy int
+
+const (
+	// NormalizationPolicyNormalize normalizes embeddings that aren't normalized.
+	// This is the default.
+	NormalizationPolicyNormalize NormalizationPolicy = iota
+	// NormalizationPolicyReject rejects embeddings that aren't normalized with an
+	// error wrapping [ErrNotNormalized]. This is useful to detect embeddings that
+	// were created by a different model or pipeline than expected.
+	NormalizationPolicyReject
+)
+
+// SetNormalizationPolicy sets how the collection handles precomputed embeddings
+// that aren't normalized when adding documents. For persistent collections it's
+// persisted.
+// Embeddings with zero magnitude or NaN or infinite values are always rejected,
+// as they can't be normalized.
+func (c *Collection) SetNormalizationPolicy(policy NormalizationPolicy) error {
+	switch policy {
+	case NormalizationPolicyNormalize, NormalizationPolicyReject:
+	default:
+		return fmt.Errorf("unknown normalization policy %d", policy)
+	}
+
+	c.configLock.Lock()
+	c.config.NormalizationPolicy = policy
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// NormalizationPolicy returns the collection's normalization policy.
+func (c *Collection) NormalizationPolicy() NormalizationPolicy {
+	return c.getConfig().NormalizationPolicy
+}
+
+// validateEmbedding checks that the embedding's values are finite and that it
+// isn't a zero vector, and checks the normalization according to the policy.
+// It returns the embedding, normalized if necessary.
+func validateEmbedding(v []float32, policy NormalizationPolicy) ([]float32, error) {
+	var sqSum float64
+	for i, val := range v {
+		if math.IsNaN(float64(val)) || math.IsInf(float64(val), 0) {
+			return nil, fmt.Errorf("embedding has invalid value %v at index %d", val, i)
+		}
+		sqSum += float64(val) * float64(val)
+	}
+	if sqSum == 0 {
+		return nil, errors.New("embedding has zero magnitude")
+	}
+
+	magnitude := math.Sqrt(sqSum)
+	if math.Abs(magnitude-1) < isNormalizedPrecisionTolerance {
+		return v, nil
+	}
+	if policy == NormalizationPolicyReject {
+		return nil, fmt.Errorf("%w: magnitude is %v", ErrNotNormalized, magnitude)
+	}
+	return normalizeVector(v), nil
+}
@@ -0,0 +1,72 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestValidateEmbedding(t *testing.T) {
+	normalized := []float32{-0.40824828, 0.40824828, 0.81649655}
+
+	tt := []struct {
+		name    string
+		v       []float32
+		policy  NormalizationPolicy
+		want    []float32
+		wantErr error
+	}{
+		{name: "normalized", v: normalized, policy: NormalizationPolicyReject, want: normalized},
+		{name: "normalize", v: []float32{-0.1, 0.1, 0.2}, policy: NormalizationPolicyNormalize, want: normalized},
+		{name: "reject", v: []float32{-0.1, 0.1, 0.2}, policy: NormalizationPolicyReject, wantErr: ErrNotNormalized},
+		{name: "zero", v: []float32{0, 0, 0}, policy: NormalizationPolicyNormalize, wantErr: errors.New("embedding has zero magnitude")},
+		{name: "NaN", v: []float32{0, float32(math.NaN()), 1}, policy: NormalizationPolicyNormalize, wantErr: errors.New("embedding has invalid value NaN at index 1")},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := validateEmbedding(tc.v, tc.policy)
+			if tc.wantErr != nil {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !errors.Is(err, tc.wantErr) && err.Error() != tc.wantErr.Error() {
+					t.Fatal("expected error", tc.wantErr, "got", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Fatal("expected", tc.want, "got", got)
+			}
+		})
+	}
+}
+
+func TestCollection_NormalizationPolicy(t *testing.T) {
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetNormalizationPolicy(NormalizationPolicyReject)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.AddDocument(context.Background(), Document{ID: "1", Embedding: []float32{-0.1, 0.1, 0.2}})
+	if !errors.Is(err, ErrNotNormalized) {
+		t.Fatal("expected ErrNotNormalized, got", err)
+	}
+	if c.Count() != 0 {
+		t.Fatal("expected 0 documents, got", c.Count())
+	}
+
+	err = c.SetNormalizationPolicy(NormalizationPolicy(42))
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
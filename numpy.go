@@ -0,0 +1,320 @@
+package chromem
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// npyMagic is the magic string at the start of every .npy file.
+const npyMagic = "\x93NUMPY"
+
+// ReadNPY reads a 2-dimensional array of float32 or float64 values from a NumPy
+// .npy file, as written by numpy.save(), and returns it as one embedding per row.
+// Both byte orders and C as well as Fortran order are supported. float64 values
+// are converted to float32.
+func ReadNPY(r io.Reader) ([][]float32, error) {
+	br := bufio.NewReader(r)
+
+	preamble := make([]byte, len(npyMagic)+2)
+	_, err := io.ReadFull(br, preamble)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read magic string: %w", err)
+	}
+	if string(preamble[:len(npyMagic)]) != npyMagic {
+		return nil, errors.New("not a .npy file")
+	}
+	var headerLen int
+	switch major := preamble[len(npyMagic)]; major {
+	case 1:
+		var l uint16
+		err = binary.Read(br, binary.LittleEndian, &l)
+		headerLen = int(l)
+	case 2, 3:
+		var l uint32
+		err = binary.Read(br, binary.LittleEndian, &l)
+		headerLen = int(l)
+	default:
+		return nil, fmt.Errorf("unsupported .npy format version %d", major)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read header length: %w", err)
+	}
+	header := make([]byte, headerLen)
+	_, err = io.ReadFull(br, header)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read header: %w", err)
+	}
+
+	descr, fortranOrder, shape, err := parseNPYHeader(string(header))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse header: %w", err)
+	}
+	if len(shape) != 2 {
+		return nil, fmt.Errorf("expected 2-dimensional array, got shape %v", shape)
+	}
+	var order binary.ByteOrder
+	switch descr[0] {
+	case '<', '|':
+		order = binary.LittleEndian
+	case '>':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("unsupported dtype %q", descr)
+	}
+	var itemSize int
+	switch descr[1:] {
+	case "f4":
+		itemSize = 4
+	case "f8":
+		itemSize = 8
+	default:
+		return nil, fmt.Errorf("unsupported dtype %q, only float32 and float64 are supported", descr)
+	}
+
+	rows, cols := shape[0], shape[1]
+	data := make([]byte, rows*cols*itemSize)
+	_, err = io.ReadFull(br, data)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read data: %w", err)
+	}
+
+	embeddings := make([][]float32, rows)
+	for i := range embeddings {
+		embeddings[i] = make([]float32, cols)
+	}
+	for n := 0; n < rows*cols; n++ {
+		// In Fortran order, the first index varies fastest.
+		i, j := n/cols, n%cols
+		if fortranOrder {
+			i, j = n%rows, n/rows
+		}
+		b := data[n*itemSize : (n+1)*itemSize]
+		if itemSize == 4 {
+			embeddings[i][j] = math.Float32frombits(order.Uint32(b))
+		} else {
+			embeddings[i][j] = float32(math.Float64frombits(order.Uint64(b)))
+		}
+	}
+
+	return embeddings, nil
+}
+
+// parseNPYHeader parses the Python dict literal of a .npy header, for example
+// "{'descr': '<f4', 'fortran_order': False, 'shape': (3, 384), }".
+func parseNPYHeader(header string) (descr string, fortranOrder bool, shape []int, err error) {
+	value := func(key string) (string, bool) {
+		_, after, found := strings.Cut(header, "'"+key+"':")
+		return strings.TrimSpace(after), found
+	}
+
+	v, ok := value("descr")
+	if !ok || len(v) < 2 || v[0] != '\'' {
+		return "", false, nil, errors.New("missing descr")
+	}
+	descr, _, _ = strings.Cut(v[1:], "'")
+	if len(descr) < 3 {
+		return "", false, nil, fmt.Errorf("invalid descr %q", descr)
+	}
+
+	v, ok = value("fortran_order")
+	if !ok {
+		return "", false, nil, errors.New("missing fortran_order")
+	}
+	fortranOrder = strings.HasPrefix(v, "True")
+
+	v, ok = value("shape")
+	if !ok || !strings.HasPrefix(v, "(") {
+		return "", false, nil, errors.New("missing shape")
+	}
+	dims, _, _ := strings.Cut(v[1:], ")")
+	for _, dim := range strings.Split(dims, ",") {
+		dim = strings.TrimSpace(dim)
+		if dim == "" {
+			// Trailing comma of 1-tuples
+			continue
+		}
+		n, err := strconv.Atoi(dim)
+		if err != nil || n < 0 {
+			return "", false, nil, fmt.Errorf("invalid shape %q", dims)
+		}
+		shape = append(shape, n)
+	}
+
+	return descr, fortranOrder, shape, nil
+}
+
+// ReadNPZ reads all arrays from a NumPy .npz file, as written by numpy.savez()
+// or numpy.savez_compressed(). All arrays must be 2-dimensional float32 or float64
+// arrays. The returned map's keys are the array names, like "embeddings" for
+// numpy.savez(path, embeddings=arr), or "arr_0" for unnamed arrays.
+func ReadNPZ(path string) (map[string][][]float32, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open .npz file: %w", err)
+	}
+	defer zr.Close()
+
+	arrays := make(map[string][][]float32, len(zr.File))
+	for _, f := range zr.File {
+		name, ok := strings.CutSuffix(f.Name, ".npy")
+		if !ok {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open array %q: %w", name, err)
+		}
+		arrays[name], err = ReadNPY(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read array %q: %w", name, err)
+		}
+	}
+
+	return arrays, nil
+}
+
+// readNPZArray reads a single array from a .npz file, so that other arrays in
+// the file, for example with string IDs, don't have to be supported. If name is
+// empty, the array named "embeddings" is read, or the only array.
+func readNPZArray(path, name string) ([][]float32, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open .npz file: %w", err)
+	}
+	defer zr.Close()
+
+	var npyFiles []*zip.File
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, ".npy") {
+			npyFiles = append(npyFiles, f)
+		}
+	}
+	if name == "" {
+		name = "embeddings"
+		if len(npyFiles) == 1 {
+			name = strings.TrimSuffix(npyFiles[0].Name, ".npy")
+		}
+	}
+	for _, f := range npyFiles {
+		if f.Name != name+".npy" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open array %q: %w", name, err)
+		}
+		defer rc.Close()
+		embeddings, err := ReadNPY(rc)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read array %q: %w", name, err)
+		}
+		return embeddings, nil
+	}
+	return nil, fmt.Errorf("array %q not found in .npz file", name)
+}
+
+// NumpyImportOptions are the options for [Collection.ImportFromNumpy].
+type NumpyImportOptions struct {
+	// The name of the array in a .npz file. Optional. If empty, the array named
+	// "embeddings" is used, or the only array if there's just one.
+	ArrayName string
+
+	// The number of goroutines to use for adding the documents. Optional,
+	// defaults to [runtime.NumCPU].
+	Concurrency int
+}
+
+// ImportFromNumpy bulk-inserts precomputed embeddings from a NumPy .npy or .npz
+// file into the collection, without calling the collection's embedding function.
+// This is useful when embeddings are created by batch jobs in Python.
+//
+// The IDs are read from a separate file, with one ID per line, or as JSON array
+// of strings if the file name ends with ".json". The number of IDs must match the
+// number of rows in the embeddings array, with the n-th ID belonging to the n-th
+// row. The documents have neither content nor metadata, so you can only query
+// them by embedding, or add content and metadata later by adding the documents
+// again with the same IDs.
+//
+//   - embeddingsPath: Path to the .npy or .npz file with a 2-dimensional float32
+//     or float64 array
+//   - idsPath: Path to the file with the IDs
+//   - opts: Options for the import, see [NumpyImportOptions]
+func (c *Collection) ImportFromNumpy(ctx context.Context, embeddingsPath, idsPath string, opts NumpyImportOptions) error {
+	var embeddings [][]float32
+	if strings.HasSuffix(embeddingsPath, ".npz") {
+		var err error
+		embeddings, err = readNPZArray(embeddingsPath, opts.ArrayName)
+		if err != nil {
+			return err
+		}
+	} else {
+		f, err := os.Open(embeddingsPath)
+		if err != nil {
+			return fmt.Errorf("couldn't open embeddings file: %w", err)
+		}
+		defer f.Close()
+		embeddings, err = ReadNPY(f)
+		if err != nil {
+			return fmt.Errorf("couldn't read embeddings file: %w", err)
+		}
+	}
+
+	ids, err := readIDsFile(idsPath)
+	if err != nil {
+		return err
+	}
+	if len(ids) != len(embeddings) {
+		return fmt.Errorf("got %d IDs for %d embeddings", len(ids), len(embeddings))
+	}
+
+	docs := make([]Document, len(ids))
+	for i, id := range ids {
+		docs[i] = Document{ID: id, Embedding: embeddings[i]}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	ctx, _ = startProgress(ctx, "import", len(docs))
+	return c.AddDocuments(ctx, docs, concurrency)
+}
+
+// readIDsFile reads document IDs from a file with one ID per line, or from a
+// JSON array if the file name ends with ".json".
+func readIDsFile(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read IDs file: %w", err)
+	}
+
+	var ids []string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &ids)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decode IDs file: %w", err)
+		}
+		return ids, nil
+	}
+
+	if len(b) == 0 {
+		return nil, nil
+	}
+	// Allow Windows line endings and a trailing newline.
+	for _, line := range strings.Split(strings.TrimRight(string(b), "\r\n"), "\n") {
+		ids = append(ids, strings.TrimSuffix(line, "\r"))
+	}
+	return ids, nil
+}
@@ -0,0 +1,180 @@
+package chromem
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// npyBytes creates a .npy file like numpy.save() does.
+func npyBytes(t *testing.T, descr string, fortranOrder bool, shape string, data any) []byte {
+	t.Helper()
+
+	order := "False"
+	if fortranOrder {
+		order = "True"
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': %s, 'shape': %s, }", descr, order, shape)
+	// Pad the header so that the data starts at a multiple of 64 bytes.
+	header += strings.Repeat(" ", 63-(10+len(header))%64) + "\n"
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(npyMagic + "\x01\x00")
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if descr[0] == '>' {
+		byteOrder = binary.BigEndian
+	}
+	if err := binary.Write(buf, byteOrder, data); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadNPY(t *testing.T) {
+	want := [][]float32{{1, 2, 3}, {4, 5, 6}}
+
+	tt := []struct {
+		name    string
+		npy     []byte
+		wantErr bool
+	}{
+		{
+			name: "float32",
+			npy:  npyBytes(t, "<f4", false, "(2, 3)", []float32{1, 2, 3, 4, 5, 6}),
+		},
+		{
+			name: "float64",
+			npy:  npyBytes(t, "<f8", false, "(2, 3)", []float64{1, 2, 3, 4, 5, 6}),
+		},
+		{
+			name: "big endian",
+			npy:  npyBytes(t, ">f4", false, "(2, 3)", []float32{1, 2, 3, 4, 5, 6}),
+		},
+		{
+			name: "Fortran order",
+			npy:  npyBytes(t, "<f4", true, "(2, 3)", []float32{1, 4, 2, 5, 3, 6}),
+		},
+		{
+			name:    "int",
+			npy:     npyBytes(t, "<i4", false, "(2, 3)", []int32{1, 2, 3, 4, 5, 6}),
+			wantErr: true,
+		},
+		{
+			name:    "1-dimensional",
+			npy:     npyBytes(t, "<f4", false, "(6,)", []float32{1, 2, 3, 4, 5, 6}),
+			wantErr: true,
+		},
+		{
+			name:    "truncated",
+			npy:     npyBytes(t, "<f4", false, "(3, 3)", []float32{1, 2, 3, 4, 5, 6}),
+			wantErr: true,
+		},
+		{
+			name:    "no npy",
+			npy:     []byte("hello world"),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadNPY(bytes.NewReader(tc.npy))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestCollection_ImportFromNumpy(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		t.Fatal("embedding func must not be called")
+		return nil, nil
+	}
+
+	npy := npyBytes(t, "<f4", false, "(2, 3)", []float32{1, 0, 0, 0, 3, 4})
+	npyPath := filepath.Join(dir, "embeddings.npy")
+	if err := os.WriteFile(npyPath, npy, 0o600); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	npzPath := filepath.Join(dir, "embeddings.npz")
+	f, err := os.Create(npzPath)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	zw := zip.NewWriter(f)
+	// The other array isn't a float array, so it must not be read
+	for name, content := range map[string][]byte{"ids.npy": []byte("not a float array"), "embeddings.npy": npy} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		_, _ = w.Write(content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	f.Close()
+
+	idsPath := filepath.Join(dir, "ids.txt")
+	if err := os.WriteFile(idsPath, []byte("a\r\nb\n"), 0o600); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	jsonIDsPath := filepath.Join(dir, "ids.json")
+	if err := os.WriteFile(jsonIDsPath, []byte(`["a","b"]`), 0o600); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, paths := range [][2]string{{npyPath, idsPath}, {npzPath, jsonIDsPath}} {
+		t.Run(filepath.Base(paths[0]), func(t *testing.T) {
+			c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.ImportFromNumpy(ctx, paths[0], paths[1], NumpyImportOptions{})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if c.Count() != 2 {
+				t.Fatal("expected 2 documents, got", c.Count())
+			}
+			// Embeddings are normalized
+			if got := c.documents["b"].Embedding; !reflect.DeepEqual(got, []float32{0, 0.6, 0.8}) {
+				t.Fatal("unexpected embedding", got)
+			}
+		})
+	}
+
+	// Mismatching number of IDs
+	if err := os.WriteFile(idsPath, []byte("a\n"), 0o600); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.ImportFromNumpy(ctx, npyPath, idsPath, NumpyImportOptions{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
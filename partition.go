@@ -0,0 +1,194 @@
+package chromem
+
+import (
+	"maps"
+	"slices"
+)
+
+// PartitionBy partitions the collection's documents by the value of the
+// metadata key, for example "tenant_id" or "language". Queries whose where
+// filter has the key then only scan the documents of the partition with the
+// filter's value, instead of all documents. For collections with many
+// partitions, this makes queries much faster. Unlike with a vector index (see
+// [Collection.SetIndex]), the results are exact.
+//
+// The partitions are kept up to date when documents are added, updated and
+// deleted. Documents without the key are in the partition of the empty value,
+// as they match a where filter with an empty value. An empty key removes the
+// partitioning.
+// The key is persisted, and the partitions are recalculated when the collection
+// is loaded.
+func (c *Collection) PartitionBy(key string) error {
+	c.configLock.Lock()
+	c.config.PartitionKey = key
+	c.configLock.Unlock()
+
+	c.documentsLock.Lock()
+	c.partitions = newPartitions(key, c.documents)
+	c.documentsLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// PartitionKey returns the metadata key the collection is partitioned by, or an
+// empty string if it isn't partitioned.
+func (c *Collection) PartitionKey() string {
+	return c.getConfig().PartitionKey
+}
+
+// Partitions returns the number of documents per value of the partition key,
+// see [Collection.PartitionBy]. It returns nil if the collection isn't
+// partitioned.
+func (c *Collection) Partitions() map[string]int {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	if c.partitions == nil {
+		return nil
+	}
+	res := make(map[string]int, len(c.partitions.lists.lists))
+	for value, ids := range c.partitions.lists.lists {
+		res[value] = len(ids)
+	}
+	return res
+}
+
+// partitions are the IDs of a collection's documents by the value of the
+// partition key. They're guarded by the collection's documents lock. The methods
+// of nil partitions do nothing.
+type partitions struct {
+	key   string
+	lists *postingLists[string]
+}
+
+// newPartitions partitions the documents by the key. It returns nil for an
+// empty key.
+func newPartitions(key string, documents map[string]*Document) *partitions {
+	if key == "" {
+		return nil
+	}
+	p := &partitions{
+		key:   key,
+		lists: newPostingLists[string](len(documents)),
+	}
+	for id, doc := range documents {
+		p.lists.add(id, doc.Metadata[key])
+	}
+	return p
+}
+
+// set moves the added or replaced document to its partition.
+func (p *partitions) set(doc *Document) {
+	if p == nil {
+		return
+	}
+	value := doc.Metadata[p.key]
+	if current, ok := p.lists.listOf(doc.ID); ok {
+		if current == value {
+			return
+		}
+		p.lists.remove(doc.ID)
+	}
+	p.lists.add(doc.ID, value)
+}
+
+// clone returns a copy of the partitions, for snapshots.
+func (p *partitions) clone() *partitions {
+	if p == nil {
+		return nil
+	}
+	return &partitions{key: p.key, lists: p.lists.clone()}
+}
+
+// remove removes the document from its partition.
+func (p *partitions) remove(id string) {
+	if p == nil {
+		return
+	}
+	p.lists.remove(id)
+}
+
+// candidates returns the documents of the partition the filter restricts the
+// query to, or false if it doesn't restrict it to a partition.
+func (p *partitions) candidates(filter *Filter, documents map[string]*Document) ([]*Document, bool) {
+	if p == nil {
+		return nil, false
+	}
+	value, ok := filter.metadataValue(p.key)
+	if !ok {
+		return nil, false
+	}
+	ids := p.lists.lists[value]
+	docs := make([]*Document, 0, len(ids))
+	for _, id := range ids {
+		if doc, ok := documents[id]; ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, true
+}
+
+// postingLists assign document IDs to lists, like the partitions of a
+// collection or the clusters of an IVF index. Documents can be removed in
+// constant time, by replacing them with the last document of their list.
+type postingLists[K comparable] struct {
+	lists map[K][]string
+	// Document ID -> position in the lists
+	postings map[string]posting[K]
+}
+
+type posting[K comparable] struct {
+	list K
+	i    int
+}
+
+func newPostingLists[K comparable](size int) *postingLists[K] {
+	return &postingLists[K]{
+		lists:    make(map[K][]string),
+		postings: make(map[string]posting[K], size),
+	}
+}
+
+// add adds the document to the list. It must not be in a list yet.
+func (p *postingLists[K]) add(id string, list K) {
+	p.postings[id] = posting[K]{list: list, i: len(p.lists[list])}
+	p.lists[list] = append(p.lists[list], id)
+}
+
+// listOf returns the list of the document, or false if it isn't in a list.
+func (p *postingLists[K]) listOf(id string) (K, bool) {
+	posting, ok := p.postings[id]
+	return posting.list, ok
+}
+
+// clone returns a deep copy of the lists.
+func (p *postingLists[K]) clone() *postingLists[K] {
+	lists := make(map[K][]string, len(p.lists))
+	for k, ids := range p.lists {
+		lists[k] = slices.Clone(ids)
+	}
+	return &postingLists[K]{
+		lists:    lists,
+		postings: maps.Clone(p.postings),
+	}
+}
+
+// remove removes the document from its list, if it's in one.
+func (p *postingLists[K]) remove(id string) {
+	pos, ok := p.postings[id]
+	if !ok {
+		return
+	}
+	delete(p.postings, id)
+	list := p.lists[pos.list]
+	last := len(list) - 1
+	if pos.i != last {
+		list[pos.i] = list[last]
+		p.postings[list[pos.i]] = pos
+	}
+	if last == 0 {
+		delete(p.lists, pos.list)
+	} else {
+		p.lists[pos.list] = list[:last]
+	}
+}
@@ -0,0 +1,126 @@
+package chromem
+
+import (
+	"context"
+	"maps"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_PartitionBy(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	var docs []Document
+	for i := 0; i < 30; i++ {
+		docs = append(docs, Document{
+			ID:        strconv.Itoa(i),
+			Embedding: []float32{1, float32(i) / 30},
+			Metadata:  map[string]string{"tenant": "t" + strconv.Itoa(i%3)},
+		})
+	}
+	docs = append(docs, Document{ID: "none", Embedding: []float32{1, 0}})
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.PartitionBy("tenant")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected := map[string]int{"t0": 10, "t1": 10, "t2": 10, "": 1}
+	if partitions := c.Partitions(); !maps.Equal(partitions, expected) {
+		t.Fatal("expected", expected, "got", partitions)
+	}
+
+	// Only the partition is scanned, with exact results.
+	res, stats, err := c.QueryWithStats(ctx, QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 2, Where: map[string]string{"tenant": "t1"}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats.DocumentsScanned != 10 {
+		t.Fatal("expected 10 documents to be scanned, got", stats.DocumentsScanned)
+	}
+	if len(res) != 2 || res[0].ID != "1" || res[1].ID != "4" {
+		t.Fatal("expected documents 1 and 4, got", res)
+	}
+	_, stats, err = c.QueryWithStats(ctx, QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 1})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats.DocumentsScanned != 31 {
+		t.Fatal("expected all documents to be scanned, got", stats.DocumentsScanned)
+	}
+
+	// Updated and deleted documents change their partitions.
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Metadata: map[string]string{"tenant": "t2"}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "none")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected = map[string]int{"t0": 10, "t1": 9, "t2": 11}
+	if partitions := c.Partitions(); !maps.Equal(partitions, expected) {
+		t.Fatal("expected", expected, "got", partitions)
+	}
+	res, err = c.QueryEmbedding(ctx, []float32{1, 0}, 1, map[string]string{"tenant": "t2"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "1" {
+		t.Fatal("expected document 1, got", res[0].ID)
+	}
+
+	// The partitions are recalculated when loading the collection.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if c2.PartitionKey() != "tenant" {
+		t.Fatal("expected partition key tenant, got", c2.PartitionKey())
+	}
+	if partitions := c2.Partitions(); !maps.Equal(partitions, expected) {
+		t.Fatal("expected", expected, "got", partitions)
+	}
+
+	err = c.PartitionBy("")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if partitions := c.Partitions(); partitions != nil {
+		t.Fatal("expected no partitions, got", partitions)
+	}
+}
+
+func TestPostingLists_remove(t *testing.T) {
+	p := newPostingLists[int](3)
+	p.add("1", 0)
+	p.add("2", 0)
+	p.add("3", 1)
+
+	// The last document of the list takes the place of the removed one.
+	p.remove("1")
+	if len(p.lists[0]) != 1 || p.lists[0][0] != "2" || p.postings["2"] != (posting[int]{list: 0, i: 0}) {
+		t.Fatal("expected document 2 at position 0, got", p.lists, p.postings)
+	}
+	// Empty lists are removed.
+	p.remove("3")
+	if _, ok := p.lists[1]; ok {
+		t.Fatal("expected list 1 to be removed, got", p.lists)
+	}
+	if _, ok := p.listOf("3"); ok {
+		t.Fatal("expected document 3 not to be in a list")
+	}
+	p.remove("missing")
+}
@@ -0,0 +1,161 @@
+package chromem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// maxPathNameLength is the maximum length of encoded path names. It leaves
+	// room for file extensions within the 255 bytes that most file systems
+	// allow, and keeps paths short for Windows.
+	maxPathNameLength = 128
+	// hashedPathNameLength is the length of the prefix of names that are too
+	// long and are shortened with a hash.
+	hashedPathNameLength = 96
+)
+
+// reservedPathNames are names that encoded names must not be equal to: the
+// device names of Windows, which can't be used as file names with any
+// extension and in any case, and the names of the files and directories of
+// the persistence directory itself.
+var reservedPathNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com0": true, "com1": true, "com2": true, "com3": true, "com4": true,
+	"com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt0": true, "lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true,
+	"lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+	metadataFileName: true, blobDirName: true, trashDirName: true, segmentDirName: true,
+}
+
+// EncodePathName encodes a collection name or document ID as the name of a
+// file or directory, the way persistent DBs name the directories of
+// collections and the files of documents. The encoding is deterministic and
+// safe on all operating systems, including case-insensitive file systems and
+// Windows:
+//
+//   - The characters a-z, 0-9, "-" and "_" are kept.
+//   - All other bytes, including uppercase letters, "." and the bytes of
+//     non-ASCII characters, are escaped as "%" followed by two lowercase hex
+//     digits. Names with path separators or ".." can't escape the directory.
+//   - Names that are reserved on Windows, like "con", or by chromem-go are
+//     escaped as well.
+//
+// The encoding is reversible with [DecodePathName], except for names whose
+// encoding is longer than 128 bytes. They're shortened and a hash of the name
+// is appended after a "~", which the encoding doesn't produce otherwise. The
+// names of such collections are in the collections' metadata files, see
+// [DB.CollectionNameForPath].
+func EncodePathName(name string) string {
+	sb := strings.Builder{}
+	sb.Grow(len(name))
+	for i := 0; i < len(name); i++ {
+		b := name[i]
+		if (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '-' || b == '_' {
+			sb.WriteByte(b)
+		} else {
+			fmt.Fprintf(&sb, "%%%02x", b)
+		}
+	}
+	encoded := sb.String()
+	if reservedPathNames[encoded] {
+		encoded = fmt.Sprintf("%%%02x", encoded[0]) + encoded[1:]
+	}
+
+	if len(encoded) > maxPathNameLength || name == "" {
+		prefix := encoded[:min(len(encoded), hashedPathNameLength)]
+		// Don't cut escape sequences
+		if i := strings.LastIndexByte(prefix, '%'); i >= len(prefix)-2 && i != -1 {
+			prefix = prefix[:i]
+		}
+		hash := sha256.Sum256([]byte(name))
+		encoded = prefix + "~" + hex.EncodeToString(hash[:8])
+	}
+	return encoded
+}
+
+// DecodePathName decodes a name that was encoded with [EncodePathName]. It
+// returns false if the name isn't a valid encoding, or if it was shortened
+// because it was too long.
+func DecodePathName(encoded string) (string, bool) {
+	if encoded == "" || strings.Contains(encoded, "~") {
+		return "", false
+	}
+	sb := strings.Builder{}
+	sb.Grow(len(encoded))
+	for i := 0; i < len(encoded); i++ {
+		b := encoded[i]
+		if b != '%' {
+			sb.WriteByte(b)
+			continue
+		}
+		if i+2 >= len(encoded) {
+			return "", false
+		}
+		decoded, err := hex.DecodeString(encoded[i+1 : i+3])
+		if err != nil || strings.ToLower(encoded[i+1:i+3]) != encoded[i+1:i+3] {
+			return "", false
+		}
+		sb.WriteByte(decoded[0])
+		i += 2
+	}
+	// Only the canonical encoding is valid, so that each name has exactly
+	// one encoding.
+	name := sb.String()
+	if EncodePathName(name) != encoded {
+		return "", false
+	}
+	return name, true
+}
+
+// CollectionNameForPath returns the name of the collection whose directory has
+// the given name (or path) in the persistence directory, which also works for
+// directories whose names can't be decoded with [DecodePathName]. It returns
+// false if there is no such collection.
+func (db *DB) CollectionNameForPath(dir string) (string, bool) {
+	db.collectionsLock.RLock()
+	defer db.collectionsLock.RUnlock()
+
+	for name, c := range db.collections {
+		if c.persistDirectory == "" {
+			continue
+		}
+		if dir == c.persistDirectory || dir == filepath.Base(c.persistDirectory) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// migratingSuffix is the suffix of files that are renamed by renameFiles.
+const migratingSuffix = ".migrating"
+
+// renameFiles renames files from the keys to the values of the map. It renames
+// them via temporary names first, so that the new name of one file can be the
+// old name of another one. Keys with the temporary suffix are files of an
+// interrupted earlier run, which are only renamed to their new name.
+func renameFiles(renames map[string]string) error {
+	for oldPath := range renames {
+		if strings.HasSuffix(oldPath, migratingSuffix) {
+			continue
+		}
+		err := os.Rename(oldPath, oldPath+migratingSuffix)
+		if err != nil {
+			return fmt.Errorf("couldn't rename file: %w", err)
+		}
+	}
+	for oldPath, newPath := range renames {
+		if !strings.HasSuffix(oldPath, migratingSuffix) {
+			oldPath += migratingSuffix
+		}
+		err := os.Rename(oldPath, newPath)
+		if err != nil {
+			return fmt.Errorf("couldn't rename file: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncodePathName(t *testing.T) {
+	tt := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "abc-123_x", encoded: "abc-123_x"},
+		{name: "Foo", encoded: "%46oo"},
+		{name: "a.b", encoded: "a%2eb"},
+		{name: "../x", encoded: "%2e%2e%2fx"},
+		{name: `a\b:c`, encoded: "a%5cb%3ac"},
+		{name: "日本", encoded: "%e6%97%a5%e6%9c%ac"},
+		{name: "con", encoded: "%63on"},
+		{name: "00000000", encoded: "%300000000"},
+		{name: "blobs", encoded: "%62lobs"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded := EncodePathName(tc.name)
+			if encoded != tc.encoded {
+				t.Fatalf("expected %q, got %q", tc.encoded, encoded)
+			}
+			name, ok := DecodePathName(encoded)
+			if !ok || name != tc.name {
+				t.Fatalf("expected %q, got %q (%v)", tc.name, name, ok)
+			}
+		})
+	}
+
+	t.Run("long", func(t *testing.T) {
+		name := "x" + strings.Repeat("A", 100)
+		encoded := EncodePathName(name)
+		if len(encoded) > maxPathNameLength || !strings.Contains(encoded, "~") {
+			t.Fatal("expected shortened name, got", encoded)
+		}
+		if !strings.HasPrefix(encoded, "x"+strings.Repeat("%41", 31)+"~") {
+			t.Fatal("expected escape sequences not to be cut, got", encoded)
+		}
+		if encoded == EncodePathName(name+"A") {
+			t.Fatal("expected different encodings for different names")
+		}
+		if _, ok := DecodePathName(encoded); ok {
+			t.Fatal("expected shortened name not to be decodable")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, encoded := range []string{"", "%zz", "%4", "%4F", "%61", "con", "A", "a.b"} {
+			if name, ok := DecodePathName(encoded); ok {
+				t.Fatalf("expected %q to be invalid, got %q", encoded, name)
+			}
+		}
+	})
+}
+
+func TestNewPersistentDB_pathMigration(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("Test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "a", Embedding: []float32{1, 0}, Content: "a"},
+		{ID: "b", Embedding: []float32{0, 1}, Content: "b"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Simulate the hashed names and the gob extension of earlier versions,
+	// where the name of one document's file is the name of another one's.
+	legacyDir := filepath.Join(dir, "1a2b3c4d")
+	err = os.Rename(c.persistDirectory, legacyDir)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = os.Rename(filepath.Join(legacyDir, "a"+documentExt), filepath.Join(legacyDir, "b.gob"))
+	if err == nil {
+		err = os.Rename(filepath.Join(legacyDir, "b"+documentExt), filepath.Join(legacyDir, "a.gob"))
+	}
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("Test", nil)
+	if c.Count() != 2 || c.documents["a"].Content != "a" || c.documents["b"].Content != "b" {
+		t.Fatal("expected 2 migrated documents, got", c.Count())
+	}
+	if c.persistDirectory != filepath.Join(dir, "%54est") {
+		t.Fatal("expected migrated collection directory, got", c.persistDirectory)
+	}
+	for _, id := range []string{"a", "b"} {
+		doc := &Document{}
+		err = readFromFile(c.getDocPath(id), doc, "")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if doc.ID != id {
+			t.Fatalf("expected document %q at %q, got %q", id, c.getDocPath(id), doc.ID)
+		}
+	}
+
+	if files, _ := filepath.Glob(filepath.Join(c.persistDirectory, "*.gob")); len(files) != 1 || filepath.Base(files[0]) != metadataFileName+".gob" {
+		t.Fatal("expected only the metadata file with the gob extension, got", files)
+	}
+
+	name, ok := db.CollectionNameForPath("%54est")
+	if !ok || name != "Test" {
+		t.Fatalf("expected collection name %q, got %q (%v)", "Test", name, ok)
+	}
+	if _, ok := db.CollectionNameForPath("1a2b3c4d"); ok {
+		t.Fatal("expected no collection for the old directory")
+	}
+}
@@ -1,14 +1,13 @@
 package chromem
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/gob"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -19,19 +18,95 @@ import (
 
 const metadataFileName = "00000000"
 
-func hash2hex(name string) string {
-	hash := sha256.Sum256([]byte(name))
-	// We encode 4 of the 32 bytes (32 out of 256 bits), so 8 hex characters.
-	// It's enough to avoid collisions in reasonable amounts of documents per collection
-	// and being shorter is better for file paths.
-	return hex.EncodeToString(hash[:4])
+// persistenceDB is the DB as encoded in export files, with exported fields so
+// that it can be encoded as gob.
+type persistenceDB struct {
+	Collections map[string]*persistenceCollection
+	Aliases     map[string]string
+}
+
+// persistenceCollection is a collection as encoded in export files.
+type persistenceCollection struct {
+	Name      string
+	Metadata  map[string]string
+	Documents map[string]*Document
+	Config    collectionConfig
+	// The archived documents that aren't shadowed by documents, see
+	// [Collection.Archive].
+	Archived map[string]*Document
+	// The documents and archived documents in the binary format, see
+	// [encodeDocumentRecords]. Exports encode the documents in them instead of
+	// Documents and Archived, which are only set in the exports of earlier
+	// versions.
+	DocumentRecords []byte
+	ArchivedRecords []byte
+}
+
+// withDocumentRecords returns a copy of the DB whose documents are encoded in
+// the binary format, for exports.
+func (p persistenceDB) withDocumentRecords() (persistenceDB, error) {
+	res := persistenceDB{
+		Collections: make(map[string]*persistenceCollection, len(p.Collections)),
+		Aliases:     p.Aliases,
+	}
+	for name, pc := range p.Collections {
+		encoded := *pc
+		var err error
+		encoded.DocumentRecords, err = encodeDocumentRecords(pc.Documents)
+		if err == nil {
+			encoded.ArchivedRecords, err = encodeDocumentRecords(pc.Archived)
+		}
+		if err != nil {
+			return persistenceDB{}, fmt.Errorf("couldn't encode documents of collection %q: %w", name, err)
+		}
+		encoded.Documents = nil
+		encoded.Archived = nil
+		res.Collections[name] = &encoded
+	}
+	return res, nil
+}
+
+// decodeDocumentRecords decodes the documents of an export that are encoded in
+// the binary format.
+func (p *persistenceDB) decodeDocumentRecords() error {
+	for name, pc := range p.Collections {
+		if len(pc.DocumentRecords) != 0 {
+			docs, err := decodeDocumentRecords(pc.DocumentRecords)
+			if err != nil {
+				return fmt.Errorf("couldn't decode documents of collection %q: %w", name, err)
+			}
+			pc.Documents = docs
+		}
+		if len(pc.ArchivedRecords) != 0 {
+			docs, err := decodeDocumentRecords(pc.ArchivedRecords)
+			if err != nil {
+				return fmt.Errorf("couldn't decode archived documents of collection %q: %w", name, err)
+			}
+			pc.Archived = docs
+		}
+		pc.DocumentRecords, pc.ArchivedRecords = nil, nil
+	}
+	return nil
+}
+
+// persistenceCollectionMetadata is the content of a persistent collection's
+// metadata file.
+type persistenceCollectionMetadata struct {
+	Name     string
+	Metadata map[string]string
+	Config   collectionConfig
 }
 
 // persistToFile persists an object to a file at the given path. The object is serialized
-// as gob, optionally compressed with flate (as gzip) and optionally encrypted with
+// as gob (documents in a binary format, see [encodeDocument]), optionally compressed with flate (as gzip) and optionally encrypted with
 // AES-GCM. The encryption key must be 32 bytes long. If the file exists, it's
 // overwritten, otherwise created.
 func persistToFile(filePath string, obj any, compress bool, encryptionKey string) error {
+	return persistToFileSynced(filePath, obj, compress, encryptionKey, nil)
+}
+
+// persistToFileSynced is like persistToFile, but syncs the file with the syncer.
+func persistToFileSynced(filePath string, obj any, compress bool, encryptionKey string, syncer *fileSyncer) error {
 	if filePath == "" {
 		return fmt.Errorf("file path is empty")
 	}
@@ -45,6 +120,7 @@ func persistToFile(filePath string, obj any, compress bool, encryptionKey string
 	// If path doesn't exist, create the parent path.
 	// If path exists, and it's a directory, return an error.
 	fi, err := os.Stat(filePath)
+	created := err != nil
 	if err != nil {
 		if !errors.Is(err, fs.ErrNotExist) {
 			return fmt.Errorf("couldn't get info about the path: %w", err)
@@ -66,11 +142,23 @@ func persistToFile(filePath string, obj any, compress bool, encryptionKey string
 	}
 	defer f.Close()
 
-	return persistToWriter(f, obj, compress, encryptionKey)
+	err = persistToWriter(f, obj, compress, encryptionKey)
+	if err != nil {
+		return err
+	}
+	err = syncer.syncFile(f)
+	if err != nil {
+		return err
+	}
+	if created {
+		return syncer.syncDir(filePath)
+	}
+	return nil
 }
 
 // persistToWriter persists an object to a writer. The object is serialized
-// as gob, optionally compressed with flate (as gzip) and optionally encrypted with
+// as gob (documents, also in exported DBs, in a binary format, see
+// [encodeDocument]), optionally compressed with flate (as gzip) and optionally encrypted with
 // AES-GCM. The encryption key must be 32 bytes long.
 // If the writer has to be closed, it's the caller's responsibility.
 func persistToWriter(w io.Writer, obj any, compress bool, encryptionKey string) error {
@@ -95,17 +183,36 @@ func persistToWriter(w io.Writer, obj any, compress bool, encryptionKey string)
 	}
 
 	var gzw *gzip.Writer
-	var enc *gob.Encoder
+	encWriter := chainedWriter
 	if compress {
 		gzw = gzip.NewWriter(chainedWriter)
-		enc = gob.NewEncoder(gzw)
-	} else {
-		enc = gob.NewEncoder(chainedWriter)
+		encWriter = gzw
 	}
 
-	// Start encoding, it will write to the chain of writers.
-	if err := enc.Encode(obj); err != nil {
-		return fmt.Errorf("couldn't encode or write object: %w", err)
+	// Start encoding, it will write to the chain of writers. Documents are
+	// encoded in a binary format that's faster to decode than gob.
+	var err error
+	switch v := obj.(type) {
+	case *Document:
+		err = encodeDocument(encWriter, v)
+	case Document:
+		err = encodeDocument(encWriter, &v)
+	case persistenceDB:
+		v, err = v.withDocumentRecords()
+		if err == nil {
+			err = gob.NewEncoder(encWriter).Encode(v)
+		}
+		if err != nil {
+			err = fmt.Errorf("couldn't encode or write DB: %w", err)
+		}
+	default:
+		err = gob.NewEncoder(encWriter).Encode(obj)
+		if err != nil {
+			err = fmt.Errorf("couldn't encode or write object: %w", err)
+		}
+	}
+	if err != nil {
+		return err
 	}
 
 	// If compressing, close the gzip writer. Otherwise, the gzip footer won't be
@@ -149,7 +256,7 @@ func persistToWriter(w io.Writer, obj any, compress bool, encryptionKey string)
 }
 
 // readFromFile reads an object from a file at the given path. The object is deserialized
-// from gob. `obj` must be a pointer to an instantiated object. The file may
+// from gob, or from the binary format for documents. `obj` must be a pointer to an instantiated object. The file may
 // optionally be compressed as gzip and/or encrypted with AES-GCM. The encryption
 // key must be 32 bytes long.
 func readFromFile(filePath string, obj any, encryptionKey string) error {
@@ -172,12 +279,12 @@ func readFromFile(filePath string, obj any, encryptionKey string) error {
 	return readFromReader(r, obj, encryptionKey)
 }
 
-// readFromReader reads an object from a Reader. The object is deserialized from gob.
-// `obj` must be a pointer to an instantiated object. The stream may optionally
+// readFromReader reads an object from a Reader. The object is deserialized from gob,
+// or from the binary format for documents. `obj` must be a pointer to an instantiated object. The stream may optionally
 // be compressed as gzip and/or encrypted with AES-GCM. The encryption key must
 // be 32 bytes long.
 // If the reader has to be closed, it's the caller's responsibility.
-func readFromReader(r io.ReadSeeker, obj any, encryptionKey string) error {
+func readFromReader(r io.Reader, obj any, encryptionKey string) error {
 	// AES 256 requires a 32 byte key
 	if encryptionKey != "" {
 		if len(encryptionKey) != 32 {
@@ -191,8 +298,6 @@ func readFromReader(r io.ReadSeeker, obj any, encryptionKey string) error {
 	// To reduce memory usage we chain the readers instead of buffering, so we start
 	// from the end. For the decryption there's no reader though.
 
-	// For the chainedReader we don't declare it as ReadSeeker, so we can reassign
-	// the gzip reader to it.
 	var chainedReader io.Reader
 
 	// Decrypt if an encryption key is provided
@@ -224,26 +329,16 @@ func readFromReader(r io.ReadSeeker, obj any, encryptionKey string) error {
 		chainedReader = r
 	}
 
-	// Determine if the stream is compressed
-	magicNumber := make([]byte, 2)
-	_, err := chainedReader.Read(magicNumber)
+	// Determine if the stream is compressed. Peeking instead of reading the
+	// magic number means the reader doesn't have to support seeking, so it can
+	// be a network stream for example.
+	br := bufio.NewReader(chainedReader)
+	chainedReader = br
+	magicNumber, err := br.Peek(2)
 	if err != nil {
 		return fmt.Errorf("couldn't read magic number to determine whether the stream is compressed: %w", err)
 	}
-	var compressed bool
-	if magicNumber[0] == 0x1f && magicNumber[1] == 0x8b {
-		compressed = true
-	}
-
-	// Reset reader. Both the reader from the param and bytes.Reader support seeking.
-	if s, ok := chainedReader.(io.Seeker); !ok {
-		return fmt.Errorf("reader doesn't support seeking")
-	} else {
-		_, err := s.Seek(0, 0)
-		if err != nil {
-			return fmt.Errorf("couldn't reset reader: %w", err)
-		}
-	}
+	compressed := magicNumber[0] == 0x1f && magicNumber[1] == 0x8b
 
 	if compressed {
 		gzr, err := gzip.NewReader(chainedReader)
@@ -254,11 +349,24 @@ func readFromReader(r io.ReadSeeker, obj any, encryptionKey string) error {
 		chainedReader = gzr
 	}
 
+	// Documents can be in the binary format, or in gob if they were persisted
+	// by earlier versions.
+	if doc, ok := obj.(*Document); ok {
+		br := bufio.NewReader(chainedReader)
+		if isBinaryDocument(br) {
+			return decodeDocument(br, doc)
+		}
+		chainedReader = br
+	}
+
 	dec := gob.NewDecoder(chainedReader)
 	err = dec.Decode(obj)
 	if err != nil {
 		return fmt.Errorf("couldn't decode object: %w", err)
 	}
+	if p, ok := obj.(*persistenceDB); ok {
+		return p.decodeDocumentRecords()
+	}
 
 	return nil
 }
@@ -0,0 +1,217 @@
+package chromem
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// documentMagic is the start of documents that are persisted in the binary
+// format, followed by the version of the format. Gob streams can't start with
+// a null byte, as it would be a message of length 0, so files can be told
+// apart from the gob files of earlier versions.
+const (
+	documentMagic         = "\x00CHROMEMDOC"
+	documentFormatVersion = 1
+)
+
+// documentExt is the file extension of documents in the binary format.
+// Document files of earlier versions have the ".gob" extension, and are renamed
+// when the DB is loaded.
+const documentExt = ".doc"
+
+// encodeDocument encodes the document in the binary format, which is much
+// faster to decode than gob, especially for the embeddings, which are stored
+// as raw little-endian float32. All lengths are uvarints:
+//
+//	magic, version byte
+//	ID length, ID
+//	number of metadata entries, (key length, key, value length, value)...
+//	embedding length, embedding as 4 bytes per value
+//	content length, content
+//	media flag byte, if 1: URL length, URL, data length, data, MIME type length, MIME type
+func encodeDocument(w io.Writer, doc *Document) error {
+	size := len(documentMagic) + 1 + 5*binary.MaxVarintLen64 + len(doc.ID) + 4*len(doc.Embedding) + len(doc.Content)
+	for k, v := range doc.Metadata {
+		size += 2*binary.MaxVarintLen64 + len(k) + len(v)
+	}
+	if doc.Media != nil {
+		size += 3*binary.MaxVarintLen64 + len(doc.Media.URL) + len(doc.Media.Data) + len(doc.Media.MIMEType)
+	}
+
+	b := make([]byte, 0, size)
+	b = append(b, documentMagic...)
+	b = append(b, documentFormatVersion)
+	b = appendBinaryString(b, doc.ID)
+	b = binary.AppendUvarint(b, uint64(len(doc.Metadata)))
+	for k, v := range doc.Metadata {
+		b = appendBinaryString(b, k)
+		b = appendBinaryString(b, v)
+	}
+	b = binary.AppendUvarint(b, uint64(len(doc.Embedding)))
+	for _, v := range doc.Embedding {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+	}
+	b = appendBinaryString(b, doc.Content)
+	if doc.Media == nil {
+		b = append(b, 0)
+	} else {
+		b = append(b, 1)
+		b = appendBinaryString(b, doc.Media.URL)
+		b = binary.AppendUvarint(b, uint64(len(doc.Media.Data)))
+		b = append(b, doc.Media.Data...)
+		b = appendBinaryString(b, doc.Media.MIMEType)
+	}
+
+	_, err := w.Write(b)
+	if err != nil {
+		return fmt.Errorf("couldn't write document: %w", err)
+	}
+	return nil
+}
+
+func appendBinaryString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// isBinaryDocument reports whether the reader's next bytes are the magic of
+// the binary document format, without consuming them.
+func isBinaryDocument(r *bufio.Reader) bool {
+	magic, err := r.Peek(len(documentMagic))
+	return err == nil && string(magic) == documentMagic
+}
+
+// decodeDocument decodes a document in the binary format, see [encodeDocument].
+func decodeDocument(r io.Reader, doc *Document) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("couldn't read document: %w", err)
+	}
+	d := binaryDecoder{b: b}
+	if string(d.bytes(len(documentMagic))) != documentMagic {
+		return errors.New("invalid document magic")
+	}
+	if version := d.bytes(1); d.err == nil && version[0] != documentFormatVersion {
+		return fmt.Errorf("unsupported document format version %d", version[0])
+	}
+
+	res := Document{ID: d.string()}
+	if n := d.length(2); n > 0 {
+		res.Metadata = make(map[string]string, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			k := d.string()
+			res.Metadata[k] = d.string()
+		}
+	}
+	if n := d.length(4); n > 0 {
+		raw := d.bytes(4 * n)
+		if d.err == nil {
+			res.Embedding = make([]float32, n)
+			for i := range res.Embedding {
+				res.Embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+			}
+		}
+	}
+	res.Content = d.string()
+	if flag := d.bytes(1); d.err == nil && flag[0] == 1 {
+		res.Media = &Media{URL: d.string()}
+		if data := d.bytes(d.length(1)); len(data) > 0 {
+			res.Media.Data = append([]byte(nil), data...)
+		}
+		res.Media.MIMEType = d.string()
+	}
+	if d.err != nil {
+		return fmt.Errorf("couldn't decode document: %w", d.err)
+	}
+	if len(d.b) != 0 {
+		return errors.New("couldn't decode document: trailing data")
+	}
+
+	*doc = res
+	return nil
+}
+
+// encodeDocumentRecords encodes the documents in the binary format for
+// exports, each prefixed with its length as uvarint, see [encodeDocument].
+func encodeDocumentRecords(docs map[string]*Document) ([]byte, error) {
+	var res []byte
+	buf := &bytes.Buffer{}
+	for _, doc := range docs {
+		buf.Reset()
+		if err := encodeDocument(buf, doc); err != nil {
+			return nil, err
+		}
+		res = binary.AppendUvarint(res, uint64(buf.Len()))
+		res = append(res, buf.Bytes()...)
+	}
+	return res, nil
+}
+
+// decodeDocumentRecords decodes the documents that were encoded with
+// [encodeDocumentRecords].
+func decodeDocumentRecords(b []byte) (map[string]*Document, error) {
+	d := binaryDecoder{b: b}
+	docs := make(map[string]*Document)
+	for len(d.b) != 0 {
+		record := d.bytes(d.length(1))
+		if d.err != nil {
+			return nil, fmt.Errorf("couldn't decode document record: %w", d.err)
+		}
+		doc := &Document{}
+		if err := decodeDocument(bytes.NewReader(record), doc); err != nil {
+			return nil, err
+		}
+		docs[doc.ID] = doc
+	}
+	return docs, nil
+}
+
+// binaryDecoder decodes the values of the binary document format. After the
+// first error, all methods return zero values, so the error only has to be
+// checked at the end.
+type binaryDecoder struct {
+	b   []byte
+	err error
+}
+
+// length decodes a length, and checks that the remaining bytes can hold that
+// many elements of the given minimum size, so corrupt lengths don't lead to
+// huge allocations.
+func (d *binaryDecoder) length(minSize int) int {
+	if d.err != nil {
+		return 0
+	}
+	n, read := binary.Uvarint(d.b)
+	if read <= 0 {
+		d.err = errors.New("invalid length")
+		return 0
+	}
+	d.b = d.b[read:]
+	if n > uint64(len(d.b)/minSize) {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	return int(n)
+}
+
+func (d *binaryDecoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.b) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	res := d.b[:n]
+	d.b = d.b[n:]
+	return res
+}
+
+func (d *binaryDecoder) string() string {
+	return string(d.bytes(d.length(1)))
+}
@@ -0,0 +1,183 @@
+package chromem
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPersistence_Document(t *testing.T) {
+	docs := []Document{
+		{
+			ID:        "1",
+			Metadata:  map[string]string{"foo": "bar", "": "empty key"},
+			Embedding: []float32{-0.40824828, 0.40824828, 0.81649655},
+			Content:   "hello world",
+		},
+		{
+			ID:        "2",
+			Embedding: []float32{1},
+			Media:     &Media{URL: "https://example.com/cat.jpg"},
+		},
+		{
+			ID:    "3",
+			Media: &Media{Data: []byte{0x89, 'P', 'N', 'G'}, MIMEType: "image/png"},
+		},
+		{
+			ID: "4",
+		},
+	}
+
+	for _, doc := range docs {
+		for _, compress := range []bool{false, true} {
+			filePath := filepath.Join(t.TempDir(), "doc"+documentExt)
+			err := persistToFile(filePath, doc, compress, "")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			res := Document{}
+			err = readFromFile(filePath, &res, "")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if !reflect.DeepEqual(doc, res) {
+				t.Fatalf("expected %+v, got %+v", doc, res)
+			}
+		}
+	}
+
+	t.Run("gob", func(t *testing.T) {
+		// Documents persisted by earlier versions are gob encoded
+		doc := docs[0]
+		filePath := filepath.Join(t.TempDir(), "doc.gob")
+		buf := bytes.Buffer{}
+		err := gob.NewEncoder(&buf).Encode(doc)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = os.WriteFile(filePath, buf.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		res := Document{}
+		err = readFromFile(filePath, &res, "")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if !reflect.DeepEqual(doc, res) {
+			t.Fatalf("expected %+v, got %+v", doc, res)
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		err := encodeDocument(&buf, &docs[0])
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		b := buf.Bytes()
+		for _, corrupt := range [][]byte{b[:len(b)-1], append(b, 0), b[:len(documentMagic)+1]} {
+			err = decodeDocument(bytes.NewReader(corrupt), &Document{})
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		}
+		// Huge lengths don't lead to huge allocations
+		corrupt := append([]byte(documentMagic), documentFormatVersion, 0xff, 0xff, 0xff, 0xff, 0x0f)
+		err = decodeDocument(bytes.NewReader(corrupt), &Document{})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestPersistence_Export(t *testing.T) {
+	exported := persistenceDB{
+		Collections: map[string]*persistenceCollection{
+			"test": {
+				Name: "test",
+				Documents: map[string]*Document{
+					"1": {ID: "1", Metadata: map[string]string{"foo": "bar"}, Embedding: []float32{0.6, 0.8}, Content: "hello world"},
+					"2": {ID: "2", Embedding: []float32{1, 0}},
+				},
+			},
+		},
+		Aliases: map[string]string{"alias": "test"},
+	}
+
+	// The documents are encoded in the binary format, and the exported DB
+	// isn't changed.
+	buf := bytes.Buffer{}
+	err := persistToWriter(&buf, exported, false, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(documentMagic)) {
+		t.Fatal("expected documents in the binary format")
+	}
+	if exported.Collections["test"].DocumentRecords != nil {
+		t.Fatal("expected exported DB to be unchanged")
+	}
+	res := persistenceDB{}
+	err = readFromReader(&buf, &res, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !reflect.DeepEqual(exported, res) {
+		t.Fatalf("expected %+v, got %+v", exported, res)
+	}
+
+	t.Run("gob", func(t *testing.T) {
+		// Exports of earlier versions encode the documents as gob
+		buf := bytes.Buffer{}
+		err := gob.NewEncoder(&buf).Encode(exported)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		res := persistenceDB{}
+		err = readFromReader(&buf, &res, "")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if !reflect.DeepEqual(exported, res) {
+			t.Fatalf("expected %+v, got %+v", exported, res)
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		_, err := decodeDocumentRecords([]byte{0x05, 0x00})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func BenchmarkPersistence_ReadDocument(b *testing.B) {
+	doc := Document{
+		ID:        "1",
+		Metadata:  map[string]string{"foo": "bar"},
+		Embedding: make([]float32, 1536),
+		Content:   strings.Repeat("hello world ", 100),
+	}
+	for i := range doc.Embedding {
+		doc.Embedding[i] = float32(i) / 1536
+	}
+	filePath := filepath.Join(b.TempDir(), "doc"+documentExt)
+	err := persistToFile(filePath, doc, false, "")
+	if err != nil {
+		b.Fatal("expected no error, got", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		res := Document{}
+		err := readFromFile(filePath, &res, "")
+		if err != nil {
+			b.Fatal("expected no error, got", err)
+		}
+	}
+}
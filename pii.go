@@ -0,0 +1,154 @@
+package chromem
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Types of personally identifiable information (PII) that the built-in
+// detector finds, see [NewPIIDetectorRegex].
+const (
+	PIITypeEmail      = "email"
+	PIITypePhone      = "phone"
+	PIITypeCreditCard = "credit_card"
+)
+
+var (
+	piiEmailRegex      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	piiCreditCardRegex = regexp.MustCompile(`\d(?:[ -]?\d){12,18}`)
+	piiPhoneRegex      = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}(?:[ .-]?\d{2,4}){1,4}`)
+)
+
+// PIIMatch is personally identifiable information (PII) in a text.
+type PIIMatch struct {
+	// The type of the PII, for example [PIITypeEmail].
+	Type string
+	// The byte offsets of the PII in the text.
+	Start, End int
+}
+
+// PIIDetector detects personally identifiable information (PII) in texts, for
+// redacting it with [NewPreprocessorPII]. Implement it to detect other types of
+// PII, for example with a named entity recognition model or service.
+type PIIDetector interface {
+	// DetectPII returns the PII in the text. The matches may overlap.
+	DetectPII(text string) []PIIMatch
+}
+
+// NewPIIDetectorRegex returns a [PIIDetector] that detects email addresses,
+// phone numbers and credit card numbers with regular expressions. Credit card
+// numbers must have a valid Luhn checksum. Phone numbers must have 7 to 15
+// digits if they start with a country code or an area code in parentheses, and
+// 9 to 15 digits otherwise, so that dates and other numbers aren't detected,
+// but short local numbers aren't either.
+func NewPIIDetectorRegex() PIIDetector {
+	return regexPIIDetector{}
+}
+
+type regexPIIDetector struct{}
+
+func (regexPIIDetector) DetectPII(text string) []PIIMatch {
+	var res []PIIMatch
+	for _, loc := range piiEmailRegex.FindAllStringIndex(text, -1) {
+		res = append(res, PIIMatch{Type: PIITypeEmail, Start: loc[0], End: loc[1]})
+	}
+	for _, loc := range piiCreditCardRegex.FindAllStringIndex(text, -1) {
+		if isStandalone(text, loc[0], loc[1]) && luhnValid(text[loc[0]:loc[1]]) {
+			res = append(res, PIIMatch{Type: PIITypeCreditCard, Start: loc[0], End: loc[1]})
+		}
+	}
+	for _, loc := range piiPhoneRegex.FindAllStringIndex(text, -1) {
+		phone := text[loc[0]:loc[1]]
+		minDigits := 9
+		if phone[0] == '+' || phone[0] == '(' {
+			minDigits = 7
+		}
+		if digits := countDigits(phone); isStandalone(text, loc[0], loc[1]) && digits >= minDigits && digits <= 15 {
+			res = append(res, PIIMatch{Type: PIITypePhone, Start: loc[0], End: loc[1]})
+		}
+	}
+	return res
+}
+
+// NewPreprocessorPII returns a [Preprocessor] that replaces the personally
+// identifiable information (PII) that the detector finds with its upper-case
+// type in brackets, for example "[EMAIL]", before documents are embedded and
+// stored, see [Collection.SetPreprocessing]. Of overlapping matches, the first
+// and then the longest one is replaced. If the detector is nil,
+// [NewPIIDetectorRegex] is used.
+func NewPreprocessorPII(detector PIIDetector) Preprocessor {
+	if detector == nil {
+		detector = NewPIIDetectorRegex()
+	}
+	return func(content string) (string, error) {
+		matches := detector.DetectPII(content)
+		if len(matches) == 0 {
+			return content, nil
+		}
+		slices.SortFunc(matches, func(a, b PIIMatch) int {
+			if a.Start != b.Start {
+				return cmp.Compare(a.Start, b.Start)
+			}
+			return cmp.Compare(b.End, a.End)
+		})
+
+		sb := strings.Builder{}
+		end := 0
+		for _, m := range matches {
+			if m.Start < end || m.Start >= m.End || m.End > len(content) {
+				continue
+			}
+			sb.WriteString(content[end:m.Start])
+			sb.WriteString("[" + strings.ToUpper(m.Type) + "]")
+			end = m.End
+		}
+		sb.WriteString(content[end:])
+		return sb.String(), nil
+	}
+}
+
+// isStandalone returns true if the match isn't part of a longer word or number.
+func isStandalone(text string, start, end int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return false
+	}
+	return true
+}
+
+func countDigits(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// luhnValid returns true if the digits of the number have a valid Luhn
+// checksum, like credit card numbers.
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		if number[i] < '0' || number[i] > '9' {
+			continue
+		}
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
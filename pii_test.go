@@ -0,0 +1,72 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewPreprocessorPII(t *testing.T) {
+	tt := []struct {
+		content  string
+		expected string
+	}{
+		{"Contact jane.doe+news@mail.example.com for details.", "Contact [EMAIL] for details."},
+		{"Call +49 30 1234567 or (555) 123-4567.", "Call [PHONE] or [PHONE]."},
+		{"Call 555-123-4567 today.", "Call [PHONE] today."},
+		{"Paid with 4111 1111 1111 1111 yesterday.", "Paid with [CREDIT_CARD] yesterday."},
+		{"Paid with 4111-1111-1111-1111.", "Paid with [CREDIT_CARD]."},
+		// Not PII: invalid checksum, dates, short numbers, versions, IDs
+		{"Order 4111 1111 1111 1112 shipped on 2024-01-15.", "Order 4111 1111 1111 1112 shipped on 2024-01-15."},
+		{"Version 1.21 has 300 new tests.", "Version 1.21 has 300 new tests."},
+		{"ID abc1234567890", "ID abc1234567890"},
+		{"", ""},
+	}
+	preprocess := NewPreprocessorPII(nil)
+	for _, tc := range tt {
+		res, err := preprocess(tc.content)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if res != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, res)
+		}
+	}
+}
+
+type testPIIDetector struct{}
+
+func (testPIIDetector) DetectPII(text string) []PIIMatch {
+	// Overlapping and invalid matches
+	return []PIIMatch{
+		{Type: "name", Start: 6, End: 11},
+		{Type: "person", Start: 0, End: 11},
+		{Type: "invalid", Start: 20, End: 100},
+	}
+}
+
+func TestCollection_SetPreprocessing_pii(t *testing.T) {
+	ctx := context.Background()
+	var embedded string
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		embedded = text
+		return []float32{0, 1}, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c.SetPreprocessing(PreprocessingOptions{Preprocessors: []Preprocessor{NewPreprocessorPII(testPIIDetector{})}})
+
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "Alice Smith wrote this."})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected := "[PERSON] wrote this."
+	if embedded != expected {
+		t.Fatalf("expected %q to be embedded, got %q", expected, embedded)
+	}
+	if content := c.documents["1"].Content; content != expected {
+		t.Fatalf("expected %q to be stored, got %q", expected, content)
+	}
+}
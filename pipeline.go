@@ -0,0 +1,220 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// PipelineQuery is the state of a query in a [QueryPipeline], which the stages
+// read and modify.
+type PipelineQuery struct {
+	// The options of the query. Stages can change them, for example rewrite
+	// the QueryText or add a filter.
+	Options QueryOptions
+
+	// Additional query texts, for example from query expansion or from the
+	// decomposition of a complex query into simpler ones. Each one is retrieved
+	// with the same options as the query, and the rankings are fused with
+	// reciprocal rank fusion.
+	Expansions []string
+	// The embeddings of the expansions, if they're embedded already, in the
+	// same order. Set by [EmbedStage].
+	ExpansionEmbeddings [][]float32
+
+	// The self-query that the query text was parsed into, set by
+	// [SelfQueryStage]. Nil otherwise.
+	SelfQuery *SelfQuery
+
+	// The results, set by [RetrieveStage] and changed by later stages, like
+	// [RerankStage].
+	Results []Result
+}
+
+// QueryStage is a stage of a [QueryPipeline]. It reads and modifies the query.
+// An error aborts the pipeline.
+type QueryStage func(ctx context.Context, c *Collection, q *PipelineQuery) error
+
+// QueryPipeline runs a query through stages, like rewriting the query,
+// expanding it into multiple queries, embedding, retrieving, reranking and
+// post-processing the results. Stages are applied in order, so they can be
+// composed and reordered, and custom stages can be added for advanced retrieval
+// strategies.
+//
+// For example:
+//
+//	pipeline := chromem.NewQueryPipeline(
+//		chromem.RewriteStage(fixTypos),
+//		chromem.ExpandStage(decomposeWithLLM),
+//		chromem.RetrieveStage(),
+//		chromem.RerankStage(crossEncoder),
+//	)
+//	results, err := pipeline.Query(ctx, c, chromem.QueryOptions{QueryText: "...", NResults: 10})
+type QueryPipeline struct {
+	stages []QueryStage
+}
+
+// NewQueryPipeline returns a [QueryPipeline] with the stages. Without a
+// [RetrieveStage] or another stage that sets the results, the pipeline returns
+// no results.
+func NewQueryPipeline(stages ...QueryStage) *QueryPipeline {
+	return &QueryPipeline{stages: append([]QueryStage(nil), stages...)}
+}
+
+// Query runs the query through the stages of the pipeline and returns its
+// results.
+func (p *QueryPipeline) Query(ctx context.Context, c *Collection, options QueryOptions) ([]Result, error) {
+	q := &PipelineQuery{Options: options}
+	for i, stage := range p.stages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := stage(ctx, c, q); err != nil {
+			return nil, fmt.Errorf("query pipeline stage %d failed: %w", i, err)
+		}
+	}
+	return q.Results, nil
+}
+
+// RewriteStage returns a [QueryStage] that replaces the query text with the
+// result of the rewrite function, for example an LLM call that fixes typos,
+// resolves references to earlier messages of a conversation or adds synonyms.
+// It does nothing if the query has no text.
+func RewriteStage(rewrite func(ctx context.Context, query string) (string, error)) QueryStage {
+	return func(ctx context.Context, _ *Collection, q *PipelineQuery) error {
+		if q.Options.QueryText == "" {
+			return nil
+		}
+		text, err := rewrite(ctx, q.Options.QueryText)
+		if err != nil {
+			return fmt.Errorf("couldn't rewrite query: %w", err)
+		}
+		if text == "" {
+			return errors.New("rewritten query is empty")
+		}
+		q.Options.QueryText = text
+		// The embedding of the previous text doesn't match anymore.
+		q.Options.QueryEmbedding = nil
+		return nil
+	}
+}
+
+// ExpandStage returns a [QueryStage] that adds the query texts that the expand
+// function returns to the query's expansions, for example paraphrases or the
+// sub-questions of a complex question. It does nothing if the query has no
+// text.
+func ExpandStage(expand func(ctx context.Context, query string) ([]string, error)) QueryStage {
+	return func(ctx context.Context, _ *Collection, q *PipelineQuery) error {
+		if q.Options.QueryText == "" {
+			return nil
+		}
+		expansions, err := expand(ctx, q.Options.QueryText)
+		if err != nil {
+			return fmt.Errorf("couldn't expand query: %w", err)
+		}
+		for _, expansion := range expansions {
+			if expansion != "" {
+				q.Expansions = append(q.Expansions, expansion)
+			}
+		}
+		return nil
+	}
+}
+
+// EmbedStage returns a [QueryStage] that creates the embeddings of the query
+// text and the expansions that aren't embedded yet, with the collection's
+// embedding function. It's optional, as [RetrieveStage] embeds the queries as
+// well, but stages after it can use or change the embeddings.
+func EmbedStage() QueryStage {
+	return func(ctx context.Context, c *Collection, q *PipelineQuery) error {
+		if len(q.Options.QueryEmbedding) == 0 && q.Options.QueryText != "" {
+			embedding, err := c.embedQuery(ctx, q.Options.QueryText)
+			if err != nil {
+				return fmt.Errorf("couldn't create embedding of query: %w", err)
+			}
+			q.Options.QueryEmbedding = embedding
+		}
+		for i, expansion := range q.Expansions {
+			if i < len(q.ExpansionEmbeddings) && len(q.ExpansionEmbeddings[i]) != 0 {
+				continue
+			}
+			embedding, err := c.embedQuery(ctx, expansion)
+			if err != nil {
+				return fmt.Errorf("couldn't create embedding of query expansion: %w", err)
+			}
+			if i < len(q.ExpansionEmbeddings) {
+				q.ExpansionEmbeddings[i] = embedding
+			} else {
+				q.ExpansionEmbeddings = append(q.ExpansionEmbeddings, embedding)
+			}
+		}
+		return nil
+	}
+}
+
+// RetrieveStage returns a [QueryStage] that queries the collection with the
+// query's options (see [Collection.QueryWithOptions]) and each of its
+// expansions, and sets the results, fused with reciprocal rank fusion if there
+// are expansions.
+func RetrieveStage() QueryStage {
+	return func(ctx context.Context, c *Collection, q *PipelineQuery) error {
+		results, err := c.QueryWithOptions(ctx, q.Options)
+		if err != nil {
+			return err
+		}
+		if len(q.Expansions) == 0 {
+			q.Results = results
+			return nil
+		}
+
+		rankings := [][]Result{results}
+		weights := []float32{1}
+		for i, expansion := range q.Expansions {
+			options := q.Options
+			options.QueryText = expansion
+			options.QueryEmbedding = nil
+			if i < len(q.ExpansionEmbeddings) {
+				options.QueryEmbedding = q.ExpansionEmbeddings[i]
+			}
+			results, err := c.QueryWithOptions(ctx, options)
+			if err != nil {
+				return fmt.Errorf("couldn't query expansion %q: %w", expansion, err)
+			}
+			rankings = append(rankings, results)
+			weights = append(weights, 1)
+		}
+		q.Results = fuseRankings(q.Options.NResults, weights, rankings...)
+		return nil
+	}
+}
+
+// RerankStage returns a [QueryStage] that reorders the results with the rerank
+// function, for example a cross-encoder model. The function gets the query
+// text and can also drop results.
+func RerankStage(rerank func(ctx context.Context, query string, results []Result) ([]Result, error)) QueryStage {
+	return func(ctx context.Context, _ *Collection, q *PipelineQuery) error {
+		results, err := rerank(ctx, q.Options.QueryText, q.Results)
+		if err != nil {
+			return fmt.Errorf("couldn't rerank results: %w", err)
+		}
+		for i := range results {
+			results[i].Rank = i + 1
+		}
+		q.Results = results
+		return nil
+	}
+}
+
+// PostProcessStage returns a [QueryStage] that replaces the results with the
+// ones the function returns, for example to drop results below a similarity
+// threshold, to merge adjacent chunks or to add data from another source.
+func PostProcessStage(process func(ctx context.Context, results []Result) ([]Result, error)) QueryStage {
+	return func(ctx context.Context, _ *Collection, q *PipelineQuery) error {
+		results, err := process(ctx, q.Results)
+		if err != nil {
+			return fmt.Errorf("couldn't post-process results: %w", err)
+		}
+		q.Results = results
+		return nil
+	}
+}
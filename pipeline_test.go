@@ -0,0 +1,96 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestQueryPipeline(t *testing.T) {
+	ctx := context.Background()
+	vectors := map[string][]float32{
+		"cats and dogs": {1, 1, 0},
+		"cats":          {1, 0, 0},
+		"dogs":          {0, 1, 0},
+		"birds":         {0, 0, 1},
+	}
+	var embedded []string
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		embedded = append(embedded, text)
+		return vectors[text], nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, id := range []string{"cats", "dogs", "birds"} {
+		err = c.AddDocument(ctx, Document{ID: id, Content: id})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	rewrite := func(_ context.Context, query string) (string, error) {
+		if query == "cats & dogs" {
+			return "cats and dogs", nil
+		}
+		return query, nil
+	}
+	decompose := func(_ context.Context, query string) ([]string, error) {
+		return []string{"cats", "dogs", ""}, nil
+	}
+	var reranked string
+	reverse := func(_ context.Context, query string, results []Result) ([]Result, error) {
+		reranked = query
+		slices.Reverse(results)
+		return results, nil
+	}
+	dropBirds := func(_ context.Context, results []Result) ([]Result, error) {
+		return slices.DeleteFunc(results, func(res Result) bool { return res.ID == "birds" }), nil
+	}
+	p := NewQueryPipeline(
+		RewriteStage(rewrite),
+		ExpandStage(decompose),
+		EmbedStage(),
+		RetrieveStage(),
+		PostProcessStage(dropBirds),
+		RerankStage(reverse),
+	)
+
+	embedded = nil
+	res, err := p.Query(ctx, c, QueryOptions{QueryText: "cats & dogs", NResults: 3})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !slices.Equal(embedded, []string{"cats and dogs", "cats", "dogs"}) {
+		t.Fatal("expected the rewritten query and the expansions to be embedded once, got", embedded)
+	}
+	if reranked != "cats and dogs" {
+		t.Fatal("expected the rewritten query to be reranked with, got", reranked)
+	}
+	// Cats and dogs are equally similar to the rewritten query, so cats are
+	// first by ID. Cats are then first in two of the rankings, so they're
+	// before dogs in the fused ranking, which is then reversed.
+	if len(res) != 2 || res[0].ID != "dogs" || res[0].Rank != 1 || res[1].ID != "cats" || res[1].Rank != 2 {
+		t.Fatal("expected dogs and cats, got", res)
+	}
+
+	// Without expansions, the results are the collection's.
+	res, err = NewQueryPipeline(RetrieveStage()).Query(ctx, c, QueryOptions{QueryText: "birds", NResults: 1})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "birds" {
+		t.Fatal("expected birds, got", res)
+	}
+
+	failing := func(context.Context, string) (string, error) {
+		return "", errors.New("LLM unavailable")
+	}
+	_, err = NewQueryPipeline(RewriteStage(failing), RetrieveStage()).Query(ctx, c, QueryOptions{QueryText: "birds", NResults: 1})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
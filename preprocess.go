@@ -0,0 +1,196 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+)
+
+// defaultBoilerplatePatterns are the patterns of [NewPreprocessorBoilerplate]
+// if none are given. They match whole lines, case-insensitively.
+var defaultBoilerplatePatterns = []string{
+	`^(copyright|©|\(c\)).*`,
+	`.*all rights reserved\.?$`,
+	`.*\b(we|this (web)?site) uses? cookies\b.*`,
+	`^(accept|reject|manage) (all )?cookies$`,
+	`^skip to (main )?content$`,
+	`^(back to top|read more|share this( article)?|print this page)$`,
+	`^(subscribe to|sign up for) (our|the) newsletter.*`,
+}
+
+// Preprocessor transforms the content of a document before it's embedded and
+// stored, see [Collection.SetPreprocessing].
+type Preprocessor func(content string) (string, error)
+
+// PreprocessingOptions configure the preprocessing of documents when they're
+// added to a collection.
+type PreprocessingOptions struct {
+	// The preprocessors, which are applied to the content of each document in
+	// order.
+	Preprocessors []Preprocessor
+
+	// KeepOriginal stores the original content of documents whose content is
+	// changed by the preprocessors in the DB's blob store (see [DB.PutBlob]),
+	// and references it in the document's metadata with
+	// [MetadataKeySourceBlob], unless the document references a blob already.
+	KeepOriginal bool
+}
+
+// SetPreprocessing sets the preprocessing of the content of documents that are
+// added to the collection, for example to strip HTML tags and boilerplate and
+// to normalize whitespace, so that they don't dilute the embeddings and don't
+// take up memory. The preprocessing is applied before the documents are
+// checked, embedded and stored, so for example the limits (see
+// [Collection.SetLimits]) apply to the preprocessed content. Documents that
+// already have an embedding are preprocessed as well, and documents without
+// content aren't.
+//
+// Like the embedding function, the preprocessors aren't persisted, so you have
+// to set them again after loading a persistent DB.
+func (c *Collection) SetPreprocessing(options PreprocessingOptions) {
+	options.Preprocessors = append([]Preprocessor(nil), options.Preprocessors...)
+
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+
+	c.preprocessing = options
+}
+
+// preprocessDocument applies the collection's preprocessing to the document.
+func (c *Collection) preprocessDocument(doc Document) (Document, error) {
+	c.configLock.RLock()
+	options := c.preprocessing
+	c.configLock.RUnlock()
+
+	if len(options.Preprocessors) == 0 || doc.Content == "" {
+		return doc, nil
+	}
+	content := doc.Content
+	for _, preprocess := range options.Preprocessors {
+		var err error
+		content, err = preprocess(content)
+		if err != nil {
+			return Document{}, fmt.Errorf("couldn't preprocess document %q: %w", doc.ID, err)
+		}
+	}
+	if content == doc.Content {
+		return doc, nil
+	}
+
+	if options.KeepOriginal && doc.Metadata[MetadataKeySourceBlob] == "" {
+		if c.db == nil {
+			return Document{}, errors.New("keeping the original content requires a collection of a DB")
+		}
+		hash, err := c.db.PutBlob(strings.NewReader(doc.Content))
+		if err != nil {
+			return Document{}, fmt.Errorf("couldn't store original content of document %q: %w", doc.ID, err)
+		}
+		metadata := maps.Clone(doc.Metadata)
+		if metadata == nil {
+			metadata = make(map[string]string, 1)
+		}
+		metadata[MetadataKeySourceBlob] = hash
+		doc.Metadata = metadata
+	}
+	doc.Content = content
+	return doc, nil
+}
+
+// NewPreprocessorWhitespace returns a [Preprocessor] that normalizes
+// whitespace: Runs of spaces and tabs are collapsed into a single space, lines
+// are trimmed, runs of empty lines are collapsed into a single empty line, and
+// leading and trailing empty lines are removed.
+func NewPreprocessorWhitespace() Preprocessor {
+	return func(content string) (string, error) {
+		var lines []string
+		empty := false
+		for _, line := range strings.Split(content, "\n") {
+			line = strings.Join(strings.Fields(line), " ")
+			if line == "" {
+				empty = len(lines) != 0
+				continue
+			}
+			if empty {
+				lines = append(lines, "")
+				empty = false
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+}
+
+// NewPreprocessorHTML returns a [Preprocessor] that extracts the readable text
+// of HTML content, like [Collection.AddFromURLs]: Tags, scripts, styles and
+// navigation elements like <nav> and <footer> are removed, entities are
+// unescaped, and if there's a <main> or <article> element, only its text is
+// kept.
+func NewPreprocessorHTML() Preprocessor {
+	return func(content string) (string, error) {
+		_, text := extractHTMLText(content)
+		return text, nil
+	}
+}
+
+// NewPreprocessorBoilerplate returns a [Preprocessor] that removes lines that
+// are boilerplate, like copyright notices, cookie banners and "read more"
+// links. The lines are matched case-insensitively, after trimming whitespace,
+// against the regular expressions (see [regexp/syntax]), which must match the
+// whole line. Without patterns, patterns for common English boilerplate are
+// used.
+func NewPreprocessorBoilerplate(patterns ...string) (Preprocessor, error) {
+	if len(patterns) == 0 {
+		patterns = defaultBoilerplatePatterns
+	}
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(`(?i)^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boilerplate pattern %q: %w", pattern, err)
+		}
+		res = append(res, re)
+	}
+	return func(content string) (string, error) {
+		lines := strings.Split(content, "\n")
+		var kept []string
+		for _, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			boilerplate := false
+			for _, re := range res {
+				if re.MatchString(trimmed) {
+					boilerplate = true
+					break
+				}
+			}
+			if !boilerplate {
+				kept = append(kept, line)
+			}
+		}
+		return strings.Join(kept, "\n"), nil
+	}, nil
+}
+
+// NewPreprocessorRedact returns a [Preprocessor] that replaces all matches of
+// the regular expressions (see [regexp/syntax]) with the replacement, for
+// example to redact personal data like customer IDs with "[REDACTED]".
+func NewPreprocessorRedact(replacement string, patterns ...string) (Preprocessor, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("no patterns to redact")
+	}
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		res = append(res, re)
+	}
+	return func(content string) (string, error) {
+		for _, re := range res {
+			content = re.ReplaceAllLiteralString(content, replacement)
+		}
+		return content, nil
+	}, nil
+}
@@ -0,0 +1,148 @@
+package chromem
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestPreprocessors(t *testing.T) {
+	boilerplate, err := NewPreprocessorBoilerplate()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	redact, err := NewPreprocessorRedact("[REDACTED]", `CUST-\d+`, `secret`)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	tt := []struct {
+		name         string
+		preprocessor Preprocessor
+		content      string
+		expected     string
+	}{
+		{
+			name:         "whitespace",
+			preprocessor: NewPreprocessorWhitespace(),
+			content:      "\n\n  Title \t here\n\n\n\nFirst  line\n   \nSecond line  \n\n",
+			expected:     "Title here\n\nFirst line\n\nSecond line",
+		},
+		{
+			name:         "HTML",
+			preprocessor: NewPreprocessorHTML(),
+			content:      "<html><head><title>T</title></head><body><nav>Menu</nav><p>Fish &amp; chips</p><script>x()</script><p>are <b>tasty</b></p></body></html>",
+			expected:     "Fish & chips\nare tasty",
+		},
+		{
+			name:         "boilerplate",
+			preprocessor: boilerplate,
+			content:      "Skip to content\nThe actual text.\nThis website uses cookies to improve your experience.\nRead more\n© 2024 Example Inc. All rights reserved.",
+			expected:     "The actual text.",
+		},
+		{
+			name:         "redact",
+			preprocessor: redact,
+			content:      "Customer CUST-123 told us a secret.",
+			expected:     "Customer [REDACTED] told us a [REDACTED].",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.preprocessor(tc.content)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if res != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, res)
+			}
+		})
+	}
+
+	if _, err := NewPreprocessorBoilerplate("("); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if _, err := NewPreprocessorRedact("x"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_SetPreprocessing(t *testing.T) {
+	ctx := context.Background()
+	var embedded []string
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		embedded = append(embedded, text)
+		return []float32{0, 1}, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollectionWithOptions("test", CollectionOptions{
+		EmbeddingFunc: embeddingFunc,
+		Preprocessing: PreprocessingOptions{
+			Preprocessors: []Preprocessor{NewPreprocessorHTML(), NewPreprocessorWhitespace()},
+			KeepOriginal:  true,
+		},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	original := "<p>Hello   <b>world</b></p>"
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "html", Content: original},
+		{ID: "plain", Content: "Hello"},
+		{ID: "blob", Content: original, Metadata: map[string]string{MetadataKeySourceBlob: "abc"}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if embedded[0] != "Hello world" {
+		t.Fatal("expected preprocessed content to be embedded, got", embedded[0])
+	}
+	doc := c.documents["html"]
+	if doc.Content != "Hello world" {
+		t.Fatal("expected preprocessed content to be stored, got", doc.Content)
+	}
+	r, err := db.OpenBlob(doc.Metadata[MetadataKeySourceBlob])
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if string(b) != original {
+		t.Fatal("expected the original content in the blob store, got", string(b))
+	}
+	// Unchanged contents and existing blob references aren't stored.
+	if _, ok := c.documents["plain"].Metadata[MetadataKeySourceBlob]; ok {
+		t.Fatal("expected no blob for unchanged content")
+	}
+	if blob := c.documents["blob"].Metadata[MetadataKeySourceBlob]; blob != "abc" {
+		t.Fatal("expected existing blob reference to be kept, got", blob)
+	}
+
+	// Preprocessing applies before the limits.
+	err = c.SetLimits(CollectionLimits{MaxContentBytes: 5})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Tx(ctx, func(tx *Txn) error {
+		return tx.AddDocument(Document{ID: "tx", Content: "<div>Hi</div>"})
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if content := c.documents["tx"].Content; content != "Hi" {
+		t.Fatal("expected preprocessed content, got", content)
+	}
+
+	failing := func(string) (string, error) {
+		return "", io.ErrUnexpectedEOF
+	}
+	c.SetPreprocessing(PreprocessingOptions{Preprocessors: []Preprocessor{failing}})
+	err = c.AddDocument(ctx, Document{ID: "fail", Content: "text"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,101 @@
+package chromem
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Progress is the progress of a long-running operation, see
+// [ContextWithProgress].
+type Progress struct {
+	// The operation, like "add", "import" or "compact".
+	Operation string
+	// The number of processed documents.
+	Processed int
+	// The total number of documents, or 0 if it's unknown, for example for
+	// imports from paginated APIs.
+	Total int
+	// The time since the start of the operation.
+	Elapsed time.Duration
+}
+
+// ETA returns the estimated remaining time of the operation, based on the
+// average rate so far. It's 0 if the total is unknown or no document has been
+// processed yet.
+func (p Progress) ETA() time.Duration {
+	if p.Total == 0 || p.Processed == 0 || p.Processed >= p.Total {
+		return 0
+	}
+	perDoc := p.Elapsed / time.Duration(p.Processed)
+	return perDoc * time.Duration(p.Total-p.Processed)
+}
+
+// ProgressFunc is called with the progress of long-running operations. Calls
+// for the same operation are serialized.
+type ProgressFunc func(p Progress)
+
+type progressFuncKey struct{}
+
+type progressReporterKey struct{}
+
+// ContextWithProgress returns a copy of the context that carries the progress
+// function. Long-running operations that are called with the context call it
+// after each processed document, so that CLIs and servers can show progress
+// bars. This includes [Collection.AddDocuments], [Collection.Compact] and the
+// imports like [DB.ImportFromChroma] and [Collection.ImportFromNumpy].
+// Operations that are part of another operation, like adding documents during
+// an import, are reported as part of the outer operation.
+// To cancel an operation, cancel the context.
+func ContextWithProgress(ctx context.Context, f ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressFuncKey{}, f)
+}
+
+// progressReporter reports the progress of an operation.
+type progressReporter struct {
+	f         ProgressFunc
+	operation string
+	total     int
+	processed int
+	start     time.Time
+	lock      sync.Mutex
+}
+
+// startProgress starts the progress reporting of an operation, if the context
+// carries a progress function. The returned context must be passed to nested
+// operations, so that they report to the same reporter. The returned reporter
+// is nil if there's no progress function.
+func startProgress(ctx context.Context, operation string, total int) (context.Context, *progressReporter) {
+	if r, ok := ctx.Value(progressReporterKey{}).(*progressReporter); ok {
+		return ctx, r
+	}
+	f, ok := ctx.Value(progressFuncKey{}).(ProgressFunc)
+	if !ok || f == nil {
+		return ctx, nil
+	}
+	r := &progressReporter{
+		f:         f,
+		operation: operation,
+		total:     total,
+		start:     time.Now(),
+	}
+	return context.WithValue(ctx, progressReporterKey{}, r), r
+}
+
+// add adds to the processed documents and reports the progress. It's a no-op on
+// a nil reporter.
+func (r *progressReporter) add(n int) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.processed += n
+	r.f(Progress{
+		Operation: r.operation,
+		Processed: r.processed,
+		Total:     r.total,
+		Elapsed:   time.Since(r.start),
+	})
+}
@@ -0,0 +1,75 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProgress_ETA(t *testing.T) {
+	tt := []struct {
+		name     string
+		progress Progress
+		expected time.Duration
+	}{
+		{"unknown total", Progress{Processed: 10, Elapsed: time.Second}, 0},
+		{"nothing processed", Progress{Total: 10, Elapsed: time.Second}, 0},
+		{"done", Progress{Processed: 10, Total: 10, Elapsed: time.Second}, 0},
+		{"in progress", Progress{Processed: 10, Total: 40, Elapsed: time.Second}, 3 * time.Second},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if eta := tc.progress.ETA(); eta != tc.expected {
+				t.Fatalf("expected ETA %v, got %v", tc.expected, eta)
+			}
+		})
+	}
+}
+
+func TestContextWithProgress(t *testing.T) {
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	var reports []Progress
+	ctx := ContextWithProgress(context.Background(), func(p Progress) {
+		reports = append(reports, p)
+	})
+	docs := []Document{
+		{ID: "1", Embedding: []float32{1, 0}},
+		{ID: "2", Embedding: []float32{0, 1}},
+		{ID: "3", Embedding: []float32{1, 1}},
+	}
+	err = c.AddDocuments(ctx, docs, 2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(reports) != 3 {
+		t.Fatal("expected 3 reports, got", len(reports))
+	}
+	for i, p := range reports {
+		if p.Operation != "add" || p.Processed != i+1 || p.Total != 3 {
+			t.Fatalf("expected add %d/3, got %+v", i+1, p)
+		}
+	}
+
+	// Nested operations report to the outer operation
+	reports = nil
+	importCtx, _ := startProgress(ctx, "import", 0)
+	err = c.AddDocuments(importCtx, docs[:2], 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(reports) != 2 || reports[1].Operation != "import" || reports[1].Processed != 2 || reports[1].Total != 0 {
+		t.Fatal("expected 2 import reports, got", reports)
+	}
+
+	// Without progress function there's no reporter
+	_, r := startProgress(context.Background(), "add", 1)
+	if r != nil {
+		t.Fatal("expected no reporter, got", r)
+	}
+	r.add(1)
+}
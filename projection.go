@@ -0,0 +1,300 @@
+package chromem
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+)
+
+// ProjectionFunc reduces the given embeddings to the given number of dimensions,
+// for example for visualization. It must return one vector with the given
+// number of dimensions per embedding, in the same order.
+//
+// [ProjectPCA] is the built-in implementation. For other methods like UMAP or
+// t-SNE you can implement the function yourself, for example by calling a
+// Python service.
+type ProjectionFunc func(ctx context.Context, embeddings [][]float32, dimensions int) ([][]float32, error)
+
+// ProjectPCA is a [ProjectionFunc] that projects the embeddings onto their first
+// principal components, as found by principal component analysis (PCA).
+// The components are calculated with power iteration, so the covariance matrix
+// doesn't have to be built, which would be large for embeddings with many
+// dimensions. The result is deterministic.
+func ProjectPCA(ctx context.Context, embeddings [][]float32, dimensions int) ([][]float32, error) {
+	if len(embeddings) == 0 {
+		return nil, errors.New("embeddings are empty")
+	}
+	dim := len(embeddings[0])
+	if dimensions < 1 || dimensions > dim {
+		return nil, fmt.Errorf("dimensions must be between 1 and %d, got %d", dim, dimensions)
+	}
+
+	// Center the data. We use float64 for the calculations to limit rounding errors.
+	mean := make([]float64, dim)
+	for _, v := range embeddings {
+		if len(v) != dim {
+			return nil, errors.New("embeddings must have the same length")
+		}
+		for j, val := range v {
+			mean[j] += float64(val)
+		}
+	}
+	for j := range mean {
+		mean[j] /= float64(len(embeddings))
+	}
+	data := make([][]float64, len(embeddings))
+	for i, v := range embeddings {
+		data[i] = make([]float64, dim)
+		for j, val := range v {
+			data[i][j] = float64(val) - mean[j]
+		}
+	}
+
+	// Find the components one by one, with power iteration on the covariance
+	// matrix (XᵀX), orthogonalized against the previous components.
+	rnd := rand.New(rand.NewSource(1))
+	components := make([][]float64, 0, dimensions)
+	for k := 0; k < dimensions; k++ {
+		v := make([]float64, dim)
+		for j := range v {
+			v[j] = rnd.Float64() - 0.5
+		}
+		orthonormalize(v, components)
+
+		for iter := 0; iter < 200; iter++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			w := make([]float64, dim)
+			for _, row := range data {
+				p := dotProduct64(row, v)
+				for j, val := range row {
+					w[j] += p * val
+				}
+			}
+			if !orthonormalize(w, components) {
+				// No variance left, for example if there are fewer distinct
+				// embeddings than dimensions. The coordinates will be 0.
+				v = make([]float64, dim)
+				break
+			}
+			converged := math.Abs(math.Abs(dotProduct64(v, w))-1) < 1e-10
+			v = w
+			if converged {
+				break
+			}
+		}
+
+		// Flip the sign so that the largest value is positive, which makes the
+		// result deterministic.
+		var maxAbs float64
+		for _, val := range v {
+			if math.Abs(val) > math.Abs(maxAbs) {
+				maxAbs = val
+			}
+		}
+		if maxAbs < 0 {
+			for j := range v {
+				v[j] = -v[j]
+			}
+		}
+		components = append(components, v)
+	}
+
+	res := make([][]float32, len(data))
+	for i, row := range data {
+		res[i] = make([]float32, dimensions)
+		for k, component := range components {
+			res[i][k] = float32(dotProduct64(row, component))
+		}
+	}
+	return res, nil
+}
+
+// orthonormalize makes v orthogonal to the given orthonormal vectors and
+// normalizes it, in place. It returns false if nothing is left of v.
+func orthonormalize(v []float64, basis [][]float64) bool {
+	for _, b := range basis {
+		if isZero64(b) {
+			continue
+		}
+		p := dotProduct64(v, b)
+		for j := range v {
+			v[j] -= p * b[j]
+		}
+	}
+	norm := math.Sqrt(dotProduct64(v, v))
+	if norm < 1e-12 {
+		return false
+	}
+	for j := range v {
+		v[j] /= norm
+	}
+	return true
+}
+
+func dotProduct64(a, b []float64) float64 {
+	var res float64
+	for i := range a {
+		res += a[i] * b[i]
+	}
+	return res
+}
+
+func isZero64(v []float64) bool {
+	for _, val := range v {
+		if val != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ProjectedPoint is a document projected to a low number of dimensions, as
+// returned by [Collection.Project].
+type ProjectedPoint struct {
+	ID          string            `json:"id"`
+	Coordinates []float32         `json:"coordinates"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Content     string            `json:"content,omitempty"`
+}
+
+// Project projects the embeddings of all documents in the collection to 2 or 3
+// dimensions, so that the collection can be visualized, for example in an
+// embedding projector. The points are sorted by document ID.
+// If projection is nil, [ProjectPCA] is used.
+// Use [WriteProjection] to write the points as JSON or CSV.
+func (c *Collection) Project(ctx context.Context, dimensions int, projection ProjectionFunc) ([]ProjectedPoint, error) {
+	if dimensions != 2 && dimensions != 3 {
+		return nil, errors.New("dimensions must be 2 or 3")
+	}
+	if projection == nil {
+		projection = ProjectPCA
+	}
+
+	docs := c.sortedDocuments()
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	embeddings := make([][]float32, len(docs))
+	for i, doc := range docs {
+		embeddings[i] = doc.Embedding
+	}
+	coordinates, err := projection(ctx, embeddings, dimensions)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't project embeddings: %w", err)
+	}
+	if len(coordinates) != len(docs) {
+		return nil, fmt.Errorf("expected %d projected points, got %d", len(docs), len(coordinates))
+	}
+
+	points := make([]ProjectedPoint, len(docs))
+	for i, doc := range docs {
+		if len(coordinates[i]) != dimensions {
+			return nil, fmt.Errorf("expected %d dimensions for document %q, got %d", dimensions, doc.ID, len(coordinates[i]))
+		}
+		// With content spillover, the content is only on disk.
+		doc, err := c.withContent(doc)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = ProjectedPoint{
+			ID:          doc.ID,
+			Coordinates: coordinates[i],
+			Metadata:    maps.Clone(doc.Metadata),
+			Content:     doc.Content,
+		}
+	}
+	return points, nil
+}
+
+// ProjectionFormat is the format of [WriteProjection].
+type ProjectionFormat string
+
+const (
+	// ProjectionFormatJSON is a JSON array of [ProjectedPoint] objects.
+	ProjectionFormatJSON ProjectionFormat = "json"
+	// ProjectionFormatCSV is CSV with a header row and the columns "id", "x",
+	// "y", ("z"), "content" and one column per metadata key, sorted by key.
+	ProjectionFormatCSV ProjectionFormat = "csv"
+)
+
+// WriteProjection writes the points, as returned by [Collection.Project], to
+// the writer in the given format.
+func WriteProjection(w io.Writer, points []ProjectedPoint, format ProjectionFormat) error {
+	switch format {
+	case ProjectionFormatJSON:
+		if points == nil {
+			points = []ProjectedPoint{}
+		}
+		err := json.NewEncoder(w).Encode(points)
+		if err != nil {
+			return fmt.Errorf("couldn't encode points: %w", err)
+		}
+		return nil
+	case ProjectionFormatCSV:
+		return writeProjectionCSV(w, points)
+	default:
+		return fmt.Errorf("unsupported projection format %q", format)
+	}
+}
+
+func writeProjectionCSV(w io.Writer, points []ProjectedPoint) error {
+	dimensions := 0
+	var metadataKeys []string
+	seen := make(map[string]struct{})
+	for _, p := range points {
+		dimensions = max(dimensions, len(p.Coordinates))
+		for k := range p.Metadata {
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				metadataKeys = append(metadataKeys, k)
+			}
+		}
+	}
+	if dimensions > 3 {
+		return fmt.Errorf("expected up to 3 dimensions, got %d", dimensions)
+	}
+	slices.Sort(metadataKeys)
+
+	cw := csv.NewWriter(w)
+	header := append([]string{"id"}, []string{"x", "y", "z"}[:dimensions]...)
+	header = append(header, "content")
+	header = append(header, metadataKeys...)
+	err := cw.Write(header)
+	if err != nil {
+		return fmt.Errorf("couldn't write header: %w", err)
+	}
+	for _, p := range points {
+		record := make([]string, 0, len(header))
+		record = append(record, p.ID)
+		for i := 0; i < dimensions; i++ {
+			var val string
+			if i < len(p.Coordinates) {
+				val = strconv.FormatFloat(float64(p.Coordinates[i]), 'g', -1, 32)
+			}
+			record = append(record, val)
+		}
+		record = append(record, p.Content)
+		for _, k := range metadataKeys {
+			record = append(record, p.Metadata[k])
+		}
+		err := cw.Write(record)
+		if err != nil {
+			return fmt.Errorf("couldn't write record: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("couldn't write CSV: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestProjectPCA(t *testing.T) {
+	ctx := context.Background()
+	// Most variance along the first axis, some along the second, none along the
+	// third.
+	embeddings := [][]float32{
+		{-2, 0.5, 1},
+		{-1, -0.5, 1},
+		{1, -0.5, 1},
+		{2, 0.5, 1},
+	}
+	res, err := ProjectPCA(ctx, embeddings, 2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected := [][]float32{
+		{-2, 0.5},
+		{-1, -0.5},
+		{1, -0.5},
+		{2, 0.5},
+	}
+	if len(res) != len(expected) {
+		t.Fatalf("expected %d points, got %d", len(expected), len(res))
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if math.Abs(float64(res[i][j]-expected[i][j])) > 1e-5 {
+				t.Fatalf("expected %v, got %v", expected, res)
+			}
+		}
+	}
+
+	// Invalid dimensions
+	_, err = ProjectPCA(ctx, embeddings, 4)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_Project(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Embedding: []float32{-0.40824828, 0.40824828, 0.81649655}, Content: "hello world", Metadata: map[string]string{"lang": "en"}},
+		{ID: "2", Embedding: []float32{0.40824828, -0.40824828, 0.81649655}, Content: "hallo welt", Metadata: map[string]string{"lang": "de"}},
+		{ID: "3", Embedding: []float32{0.81649655, 0.40824828, 0.40824828}, Content: "hola, mundo"},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	points, err := c.Project(ctx, 2, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(points) != 3 {
+		t.Fatal("expected 3 points, got", len(points))
+	}
+	for i, p := range points {
+		if p.ID != docs[i].ID || p.Content != docs[i].Content || len(p.Coordinates) != 2 {
+			t.Fatalf("expected point for document %q, got %+v", docs[i].ID, p)
+		}
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		err := WriteProjection(buf, points, ProjectionFormatJSON)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		var got []ProjectedPoint
+		err = json.Unmarshal(buf.Bytes(), &got)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(got) != 3 || got[0].ID != "1" || got[0].Metadata["lang"] != "en" {
+			t.Fatalf("expected points, got %+v", got)
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		err := WriteProjection(buf, points, ProjectionFormatCSV)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 4 {
+			t.Fatal("expected 4 lines, got", len(lines))
+		}
+		if lines[0] != "id,x,y,content,lang" {
+			t.Fatal("expected header \"id,x,y,content,lang\", got", lines[0])
+		}
+		if !strings.HasPrefix(lines[1], "1,") || !strings.HasSuffix(lines[1], ",hello world,en") {
+			t.Fatal("unexpected line", lines[1])
+		}
+		if !strings.HasSuffix(lines[3], ",\"hola, mundo\",") {
+			t.Fatal("unexpected line", lines[3])
+		}
+	})
+
+	t.Run("Custom projection", func(t *testing.T) {
+		projection := func(_ context.Context, embeddings [][]float32, dimensions int) ([][]float32, error) {
+			res := make([][]float32, len(embeddings))
+			for i, v := range embeddings {
+				res[i] = v[:dimensions]
+			}
+			return res, nil
+		}
+		points, err := c.Project(ctx, 3, projection)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if points[1].Coordinates[0] != 0.40824828 {
+			t.Fatal("expected coordinates of the custom projection, got", points[1].Coordinates)
+		}
+
+		errProjection := errors.New("projection failed")
+		_, err = c.Project(ctx, 2, func(context.Context, [][]float32, int) ([][]float32, error) {
+			return nil, errProjection
+		})
+		if !errors.Is(err, errProjection) {
+			t.Fatal("expected projection error, got", err)
+		}
+	})
+}
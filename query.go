@@ -7,23 +7,40 @@ import (
 	"fmt"
 	"runtime"
 	"slices"
-	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-var supportedFilters = []string{"$contains", "$not_contains"}
+// scanBatchSize is the number of documents per batch when documents are
+// filtered and scored in a single pass.
+const scanBatchSize = 256
 
 type docSim struct {
 	docID      string
 	similarity float32
+	// The document, so results don't have to look it up, which could fail
+	// after it's deleted concurrently.
+	doc *Document
 }
 
-// docMaxHeap is a max-heap of docSims, based on similarity.
+// compareDocSims orders docSims by similarity (descending), and docSims with
+// the same similarity by ID, so that results don't depend on the order in which
+// documents are scanned.
+func compareDocSims(a, b docSim) int {
+	if c := cmp.Compare(b.similarity, a.similarity); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.docID, b.docID)
+}
+
+// docMaxHeap is a max-heap of docSims, based on similarity. Its root is the
+// docSim that's ordered last by compareDocSims.
 // See https://pkg.go.dev/container/heap@go1.22#example-package-IntHeap
 type docMaxHeap []docSim
 
 func (h docMaxHeap) Len() int           { return len(h) }
-func (h docMaxHeap) Less(i, j int) bool { return h[i].similarity < h[j].similarity }
+func (h docMaxHeap) Less(i, j int) bool { return compareDocSims(h[i], h[j]) > 0 }
 func (h docMaxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
 
 func (h *docMaxHeap) Push(x any) {
@@ -42,7 +59,8 @@ func (h *docMaxHeap) Pop() any {
 
 // maxDocSims manages a max-heap of docSims with a fixed size, keeping the n highest
 // similarities. It's safe for concurrent use, but not the result of values().
-// In our benchmarks this was faster than sorting a slice of docSims at the end.
+// In our benchmarks this was faster than sorting a slice of docSims at the end,
+// unless n is large relative to the number of documents, see [TopKAlgorithm].
 type maxDocSims struct {
 	h    docMaxHeap
 	lock sync.RWMutex
@@ -63,30 +81,46 @@ func (d *maxDocSims) add(doc docSim) {
 	defer d.lock.Unlock()
 	if d.h.Len() < d.size {
 		heap.Push(&d.h, doc)
-	} else if d.h.Len() > 0 && d.h[0].similarity < doc.similarity {
-		// Replace the smallest similarity if the new doc's similarity is higher
+	} else if d.h.Len() > 0 && compareDocSims(doc, d.h[0]) < 0 {
+		// Replace the smallest similarity if the new doc's similarity is higher,
+		// or if it's the same and the new doc's ID is smaller
 		heap.Pop(&d.h)
 		heap.Push(&d.h, doc)
 	}
 }
 
-// values returns the docSims in the heap, sorted by similarity (descending).
-// The call itself is safe for concurrent use with add(), but the result isn't.
-// Only work with the result after all calls to add() have finished.
+// values returns the docSims in the heap, sorted by similarity (descending)
+// and ID. The call itself is safe for concurrent use with add(), but the result
+// isn't. Only work with the result after all calls to add() have finished.
 func (d *maxDocSims) values() []docSim {
 	d.lock.RLock()
 	defer d.lock.RUnlock()
-	slices.SortFunc(d.h, func(i, j docSim) int {
-		return cmp.Compare(j.similarity, i.similarity)
-	})
+	slices.SortFunc(d.h, compareDocSims)
 	return d.h
 }
 
+// bestDocSims keeps the most similar docSim per group, to deduplicate results.
+type bestDocSims struct {
+	best map[string]docSim
+	lock sync.Mutex
+}
+
+// add keeps the docSim if it's more similar than the group's previous best.
+func (b *bestDocSims) add(group string, doc docSim) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if prev, ok := b.best[group]; !ok || compareDocSims(doc, prev) < 0 {
+		b.best[group] = doc
+	}
+}
+
 // filterDocs filters a map of documents by metadata and content.
 // It does this concurrently.
-func filterDocs(docs map[string]*Document, where, whereDocument map[string]string) []*Document {
+func filterDocs(executor Executor, docs map[string]*Document, filter *Filter) ([]*Document, error) {
 	filteredDocs := make([]*Document, 0, len(docs))
 	filteredDocsLock := sync.Mutex{}
+	var sharedErr error
+	sharedErrLock := sync.Mutex{}
 
 	// Determine concurrency. Use number of docs or CPUs, whichever is smaller.
 	numCPUs := runtime.NumCPU()
@@ -101,16 +135,26 @@ func filterDocs(docs map[string]*Document, where, whereDocument map[string]strin
 	wg := sync.WaitGroup{}
 	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
-		go func() {
+		executor.Submit(func() {
 			defer wg.Done()
+			defer recoverPanic(func(err error) {
+				sharedErrLock.Lock()
+				if sharedErr == nil {
+					sharedErr = err
+				}
+				sharedErrLock.Unlock()
+				// Let the sender continue, in case all goroutines panic.
+				for range docChan {
+				}
+			})
 			for doc := range docChan {
-				if documentMatchesFilters(doc, where, whereDocument) {
+				if filter.matches(doc) {
 					filteredDocsLock.Lock()
 					filteredDocs = append(filteredDocs, doc)
 					filteredDocsLock.Unlock()
 				}
 			}
-		}()
+		})
 	}
 
 	for _, doc := range docs {
@@ -120,51 +164,101 @@ func filterDocs(docs map[string]*Document, where, whereDocument map[string]strin
 
 	wg.Wait()
 
+	if sharedErr != nil {
+		return nil, sharedErr
+	}
 	// With filteredDocs being initialized as potentially large slice, let's return
 	// nil instead of the empty slice.
 	if len(filteredDocs) == 0 {
 		filteredDocs = nil
 	}
-	return filteredDocs
+	return filteredDocs, nil
 }
 
-// documentMatchesFilters checks if a document matches the given filters.
-// When calling this function, the whereDocument keys must already be validated!
-func documentMatchesFilters(document *Document, where, whereDocument map[string]string) bool {
-	// A document's metadata must have *all* the fields in the where clause.
-	for k, v := range where {
-		// TODO: Do we want to check for existence of the key? I.e. should
-		// a where clause with empty string as value match a document's
-		// metadata that doesn't have the key at all?
-		if document.Metadata[k] != v {
-			return false
-		}
+// docScorer calculates the similarities of documents to the query and keeps the
+// most similar ones in the collector. If dedupeBy is set, only the most similar document per
+// value of the metadata key is kept. Documents without the key aren't
+// deduplicated. The boosts of matching boost rules and the feedback boosts are
+// added to the similarities. It's safe for concurrent use.
+type docScorer struct {
+	queryVector             []float32
+	negativeVector          []float32
+	negativeFilterThreshold float32
+	dedupeBy                string
+	boostRules              []BoostRule
+	// Document ID -> boost, see [Collection.SetFeedbackBoost]. Can be nil.
+	feedbackBoosts map[string]float32
+
+	topK   topKCollector
+	groups *bestDocSims
+
+	// Called with every scored document, if it isn't nil
+	onCandidate func(doc *Document, similarity float32)
+}
+
+func newDocScorer(queryVector, negativeVector []float32, negativeFilterThreshold float32, topK topKCollector, dedupeBy string, boostRules []BoostRule) *docScorer {
+	return &docScorer{
+		queryVector:             queryVector,
+		negativeVector:          negativeVector,
+		negativeFilterThreshold: negativeFilterThreshold,
+		dedupeBy:                dedupeBy,
+		boostRules:              boostRules,
+		topK:                    topK,
+		groups:                  &bestDocSims{best: make(map[string]docSim)},
 	}
+}
 
-	// A document must satisfy *all* filters, until we support the `$or` operator.
-	for k, v := range whereDocument {
-		switch k {
-		case "$contains":
-			if !strings.Contains(document.Content, v) {
-				return false
-			}
-		case "$not_contains":
-			if strings.Contains(document.Content, v) {
-				return false
-			}
-		default:
-			// No handling (error) required because we already validated the
-			// operators. This simplifies the concurrency logic (no err var
-			// and lock, no context to cancel).
+// score calculates the similarity of the document and keeps it if it's among
+// the most similar ones.
+func (s *docScorer) score(doc *Document) error {
+	// As the vectors are normalized, the dot product is the cosine similarity.
+	sim, err := dotProduct(s.queryVector, doc.Embedding)
+	if err != nil {
+		return fmt.Errorf("couldn't calculate similarity for document '%s': %w", doc.ID, err)
+	}
+
+	if s.negativeFilterThreshold > 0 {
+		nsim, err := dotProduct(s.negativeVector, doc.Embedding)
+		if err != nil {
+			return fmt.Errorf("couldn't calculate negative similarity for document '%s': %w", doc.ID, err)
+		}
+
+		if nsim > s.negativeFilterThreshold {
+			return nil
 		}
 	}
 
-	return true
+	if len(s.boostRules) != 0 {
+		sim += boost(doc, s.boostRules)
+	}
+	if s.feedbackBoosts != nil {
+		sim += s.feedbackBoosts[doc.ID]
+	}
+	if s.onCandidate != nil {
+		s.onCandidate(doc, sim)
+	}
+
+	if group, ok := doc.Metadata[s.dedupeBy]; ok && s.dedupeBy != "" {
+		s.groups.add(group, docSim{docID: doc.ID, similarity: sim, doc: doc})
+		return nil
+	}
+	s.topK.add(docSim{docID: doc.ID, similarity: sim, doc: doc})
+	return nil
 }
 
-func getMostSimilarDocs(ctx context.Context, queryVectors, negativeVector []float32, negativeFilterThreshold float32, docs []*Document, n int) ([]docSim, error) {
-	nMaxDocs := newMaxDocSims(n)
+// values returns the most similar documents, sorted by similarity (descending).
+// Only call it after all calls to score() have finished.
+func (s *docScorer) values() []docSim {
+	for _, doc := range s.groups.best {
+		s.topK.add(doc)
+	}
+	return s.topK.values()
+}
 
+// getMostSimilarDocs scores the documents with the scorer.
+// If the deadline isn't zero and is reached, the documents that weren't scanned
+// yet are skipped. The number of scanned documents is returned.
+func getMostSimilarDocs(ctx context.Context, executor Executor, scorer *docScorer, docs []*Document, deadline time.Time) (int, error) {
 	// Determine concurrency. Use number of docs or CPUs, whichever is smaller.
 	numCPUs := runtime.NumCPU()
 	numDocs := len(docs)
@@ -188,6 +282,7 @@ func getMostSimilarDocs(ctx context.Context, queryVectors, negativeVector []floa
 		}
 	}
 
+	scanned := atomic.Int64{}
 	wg := sync.WaitGroup{}
 	// Instead of using a channel to pass documents into the goroutines, we just
 	// split the slice into sub-slices and pass those to the goroutines.
@@ -201,45 +296,152 @@ func getMostSimilarDocs(ctx context.Context, queryVectors, negativeVector []floa
 		if i == concurrency-1 {
 			end += rem
 		}
+		subSlice := docs[start:end]
 
 		wg.Add(1)
-		go func(subSlice []*Document) {
+		executor.Submit(func() {
 			defer wg.Done()
-			for _, doc := range subSlice {
+			defer recoverPanic(setSharedErr)
+			for i, doc := range subSlice {
 				// Stop work if another goroutine encountered an error.
 				if ctx.Err() != nil {
 					return
 				}
+				// Checking the time for each document would be too slow.
+				if !deadline.IsZero() && i%deadlineCheckInterval == 0 && !time.Now().Before(deadline) {
+					return
+				}
+				scanned.Add(1)
 
-				// As the vectors are normalized, the dot product is the cosine similarity.
-				sim, err := dotProduct(queryVectors, doc.Embedding)
-				if err != nil {
-					setSharedErr(fmt.Errorf("couldn't calculate similarity for document '%s': %w", doc.ID, err))
+				if err := scorer.score(doc); err != nil {
+					setSharedErr(err)
 					return
 				}
+			}
+		})
+	}
 
-				if negativeFilterThreshold > 0 {
-					nsim, err := dotProduct(negativeVector, doc.Embedding)
-					if err != nil {
-						setSharedErr(fmt.Errorf("couldn't calculate negative similarity for document '%s': %w", doc.ID, err))
-						return
-					}
+	wg.Wait()
 
-					if nsim > negativeFilterThreshold {
-						continue
-					}
-				}
+	if sharedErr != nil {
+		return 0, sharedErr
+	}
+	return int(scanned.Load()), nil
+}
+
+// filterAndScoreDocs filters the documents by metadata and content, and scores
+// the matching ones with the scorer, in a single concurrent pass. Unlike
+// filtering first, this doesn't need a list of the matching documents.
+// The scan stops when the limits are reached. The number of scanned documents
+// is returned, and whether the scan stopped before all matching documents were
+// scanned.
+func filterAndScoreDocs(ctx context.Context, executor Executor, scorer *docScorer, docs []*Document, filter *Filter, limits queryLimits) (int, bool, error) {
+	scanned := atomic.Int64{}
+	visited := atomic.Int64{}
+	truncated, err := scanDocs(ctx, executor, docs, limits.deadline, func(batch []*Document) (bool, error) {
+		cont := true
+		if limits.maxVisited > 0 {
+			// Only the part of the batch within the limit is visited.
+			over := visited.Add(int64(len(batch))) - int64(limits.maxVisited)
+			if over > 0 {
+				batch = batch[:max(0, int64(len(batch))-over)]
+				cont = false
+			}
+		}
+		for _, doc := range batch {
+			if !filter.matches(doc) {
+				continue
+			}
+			if n := scanned.Add(1); limits.maxScanned > 0 && n > int64(limits.maxScanned) {
+				scanned.Add(-1)
+				return false, nil
+			}
+			if err := scorer.score(doc); err != nil {
+				return false, err
+			}
+		}
+		return cont, nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return int(scanned.Load()), truncated, nil
+}
+
+// scanDocs passes the documents in batches to concurrent calls of fn. The scan
+// stops early when fn returns false or an error, or when the deadline is
+// reached, if it isn't zero. It returns whether the scan stopped early without
+// an error.
+func scanDocs(ctx context.Context, executor Executor, docs []*Document, deadline time.Time, fn func(batch []*Document) (bool, error)) (bool, error) {
+	// Determine concurrency. Use number of batches or CPUs, whichever is smaller.
+	concurrency := min(runtime.NumCPU(), (len(docs)+scanBatchSize-1)/scanBatchSize)
+	if concurrency == 0 {
+		return false, nil
+	}
+
+	var sharedErr error
+	sharedErrLock := sync.Mutex{}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	setSharedErr := func(err error) {
+		sharedErrLock.Lock()
+		defer sharedErrLock.Unlock()
+		// Another goroutine might have already set the error.
+		if sharedErr == nil {
+			sharedErr = err
+			// Cancel the operation for all other goroutines.
+			cancel(sharedErr)
+		}
+	}
 
-				nMaxDocs.add(docSim{docID: doc.ID, similarity: sim})
+	stopped := atomic.Bool{}
+	// Documents are passed to the goroutines in batches, as passing each one
+	// through the channel would be slower. The batches are sub-slices, so
+	// they're not allocated.
+	batchChan := make(chan []*Document, concurrency)
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		executor.Submit(func() {
+			defer wg.Done()
+			defer recoverPanic(func(err error) {
+				setSharedErr(err)
+				// Let the sender continue, in case all goroutines panic.
+				for range batchChan {
+				}
+			})
+			for batch := range batchChan {
+				// Skip the remaining batches if another goroutine encountered
+				// an error or stopped the scan.
+				if ctx.Err() != nil || stopped.Load() {
+					continue
+				}
+				if !deadline.IsZero() && !time.Now().Before(deadline) {
+					stopped.Store(true)
+					continue
+				}
+				cont, err := fn(batch)
+				if err != nil {
+					setSharedErr(err)
+				} else if !cont {
+					stopped.Store(true)
+				}
 			}
-		}(docs[start:end])
+		})
 	}
 
+	for start := 0; start < len(docs); start += scanBatchSize {
+		if ctx.Err() != nil || stopped.Load() {
+			break
+		}
+		batchChan <- docs[start:min(start+scanBatchSize, len(docs))]
+	}
+	close(batchChan)
+
 	wg.Wait()
 
 	if sharedErr != nil {
-		return nil, sharedErr
+		return false, sharedErr
 	}
-
-	return nMaxDocs.values(), nil
+	return stopped.Load(), nil
 }
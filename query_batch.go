@@ -0,0 +1,184 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// QueryRequest is a query of [Collection.QueryBatch]. The fields correspond to
+// the ones of [QueryOptions].
+type QueryRequest struct {
+	// The text to search for.
+	QueryText string
+
+	// The embedding of the query to search for. If both QueryText and
+	// QueryEmbedding are set, QueryEmbedding will be used.
+	QueryEmbedding []float32
+
+	// The number of results to return.
+	NResults int
+
+	// Conditional filtering on metadata and documents. Optional.
+	Where         map[string]string
+	WhereDocument map[string]string
+	// A compiled filter, as alternative to Where and WhereDocument. Optional.
+	Filter *Filter
+}
+
+// QueryBatch performs multiple exhaustive nearest neighbor searches on the
+// collection in a single pass over the documents: each document's embedding
+// is compared with all query embeddings while it's in the CPU cache, instead
+// of scanning the collection once per query. For many queries, for example in
+// evaluation or reranking pipelines, this is much faster than separate queries.
+//
+// The results are returned in the order of the queries. Query texts are
+// embedded one after another with the collection's embedding function, so for
+// large batches it's best to pass precomputed embeddings.
+func (c *Collection) QueryBatch(ctx context.Context, queries []QueryRequest) ([][]Result, error) {
+	if len(queries) == 0 {
+		return nil, errors.New("queries are empty")
+	}
+
+	vectors := make([][]float32, len(queries))
+	filters := make([]*Filter, len(queries))
+	for i, q := range queries {
+		if q.NResults <= 0 {
+			return nil, fmt.Errorf("query %d: nResults must be > 0", i)
+		}
+		filter := q.Filter
+		if filter == nil {
+			var err error
+			filter, err = CompileFilter(q.Where, q.WhereDocument)
+			if err != nil {
+				return nil, fmt.Errorf("query %d: %w", i, err)
+			}
+		} else if len(q.Where) != 0 || len(q.WhereDocument) != 0 {
+			return nil, fmt.Errorf("query %d: Filter can't be combined with Where and WhereDocument", i)
+		}
+		filters[i] = filter
+
+		vector := q.QueryEmbedding
+		if len(vector) == 0 {
+			if q.QueryText == "" {
+				return nil, fmt.Errorf("query %d: QueryText and QueryEmbedding are empty", i)
+			}
+			var err error
+			vector, err = c.embedQuery(ctx, q.QueryText)
+			if err != nil {
+				return nil, fmt.Errorf("query %d: couldn't create embedding of query: %w", i, err)
+			}
+		}
+		if !isNormalized(vector) {
+			vector = normalizeVector(vector)
+		}
+		vectors[i] = vector
+	}
+
+	// The documents are only locked to prepare the scan and to get the
+	// contents of the results, but not while scanning them, see documentsMutex.
+	c.documentsLock.RLock()
+	docs := c.documentsSliceLocked()
+	scorers, contentMatches, err := c.newBatchScorersLocked(queries, vectors, filters)
+	c.documentsLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([][]Result, len(queries))
+	if len(docs) == 0 {
+		return res, nil
+	}
+
+	scanned := atomic.Int64{}
+	_, err = scanDocs(ctx, c.executor(), docs, time.Time{}, func(batch []*Document) (bool, error) {
+		for _, doc := range batch {
+			for i, scorer := range scorers {
+				if contentMatches != nil && contentMatches[i] != nil {
+					if _, ok := contentMatches[i][doc.ID]; !ok {
+						continue
+					}
+				} else if !filters[i].matches(doc) {
+					continue
+				}
+				scanned.Add(1)
+				if err := scorer.score(doc); err != nil {
+					return false, err
+				}
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get most similar docs: %w", err)
+	}
+	// A canceled scan is incomplete.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.counters().queryServed(len(queries), int(scanned.Load()))
+
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	for i, scorer := range scorers {
+		nMaxDocs := scorer.values()
+		if len(nMaxDocs) == 0 {
+			continue
+		}
+		res[i] = make([]Result, 0, len(nMaxDocs))
+		for rank, d := range nMaxDocs {
+			doc, err := c.withContentLocked(d.doc)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = append(res[i], c.newResult(doc, d.similarity, rank+1, filters[i]))
+		}
+	}
+	return res, nil
+}
+
+// newBatchScorersLocked returns the scorers of the queries, and the IDs of the
+// documents that match the content filters of queries, if the contents are
+// spilled to disk and have to be loaded for the filters. The caller must hold
+// the documents lock.
+func (c *Collection) newBatchScorersLocked(queries []QueryRequest, vectors [][]float32, filters []*Filter) ([]*docScorer, []map[string]struct{}, error) {
+	if len(c.documents) == 0 {
+		return nil, nil, nil
+	}
+
+	dim := c.dimensionLocked("")
+	config := c.getConfig()
+	scorers := make([]*docScorer, len(queries))
+	var contentMatches []map[string]struct{}
+	for i, q := range queries {
+		if q.NResults > len(c.documents) {
+			return nil, nil, fmt.Errorf("query %d: nResults must be <= the number of documents in the collection", i)
+		}
+		if len(vectors[i]) != dim {
+			return nil, nil, fmt.Errorf("query %d: %w", i, &DimensionMismatchError{Expected: dim, Actual: len(vectors[i])})
+		}
+		topK, err := newTopKCollector(TopKAuto, q.NResults, len(c.documents))
+		if err != nil {
+			return nil, nil, err
+		}
+		scorers[i] = newDocScorer(vectors[i], nil, 0, topK, "", config.BoostRules)
+		scorers[i].feedbackBoosts = c.feedback.boosts(vectors[i], config.FeedbackBoost)
+
+		if c.contentCache != nil && filters[i].hasContentConditions() {
+			if contentMatches == nil {
+				contentMatches = make([]map[string]struct{}, len(queries))
+			}
+			docs, err := c.filterDocsLocked(filters[i])
+			if err != nil {
+				return nil, nil, fmt.Errorf("query %d: couldn't filter documents: %w", i, err)
+			}
+			contentMatches[i] = make(map[string]struct{}, len(docs))
+			for _, doc := range docs {
+				contentMatches[i][doc.ID] = struct{}{}
+			}
+		}
+	}
+	return scorers, contentMatches, nil
+}
@@ -0,0 +1,88 @@
+package chromem
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_QueryBatch(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	randomVector := func() []float32 {
+		v := make([]float32, 8)
+		for i := range v {
+			v[i] = r.Float32()*2 - 1
+		}
+		return v
+	}
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0, 0, 0, 0, 0, 0, 0}, nil
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	var docs []Document
+	for i := 0; i < 1000; i++ {
+		docs = append(docs, Document{
+			ID:        strconv.Itoa(i),
+			Metadata:  map[string]string{"even": strconv.FormatBool(i%2 == 0)},
+			Embedding: randomVector(),
+			Content:   "document " + strconv.Itoa(i),
+		})
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	queries := []QueryRequest{
+		{QueryEmbedding: randomVector(), NResults: 10},
+		{QueryEmbedding: randomVector(), NResults: 5, Where: map[string]string{"even": "true"}},
+		{QueryText: "foo", NResults: 3, WhereDocument: map[string]string{"$contains": "document 1"}},
+		{QueryEmbedding: randomVector(), NResults: 5, WhereDocument: map[string]string{"$contains": "no match"}},
+	}
+	res, err := c.QueryBatch(ctx, queries)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != len(queries) {
+		t.Fatalf("expected %d result lists, got %d", len(queries), len(res))
+	}
+	// The results must be the same as with separate queries.
+	for i, q := range queries {
+		expected, err := c.QueryWithOptions(ctx, QueryOptions{
+			QueryText:      q.QueryText,
+			QueryEmbedding: q.QueryEmbedding,
+			NResults:       q.NResults,
+			Where:          q.Where,
+			WhereDocument:  q.WhereDocument,
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if !reflect.DeepEqual(res[i], expected) {
+			t.Fatalf("query %d: expected %v, got %v", i, expected, res[i])
+		}
+	}
+
+	// Errors
+	for _, queries := range [][]QueryRequest{
+		nil,
+		{{QueryEmbedding: randomVector()}},
+		{{NResults: 1}},
+		{{QueryEmbedding: []float32{1, 0}, NResults: 1}},
+		{{QueryEmbedding: randomVector(), NResults: 1001}},
+		{{QueryEmbedding: randomVector(), NResults: 1, WhereDocument: map[string]string{"$foo": "bar"}}},
+	} {
+		_, err := c.QueryBatch(ctx, queries)
+		if err == nil {
+			t.Fatal("expected error, got nil for", queries)
+		}
+	}
+}
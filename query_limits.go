@@ -0,0 +1,102 @@
+package chromem
+
+import (
+	"errors"
+	"time"
+	"unsafe"
+)
+
+// deadlineCheckInterval is the number of documents after which the scan checks
+// the deadline of [QueryLimits.Timeout].
+const deadlineCheckInterval = 64
+
+// candidateSize is the memory that each candidate document of a query needs for
+// [QueryLimits.MaxMemory].
+const candidateSize = int64(unsafe.Sizeof((*Document)(nil)))
+
+// QueryLimits limit the resources of a query, so that queries on huge
+// collections stay within a latency or memory budget. When a limit is reached,
+// the query doesn't fail, but returns the most similar documents among the
+// ones it scanned, and [QueryStats.Truncated] is set. Zero values disable the
+// respective limit.
+type QueryLimits struct {
+	// The maximum duration of the query, starting when it's called. After it,
+	// the remaining documents aren't scanned anymore. Unlike a context deadline,
+	// which leads to an error, this returns partial results. The time for
+	// creating the query embedding counts towards it, but isn't interrupted.
+	Timeout time.Duration
+
+	// The maximum number of documents whose similarity is calculated. Documents
+	// that don't match the filters don't count. If more documents match, an
+	// arbitrary subset of them is scanned.
+	MaxDocumentsScanned int
+
+	// The maximum number of documents that are checked against the filters,
+	// including the ones that don't match. Unlike MaxDocumentsScanned, this
+	// also limits the work of queries with selective filters. For content
+	// filters on collections with content spillover, the metadata filters are
+	// checked for all documents, and it limits the documents whose contents
+	// are loaded from disk.
+	MaxDocumentsVisited int
+
+	// The maximum estimated memory of the query in bytes. It consists of the
+	// results, with their size as documents (see [MemoryUsage]), and for
+	// content filters on collections with content spillover, of the list of
+	// documents that match the filters. If the list exceeds it, fewer documents
+	// are scanned, and if the results exceed it, the least similar ones are
+	// dropped.
+	MaxMemory int64
+}
+
+// QueryStats are the statistics of a query, see [Collection.QueryWithStats].
+type QueryStats struct {
+	// The number of documents whose similarity was calculated.
+	DocumentsScanned int
+
+	// Truncated is true if the query reached one of its [QueryLimits], so that
+	// there might be more similar documents than the results.
+	Truncated bool
+
+	// The ID of the query for recording feedback, see [Collection.RecordFeedback].
+	// Empty if the query wasn't logged in the DB's query log (see
+	// [DB.SetQueryLog]), has no retrieval variant, and the collection has no
+	// feedback boost (see [Collection.SetFeedbackBoost]).
+	QueryID string
+}
+
+// queryLimits are the limits of a running query.
+type queryLimits struct {
+	deadline   time.Time
+	maxScanned int
+	maxVisited int
+	maxMemory  int64
+}
+
+// start validates the limits and returns them for a query that starts now.
+func (l QueryLimits) start(now time.Time) (queryLimits, error) {
+	if l.Timeout < 0 || l.MaxDocumentsScanned < 0 || l.MaxDocumentsVisited < 0 || l.MaxMemory < 0 {
+		return queryLimits{}, errors.New("query limits must be >= 0")
+	}
+	limits := queryLimits{
+		maxScanned: l.MaxDocumentsScanned,
+		maxVisited: l.MaxDocumentsVisited,
+		maxMemory:  l.MaxMemory,
+	}
+	if l.Timeout > 0 {
+		limits.deadline = now.Add(l.Timeout)
+	}
+	return limits, nil
+}
+
+// limitCandidates reduces the documents that match the filters to the number
+// of documents that can be scanned. It returns whether they were reduced.
+func (l queryLimits) limitCandidates(docs []*Document) ([]*Document, bool) {
+	n := len(docs)
+	if l.maxScanned > 0 {
+		n = min(n, l.maxScanned)
+	}
+	if l.maxMemory > 0 {
+		n = min(n, int(l.maxMemory/candidateSize))
+	}
+	return docs[:n], n < len(docs)
+}
@@ -0,0 +1,135 @@
+package chromem
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCollection_QueryWithStats_Limits(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < 100; i++ {
+		err := c.AddDocument(ctx, Document{
+			ID:        strconv.Itoa(i),
+			Embedding: []float32{float32(i + 1), 1},
+			Content:   "hello world",
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	query := []float32{1, 0}
+
+	tt := []struct {
+		name            string
+		limits          QueryLimits
+		expectedScanned int
+		expectedResults int
+		expectTruncated bool
+	}{
+		{
+			name:            "no limits",
+			expectedScanned: 100,
+			expectedResults: 10,
+		},
+		{
+			name:            "max documents scanned",
+			limits:          QueryLimits{MaxDocumentsScanned: 20},
+			expectedScanned: 20,
+			expectedResults: 10,
+			expectTruncated: true,
+		},
+		{
+			name:            "max documents scanned not reached",
+			limits:          QueryLimits{MaxDocumentsScanned: 100},
+			expectedScanned: 100,
+			expectedResults: 10,
+		},
+		{
+			name:            "max documents visited",
+			limits:          QueryLimits{MaxDocumentsVisited: 20},
+			expectedScanned: 20,
+			expectedResults: 10,
+			expectTruncated: true,
+		},
+		{
+			name:            "max memory",
+			limits:          QueryLimits{MaxMemory: 3 * documentMemoryUsage(c.documents["99"]).Total()},
+			expectedScanned: 100,
+			expectedResults: 3,
+			expectTruncated: true,
+		},
+		{
+			name:            "timeout",
+			limits:          QueryLimits{Timeout: time.Nanosecond},
+			expectedScanned: 0,
+			expectedResults: 0,
+			expectTruncated: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res, stats, err := c.QueryWithStats(ctx, QueryOptions{
+				QueryEmbedding: query,
+				NResults:       10,
+				Limits:         tc.limits,
+			})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if stats.DocumentsScanned != tc.expectedScanned {
+				t.Fatalf("expected %d scanned documents, got %d", tc.expectedScanned, stats.DocumentsScanned)
+			}
+			if len(res) != tc.expectedResults {
+				t.Fatalf("expected %d results, got %d", tc.expectedResults, len(res))
+			}
+			if stats.Truncated != tc.expectTruncated {
+				t.Fatalf("expected truncated to be %v, got %v", tc.expectTruncated, stats.Truncated)
+			}
+		})
+	}
+
+	// Documents that don't match the filters count as visited, but not as
+	// scanned.
+	for _, tc := range []struct {
+		limits          QueryLimits
+		expectTruncated bool
+	}{
+		{QueryLimits{MaxDocumentsScanned: 50}, false},
+		{QueryLimits{MaxDocumentsVisited: 100}, false},
+		{QueryLimits{MaxDocumentsVisited: 50}, true},
+	} {
+		_, stats, err := c.QueryWithStats(ctx, QueryOptions{
+			QueryEmbedding: query,
+			NResults:       10,
+			Where:          map[string]string{"$id_prefix": "1"},
+			Limits:         tc.limits,
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if stats.Truncated != tc.expectTruncated {
+			t.Fatalf("expected truncated to be %v with %+v, got %v", tc.expectTruncated, tc.limits, stats.Truncated)
+		}
+		if !tc.expectTruncated && stats.DocumentsScanned != 11 {
+			t.Fatalf("expected 11 scanned documents with %+v, got %d", tc.limits, stats.DocumentsScanned)
+		}
+	}
+
+	// Invalid limits
+	_, _, err = c.QueryWithStats(ctx, QueryOptions{
+		QueryEmbedding: query,
+		NResults:       10,
+		Limits:         QueryLimits{MaxMemory: -1},
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
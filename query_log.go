@@ -0,0 +1,463 @@
+package chromem
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	mathrand "math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+type queryIDKey struct{}
+
+// ContextWithQueryID returns a copy of the context that carries the query ID,
+// which is used as ID of the query's entry in the DB's query log, see
+// [DB.SetQueryLog], and for feedback (see [Collection.RecordFeedback]). Without
+// it, a random ID is generated. It's useful to refer to queries of
+// [Collection.Query] and [Collection.QueryEmbedding] when recording feedback, as
+// their query ID isn't returned otherwise.
+func ContextWithQueryID(ctx context.Context, queryID string) context.Context {
+	return context.WithValue(ctx, queryIDKey{}, queryID)
+}
+
+// QueryIDFromContext returns the query ID of the context, or an empty string if
+// there's none.
+func QueryIDFromContext(ctx context.Context) string {
+	queryID, _ := ctx.Value(queryIDKey{}).(string)
+	return queryID
+}
+
+// FeedbackSignal is the kind of feedback on a query result, see
+// [DB.LogQueryFeedback].
+type FeedbackSignal string
+
+// The feedback signals.
+const (
+	// The user clicked or opened the result.
+	FeedbackSignalClick FeedbackSignal = "click"
+	// The user accepted the result, e.g. used it in an answer.
+	FeedbackSignalAccept FeedbackSignal = "accept"
+	// The user marked the result as not relevant.
+	FeedbackSignalReject FeedbackSignal = "reject"
+)
+
+// QueryLogEntry is an entry of the query log.
+type QueryLogEntry struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Collection string    `json:"collection"`
+	// The query text. Empty for queries by embedding, and when the text is
+	// redacted, see [QueryLogOptions.RedactQueryText].
+	QueryText string `json:"query_text,omitempty"`
+	// The hex encoded SHA-256 of the query text. Empty for queries by embedding.
+	QueryTextHash string `json:"query_text_hash,omitempty"`
+	// The hex encoded SHA-256 of the query vector, i.e. the embedding of the
+	// query text, media and concepts, so that queries with the same vector can
+	// be grouped.
+	EmbeddingHash string            `json:"embedding_hash"`
+	Where         map[string]string `json:"where,omitempty"`
+	WhereDocument map[string]string `json:"where_document,omitempty"`
+	NResults      int               `json:"n_results"`
+	Results       []QueryLogResult  `json:"results"`
+	// The retrieval variant of the query, see [QueryOptions.Variant].
+	Variant string `json:"variant,omitempty"`
+
+	// The feedback on the results, oldest first. Filled by
+	// [QueryLog.Entries].
+	Feedback []QueryFeedback `json:"-"`
+}
+
+// QueryLogResult is a result of a query in a [QueryLogEntry].
+type QueryLogResult struct {
+	DocumentID string  `json:"document_id"`
+	Similarity float32 `json:"similarity"`
+}
+
+// QueryFeedback is feedback on a result of a logged query.
+type QueryFeedback struct {
+	Time       time.Time      `json:"time"`
+	QueryID    string         `json:"query_id"`
+	DocumentID string         `json:"document_id"`
+	Signal     FeedbackSignal `json:"signal"`
+}
+
+// QueryLogFilter filters the entries of a query log. Empty fields match all
+// entries.
+type QueryLogFilter struct {
+	Collection string
+	// Matches entries at or after Since and before Until.
+	Since time.Time
+	Until time.Time
+}
+
+func (f QueryLogFilter) matches(entry QueryLogEntry) bool {
+	if f.Collection != "" && entry.Collection != f.Collection {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// QueryLog is an append-only log of the queries of a DB and the feedback on
+// their results, for analyzing the retrieval quality over time.
+// Implementations must be safe for concurrent use.
+// chromem-go comes with [NewMemoryQueryLog] and [NewFileQueryLog], but you can
+// implement your own, for example to write to an analytics system.
+type QueryLog interface {
+	// AppendQuery adds the query entry to the log.
+	AppendQuery(ctx context.Context, entry QueryLogEntry) error
+	// AppendFeedback adds feedback on a query to the log.
+	AppendFeedback(ctx context.Context, feedback QueryFeedback) error
+	// Entries returns the query entries that match the filter, oldest first,
+	// with their feedback.
+	Entries(ctx context.Context, filter QueryLogFilter) ([]QueryLogEntry, error)
+}
+
+// QueryLogOptions are the options of the query log, see [DB.SetQueryLog].
+type QueryLogOptions struct {
+	// The fraction of queries that are logged, between 0 and 1. 0 means all
+	// queries are logged.
+	SampleRate float64
+
+	// RedactQueryText enables the privacy mode: Only the hash of the query text
+	// is logged, but not the text itself. Note that filters are logged
+	// regardless, so they shouldn't contain personal data then.
+	RedactQueryText bool
+
+	// OnError is called when appending to the query log fails, as this doesn't
+	// fail the query. By default the errors are logged via [slog.Default].
+	OnError func(err error)
+}
+
+// SetQueryLog sets the query log of the DB. A sample of the queries (of
+// [Collection.Query], [Collection.QueryEmbedding], [Collection.QueryWithOptions]
+// and the methods based on it) is then recorded in it, with the query text
+// or its hash, the hash of the query vector, the filters, and the IDs and
+// similarities of the results. The ID of a logged query is returned in
+// [QueryStats.QueryID], or can be set via [ContextWithQueryID], for recording
+// feedback with [DB.LogQueryFeedback].
+// A nil query log disables the logging. The setting isn't persisted.
+func (db *DB) SetQueryLog(log QueryLog, options QueryLogOptions) error {
+	if options.SampleRate < 0 || options.SampleRate > 1 {
+		return errors.New("sample rate must be between 0 and 1")
+	}
+	if options.SampleRate == 0 {
+		options.SampleRate = 1
+	}
+	if options.OnError == nil {
+		options.OnError = func(err error) {
+			slog.Default().Error("Couldn't append to query log", "error", err)
+		}
+	}
+
+	db.queryLogLock.Lock()
+	defer db.queryLogLock.Unlock()
+
+	db.queryLog = log
+	db.queryLogOptions = options
+	return nil
+}
+
+// LogQueryFeedback records feedback on a result of a logged query in the DB's
+// query log, for example when a user clicks or accepts the result.
+func (db *DB) LogQueryFeedback(ctx context.Context, queryID, docID string, signal FeedbackSignal) error {
+	if queryID == "" {
+		return errors.New("queryID is empty")
+	}
+	if docID == "" {
+		return errors.New("docID is empty")
+	}
+	switch signal {
+	case FeedbackSignalClick, FeedbackSignalAccept, FeedbackSignalReject:
+	default:
+		return fmt.Errorf("unsupported feedback signal: %q", signal)
+	}
+
+	db.queryLogLock.RLock()
+	log := db.queryLog
+	db.queryLogLock.RUnlock()
+	if log == nil {
+		return errors.New("no query log is set")
+	}
+
+	feedback := QueryFeedback{
+		Time:       time.Now(),
+		QueryID:    queryID,
+		DocumentID: docID,
+		Signal:     signal,
+	}
+	err := log.AppendFeedback(ctx, feedback)
+	if err != nil {
+		return fmt.Errorf("couldn't append to query log: %w", err)
+	}
+	return nil
+}
+
+// recordQuery remembers the query for feedback, if the collection or the
+// retrieval variant has a feedback boost (see [Collection.SetFeedbackBoost]),
+// and appends it to the query log (see [DB.SetQueryLog]). It returns the ID of
+// the query, or an empty string if it's neither remembered nor logged and has no
+// variant.
+func (c *Collection) recordQuery(ctx context.Context, variant *RetrievalVariant, queryText string, queryVector []float32, where, whereDocument map[string]string, nResults int, results []Result) string {
+	var queryID, variantName string
+	feedbackBoost := c.getConfig().FeedbackBoost
+	if variant != nil {
+		queryID = queryIDOrNew(ctx)
+		variantName = variant.Name
+		feedbackBoost = variant.FeedbackBoost
+	}
+	if c.feedback != nil && feedbackBoost.Weight > 0 {
+		if queryID == "" {
+			queryID = queryIDOrNew(ctx)
+		}
+		c.feedback.rememberQuery(queryID, queryVector)
+	}
+	if loggedID := c.logQuery(ctx, queryID, variantName, queryText, queryVector, where, whereDocument, nResults, results); loggedID != "" {
+		queryID = loggedID
+	}
+	return queryID
+}
+
+// logQuery appends an entry for the query to the query log of the collection's
+// DB, if there is one and the query is sampled. If the query ID is empty, it's
+// taken from the context or generated. It returns the ID of the entry, or an
+// empty string if the query isn't logged.
+func (c *Collection) logQuery(ctx context.Context, queryID, variant, queryText string, queryVector []float32, where, whereDocument map[string]string, nResults int, results []Result) string {
+	if c.db == nil {
+		return ""
+	}
+	c.db.queryLogLock.RLock()
+	log, options := c.db.queryLog, c.db.queryLogOptions
+	c.db.queryLogLock.RUnlock()
+	if log == nil || (options.SampleRate < 1 && mathrand.Float64() >= options.SampleRate) {
+		return ""
+	}
+
+	if queryID == "" {
+		queryID = queryIDOrNew(ctx)
+	}
+	entry := QueryLogEntry{
+		ID:            queryID,
+		Time:          time.Now(),
+		Collection:    c.Name,
+		EmbeddingHash: hashVector(queryVector),
+		Where:         maps.Clone(where),
+		WhereDocument: maps.Clone(whereDocument),
+		NResults:      nResults,
+		Results:       make([]QueryLogResult, len(results)),
+		Variant:       variant,
+	}
+	if queryText != "" {
+		sum := sha256.Sum256([]byte(queryText))
+		entry.QueryTextHash = hex.EncodeToString(sum[:])
+		if !options.RedactQueryText {
+			entry.QueryText = queryText
+		}
+	}
+	for i, res := range results {
+		entry.Results[i] = QueryLogResult{DocumentID: res.ID, Similarity: res.Similarity}
+	}
+
+	err := log.AppendQuery(ctx, entry)
+	if err != nil {
+		options.OnError(fmt.Errorf("couldn't append query to query log: %w", err))
+		return ""
+	}
+	return queryID
+}
+
+// queryIDOrNew returns the query ID of the context, or a new one if there's
+// none.
+func queryIDOrNew(ctx context.Context) string {
+	if queryID := QueryIDFromContext(ctx); queryID != "" {
+		return queryID
+	}
+	return newQueryID()
+}
+
+// newQueryID returns a random, hex encoded 128 bit ID.
+func newQueryID() string {
+	b := make([]byte, 16)
+	// It doesn't fail on the supported platforms.
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// hashVector returns the hex encoded SHA-256 of the vector's little-endian
+// float32 representation.
+func hashVector(v []float32) string {
+	h := sha256.New()
+	b := make([]byte, 4)
+	for _, f := range v {
+		binary.LittleEndian.PutUint32(b, math.Float32bits(f))
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MemoryQueryLog is a [QueryLog] that keeps the entries in memory.
+type MemoryQueryLog struct {
+	entries  []QueryLogEntry
+	feedback map[string][]QueryFeedback
+	lock     sync.RWMutex
+}
+
+// NewMemoryQueryLog creates a new in-memory query log.
+func NewMemoryQueryLog() *MemoryQueryLog {
+	return &MemoryQueryLog{
+		feedback: make(map[string][]QueryFeedback),
+	}
+}
+
+// AppendQuery implements [QueryLog].
+func (l *MemoryQueryLog) AppendQuery(_ context.Context, entry QueryLogEntry) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// AppendFeedback implements [QueryLog].
+func (l *MemoryQueryLog) AppendFeedback(_ context.Context, feedback QueryFeedback) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.feedback[feedback.QueryID] = append(l.feedback[feedback.QueryID], feedback)
+	return nil
+}
+
+// Entries implements [QueryLog].
+func (l *MemoryQueryLog) Entries(_ context.Context, filter QueryLogFilter) ([]QueryLogEntry, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	var res []QueryLogEntry
+	for _, entry := range l.entries {
+		if filter.matches(entry) {
+			entry.Feedback = append([]QueryFeedback(nil), l.feedback[entry.ID]...)
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+// queryLogRecord is a line of a [FileQueryLog]. Exactly one of the fields is
+// set.
+type queryLogRecord struct {
+	Query    *QueryLogEntry `json:"query,omitempty"`
+	Feedback *QueryFeedback `json:"feedback,omitempty"`
+}
+
+// FileQueryLog is a [QueryLog] that appends the entries and feedback to a file
+// as JSON lines.
+type FileQueryLog struct {
+	path string
+	f    *os.File
+	lock sync.Mutex
+}
+
+// NewFileQueryLog opens or creates the query log file at the given path.
+// Query entries and feedback are appended to the file as JSON lines, one per
+// entry or feedback, and are never modified or removed. Call
+// [FileQueryLog.Close] when done.
+func NewFileQueryLog(path string) (*FileQueryLog, error) {
+	if path == "" {
+		return nil, errors.New("path is empty")
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open query log file: %w", err)
+	}
+	return &FileQueryLog{
+		path: path,
+		f:    f,
+	}, nil
+}
+
+// AppendQuery implements [QueryLog].
+func (l *FileQueryLog) AppendQuery(_ context.Context, entry QueryLogEntry) error {
+	return l.append(queryLogRecord{Query: &entry})
+}
+
+// AppendFeedback implements [QueryLog].
+func (l *FileQueryLog) AppendFeedback(_ context.Context, feedback QueryFeedback) error {
+	return l.append(queryLogRecord{Feedback: &feedback})
+}
+
+func (l *FileQueryLog) append(record queryLogRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal query log record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// A single write, so that records aren't interleaved, even with multiple
+	// processes appending to the file.
+	_, err = l.f.Write(line)
+	if err != nil {
+		return fmt.Errorf("couldn't write query log record: %w", err)
+	}
+	return nil
+}
+
+// Entries implements [QueryLog]. It reads the whole file.
+func (l *FileQueryLog) Entries(ctx context.Context, filter QueryLogFilter) ([]QueryLogEntry, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open query log file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []QueryLogEntry
+	feedback := make(map[string][]QueryFeedback)
+	dec := json.NewDecoder(f)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var record queryLogRecord
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("couldn't decode query log record: %w", err)
+		}
+		if record.Query != nil && filter.matches(*record.Query) {
+			entries = append(entries, *record.Query)
+		} else if record.Feedback != nil {
+			feedback[record.Feedback.QueryID] = append(feedback[record.Feedback.QueryID], *record.Feedback)
+		}
+	}
+	for i := range entries {
+		entries[i].Feedback = feedback[entries[i].ID]
+	}
+	return entries, nil
+}
+
+// Close closes the query log file.
+func (l *FileQueryLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.f.Close()
+}
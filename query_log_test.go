@@ -0,0 +1,119 @@
+package chromem
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestDB_SetQueryLog(t *testing.T) {
+	fileLog, err := NewFileQueryLog(filepath.Join(t.TempDir(), "queries.jsonl"))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer fileLog.Close()
+
+	tt := []struct {
+		name string
+		log  QueryLog
+	}{
+		{"Memory", NewMemoryQueryLog()},
+		{"File", fileLog},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := NewDB()
+			embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+				return []float32{1, 0}, nil
+			}
+			c, err := db.CreateCollection("test", nil, embeddingFunc)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.AddDocuments(ctx, []Document{
+				{ID: "1", Embedding: []float32{1, 0}, Metadata: map[string]string{"lang": "en"}},
+				{ID: "2", Embedding: []float32{0, 1}, Metadata: map[string]string{"lang": "en"}},
+			}, 1)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+
+			if err := db.SetQueryLog(tc.log, QueryLogOptions{SampleRate: 2}); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			err = db.SetQueryLog(tc.log, QueryLogOptions{RedactQueryText: true})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+
+			// A query with stats returns the ID, the others take it from the
+			// context.
+			_, stats, err := c.QueryWithStats(ctx, QueryOptions{QueryText: "secret", NResults: 2, Where: map[string]string{"lang": "en"}})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if stats.QueryID == "" {
+				t.Fatal("expected query ID, got empty string")
+			}
+			_, err = c.QueryEmbedding(ContextWithQueryID(ctx, "q2"), []float32{0, 1}, 1, nil, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+
+			err = db.LogQueryFeedback(ctx, stats.QueryID, "1", FeedbackSignalAccept)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if err := db.LogQueryFeedback(ctx, "q2", "2", "like"); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+
+			entries, err := tc.log.Entries(ctx, QueryLogFilter{Collection: "test"})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if len(entries) != 2 {
+				t.Fatal("expected 2 entries, got", len(entries))
+			}
+
+			entry := entries[0]
+			if entry.ID != stats.QueryID || entry.QueryText != "" || entry.QueryTextHash == "" {
+				t.Fatalf("expected entry with redacted query text, got %+v", entry)
+			}
+			if entry.Where["lang"] != "en" || entry.NResults != 2 {
+				t.Fatalf("expected filter and nResults, got %+v", entry)
+			}
+			if len(entry.Results) != 2 || entry.Results[0].DocumentID != "1" || entry.Results[0].Similarity != 1 {
+				t.Fatal("expected results 1 and 2, got", entry.Results)
+			}
+			if len(entry.Feedback) != 1 || entry.Feedback[0].DocumentID != "1" || entry.Feedback[0].Signal != FeedbackSignalAccept {
+				t.Fatal("expected accept feedback for document 1, got", entry.Feedback)
+			}
+
+			entry = entries[1]
+			if entry.ID != "q2" || entry.QueryTextHash != "" || len(entry.Results) != 1 || entry.Results[0].DocumentID != "2" {
+				t.Fatalf("expected entry q2 with result 2, got %+v", entry)
+			}
+			if entry.EmbeddingHash == entries[0].EmbeddingHash {
+				t.Fatal("expected different embedding hashes, got", entry.EmbeddingHash)
+			}
+
+			// Disabled
+			err = db.SetQueryLog(nil, QueryLogOptions{})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			_, stats, err = c.QueryWithStats(ctx, QueryOptions{QueryText: "secret", NResults: 1})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if stats.QueryID != "" {
+				t.Fatal("expected no query ID, got", stats.QueryID)
+			}
+			if err := db.LogQueryFeedback(ctx, "q2", "2", FeedbackSignalClick); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
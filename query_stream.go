@@ -0,0 +1,53 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// QueryStream is like [Collection.QueryWithOptions], but while the documents
+// are scanned, it additionally passes each candidate with a similarity of at
+// least threshold to fn, as soon as it's found. This allows latency-sensitive
+// UIs to render first results before the scan is finished.
+//
+// The candidates are passed in the order in which they're found, not sorted by
+// similarity, and their Rank is 0. They can include documents that aren't in
+// the final results, for example because more similar documents were found
+// later, or because of [QueryOptions.DedupeBy]. The final results are returned
+// as with [Collection.QueryWithOptions].
+//
+// fn isn't called concurrently, but it's called while the collection is locked
+// for reading, so it must not modify the collection, and it should return
+// quickly, as it blocks the scan.
+func (c *Collection) QueryStream(ctx context.Context, options QueryOptions, threshold float32, fn func(Result)) ([]Result, error) {
+	if fn == nil {
+		return nil, errors.New("fn is nil")
+	}
+	stream := &candidateStream{
+		threshold:   threshold,
+		snippetSize: options.SnippetSize,
+		fn:          fn,
+	}
+	res, _, err := c.queryWithStats(ctx, options, stream)
+	return res, err
+}
+
+// candidateStream passes the candidates of a query to a callback, see
+// [Collection.QueryStream].
+type candidateStream struct {
+	threshold   float32
+	snippetSize int
+	fn          func(Result)
+	lock        sync.Mutex
+}
+
+// send passes the candidate to the callback. It's safe for concurrent use.
+func (s *candidateStream) send(res Result) {
+	if s.snippetSize > 0 {
+		res.Content, res.Highlights = snippet(res.Content, res.Highlights, s.snippetSize)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.fn(res)
+}
@@ -0,0 +1,59 @@
+package chromem
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_QueryStream(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Similarities from 0 (document 0) to about 0.7 (document 99)
+	for i := 0; i < 100; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: []float32{float32(i), 100}, Content: "hello world"})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	var streamed []string
+	res, err := c.QueryStream(ctx, QueryOptions{
+		QueryEmbedding: []float32{1, 0},
+		NResults:       3,
+		SnippetSize:    5,
+	}, 0.5, func(r Result) {
+		if r.Similarity < 0.5 {
+			t.Errorf("expected similarity >= 0.5, got %v", r.Similarity)
+		}
+		if r.Content != "hello…" {
+			t.Errorf("expected snippet, got %q", r.Content)
+		}
+		streamed = append(streamed, r.ID)
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 3 || res[0].ID != "99" {
+		t.Fatal("expected 3 results starting with document 99, got", res)
+	}
+
+	// Documents 58 to 99 have a similarity >= 0.5.
+	if len(streamed) != 42 {
+		t.Fatalf("expected 42 streamed candidates, got %d", len(streamed))
+	}
+	for _, r := range res {
+		if !slices.Contains(streamed, r.ID) {
+			t.Fatalf("expected result %s to be streamed", r.ID)
+		}
+	}
+
+	_, err = c.QueryStream(ctx, QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 3}, 0.5, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"reflect"
 	"slices"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestFilterDocs(t *testing.T) {
@@ -91,7 +93,14 @@ func TestFilterDocs(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			got := filterDocs(docs, tc.where, tc.whereDocument)
+			filter, err := CompileFilter(tc.where, tc.whereDocument)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			got, err := filterDocs(goroutineExecutor{}, docs, filter)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
 
 			if !reflect.DeepEqual(got, tc.want) {
 				// If len is 2, the order might be different (function under test
@@ -196,3 +205,63 @@ func TestNegative(t *testing.T) {
 		}
 	})
 }
+
+func TestFilterAndScoreDocs(t *testing.T) {
+	ctx := context.Background()
+	// More documents than fit into one batch
+	docs := make(map[string]*Document)
+	for i := 0; i < 3*scanBatchSize+10; i++ {
+		id := strconv.Itoa(i)
+		language := "en"
+		if i%2 == 0 {
+			language = "de"
+		}
+		docs[id] = &Document{
+			ID:        id,
+			Metadata:  map[string]string{"language": language},
+			Embedding: normalizeVector([]float32{float32(i), 100}),
+			Content:   "hello " + id,
+		}
+	}
+	docSlice := make([]*Document, 0, len(docs))
+	for _, doc := range docs {
+		docSlice = append(docSlice, doc)
+	}
+	query := normalizeVector([]float32{1, 0})
+	filter, err := CompileFilter(map[string]string{"language": "de"}, map[string]string{"$not_contains": "hello 1"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The single pass must have the same result as filtering first.
+	scorer := newDocScorer(query, nil, 0, newMaxDocSims(5), "", nil)
+	scanned, truncated, err := filterAndScoreDocs(ctx, goroutineExecutor{}, scorer, docSlice, filter, queryLimits{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	filtered, err := filterDocs(goroutineExecutor{}, docs, filter)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if scanned != len(filtered) || truncated {
+		t.Fatalf("expected %d scanned documents without truncation, got %d and %v", len(filtered), scanned, truncated)
+	}
+	expectedScorer := newDocScorer(query, nil, 0, newMaxDocSims(5), "", nil)
+	_, err = getMostSimilarDocs(ctx, goroutineExecutor{}, expectedScorer, filtered, time.Time{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if got, expected := scorer.values(), expectedScorer.values(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	// Limit of scanned documents
+	scorer = newDocScorer(query, nil, 0, newMaxDocSims(5), "", nil)
+	scanned, truncated, err = filterAndScoreDocs(ctx, goroutineExecutor{}, scorer, docSlice, filter, queryLimits{maxScanned: 100})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if scanned != 100 || !truncated {
+		t.Fatalf("expected 100 scanned documents with truncation, got %d and %v", scanned, truncated)
+	}
+}
@@ -0,0 +1,177 @@
+// Package rag provides a retrieve-and-build-prompt helper for the most common
+// end-to-end flow of retrieval augmented generation (RAG) with chromem-go:
+// retrieving the documents that are relevant to a query, and rendering them
+// into the prompt for an LLM, with numbered sources for citations.
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// DefaultTemplate is the prompt template that's used if [Options.Template]
+// isn't set.
+var DefaultTemplate = template.Must(template.New("prompt").Parse(`Answer the question based on the sources below. Cite the sources you use with their number in square brackets, like [1]. If the sources don't contain the answer, say so.
+
+Sources:
+{{range .Sources}}[{{.Index}}] {{.Content}}
+{{end}}
+Question: {{.Query}}`))
+
+// RerankFunc reorders or filters the retrieved results, for example with a
+// cross-encoder model, which is more accurate than the embedding similarity.
+type RerankFunc func(ctx context.Context, query string, results []chromem.Result) ([]chromem.Result, error)
+
+// Options are the options for [BuildPrompt].
+type Options struct {
+	// The number of documents to retrieve. Optional, defaults to 5.
+	NResults int
+
+	// Conditional filtering on metadata and documents, see
+	// [chromem.QueryOptions]. Optional.
+	Where         map[string]string
+	WhereDocument map[string]string
+
+	// The metadata key to deduplicate the results by, for example to only use
+	// the best chunk per source document, see [chromem.QueryOptions.DedupeBy].
+	// Optional.
+	DedupeBy string
+
+	// Rerank reranks the retrieved results before they're added to the
+	// prompt. Optional.
+	Rerank RerankFunc
+
+	// The maximum number of tokens of the sources' content in the prompt.
+	// Sources are added in the order of relevance until the budget is
+	// exhausted. If not even the first source fits, it's truncated. Optional,
+	// defaults to no limit.
+	MaxContextTokens int
+
+	// The tokenizer for the token budget. Optional, defaults to
+	// [chromem.NewTokenizerHeuristic].
+	Tokenizer chromem.Tokenizer
+
+	// The prompt template. It's executed with a [TemplateData] value.
+	// Optional, defaults to [DefaultTemplate].
+	Template *template.Template
+}
+
+// Source is a retrieved document that's part of the prompt.
+type Source struct {
+	// The number of the source for citations, starting at 1.
+	Index      int
+	ID         string
+	Content    string
+	Metadata   map[string]string
+	Similarity float32
+	// The reference to the document's source, see [chromem.Citation]. Nil if
+	// the document's metadata doesn't contain one.
+	Citation *chromem.Citation
+}
+
+// TemplateData is the data that prompt templates are executed with.
+type TemplateData struct {
+	Query   string
+	Sources []Source
+}
+
+// Prompt is the result of [BuildPrompt].
+type Prompt struct {
+	// The rendered prompt.
+	Text string
+	// The sources in the prompt, so that citations like [1] in the LLM's
+	// answer can be resolved to documents.
+	Sources []Source
+}
+
+// BuildPrompt retrieves the documents of the collection that are relevant to
+// the query, and renders them with the query into a prompt:
+//
+//	prompt, err := rag.BuildPrompt(ctx, collection, question, rag.Options{MaxContextTokens: 2000})
+//	// Send prompt.Text to the LLM
+func BuildPrompt(ctx context.Context, c *chromem.Collection, query string, opts Options) (Prompt, error) {
+	if c == nil {
+		return Prompt{}, errors.New("collection is nil")
+	}
+	if query == "" {
+		return Prompt{}, errors.New("query is empty")
+	}
+	if opts.NResults <= 0 {
+		opts.NResults = 5
+	}
+	if opts.MaxContextTokens < 0 {
+		return Prompt{}, errors.New("maxContextTokens must be >= 0")
+	}
+	if opts.Tokenizer == nil {
+		opts.Tokenizer = chromem.NewTokenizerHeuristic()
+	}
+	if opts.Template == nil {
+		opts.Template = DefaultTemplate
+	}
+
+	var results []chromem.Result
+	if count := c.Count(); count > 0 {
+		var err error
+		results, err = c.QueryWithOptions(ctx, chromem.QueryOptions{
+			QueryText:     query,
+			NResults:      min(opts.NResults, count),
+			Where:         opts.Where,
+			WhereDocument: opts.WhereDocument,
+			DedupeBy:      opts.DedupeBy,
+		})
+		if err != nil {
+			return Prompt{}, fmt.Errorf("couldn't retrieve documents: %w", err)
+		}
+	}
+	if opts.Rerank != nil && len(results) > 0 {
+		var err error
+		results, err = opts.Rerank(ctx, query, results)
+		if err != nil {
+			return Prompt{}, fmt.Errorf("couldn't rerank results: %w", err)
+		}
+	}
+
+	sources := make([]Source, 0, len(results))
+	tokens := 0
+	for _, r := range results {
+		content := r.Content
+		if opts.MaxContextTokens > 0 {
+			n := opts.Tokenizer.CountTokens(content)
+			if tokens+n > opts.MaxContextTokens {
+				if len(sources) > 0 {
+					break
+				}
+				content = opts.Tokenizer.Truncate(content, opts.MaxContextTokens)
+				n = opts.MaxContextTokens
+			}
+			tokens += n
+		}
+		sources = append(sources, Source{
+			Index:      len(sources) + 1,
+			ID:         r.ID,
+			Content:    content,
+			Metadata:   r.Metadata,
+			Similarity: r.Similarity,
+			Citation:   r.Citation,
+		})
+	}
+
+	sb := strings.Builder{}
+	err := opts.Template.Execute(&sb, TemplateData{
+		Query:   query,
+		Sources: sources,
+	})
+	if err != nil {
+		return Prompt{}, fmt.Errorf("couldn't execute prompt template: %w", err)
+	}
+
+	return Prompt{
+		Text:    sb.String(),
+		Sources: sources,
+	}, nil
+}
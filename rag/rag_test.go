@@ -0,0 +1,103 @@
+package rag
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func newCollection(t *testing.T) *chromem.Collection {
+	t.Helper()
+	vectors := map[string][]float32{
+		"What do cats eat?":         {1, 0},
+		"Cats eat fish.":            {0.9, 0.43588989},
+		"Cats also like chicken.":   {0.8, 0.6},
+		"Dogs bark at the mailman.": {0, 1},
+	}
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		return vectors[text], nil
+	}
+	c, err := chromem.NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(context.Background(), []chromem.Document{
+		{ID: "fish", Content: "Cats eat fish.", Metadata: map[string]string{"source": "a"}},
+		{ID: "chicken", Content: "Cats also like chicken.", Metadata: map[string]string{"source": "a"}},
+		{ID: "dogs", Content: "Dogs bark at the mailman.", Metadata: map[string]string{"source": "b"}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	return c
+}
+
+func sourceIDs(sources []Source) []string {
+	var ids []string
+	for _, s := range sources {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestBuildPrompt(t *testing.T) {
+	ctx := context.Background()
+	c := newCollection(t)
+
+	prompt, err := BuildPrompt(ctx, c, "What do cats eat?", Options{NResults: 2})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !slices.Equal([]string{"fish", "chicken"}, sourceIDs(prompt.Sources)) {
+		t.Fatal("unexpected sources", sourceIDs(prompt.Sources))
+	}
+	if prompt.Sources[1].Index != 2 || prompt.Sources[1].Metadata["source"] != "a" {
+		t.Fatalf("unexpected source %+v", prompt.Sources[1])
+	}
+	if !strings.Contains(prompt.Text, "[1] Cats eat fish.\n[2] Cats also like chicken.\n") || !strings.HasSuffix(prompt.Text, "Question: What do cats eat?") {
+		t.Fatal("unexpected prompt", prompt.Text)
+	}
+
+	// Dedupe and custom template
+	prompt, err = BuildPrompt(ctx, c, "What do cats eat?", Options{
+		NResults: 3,
+		DedupeBy: "source",
+		Template: template.Must(template.New("").Parse("{{range .Sources}}{{.ID}};{{end}}")),
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if prompt.Text != "fish;dogs;" {
+		t.Fatal("unexpected prompt", prompt.Text)
+	}
+
+	// Rerank and token budget
+	prompt, err = BuildPrompt(ctx, c, "What do cats eat?", Options{
+		NResults: 3,
+		Rerank: func(_ context.Context, _ string, results []chromem.Result) ([]chromem.Result, error) {
+			slices.Reverse(results)
+			return results, nil
+		},
+		// The heuristic tokenizer counts 4 characters per token
+		MaxContextTokens: 10,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !slices.Equal([]string{"dogs"}, sourceIDs(prompt.Sources)) {
+		t.Fatal("unexpected sources", sourceIDs(prompt.Sources))
+	}
+
+	// The first source is truncated if it doesn't fit
+	prompt, err = BuildPrompt(ctx, c, "What do cats eat?", Options{MaxContextTokens: 1})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(prompt.Sources) != 1 || prompt.Sources[0].Content != "Cats" {
+		t.Fatalf("unexpected sources %+v", prompt.Sources)
+	}
+}
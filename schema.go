@@ -0,0 +1,184 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// MetadataType is the type of a metadata value in a [MetadataSchema]. As
+// metadata values are strings, it defines how they must be formatted.
+type MetadataType string
+
+const (
+	// Any string. This is the default.
+	MetadataTypeString MetadataType = "string"
+	// An integer like "42", as parsed by [strconv.ParseInt].
+	MetadataTypeInt MetadataType = "int"
+	// A floating point number like "0.5", as parsed by [strconv.ParseFloat].
+	MetadataTypeFloat MetadataType = "float"
+	// "true" or "false".
+	MetadataTypeBool MetadataType = "bool"
+	// A timestamp in RFC 3339 format like "2006-01-02T15:04:05Z".
+	MetadataTypeTime MetadataType = "time"
+)
+
+// MetadataField describes the values of a metadata key in a [MetadataSchema].
+type MetadataField struct {
+	// The type of the values. Optional, defaults to [MetadataTypeString].
+	Type MetadataType
+
+	// If Required is true, documents must have the key.
+	Required bool
+
+	// The values that are allowed. Optional, all values of the type are allowed
+	// if it's empty.
+	AllowedValues []string
+}
+
+// MetadataSchema is a schema for the metadata of the documents of a
+// collection, see [Collection.SetMetadataSchema].
+type MetadataSchema struct {
+	// The fields by metadata key.
+	Fields map[string]MetadataField
+
+	// If Strict is true, documents can't have keys that aren't in Fields.
+	Strict bool
+}
+
+// MetadataSchemaError is returned when a document's metadata doesn't match the
+// collection's [MetadataSchema].
+type MetadataSchemaError struct {
+	DocumentID string
+	// The metadata key that doesn't match the schema.
+	Key string
+	// Why the key doesn't match the schema.
+	Reason string
+}
+
+func (e *MetadataSchemaError) Error() string {
+	return fmt.Sprintf("metadata of document %q doesn't match the schema: key %q %s", e.DocumentID, e.Key, e.Reason)
+}
+
+// SetMetadataSchema sets the schema for the metadata of the collection's
+// documents. Documents that are added afterwards must match it, otherwise
+// adding them fails with a [MetadataSchemaError]. This prevents malformed
+// metadata, like a typo in a key or a number that can't be parsed, from being
+// stored silently and breaking filters later. Existing documents aren't
+// validated.
+// The schema is persisted. Nil disables the validation.
+func (c *Collection) SetMetadataSchema(schema *MetadataSchema) error {
+	schema, err := cloneMetadataSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	c.configLock.Lock()
+	c.config.MetadataSchema = schema
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// MetadataSchema returns a copy of the collection's metadata schema, or nil if
+// it has none.
+func (c *Collection) MetadataSchema() *MetadataSchema {
+	// The error can only occur for invalid schemas, which can't be set.
+	schema, _ := cloneMetadataSchema(c.getConfig().MetadataSchema)
+	return schema
+}
+
+// cloneMetadataSchema validates the schema and returns a deep copy of it.
+func cloneMetadataSchema(schema *MetadataSchema) (*MetadataSchema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	res := &MetadataSchema{
+		Fields: make(map[string]MetadataField, len(schema.Fields)),
+		Strict: schema.Strict,
+	}
+	for key, field := range schema.Fields {
+		if key == "" {
+			return nil, errors.New("metadata schema has an empty key")
+		}
+		if field.Type == "" {
+			field.Type = MetadataTypeString
+		}
+		if !slices.Contains(metadataTypes, field.Type) {
+			return nil, fmt.Errorf("unsupported metadata type %q of key %q", field.Type, key)
+		}
+		for _, v := range field.AllowedValues {
+			if !field.Type.matches(v) {
+				return nil, fmt.Errorf("allowed value %q of key %q isn't of type %s", v, key, field.Type)
+			}
+		}
+		field.AllowedValues = slices.Clone(field.AllowedValues)
+		res.Fields[key] = field
+	}
+	return res, nil
+}
+
+// validate returns an error if the metadata doesn't match the schema. A nil
+// schema matches all metadata.
+func (s *MetadataSchema) validate(docID string, metadata map[string]string) error {
+	if s == nil {
+		return nil
+	}
+	// Check the keys in a deterministic order, so the error is the same for
+	// the same metadata.
+	keys := make([]string, 0, len(s.Fields))
+	for key := range s.Fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		field := s.Fields[key]
+		value, ok := metadata[key]
+		if !ok {
+			if field.Required {
+				return &MetadataSchemaError{DocumentID: docID, Key: key, Reason: "is required"}
+			}
+			continue
+		}
+		if !field.Type.matches(value) {
+			return &MetadataSchemaError{DocumentID: docID, Key: key, Reason: fmt.Sprintf("has value %q, which isn't of type %s", value, field.Type)}
+		}
+		if len(field.AllowedValues) != 0 && !slices.Contains(field.AllowedValues, value) {
+			return &MetadataSchemaError{DocumentID: docID, Key: key, Reason: fmt.Sprintf("has value %q, which isn't one of the allowed values %q", value, field.AllowedValues)}
+		}
+	}
+	if s.Strict {
+		var unknown []string
+		for key := range metadata {
+			if _, ok := s.Fields[key]; !ok {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) != 0 {
+			slices.Sort(unknown)
+			return &MetadataSchemaError{DocumentID: docID, Key: unknown[0], Reason: "isn't in the schema"}
+		}
+	}
+	return nil
+}
+
+// metadataTypes are the supported metadata types.
+var metadataTypes = []MetadataType{MetadataTypeString, MetadataTypeInt, MetadataTypeFloat, MetadataTypeBool, MetadataTypeTime}
+
+// matches returns true if the value is formatted as the type.
+func (t MetadataType) matches(value string) bool {
+	var err error
+	switch t {
+	case MetadataTypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case MetadataTypeFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case MetadataTypeBool:
+		return value == "true" || value == "false"
+	case MetadataTypeTime:
+		_, err = time.Parse(time.RFC3339, value)
+	}
+	return err == nil
+}
@@ -0,0 +1,131 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCollection_SetMetadataSchema(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Invalid schemas
+	for _, schema := range []*MetadataSchema{
+		{Fields: map[string]MetadataField{"": {}}},
+		{Fields: map[string]MetadataField{"year": {Type: "date"}}},
+		{Fields: map[string]MetadataField{"year": {Type: MetadataTypeInt, AllowedValues: []string{"2024", "next"}}}},
+	} {
+		if err := c.SetMetadataSchema(schema); err == nil {
+			t.Fatal("expected error, got nil for", schema)
+		}
+	}
+
+	schema := &MetadataSchema{
+		Fields: map[string]MetadataField{
+			"type":      {Required: true, AllowedValues: []string{"faq", "article"}},
+			"year":      {Type: MetadataTypeInt},
+			"score":     {Type: MetadataTypeFloat},
+			"draft":     {Type: MetadataTypeBool},
+			"published": {Type: MetadataTypeTime},
+		},
+		Strict: true,
+	}
+	err = c.SetMetadataSchema(schema)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	tt := []struct {
+		name        string
+		metadata    map[string]string
+		expectedKey string
+	}{
+		{
+			name:     "valid",
+			metadata: map[string]string{"type": "faq", "year": "2024", "score": "0.5", "draft": "false", "published": "2024-01-02T03:04:05Z"},
+		},
+		{
+			name:        "missing required key",
+			metadata:    map[string]string{"year": "2024"},
+			expectedKey: "type",
+		},
+		{
+			name:        "value not allowed",
+			metadata:    map[string]string{"type": "blog"},
+			expectedKey: "type",
+		},
+		{
+			name:        "invalid int",
+			metadata:    map[string]string{"type": "faq", "year": "20x4"},
+			expectedKey: "year",
+		},
+		{
+			name:        "invalid float",
+			metadata:    map[string]string{"type": "faq", "score": "high"},
+			expectedKey: "score",
+		},
+		{
+			name:        "invalid bool",
+			metadata:    map[string]string{"type": "faq", "draft": "yes"},
+			expectedKey: "draft",
+		},
+		{
+			name:        "invalid time",
+			metadata:    map[string]string{"type": "faq", "published": "yesterday"},
+			expectedKey: "published",
+		},
+		{
+			name:        "unknown key",
+			metadata:    map[string]string{"type": "faq", "yaer": "2024"},
+			expectedKey: "yaer",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Metadata: tc.metadata})
+			if tc.expectedKey == "" {
+				if err != nil {
+					t.Fatal("expected no error, got", err)
+				}
+				return
+			}
+			schemaErr := &MetadataSchemaError{}
+			if !errors.As(err, &schemaErr) {
+				t.Fatal("expected schema error, got", err)
+			}
+			if schemaErr.Key != tc.expectedKey {
+				t.Fatalf("expected error for key %q, got %v", tc.expectedKey, err)
+			}
+		})
+	}
+
+	// The schema is persisted.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected, _ := cloneMetadataSchema(schema)
+	if got := db2.GetCollection("test", nil).MetadataSchema(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+
+	// Nil disables the validation.
+	err = c.SetMetadataSchema(nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{1, 0}, Metadata: map[string]string{"type": "blog"}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
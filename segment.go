@@ -0,0 +1,357 @@
+package chromem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// segmentDirName is the name of the directory in a collection's
+	// persistence directory that contains the segment files.
+	segmentDirName = "segments"
+	segmentExt     = ".seg"
+
+	// DefaultSegmentSize is the number of records per segment file if segment
+	// persistence is enabled for a collection that was persisted with segments
+	// before, but [PersistentDBOptions.SegmentSize] isn't set.
+	DefaultSegmentSize = 1000
+)
+
+// The operations of segment records.
+const (
+	segmentOpPut    byte = 1
+	segmentOpDelete byte = 2
+)
+
+// segmentStore persists the documents of a collection in append-only segment
+// files, each with up to a fixed number of records, instead of one file per
+// document. A record is:
+//
+//	operation byte, ID length (uvarint), ID, payload length (uvarint), payload, CRC-32
+//
+// The payload of a put is the document as written by [persistToWriter], and
+// delete records have no payload. The record headers serve as index of the
+// file: the location of each document's latest record is kept in memory, so
+// single documents can be read without scanning the segments, for example
+// for content spillover.
+//
+// Segments only grow, until [Collection.Compact] rewrites the live documents
+// into new segments.
+type segmentStore struct {
+	dir      string
+	compress bool
+	size     int
+	syncer   *fileSyncer
+
+	// The number of the segment that's appended to, and its number of records.
+	active        int
+	activeRecords int
+	// The location of each document's latest put record.
+	index map[string]segmentLocation
+	lock  sync.Mutex
+}
+
+type segmentLocation struct {
+	segment int
+	offset  int64
+	length  int
+}
+
+func newSegmentStore(collectionDir string, compress bool, size int, syncer *fileSyncer) *segmentStore {
+	return &segmentStore{
+		dir:      filepath.Join(collectionDir, segmentDirName),
+		compress: compress,
+		size:     size,
+		syncer:   syncer,
+		active:   1,
+		index:    make(map[string]segmentLocation),
+	}
+}
+
+// initSegments sets up the persistence in segments if the size is > 0, or if
+// the collection was persisted in segments before. It's a no-op for in-memory
+// collections.
+func (c *Collection) initSegments(size int) {
+	if c.persistDirectory == "" {
+		return
+	}
+	if size == 0 {
+		fi, err := os.Stat(filepath.Join(c.persistDirectory, segmentDirName))
+		if err != nil || !fi.IsDir() {
+			return
+		}
+		size = DefaultSegmentSize
+	}
+	c.segments = newSegmentStore(c.persistDirectory, c.compress, size, c.syncer)
+}
+
+// load replays all segments in order and applies their records to the
+// documents. A torn record at the end of the last segment, for example after
+// a crash during a write, is truncated. Any other corruption is an error.
+func (s *segmentStore) load(documents map[string]*Document) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	segments, err := s.segmentsLocked()
+	if err != nil {
+		return err
+	}
+	for i, segment := range segments {
+		path := s.path(segment)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("couldn't read segment: %w", err)
+		}
+		offset, records, err := s.replayLocked(segment, data, documents)
+		if err != nil {
+			if i != len(segments)-1 {
+				return fmt.Errorf("couldn't read segment %q: %w", path, err)
+			}
+			err = os.Truncate(path, int64(offset))
+			if err != nil {
+				return fmt.Errorf("couldn't truncate torn segment: %w", err)
+			}
+		}
+		s.active, s.activeRecords = segment, records
+	}
+	return nil
+}
+
+// replayLocked applies the records of the segment's data to the documents. It
+// returns the offset after the last valid record and the number of records.
+func (s *segmentStore) replayLocked(segment int, data []byte, documents map[string]*Document) (int, int, error) {
+	offset, records := 0, 0
+	for offset < len(data) {
+		d := binaryDecoder{b: data[offset:]}
+		op := d.bytes(1)
+		id := d.string()
+		payloadLength := d.length(1)
+		payloadStart := len(data) - len(d.b)
+		d.bytes(payloadLength)
+		checksum := d.bytes(4)
+		if d.err != nil {
+			return offset, records, d.err
+		}
+		end := len(data) - len(d.b)
+		if crc32.ChecksumIEEE(data[offset:end-4]) != binary.LittleEndian.Uint32(checksum) {
+			return offset, records, errors.New("checksum mismatch")
+		}
+
+		switch op[0] {
+		case segmentOpPut:
+			doc := &Document{}
+			err := readFromReader(bytes.NewReader(data[payloadStart:payloadStart+payloadLength]), doc, "")
+			if err != nil {
+				return offset, records, err
+			}
+			documents[id] = doc
+			s.index[id] = segmentLocation{segment: segment, offset: int64(payloadStart), length: payloadLength}
+		case segmentOpDelete:
+			delete(documents, id)
+			delete(s.index, id)
+		default:
+			return offset, records, fmt.Errorf("unknown operation %d", op[0])
+		}
+		offset = end
+		records++
+	}
+	return offset, records, nil
+}
+
+// put appends the document to the active segment.
+func (s *segmentStore) put(doc *Document) error {
+	payload := bytes.Buffer{}
+	err := persistToWriter(&payload, doc, s.compress, "")
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	loc, err := s.appendLocked(segmentOpPut, doc.ID, payload.Bytes())
+	if err != nil {
+		return err
+	}
+	s.index[doc.ID] = loc
+	return nil
+}
+
+// remove appends a delete record for the document, if it's in the segments.
+func (s *segmentStore) remove(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.index[id]; !ok {
+		return nil
+	}
+	_, err := s.appendLocked(segmentOpDelete, id, nil)
+	if err != nil {
+		return err
+	}
+	delete(s.index, id)
+	return nil
+}
+
+// appendLocked appends a record to the active segment, and starts a new one
+// if it's full. It returns the location of the payload.
+func (s *segmentStore) appendLocked(op byte, id string, payload []byte) (segmentLocation, error) {
+	if s.activeRecords >= s.size {
+		s.active++
+		s.activeRecords = 0
+	}
+
+	record := make([]byte, 0, 1+2*binary.MaxVarintLen64+len(id)+len(payload)+4)
+	record = append(record, op)
+	record = appendBinaryString(record, id)
+	record = binary.AppendUvarint(record, uint64(len(payload)))
+	payloadOffset := len(record)
+	record = append(record, payload...)
+	record = binary.LittleEndian.AppendUint32(record, crc32.ChecksumIEEE(record))
+
+	err := os.MkdirAll(s.dir, 0o700)
+	if err != nil {
+		return segmentLocation{}, fmt.Errorf("couldn't create segment directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path(s.active), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return segmentLocation{}, fmt.Errorf("couldn't open segment: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return segmentLocation{}, fmt.Errorf("couldn't get segment info: %w", err)
+	}
+	_, err = f.Write(record)
+	if err != nil {
+		return segmentLocation{}, fmt.Errorf("couldn't write to segment: %w", err)
+	}
+	err = s.syncer.syncFile(f)
+	if err != nil {
+		return segmentLocation{}, err
+	}
+	err = f.Close()
+	if err != nil {
+		return segmentLocation{}, fmt.Errorf("couldn't close segment: %w", err)
+	}
+	if fi.Size() == 0 {
+		err = s.syncer.syncDir(f.Name())
+		if err != nil {
+			return segmentLocation{}, err
+		}
+	}
+	s.activeRecords++
+
+	return segmentLocation{
+		segment: s.active,
+		offset:  fi.Size() + int64(payloadOffset),
+		length:  len(payload),
+	}, nil
+}
+
+// read reads the document from its latest record. It returns nil if the
+// document isn't in the segments.
+func (s *segmentStore) read(id string) (*Document, error) {
+	s.lock.Lock()
+	loc, ok := s.index[id]
+	s.lock.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	f, err := os.Open(s.path(loc.segment))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open segment: %w", err)
+	}
+	defer f.Close()
+	payload := make([]byte, loc.length)
+	_, err = f.ReadAt(payload, loc.offset)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read segment: %w", err)
+	}
+	doc := &Document{}
+	err = readFromReader(bytes.NewReader(payload), doc, "")
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// rewrite writes the documents into new segments and removes the old ones.
+// If it fails, the old segments are kept, so no documents are lost.
+func (s *segmentStore) rewrite(docs []*Document) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	old, err := s.segmentsLocked()
+	if err != nil {
+		return err
+	}
+	active, activeRecords, index := s.active, s.activeRecords, s.index
+	s.active++
+	s.activeRecords = 0
+	s.index = make(map[string]segmentLocation, len(docs))
+	for _, doc := range docs {
+		payload := bytes.Buffer{}
+		err := persistToWriter(&payload, doc, s.compress, "")
+		if err == nil {
+			s.index[doc.ID], err = s.appendLocked(segmentOpPut, doc.ID, payload.Bytes())
+		}
+		if err != nil {
+			// Remove the new segments and keep using the old ones.
+			for segment := active + 1; segment <= s.active; segment++ {
+				_ = removeFile(s.path(segment))
+			}
+			s.active, s.activeRecords, s.index = active, activeRecords, index
+			return err
+		}
+	}
+
+	for _, segment := range old {
+		err := removeFile(s.path(segment))
+		if err != nil {
+			return err
+		}
+	}
+	return s.syncer.syncDir(s.path(s.active))
+}
+
+// segmentsLocked returns the numbers of the existing segments, in ascending
+// order.
+func (s *segmentStore) segmentsLocked() ([]int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't read segment directory: %w", err)
+	}
+	var segments []int
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), segmentExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		segment, err := strconv.Atoi(name)
+		if err != nil || segment <= 0 {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	slices.Sort(segments)
+	return segments, nil
+}
+
+func (s *segmentStore) path(segment int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%08d", segment)+segmentExt)
+}
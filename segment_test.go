@@ -0,0 +1,201 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewPersistentDBWithOptions_Segments(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDBWithOptions(dir, PersistentDBOptions{SegmentSize: 2})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Content: "one", Metadata: map[string]string{"foo": "bar"}},
+		{ID: "2", Embedding: []float32{0, 1}, Content: "two"},
+		{ID: "3", Embedding: []float32{1, 0}, Content: "three"},
+		{ID: "4", Embedding: []float32{0, 1}, Content: "four"},
+	}
+	for _, doc := range docs {
+		err = c.AddDocument(ctx, doc)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Content: "one updated"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// 6 records in 3 segments, and no document files
+	segments, err := os.ReadDir(filepath.Join(c.persistDirectory, segmentDirName))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(segments) != 3 {
+		t.Fatal("expected 3 segments, got", len(segments))
+	}
+	if _, err := os.Stat(c.getDocPath("1")); !os.IsNotExist(err) {
+		t.Fatal("expected no document file, got", err)
+	}
+
+	checkDocuments := func(t *testing.T, c *Collection) {
+		t.Helper()
+		if c.Count() != 3 {
+			t.Fatal("expected 3 documents, got", c.Count())
+		}
+		if c.documents["1"].Content != "one updated" || c.documents["1"].Metadata != nil {
+			t.Fatalf("expected updated document 1, got %+v", c.documents["1"])
+		}
+		if !reflect.DeepEqual(docs[2], *c.documents["3"]) {
+			t.Fatalf("expected %+v, got %+v", docs[2], *c.documents["3"])
+		}
+		if _, ok := c.documents["2"]; ok {
+			t.Fatal("expected document 2 to be deleted")
+		}
+	}
+
+	// The collection is loaded from the segments, even without the option
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	checkDocuments(t, c)
+	if c.segments == nil || c.segments.size != DefaultSegmentSize {
+		t.Fatal("expected segments with default size")
+	}
+
+	// Compaction drops overwritten and deleted records
+	stats, err := c.Compact(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats.BytesReclaimed <= 0 {
+		t.Fatal("expected reclaimed bytes, got", stats.BytesReclaimed)
+	}
+	segments, err = os.ReadDir(filepath.Join(c.persistDirectory, segmentDirName))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(segments) != 1 {
+		t.Fatal("expected 1 segment, got", len(segments))
+	}
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	checkDocuments(t, db.GetCollection("test", nil))
+
+	// A torn record at the end is truncated
+	segmentPath := filepath.Join(c.persistDirectory, segmentDirName, segments[0].Name())
+	before, err := os.ReadFile(segmentPath)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = os.WriteFile(segmentPath, append(before, segmentOpPut, 1, '5', 100, 'x'), 0o600)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	checkDocuments(t, db.GetCollection("test", nil))
+	after, err := os.ReadFile(segmentPath)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("expected torn record to be truncated to %d bytes, got %d", len(before), len(after))
+	}
+}
+
+func TestNewPersistentDBWithOptions_Segments_Migration(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Content: "one"},
+		{ID: "2", Embedding: []float32{0, 1}, Content: "two"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	db, err = NewPersistentDBWithOptions(dir, PersistentDBOptions{Compress: true, SegmentSize: 10})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	err = c.AddDocument(ctx, Document{ID: "3", Embedding: []float32{1, 0}, Content: "three"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Deleting a document that was persisted as file removes the file
+	err = c.Delete(ctx, nil, nil, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, err := os.Stat(c.getDocPath("1")); !os.IsNotExist(err) {
+		t.Fatal("expected document file to be removed, got", err)
+	}
+	// Spilled over contents are read from files and segments
+	err = c.SetContentSpillover(true, 0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, id := range []string{"2", "3"} {
+		doc, err := c.readPersistedDocument(id)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if doc.ID != id {
+			t.Fatal("expected document", id, "got", doc.ID)
+		}
+	}
+
+	// Compaction moves all documents to segments
+	_, err = c.Compact(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, err := os.Stat(c.getDocPath("2")); !os.IsNotExist(err) {
+		t.Fatal("expected document file to be removed, got", err)
+	}
+	db, err = NewPersistentDB(dir, true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	if c.Count() != 2 {
+		t.Fatal("expected 2 documents, got", c.Count())
+	}
+	res, err := c.QueryEmbedding(ctx, []float32{0, 1}, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "2" || res[0].Content != "two" {
+		t.Fatalf("expected document 2, got %+v", res[0])
+	}
+}
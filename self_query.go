@@ -0,0 +1,171 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// SelfQueryField describes a metadata key that queries can be filtered by, for
+// the parser of a self-query, see [Collection.ParseSelfQuery].
+type SelfQueryField struct {
+	Key string
+
+	// A description of the key for the parser, for example "the year the
+	// document was published". Optional.
+	Description string
+
+	// The type of the values. Optional, defaults to [MetadataTypeString].
+	Type MetadataType
+
+	// The values that are allowed. Optional, all values of the type are allowed
+	// if it's empty.
+	AllowedValues []string
+}
+
+// SelfQuery is a natural language query that's split into the part for the
+// semantic search and the metadata filter, see [Collection.ParseSelfQuery].
+type SelfQuery struct {
+	// The query without the constraints, for example "documents about vector
+	// databases" for "documents about vector databases from 2023 by Alice".
+	Query string
+
+	// The metadata filter for the constraints, for example
+	// {"year": "2023", "author": "Alice"}.
+	Where map[string]string
+
+	// The constraints of the parser that were ignored, because their key isn't
+	// one of the fields or their value doesn't match the field, or because the
+	// query filters by the key explicitly already (see [SelfQueryStage]).
+	Ignored map[string]string
+}
+
+// SelfQueryParser parses the constraints on the fields out of the query, for
+// example with an LLM that's prompted with the fields and asked to answer in
+// JSON.
+type SelfQueryParser func(ctx context.Context, query string, fields []SelfQueryField) (SelfQuery, error)
+
+// ParseSelfQuery parses constraints like "from 2023 by Alice" out of the
+// natural language query into a metadata filter with the parser, so they don't
+// need to be set as filters explicitly. The result can be shown to users for
+// transparency, and its Query and Where used for [QueryOptions].
+//
+// The constraints are validated against the fields, as parsers like LLMs can
+// make mistakes, and invalid constraints are ignored. If fields is nil, they're
+// derived from the collection's [MetadataSchema]. If the parsed query is empty,
+// the original query is kept.
+func (c *Collection) ParseSelfQuery(ctx context.Context, query string, fields []SelfQueryField, parse SelfQueryParser) (SelfQuery, error) {
+	if query == "" {
+		return SelfQuery{}, errors.New("query is empty")
+	}
+	if fields == nil {
+		fields = c.selfQueryFields()
+	}
+	if len(fields) == 0 {
+		return SelfQuery{}, errors.New("no fields to filter by")
+	}
+
+	parsed, err := parse(ctx, query, slices.Clone(fields))
+	if err != nil {
+		return SelfQuery{}, fmt.Errorf("couldn't parse self-query: %w", err)
+	}
+	res := SelfQuery{Query: parsed.Query}
+	if res.Query == "" {
+		res.Query = query
+	}
+	for key, value := range parsed.Where {
+		i := slices.IndexFunc(fields, func(f SelfQueryField) bool { return f.Key == key })
+		if i == -1 || !fields[i].matches(value) {
+			if res.Ignored == nil {
+				res.Ignored = make(map[string]string)
+			}
+			res.Ignored[key] = value
+			continue
+		}
+		if res.Where == nil {
+			res.Where = make(map[string]string)
+		}
+		res.Where[key] = value
+	}
+	return res, nil
+}
+
+// selfQueryFields returns the fields of the collection's metadata schema,
+// sorted by key.
+func (c *Collection) selfQueryFields() []SelfQueryField {
+	schema := c.getConfig().MetadataSchema
+	if schema == nil {
+		return nil
+	}
+	res := make([]SelfQueryField, 0, len(schema.Fields))
+	for key, field := range schema.Fields {
+		res = append(res, SelfQueryField{Key: key, Type: field.Type, AllowedValues: slices.Clone(field.AllowedValues)})
+	}
+	slices.SortFunc(res, func(a, b SelfQueryField) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return res
+}
+
+func (f SelfQueryField) matches(value string) bool {
+	typ := f.Type
+	if typ == "" {
+		typ = MetadataTypeString
+	}
+	if !typ.matches(value) {
+		return false
+	}
+	return len(f.AllowedValues) == 0 || slices.Contains(f.AllowedValues, value)
+}
+
+// SelfQueryStage returns a [QueryStage] that parses the constraints out of the
+// query text like [Collection.ParseSelfQuery], replaces the query text with
+// the rest of the query, and adds the constraints to the query's filter.
+// Constraints on keys that the query filters by already are ignored, so
+// explicit filters take precedence. The parsed self-query is set in
+// [PipelineQuery.SelfQuery]. It does nothing if the query has no text.
+func SelfQueryStage(fields []SelfQueryField, parse SelfQueryParser) QueryStage {
+	return func(ctx context.Context, c *Collection, q *PipelineQuery) error {
+		if q.Options.QueryText == "" {
+			return nil
+		}
+		selfQuery, err := c.ParseSelfQuery(ctx, q.Options.QueryText, fields, parse)
+		if err != nil {
+			return err
+		}
+
+		where := maps.Clone(q.Options.Where)
+		filter := q.Options.Filter
+		for key, value := range selfQuery.Where {
+			_, explicit := q.Options.Where[key]
+			if _, ok := filter.metadataValue(key); ok || explicit {
+				delete(selfQuery.Where, key)
+				if selfQuery.Ignored == nil {
+					selfQuery.Ignored = make(map[string]string)
+				}
+				selfQuery.Ignored[key] = value
+				continue
+			}
+			if filter != nil {
+				filter = filter.withMetadataCondition(key, value)
+				continue
+			}
+			if where == nil {
+				where = make(map[string]string)
+			}
+			where[key] = value
+		}
+
+		q.Options.Where = where
+		q.Options.Filter = filter
+		if selfQuery.Query != q.Options.QueryText {
+			q.Options.QueryText = selfQuery.Query
+			q.Options.QueryEmbedding = nil
+		}
+		q.SelfQuery = &selfQuery
+		return nil
+	}
+}
@@ -0,0 +1,126 @@
+package chromem
+
+import (
+	"context"
+	"maps"
+	"testing"
+)
+
+func TestCollection_ParseSelfQuery(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{0, 1}, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollectionWithOptions("test", CollectionOptions{
+		EmbeddingFunc: embeddingFunc,
+		MetadataSchema: &MetadataSchema{Fields: map[string]MetadataField{
+			"year":   {Type: MetadataTypeInt},
+			"author": {AllowedValues: []string{"Alice", "Bob"}},
+		}},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	var gotFields []SelfQueryField
+	parse := func(_ context.Context, query string, fields []SelfQueryField) (SelfQuery, error) {
+		gotFields = fields
+		return SelfQuery{
+			Query: "vector databases",
+			Where: map[string]string{"year": "2023", "author": "Alice", "topic": "go", "month": "May"},
+		}, nil
+	}
+	res, err := c.ParseSelfQuery(ctx, "vector databases from 2023 by Alice", nil, parse)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(gotFields) != 2 || gotFields[0].Key != "author" || gotFields[1].Key != "year" || gotFields[1].Type != MetadataTypeInt {
+		t.Fatal("expected the fields of the schema, got", gotFields)
+	}
+	if res.Query != "vector databases" {
+		t.Fatal("expected the query without constraints, got", res.Query)
+	}
+	if expected := map[string]string{"year": "2023", "author": "Alice"}; !maps.Equal(res.Where, expected) {
+		t.Fatal("expected", expected, "got", res.Where)
+	}
+	if expected := map[string]string{"topic": "go", "month": "May"}; !maps.Equal(res.Ignored, expected) {
+		t.Fatal("expected", expected, "to be ignored, got", res.Ignored)
+	}
+
+	// Invalid values are ignored.
+	res, err = c.ParseSelfQuery(ctx, "by Carol in the year 2k", []SelfQueryField{{Key: "year", Type: MetadataTypeInt}, {Key: "author", AllowedValues: []string{"Alice"}}},
+		func(context.Context, string, []SelfQueryField) (SelfQuery, error) {
+			return SelfQuery{Where: map[string]string{"year": "2k", "author": "Carol"}}, nil
+		})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res.Query != "by Carol in the year 2k" || len(res.Where) != 0 || len(res.Ignored) != 2 {
+		t.Fatal("expected the original query without filter, got", res)
+	}
+
+	_, err = c.ParseSelfQuery(ctx, "query", []SelfQueryField{}, parse)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestSelfQueryStage(t *testing.T) {
+	ctx := context.Background()
+	var embedded []string
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		embedded = append(embedded, text)
+		return []float32{0, 1}, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{0, 1}, Metadata: map[string]string{"year": "2023", "author": "Alice", "lang": "en"}},
+		{ID: "2", Embedding: []float32{0, 1}, Metadata: map[string]string{"year": "2023", "author": "Bob", "lang": "en"}},
+		{ID: "3", Embedding: []float32{0, 1}, Metadata: map[string]string{"year": "2022", "author": "Alice", "lang": "de"}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	fields := []SelfQueryField{{Key: "year"}, {Key: "author"}, {Key: "lang"}}
+	parse := func(context.Context, string, []SelfQueryField) (SelfQuery, error) {
+		return SelfQuery{Query: "databases", Where: map[string]string{"year": "2023", "author": "Alice", "lang": "de"}}, nil
+	}
+	var q *PipelineQuery
+	capture := func(_ context.Context, _ *Collection, pq *PipelineQuery) error {
+		q = pq
+		return nil
+	}
+	p := NewQueryPipeline(SelfQueryStage(fields, parse), RetrieveStage(), capture)
+	res, err := p.Query(ctx, c, QueryOptions{QueryText: "databases from 2023 by Alice in German", NResults: 3, Where: map[string]string{"lang": "en"}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" {
+		t.Fatal("expected document 1, got", res)
+	}
+	if len(embedded) != 1 || embedded[0] != "databases" {
+		t.Fatal("expected the query without constraints to be embedded, got", embedded)
+	}
+	if q.SelfQuery == nil || q.SelfQuery.Ignored["lang"] != "de" || len(q.SelfQuery.Where) != 2 {
+		t.Fatal("expected the explicit filter to take precedence, got", q.SelfQuery)
+	}
+
+	// With a compiled filter
+	filter, err := CompileFilter(map[string]string{"lang": "en"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err = p.Query(ctx, c, QueryOptions{QueryText: "databases from 2023 by Alice", NResults: 3, Filter: filter})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" {
+		t.Fatal("expected document 1, got", res)
+	}
+}
@@ -0,0 +1,198 @@
+package chromem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync/atomic"
+)
+
+type sessionContextKey struct{}
+
+// ContextWithSession returns a copy of the context with the ID of the session,
+// for example a chat conversation, that queries and writes belong to. Queries
+// with a session use the collection's session cache, and adds with a session
+// only invalidate the cache of their session, see [Collection.SetSessionCache].
+func ContextWithSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sessionID)
+}
+
+// SessionFromContext returns the session ID of the context, or an empty string
+// if it has none.
+func SessionFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionContextKey{}).(string)
+	return sessionID
+}
+
+// SetSessionCache enables caching query results per session, for applications
+// that repeat queries in a session, like chat applications that retrieve
+// documents on every turn of a conversation. Queries belong to a session via
+// [ContextWithSession], and queries without a session aren't cached. The cache
+// keeps the results of up to maxQueries different queries for each of up to
+// maxSessions sessions, dropping the least recently used ones.
+//
+// Adds of new documents with a session, like documents that are added for a
+// conversation, only invalidate the cache of their session, so other sessions
+// don't see them until their caches are invalidated. All other changes, like
+// adds without a session, updates, deletes and changes of the collection's
+// configuration, invalidate the caches of all sessions, so cached results never
+// contain outdated or deleted documents. Invalidate a session explicitly with
+// [Collection.InvalidateSession], for example when it ends.
+//
+// Queries with a [QueryOptions.Filter], [QueryOptions.QueryMedia],
+// [QueryOptions.HyDE] or [QueryOptions.Limits] aren't cached. Cached results
+// aren't logged in the DB's query log and not counted in the variant stats
+// again. A maxSessions of 0 disables the cache. The cache isn't persisted.
+func (c *Collection) SetSessionCache(maxSessions, maxQueries int) error {
+	if maxSessions < 0 || maxQueries < 0 {
+		return errors.New("maxSessions and maxQueries must be >= 0")
+	}
+	var cache *sessionCache
+	if maxSessions > 0 && maxQueries > 0 {
+		cache = &sessionCache{
+			sessions:   newLRUCache[string, *lruCache[string, sessionCacheEntry]](maxSessions),
+			maxQueries: maxQueries,
+		}
+	}
+
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+
+	c.sessionCache = cache
+	return nil
+}
+
+// InvalidateSession drops the cached query results of the session, see
+// [Collection.SetSessionCache].
+func (c *Collection) InvalidateSession(sessionID string) {
+	c.getSessionCache().invalidate(sessionID)
+}
+
+func (c *Collection) getSessionCache() *sessionCache {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+
+	return c.sessionCache
+}
+
+// added invalidates the session caches after documents were added without
+// replacing others: The cache of the context's session, or all caches if the
+// context has no session.
+func (c *Collection) added(ctx context.Context) {
+	c.getSessionCache().invalidate(SessionFromContext(ctx))
+}
+
+// changed invalidates the caches of all sessions after documents were updated
+// or deleted, or the configuration of the collection changed.
+func (c *Collection) changed() {
+	c.getSessionCache().invalidate("")
+}
+
+// sessionCache caches query results per session. It's safe for concurrent use,
+// and the methods of a nil cache do nothing.
+type sessionCache struct {
+	// Session ID -> query key -> entry
+	sessions   *lruCache[string, *lruCache[string, sessionCacheEntry]]
+	maxQueries int
+	// Incremented by changes that invalidate all sessions. Entries of older
+	// generations are invalid.
+	generation atomic.Uint64
+}
+
+type sessionCacheEntry struct {
+	generation uint64
+	results    []Result
+	stats      QueryStats
+}
+
+// sessionCacheLookup is a lookup of a query in a session's cache.
+type sessionCacheLookup struct {
+	queries    *lruCache[string, sessionCacheEntry]
+	key        string
+	generation uint64
+}
+
+// lookup returns the cached results of the query, or a lookup to add them to
+// the cache with. The lookup is nil if the query can't be cached.
+func (s *sessionCache) lookup(sessionID string, options QueryOptions) ([]Result, QueryStats, bool, *sessionCacheLookup) {
+	if s == nil || sessionID == "" {
+		return nil, QueryStats{}, false, nil
+	}
+	key, ok := sessionCacheKey(options)
+	if !ok {
+		return nil, QueryStats{}, false, nil
+	}
+	// The generation is loaded before the documents are read by the query, so
+	// results of a query that overlaps with a change are never valid.
+	l := &sessionCacheLookup{key: key, generation: s.generation.Load()}
+	queries, ok := s.sessions.get(sessionID)
+	if !ok {
+		queries = newLRUCache[string, sessionCacheEntry](s.maxQueries)
+		s.sessions.add(sessionID, queries)
+	}
+	l.queries = queries
+	if entry, ok := queries.get(key); ok && entry.generation == l.generation {
+		stats := entry.stats
+		stats.QueryID = ""
+		return slices.Clone(entry.results), stats, true, l
+	}
+	return nil, QueryStats{}, false, l
+}
+
+// add caches the results of the looked up query. It does nothing if the lookup
+// is nil.
+func (l *sessionCacheLookup) add(results []Result, stats QueryStats) {
+	if l == nil {
+		return
+	}
+	l.queries.add(l.key, sessionCacheEntry{generation: l.generation, results: slices.Clone(results), stats: stats})
+}
+
+// invalidate drops the cache of the session, or the caches of all sessions if
+// the session ID is empty.
+func (s *sessionCache) invalidate(sessionID string) {
+	if s == nil {
+		return
+	}
+	if sessionID == "" {
+		s.generation.Add(1)
+		return
+	}
+	s.sessions.remove(sessionID)
+}
+
+// sessionCacheKey returns the key of the query for the session cache, or false
+// if it can't be cached.
+func sessionCacheKey(options QueryOptions) (string, bool) {
+	if options.Filter != nil || options.QueryMedia != nil || options.HyDE.Generate != nil || options.Limits != (QueryLimits{}) {
+		return "", false
+	}
+	b, err := json.Marshal(struct {
+		QueryText       string
+		QueryEmbedding  []float32
+		NResults        int
+		Where           map[string]string
+		WhereDocument   map[string]string
+		Negative        NegativeQueryOptions
+		Concepts        []QueryConcept
+		SnippetSize     int
+		DedupeBy        string
+		TopKAlgorithm   TopKAlgorithm
+		Variant         string
+		Exhaustive      bool
+		SameLanguage    bool
+		IncludeArchived bool
+	}{
+		options.QueryText, options.QueryEmbedding, options.NResults, options.Where, options.WhereDocument,
+		options.Negative, options.Concepts, options.SnippetSize, options.DedupeBy, options.TopKAlgorithm,
+		options.Variant, options.Exhaustive, options.SameLanguage, options.IncludeArchived,
+	})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), true
+}
@@ -0,0 +1,156 @@
+package chromem
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCollection_SetSessionCache(t *testing.T) {
+	ctx := context.Background()
+	var embedCalls atomic.Int32
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		embedCalls.Add(1)
+		return []float32{1, 0}, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{0.6, 0.8}, Content: "foo"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.SetSessionCache(-1, 10)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.SetSessionCache(10, 10)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// query returns the ID of the top result and whether the query was cached,
+	// which is the case if the query text wasn't embedded.
+	query := func(ctx context.Context) (string, bool) {
+		t.Helper()
+		before := embedCalls.Load()
+		res, err := c.Query(ctx, "query", 1, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		return res[0].ID, embedCalls.Load() == before
+	}
+
+	ctxA := ContextWithSession(ctx, "a")
+	ctxB := ContextWithSession(ctx, "b")
+	if SessionFromContext(ctxA) != "a" || SessionFromContext(ctx) != "" {
+		t.Fatal("expected session IDs from context")
+	}
+
+	if _, cached := query(ctxA); cached {
+		t.Fatal("expected first query not to be cached")
+	}
+	if id, cached := query(ctxA); !cached || id != "1" {
+		t.Fatal("expected second query to be cached with document 1, got", id, cached)
+	}
+	if _, cached := query(ctx); cached {
+		t.Fatal("expected query without session not to be cached")
+	}
+	if _, cached := query(ctxB); cached {
+		t.Fatal("expected first query of other session not to be cached")
+	}
+
+	// Adds with a session only invalidate the session.
+	err = c.AddDocument(ctxA, Document{ID: "2", Embedding: []float32{1, 0}, Content: "bar"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if id, cached := query(ctxA); cached || id != "2" {
+		t.Fatal("expected query of invalidated session not to be cached with document 2, got", id, cached)
+	}
+	if id, cached := query(ctxB); !cached || id != "1" {
+		t.Fatal("expected query of other session to be cached with document 1, got", id, cached)
+	}
+
+	// Deletes invalidate all sessions, also with a session.
+	query(ctxA)
+	query(ctxB)
+	err = c.Delete(ctxA, nil, nil, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, ctx := range []context.Context{ctxA, ctxB} {
+		if id, cached := query(ctx); cached || id != "1" {
+			t.Fatal("expected query not to be cached with document 1, got", id, cached)
+		}
+	}
+
+	// Updates invalidate all sessions, also with a session.
+	err = c.AddDocument(ctxA, Document{ID: "1", Embedding: []float32{0.6, 0.8}, Content: "updated"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err := c.Query(ctxB, "query", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].Content != "updated" {
+		t.Fatal("expected updated content in other session, got", res[0].Content)
+	}
+
+	// Adds without session invalidate all sessions.
+	query(ctxA)
+	query(ctxB)
+	err = c.AddDocument(ctx, Document{ID: "3", Embedding: []float32{0, 1}, Content: "baz"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, ctx := range []context.Context{ctxA, ctxB} {
+		if _, cached := query(ctx); cached {
+			t.Fatal("expected query not to be cached after add without session")
+		}
+	}
+
+	// Configuration changes invalidate all sessions.
+	err = c.SetBoostRules(nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, cached := query(ctxA); cached {
+		t.Fatal("expected query not to be cached after configuration change")
+	}
+
+	c.InvalidateSession("a")
+	if _, cached := query(ctxA); cached {
+		t.Fatal("expected query of invalidated session not to be cached")
+	}
+
+	// Queries with a filter aren't cached.
+	filter, err := CompileFilter(nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < 2; i++ {
+		before := embedCalls.Load()
+		_, err = c.QueryWithOptions(ctxA, QueryOptions{QueryText: "query", NResults: 1, Filter: filter})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if embedCalls.Load() == before {
+			t.Fatal("expected query with filter not to be cached")
+		}
+	}
+
+	// Disabling the cache.
+	err = c.SetSessionCache(0, 0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	query(ctxA)
+	if _, cached := query(ctxA); cached {
+		t.Fatal("expected query not to be cached with disabled cache")
+	}
+}
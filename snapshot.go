@@ -0,0 +1,124 @@
+package chromem
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// Snapshot is a read-only view of a collection's documents at a point in time,
+// see [Collection.Snapshot].
+type Snapshot struct {
+	// The time when the snapshot was taken.
+	CreatedAt time.Time
+
+	// A copy of the collection with the documents at the time of the snapshot.
+	// It's never modified.
+	c *Collection
+}
+
+// Snapshot returns a snapshot of the collection's documents, for queries and
+// iterations that see a consistent state, while documents are added to and
+// deleted from the collection concurrently. For example, a long export or a
+// paginated query don't see some of the changes but not others. The snapshot
+// can be kept to query the collection as of the time it was taken.
+//
+// Taking a snapshot copies the map of documents, the partitions (see
+// [Collection.PartitionBy]) and the vector index (see [Collection.SetIndex]),
+// but not the documents, as they're replaced instead of modified. So it takes
+// time and memory proportional to the number of documents, but not to their
+// size, and changes of the collection wait for it. With content spillover (see
+// [Collection.SetContentSpillover]), the contents are read from disk when the
+// snapshot is taken, as the files of updated and deleted documents change, so
+// it then also takes time and memory proportional to their size.
+//
+// Some parts aren't copied: The snapshot uses the collection's embedding
+// functions and configuration of the time it was taken, it shares the feedback
+// and access stats with the collection, and it doesn't contain the archived
+// documents (see [Collection.Archive]). A DiskANN index (see
+// [IndexTypeDiskANN]) shares its file with the collection's, so once the
+// collection's index is rebuilt or dropped, queries of the snapshot scan all
+// documents.
+func (c *Collection) Snapshot() (*Snapshot, error) {
+	c.configLock.RLock()
+	clone := &Collection{
+		Name:            c.Name,
+		metadata:        c.metadata,
+		embed:           c.embed,
+		embedMultimodal: c.embedMultimodal,
+		db:              c.db,
+		config:          c.config,
+		feedback:        c.feedback,
+		variantStats:    c.variantStats,
+		accessStats:     c.accessStats,
+	}
+	c.configLock.RUnlock()
+	// Without spilled contents, as they're read below.
+	clone.config.ContentSpillover = false
+
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	if c.contentCache == nil {
+		clone.documents = maps.Clone(c.documents)
+	} else {
+		clone.documents = make(map[string]*Document, len(c.documents))
+		for id, doc := range c.documents {
+			// Not via withContentLocked, to not evict the cached contents of
+			// frequently retrieved documents.
+			content, ok := c.contentCache.get(id)
+			if !ok {
+				persisted, err := c.readPersistedDocument(id)
+				if err != nil {
+					return nil, fmt.Errorf("couldn't read content of document %q: %w", id, err)
+				}
+				content = persisted.Content
+			}
+			withContent := *doc
+			withContent.Content = content
+			clone.documents[id] = &withContent
+		}
+	}
+	if c.index != nil {
+		clone.index = c.index.clone()
+	}
+	clone.partitions = c.partitions.clone()
+	return &Snapshot{
+		CreatedAt: time.Now(),
+		c:         clone,
+	}, nil
+}
+
+// Name returns the name of the collection.
+func (s *Snapshot) Name() string {
+	return s.c.Name
+}
+
+// Count returns the number of documents in the snapshot.
+func (s *Snapshot) Count() int {
+	return s.c.Count()
+}
+
+// Query is like [Collection.Query], for the documents of the snapshot.
+func (s *Snapshot) Query(ctx context.Context, queryText string, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	return s.c.Query(ctx, queryText, nResults, where, whereDocument)
+}
+
+// QueryWithOptions is like [Collection.QueryWithOptions], for the documents of
+// the snapshot.
+func (s *Snapshot) QueryWithOptions(ctx context.Context, options QueryOptions) ([]Result, error) {
+	return s.c.QueryWithOptions(ctx, options)
+}
+
+// QueryWithStats is like [Collection.QueryWithStats], for the documents of the
+// snapshot.
+func (s *Snapshot) QueryWithStats(ctx context.Context, options QueryOptions) ([]Result, QueryStats, error) {
+	return s.c.QueryWithStats(ctx, options)
+}
+
+// QueryEmbedding is like [Collection.QueryEmbedding], for the documents of the
+// snapshot.
+func (s *Snapshot) QueryEmbedding(ctx context.Context, queryEmbedding []float32, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	return s.c.QueryEmbedding(ctx, queryEmbedding, nResults, where, whereDocument)
+}
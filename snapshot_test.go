@@ -0,0 +1,150 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCollection_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	for _, spillover := range []bool{false, true} {
+		db, err := NewPersistentDB(t.TempDir(), false)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		c, err := db.CreateCollectionWithOptions("test", CollectionOptions{ContentSpillover: spillover})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = c.AddDocuments(ctx, []Document{
+			{ID: "1", Embedding: []float32{1, 0}, Content: "one"},
+			{ID: "2", Embedding: []float32{0.6, 0.8}, Content: "two"},
+		}, 1)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+
+		s, err := c.Snapshot()
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+
+		// Changes after the snapshot
+		err = c.AddDocument(ctx, Document{ID: "3", Embedding: []float32{1, 0}, Content: "three"})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{0, 1}, Content: "two v2"})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+
+		if s.Count() != 2 {
+			t.Fatal("expected 2 documents in snapshot, got", s.Count())
+		}
+		if c.Count() != 3 {
+			t.Fatal("expected 3 documents in collection, got", c.Count())
+		}
+		res, err := s.QueryEmbedding(ctx, []float32{1, 0}, 2, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 2 || res[0].ID != "1" || res[1].ID != "2" {
+			t.Fatal("expected documents 1 and 2, got", res)
+		}
+		// The previous embedding of document 2
+		if res[1].Similarity < 0.59 || res[1].Similarity > 0.61 {
+			t.Fatal("expected similarity 0.6 of previous document 2, got", res[1].Similarity)
+		}
+		if res[1].Content != "two" {
+			t.Fatal("expected previous content of document 2, got", res[1].Content)
+		}
+
+		// Deletes after the snapshot
+		err = c.Delete(ctx, nil, nil, "1")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		res, err = s.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 1})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 1 || res[0].ID != "1" || res[0].Content != "one" {
+			t.Fatal("expected document 1, got", res)
+		}
+	}
+}
+
+func TestCollection_Snapshot_index(t *testing.T) {
+	ctx := context.Background()
+	for _, indexType := range []IndexType{indexTypeTestAll, IndexTypeIVF, IndexTypeDiskANN} {
+		t.Run(string(indexType), func(t *testing.T) {
+			db, err := NewPersistentDB(t.TempDir(), false)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			c, err := db.CreateCollection("test", nil, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.AddDocuments(ctx, []Document{
+				{ID: "1", Embedding: []float32{1, 0}, Metadata: map[string]string{"tenant": "a"}},
+				{ID: "2", Embedding: []float32{0.6, 0.8}, Metadata: map[string]string{"tenant": "b"}},
+			}, 1)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.PartitionBy("tenant")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.SetIndex(ctx, IndexOptions{Type: indexType, Lists: 1})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+
+			s, err := c.Snapshot()
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+
+			// Changes of the index and partitions after the snapshot
+			err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{0, 1}, Metadata: map[string]string{"tenant": "b"}})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.Delete(ctx, nil, nil, "2")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+
+			query := func() {
+				t.Helper()
+				res, err := s.QueryEmbedding(ctx, []float32{1, 0}, 1, map[string]string{"tenant": "a"}, nil)
+				if err != nil {
+					t.Fatal("expected no error, got", err)
+				}
+				if len(res) != 1 || res[0].ID != "1" || res[0].Similarity < 0.99 {
+					t.Fatal("expected previous document 1, got", res)
+				}
+				res, err = s.QueryEmbedding(ctx, []float32{0, 1}, 2, nil, nil)
+				if err != nil {
+					t.Fatal("expected no error, got", err)
+				}
+				if len(res) != 2 || res[0].ID != "2" || res[1].ID != "1" {
+					t.Fatal("expected documents 2 and 1, got", res)
+				}
+			}
+			query()
+
+			// Rebuilding the index of the collection doesn't break the
+			// snapshot, which scans all documents instead if it shares the
+			// dropped index file.
+			err = c.RebuildIndex(ctx)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			query()
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package chromem
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetSeparator separates the windows of a snippet, and marks content that
+// was cut off at the start or end.
+const snippetSeparator = "…"
+
+// snippet returns a shortened version of the content with windows of about
+// size bytes around the highlights, and the highlights with their positions in
+// the snippet. Overlapping windows are merged, and windows are cut at rune
+// boundaries. Without highlights, the snippet is the start of the content.
+func snippet(content string, highlights []Highlight, size int) (string, []Highlight) {
+	if len(highlights) == 0 {
+		end := runeEnd(content, size)
+		if end == len(content) {
+			return content, nil
+		}
+		return content[:end] + snippetSeparator, nil
+	}
+
+	// The windows around the highlights, merged if they overlap
+	type window struct{ start, end int }
+	var windows []window
+	for _, h := range highlights {
+		padding := max(0, size-(h.End-h.Start)) / 2
+		w := window{
+			start: runeStart(content, h.Start-padding),
+			end:   runeEnd(content, h.End+padding),
+		}
+		if len(windows) > 0 && w.start <= windows[len(windows)-1].end {
+			windows[len(windows)-1].end = max(windows[len(windows)-1].end, w.end)
+			continue
+		}
+		windows = append(windows, w)
+	}
+
+	sb := strings.Builder{}
+	res := make([]Highlight, 0, len(highlights))
+	hIdx := 0
+	for _, w := range windows {
+		if w.start > 0 {
+			sb.WriteString(snippetSeparator)
+		}
+		offset := sb.Len() - w.start
+		sb.WriteString(content[w.start:w.end])
+		for ; hIdx < len(highlights) && highlights[hIdx].Start < w.end; hIdx++ {
+			res = append(res, Highlight{
+				Start: highlights[hIdx].Start + offset,
+				End:   highlights[hIdx].End + offset,
+			})
+		}
+	}
+	if windows[len(windows)-1].end < len(content) {
+		sb.WriteString(snippetSeparator)
+	}
+	return sb.String(), res
+}
+
+// runeStart returns the position i, clamped to the content and moved back to
+// the start of the rune it's in.
+func runeStart(content string, i int) int {
+	if i <= 0 {
+		return 0
+	}
+	if i >= len(content) {
+		return len(content)
+	}
+	for i > 0 && !utf8.RuneStart(content[i]) {
+		i--
+	}
+	return i
+}
+
+// runeEnd returns the position i, clamped to the content and moved forward to
+// the end of the rune before it.
+func runeEnd(content string, i int) int {
+	if i <= 0 {
+		return 0
+	}
+	if i >= len(content) {
+		return len(content)
+	}
+	for i < len(content) && !utf8.RuneStart(content[i]) {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,109 @@
+package chromem
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSnippet(t *testing.T) {
+	tt := []struct {
+		name               string
+		content            string
+		highlights         []Highlight
+		size               int
+		expected           string
+		expectedHighlights []Highlight
+	}{
+		{
+			name:     "short content without highlights",
+			content:  "hello world",
+			size:     20,
+			expected: "hello world",
+		},
+		{
+			name:     "long content without highlights",
+			content:  "hello world",
+			size:     5,
+			expected: "hello…",
+		},
+		{
+			name:               "single highlight in the middle",
+			content:            "the quick brown fox jumps over the lazy dog",
+			highlights:         []Highlight{{Start: 16, End: 19}},
+			size:               9,
+			expected:           "…wn fox ju…",
+			expectedHighlights: []Highlight{{Start: 6, End: 9}},
+		},
+		{
+			name:               "overlapping windows are merged",
+			content:            "the quick brown fox jumps over the lazy dog",
+			highlights:         []Highlight{{Start: 10, End: 15}, {Start: 16, End: 19}},
+			size:               9,
+			expected:           "…k brown fox ju…",
+			expectedHighlights: []Highlight{{Start: 5, End: 10}, {Start: 11, End: 14}},
+		},
+		{
+			name:               "separate windows",
+			content:            "the quick brown fox jumps over the lazy dog",
+			highlights:         []Highlight{{Start: 0, End: 3}, {Start: 40, End: 43}},
+			size:               7,
+			expected:           "the q…y dog",
+			expectedHighlights: []Highlight{{Start: 0, End: 3}, {Start: 10, End: 13}},
+		},
+		{
+			name:               "cut at rune boundaries",
+			content:            "äöü cat äöü",
+			highlights:         []Highlight{{Start: 7, End: 10}},
+			size:               8,
+			expected:           "…ü cat ä…",
+			expectedHighlights: []Highlight{{Start: 6, End: 9}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res, highlights := snippet(tc.content, tc.highlights, tc.size)
+			if res != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, res)
+			}
+			if !slices.Equal(tc.expectedHighlights, highlights) {
+				t.Fatalf("expected highlights %v, got %v", tc.expectedHighlights, highlights)
+			}
+		})
+	}
+}
+
+func TestCollection_QueryWithOptions_Snippet(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{
+		ID:        "1",
+		Embedding: []float32{1, 0},
+		Content:   "The cat sat on the mat. Much later, the dog sat there too.",
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	res, err := c.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: []float32{1, 0},
+		NResults:       1,
+		WhereDocument:  map[string]string{"$contains": "dog"},
+		SnippetSize:    11,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].Content != "…the dog sat…" {
+		t.Fatalf("expected snippet, got %q", res[0].Content)
+	}
+	h := res[0].Highlights[0]
+	if res[0].Content[h.Start:h.End] != "dog" {
+		t.Fatal("expected highlighted dog, got", res[0].Content[h.Start:h.End])
+	}
+}
@@ -0,0 +1,169 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// defaultContentCacheSize is the default number of document contents that are
+// cached in memory when the content spillover is enabled.
+const defaultContentCacheSize = 1000
+
+// SetContentSpillover enables or disables the content spillover of a persistent
+// collection. When it's enabled, only the embeddings, metadata and IDs of the
+// documents are kept in memory, and their contents are loaded from disk when
+// they're needed, for example for query results or content filters. The
+// contents of the cacheSize most recently used documents are cached in memory.
+// This reduces the memory usage a lot for collections with long contents, as
+// the contents are usually only needed for the top results of a query.
+//
+// The setting is persisted, so it's also applied after restarts.
+//
+//   - enabled: Whether contents are spilled to disk
+//   - cacheSize: The number of contents to cache in memory. Optional, defaults
+//     to 1000 if <= 0.
+func (c *Collection) SetContentSpillover(enabled bool, cacheSize int) error {
+	if c.persistDirectory == "" {
+		return errors.New("content spillover requires a persistent collection")
+	}
+	if cacheSize <= 0 {
+		cacheSize = defaultContentCacheSize
+	}
+
+	c.documentsLock.Lock()
+	if enabled {
+		c.enableContentSpilloverLocked(cacheSize)
+	} else if c.contentCache != nil {
+		// Load all contents back into memory.
+		for id, doc := range c.documents {
+			withContent, err := c.withContentLocked(doc)
+			if err != nil {
+				c.documentsLock.Unlock()
+				return err
+			}
+			c.documents[id] = withContent
+		}
+		c.contentCache = nil
+	}
+	c.documentsLock.Unlock()
+	c.recalculateMemoryUsage()
+
+	c.configLock.Lock()
+	c.config.ContentSpillover = enabled
+	c.config.ContentCacheSize = cacheSize
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// enableContentSpilloverLocked removes the contents of all documents from
+// memory, as they're persisted anyway. The caller must hold the documents lock
+// for writing.
+func (c *Collection) enableContentSpilloverLocked(cacheSize int) {
+	if c.contentCache == nil || c.contentCache.size != cacheSize {
+		c.contentCache = newLRUCache[string, string](cacheSize)
+	}
+	for id, doc := range c.documents {
+		if doc.Content == "" {
+			continue
+		}
+		withoutContent := *doc
+		withoutContent.Content = ""
+		c.documents[id] = &withoutContent
+	}
+}
+
+// withContentLocked returns the document with its content. If the content is
+// spilled to disk, it's loaded from the cache or disk and a copy of the document
+// is returned. The caller must hold the documents lock.
+func (c *Collection) withContentLocked(doc *Document) (*Document, error) {
+	if c.contentCache == nil {
+		return doc, nil
+	}
+
+	content, ok := c.contentCache.get(doc.ID)
+	c.counters().contentCacheLookup(ok)
+	if !ok {
+		persisted, err := c.readPersistedDocument(doc.ID)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && c.documents[doc.ID] != doc {
+				// Deleted during a query
+				return doc, nil
+			}
+			return nil, fmt.Errorf("couldn't read content: %w", err)
+		}
+		content = persisted.Content
+		c.contentCache.add(doc.ID, content)
+	}
+	withContent := *doc
+	withContent.Content = content
+	return &withContent, nil
+}
+
+// withContent is like withContentLocked, but locks the documents itself.
+func (c *Collection) withContent(doc *Document) (*Document, error) {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	return c.withContentLocked(doc)
+}
+
+// filterDocsLocked filters the collection's documents by metadata and content.
+// If the contents are spilled to disk, the documents are first filtered by
+// metadata, and then the contents of the remaining ones are loaded for the
+// content filters. The caller must hold the documents lock.
+func (c *Collection) filterDocsLocked(filter *Filter) ([]*Document, error) {
+	docs, _, err := c.filterDocsLimitedLocked(filter, 0)
+	return docs, err
+}
+
+// filterDocsLimitedLocked is like filterDocsLocked, but with content filters on
+// spilled contents, it loads the contents of at most maxVisited documents that
+// match the metadata filters, if it's not 0. It returns whether the limit was
+// reached before all documents were checked.
+func (c *Collection) filterDocsLimitedLocked(filter *Filter, maxVisited int) ([]*Document, bool, error) {
+	if c.contentCache == nil || !filter.hasContentConditions() {
+		docs, err := filterDocs(c.executor(), c.documents, filter)
+		return docs, false, err
+	}
+
+	metadataFiltered, err := filterDocs(c.executor(), c.documents, filter.withoutContentConditions())
+	if err != nil {
+		return nil, false, err
+	}
+	truncated := false
+	if maxVisited > 0 && len(metadataFiltered) > maxVisited {
+		metadataFiltered, truncated = metadataFiltered[:maxVisited], true
+	}
+	var filteredDocs []*Document
+	for _, doc := range metadataFiltered {
+		withContent, err := c.withContentLocked(doc)
+		if err != nil {
+			return nil, false, err
+		}
+		if filter.matches(withContent) {
+			filteredDocs = append(filteredDocs, doc)
+		}
+	}
+	return filteredDocs, truncated, nil
+}
+
+// documentsWithContentLocked returns the collection's documents with their
+// contents, loading them from disk if they're spilled. The caller must hold the
+// documents lock.
+func (c *Collection) documentsWithContentLocked() (map[string]*Document, error) {
+	if c.contentCache == nil {
+		return c.documents, nil
+	}
+
+	docs := make(map[string]*Document, len(c.documents))
+	for id, doc := range c.documents {
+		withContent, err := c.withContentLocked(doc)
+		if err != nil {
+			return nil, err
+		}
+		docs[id] = withContent
+	}
+	return docs, nil
+}
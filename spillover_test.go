@@ -0,0 +1,124 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestCollection_SetContentSpillover(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{-0.40824828, 0.40824828, 0.81649655}, Content: "hello world"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.SetContentSpillover(true, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{0.40824828, -0.40824828, 0.81649655}, Content: "hallo welt"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for id, doc := range c.documents {
+		if doc.Content != "" {
+			t.Fatalf("expected no content of document %q in memory, got %q", id, doc.Content)
+		}
+	}
+
+	// The content is loaded for content filters and results
+	checkQuery := func(t *testing.T, c *Collection) {
+		t.Helper()
+		res, err := c.Query(ctx, "hello", 1, nil, map[string]string{"$contains": "welt"})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 1 || res[0].ID != "2" || res[0].Content != "hallo welt" {
+			t.Fatalf("expected document 2 with content, got %+v", res)
+		}
+		// ID conditions apply before the contents are loaded
+		res, err = c.Query(ctx, "hello", 1, map[string]string{"$id_in": "1"}, map[string]string{"$contains": "welt"})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 0 {
+			t.Fatalf("expected no results, got %+v", res)
+		}
+	}
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{-0.40824828, 0.40824828, 0.81649655}, nil
+	}
+	c.embed = embeddingFunc
+	checkQuery(t, c)
+
+	// The visited documents limit the contents that are loaded
+	_, stats, err := c.QueryWithStats(ctx, QueryOptions{
+		QueryText:     "hello",
+		NResults:      1,
+		WhereDocument: map[string]string{"$contains": "l"},
+		Limits:        QueryLimits{MaxDocumentsVisited: 1},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats.DocumentsScanned != 1 || !stats.Truncated {
+		t.Fatalf("expected 1 scanned document with truncation, got %+v", stats)
+	}
+
+	// The setting is persisted
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", embeddingFunc)
+	if c.contentCache == nil {
+		t.Fatal("expected content spillover to be enabled")
+	}
+	checkQuery(t, c)
+
+	// Exports contain the contents
+	buf := &bytes.Buffer{}
+	err = db.ExportToWriter(buf, false, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	db2 := NewDB()
+	err = db2.ImportFromReader(bytes.NewReader(buf.Bytes()), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if got := db2.GetCollection("test", nil).documents["1"].Content; got != "hello world" {
+		t.Fatal("expected exported content \"hello world\", got", got)
+	}
+
+	// Disabling loads the contents into memory again
+	err = c.SetContentSpillover(false, 0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if got := c.documents["1"].Content; got != "hello world" {
+		t.Fatal("expected content \"hello world\", got", got)
+	}
+}
+
+func TestCollection_SetContentSpillover_InMemory(t *testing.T) {
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetContentSpillover(true, 0)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,99 @@
+package chromem
+
+import "sync/atomic"
+
+// Stats are counters of the DB's internal state since it was created, see
+// [DB.Stats].
+type Stats struct {
+	// The number of collections and documents.
+	Collections int `json:"collections"`
+	Documents   int `json:"documents"`
+
+	// The number of successful queries. Each query of [Collection.QueryBatch]
+	// and each page of [Collection.QueryIter] counts as one.
+	QueriesServed int64 `json:"queries_served"`
+	// The number of documents the queries scanned, i.e. that matched their
+	// filters and whose similarity was calculated.
+	DocumentsScanned int64 `json:"documents_scanned"`
+
+	// Lookups in the content caches of collections with content spillover, see
+	// [Collection.SetContentSpillover].
+	ContentCacheHits   int64 `json:"content_cache_hits"`
+	ContentCacheMisses int64 `json:"content_cache_misses"`
+	// The ratio of hits to lookups, or 0 without lookups.
+	ContentCacheHitRate float64 `json:"content_cache_hit_rate"`
+
+	// The number of errors of embedding functions, when embedding documents,
+	// queries and media.
+	EmbeddingErrors int64 `json:"embedding_errors"`
+}
+
+// Stats returns the DB's counters, for monitoring without a metrics framework.
+// They can be published with the expvar package, for example:
+//
+//	expvar.Publish("chromem", expvar.Func(func() any { return db.Stats() }))
+//
+// The counters of deleted collections are kept.
+func (db *DB) Stats() Stats {
+	db.collectionsLock.RLock()
+	stats := Stats{Collections: len(db.collections)}
+	for _, c := range db.collections {
+		stats.Documents += c.Count()
+	}
+	db.collectionsLock.RUnlock()
+
+	stats.QueriesServed = db.counters.queries.Load()
+	stats.DocumentsScanned = db.counters.documentsScanned.Load()
+	stats.ContentCacheHits = db.counters.contentCacheHits.Load()
+	stats.ContentCacheMisses = db.counters.contentCacheMisses.Load()
+	if lookups := stats.ContentCacheHits + stats.ContentCacheMisses; lookups > 0 {
+		stats.ContentCacheHitRate = float64(stats.ContentCacheHits) / float64(lookups)
+	}
+	stats.EmbeddingErrors = db.counters.embeddingErrors.Load()
+	return stats
+}
+
+// dbCounters are the counters of [Stats]. Its methods are no-ops for nil, which
+// is the case for collections without a DB.
+type dbCounters struct {
+	queries            atomic.Int64
+	documentsScanned   atomic.Int64
+	contentCacheHits   atomic.Int64
+	contentCacheMisses atomic.Int64
+	embeddingErrors    atomic.Int64
+}
+
+// counters returns the counters of the collection's DB, or nil if it has none.
+func (c *Collection) counters() *dbCounters {
+	if c.db == nil {
+		return nil
+	}
+	return &c.db.counters
+}
+
+func (s *dbCounters) queryServed(queries, documentsScanned int) {
+	if s == nil {
+		return
+	}
+	s.queries.Add(int64(queries))
+	s.documentsScanned.Add(int64(documentsScanned))
+}
+
+func (s *dbCounters) contentCacheLookup(hit bool) {
+	if s == nil {
+		return
+	}
+	if hit {
+		s.contentCacheHits.Add(1)
+	} else {
+		s.contentCacheMisses.Add(1)
+	}
+}
+
+// embedded counts the error of an embedding function, if it isn't nil.
+func (s *dbCounters) embedded(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.embeddingErrors.Add(1)
+}
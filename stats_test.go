@@ -0,0 +1,80 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDB_Stats(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewPersistentDB(t.TempDir(), false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		if text == "fail" {
+			return nil, errors.New("embedding failed")
+		}
+		return []float32{1, 0}, nil
+	}
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetContentSpillover(true, 10)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Content: "hello world"},
+		{ID: "2", Embedding: []float32{0, 1}, Content: "hallo welt"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Reload the DB, so that the counters and the content cache are empty
+	db, err = NewPersistentDB(db.persistDirectory, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", embeddingFunc)
+
+	_, err = c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.Query(ctx, "fail", 1, nil, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	// Loads the contents for the filter and the result
+	_, err = c.Query(ctx, "hello", 1, nil, map[string]string{"$contains": "welt"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	stats := db.Stats()
+	expected := Stats{
+		Collections:      1,
+		Documents:        2,
+		QueriesServed:    2,
+		DocumentsScanned: 3,
+		// The content of document 1 is cached when it's loaded for the first
+		// result, and the one of document 2 when it's loaded for the filter.
+		ContentCacheHits:    2,
+		ContentCacheMisses:  2,
+		ContentCacheHitRate: 0.5,
+		EmbeddingErrors:     1,
+	}
+	if stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+
+	// It can be published as expvar.Func, which marshals it to JSON.
+	_, err = json.Marshal(stats)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
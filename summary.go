@@ -0,0 +1,60 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+)
+
+// SummaryIndexing configures the embedding of summaries of documents instead of
+// their full content, see [Collection.SetSummaryIndexing].
+type SummaryIndexing struct {
+	// Summarize returns the summary of the content, for example generated by an
+	// LLM. It's called concurrently when documents are added concurrently.
+	Summarize func(ctx context.Context, content string) (string, error)
+
+	// The minimum length of the content in characters for a document to be
+	// summarized. Shorter documents are embedded as they are. 0 means all
+	// documents are summarized.
+	MinLength int
+
+	// The metadata key that the summary is stored with in the document's
+	// metadata, for example to show it in the results. Optional.
+	MetadataKey string
+}
+
+// SetSummaryIndexing enables embedding a summary of each document instead of
+// its content, when documents without embedding are added. Queries then match
+// the summaries, but the results have the full content. This improves the
+// retrieval of very long documents, whose embeddings would otherwise be
+// diluted by all the details, or be cut off at the maximum input length of the
+// embedding model. A nil Summarize function disables it. Documents with media
+// aren't summarized.
+//
+// Like the embedding function, the summarization isn't persisted, so you have
+// to set it again after loading a persistent DB.
+func (c *Collection) SetSummaryIndexing(options SummaryIndexing) error {
+	if options.MinLength < 0 {
+		return errors.New("minLength must be >= 0")
+	}
+
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+
+	c.summaryIndexing = options
+	return nil
+}
+
+// summarize returns the summary of the content to embed instead of it, or an
+// empty string if it isn't summarized, and the metadata key to store it with.
+func (c *Collection) summarize(ctx context.Context, content string) (string, string, error) {
+	c.configLock.RLock()
+	options := c.summaryIndexing
+	c.configLock.RUnlock()
+
+	if options.Summarize == nil || utf8.RuneCountInString(content) < options.MinLength {
+		return "", "", nil
+	}
+	summary, err := options.Summarize(ctx, content)
+	return summary, options.MetadataKey, err
+}
@@ -0,0 +1,73 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCollection_SetSummaryIndexing(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		if strings.HasPrefix(text, "Summary") {
+			return []float32{1, 0}, nil
+		}
+		return []float32{0, 1}, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollectionWithOptions("test", CollectionOptions{
+		EmbeddingFunc: embeddingFunc,
+		SummaryIndexing: SummaryIndexing{
+			Summarize: func(_ context.Context, content string) (string, error) {
+				return "Summary of " + content[:4], nil
+			},
+			MinLength:   10,
+			MetadataKey: "summary",
+		},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	long := "A long document with many details."
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "long", Content: long},
+		{ID: "short", Content: "Short"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The summary is embedded, but the full content is returned.
+	res, err := c.QueryEmbedding(ctx, []float32{1, 0}, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "long" || res[0].Content != long {
+		t.Fatal("expected the long document with its full content, got", res[0])
+	}
+	if summary := res[0].Metadata["summary"]; summary != "Summary of A lo" {
+		t.Fatal("expected the summary in the metadata, got", summary)
+	}
+	if _, ok := c.documents["short"].Metadata["summary"]; ok {
+		t.Fatal("expected the short document not to be summarized")
+	}
+
+	err = c.SetSummaryIndexing(SummaryIndexing{MinLength: -1})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.SetSummaryIndexing(SummaryIndexing{
+		Summarize: func(context.Context, string) (string, error) {
+			return "", errors.New("LLM unavailable")
+		},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "fail", Content: long})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
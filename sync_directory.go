@@ -0,0 +1,217 @@
+package chromem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The metadata keys of the documents created by [SyncDirectory].
+const (
+	syncPathKey  = "source_path"
+	syncHashKey  = "source_hash"
+	syncMtimeKey = "source_mtime"
+)
+
+// SyncDirectoryOptions are the options for [SyncDirectory].
+type SyncDirectoryOptions struct {
+	// The extensions of the files to index, including the dot. Optional,
+	// defaults to ".md", ".markdown", ".txt" and ".rst".
+	Extensions []string
+
+	// The maximum size of a chunk in characters. Optional, defaults to 1000.
+	ChunkSize int
+
+	// The number of characters that consecutive chunks share. Optional,
+	// defaults to 100. Negative values disable the overlap.
+	ChunkOverlap int
+
+	// The concurrency of adding documents, i.e. of creating embeddings.
+	// Optional, defaults to [runtime.NumCPU].
+	Concurrency int
+}
+
+// SyncDirectoryStats are the statistics of a [SyncDirectory] run.
+type SyncDirectoryStats struct {
+	FilesAdded     int
+	FilesUpdated   int
+	FilesDeleted   int
+	FilesUnchanged int
+}
+
+// SyncDirectory indexes the text files in the directory and its subdirectories
+// into the collection, which makes "index my docs folder" a single call.
+// The files are split into chunks, which become documents with the IDs
+// "<path>#<index>", where path is the slash-separated path relative to the
+// directory. Their metadata contains the path as "source_path".
+//
+// Calling it again syncs the collection incrementally: files whose
+// modification time and SHA-256 hash are unchanged are skipped, changed files
+// are re-indexed and the documents of deleted files are deleted. Hidden files
+// and directories (starting with ".") are ignored.
+// Only documents with "source_path" metadata are managed, so the collection
+// should be dedicated to the directory.
+func SyncDirectory(ctx context.Context, path string, c *Collection, opts SyncDirectoryOptions) (SyncDirectoryStats, error) {
+	stats := SyncDirectoryStats{}
+	if path == "" {
+		return stats, errors.New("path is empty")
+	}
+	if c == nil {
+		return stats, errors.New("collection is nil")
+	}
+	if len(opts.Extensions) == 0 {
+		opts.Extensions = []string{".md", ".markdown", ".txt", ".rst"}
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 1000
+	}
+	if opts.ChunkOverlap == 0 {
+		opts.ChunkOverlap = 100
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = runtime.NumCPU()
+	}
+	ctx, _ = startProgress(ctx, "sync", 0)
+
+	indexed := c.syncedFiles()
+	seen := make(map[string]struct{})
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if p != path && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !slices.Contains(opts.Extensions, filepath.Ext(p)) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return fmt.Errorf("couldn't get relative path: %w", err)
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = struct{}{}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("couldn't get file info of %q: %w", rel, err)
+		}
+		mtime := strconv.FormatInt(info.ModTime().UnixNano(), 10)
+		prev, ok := indexed[rel]
+		if ok && prev.mtime == mtime {
+			stats.FilesUnchanged++
+			return nil
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("couldn't read file %q: %w", rel, err)
+		}
+		hash := sha256.Sum256(content)
+		hashHex := hex.EncodeToString(hash[:])
+		if ok && prev.hash == hashHex {
+			stats.FilesUnchanged++
+			return nil
+		}
+
+		var docs []Document
+		for i, chunk := range splitText(string(content), opts.ChunkSize, opts.ChunkOverlap) {
+			docs = append(docs, Document{
+				ID: rel + "#" + strconv.Itoa(i),
+				Metadata: map[string]string{
+					syncPathKey:  rel,
+					syncHashKey:  hashHex,
+					syncMtimeKey: mtime,
+				},
+				Content: chunk,
+			})
+		}
+		if len(docs) > 0 {
+			err = c.AddDocuments(ctx, docs, opts.Concurrency)
+			if err != nil {
+				return fmt.Errorf("couldn't add documents of %q: %w", rel, err)
+			}
+		}
+		// Delete the chunks that don't exist anymore, for example because the
+		// file got shorter.
+		var stale []string
+		for _, id := range prev.ids {
+			if !slices.ContainsFunc(docs, func(doc Document) bool { return doc.ID == id }) {
+				stale = append(stale, id)
+			}
+		}
+		if len(stale) > 0 {
+			err = c.Delete(ctx, nil, nil, stale...)
+			if err != nil {
+				return fmt.Errorf("couldn't delete stale documents of %q: %w", rel, err)
+			}
+		}
+
+		if ok {
+			stats.FilesUpdated++
+		} else {
+			stats.FilesAdded++
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("couldn't sync directory: %w", err)
+	}
+
+	for rel, f := range indexed {
+		if _, ok := seen[rel]; ok {
+			continue
+		}
+		err := c.Delete(ctx, nil, nil, f.ids...)
+		if err != nil {
+			return stats, fmt.Errorf("couldn't delete documents of %q: %w", rel, err)
+		}
+		stats.FilesDeleted++
+	}
+
+	return stats, nil
+}
+
+// syncedFile is the state of a file that was indexed with SyncDirectory.
+type syncedFile struct {
+	hash  string
+	mtime string
+	ids   []string
+}
+
+// syncedFiles returns the files that were indexed with SyncDirectory, by path.
+func (c *Collection) syncedFiles() map[string]syncedFile {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	res := make(map[string]syncedFile)
+	for id, doc := range c.documents {
+		path, ok := doc.Metadata[syncPathKey]
+		if !ok {
+			continue
+		}
+		f := res[path]
+		f.hash = doc.Metadata[syncHashKey]
+		f.mtime = doc.Metadata[syncMtimeKey]
+		f.ids = append(f.ids, id)
+		res[path] = f
+	}
+	return res
+}
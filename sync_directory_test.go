@@ -0,0 +1,92 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncDirectory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0o700)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	writeFile("a.md", "hello world")
+	writeFile("sub/b.txt", strings.Repeat("word ", 30))
+	writeFile("image.png", "not text")
+	writeFile(".git/c.md", "hidden")
+
+	embedded := 0
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		embedded++
+		return []float32{float32(len(text)), 1}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	opts := SyncDirectoryOptions{ChunkSize: 50, ChunkOverlap: -1, Concurrency: 1}
+
+	stats, err := SyncDirectory(ctx, dir, c, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats != (SyncDirectoryStats{FilesAdded: 2}) {
+		t.Fatalf("expected 2 added files, got %+v", stats)
+	}
+	if c.Count() != 4 {
+		t.Fatal("expected 4 documents, got", c.Count())
+	}
+	doc, ok := c.documents["sub/b.txt#2"]
+	if !ok || doc.Metadata["source_path"] != "sub/b.txt" {
+		t.Fatalf("expected chunk of sub/b.txt, got %+v", doc)
+	}
+
+	// Unchanged files aren't embedded again
+	embedded = 0
+	stats, err = SyncDirectory(ctx, dir, c, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats != (SyncDirectoryStats{FilesUnchanged: 2}) || embedded != 0 {
+		t.Fatalf("expected 2 unchanged files, got %+v and %d embeddings", stats, embedded)
+	}
+
+	// Changed and deleted files
+	writeFile("sub/b.txt", "short")
+	future := time.Now().Add(time.Hour)
+	err = os.Chtimes(filepath.Join(dir, "sub/b.txt"), future, future)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = os.Remove(filepath.Join(dir, "a.md"))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	stats, err = SyncDirectory(ctx, dir, c, opts)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if stats != (SyncDirectoryStats{FilesUpdated: 1, FilesDeleted: 1}) {
+		t.Fatalf("expected 1 updated and 1 deleted file, got %+v", stats)
+	}
+	if c.Count() != 1 {
+		t.Fatal("expected 1 document, got", c.Count())
+	}
+	if doc := c.documents["sub/b.txt#0"]; doc == nil || doc.Content != "short" {
+		t.Fatalf("expected updated chunk, got %+v", doc)
+	}
+}
@@ -0,0 +1,353 @@
+package chromem
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens of texts and truncates them to a maximum number
+// of tokens. It's used to check texts against the maximum input length of an
+// embedding model before sending them to its API.
+type Tokenizer interface {
+	// CountTokens returns the number of tokens of the text.
+	CountTokens(text string) int
+	// Truncate returns the longest prefix of the text that has at most maxTokens
+	// tokens.
+	Truncate(text string, maxTokens int) string
+}
+
+// NewTokenizerHeuristic returns a [Tokenizer] that doesn't actually tokenize,
+// but estimates the number of tokens with 4 characters per token, which is a
+// common rule of thumb for English texts. It's fast and works for every model,
+// but can be off by a lot for other languages and code.
+// Texts are truncated at whitespace where possible.
+func NewTokenizerHeuristic() Tokenizer {
+	return heuristicTokenizer{}
+}
+
+// heuristicCharsPerToken is the number of characters per token that the
+// heuristic tokenizer assumes.
+const heuristicCharsPerToken = 4
+
+type heuristicTokenizer struct{}
+
+func (heuristicTokenizer) CountTokens(text string) int {
+	return (utf8.RuneCountInString(text) + heuristicCharsPerToken - 1) / heuristicCharsPerToken
+}
+
+func (heuristicTokenizer) Truncate(text string, maxTokens int) string {
+	// Fast path without counting runes
+	if len(text) <= maxTokens*heuristicCharsPerToken {
+		return text
+	}
+	return truncateRunes(text, maxTokens*heuristicCharsPerToken)
+}
+
+// truncateRunes truncates the text to at most maxRunes runes, cutting at
+// whitespace if there is some in the second half of the truncated text.
+func truncateRunes(text string, maxRunes int) string {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	end := maxRunes
+	for i := maxRunes; i > maxRunes/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			end = i
+			break
+		}
+	}
+	return string(trimSpaceRunes(runes[:end]))
+}
+
+// BPETokenizer is a byte pair encoding (BPE) tokenizer that's compatible with
+// OpenAI's tiktoken, so it counts tokens exactly like OpenAI's API does.
+// Create it with [LoadTokenizerTiktoken].
+type BPETokenizer struct {
+	ranks   map[string]int
+	decoder map[int]string
+}
+
+var _ Tokenizer = (*BPETokenizer)(nil)
+
+// LoadTokenizerTiktoken loads a BPE tokenizer from a tiktoken rank file, which
+// has one base64 encoded token and its rank per line. For OpenAI's embedding
+// models, that's the "cl100k_base" encoding, which you can download from
+// https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken
+// (we don't embed it in chromem-go, as it's about 1.7 MB).
+//
+// The texts are split into pieces the way the cl100k_base encoding does before
+// applying the merges. Special tokens like "<|endoftext|>" are encoded as
+// regular text.
+func LoadTokenizerTiktoken(r io.Reader) (*BPETokenizer, error) {
+	t := &BPETokenizer{
+		ranks:   make(map[string]int),
+		decoder: make(map[int]string),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		token, rankStr, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %d", lineNum)
+		}
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decode token in line %d: %w", lineNum, err)
+		}
+		rank, err := strconv.Atoi(rankStr)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse rank in line %d: %w", lineNum, err)
+		}
+		t.ranks[string(b)] = rank
+		t.decoder[rank] = string(b)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't read tiktoken file: %w", err)
+	}
+	if len(t.ranks) == 0 {
+		return nil, errors.New("tiktoken file is empty")
+	}
+
+	return t, nil
+}
+
+// Encode returns the tokens of the text.
+func (t *BPETokenizer) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range splitPiecesCL100K(text) {
+		if rank, ok := t.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		tokens = append(tokens, t.bytePairEncode(piece)...)
+	}
+	return tokens
+}
+
+// Decode returns the text of the tokens. Unknown tokens are skipped.
+func (t *BPETokenizer) Decode(tokens []int) string {
+	sb := strings.Builder{}
+	for _, token := range tokens {
+		sb.WriteString(t.decoder[token])
+	}
+	return sb.String()
+}
+
+// CountTokens returns the number of tokens of the text.
+func (t *BPETokenizer) CountTokens(text string) int {
+	return len(t.Encode(text))
+}
+
+// Truncate returns the longest prefix of the text that has at most maxTokens
+// tokens, without cutting a UTF-8 character in half.
+func (t *BPETokenizer) Truncate(text string, maxTokens int) string {
+	tokens := t.Encode(text)
+	if len(tokens) <= maxTokens {
+		return text
+	}
+	// The tokens are the bytes of the text, so we can cut the original text.
+	n := 0
+	for _, token := range tokens[:max(maxTokens, 0)] {
+		n += len(t.decoder[token])
+	}
+	text = text[:n]
+	for len(text) > 0 && !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+// bytePairEncode encodes a piece that isn't a token itself, by starting with
+// single bytes and repeatedly merging the adjacent pair with the lowest rank.
+func (t *BPETokenizer) bytePairEncode(piece string) []int {
+	// Boundaries of the parts, initially single bytes.
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+
+	for len(parts) > 2 {
+		minRank, minIdx := math.MaxInt, -1
+		for i := 0; i < len(parts)-2; i++ {
+			if rank, ok := t.ranks[piece[parts[i]:parts[i+2]]]; ok && rank < minRank {
+				minRank, minIdx = rank, i
+			}
+		}
+		if minIdx == -1 {
+			break
+		}
+		parts = append(parts[:minIdx+1], parts[minIdx+2:]...)
+	}
+
+	tokens := make([]int, 0, len(parts)-1)
+	for i := 0; i < len(parts)-1; i++ {
+		rank, ok := t.ranks[piece[parts[i]:parts[i+1]]]
+		if !ok {
+			// Can only happen with incomplete rank files, as the tiktoken
+			// encodings contain all single bytes.
+			continue
+		}
+		tokens = append(tokens, rank)
+	}
+	return tokens
+}
+
+// splitPiecesCL100K splits a text into the pieces that the cl100k_base encoding
+// applies the BPE merges to. It's equivalent to splitting with the regular
+// expression that tiktoken uses, which Go's regexp package can't handle due to
+// the lookahead:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitPiecesCL100K(text string) []string {
+	runes := []rune(text)
+	isNewline := func(r rune) bool { return r == '\r' || r == '\n' }
+	isOther := func(r rune) bool { return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r) }
+
+	var pieces []string
+	for i := 0; i < len(runes); {
+		end := i
+		r := runes[i]
+		next := rune(-1)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		// Contractions
+		case r == '\'' && matchContraction(runes[i+1:]) > 0:
+			end = i + 1 + matchContraction(runes[i+1:])
+		// Words, optionally with one leading non-letter, non-number character
+		case unicode.IsLetter(r) || (!isNewline(r) && !unicode.IsNumber(r) && unicode.IsLetter(next)):
+			end = i + 1
+			for end < len(runes) && unicode.IsLetter(runes[end]) {
+				end++
+			}
+		// Up to three digits
+		case unicode.IsNumber(r):
+			end = i + 1
+			for end < len(runes) && end < i+3 && unicode.IsNumber(runes[end]) {
+				end++
+			}
+		// Punctuation and symbols, optionally with a leading space and trailing newlines
+		case isOther(r) || (r == ' ' && next != -1 && isOther(next)):
+			end = i + 1
+			for end < len(runes) && isOther(runes[end]) {
+				end++
+			}
+			for end < len(runes) && isNewline(runes[end]) {
+				end++
+			}
+		// Whitespace
+		default:
+			wsEnd := i
+			lastNewline := -1
+			for wsEnd < len(runes) && unicode.IsSpace(runes[wsEnd]) {
+				if isNewline(runes[wsEnd]) {
+					lastNewline = wsEnd
+				}
+				wsEnd++
+			}
+			switch {
+			case lastNewline != -1:
+				// \s*[\r\n]+
+				end = lastNewline + 1
+			case wsEnd == len(runes) || wsEnd-1 == i:
+				// \s+(?!\S) at the end of the text, or \s+ for a single space
+				end = wsEnd
+			default:
+				// \s+(?!\S), leaving the last space for the next piece
+				end = wsEnd - 1
+			}
+		}
+
+		pieces = append(pieces, string(runes[i:end]))
+		i = end
+	}
+	return pieces
+}
+
+// matchContraction returns the length of the contraction suffix ("s", "t",
+// "re", "ve", "m", "ll", "d", case-insensitive) at the start of the runes, or 0.
+func matchContraction(runes []rune) int {
+	if len(runes) >= 2 {
+		switch strings.ToLower(string(runes[:2])) {
+		case "re", "ve", "ll":
+			return 2
+		}
+	}
+	if len(runes) >= 1 {
+		switch unicode.ToLower(runes[0]) {
+		case 's', 't', 'm', 'd':
+			return 1
+		}
+	}
+	return 0
+}
+
+// embeddingModelMaxTokens is the maximum input length in tokens of known
+// embedding models.
+var embeddingModelMaxTokens = map[string]int{
+	// OpenAI
+	string(EmbeddingModelOpenAI2Ada):   8191,
+	string(EmbeddingModelOpenAI3Small): 8191,
+	string(EmbeddingModelOpenAI3Large): 8191,
+	// Mistral
+	embeddingModelMistral: 8192,
+	// Cohere
+	string(EmbeddingModelCohereMultilingualV2):      512,
+	string(EmbeddingModelCohereEnglishLightV2):      512,
+	string(EmbeddingModelCohereEnglishV2):           512,
+	string(EmbeddingModelCohereMultilingualLightV3): 512,
+	string(EmbeddingModelCohereEnglishLightV3):      512,
+	string(EmbeddingModelCohereMultilingualV3):      512,
+	string(EmbeddingModelCohereEnglishV3):           512,
+	// Jina
+	string(EmbeddingModelJina2BaseEN):   8192,
+	string(EmbeddingModelJina2BaseDE):   8192,
+	string(EmbeddingModelJina2BaseCode): 8192,
+	string(EmbeddingModelJina2BaseZH):   8192,
+	// mixedbread.ai, and the same open models elsewhere
+	string(EmbeddingModelMixedbreadUAELargeV1):          512,
+	string(EmbeddingModelMixedbreadBGELargeENV15):       512,
+	string(EmbeddingModelMixedbreadGTELarge):            512,
+	string(EmbeddingModelMixedbreadE5LargeV2):           512,
+	string(EmbeddingModelMixedbreadMultilingualE5Large): 512,
+	string(EmbeddingModelMixedbreadMultilingualE5Base):  512,
+	string(EmbeddingModelMixedbreadAllMiniLML6V2):       256,
+	string(EmbeddingModelMixedbreadGTELargeZh):          512,
+	// Popular local models
+	"nomic-embed-text":  8192,
+	"mxbai-embed-large": 512,
+	"all-minilm":        256,
+	"bge-m3":            8192,
+}
+
+// MaxTokensForModel returns the maximum input length in tokens of a known
+// embedding model, like "text-embedding-3-small" or "nomic-embed-text". For
+// model names with an organization prefix (e.g. "BAAI/bge-large-en-v1.5") or a
+// tag suffix (e.g. "nomic-embed-text:v1.5"), the bare name is looked up as well.
+// The second return value is false for unknown models.
+func MaxTokensForModel(model string) (int, bool) {
+	if n, ok := embeddingModelMaxTokens[model]; ok {
+		return n, true
+	}
+	if i := strings.LastIndex(model, "/"); i != -1 {
+		model = model[i+1:]
+	}
+	model, _, _ = strings.Cut(model, ":")
+	n, ok := embeddingModelMaxTokens[model]
+	return n, ok
+}
@@ -0,0 +1,149 @@
+package chromem
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitPiecesCL100K(t *testing.T) {
+	tt := []struct {
+		text string
+		want []string
+	}{
+		{text: "Hello world", want: []string{"Hello", " world"}},
+		{text: "I'm here  now\n\nok", want: []string{"I", "'m", " here", " ", " now", "\n\n", "ok"}},
+		{text: "12345 abc!!\n", want: []string{"123", "45", " abc", "!!\n"}},
+		{text: "a , b", want: []string{"a", " ,", " b"}},
+		{text: "trailing   ", want: []string{"trailing", "   "}},
+		{text: "x \n y", want: []string{"x", " \n", " y"}},
+		{text: "Grüße, 世界", want: []string{"Grüße", ",", " 世界"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.text, func(t *testing.T) {
+			got := splitPiecesCL100K(tc.text)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+// newTestBPETokenizer returns a tokenizer with all single bytes and a few merges.
+func newTestBPETokenizer(t *testing.T) *BPETokenizer {
+	t.Helper()
+
+	sb := strings.Builder{}
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, merge := range []string{"he", "ll", "hell", "hello", " w", "or", " wor"} {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(merge)), 256+i)
+	}
+	tokenizer, err := LoadTokenizerTiktoken(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	return tokenizer
+}
+
+func TestBPETokenizer(t *testing.T) {
+	tokenizer := newTestBPETokenizer(t)
+
+	text := "hello world"
+	tokens := tokenizer.Encode(text)
+	// "hello" is a token, " world" is merged to " wor", "l", "d"
+	if want := []int{259, 262, 'l', 'd'}; !reflect.DeepEqual(tokens, want) {
+		t.Fatal("expected", want, "got", tokens)
+	}
+	if got := tokenizer.Decode(tokens); got != text {
+		t.Fatal("expected", text, "got", got)
+	}
+	if n := tokenizer.CountTokens(text); n != 4 {
+		t.Fatal("expected 4 tokens, got", n)
+	}
+
+	if got := tokenizer.Truncate(text, 2); got != "hello wor" {
+		t.Fatal("expected \"hello wor\", got", got)
+	}
+	if got := tokenizer.Truncate(text, 10); got != text {
+		t.Fatal("expected", text, "got", got)
+	}
+	// Multi-byte characters aren't cut in half
+	if got := tokenizer.Truncate("hello ü", 2); got != "hello " {
+		t.Fatalf("expected %q, got %q", "hello ", got)
+	}
+
+	_, err := LoadTokenizerTiktoken(strings.NewReader("not base64 0\n"))
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestMaxTokensForModel(t *testing.T) {
+	tt := []struct {
+		model  string
+		want   int
+		wantOK bool
+	}{
+		{model: "text-embedding-3-small", want: 8191, wantOK: true},
+		{model: "nomic-embed-text:v1.5", want: 8192, wantOK: true},
+		{model: "mixedbread-ai/mxbai-embed-large", want: 512, wantOK: true},
+		{model: "unknown", want: 0, wantOK: false},
+	}
+	for _, tc := range tt {
+		got, ok := MaxTokensForModel(tc.model)
+		if got != tc.want || ok != tc.wantOK {
+			t.Fatal("expected", tc.want, tc.wantOK, "got", got, ok)
+		}
+	}
+}
+
+func TestNewEmbeddingMiddlewareTokenLimit(t *testing.T) {
+	tokenizer := newTestBPETokenizer(t)
+	var got string
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		got = text
+		return []float32{1}, nil
+	}
+
+	// Truncate
+	f := NewEmbeddingMiddlewareTokenLimit(2, tokenizer, TokenLimitTruncate)(embeddingFunc)
+	_, err := f(context.Background(), "hello world")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if got != "hello wor" {
+		t.Fatal("expected \"hello wor\", got", got)
+	}
+
+	// Warn
+	f = NewEmbeddingMiddlewareTokenLimit(2, tokenizer, TokenLimitWarn)(embeddingFunc)
+	_, err = f(context.Background(), "hello world")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if got != "hello world" {
+		t.Fatal("expected \"hello world\", got", got)
+	}
+
+	// Reject
+	got = ""
+	f = NewEmbeddingMiddlewareTokenLimit(2, tokenizer, TokenLimitReject)(embeddingFunc)
+	_, err = f(context.Background(), "hello world")
+	if !errors.Is(err, ErrInputTooLong) {
+		t.Fatal("expected ErrInputTooLong, got", err)
+	}
+	if got != "" {
+		t.Fatal("expected embedding func not to be called")
+	}
+	_, err = f(context.Background(), "hello")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
@@ -0,0 +1,145 @@
+package chromem
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// TopKAlgorithm is the algorithm with which a query selects the most similar
+// documents, see [QueryOptions.TopKAlgorithm].
+type TopKAlgorithm string
+
+const (
+	// TopKAuto selects the algorithm based on the number of results relative to
+	// the number of documents. This is the default.
+	TopKAuto TopKAlgorithm = ""
+	// TopKHeap keeps the most similar documents in a heap while scanning. It's
+	// the fastest when the number of results is small relative to the number of
+	// documents, and it needs memory only for the results.
+	TopKHeap TopKAlgorithm = "heap"
+	// TopKSelect collects the similarities of all documents and selects the
+	// most similar ones with quickselect, which takes linear time independent of
+	// the number of results.
+	TopKSelect TopKAlgorithm = "select"
+	// TopKSort collects the similarities of all documents and sorts them. It's
+	// used when all documents are returned, as there's nothing to select then.
+	TopKSort TopKAlgorithm = "sort"
+)
+
+// topKSelectRatio is the ratio of the number of results to the number of
+// documents, above which [TopKAuto] uses quickselect instead of the heap. It's
+// guarded by BenchmarkTopK.
+const topKSelectRatio = 0.02
+
+// topKCollector keeps the most similar docSims. It's safe for concurrent use,
+// but not the result of values().
+type topKCollector interface {
+	add(doc docSim)
+	// values returns the most similar docSims, sorted by similarity
+	// (descending) and ID, see compareDocSims. Only call it after all calls to
+	// add() have finished.
+	values() []docSim
+}
+
+// newTopKCollector returns the collector for the algorithm, which keeps the n
+// most similar of at most corpusSize docSims.
+func newTopKCollector(algorithm TopKAlgorithm, n, corpusSize int) (topKCollector, error) {
+	if algorithm == TopKAuto {
+		switch {
+		case n >= corpusSize:
+			algorithm = TopKSort
+		case float64(n)/float64(corpusSize) >= topKSelectRatio:
+			algorithm = TopKSelect
+		default:
+			algorithm = TopKHeap
+		}
+	}
+	switch algorithm {
+	case TopKHeap:
+		return newMaxDocSims(n), nil
+	case TopKSelect, TopKSort:
+		return &allDocSims{
+			docs:   make([]docSim, 0, corpusSize),
+			n:      n,
+			sorted: algorithm == TopKSort,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported top-k algorithm: %q", algorithm)
+	}
+}
+
+// allDocSims collects all docSims, and selects the n most similar ones at the
+// end, either by quickselect or by sorting all of them.
+type allDocSims struct {
+	docs   []docSim
+	lock   sync.Mutex
+	n      int
+	sorted bool
+}
+
+func (a *allDocSims) add(doc docSim) {
+	a.lock.Lock()
+	a.docs = append(a.docs, doc)
+	a.lock.Unlock()
+}
+
+func (a *allDocSims) values() []docSim {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	n := min(a.n, len(a.docs))
+	if !a.sorted {
+		quickselectDocSims(a.docs, n)
+		a.docs = a.docs[:n]
+	}
+	slices.SortFunc(a.docs, compareDocSims)
+	return a.docs[:n]
+}
+
+// quickselectDocSims reorders the docSims so that the n first ones by
+// compareDocSims are at the start, in any order.
+func quickselectDocSims(docs []docSim, n int) {
+	if n <= 0 || n >= len(docs) {
+		return
+	}
+	lo, hi := 0, len(docs)-1
+	for lo < hi {
+		// Median of three as pivot, to avoid the worst case for sorted input.
+		mid := lo + (hi-lo)/2
+		if compareDocSims(docs[mid], docs[lo]) < 0 {
+			docs[mid], docs[lo] = docs[lo], docs[mid]
+		}
+		if compareDocSims(docs[hi], docs[lo]) < 0 {
+			docs[hi], docs[lo] = docs[lo], docs[hi]
+		}
+		if compareDocSims(docs[hi], docs[mid]) < 0 {
+			docs[hi], docs[mid] = docs[mid], docs[hi]
+		}
+		// docs[lo] <= docs[mid] <= docs[hi] by compareDocSims. Partition with
+		// the median.
+		pivot := docs[mid]
+		i, j := lo, hi
+		for i <= j {
+			for compareDocSims(docs[i], pivot) < 0 {
+				i++
+			}
+			for compareDocSims(docs[j], pivot) > 0 {
+				j--
+			}
+			if i <= j {
+				docs[i], docs[j] = docs[j], docs[i]
+				i++
+				j--
+			}
+		}
+		// docs[lo:j+1] <= pivot <= docs[i:hi+1], and docs[j+1:i] == pivot.
+		switch {
+		case n <= j:
+			hi = j
+		case n >= i:
+			lo = i
+		default:
+			return
+		}
+	}
+}
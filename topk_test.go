@@ -0,0 +1,123 @@
+package chromem
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestNewTopKCollector(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	docs := make([]docSim, 1000)
+	for i := range docs {
+		// With duplicates, to test the partitioning of equal similarities
+		docs[i] = docSim{docID: strconv.Itoa(i), similarity: float32(r.Intn(200))}
+	}
+
+	for _, n := range []int{1, 10, 500, 999, 1000} {
+		expected, err := newTopKCollector(TopKSort, n, len(docs))
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		for _, doc := range docs {
+			expected.add(doc)
+		}
+		expectedValues := slices.Clone(expected.values())
+		if len(expectedValues) != n {
+			t.Fatalf("expected %d values, got %d", n, len(expectedValues))
+		}
+
+		for _, algorithm := range []TopKAlgorithm{TopKAuto, TopKHeap, TopKSelect} {
+			t.Run(fmt.Sprintf("%s_%d", algorithm, n), func(t *testing.T) {
+				topK, err := newTopKCollector(algorithm, n, len(docs))
+				if err != nil {
+					t.Fatal("expected no error, got", err)
+				}
+				// Equal similarities are ordered by ID, independent of the
+				// algorithm and the order of the docs.
+				for _, i := range r.Perm(len(docs)) {
+					topK.add(docs[i])
+				}
+				if got := topK.values(); !reflect.DeepEqual(got, expectedValues) {
+					t.Fatalf("expected %v, got %v", expectedValues, got)
+				}
+			})
+		}
+	}
+
+	_, err := newTopKCollector("foo", 1, 1)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_QueryWithOptions_TopKAlgorithm(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < 100; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: []float32{float32(i), 100}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	for _, algorithm := range []TopKAlgorithm{TopKAuto, TopKHeap, TopKSelect, TopKSort} {
+		res, err := c.QueryWithOptions(ctx, QueryOptions{
+			QueryEmbedding: []float32{1, 0},
+			NResults:       60,
+			TopKAlgorithm:  algorithm,
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 60 || res[0].ID != "99" || res[59].ID != "40" {
+			t.Fatalf("expected documents 99 to 40 with algorithm %q, got %v", algorithm, res)
+		}
+	}
+
+	_, err = c.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: []float32{1, 0},
+		NResults:       1,
+		TopKAlgorithm:  "foo",
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+// BenchmarkTopK compares the algorithms for different ratios of results to
+// documents. The threshold of TopKAuto (topKSelectRatio) is where the heap stops
+// being the fastest algorithm.
+func BenchmarkTopK(b *testing.B) {
+	const corpusSize = 100_000
+	r := rand.New(rand.NewSource(42))
+	docs := make([]docSim, corpusSize)
+	for i := range docs {
+		docs[i] = docSim{docID: strconv.Itoa(i), similarity: r.Float32()}
+	}
+
+	for _, ratio := range []float64{0.001, 0.01, 0.02, 0.1, 0.5, 1} {
+		n := int(ratio * corpusSize)
+		for _, algorithm := range []TopKAlgorithm{TopKHeap, TopKSelect, TopKSort} {
+			b.Run(fmt.Sprintf("%v_%s", ratio, algorithm), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					topK, err := newTopKCollector(algorithm, n, corpusSize)
+					if err != nil {
+						b.Fatal("expected no error, got", err)
+					}
+					for _, doc := range docs {
+						topK.add(doc)
+					}
+					_ = topK.values()
+				}
+			})
+		}
+	}
+}
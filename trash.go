@@ -0,0 +1,248 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// trashDirName is the name of the subdirectory of a collection's directory in
+// which soft deleted documents are persisted.
+const trashDirName = "trash"
+
+// trashedDocument is a soft deleted document. Its fields are exported so that
+// it can be persisted as gob.
+type trashedDocument struct {
+	// The document including its content, even with content spillover.
+	Document  *Document
+	DeletedAt time.Time
+}
+
+// TrashedDocument is a soft deleted document, as returned by [Collection.Trash].
+type TrashedDocument struct {
+	ID        string
+	DeletedAt time.Time
+}
+
+// SetSoftDelete enables or disables soft deletes. When they're enabled,
+// [Collection.Delete] moves documents to the collection's trash instead of
+// deleting them permanently. Trashed documents are excluded from queries and
+// all other methods, but can be restored with [Collection.Restore] within the
+// purge window. This protects against accidental bulk deletes, for example by
+// buggy ingestion code.
+//
+// Documents that have been in the trash for longer than purgeAfter are purged,
+// i.e. deleted permanently, when documents are deleted or when
+// [Collection.PurgeTrash] is called.
+// The setting is persisted. The trash itself is persisted for persistent
+// collections, but it's not part of exports.
+//
+//   - purgeAfter: The purge window. 0 disables soft deletes, which doesn't
+//     affect documents that are in the trash already.
+func (c *Collection) SetSoftDelete(purgeAfter time.Duration) error {
+	if purgeAfter < 0 {
+		return errors.New("purgeAfter must be >= 0")
+	}
+
+	c.configLock.Lock()
+	c.config.SoftDeletePurgeAfter = purgeAfter
+	c.configLock.Unlock()
+
+	return c.persistMetadata()
+}
+
+// Trash returns the soft deleted documents of the collection, sorted by ID.
+func (c *Collection) Trash() []TrashedDocument {
+	c.documentsLock.RLock()
+	res := make([]TrashedDocument, 0, len(c.trash))
+	for id, t := range c.trash {
+		res = append(res, TrashedDocument{ID: id, DeletedAt: t.DeletedAt})
+	}
+	c.documentsLock.RUnlock()
+
+	slices.SortFunc(res, func(a, b TrashedDocument) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return res
+}
+
+// Restore moves soft deleted documents from the trash back into the collection.
+// It returns an error if one of the documents isn't in the trash, or if a
+// document with the same ID has been added in the meantime. In that case none
+// of the documents are restored.
+func (c *Collection) Restore(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return errors.New("ids are empty")
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	for _, id := range ids {
+		t, ok := c.trash[id]
+		if !ok {
+			return fmt.Errorf("%w in the trash: %q", ErrDocumentNotFound, id)
+		}
+		if _, ok := c.documents[id]; ok {
+			return fmt.Errorf("document %q exists already", id)
+		}
+		if dim := c.dimensionLocked(""); dim != 0 && dim != len(t.Document.Embedding) {
+			return &DimensionMismatchError{DocumentID: id, Expected: dim, Actual: len(t.Document.Embedding)}
+		}
+	}
+
+	// Also when restoring some of the documents fails.
+	defer c.added(ctx)
+	for _, id := range ids {
+		doc := c.trash[id].Document
+		if c.persistDirectory != "" {
+			err := c.persistDocument(doc)
+			if err != nil {
+				return err
+			}
+			trashPath := c.getTrashPath(id)
+			err = removeFile(trashPath)
+			if err != nil {
+				return fmt.Errorf("couldn't remove trashed document at %q: %w", trashPath, err)
+			}
+		}
+
+		stored := doc
+		if c.contentCache != nil {
+			withoutContent := *doc
+			withoutContent.Content = ""
+			stored = &withoutContent
+		}
+		c.documents[id] = stored
+		c.indexDocumentLocked(stored)
+		c.memoryUsage.Add(documentMemoryUsage(stored).Total())
+		delete(c.trash, id)
+	}
+
+	return c.audit(ctx, AuditActionRestore, ids...)
+}
+
+// PurgeTrash permanently deletes the documents that have been in the trash for
+// longer than the purge window set with [Collection.SetSoftDelete]. If soft
+// deletes are disabled, all documents in the trash are deleted.
+// It returns the number of deleted documents.
+func (c *Collection) PurgeTrash() (int, error) {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	return c.purgeTrashLocked(context.Background())
+}
+
+// purgeTrashLocked is like PurgeTrash, but the caller must hold the documents
+// lock for writing. The purged documents are recorded in the audit log with
+// the context.
+func (c *Collection) purgeTrashLocked(ctx context.Context) (purged int, err error) {
+	purgeAfter := c.getConfig().SoftDeletePurgeAfter
+	now := time.Now()
+	var ids []string
+	defer func() {
+		if auditErr := c.auditSystem(ctx, AuditActionPurge, ids...); err == nil {
+			err = auditErr
+		}
+	}()
+	for id, t := range c.trash {
+		if purgeAfter != 0 && now.Sub(t.DeletedAt) <= purgeAfter {
+			continue
+		}
+		if c.persistDirectory != "" {
+			trashPath := c.getTrashPath(id)
+			err := removeFile(trashPath)
+			if err != nil {
+				return purged, fmt.Errorf("couldn't remove trashed document at %q: %w", trashPath, err)
+			}
+		}
+		delete(c.trash, id)
+		ids = append(ids, id)
+		purged++
+	}
+	if len(c.trash) == 0 {
+		c.trash = nil
+	}
+	return purged, nil
+}
+
+// trashLocked moves the document to the trash. The caller must hold the
+// documents lock for writing, and the document must be removed from the
+// collection's documents afterwards.
+func (c *Collection) trashLocked(doc *Document) error {
+	// With content spillover, the content is only on disk.
+	doc, err := c.withContentLocked(doc)
+	if err != nil {
+		return err
+	}
+	t := &trashedDocument{
+		Document:  doc,
+		DeletedAt: time.Now(),
+	}
+	if c.persistDirectory != "" {
+		trashPath := c.getTrashPath(doc.ID)
+		err := persistToFileSynced(trashPath, t, c.compress, "", c.syncer)
+		if err != nil {
+			return fmt.Errorf("couldn't persist trashed document to %q: %w", trashPath, err)
+		}
+	}
+	if c.trash == nil {
+		c.trash = make(map[string]*trashedDocument)
+	}
+	c.trash[doc.ID] = t
+	return nil
+}
+
+// loadTrash reads the persisted trash of the collection. It's a no-op if the
+// collection has no trash directory.
+func (c *Collection) loadTrash(ext string) error {
+	trashDir := filepath.Join(c.persistDirectory, trashDirName)
+	entries, err := os.ReadDir(trashDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("couldn't read trash directory: %w", err)
+	}
+	renames := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !(strings.HasSuffix(entry.Name(), ext) || strings.HasSuffix(entry.Name(), ext+migratingSuffix)) {
+			continue
+		}
+		fPath := filepath.Join(trashDir, entry.Name())
+		t := &trashedDocument{}
+		err := readFromFile(fPath, t, "")
+		if err != nil {
+			return fmt.Errorf("couldn't read trashed document: %w", err)
+		}
+		if c.trash == nil {
+			c.trash = make(map[string]*trashedDocument)
+		}
+		c.trash[t.Document.ID] = t
+		// Files of earlier versions are named by a hash of the ID.
+		if trashPath := c.getTrashPath(t.Document.ID); fPath != trashPath {
+			renames[fPath] = trashPath
+		}
+	}
+	err = renameFiles(renames)
+	if err != nil {
+		return fmt.Errorf("couldn't migrate trashed document file names: %w", err)
+	}
+	return nil
+}
+
+// getTrashPath generates the path to the trashed document's file.
+func (c *Collection) getTrashPath(docID string) string {
+	safeID := EncodePathName(docID)
+	trashPath := filepath.Join(c.persistDirectory, trashDirName, safeID)
+	trashPath += ".gob"
+	if c.compress {
+		trashPath += ".gz"
+	}
+	return trashPath
+}
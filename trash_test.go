@@ -0,0 +1,121 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCollection_SoftDelete(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetSoftDelete(time.Hour)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Embedding: []float32{-0.40824828, 0.40824828, 0.81649655}, Content: "hello world"},
+		{ID: "2", Embedding: []float32{0.40824828, -0.40824828, 0.81649655}, Content: "hallo welt"},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Deleted documents are moved to the trash
+	err = c.Delete(ctx, nil, nil, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != 1 {
+		t.Fatal("expected 1 document, got", c.Count())
+	}
+	trash := c.Trash()
+	if len(trash) != 1 || trash[0].ID != "1" {
+		t.Fatalf("expected document 1 in trash, got %+v", trash)
+	}
+
+	// The trash is persisted
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	if len(c.Trash()) != 1 {
+		t.Fatal("expected 1 document in trash, got", len(c.Trash()))
+	}
+
+	// Restoring
+	err = c.Restore(ctx, "2")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.Restore(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != 2 || len(c.Trash()) != 0 {
+		t.Fatalf("expected 2 documents and empty trash, got %d and %d", c.Count(), len(c.Trash()))
+	}
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	if c.Count() != 2 || len(c.Trash()) != 0 {
+		t.Fatalf("expected 2 documents and empty trash, got %d and %d", c.Count(), len(c.Trash()))
+	}
+	if got := c.documents["1"].Content; got != "hello world" {
+		t.Fatal("expected content \"hello world\", got", got)
+	}
+
+	// Purging after the window
+	err = c.Delete(ctx, nil, nil, "1", "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	n, err := c.PurgeTrash()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if n != 0 {
+		t.Fatal("expected no purged documents, got", n)
+	}
+	c.trash["1"].DeletedAt = time.Now().Add(-2 * time.Hour)
+	n, err = c.PurgeTrash()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if n != 1 || len(c.Trash()) != 1 {
+		t.Fatalf("expected 1 purged and 1 trashed document, got %d and %d", n, len(c.Trash()))
+	}
+
+	// Disabling soft deletes
+	err = c.SetSoftDelete(0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	n, err = c.PurgeTrash()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if n != 1 || len(c.Trash()) != 0 {
+		t.Fatalf("expected 1 purged document and empty trash, got %d and %d", n, len(c.Trash()))
+	}
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	if c.Count() != 0 || len(c.Trash()) != 0 {
+		t.Fatalf("expected no documents and empty trash, got %d and %d", c.Count(), len(c.Trash()))
+	}
+}
@@ -0,0 +1,536 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// txJournalFileName is the name of the file in a collection's directory, to
+// which the changes of a transaction are written before they're applied. If it
+// exists when the DB is loaded, the changes are applied again.
+const txJournalFileName = "transaction.journal"
+
+// Txn is a transaction of a collection, see [Collection.Tx]. Its methods only
+// stage the changes, which are applied when the transaction is committed.
+// It's safe for concurrent use, for example to create embeddings concurrently.
+type Txn struct {
+	c      *Collection
+	ctx    context.Context
+	config collectionConfig
+
+	lock sync.Mutex
+	// The staged documents by ID, nil for deleted ones.
+	docs map[string]*Document
+	// The IDs of docs in the order of the first change, so the changes are
+	// applied deterministically.
+	ids []string
+	// Filters of documents to delete when the transaction is committed.
+	deleteFilters []*Filter
+	done          bool
+}
+
+// Tx runs fn with a transaction, whose adds, updates and deletes are applied
+// atomically when fn returns nil: either all of them are applied, in memory and
+// on disk, or none. This is useful when the documents depend on each other,
+// for example when replacing all chunks of a re-parsed source document.
+// If fn returns an error, the changes are discarded and the error is returned.
+//
+// The documents are validated and their embeddings are created when they're
+// added to the transaction, so committing it can only fail when the documents
+// don't fit together with the collection's, for example because of different
+// dimensions or the collection's limits, or on write errors. Queries during
+// the commit see either none or all of the changes. If the process crashes
+// while a persistent collection is committing the transaction, the changes are
+// applied when the DB is loaded again.
+//
+// With soft deletes, deleted documents are moved to the trash, see
+// [Collection.SetSoftDelete]. With the [MemoryBudgetEvictRandom] policy,
+// documents that are evicted to make room for the transaction's documents are
+// removed independent of the transaction.
+func (c *Collection) Tx(ctx context.Context, fn func(tx *Txn) error) error {
+	if err := c.db.checkOpen(); err != nil {
+		return err
+	}
+	tx := &Txn{
+		c:      c,
+		ctx:    ctx,
+		config: c.getConfig(),
+		docs:   make(map[string]*Document),
+	}
+	err := fn(tx)
+	tx.lock.Lock()
+	tx.done = true
+	tx.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return c.commit(ctx, tx)
+}
+
+// AddDocument stages the document to be added, or to replace the document with
+// the same ID. Like [Collection.AddDocument], it creates the embedding if the
+// document doesn't have one.
+func (tx *Txn) AddDocument(doc Document) error {
+	doc, err := tx.c.preprocessDocument(doc)
+	if err != nil {
+		return err
+	}
+	if err := tx.c.checkDocument(doc, tx.config); err != nil {
+		return err
+	}
+	doc, err = tx.c.prepareDocument(tx.ctx, doc, tx.config)
+	if err != nil {
+		return err
+	}
+	return tx.stage(doc.ID, &doc)
+}
+
+// AddDocuments stages the documents like [Txn.AddDocument], one after another.
+func (tx *Txn) AddDocuments(docs []Document) error {
+	for _, doc := range docs {
+		err := tx.AddDocument(doc)
+		if err != nil {
+			return fmt.Errorf("couldn't add document '%s': %w", doc.ID, err)
+		}
+	}
+	return nil
+}
+
+// Delete stages the documents with the given IDs to be deleted, including the
+// ones added in the transaction before and archived ones (see
+// [Collection.Archive]). IDs of documents that don't exist are ignored.
+func (tx *Txn) Delete(ids ...string) error {
+	for _, id := range ids {
+		if err := tx.stage(id, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteWhere stages the documents that match the metadata and content
+// filters to be deleted. Unlike [Txn.Delete], the filters are applied when the
+// transaction is committed, so documents that are added concurrently are
+// deleted as well. Documents that are added in the transaction aren't deleted.
+// See [CompileFilter] for the filters.
+func (tx *Txn) DeleteWhere(where, whereDocument map[string]string) error {
+	if len(where) == 0 && len(whereDocument) == 0 {
+		return errors.New("must have at least one of where or whereDocument")
+	}
+	filter, err := CompileFilter(where, whereDocument)
+	if err != nil {
+		return err
+	}
+
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.done {
+		return errors.New("transaction is done")
+	}
+	tx.deleteFilters = append(tx.deleteFilters, filter)
+	return nil
+}
+
+func (tx *Txn) stage(id string, doc *Document) error {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.done {
+		return errors.New("transaction is done")
+	}
+	if _, ok := tx.docs[id]; !ok {
+		tx.ids = append(tx.ids, id)
+	}
+	tx.docs[id] = doc
+	return nil
+}
+
+// txJournal contains the changes of a transaction.
+type txJournal struct {
+	Docs    []*Document
+	Deletes []string
+}
+
+// commit applies the changes of the transaction.
+func (c *Collection) commit(ctx context.Context, tx *Txn) error {
+	if len(tx.ids) == 0 && len(tx.deleteFilters) == 0 {
+		return nil
+	}
+	if err := c.db.checkOpen(); err != nil {
+		return err
+	}
+
+	// Check the memory budget. This is done before locking the documents, as it
+	// needs to lock the DB's collections.
+	var usage int64
+	for _, doc := range tx.docs {
+		if doc != nil {
+			usage += documentMemoryUsage(doc).Total()
+		}
+	}
+	evictBytes, err := c.checkMemoryBudget(usage)
+	if err != nil {
+		return fmt.Errorf("couldn't commit transaction: %w", err)
+	}
+	accessStats := c.getConfig().AccessStats
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	for _, filter := range tx.deleteFilters {
+		docs, err := c.filterDocsLocked(filter)
+		if err != nil {
+			return err
+		}
+		// Sorted, so the changes are applied deterministically.
+		slices.SortFunc(docs, func(a, b *Document) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+		for _, doc := range docs {
+			if _, ok := tx.docs[doc.ID]; !ok {
+				tx.ids = append(tx.ids, doc.ID)
+				tx.docs[doc.ID] = nil
+			}
+		}
+	}
+	if err := c.checkTxLocked(tx); err != nil {
+		return err
+	}
+	// Also when the commit fails, as documents might have been evicted.
+	// Updates, deletes and evictions change the results of all sessions.
+	onlyAdds := evictBytes <= 0
+	for _, id := range tx.ids {
+		if _, ok := c.documents[id]; ok || tx.docs[id] == nil {
+			onlyAdds = false
+			break
+		}
+	}
+	defer func() {
+		if onlyAdds {
+			c.added(ctx)
+		} else {
+			c.changed()
+		}
+	}()
+	var evicted []string
+	if evictBytes > 0 {
+		ids, err := c.evictLocked(evictBytes, "", accessStats)
+		if err != nil {
+			return fmt.Errorf("couldn't commit transaction: %w", err)
+		}
+		for _, id := range ids {
+			// Evicted documents that the transaction adds again are replaced
+			// anyway.
+			if tx.docs[id] == nil {
+				evicted = append(evicted, id)
+			}
+		}
+	}
+
+	// Keep the previous documents with their content, to roll back.
+	journal := txJournal{}
+	previous := make(map[string]*Document)
+	for _, id := range tx.ids {
+		if old, ok := c.documents[id]; ok {
+			old, err := c.withContentLocked(old)
+			if err != nil {
+				return err
+			}
+			previous[id] = old
+		}
+		if doc := tx.docs[id]; doc != nil {
+			journal.Docs = append(journal.Docs, doc)
+		} else if _, ok := previous[id]; ok {
+			journal.Deletes = append(journal.Deletes, id)
+		}
+	}
+
+	// Move the deleted documents to the trash, and restore the previous trash
+	// on errors.
+	var trashed map[string]*trashedDocument
+	if c.getConfig().SoftDeletePurgeAfter != 0 {
+		trashed = make(map[string]*trashedDocument)
+		for _, id := range journal.Deletes {
+			trashed[id] = c.trash[id]
+			err := c.trashLocked(previous[id])
+			if err != nil {
+				c.restoreTrashLocked(trashed)
+				return fmt.Errorf("couldn't move document %q to trash: %w", id, err)
+			}
+		}
+	}
+
+	if err := c.applyTxJournal(journal, previous); err != nil {
+		c.restoreTrashLocked(trashed)
+		return err
+	}
+
+	for _, id := range evicted {
+		err := c.removePersistedDocument(id)
+		if err != nil {
+			return fmt.Errorf("couldn't remove evicted document: %w", err)
+		}
+	}
+
+	// Apply the changes in memory.
+	var added, updated []string
+	for _, doc := range journal.Docs {
+		stored := doc
+		if c.contentCache != nil {
+			withoutContent := *doc
+			withoutContent.Content = ""
+			stored = &withoutContent
+			c.contentCache.add(doc.ID, doc.Content)
+		}
+		usage := documentMemoryUsage(stored).Total()
+		if old, ok := c.documents[doc.ID]; ok {
+			usage -= documentMemoryUsage(old).Total()
+			updated = append(updated, doc.ID)
+		} else {
+			added = append(added, doc.ID)
+		}
+		c.documents[doc.ID] = stored
+		c.indexDocumentLocked(stored)
+		c.memoryUsage.Add(usage)
+	}
+	for _, id := range journal.Deletes {
+		if old, ok := c.documents[id]; ok {
+			c.memoryUsage.Add(-documentMemoryUsage(old).Total())
+		}
+		delete(c.documents, id)
+		c.unindexDocumentLocked(id)
+		if c.contentCache != nil {
+			c.contentCache.remove(id)
+		}
+	}
+	// Archived documents are deleted after the transaction is applied. They
+	// aren't in its journal, so after a crash they might still be archived.
+	var deleteIDs []string
+	for _, id := range tx.ids {
+		if tx.docs[id] == nil {
+			deleteIDs = append(deleteIDs, id)
+		}
+	}
+	archived, err := c.deleteArchivedLocked(deleteIDs, tx.deleteFilters...)
+	if err != nil {
+		return fmt.Errorf("couldn't delete archived documents: %w", err)
+	}
+	deleted := appendMissing(slices.Clone(journal.Deletes), archived)
+
+	if c.trash != nil {
+		_, err := c.purgeTrashLocked(ctx)
+		if err != nil {
+			return fmt.Errorf("couldn't purge trash: %w", err)
+		}
+	}
+
+	for _, entry := range []struct {
+		action AuditAction
+		ids    []string
+	}{
+		{AuditActionAdd, added},
+		{AuditActionUpdate, updated},
+		{AuditActionDelete, deleted},
+	} {
+		if err := c.audit(ctx, entry.action, entry.ids...); err != nil {
+			return err
+		}
+	}
+	return c.auditSystem(ctx, AuditActionEvict, evicted...)
+}
+
+// checkTxLocked returns an error if the documents of the transaction don't fit
+// together with the collection's, because of different dimensions or because
+// they would exceed the maximum number of documents. The caller must hold the
+// documents lock.
+func (c *Collection) checkTxLocked(tx *Txn) error {
+	// The dimension of the documents that the transaction doesn't change.
+	dim := 0
+	for id, doc := range c.documents {
+		if _, ok := tx.docs[id]; !ok {
+			dim = len(doc.Embedding)
+			break
+		}
+	}
+	count := len(c.documents)
+	firstAdded := ""
+	for _, id := range tx.ids {
+		doc := tx.docs[id]
+		_, exists := c.documents[id]
+		if doc == nil {
+			if exists {
+				count--
+			}
+			continue
+		}
+		if dim == 0 {
+			dim = len(doc.Embedding)
+		} else if dim != len(doc.Embedding) {
+			return &DimensionMismatchError{DocumentID: id, Expected: dim, Actual: len(doc.Embedding)}
+		}
+		if !exists {
+			count++
+			if firstAdded == "" {
+				firstAdded = id
+			}
+		}
+	}
+	limits := tx.config.Limits
+	if limits.MaxDocuments > 0 && count > limits.MaxDocuments && count > len(c.documents) {
+		return &LimitError{DocumentID: firstAdded, Limit: "MaxDocuments", Max: limits.MaxDocuments, Actual: count}
+	}
+	return nil
+}
+
+// restoreTrashLocked restores the given entries of the trash, nil ones by
+// removing them. The caller must hold the documents lock for writing.
+func (c *Collection) restoreTrashLocked(trash map[string]*trashedDocument) {
+	for id, t := range trash {
+		if t != nil {
+			c.trash[id] = t
+			if c.persistDirectory != "" {
+				_ = persistToFileSynced(c.getTrashPath(id), t, c.compress, "", c.syncer)
+			}
+			continue
+		}
+		delete(c.trash, id)
+		if c.persistDirectory != "" {
+			_ = removeFile(c.getTrashPath(id))
+		}
+	}
+}
+
+// applyTxJournal writes the changes of a transaction to disk, if the
+// collection is persistent. The journal is written first, so that the changes
+// can be applied again after a crash. On errors, the previous documents are
+// restored.
+func (c *Collection) applyTxJournal(journal txJournal, previous map[string]*Document) error {
+	if c.persistDirectory == "" {
+		return nil
+	}
+
+	journalPath := filepath.Join(c.persistDirectory, txJournalFileName)
+	tmpPath := journalPath + ".tmp"
+	err := persistToFileSynced(tmpPath, journal, c.compress, "", c.syncer)
+	if err != nil {
+		return fmt.Errorf("couldn't persist transaction journal: %w", err)
+	}
+	err = os.Rename(tmpPath, journalPath)
+	if err != nil {
+		return fmt.Errorf("couldn't rename transaction journal file: %w", err)
+	}
+	err = c.syncer.syncDir(journalPath)
+	if err != nil {
+		return fmt.Errorf("couldn't persist transaction journal: %w", err)
+	}
+
+	// Apply the changes, and roll them back on errors.
+	var applied []string
+	err = func() error {
+		for _, doc := range journal.Docs {
+			applied = append(applied, doc.ID)
+			if err := c.persistDocument(doc); err != nil {
+				return err
+			}
+		}
+		for _, id := range journal.Deletes {
+			applied = append(applied, id)
+			if err := c.removePersistedDocument(id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		for _, id := range applied {
+			if old, ok := previous[id]; ok {
+				_ = c.persistDocument(old)
+			} else {
+				_ = c.removePersistedDocument(id)
+			}
+		}
+		_ = removeFile(journalPath)
+		return fmt.Errorf("couldn't commit transaction: %w", err)
+	}
+
+	err = removeFile(journalPath)
+	if err != nil {
+		return fmt.Errorf("couldn't remove transaction journal: %w", err)
+	}
+	return c.syncer.syncDir(journalPath)
+}
+
+// replayTxJournal applies the changes of a transaction that was interrupted by
+// a crash, if there is one. It's called when the collection is loaded, before
+// the DB is used.
+func (c *Collection) replayTxJournal() error {
+	journalPath := filepath.Join(c.persistDirectory, txJournalFileName)
+	if _, err := os.Stat(journalPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	journal := txJournal{}
+	err := readFromFile(journalPath, &journal, "")
+	if err != nil {
+		return fmt.Errorf("couldn't read transaction journal: %w", err)
+	}
+	for _, doc := range journal.Docs {
+		if err := c.persistDocument(doc); err != nil {
+			return err
+		}
+		c.documents[doc.ID] = doc
+	}
+	for _, id := range journal.Deletes {
+		if err := c.removePersistedDocument(id); err != nil {
+			return err
+		}
+		delete(c.documents, id)
+	}
+	err = removeFile(journalPath)
+	if err != nil {
+		return fmt.Errorf("couldn't remove transaction journal: %w", err)
+	}
+	return c.syncer.syncDir(journalPath)
+}
+
+// ReplaceGroup atomically replaces the documents whose metadata has the given
+// value for the key with the given documents, for example to replace all
+// chunks of a source file after it changed. The documents get the key and
+// value in their metadata, if they don't have it already. See [Collection.Tx]
+// for the atomicity.
+func (c *Collection) ReplaceGroup(ctx context.Context, groupKey, groupValue string, docs []Document) error {
+	if groupKey == "" || groupValue == "" {
+		// Empty values would also match documents without the key.
+		return errors.New("group key and value must not be empty")
+	}
+	return c.Tx(ctx, func(tx *Txn) error {
+		err := tx.DeleteWhere(map[string]string{groupKey: groupValue}, nil)
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if value, ok := doc.Metadata[groupKey]; !ok {
+				doc.Metadata = maps.Clone(doc.Metadata)
+				if doc.Metadata == nil {
+					doc.Metadata = make(map[string]string, 1)
+				}
+				doc.Metadata[groupKey] = groupValue
+			} else if value != groupValue {
+				return fmt.Errorf("document %q has the value %q for the group key %q instead of %q", doc.ID, value, groupKey, groupValue)
+			}
+			err := tx.AddDocument(doc)
+			if err != nil {
+				return fmt.Errorf("couldn't add document '%s': %w", doc.ID, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,242 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCollection_Tx(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Content: "one"},
+		{ID: "2", Embedding: []float32{0, 1}, Content: "two"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	checkDocs := func(t *testing.T, c *Collection, expected map[string]string) {
+		t.Helper()
+		if c.Count() != len(expected) {
+			t.Fatalf("expected %d documents, got %d", len(expected), c.Count())
+		}
+		for id, content := range expected {
+			doc, ok := c.documents[id]
+			if !ok {
+				t.Fatal("expected document", id)
+			}
+			if doc.Content != content {
+				t.Fatalf("expected content %q of document %s, got %q", content, id, doc.Content)
+			}
+		}
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		err := c.Tx(ctx, func(tx *Txn) error {
+			if err := tx.Delete("1", "missing"); err != nil {
+				return err
+			}
+			return tx.AddDocuments([]Document{
+				{ID: "2", Embedding: []float32{0, 1}, Content: "two v2"},
+				{ID: "3", Embedding: []float32{1, 1}, Content: "three"},
+			})
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		expected := map[string]string{"2": "two v2", "3": "three"}
+		checkDocs(t, c, expected)
+
+		// Persisted
+		db2, err := NewPersistentDB(dir, false)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		checkDocs(t, db2.GetCollection("test", nil), expected)
+	})
+
+	t.Run("error in fn", func(t *testing.T) {
+		errFn := errors.New("fn failed")
+		err := c.Tx(ctx, func(tx *Txn) error {
+			if err := tx.Delete("2"); err != nil {
+				return err
+			}
+			return errFn
+		})
+		if !errors.Is(err, errFn) {
+			t.Fatal("expected fn error, got", err)
+		}
+		checkDocs(t, c, map[string]string{"2": "two v2", "3": "three"})
+	})
+
+	t.Run("dimension mismatch", func(t *testing.T) {
+		err := c.Tx(ctx, func(tx *Txn) error {
+			if err := tx.Delete("2"); err != nil {
+				return err
+			}
+			return tx.AddDocument(Document{ID: "4", Embedding: []float32{1, 0, 0}, Content: "four"})
+		})
+		if !errors.Is(err, ErrDimensionMismatch) {
+			t.Fatal("expected ErrDimensionMismatch, got", err)
+		}
+		checkDocs(t, c, map[string]string{"2": "two v2", "3": "three"})
+	})
+
+	t.Run("replacing all documents", func(t *testing.T) {
+		// All documents can get a new dimension at once.
+		err := c.Tx(ctx, func(tx *Txn) error {
+			if err := tx.Delete("2", "3"); err != nil {
+				return err
+			}
+			return tx.AddDocument(Document{ID: "4", Embedding: []float32{1, 0, 0}, Content: "four"})
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		checkDocs(t, c, map[string]string{"4": "four"})
+	})
+
+	t.Run("use after done", func(t *testing.T) {
+		var txn *Txn
+		err := c.Tx(ctx, func(tx *Txn) error {
+			txn = tx
+			return nil
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = txn.Delete("4")
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		checkDocs(t, c, map[string]string{"4": "four"})
+	})
+}
+
+func TestCollection_Tx_Replay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Content: "one"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// A journal that's left over from a crash during the commit
+	journal := txJournal{
+		Docs:    []*Document{{ID: "2", Embedding: []float32{0, 1}, Content: "two"}},
+		Deletes: []string{"1"},
+	}
+	journalPath := filepath.Join(c.persistDirectory, txJournalFileName)
+	err = persistToFile(journalPath, journal, false, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	var ids []string
+	for id := range c2.documents {
+		ids = append(ids, id)
+	}
+	if !slices.Equal(ids, []string{"2"}) {
+		t.Fatal("expected document 2, got", ids)
+	}
+	if c2.documents["2"].Content != "two" {
+		t.Fatal("expected content two, got", c2.documents["2"].Content)
+	}
+
+	// The journal is removed, and the changes are persisted.
+	if _, err := os.Stat(journalPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("expected journal to be removed, got", err)
+	}
+	db3, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if n := db3.GetCollection("test", nil).Count(); n != 1 {
+		t.Fatal("expected 1 document, got", n)
+	}
+}
+
+func TestCollection_ReplaceGroup(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "a.md#1", Embedding: []float32{1, 0}, Metadata: map[string]string{"source": "a.md"}, Content: "a1"},
+		{ID: "a.md#2", Embedding: []float32{1, 0}, Metadata: map[string]string{"source": "a.md"}, Content: "a2"},
+		{ID: "b.md#1", Embedding: []float32{0, 1}, Metadata: map[string]string{"source": "b.md"}, Content: "b1"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Invalid arguments
+	if err := c.ReplaceGroup(ctx, "source", "", nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.ReplaceGroup(ctx, "source", "a.md", []Document{
+		{ID: "a.md#1", Embedding: []float32{1, 0}, Metadata: map[string]string{"source": "b.md"}},
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if c.Count() != 3 {
+		t.Fatal("expected 3 documents, got", c.Count())
+	}
+
+	// The group gets fewer chunks, with the metadata set automatically.
+	err = c.ReplaceGroup(ctx, "source", "a.md", []Document{
+		{ID: "a.md#1", Embedding: []float32{1, 0}, Content: "a1 v2"},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	var ids []string
+	for id := range c.documents {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"a.md#1", "b.md#1"}) {
+		t.Fatal("expected documents a.md#1 and b.md#1, got", ids)
+	}
+	doc := c.documents["a.md#1"]
+	if doc.Content != "a1 v2" || doc.Metadata["source"] != "a.md" {
+		t.Fatalf("expected replaced document with source metadata, got %+v", doc)
+	}
+
+	// Without documents, the group is deleted.
+	err = c.ReplaceGroup(ctx, "source", "a.md", nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, ok := c.documents["b.md#1"]; !ok || c.Count() != 1 {
+		t.Fatal("expected only document b.md#1, got", c.Count())
+	}
+}
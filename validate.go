@@ -0,0 +1,120 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ValidationError is returned for invalid input, by the validation functions
+// like [ValidateFilter] as well as by the methods that take the input.
+type ValidationError struct {
+	// The invalid field or argument, like "whereDocument" or "Embedding".
+	Field string
+	// The key of the invalid entry, if the field is a map, like the operator
+	// of whereDocument. Empty otherwise.
+	Key string
+	// Why the input is invalid.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateFilter returns a [*ValidationError] if the metadata and content
+// filters are invalid, without compiling them for a query. This allows
+// validating user input, for example from web forms, before querying, and it's
+// suitable for fuzzing. See [CompileFilter] for the filters.
+func ValidateFilter(where, whereDocument map[string]string) error {
+	_, err := CompileFilter(where, whereDocument)
+	return err
+}
+
+// ValidateDocument returns a [*ValidationError] if the document can't be added
+// to a collection, independent of the collection: if the ID is empty, if none
+// of embedding, content and media is set, if the embedding has invalid values
+// or a magnitude of 0, or if the media has neither URL nor data.
+// The collection can reject the document for other reasons, like its
+// [MetadataSchema], or an embedding with a different dimension.
+func ValidateDocument(doc Document) error {
+	if doc.ID == "" {
+		return &ValidationError{Field: "ID", Err: errors.New("document ID is empty")}
+	}
+	if len(doc.Embedding) == 0 && doc.Content == "" && doc.Media == nil {
+		return &ValidationError{Field: "Embedding", Err: errors.New("either document embedding, content or media must be filled")}
+	}
+	if len(doc.Embedding) != 0 {
+		_, err := validateEmbedding(doc.Embedding, NormalizationPolicyNormalize)
+		if err != nil {
+			return &ValidationError{Field: "Embedding", Err: fmt.Errorf("invalid embedding of document %q: %w", doc.ID, err)}
+		}
+	}
+	if doc.Media != nil {
+		err := EmbeddingInput{Media: doc.Media}.validate()
+		if err != nil {
+			return &ValidationError{Field: "Media", Err: fmt.Errorf("invalid media of document %q: %w", doc.ID, err)}
+		}
+	}
+	return nil
+}
+
+// ValidateQueryOptions returns a [*ValidationError] if the query options are
+// invalid, independent of the collection, without creating embeddings or
+// querying. This allows validating user input, for example from web forms,
+// before querying, and it's suitable for fuzzing.
+func ValidateQueryOptions(options QueryOptions) error {
+	if err := validateQueryOptions(options); err != nil {
+		return err
+	}
+	if options.Filter == nil {
+		return ValidateFilter(options.Where, options.WhereDocument)
+	}
+	return nil
+}
+
+// validateQueryOptions is like [ValidateQueryOptions], but it doesn't validate
+// Where and WhereDocument, for callers that compile them anyway.
+func validateQueryOptions(options QueryOptions) error {
+	if options.QueryText == "" && len(options.QueryEmbedding) == 0 && options.QueryMedia == nil && len(options.Concepts) == 0 {
+		return &ValidationError{Field: "QueryText", Err: errors.New("QueryText, QueryEmbedding, QueryMedia and Concepts options are empty")}
+	}
+	if options.NResults <= 0 {
+		return &ValidationError{Field: "NResults", Err: errors.New("nResults must be > 0")}
+	}
+	if options.Filter != nil && (len(options.Where) != 0 || len(options.WhereDocument) != 0) {
+		return &ValidationError{Field: "Filter", Err: errors.New("Filter can't be combined with Where and WhereDocument")}
+	}
+	for i, concept := range options.Concepts {
+		if concept.Weight == 0 {
+			return &ValidationError{Field: "Concepts", Err: fmt.Errorf("weight of concept %d is 0", i)}
+		}
+		if concept.Text == "" && len(concept.Embedding) == 0 {
+			return &ValidationError{Field: "Concepts", Err: fmt.Errorf("text and embedding of concept %d are empty", i)}
+		}
+	}
+	hasNegative := options.Negative.Text != "" || len(options.Negative.Embedding) != 0
+	if hasNegative && options.Negative.Mode != NEGATIVE_MODE_SUBTRACT && options.Negative.Mode != NEGATIVE_MODE_FILTER {
+		return &ValidationError{Field: "Negative", Err: fmt.Errorf("unsupported negative mode: %q", options.Negative.Mode)}
+	}
+	if _, err := options.Limits.start(time.Time{}); err != nil {
+		return &ValidationError{Field: "Limits", Err: err}
+	}
+	if _, err := newTopKCollector(options.TopKAlgorithm, 1, 1); err != nil {
+		return &ValidationError{Field: "TopKAlgorithm", Err: err}
+	}
+	if options.HyDE.Generate != nil && options.QueryText == "" {
+		return &ValidationError{Field: "HyDE", Err: errors.New("HyDE requires QueryText")}
+	}
+	if options.HyDE.Weight < 0 {
+		return &ValidationError{Field: "HyDE", Err: errors.New("weight of HyDE must be >= 0")}
+	}
+	if options.SameLanguage && options.QueryText == "" {
+		return &ValidationError{Field: "SameLanguage", Err: errors.New("SameLanguage requires QueryText")}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package chromem
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestValidateFilter(t *testing.T) {
+	err := ValidateFilter(map[string]string{"category": "a"}, map[string]string{"$contains": "a", "$regex": "^a"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = ValidateFilter(nil, map[string]string{"$foo": "a"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatal("expected validation error, got", err)
+	}
+	if validationErr.Field != "whereDocument" || validationErr.Key != "$foo" {
+		t.Fatalf("expected invalid whereDocument key $foo, got %q and %q", validationErr.Field, validationErr.Key)
+	}
+}
+
+func TestValidateDocument(t *testing.T) {
+	tt := []struct {
+		name        string
+		doc         Document
+		expectField string
+	}{
+		{
+			name: "valid",
+			doc:  Document{ID: "1", Embedding: []float32{1, 2}},
+		},
+		{
+			name:        "empty ID",
+			doc:         Document{Content: "hello"},
+			expectField: "ID",
+		},
+		{
+			name:        "empty",
+			doc:         Document{ID: "1"},
+			expectField: "Embedding",
+		},
+		{
+			name:        "NaN",
+			doc:         Document{ID: "1", Embedding: []float32{float32(math.NaN()), 1}},
+			expectField: "Embedding",
+		},
+		{
+			name:        "zero vector",
+			doc:         Document{ID: "1", Embedding: []float32{0, 0}},
+			expectField: "Embedding",
+		},
+		{
+			name:        "empty media",
+			doc:         Document{ID: "1", Media: &Media{}},
+			expectField: "Media",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDocument(tc.doc)
+			if tc.expectField == "" {
+				if err != nil {
+					t.Fatal("expected no error, got", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatal("expected validation error, got", err)
+			}
+			if validationErr.Field != tc.expectField {
+				t.Fatalf("expected invalid field %q, got %q", tc.expectField, validationErr.Field)
+			}
+		})
+	}
+}
+
+func TestValidateQueryOptions(t *testing.T) {
+	tt := []struct {
+		name        string
+		options     QueryOptions
+		expectField string
+	}{
+		{
+			name:    "valid",
+			options: QueryOptions{QueryText: "hello", NResults: 1},
+		},
+		{
+			name:        "empty query",
+			options:     QueryOptions{NResults: 1},
+			expectField: "QueryText",
+		},
+		{
+			name:        "nResults",
+			options:     QueryOptions{QueryText: "hello"},
+			expectField: "NResults",
+		},
+		{
+			name:        "filter",
+			options:     QueryOptions{QueryText: "hello", NResults: 1, WhereDocument: map[string]string{"$regex": "("}},
+			expectField: "whereDocument",
+		},
+		{
+			name:        "concept weight",
+			options:     QueryOptions{NResults: 1, Concepts: []QueryConcept{{Text: "hello"}}},
+			expectField: "Concepts",
+		},
+		{
+			name:        "negative mode",
+			options:     QueryOptions{QueryText: "hello", NResults: 1, Negative: NegativeQueryOptions{Text: "bye", Mode: "foo"}},
+			expectField: "Negative",
+		},
+		{
+			name:        "limits",
+			options:     QueryOptions{QueryText: "hello", NResults: 1, Limits: QueryLimits{MaxMemory: -1}},
+			expectField: "Limits",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateQueryOptions(tc.options)
+			if tc.expectField == "" {
+				if err != nil {
+					t.Fatal("expected no error, got", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatal("expected validation error, got", err)
+			}
+			if validationErr.Field != tc.expectField {
+				t.Fatalf("expected invalid field %q, got %q", tc.expectField, validationErr.Field)
+			}
+		})
+	}
+}
+
+func FuzzValidateFilter(f *testing.F) {
+	f.Add("category", "a", "$contains", "a")
+	f.Add("", "", "$regex", "^(a|b)+$")
+	f.Add("$id_in", "1,2", "$length_gt", "10")
+	f.Fuzz(func(t *testing.T, key, value, operator, operand string) {
+		err := ValidateFilter(map[string]string{key: value}, map[string]string{operator: operand})
+		var validationErr *ValidationError
+		if err != nil && !errors.As(err, &validationErr) {
+			t.Fatal("expected validation error, got", err)
+		}
+	})
+}
+
+func FuzzValidateDocument(f *testing.F) {
+	f.Add("1", "hello", float32(1), float32(0))
+	f.Add("", "", float32(0), float32(0))
+	f.Fuzz(func(t *testing.T, id, content string, x, y float32) {
+		err := ValidateDocument(Document{ID: id, Content: content, Embedding: []float32{x, y}})
+		var validationErr *ValidationError
+		if err != nil && !errors.As(err, &validationErr) {
+			t.Fatal("expected validation error, got", err)
+		}
+	})
+}
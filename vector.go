@@ -1,7 +1,7 @@
 package chromem
 
 import (
-	"errors"
+	"fmt"
 	"math"
 )
 
@@ -14,7 +14,7 @@ const isNormalizedPrecisionTolerance = 1e-6
 func dotProduct(a, b []float32) (float32, error) {
 	// The vectors must have the same length
 	if len(a) != len(b) {
-		return 0, errors.New("vectors must have the same length")
+		return 0, fmt.Errorf("%w: vectors have %d and %d dimensions", ErrDimensionMismatch, len(a), len(b))
 	}
 
 	var dotProduct float32
@@ -0,0 +1,701 @@
+package chromem
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Metadata keys used by the vector stores facade, on collections and on the
+// documents (file chunks) respectively.
+const (
+	vectorStoreObjectKey    = "openai_object"
+	vectorStoreNameKey      = "openai_name"
+	vectorStoreCreatedAtKey = "openai_created_at"
+	vectorStoreFileIDKey    = "file_id"
+	vectorStoreFilenameKey  = "filename"
+)
+
+// Defaults of OpenAI's "static" chunking strategy. As we don't count tokens, we
+// estimate 4 characters per token, which is OpenAI's rule of thumb for English text.
+const (
+	vectorStoreDefaultChunkTokens   = 800
+	vectorStoreDefaultOverlapTokens = 400
+	vectorStoreCharsPerToken        = 4
+	vectorStoreMaxUploadBytes       = 512 << 20
+)
+
+// NewVectorStoresHandler returns an [http.Handler] implementing the shapes of
+// OpenAI's vector store and file search API on top of the given DB, so that
+// applications written against the Assistants "file_search" API can run fully
+// locally. Each vector store is a collection, each file is split into chunks
+// which are stored as documents.
+//
+// The handler serves the following endpoints, relative to the OpenAI base URL
+// (usually ending with "/v1"):
+//
+//   - POST /files, GET /files, GET /files/{id}, DELETE /files/{id}
+//   - POST /vector_stores, GET /vector_stores, GET /vector_stores/{id},
+//     DELETE /vector_stores/{id}
+//   - POST /vector_stores/{id}/files, GET /vector_stores/{id}/files,
+//     GET /vector_stores/{id}/files/{file_id}, DELETE /vector_stores/{id}/files/{file_id}
+//   - POST /vector_stores/{id}/search
+//
+// To serve it under "/v1", use [http.StripPrefix]:
+//
+//	http.Handle("/v1/", http.StripPrefix("/v1", chromem.NewVectorStoresHandler(db, nil)))
+//
+// Uploaded files are kept in memory only, while the chunks are persisted if the
+// DB is persistent. Search filters support the "eq" comparison and the "and"
+// compound filter. Files are processed synchronously, so they're "completed"
+// when the request returns.
+//
+// The embeddingFunc is used for new vector stores and for persisted ones that
+// don't have an embedding function yet. It can be nil, in which case the default
+// one is used.
+func NewVectorStoresHandler(db *DB, embeddingFunc EmbeddingFunc) http.Handler {
+	return &vectorStoresHandler{
+		db:            db,
+		embeddingFunc: embeddingFunc,
+		files:         make(map[string]*vectorStoreUpload),
+	}
+}
+
+type vectorStoresHandler struct {
+	db            *DB
+	embeddingFunc EmbeddingFunc
+
+	files     map[string]*vectorStoreUpload
+	filesLock sync.RWMutex
+}
+
+type vectorStoreUpload struct {
+	object  openAIFile
+	content []byte
+}
+
+type openAIFile struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int    `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+}
+
+type openAIVectorStore struct {
+	ID         string                `json:"id"`
+	Object     string                `json:"object"`
+	CreatedAt  int64                 `json:"created_at"`
+	Name       string                `json:"name"`
+	UsageBytes int                   `json:"usage_bytes"`
+	FileCounts openAIVectorFileCount `json:"file_counts"`
+	Status     string                `json:"status"`
+	Metadata   map[string]string     `json:"metadata"`
+}
+
+type openAIVectorFileCount struct {
+	InProgress int `json:"in_progress"`
+	Completed  int `json:"completed"`
+	Failed     int `json:"failed"`
+	Cancelled  int `json:"cancelled"`
+	Total      int `json:"total"`
+}
+
+type openAIVectorStoreFile struct {
+	ID            string  `json:"id"`
+	Object        string  `json:"object"`
+	CreatedAt     int64   `json:"created_at"`
+	VectorStoreID string  `json:"vector_store_id"`
+	Status        string  `json:"status"`
+	UsageBytes    int     `json:"usage_bytes"`
+	LastError     *string `json:"last_error"`
+}
+
+type openAIList[T any] struct {
+	Object  string `json:"object"`
+	Data    []T    `json:"data"`
+	HasMore bool   `json:"has_more"`
+}
+
+type openAIChunkingStrategy struct {
+	Type   string `json:"type"`
+	Static struct {
+		MaxChunkSizeTokens int `json:"max_chunk_size_tokens"`
+		ChunkOverlapTokens int `json:"chunk_overlap_tokens"`
+	} `json:"static"`
+}
+
+// openAIFilter is either a comparison filter ("eq" etc.) or a compound filter
+// ("and", "or").
+type openAIFilter struct {
+	Type    string         `json:"type"`
+	Key     string         `json:"key"`
+	Value   any            `json:"value"`
+	Filters []openAIFilter `json:"filters"`
+}
+
+func (h *vectorStoresHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments, err := splitEscapedPath(r.URL.EscapedPath())
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(segments) == 0 {
+		writeOpenAIError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch segments[0] {
+	case "files":
+		switch {
+		case len(segments) == 1 && r.Method == http.MethodPost:
+			h.uploadFile(w, r)
+		case len(segments) == 1 && r.Method == http.MethodGet:
+			h.listFiles(w)
+		case len(segments) == 2 && r.Method == http.MethodGet:
+			h.getFile(w, segments[1])
+		case len(segments) == 2 && r.Method == http.MethodDelete:
+			h.deleteFile(w, segments[1])
+		default:
+			writeOpenAIError(w, http.StatusNotFound, errors.New("not found"))
+		}
+	case "vector_stores":
+		switch {
+		case len(segments) == 1 && r.Method == http.MethodPost:
+			h.createVectorStore(w, r)
+		case len(segments) == 1 && r.Method == http.MethodGet:
+			h.listVectorStores(w, r)
+		case len(segments) == 2 && r.Method == http.MethodGet:
+			h.getVectorStore(w, r, segments[1])
+		case len(segments) == 2 && r.Method == http.MethodDelete:
+			h.deleteVectorStore(w, r, segments[1])
+		case len(segments) == 3 && segments[2] == "files" && r.Method == http.MethodPost:
+			h.createVectorStoreFile(w, r, segments[1])
+		case len(segments) == 3 && segments[2] == "files" && r.Method == http.MethodGet:
+			h.listVectorStoreFiles(w, r, segments[1])
+		case len(segments) == 4 && segments[2] == "files" && r.Method == http.MethodGet:
+			h.getVectorStoreFile(w, r, segments[1], segments[3])
+		case len(segments) == 4 && segments[2] == "files" && r.Method == http.MethodDelete:
+			h.deleteVectorStoreFile(w, r, segments[1], segments[3])
+		case len(segments) == 3 && segments[2] == "search" && r.Method == http.MethodPost:
+			h.search(w, r, segments[1])
+		default:
+			writeOpenAIError(w, http.StatusNotFound, errors.New("not found"))
+		}
+	default:
+		writeOpenAIError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (h *vectorStoresHandler) uploadFile(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, vectorStoreMaxUploadBytes)
+	f, fh, err := r.FormFile("file")
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Errorf("couldn't read file from multipart form: %w", err))
+		return
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Errorf("couldn't read file: %w", err))
+		return
+	}
+
+	upload := &vectorStoreUpload{
+		object: openAIFile{
+			ID:        "file-" + randomHex(12),
+			Object:    "file",
+			Bytes:     len(content),
+			CreatedAt: time.Now().Unix(),
+			Filename:  fh.Filename,
+			Purpose:   r.FormValue("purpose"),
+		},
+		content: content,
+	}
+	h.filesLock.Lock()
+	h.files[upload.object.ID] = upload
+	h.filesLock.Unlock()
+
+	writeJSON(w, http.StatusOK, upload.object)
+}
+
+func (h *vectorStoresHandler) listFiles(w http.ResponseWriter) {
+	h.filesLock.RLock()
+	files := make([]openAIFile, 0, len(h.files))
+	for _, f := range h.files {
+		files = append(files, f.object)
+	}
+	h.filesLock.RUnlock()
+
+	slices.SortFunc(files, func(a, b openAIFile) int {
+		return cmp.Compare(b.CreatedAt, a.CreatedAt)
+	})
+	writeJSON(w, http.StatusOK, openAIList[openAIFile]{Object: "list", Data: files})
+}
+
+func (h *vectorStoresHandler) getFile(w http.ResponseWriter, id string) {
+	h.filesLock.RLock()
+	f, ok := h.files[id]
+	h.filesLock.RUnlock()
+	if !ok {
+		writeOpenAIError(w, http.StatusNotFound, fmt.Errorf("no such file: %q", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, f.object)
+}
+
+func (h *vectorStoresHandler) deleteFile(w http.ResponseWriter, id string) {
+	h.filesLock.Lock()
+	_, ok := h.files[id]
+	delete(h.files, id)
+	h.filesLock.Unlock()
+	if !ok {
+		writeOpenAIError(w, http.StatusNotFound, fmt.Errorf("no such file: %q", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "object": "file", "deleted": true})
+}
+
+func (h *vectorStoresHandler) createVectorStore(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name             string                  `json:"name"`
+		FileIDs          []string                `json:"file_ids"`
+		Metadata         map[string]string       `json:"metadata"`
+		ChunkingStrategy *openAIChunkingStrategy `json:"chunking_strategy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Errorf("couldn't decode request body: %w", err))
+		return
+	}
+
+	// The name of the collection is generated, so the permission is checked
+	// without it.
+	if !authorized(r.Context(), "", PermissionWrite) {
+		writeOpenAIError(w, http.StatusForbidden, errors.New("no permission to create vector stores"))
+		return
+	}
+
+	metadata := make(map[string]string, len(req.Metadata)+3)
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+	metadata[vectorStoreObjectKey] = "vector_store"
+	metadata[vectorStoreNameKey] = req.Name
+	metadata[vectorStoreCreatedAtKey] = strconv.FormatInt(time.Now().Unix(), 10)
+
+	c, err := h.db.CreateCollection("vs_"+randomHex(12), metadata, h.embeddingFunc)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, fileID := range req.FileIDs {
+		if _, status, err := h.addFile(r, c, fileID, nil, req.ChunkingStrategy); err != nil {
+			writeOpenAIError(w, status, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, vectorStoreObject(c))
+}
+
+func (h *vectorStoresHandler) listVectorStores(w http.ResponseWriter, r *http.Request) {
+	var stores []openAIVectorStore
+	for name, c := range h.db.ListCollections() {
+		if c.getMetadata()[vectorStoreObjectKey] == "vector_store" && authorized(r.Context(), name, PermissionRead) {
+			stores = append(stores, vectorStoreObject(c))
+		}
+	}
+	slices.SortFunc(stores, func(a, b openAIVectorStore) int {
+		return cmp.Compare(b.CreatedAt, a.CreatedAt)
+	})
+	writeJSON(w, http.StatusOK, openAIList[openAIVectorStore]{Object: "list", Data: stores})
+}
+
+func (h *vectorStoresHandler) getVectorStore(w http.ResponseWriter, r *http.Request, id string) {
+	c, ok := h.vectorStore(w, r, id, PermissionRead)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, vectorStoreObject(c))
+}
+
+func (h *vectorStoresHandler) deleteVectorStore(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := h.vectorStore(w, r, id, PermissionWrite); !ok {
+		return
+	}
+	if err := h.db.DeleteCollectionWithContext(r.Context(), id); err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "object": "vector_store.deleted", "deleted": true})
+}
+
+func (h *vectorStoresHandler) createVectorStoreFile(w http.ResponseWriter, r *http.Request, id string) {
+	c, ok := h.vectorStore(w, r, id, PermissionWrite)
+	if !ok {
+		return
+	}
+	var req struct {
+		FileID           string                  `json:"file_id"`
+		Attributes       map[string]any          `json:"attributes"`
+		ChunkingStrategy *openAIChunkingStrategy `json:"chunking_strategy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Errorf("couldn't decode request body: %w", err))
+		return
+	}
+	f, status, err := h.addFile(r, c, req.FileID, req.Attributes, req.ChunkingStrategy)
+	if err != nil {
+		writeOpenAIError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+func (h *vectorStoresHandler) listVectorStoreFiles(w http.ResponseWriter, r *http.Request, id string) {
+	c, ok := h.vectorStore(w, r, id, PermissionRead)
+	if !ok {
+		return
+	}
+	files := vectorStoreFiles(c)
+	data := make([]openAIVectorStoreFile, 0, len(files))
+	for _, f := range files {
+		data = append(data, f)
+	}
+	slices.SortFunc(data, func(a, b openAIVectorStoreFile) int {
+		if a.CreatedAt != b.CreatedAt {
+			return cmp.Compare(b.CreatedAt, a.CreatedAt)
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	writeJSON(w, http.StatusOK, openAIList[openAIVectorStoreFile]{Object: "list", Data: data})
+}
+
+func (h *vectorStoresHandler) getVectorStoreFile(w http.ResponseWriter, r *http.Request, id, fileID string) {
+	c, ok := h.vectorStore(w, r, id, PermissionRead)
+	if !ok {
+		return
+	}
+	f, ok := vectorStoreFiles(c)[fileID]
+	if !ok {
+		writeOpenAIError(w, http.StatusNotFound, fmt.Errorf("no such vector store file: %q", fileID))
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+func (h *vectorStoresHandler) deleteVectorStoreFile(w http.ResponseWriter, r *http.Request, id, fileID string) {
+	c, ok := h.vectorStore(w, r, id, PermissionWrite)
+	if !ok {
+		return
+	}
+	if _, ok := vectorStoreFiles(c)[fileID]; !ok {
+		writeOpenAIError(w, http.StatusNotFound, fmt.Errorf("no such vector store file: %q", fileID))
+		return
+	}
+	err := c.Delete(r.Context(), map[string]string{vectorStoreFileIDKey: fileID}, nil)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": fileID, "object": "vector_store.file.deleted", "deleted": true})
+}
+
+func (h *vectorStoresHandler) search(w http.ResponseWriter, r *http.Request, id string) {
+	c, ok := h.vectorStore(w, r, id, PermissionRead)
+	if !ok {
+		return
+	}
+	var req struct {
+		Query         json.RawMessage `json:"query"`
+		MaxNumResults int             `json:"max_num_results"`
+		Filters       *openAIFilter   `json:"filters"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Errorf("couldn't decode request body: %w", err))
+		return
+	}
+	// The query can be a string or an array of strings. We join the latter.
+	var query string
+	if err := json.Unmarshal(req.Query, &query); err != nil {
+		var queries []string
+		if err := json.Unmarshal(req.Query, &queries); err != nil || len(queries) == 0 {
+			writeOpenAIError(w, http.StatusBadRequest, errors.New("query must be a string or an array of strings"))
+			return
+		}
+		for i, q := range queries {
+			if i > 0 {
+				query += "\n"
+			}
+			query += q
+		}
+	}
+	if query == "" {
+		writeOpenAIError(w, http.StatusBadRequest, errors.New("query is empty"))
+		return
+	}
+	nResults := req.MaxNumResults
+	if nResults == 0 {
+		nResults = 10
+	} else if nResults < 1 || nResults > 50 {
+		writeOpenAIError(w, http.StatusBadRequest, errors.New("max_num_results must be between 1 and 50"))
+		return
+	}
+	where := make(map[string]string)
+	if req.Filters != nil {
+		if err := openAIFilterToWhere(*req.Filters, where); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	type searchResult struct {
+		FileID     string            `json:"file_id"`
+		Filename   string            `json:"filename"`
+		Score      float32           `json:"score"`
+		Attributes map[string]string `json:"attributes"`
+		Content    []mcpContent      `json:"content"`
+	}
+	data := []searchResult{}
+	nResults = limitResults(r.Context(), nResults)
+	if count := c.Count(); nResults > count {
+		nResults = count
+	}
+	if nResults > 0 {
+		results, err := c.Query(r.Context(), query, nResults, where, nil)
+		if err != nil {
+			writeOpenAIError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, res := range results {
+			attributes := make(map[string]string, len(res.Metadata))
+			for k, v := range res.Metadata {
+				if k != vectorStoreFileIDKey && k != vectorStoreFilenameKey && k != vectorStoreCreatedAtKey {
+					attributes[k] = v
+				}
+			}
+			data = append(data, searchResult{
+				FileID:     res.Metadata[vectorStoreFileIDKey],
+				Filename:   res.Metadata[vectorStoreFilenameKey],
+				Score:      res.Similarity,
+				Attributes: attributes,
+				Content:    []mcpContent{{Type: "text", Text: res.Content}},
+			})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object":       "vector_store.search_results.page",
+		"search_query": query,
+		"data":         data,
+		"has_more":     false,
+		"next_page":    nil,
+	})
+}
+
+// addFile chunks the uploaded file with the given ID and adds the chunks to the
+// collection. It returns the vector store file object, or an HTTP status code
+// and error.
+func (h *vectorStoresHandler) addFile(r *http.Request, c *Collection, fileID string, attributes map[string]any, strategy *openAIChunkingStrategy) (openAIVectorStoreFile, int, error) {
+	h.filesLock.RLock()
+	f, ok := h.files[fileID]
+	h.filesLock.RUnlock()
+	if !ok {
+		return openAIVectorStoreFile{}, http.StatusNotFound, fmt.Errorf("no such file: %q", fileID)
+	}
+
+	chunkTokens, overlapTokens := vectorStoreDefaultChunkTokens, vectorStoreDefaultOverlapTokens
+	if strategy != nil && strategy.Type == "static" {
+		chunkTokens = strategy.Static.MaxChunkSizeTokens
+		overlapTokens = strategy.Static.ChunkOverlapTokens
+		if chunkTokens < 100 || chunkTokens > 4096 || overlapTokens < 0 || overlapTokens > chunkTokens/2 {
+			return openAIVectorStoreFile{}, http.StatusBadRequest, errors.New("invalid static chunking strategy")
+		}
+	}
+	chunks := splitText(string(f.content), chunkTokens*vectorStoreCharsPerToken, overlapTokens*vectorStoreCharsPerToken)
+
+	createdAt := time.Now().Unix()
+	docs := make([]Document, 0, len(chunks))
+	usageBytes := 0
+	for i, chunk := range chunks {
+		metadata := make(map[string]string, len(attributes)+3)
+		for k, v := range attributes {
+			metadata[k] = fmt.Sprint(v)
+		}
+		metadata[vectorStoreFileIDKey] = fileID
+		metadata[vectorStoreFilenameKey] = f.object.Filename
+		metadata[vectorStoreCreatedAtKey] = strconv.FormatInt(createdAt, 10)
+		docs = append(docs, Document{
+			ID:       fileID + "#" + strconv.Itoa(i),
+			Metadata: metadata,
+			Content:  chunk,
+		})
+		usageBytes += len(chunk)
+	}
+	if len(docs) > 0 {
+		if err := c.AddDocuments(r.Context(), docs, mcpAddConcurrency); err != nil {
+			return openAIVectorStoreFile{}, http.StatusInternalServerError, err
+		}
+	}
+
+	return openAIVectorStoreFile{
+		ID:            fileID,
+		Object:        "vector_store.file",
+		CreatedAt:     createdAt,
+		VectorStoreID: c.Name,
+		Status:        "completed",
+		UsageBytes:    usageBytes,
+	}, http.StatusOK, nil
+}
+
+// vectorStore returns the collection for the vector store with the given ID,
+// or writes a 404 or 403 error response and returns false.
+func (h *vectorStoresHandler) vectorStore(w http.ResponseWriter, r *http.Request, id string, permission Permission) (*Collection, bool) {
+	c := h.db.GetCollection(id, h.embeddingFunc)
+	if c == nil || c.getMetadata()[vectorStoreObjectKey] != "vector_store" {
+		writeOpenAIError(w, http.StatusNotFound, fmt.Errorf("no such vector store: %q", id))
+		return nil, false
+	}
+	if !authorized(r.Context(), id, permission) {
+		writeOpenAIError(w, http.StatusForbidden, fmt.Errorf("no permission for vector store: %q", id))
+		return nil, false
+	}
+	return c, true
+}
+
+// vectorStoreObject converts a collection to an OpenAI vector store object.
+func vectorStoreObject(c *Collection) openAIVectorStore {
+	collectionMetadata := c.getMetadata()
+	metadata := make(map[string]string, len(collectionMetadata))
+	for k, v := range collectionMetadata {
+		if k != vectorStoreObjectKey && k != vectorStoreNameKey && k != vectorStoreCreatedAtKey {
+			metadata[k] = v
+		}
+	}
+	createdAt, _ := strconv.ParseInt(collectionMetadata[vectorStoreCreatedAtKey], 10, 64)
+
+	files := vectorStoreFiles(c)
+	usageBytes := 0
+	for _, f := range files {
+		usageBytes += f.UsageBytes
+	}
+
+	return openAIVectorStore{
+		ID:         c.Name,
+		Object:     "vector_store",
+		CreatedAt:  createdAt,
+		Name:       collectionMetadata[vectorStoreNameKey],
+		UsageBytes: usageBytes,
+		FileCounts: openAIVectorFileCount{
+			Completed: len(files),
+			Total:     len(files),
+		},
+		Status:   "completed",
+		Metadata: metadata,
+	}
+}
+
+// vectorStoreFiles derives the vector store files from the chunks in the collection.
+func vectorStoreFiles(c *Collection) map[string]openAIVectorStoreFile {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	files := make(map[string]openAIVectorStoreFile)
+	for _, doc := range c.documents {
+		fileID := doc.Metadata[vectorStoreFileIDKey]
+		if fileID == "" {
+			continue
+		}
+		f, ok := files[fileID]
+		if !ok {
+			createdAt, _ := strconv.ParseInt(doc.Metadata[vectorStoreCreatedAtKey], 10, 64)
+			f = openAIVectorStoreFile{
+				ID:            fileID,
+				Object:        "vector_store.file",
+				CreatedAt:     createdAt,
+				VectorStoreID: c.Name,
+				Status:        "completed",
+			}
+		}
+		f.UsageBytes += len(doc.Content)
+		files[fileID] = f
+	}
+	return files
+}
+
+// openAIFilterToWhere converts an OpenAI attribute filter to a metadata where
+// clause. Only equality and inequality comparisons, optionally combined with
+// "and", are supported. Inequalities are converted to the "$not" operator.
+func openAIFilterToWhere(filter openAIFilter, where map[string]string) error {
+	switch filter.Type {
+	case "eq":
+		if filter.Key == "" {
+			return errors.New("filter key is empty")
+		}
+		value := fmt.Sprint(filter.Value)
+		if existing, ok := where[filter.Key]; ok && existing != value {
+			return fmt.Errorf("conflicting filters for key %q", filter.Key)
+		}
+		where[filter.Key] = value
+	case "ne":
+		if filter.Key == "" {
+			return errors.New("filter key is empty")
+		}
+		var nots []map[string]string
+		if existing, ok := where["$not"]; ok {
+			if err := json.Unmarshal([]byte(existing), &nots); err != nil {
+				return fmt.Errorf("couldn't decode negations: %w", err)
+			}
+		}
+		nots = append(nots, map[string]string{filter.Key: fmt.Sprint(filter.Value)})
+		b, err := json.Marshal(nots)
+		if err != nil {
+			return fmt.Errorf("couldn't encode negations: %w", err)
+		}
+		where["$not"] = string(b)
+	case "and":
+		for _, f := range filter.Filters {
+			if err := openAIFilterToWhere(f, where); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported filter type: %q", filter.Type)
+	}
+	return nil
+}
+
+// writeOpenAIError writes an error response in the shape of OpenAI's API.
+func writeOpenAIError(w http.ResponseWriter, status int, err error) {
+	errType := "invalid_request_error"
+	if status >= 500 {
+		errType = "server_error"
+	}
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"message": err.Error(),
+			"type":    errType,
+		},
+	})
+}
+
+// randomHex returns a random hex string of n bytes.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// crypto/rand.Read only fails if the OS doesn't provide randomness, in which
+	// case there's nothing reasonable to do anyway.
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("couldn't read random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,125 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVectorStoresHandler(t *testing.T) {
+	vectors := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return vectors, nil
+	}
+
+	db := NewDB()
+	ts := httptest.NewServer(http.StripPrefix("/v1", NewVectorStoresHandler(db, embeddingFunc)))
+	defer ts.Close()
+	baseURL := ts.URL + "/v1"
+
+	// Upload a file
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	_ = mw.WriteField("purpose", "assistants")
+	fw, err := mw.CreateFormFile("file", "sky.txt")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, _ = fw.Write([]byte("The sky is blue because of Rayleigh scattering."))
+	_ = mw.Close()
+	var file openAIFile
+	doJSON(t, http.MethodPost, baseURL+"/files", mw.FormDataContentType(), body, http.StatusOK, &file)
+	if !strings.HasPrefix(file.ID, "file-") || file.Filename != "sky.txt" || file.Purpose != "assistants" {
+		t.Fatalf("unexpected file: %+v", file)
+	}
+
+	// Create a vector store with the file
+	var store openAIVectorStore
+	doJSON(t, http.MethodPost, baseURL+"/vector_stores", "application/json", strings.NewReader(`{"name":"Knowledge","file_ids":["`+file.ID+`"],"metadata":{"team":"a"}}`), http.StatusOK, &store)
+	if !strings.HasPrefix(store.ID, "vs_") || store.Name != "Knowledge" || store.FileCounts.Completed != 1 || store.Metadata["team"] != "a" {
+		t.Fatalf("unexpected vector store: %+v", store)
+	}
+	if c := db.GetCollection(store.ID, nil); c == nil || c.Count() != 1 {
+		t.Fatal("expected collection with 1 document")
+	}
+
+	// Non-vector-store collections aren't listed
+	_, _ = db.CreateCollection("other", nil, embeddingFunc)
+	var stores openAIList[openAIVectorStore]
+	doJSON(t, http.MethodGet, baseURL+"/vector_stores", "", nil, http.StatusOK, &stores)
+	if len(stores.Data) != 1 || stores.Data[0].ID != store.ID {
+		t.Fatalf("unexpected vector stores: %+v", stores)
+	}
+
+	// Search, with and without matching filter
+	var searchRes struct {
+		Data []struct {
+			FileID   string `json:"file_id"`
+			Filename string
+			Content  []mcpContent
+		}
+	}
+	doJSON(t, http.MethodPost, baseURL+"/vector_stores/"+store.ID+"/search", "application/json", strings.NewReader(`{"query":"Why is the sky blue?","filters":{"type":"eq","key":"filename","value":"sky.txt"}}`), http.StatusOK, &searchRes)
+	if len(searchRes.Data) != 1 || searchRes.Data[0].FileID != file.ID || !strings.Contains(searchRes.Data[0].Content[0].Text, "Rayleigh") {
+		t.Fatalf("unexpected search result: %+v", searchRes)
+	}
+	doJSON(t, http.MethodPost, baseURL+"/vector_stores/"+store.ID+"/search", "application/json", strings.NewReader(`{"query":"sky","filters":{"type":"eq","key":"filename","value":"other.txt"}}`), http.StatusOK, &searchRes)
+	if len(searchRes.Data) != 0 {
+		t.Fatalf("expected no search results, got %+v", searchRes)
+	}
+	doJSON(t, http.MethodPost, baseURL+"/vector_stores/"+store.ID+"/search", "application/json", strings.NewReader(`{"query":"sky","filters":{"type":"and","filters":[{"type":"ne","key":"filename","value":"other.txt"},{"type":"ne","key":"filename","value":"third.txt"}]}}`), http.StatusOK, &searchRes)
+	if len(searchRes.Data) != 1 || searchRes.Data[0].FileID != file.ID {
+		t.Fatalf("unexpected search result: %+v", searchRes)
+	}
+	doJSON(t, http.MethodPost, baseURL+"/vector_stores/"+store.ID+"/search", "application/json", strings.NewReader(`{"query":"sky","filters":{"type":"ne","key":"filename","value":"sky.txt"}}`), http.StatusOK, &searchRes)
+	if len(searchRes.Data) != 0 {
+		t.Fatalf("expected no search results, got %+v", searchRes)
+	}
+	doJSON(t, http.MethodPost, baseURL+"/vector_stores/"+store.ID+"/search", "application/json", strings.NewReader(`{"query":"sky","filters":{"type":"gt","key":"n","value":1}}`), http.StatusBadRequest, nil)
+
+	// Remove the file from the vector store
+	var files openAIList[openAIVectorStoreFile]
+	doJSON(t, http.MethodGet, baseURL+"/vector_stores/"+store.ID+"/files", "", nil, http.StatusOK, &files)
+	if len(files.Data) != 1 || files.Data[0].ID != file.ID {
+		t.Fatalf("unexpected vector store files: %+v", files)
+	}
+	doJSON(t, http.MethodDelete, baseURL+"/vector_stores/"+store.ID+"/files/"+file.ID, "", nil, http.StatusOK, nil)
+	doJSON(t, http.MethodGet, baseURL+"/vector_stores/"+store.ID+"/files/"+file.ID, "", nil, http.StatusNotFound, nil)
+
+	// Delete the vector store
+	doJSON(t, http.MethodDelete, baseURL+"/vector_stores/"+store.ID, "", nil, http.StatusOK, nil)
+	doJSON(t, http.MethodGet, baseURL+"/vector_stores/"+store.ID, "", nil, http.StatusNotFound, nil)
+}
+
+// doJSON sends a request, checks the status code and decodes the JSON response
+// body into v if it's not nil.
+func doJSON(t *testing.T, method, url, contentType string, body io.Reader, wantStatus int, v any) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != wantStatus {
+		t.Fatal("expected status", wantStatus, "got", res.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package chromem
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Warmup prepares the collection for queries, so that the first query after
+// startup doesn't have the cold-start penalty: it touches the memory of all
+// embeddings, so that pages that were swapped out are loaded again, and with
+// content spillover (see [Collection.SetContentSpillover]), it loads contents
+// into the content cache until it's full.
+// It can be canceled via the context. Warming up isn't required, queries work
+// the same without it.
+func (c *Collection) Warmup(ctx context.Context) error {
+	c.documentsLock.RLock()
+	docs := c.documentsSliceLocked()
+	c.documentsLock.RUnlock()
+
+	// The sum makes sure the reads aren't optimized away.
+	var sum atomic.Uint32
+	_, err := scanDocs(ctx, c.executor(), docs, time.Time{}, func(batch []*Document) (bool, error) {
+		var s float32
+		for _, doc := range batch {
+			for _, v := range doc.Embedding {
+				s += v
+			}
+		}
+		sum.Add(math.Float32bits(s))
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't touch embeddings: %w", err)
+	}
+	// The scan stops without an error when the context is canceled.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	if c.contentCache == nil {
+		return nil
+	}
+	for _, doc := range c.documents {
+		if c.contentCache.len() >= c.contentCache.size {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_, err := c.withContentLocked(doc)
+		if err != nil {
+			return fmt.Errorf("couldn't load content of document %q: %w", doc.ID, err)
+		}
+	}
+	return nil
+}
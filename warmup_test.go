@@ -0,0 +1,54 @@
+package chromem
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_Warmup(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewPersistentDB(t.TempDir(), false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetContentSpillover(true, 2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < 5; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: []float32{float32(i), 1}, Content: "hello " + strconv.Itoa(i)})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	// Reload the collection, so the content cache is empty
+	db, err = NewPersistentDB(db.persistDirectory, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	if n := c.contentCache.len(); n != 0 {
+		t.Fatal("expected empty content cache, got", n)
+	}
+
+	err = c.Warmup(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if n := c.contentCache.len(); n != 2 {
+		t.Fatal("expected full content cache with 2 contents, got", n)
+	}
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = c.Warmup(canceledCtx)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}